@@ -26,8 +26,16 @@ var ErrInvalidToken = errors.New("invalid token")
 const (
 	TokenTypeAccess  = "access"
 	TokenTypeRefresh = "refresh"
+	// TokenTypeMFA is a short-lived token proving the password step of a
+	// two-factor login; it is only accepted by the second-factor endpoints.
+	TokenTypeMFA = "mfa"
 )
 
+// MaxSessionlessTokenLifetime is the longest an access token without a
+// session may live. Longer-lived ones, such as API tokens issued before they
+// were bound to a session, are refused because they cannot be revoked.
+const MaxSessionlessTokenLifetime = 24 * time.Hour
+
 func GenJwtSecretAndAudience() (string, string) {
 	// Generate a random byte string to use as the secret
 	secretBytes := make([]byte, 32)
@@ -47,6 +55,22 @@ func GenJwtSecretAndAudience() (string, string) {
 }
 
 func GenerateToken(userID int32, role string, secret, jwt_audience string, lifetime time.Duration, tokenType string) (string, error) {
+	return generateToken(userID, role, secret, jwt_audience, lifetime, tokenType, "")
+}
+
+// GenerateSessionToken generates a refresh token bound to a server-side session.
+// The session ID is carried in the "sid" claim.
+func GenerateSessionToken(userID int32, role string, secret, jwt_audience string, lifetime time.Duration, sessionID string) (string, error) {
+	return generateToken(userID, role, secret, jwt_audience, lifetime, TokenTypeRefresh, sessionID)
+}
+
+// GenerateAPIToken generates a long-lived access token bound to a server-side
+// session, so that it ends when the session is revoked.
+func GenerateAPIToken(userID int32, role string, secret, jwt_audience string, lifetime time.Duration, sessionID string) (string, error) {
+	return generateToken(userID, role, secret, jwt_audience, lifetime, TokenTypeAccess, sessionID)
+}
+
+func generateToken(userID int32, role string, secret, jwt_audience string, lifetime time.Duration, tokenType, sessionID string) (string, error) {
 	if tokenType == "" {
 		tokenType = TokenTypeAccess
 	}
@@ -65,6 +89,9 @@ func GenerateToken(userID int32, role string, secret, jwt_audience string, lifet
 		"aud":        jwt_audience,
 		"token_type": tokenType,
 	}
+	if sessionID != "" {
+		claims["sid"] = sessionID
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	token.Header["kid"] = "dfsafdsafdsafadsfdasdfs"
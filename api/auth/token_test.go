@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"testing"
 	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
 )
 
 func TestGenerateToken(t *testing.T) {
@@ -25,3 +27,20 @@ func TestGenerateToken(t *testing.T) {
 		t.Error("generated token does not validate ")
 	}
 }
+
+func TestGenerateSessionToken(t *testing.T) {
+	token, err := GenerateSessionToken(7, "user", "abedefg", "aud", time.Hour, "sess-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateToken(token, "abedefg", TokenTypeRefresh); err != nil {
+		t.Errorf("session token is not a valid refresh token: %v", err)
+	}
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["sid"] != "sess-1" {
+		t.Errorf("sid claim = %v", claims["sid"])
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B vectors for SHA1, truncated to six digits.
+func TestTOTPCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range cases {
+		got, err := TOTPCode(secret, TOTPStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("TOTPCode at %d = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	step := TOTPStep(now)
+	prev, _ := TOTPCode(secret, step-1)
+	old, _ := TOTPCode(secret, step-3)
+
+	if got, ok := ValidateTOTP(secret, prev, now, 0); !ok || got != step-1 {
+		t.Errorf("code from the previous period should be accepted, got %d %v", got, ok)
+	}
+	if _, ok := ValidateTOTP(secret, prev, now, step-1); ok {
+		t.Error("replayed code should be rejected")
+	}
+	if _, ok := ValidateTOTP(secret, old, now, 0); ok {
+		t.Error("code outside the skew window should be rejected")
+	}
+	if _, ok := ValidateTOTP(secret, "12345", now, 0); ok {
+		t.Error("short code should be rejected")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("Chat", "ann@example.com", "ABC")
+	if !strings.HasPrefix(uri, "otpauth://totp/Chat:ann@example.com?") || !strings.Contains(uri, "secret=ABC") || !strings.Contains(uri, "issuer=Chat") {
+		t.Errorf("unexpected uri: %s", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, c := range codes {
+		if len(c) != 11 || c[5] != '-' || seen[c] {
+			t.Errorf("bad or duplicate code %q", c)
+		}
+		seen[c] = true
+	}
+	if NormalizeRecoveryCode(" ABCDE-fghij ") != "abcdefghij" {
+		t.Error("NormalizeRecoveryCode did not normalize")
+	}
+}
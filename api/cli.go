@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/swuecho/chat_backend/config"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+const usage = `usage: app [command]
+
+commands:
+  serve                      run the HTTP server (the default)
+  migrate <command>          manage database migrations
+  user <command>             create users, grant admin rights, reset passwords
+  model <command>            list, add, enable and disable chat models
+  export-session <uuid>      print a chat session and its messages
+  purge-logs                 delete old chat logs
+  storage <command>          move uploaded files between storage backends
+
+Run "app <command> -h" for the options of a command.`
+
+// usageError is a command line mistake; main prints it without logging.
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+// runCommand dispatches the subcommand named by args[0].
+func runCommand(args []string) error {
+	if len(args) == 0 {
+		return run()
+	}
+	switch args[0] {
+	case "serve":
+		return run()
+	case "migrate":
+		return runMigrate(args[1:])
+	case "user":
+		return runUser(args[1:])
+	case "model":
+		return runModel(args[1:])
+	case "export-session":
+		return runExportSession(args[1:])
+	case "purge-logs":
+		return runPurgeLogs(args[1:])
+	case "storage":
+		return runStorage(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Println(usage)
+		return nil
+	default:
+		return usageError(fmt.Sprintf("unknown command %q\n\n%s", args[0], usage))
+	}
+}
+
+// openAdmin loads the configuration and connects to the database for an
+// admin command. The caller closes the returned DB.
+func openAdmin() (config.AppConfig, *sql.DB, *sqlc_queries.Queries, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return cfg, nil, nil, err
+	}
+	config.Set(cfg)
+	pgdb, err := openDB(cfg)
+	if err != nil {
+		return cfg, nil, nil, fmt.Errorf("database: %w", err)
+	}
+	return cfg, pgdb, sqlc_queries.New(pgdb), nil
+}
+
+// parseFlags parses args into fs, turning -h and mistakes into a usageError.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			err = nil
+		}
+		return flagUsage(fs, err)
+	}
+	return nil
+}
+
+// flagUsage describes fs, whose name is the command synopsis, after err when
+// there is one.
+func flagUsage(fs *flag.FlagSet, err error) usageError {
+	var b strings.Builder
+	if err != nil {
+		fmt.Fprintf(&b, "%v\n\n", err)
+	}
+	fmt.Fprintf(&b, "usage: app %s\n", fs.Name())
+	fs.SetOutput(&b)
+	fs.PrintDefaults()
+	return usageError(strings.TrimRight(b.String(), "\n"))
+}
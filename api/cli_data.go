@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+// sessionExport is the JSON written by export-session.
+type sessionExport struct {
+	Session  sqlc_queries.ChatSession                               `json:"session"`
+	Messages []sqlc_queries.GetChatMessagesBySessionUUIDForAdminRow `json:"messages"`
+}
+
+func runExportSession(args []string) error {
+	fs := flag.NewFlagSet("export-session [--format json|markdown] [--output FILE] SESSION_UUID", flag.ContinueOnError)
+	format := fs.String("format", "json", "json or markdown")
+	output := fs.String("output", "", "file to write; defaults to standard output")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return flagUsage(fs, errors.New("exactly one session UUID is required"))
+	}
+	if *format != "json" && *format != "markdown" {
+		return flagUsage(fs, fmt.Errorf("unknown format %q", *format))
+	}
+
+	_, pgdb, q, err := openAdmin()
+	if err != nil {
+		return err
+	}
+	defer pgdb.Close()
+	ctx := context.Background()
+	sessions := svc.NewChatSessionService(q)
+
+	session, err := sessions.GetChatSessionByUUIDWithInActive(ctx, fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("look up session %s: %w", fs.Arg(0), err)
+	}
+	// the admin read is recorded in the audit trail like one from the admin UI
+	messages, err := sessions.GetChatMessagesBySessionUUIDForAdmin(ctx, session.Uuid)
+	if err != nil {
+		return fmt.Errorf("read messages: %w", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	if *format == "markdown" {
+		return writeSessionMarkdown(out, session, messages)
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sessionExport{Session: session, Messages: messages})
+}
+
+func writeSessionMarkdown(w io.Writer, session sqlc_queries.ChatSession, messages []sqlc_queries.GetChatMessagesBySessionUUIDForAdminRow) error {
+	if _, err := fmt.Fprintf(w, "# %s\n\n", session.Topic); err != nil {
+		return err
+	}
+	for _, m := range messages {
+		heading := m.Role
+		if m.Model != "" && m.Role == "assistant" {
+			heading += " (" + m.Model + ")"
+		}
+		if _, err := fmt.Fprintf(w, "## %s\n\n_%s_\n\n%s\n\n", heading, m.CreatedAt.Format("2006-01-02 15:04:05"), m.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runPurgeLogs(args []string) error {
+	fs := flag.NewFlagSet("purge-logs [--older-than-days N]", flag.ContinueOnError)
+	days := fs.Int("older-than-days", 0, "delete chat logs older than this; defaults to CHAT_LOG_RETENTION_DAYS")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	cfg, pgdb, q, err := openAdmin()
+	if err != nil {
+		return err
+	}
+	defer pgdb.Close()
+
+	if *days == 0 {
+		*days = cfg.CHAT_LOG.RETENTION_DAYS
+	}
+	if *days <= 0 {
+		return flagUsage(fs, errors.New("--older-than-days must be positive when CHAT_LOG_RETENTION_DAYS is not set"))
+	}
+	n, err := svc.NewChatLogService(q).PurgeOlderThan(context.Background(), time.Duration(*days)*24*time.Hour)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("deleted %d chat log(s) older than %d day(s)\n", n, *days)
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+const modelUsage = `usage: app model <command>
+
+commands:
+  list
+  add --name NAME --url URL [options]
+  enable NAME
+  disable NAME`
+
+// runModel implements the model subcommand.
+func runModel(args []string) error {
+	if len(args) == 0 {
+		return usageError(modelUsage)
+	}
+	switch args[0] {
+	case "list":
+		return modelList(args[1:])
+	case "add":
+		return modelAdd(args[1:])
+	case "enable":
+		return modelSetEnabled(args[1:], true)
+	case "disable":
+		return modelSetEnabled(args[1:], false)
+	default:
+		return usageError(fmt.Sprintf("unknown model command %q\n\n%s", args[0], modelUsage))
+	}
+}
+
+func modelList(args []string) error {
+	fs := flag.NewFlagSet("model list", flag.ContinueOnError)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	_, pgdb, q, err := openAdmin()
+	if err != nil {
+		return err
+	}
+	defer pgdb.Close()
+
+	models, err := q.ListChatModels(context.Background())
+	if err != nil {
+		return fmt.Errorf("list chat models: %w", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tLABEL\tAPI TYPE\tENABLED\tDEFAULT\tURL")
+	for _, m := range models {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%t\t%t\t%s\n", m.ID, m.Name, m.Label, m.ApiType, m.IsEnable, m.IsDefault, m.Url)
+	}
+	return w.Flush()
+}
+
+func modelAdd(args []string) error {
+	fs := flag.NewFlagSet("model add --name NAME --url URL [options]", flag.ContinueOnError)
+	name := fs.String("name", "", "model name sent to the provider, e.g. gpt-4o")
+	label := fs.String("label", "", "name shown to users; defaults to --name")
+	url := fs.String("url", "", "provider endpoint")
+	apiType := fs.String("api-type", "openai", "openai, claude, gemini, ollama, custom or image")
+	authHeader := fs.String("auth-header", "Authorization", "header carrying the API key")
+	authKey := fs.String("auth-key", "", "environment variable holding the API key")
+	maxToken := fs.Int("max-token", 4096, "largest max_tokens users may request")
+	defaultToken := fs.Int("default-token", 2048, "max_tokens of new sessions")
+	order := fs.Int("order", 0, "position in the model list")
+	timeout := fs.Int("timeout", 120, "request timeout in seconds")
+	isDefault := fs.Bool("default", false, "make it the default model")
+	perModelLimit := fs.Bool("per-model-ratelimit", false, "limit use per user through model privileges")
+	owner := fs.String("owner", "", "email of the admin owning the model; defaults to the first admin")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *name == "" || *url == "" {
+		return flagUsage(fs, errors.New("--name and --url are required"))
+	}
+	if *label == "" {
+		*label = *name
+	}
+
+	_, pgdb, q, err := openAdmin()
+	if err != nil {
+		return err
+	}
+	defer pgdb.Close()
+	ctx := context.Background()
+
+	ownerID, err := modelOwner(ctx, q, *owner)
+	if err != nil {
+		return err
+	}
+	m, err := svc.NewChatModelService(q).Create(ctx, sqlc_queries.CreateChatModelParams{
+		Name: *name, Label: *label, IsDefault: *isDefault, Url: *url,
+		ApiAuthHeader: *authHeader, ApiAuthKey: *authKey, UserID: ownerID,
+		EnablePerModeRatelimit: *perModelLimit, MaxToken: int32(*maxToken), DefaultToken: int32(*defaultToken),
+		OrderNumber: int32(*order), HttpTimeOut: int32(*timeout), ApiType: *apiType,
+	})
+	if err != nil {
+		return fmt.Errorf("create chat model: %w", err)
+	}
+	fmt.Printf("added chat model %s (id %d)\n", m.Name, m.ID)
+	return nil
+}
+
+// modelOwner returns the ID of the admin with the given email, or of the
+// first admin. Only models owned by admins are offered to users.
+func modelOwner(ctx context.Context, q *sqlc_queries.Queries, email string) (int32, error) {
+	if email != "" {
+		user, err := q.GetUserByEmail(ctx, email)
+		if err != nil {
+			return 0, fmt.Errorf("look up user %s: %w", email, err)
+		}
+		if !user.IsSuperuser {
+			return 0, fmt.Errorf("user %s is not an admin", email)
+		}
+		return user.ID, nil
+	}
+	users, err := q.GetAllAuthUsers(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list users: %w", err)
+	}
+	for _, user := range users {
+		if user.IsSuperuser {
+			return user.ID, nil
+		}
+	}
+	return 0, errors.New("there is no admin yet; create one with \"app user create --superuser\"")
+}
+
+func modelSetEnabled(args []string, enabled bool) error {
+	verb := "disable"
+	if enabled {
+		verb = "enable"
+	}
+	fs := flag.NewFlagSet("model "+verb+" NAME", flag.ContinueOnError)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return flagUsage(fs, errors.New("exactly one model name is required"))
+	}
+
+	_, pgdb, q, err := openAdmin()
+	if err != nil {
+		return err
+	}
+	defer pgdb.Close()
+	ctx := context.Background()
+
+	m, err := q.ChatModelByName(ctx, fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("look up chat model %s: %w", fs.Arg(0), err)
+	}
+	if _, err := svc.NewChatModelService(q).SetEnabled(ctx, m.ID, enabled); err != nil {
+		return fmt.Errorf("%s chat model: %w", verb, err)
+	}
+	fmt.Printf("chat model %s %sd\n", m.Name, verb)
+	return nil
+}
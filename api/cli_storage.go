@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+const storageUsage = `usage: app storage <command>
+
+commands:
+  migrate    move files stored inline or in another backend to STORAGE_BACKEND
+  gc         delete stored contents no file refers to`
+
+// runStorage implements the storage subcommand.
+func runStorage(args []string) error {
+	if len(args) == 0 {
+		return usageError(storageUsage)
+	}
+	if len(args) > 1 {
+		return usageError(fmt.Sprintf("storage %s takes no arguments\n\n%s", args[0], storageUsage))
+	}
+	switch args[0] {
+	case "migrate", "gc":
+	case "help", "-h", "-help", "--help":
+		fmt.Println(storageUsage)
+		return nil
+	default:
+		return usageError(fmt.Sprintf("unknown storage command %q\n\n%s", args[0], storageUsage))
+	}
+
+	cfg, pgdb, q, err := openAdmin()
+	if err != nil {
+		return err
+	}
+	defer pgdb.Close()
+	blobs, err := openStorage(cfg, q)
+	if err != nil {
+		return fmt.Errorf("file storage: %w", err)
+	}
+	ctx := context.Background()
+
+	if args[0] == "gc" {
+		n, err := blobs.CollectGarbage(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("deleted %d unreferenced blob(s)\n", n)
+		return nil
+	}
+	result, err := blobs.Migrate(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("moved %d blob(s) and %d inline file(s) to %s\n", result.Blobs, result.Inline, blobs.Backend())
+	if result.Failed > 0 {
+		return fmt.Errorf("%d file(s) could not be moved; see the log and run the command again", result.Failed)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRunCommandUsageErrors(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"bogus"}, `unknown command "bogus"`},
+		{[]string{"user"}, "usage: app user <command>"},
+		{[]string{"user", "create"}, "--email is required"},
+		{[]string{"user", "promote"}, "exactly one email address is required"},
+		{[]string{"model", "add", "--name", "gpt-4o"}, "--name and --url are required"},
+		{[]string{"model", "rename"}, `unknown model command "rename"`},
+		{[]string{"export-session", "--format", "pdf", "uuid"}, `unknown format "pdf"`},
+		{[]string{"migrate"}, "usage: app migrate <command>"},
+		{[]string{"storage"}, "usage: app storage <command>"},
+		{[]string{"storage", "copy"}, `unknown storage command "copy"`},
+	}
+	for _, c := range cases {
+		err := runCommand(c.args)
+		var usageErr usageError
+		if !errors.As(err, &usageErr) {
+			t.Errorf("%v: expected a usage error, got %v", c.args, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%v: expected %q in %q", c.args, c.want, err.Error())
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/swuecho/chat_backend/auth"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+const userUsage = `usage: app user <command>
+
+commands:
+  create --email EMAIL [--password PASSWORD] [--superuser] [--staff]
+  promote [--staff] EMAIL
+  reset-password [--password PASSWORD] EMAIL
+
+A random password is generated and printed when --password is not given.`
+
+// runUser implements the user subcommand.
+func runUser(args []string) error {
+	if len(args) == 0 {
+		return usageError(userUsage)
+	}
+	switch args[0] {
+	case "create":
+		return userCreate(args[1:])
+	case "promote":
+		return userPromote(args[1:])
+	case "reset-password":
+		return userResetPassword(args[1:])
+	default:
+		return usageError(fmt.Sprintf("unknown user command %q\n\n%s", args[0], userUsage))
+	}
+}
+
+func userCreate(args []string) error {
+	fs := flag.NewFlagSet("user create --email EMAIL [options]", flag.ContinueOnError)
+	email := fs.String("email", "", "email address, also the username")
+	password := fs.String("password", "", "password; generated when empty")
+	firstName := fs.String("first-name", "", "first name")
+	lastName := fs.String("last-name", "", "last name")
+	superuser := fs.Bool("superuser", false, "grant admin rights")
+	staff := fs.Bool("staff", false, "grant staff rights")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return flagUsage(fs, errors.New("--email is required"))
+	}
+
+	cfg, pgdb, q, err := openAdmin()
+	if err != nil {
+		return err
+	}
+	defer pgdb.Close()
+	ctx := context.Background()
+	users := svc.NewAuthUserService(q, "", int32(cfg.OPENAI.RATELIMIT))
+
+	if _, err := users.GetUserByEmail(ctx, *email); err == nil {
+		return fmt.Errorf("user %s already exists", *email)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("look up user: %w", err)
+	}
+	generated, err := passwordOrRandom(password)
+	if err != nil {
+		return err
+	}
+	hash, err := auth.GeneratePasswordHash(*password)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+	user, err := users.CreateAuthUser(ctx, sqlc_queries.CreateAuthUserParams{
+		Email: *email, Username: *email, Password: hash, FirstName: *firstName, LastName: *lastName,
+	})
+	if err != nil {
+		return fmt.Errorf("create user: %w", err)
+	}
+	if (*superuser && !user.IsSuperuser) || *staff {
+		if user, err = users.SetRoles(ctx, user.Email, *superuser || user.IsSuperuser, *staff); err != nil {
+			return fmt.Errorf("set roles: %w", err)
+		}
+	}
+
+	fmt.Printf("created user %s (id %d, superuser %t, staff %t)\n", user.Email, user.ID, user.IsSuperuser, user.IsStaff)
+	if generated {
+		fmt.Printf("password: %s\n", *password)
+	}
+	return nil
+}
+
+func userPromote(args []string) error {
+	fs := flag.NewFlagSet("user promote [--staff] EMAIL", flag.ContinueOnError)
+	staffOnly := fs.Bool("staff", false, "grant staff rights instead of admin rights")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return flagUsage(fs, errors.New("exactly one email address is required"))
+	}
+
+	cfg, pgdb, q, err := openAdmin()
+	if err != nil {
+		return err
+	}
+	defer pgdb.Close()
+	ctx := context.Background()
+	users := svc.NewAuthUserService(q, "", int32(cfg.OPENAI.RATELIMIT))
+
+	user, err := users.GetUserByEmail(ctx, fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("look up user %s: %w", fs.Arg(0), err)
+	}
+	superuser := user.IsSuperuser || !*staffOnly
+	if user, err = users.SetRoles(ctx, user.Email, superuser, true); err != nil {
+		return fmt.Errorf("set roles: %w", err)
+	}
+	fmt.Printf("user %s: superuser %t, staff %t\n", user.Email, user.IsSuperuser, user.IsStaff)
+	return nil
+}
+
+func userResetPassword(args []string) error {
+	fs := flag.NewFlagSet("user reset-password [--password PASSWORD] EMAIL", flag.ContinueOnError)
+	password := fs.String("password", "", "new password; generated when empty")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return flagUsage(fs, errors.New("exactly one email address is required"))
+	}
+
+	cfg, pgdb, q, err := openAdmin()
+	if err != nil {
+		return err
+	}
+	defer pgdb.Close()
+	users := svc.NewAuthUserService(q, "", int32(cfg.OPENAI.RATELIMIT))
+
+	generated, err := passwordOrRandom(password)
+	if err != nil {
+		return err
+	}
+	if err := users.ResetPassword(context.Background(), fs.Arg(0), *password); err != nil {
+		return fmt.Errorf("reset password of %s: %w", fs.Arg(0), err)
+	}
+	fmt.Printf("password of %s reset, existing sessions signed out\n", fs.Arg(0))
+	if generated {
+		fmt.Printf("password: %s\n", *password)
+	}
+	return nil
+}
+
+// passwordOrRandom fills an empty password with a random one and reports
+// whether it did.
+func passwordOrRandom(password *string) (bool, error) {
+	if *password != "" {
+		return false, nil
+	}
+	p, err := auth.GenerateRandomPassword()
+	if err != nil {
+		return false, fmt.Errorf("generate password: %w", err)
+	}
+	*password = p
+	return true, nil
+}
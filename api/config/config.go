@@ -1,17 +1,44 @@
-// Package config provides application configuration via environment variables.
+// Package config provides the typed application configuration. Values come
+// from an optional YAML or TOML file named by CONFIG_FILE, overridden by
+// environment variables, e.g. server.listen_addr in the file or
+// SERVER_LISTEN_ADDR in the environment.
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
+	"net"
+	"net/url"
 	"os"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
-// AppConfig holds all application configuration.
+// AppConfig holds all application configuration. Sections listed in
+// reloadable are replaced on SIGHUP; the others need a restart.
 type AppConfig struct {
+	SERVER struct {
+		LISTEN_ADDR         string
+		READ_TIMEOUT        time.Duration
+		READ_HEADER_TIMEOUT time.Duration
+		// WRITE_TIMEOUT bounds streamed answers too, so it is long.
+		WRITE_TIMEOUT    time.Duration
+		IDLE_TIMEOUT     time.Duration
+		SHUTDOWN_TIMEOUT time.Duration
+		// ENVIRONMENT "production" or "prod" marks cookies secure behind
+		// proxies that do not forward the scheme; ENV and NODE_ENV also work.
+		ENVIRONMENT string
+	}
+	DATABASE struct {
+		// URL is a postgres:// URL used instead of the PG settings.
+		URL string
+	}
 	OPENAI struct {
 		API_KEY   string
 		RATELIMIT int
@@ -27,29 +54,333 @@ type AppConfig struct {
 		PASS string
 		DB   string
 	}
+	MAIL struct {
+		// DRIVER is smtp, file or log; empty logs messages without sending them.
+		DRIVER    string
+		FROM      string
+		SMTP_HOST string
+		SMTP_PORT int
+		SMTP_USER string
+		SMTP_PASS string
+		// FILE_DIR is where the file driver writes .eml files.
+		FILE_DIR string
+		// BASE_URL is the public URL of the web app used to build links in emails.
+		BASE_URL string
+		// REQUIRE_VERIFICATION blocks login until the email address is confirmed.
+		REQUIRE_VERIFICATION bool
+	}
+	OIDC struct {
+		// ISSUER enables single sign-on against this OpenID Connect provider.
+		ISSUER        string
+		CLIENT_ID     string
+		CLIENT_SECRET string
+		// REDIRECT_URL is the public URL of /api/auth/oidc/callback.
+		REDIRECT_URL string
+		// SCOPES is space separated; defaults to "openid email profile".
+		SCOPES string
+		// GROUPS_CLAIM is the ID token claim listing groups; defaults to "groups".
+		GROUPS_CLAIM string
+		// ADMIN_GROUPS and STAFF_GROUPS are comma separated groups granting is_superuser and is_staff.
+		ADMIN_GROUPS string
+		STAFF_GROUPS string
+		// GROUP_WORKSPACES maps groups to workspaces, e.g. "eng=Engineering,sales=Sales".
+		GROUP_WORKSPACES string
+		// DISABLE_SIGNUP stops accounts from being created on first SSO login.
+		DISABLE_SIGNUP bool
+		// LINK_BY_EMAIL links a new identity to the account with the same verified email.
+		LINK_BY_EMAIL bool
+	}
+	CREDENTIAL struct {
+		// MASTER_KEY encrypts stored provider keys: base64 32-byte keys as
+		// "id:key,id:key", the first one used for new data. Without it the
+		// credential store is off and models use their api_auth_key env var.
+		MASTER_KEY string
+		// MASTER_KEY_FILE holds MASTER_KEY instead; it is re-read on SIGHUP so
+		// master keys can be rotated without a restart.
+		MASTER_KEY_FILE string
+	}
+	METRICS struct {
+		// TOKEN must be sent as "Authorization: Bearer <token>" to read /metrics; empty disables /metrics.
+		TOKEN string
+	}
+	TRACING struct {
+		// OTLP_ENDPOINT is the OTLP/HTTP collector URL, e.g. http://localhost:4318; empty disables tracing.
+		OTLP_ENDPOINT string
+		// SERVICE_NAME defaults to "chat".
+		SERVICE_NAME string
+		// SAMPLE_RATIO is the share of traces recorded, between 0 and 1; 0 records all.
+		SAMPLE_RATIO float64
+	}
+	MIGRATE struct {
+		// SKIP_ON_START leaves pending migrations to "migrate up" instead of
+		// applying them when the server starts.
+		SKIP_ON_START bool
+	}
+	CHAT_LOG struct {
+		// RETENTION_DAYS purges chat logs older than this many days; 0 keeps them forever.
+		RETENTION_DAYS int
+	}
+	CORS struct {
+		// ALLOWED_ORIGINS lists origins allowed to call the API, or "*". When
+		// empty any localhost origin is allowed.
+		ALLOWED_ORIGINS []string
+	}
+	UPLOAD struct {
+		// MAX_FILE_SIZE is the largest file upload in bytes.
+		MAX_FILE_SIZE int64
+		// MAX_BODY_SIZE is the largest body in bytes of any other request.
+		MAX_BODY_SIZE int64
+	}
+	STORAGE struct {
+		// BACKEND holds new uploads: postgres, local or s3. Files stored in
+		// another configured backend stay readable; "storage migrate" moves them.
+		BACKEND string
+		// LOCAL_DIR is the directory of the local backend.
+		LOCAL_DIR string
+		// S3_* configure an S3-compatible bucket such as AWS S3 or MinIO.
+		S3_ENDPOINT   string
+		S3_REGION     string
+		S3_BUCKET     string
+		S3_ACCESS_KEY string
+		S3_SECRET_KEY string
+		// S3_PREFIX is prepended to object keys.
+		S3_PREFIX string
+		// S3_PATH_STYLE puts the bucket in the URL path, as MinIO needs.
+		S3_PATH_STYLE bool
+	}
+	IMAGE struct {
+		// MAX_PIXELS rejects uploaded images with more pixels; 0 allows any size.
+		MAX_PIXELS int
+		// CONVERT_COMMAND converts HEIC images, reading them on standard input
+		// and writing PNG or JPEG to standard output, e.g.
+		// "heif-convert - -" or "magick heic:- jpeg:-". Without it HEIC images
+		// are only sent to models that accept them.
+		CONVERT_COMMAND string
+	}
+	CHAT struct {
+		// Settings of new chat sessions.
+		DEFAULT_MAX_LENGTH    int
+		DEFAULT_TEMPERATURE   float64
+		DEFAULT_MAX_TOKENS    int
+		DEFAULT_TOP_P         float64
+		DEFAULT_N             int
+		DEFAULT_SYSTEM_PROMPT string
+		// PER_WORD_STREAM_LIMIT is how many characters of an answer are
+		// streamed word by word before switching to larger chunks.
+		PER_WORD_STREAM_LIMIT int
+	}
+	FEATURE struct {
+		// SIGNUP allows creating accounts with a password; the first account
+		// can always be created.
+		SIGNUP bool
+		// ARTIFACTS tells models how to produce artifacts in sessions that enable them.
+		ARTIFACTS bool
+	}
+	TTS struct {
+		// HOST and PORT reach a local engine speaking the Coqui TTS server
+		// API, used when no speech auxiliary model is configured.
+		HOST string
+		PORT string
+		// DEFAULT_VOICE and DEFAULT_SPEED apply to users who have not chosen
+		// their own; an empty voice is the engine's default.
+		DEFAULT_VOICE string
+		DEFAULT_SPEED float64
+	}
+	FLY struct {
+		// APP_NAME is set by Fly.io and enables the idle monitor.
+		APP_NAME string
+		// RESTART_INTERVAL_IF_IDLE exits the server after this long without requests.
+		RESTART_INTERVAL_IF_IDLE time.Duration
+	}
 }
 
-// Load reads configuration from environment variables into AppConfig.
-func Load() AppConfig {
+// reloadable names the sections that take effect without a restart. Code
+// using them reads Current() each time instead of keeping a copy.
+var reloadable = map[string]bool{
+	"CORS":    true,
+	"UPLOAD":  true,
+	"IMAGE":   true,
+	"CHAT":    true,
+	"FEATURE": true,
+	"TTS":     true,
+}
+
+// defaults are the values of settings missing from the file and environment.
+var defaults = map[string]any{
+	"OPENAI.RATELIMIT":             100,
+	"SERVER.LISTEN_ADDR":           ":8080",
+	"SERVER.READ_TIMEOUT":          "60s",
+	"SERVER.READ_HEADER_TIMEOUT":   "30s",
+	"SERVER.WRITE_TIMEOUT":         "10m",
+	"SERVER.IDLE_TIMEOUT":          "120s",
+	"SERVER.SHUTDOWN_TIMEOUT":      "30s",
+	"UPLOAD.MAX_FILE_SIZE":         32 << 20,
+	"UPLOAD.MAX_BODY_SIZE":         1 << 20,
+	"STORAGE.BACKEND":              "postgres",
+	"STORAGE.S3_REGION":            "us-east-1",
+	"IMAGE.MAX_PIXELS":             50_000_000,
+	"CHAT.DEFAULT_MAX_LENGTH":      10,
+	"CHAT.DEFAULT_TEMPERATURE":     0.7,
+	"CHAT.DEFAULT_MAX_TOKENS":      4096,
+	"CHAT.DEFAULT_TOP_P":           1.0,
+	"CHAT.DEFAULT_N":               1,
+	"CHAT.DEFAULT_SYSTEM_PROMPT":   "You are a helpful, concise assistant. Ask clarifying questions when needed. Provide accurate answers with short reasoning and actionable steps. If unsure, say so and suggest how to verify.",
+	"CHAT.PER_WORD_STREAM_LIMIT":   200,
+	"FEATURE.SIGNUP":               true,
+	"FEATURE.ARTIFACTS":            true,
+	"TTS.DEFAULT_SPEED":            1.0,
+	"FLY.RESTART_INTERVAL_IF_IDLE": "30m",
+}
+
+// envAliases are environment variables read for a setting besides the one
+// derived from its name, kept for existing deployments.
+var envAliases = map[string][]string{
+	"SERVER.ENVIRONMENT":         {"ENV", "ENVIRONMENT", "NODE_ENV"},
+	"CHAT.PER_WORD_STREAM_LIMIT": {"PER_WORD_STREAM_LIMIT"},
+}
+
+// Load reads the configuration file named by CONFIG_FILE, if any, and the
+// environment, which takes precedence, and validates the result.
+func Load() (AppConfig, error) {
+	return load(os.Getenv("CONFIG_FILE"), true)
+}
+
+func load(file string, env bool) (AppConfig, error) {
 	cfg := AppConfig{}
-	for _, key := range flattenKeys("", reflect.ValueOf(cfg)) {
-		envKey := strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
-		if err := viper.BindEnv(key, envKey); err != nil {
-			slog.Error("config: unable to bind env", "key", key, "error", err)
-			os.Exit(1)
+	v := viper.New()
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+	if env {
+		for _, key := range flattenKeys("", reflect.ValueOf(cfg)) {
+			envKey := strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+			if err := v.BindEnv(append([]string{key, envKey}, envAliases[key]...)...); err != nil {
+				return cfg, fmt.Errorf("bind %s: %w", envKey, err)
+			}
 		}
 	}
-	viper.AutomaticEnv()
-	if err := viper.Unmarshal(&cfg); err != nil {
-		slog.Error("config: unable to decode", "error", err)
-		os.Exit(1)
+	if file != "" {
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			return cfg, fmt.Errorf("read %s: %w", file, err)
+		}
+	}
+	if err := v.UnmarshalExact(&cfg); err != nil {
+		return cfg, fmt.Errorf("decode configuration: %w", err)
 	}
 	if cfg.OPENAI.RATELIMIT == 0 {
 		cfg.OPENAI.RATELIMIT = 100
 	}
+	return cfg, cfg.Validate()
+}
+
+// Default returns the configuration with every setting at its default.
+func Default() AppConfig {
+	cfg, _ := load("", false)
 	return cfg
 }
 
+// Validate reports every invalid setting, named by its environment variable.
+func (c AppConfig) Validate() error {
+	var problems []string
+	check := func(ok bool, name, format string, args ...any) {
+		if !ok {
+			problems = append(problems, name+": "+fmt.Sprintf(format, args...))
+		}
+	}
+
+	_, _, err := net.SplitHostPort(c.SERVER.LISTEN_ADDR)
+	check(err == nil, "SERVER_LISTEN_ADDR", "%q is not host:port", c.SERVER.LISTEN_ADDR)
+	check(c.SERVER.READ_TIMEOUT >= 0, "SERVER_READ_TIMEOUT", "must not be negative")
+	check(c.SERVER.READ_HEADER_TIMEOUT >= 0, "SERVER_READ_HEADER_TIMEOUT", "must not be negative")
+	check(c.SERVER.WRITE_TIMEOUT >= 0, "SERVER_WRITE_TIMEOUT", "must not be negative")
+	check(c.SERVER.IDLE_TIMEOUT >= 0, "SERVER_IDLE_TIMEOUT", "must not be negative")
+	check(c.SERVER.SHUTDOWN_TIMEOUT > 0, "SERVER_SHUTDOWN_TIMEOUT", "must be positive")
+	check(c.PG.PORT >= 0 && c.PG.PORT <= 65535, "PG_PORT", "%d is not a port", c.PG.PORT)
+	check(c.OPENAI.RATELIMIT > 0, "OPENAI_RATELIMIT", "must be positive")
+	check(c.MAIL.DRIVER == "" || c.MAIL.DRIVER == "smtp" || c.MAIL.DRIVER == "file" || c.MAIL.DRIVER == "log",
+		"MAIL_DRIVER", "%q is not smtp, file or log", c.MAIL.DRIVER)
+	check(c.TRACING.SAMPLE_RATIO >= 0 && c.TRACING.SAMPLE_RATIO <= 1, "TRACING_SAMPLE_RATIO", "must be between 0 and 1")
+	check(c.CHAT_LOG.RETENTION_DAYS >= 0, "CHAT_LOG_RETENTION_DAYS", "must not be negative")
+	for _, origin := range c.CORS.ALLOWED_ORIGINS {
+		origin = strings.TrimSpace(origin)
+		u, err := url.Parse(origin)
+		check(origin == "*" || (err == nil && u.Scheme != "" && u.Host != ""),
+			"CORS_ALLOWED_ORIGINS", "%q is not an origin like https://chat.example.com", origin)
+	}
+	check(c.UPLOAD.MAX_FILE_SIZE > 0, "UPLOAD_MAX_FILE_SIZE", "must be positive")
+	check(c.UPLOAD.MAX_BODY_SIZE > 0, "UPLOAD_MAX_BODY_SIZE", "must be positive")
+	switch c.STORAGE.BACKEND {
+	case "postgres":
+	case "local":
+		check(c.STORAGE.LOCAL_DIR != "", "STORAGE_LOCAL_DIR", "is required by the local backend")
+	case "s3":
+		check(c.STORAGE.S3_BUCKET != "", "STORAGE_S3_BUCKET", "is required by the s3 backend")
+		check(c.STORAGE.S3_ACCESS_KEY != "" && c.STORAGE.S3_SECRET_KEY != "",
+			"STORAGE_S3_ACCESS_KEY", "and STORAGE_S3_SECRET_KEY are required by the s3 backend")
+	default:
+		check(false, "STORAGE_BACKEND", "%q is not postgres, local or s3", c.STORAGE.BACKEND)
+	}
+	if c.STORAGE.S3_BUCKET != "" {
+		u, err := url.Parse(c.STORAGE.S3_ENDPOINT)
+		check(err == nil && u.Scheme != "" && u.Host != "", "STORAGE_S3_ENDPOINT", "%q is not a URL", c.STORAGE.S3_ENDPOINT)
+	}
+	check(c.IMAGE.MAX_PIXELS >= 0, "IMAGE_MAX_PIXELS", "must not be negative")
+	check(c.CHAT.DEFAULT_MAX_LENGTH > 0, "CHAT_DEFAULT_MAX_LENGTH", "must be positive")
+	check(c.CHAT.DEFAULT_TEMPERATURE >= 0 && c.CHAT.DEFAULT_TEMPERATURE <= 2, "CHAT_DEFAULT_TEMPERATURE", "must be between 0 and 2")
+	check(c.CHAT.DEFAULT_MAX_TOKENS > 0, "CHAT_DEFAULT_MAX_TOKENS", "must be positive")
+	check(c.CHAT.DEFAULT_TOP_P > 0 && c.CHAT.DEFAULT_TOP_P <= 1, "CHAT_DEFAULT_TOP_P", "must be above 0 and at most 1")
+	check(c.CHAT.DEFAULT_N > 0, "CHAT_DEFAULT_N", "must be positive")
+	check(c.CHAT.PER_WORD_STREAM_LIMIT >= 0, "CHAT_PER_WORD_STREAM_LIMIT", "must not be negative")
+	check(c.TTS.DEFAULT_SPEED >= 0.25 && c.TTS.DEFAULT_SPEED <= 4, "TTS_DEFAULT_SPEED", "must be between 0.25 and 4")
+	check(c.FLY.RESTART_INTERVAL_IF_IDLE > 0, "FLY_RESTART_INTERVAL_IF_IDLE", "must be positive")
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+var current atomic.Pointer[AppConfig]
+
+var defaultConfig = sync.OnceValue(Default)
+
+// Current returns the configuration installed by Set or Reload, or the
+// defaults before that.
+func Current() AppConfig {
+	if cfg := current.Load(); cfg != nil {
+		return *cfg
+	}
+	return defaultConfig()
+}
+
+// Set installs cfg as the current configuration.
+func Set(cfg AppConfig) {
+	current.Store(&cfg)
+}
+
+// Reload loads the configuration again and installs its reloadable sections.
+// The other sections keep their values; changes to them are logged as needing
+// a restart. An invalid configuration is rejected as a whole.
+func Reload() (AppConfig, error) {
+	next, err := Load()
+	if err != nil {
+		return Current(), err
+	}
+	merged := Current()
+	mv, nv := reflect.ValueOf(&merged).Elem(), reflect.ValueOf(next)
+	for i := 0; i < mv.NumField(); i++ {
+		name := mv.Type().Field(i).Name
+		if reloadable[name] {
+			mv.Field(i).Set(nv.Field(i))
+		} else if !reflect.DeepEqual(mv.Field(i).Interface(), nv.Field(i).Interface()) {
+			slog.Warn("config: changed settings take effect after a restart", "section", name)
+		}
+	}
+	Set(merged)
+	return merged, nil
+}
+
 func flattenKeys(prefix string, v reflect.Value) []string {
 	switch v.Kind() {
 	case reflect.Struct:
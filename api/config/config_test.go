@@ -2,8 +2,11 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadDefaults(t *testing.T) {
@@ -12,7 +15,10 @@ func TestLoadDefaults(t *testing.T) {
 	defer os.Unsetenv("OPENAI_API_KEY")
 	defer os.Unsetenv("OPENAI_RATELIMIT")
 
-	cfg := Load()
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if cfg.OPENAI.API_KEY != "test-key" {
 		t.Errorf("expected API_KEY=test-key, got %s", cfg.OPENAI.API_KEY)
@@ -23,7 +29,7 @@ func TestLoadDefaults(t *testing.T) {
 }
 
 func TestLoadDefaultRateLimit(t *testing.T) {
-	cfg := Load()
+	cfg := Default()
 	if cfg.OPENAI.RATELIMIT == 0 {
 		t.Error("RATELIMIT should default to 100")
 	}
@@ -40,3 +46,108 @@ func TestFlattenKeys(t *testing.T) {
 		t.Errorf("expected 2 keys, got %d: %v", len(keys), keys)
 	}
 }
+
+func TestLoadFileWithEnvOverride(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `
+server:
+  listen_addr: ":9090"
+  write_timeout: 5m
+cors:
+  allowed_origins: ["https://chat.example.com"]
+chat:
+  default_temperature: 0.2
+`
+	if err := os.WriteFile(file, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", file)
+	t.Setenv("SERVER_LISTEN_ADDR", ":7070")
+	t.Setenv("PER_WORD_STREAM_LIMIT", "50")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SERVER.LISTEN_ADDR != ":7070" {
+		t.Errorf("env should override the file, got %q", cfg.SERVER.LISTEN_ADDR)
+	}
+	if cfg.SERVER.WRITE_TIMEOUT != 5*time.Minute {
+		t.Errorf("expected write timeout 5m, got %v", cfg.SERVER.WRITE_TIMEOUT)
+	}
+	if cfg.SERVER.READ_TIMEOUT != time.Minute {
+		t.Errorf("expected default read timeout 1m, got %v", cfg.SERVER.READ_TIMEOUT)
+	}
+	if len(cfg.CORS.ALLOWED_ORIGINS) != 1 || cfg.CORS.ALLOWED_ORIGINS[0] != "https://chat.example.com" {
+		t.Errorf("unexpected origins %v", cfg.CORS.ALLOWED_ORIGINS)
+	}
+	if cfg.CHAT.DEFAULT_TEMPERATURE != 0.2 {
+		t.Errorf("expected temperature 0.2, got %v", cfg.CHAT.DEFAULT_TEMPERATURE)
+	}
+	if cfg.CHAT.PER_WORD_STREAM_LIMIT != 50 {
+		t.Errorf("legacy PER_WORD_STREAM_LIMIT should apply, got %d", cfg.CHAT.PER_WORD_STREAM_LIMIT)
+	}
+}
+
+func TestLoadRejectsUnknownFileKeys(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(file, []byte("[server]\nlisten_adr = \":9090\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", file)
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "listen_adr") {
+		t.Errorf("expected an error naming the unknown key, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("defaults should be valid: %v", err)
+	}
+	cfg.SERVER.LISTEN_ADDR = "8080"
+	cfg.CHAT.DEFAULT_TEMPERATURE = 3
+	cfg.CORS.ALLOWED_ORIGINS = []string{"chat.example.com"}
+	cfg.STORAGE.BACKEND = "local"
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, name := range []string{"SERVER_LISTEN_ADDR", "CHAT_DEFAULT_TEMPERATURE", "CORS_ALLOWED_ORIGINS", "STORAGE_LOCAL_DIR"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected %s in %q", name, err.Error())
+		}
+	}
+}
+
+func TestReloadKeepsRestartOnlySections(t *testing.T) {
+	defer current.Store(nil)
+	t.Setenv("SERVER_LISTEN_ADDR", ":8080")
+	t.Setenv("FEATURE_SIGNUP", "true")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	Set(cfg)
+
+	t.Setenv("SERVER_LISTEN_ADDR", ":9090")
+	t.Setenv("FEATURE_SIGNUP", "false")
+	reloaded, err := Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.SERVER.LISTEN_ADDR != ":8080" {
+		t.Errorf("listen address should need a restart, got %q", reloaded.SERVER.LISTEN_ADDR)
+	}
+	if Current().FEATURE.SIGNUP {
+		t.Error("feature flags should reload")
+	}
+
+	t.Setenv("CHAT_DEFAULT_N", "0")
+	if _, err := Reload(); err == nil {
+		t.Error("expected an invalid configuration to be rejected")
+	}
+	if Current().CHAT.DEFAULT_N != 1 {
+		t.Errorf("rejected reload should keep the previous configuration, got n=%d", Current().CHAT.DEFAULT_N)
+	}
+}
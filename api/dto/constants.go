@@ -13,15 +13,9 @@ const (
 	MaxHistoryItems            = 10000
 	DefaultPageLimit           = 30
 	TestDemoPrefix             = "test_demo_bestqa"
-	DefaultMaxLength           = 10
-	DefaultTemperature         = 0.7
-	DefaultMaxTokens           = 4096
-	DefaultTopP                = 1.0
-	DefaultN                   = 1
 	RequestTimeoutSeconds      = 10
 	TokenEstimateRatio         = 4
 	SummarizeThreshold         = 300
-	DefaultSystemPromptText    = "You are a helpful, concise assistant. Ask clarifying questions when needed. Provide accurate answers with short reasoning and actionable steps. If unsure, say so and suggest how to verify."
 )
 
 // Error message constants
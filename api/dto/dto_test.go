@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestAPIError(t *testing.T) {
@@ -62,3 +63,22 @@ func TestPaginationOffset(t *testing.T) {
 		t.Errorf("expected 40, got %d", p.Offset())
 	}
 }
+
+func TestRespondWithAPIErrorRateLimit(t *testing.T) {
+	apiErr := ErrTooManyRequests
+	apiErr.RateLimit = &RateLimit{Limit: 10, Remaining: 0, Reset: 1500 * time.Millisecond}
+	w := httptest.NewRecorder()
+	RespondWithAPIError(w, apiErr)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("expected Retry-After 2, got %q", got)
+	}
+	if got := w.Header().Get("X-RateLimit-Limit"); got != "10" {
+		t.Errorf("expected X-RateLimit-Limit 10, got %q", got)
+	}
+	if got := w.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("expected X-RateLimit-Remaining 0, got %q", got)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"log/slog"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/jackc/pgconn"
@@ -22,6 +23,8 @@ type APIError struct {
 	Message   string `json:"message"`
 	Detail    string `json:"detail,omitempty"`
 	DebugInfo string `json:"-"`
+	// RateLimit, when set, is sent as X-RateLimit-* and Retry-After headers.
+	RateLimit *RateLimit `json:"-"`
 }
 
 func (e APIError) Error() string {
@@ -85,6 +88,26 @@ var (
 		Code:     ErrAuth + "_005",
 		Message:  "Access denied",
 	}
+	ErrAuthEmailNotVerified = APIError{
+		HTTPCode: http.StatusForbidden,
+		Code:     ErrAuth + "_006",
+		Message:  "Email address not verified",
+	}
+	ErrAuthInvalidLinkToken = APIError{
+		HTTPCode: http.StatusBadRequest,
+		Code:     ErrAuth + "_007",
+		Message:  "Invalid or expired link",
+	}
+	ErrAuthSSOFailed = APIError{
+		HTTPCode: http.StatusForbidden,
+		Code:     ErrAuth + "_008",
+		Message:  "Single sign-on failed",
+	}
+	ErrAuthInvalidTwoFactorCode = APIError{
+		HTTPCode: http.StatusUnauthorized,
+		Code:     ErrAuth + "_009",
+		Message:  "Invalid two-factor authentication code",
+	}
 
 	// Resource errors
 	ErrResourceNotFoundGeneric = APIError{
@@ -283,6 +306,12 @@ func CreateAPIError(baseErr APIError, detail, debugInfo string) APIError {
 
 // RespondWithAPIError writes an APIError response to the client.
 func RespondWithAPIError(w http.ResponseWriter, err APIError) {
+	if err.RateLimit != nil {
+		err.RateLimit.SetHeaders(w.Header())
+		if err.HTTPCode == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", strconv.Itoa(err.RateLimit.ResetSeconds()))
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(err.HTTPCode)
 
@@ -435,6 +464,10 @@ var ErrorCatalog = map[string]APIError{
 	ErrAuthAdminRequired.Code:             ErrAuthAdminRequired,
 	ErrAuthInvalidEmailOrPassword.Code:    ErrAuthInvalidEmailOrPassword,
 	ErrAuthAccessDenied.Code:              ErrAuthAccessDenied,
+	ErrAuthEmailNotVerified.Code:          ErrAuthEmailNotVerified,
+	ErrAuthInvalidLinkToken.Code:          ErrAuthInvalidLinkToken,
+	ErrAuthSSOFailed.Code:                 ErrAuthSSOFailed,
+	ErrAuthInvalidTwoFactorCode.Code:      ErrAuthInvalidTwoFactorCode,
 	ErrResourceNotFoundGeneric.Code:       ErrResourceNotFoundGeneric,
 	ErrResourceAlreadyExistsGeneric.Code:  ErrResourceAlreadyExistsGeneric,
 	ErrTooManyRequests.Code:               ErrTooManyRequests,
@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimit is the state of the quota closest to being exhausted.
+type RateLimit struct {
+	Limit     int64
+	Remaining int64
+	// Reset is how long until usage drops, i.e. until the oldest counted
+	// request leaves the window.
+	Reset time.Duration
+}
+
+// ResetSeconds returns Reset rounded up to whole seconds.
+func (l RateLimit) ResetSeconds() int {
+	return int(math.Ceil(l.Reset.Seconds()))
+}
+
+// SetHeaders writes X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset (a Unix time) to h.
+func (l RateLimit) SetHeaders(h http.Header) {
+	h.Set("X-RateLimit-Limit", strconv.FormatInt(l.Limit, 10))
+	h.Set("X-RateLimit-Remaining", strconv.FormatInt(l.Remaining, 10))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(l.Reset).Unix(), 10))
+}
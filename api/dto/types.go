@@ -2,6 +2,7 @@
 package dto
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -55,6 +56,11 @@ type TokenResult struct {
 	ExpiresIn   int    `json:"expiresIn"`
 }
 
+// SignUpResult is returned instead of tokens when the new account must verify its email first.
+type SignUpResult struct {
+	VerificationRequired bool `json:"verificationRequired"`
+}
+
 type Artifact struct {
 	UUID     string `json:"uuid"`
 	Type     string `json:"type"`
@@ -153,17 +159,22 @@ type UpdateWorkspaceOrderRequest struct {
 	OrderPosition int32 `json:"orderPosition"`
 }
 
+type UpdateWorkspaceChatLogRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
 type WorkspaceResponse struct {
-	Uuid          string `json:"uuid"`
-	Name          string `json:"name"`
-	Description   string `json:"description"`
-	Color         string `json:"color"`
-	Icon          string `json:"icon"`
-	IsDefault     bool   `json:"isDefault"`
-	OrderPosition int32  `json:"orderPosition"`
-	SessionCount  int64  `json:"sessionCount,omitempty"`
-	CreatedAt     string `json:"createdAt"`
-	UpdatedAt     string `json:"updatedAt"`
+	Uuid           string `json:"uuid"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	Color          string `json:"color"`
+	Icon           string `json:"icon"`
+	IsDefault      bool   `json:"isDefault"`
+	OrderPosition  int32  `json:"orderPosition"`
+	ChatLogEnabled bool   `json:"chatLogEnabled"`
+	SessionCount   int64  `json:"sessionCount,omitempty"`
+	CreatedAt      string `json:"createdAt"`
+	UpdatedAt      string `json:"updatedAt"`
 }
 
 type CreateSessionInWorkspaceRequest struct {
@@ -172,6 +183,333 @@ type CreateSessionInWorkspaceRequest struct {
 	DefaultSystemPrompt string `json:"defaultSystemPrompt"`
 }
 
+// --- Prompt template types ---
+
+type PromptTemplateRequest struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Content     string   `json:"content"`
+	Model       string   `json:"model"`
+	Tags        []string `json:"tags"`
+	Visibility  string   `json:"visibility"`
+	// WorkspaceUuid scopes the template to a workspace; "" makes it user-scoped.
+	// Updates leave the scope unchanged when it is omitted.
+	WorkspaceUuid *string `json:"workspaceUuid,omitempty"`
+}
+
+type PromptTemplateResponse struct {
+	Uuid          string     `json:"uuid"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	Content       string     `json:"content"`
+	Model         string     `json:"model"`
+	Tags          []string   `json:"tags"`
+	Variables     []string   `json:"variables"`
+	Visibility    string     `json:"visibility"`
+	WorkspaceUuid string     `json:"workspaceUuid,omitempty"`
+	IsOwner       bool       `json:"isOwner"`
+	Version       int32      `json:"version"`
+	UsageCount    int32      `json:"usageCount"`
+	LastUsedAt    *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt     string     `json:"createdAt"`
+	UpdatedAt     string     `json:"updatedAt"`
+}
+
+type PromptTemplateVersionResponse struct {
+	Version   int32    `json:"version"`
+	Content   string   `json:"content"`
+	Model     string   `json:"model"`
+	Variables []string `json:"variables"`
+	IsCurrent bool     `json:"isCurrent"`
+	CreatedAt string   `json:"createdAt"`
+}
+
+type SharePromptTemplateRequest struct {
+	Email string `json:"email"`
+}
+
+type CreateSessionFromTemplateRequest struct {
+	Variables     map[string]string `json:"variables"`
+	Version       int32             `json:"version,omitempty"`
+	WorkspaceUuid string            `json:"workspaceUuid,omitempty"`
+	Topic         string            `json:"topic,omitempty"`
+	Model         string            `json:"model,omitempty"`
+}
+
+// --- Slash command types ---
+
+type SlashCommandRequest struct {
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Template      string   `json:"template"`
+	Model         string   `json:"model"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	WorkspaceUuid string   `json:"workspaceUuid,omitempty"`
+}
+
+type SlashCommandResponse struct {
+	Uuid          string   `json:"uuid"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Template      string   `json:"template"`
+	Model         string   `json:"model"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	WorkspaceUuid string   `json:"workspaceUuid,omitempty"`
+	CreatedAt     string   `json:"createdAt"`
+	UpdatedAt     string   `json:"updatedAt"`
+}
+
+// --- Chatbot publication types ---
+
+type ChatBotPublicationRequest struct {
+	AccessMode     string   `json:"accessMode"`
+	RateLimit      int32    `json:"rateLimit"`
+	AllowedOrigins []string `json:"allowedOrigins"`
+}
+
+type ChatBotPublicationResponse struct {
+	BotUuid        string   `json:"botUuid"`
+	AccessMode     string   `json:"accessMode"`
+	RateLimit      int32    `json:"rateLimit"`
+	AllowedOrigins []string `json:"allowedOrigins"`
+	HasToken       bool     `json:"hasToken"`
+	EmbedUrl       string   `json:"embedUrl"`
+}
+
+type ChatBotTokenResponse struct {
+	Token string `json:"token"`
+}
+
+type PublicBotInfoResponse struct {
+	Uuid    string `json:"uuid"`
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+}
+
+type PublicBotChatRequest struct {
+	Message   string `json:"message"`
+	VisitorId string `json:"visitorId"`
+	Stream    bool   `json:"stream"`
+}
+
+type PublicBotHistoryItem struct {
+	Prompt    string `json:"prompt"`
+	Answer    string `json:"answer"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// --- Chatbot version types ---
+
+type ChatBotVersionRequest struct {
+	SystemPrompt string              `json:"systemPrompt"`
+	Examples     []SimpleChatMessage `json:"examples"`
+	Model        string              `json:"model"`
+	FileIds      []int32             `json:"fileIds"`
+	Note         string              `json:"note"`
+}
+
+type ChatBotVersionResponse struct {
+	Version      int32               `json:"version"`
+	Model        string              `json:"model"`
+	SystemPrompt string              `json:"systemPrompt"`
+	Examples     []SimpleChatMessage `json:"examples"`
+	FileIds      []int32             `json:"fileIds"`
+	Note         string              `json:"note"`
+	Current      bool                `json:"current"`
+	CreatedAt    string              `json:"createdAt"`
+}
+
+type ChatBotFileResponse struct {
+	ID        int32  `json:"id"`
+	Name      string `json:"name"`
+	MimeType  string `json:"mimeType"`
+	Size      int32  `json:"size"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// --- Mail types ---
+
+type InviteUserRequest struct {
+	Email     string `json:"email"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
+
+// --- Single sign-on types ---
+
+type SSOConfigResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+type SSOLinkResponse struct {
+	URL string `json:"url"`
+}
+
+type UserIdentityResponse struct {
+	ID          int32  `json:"id"`
+	Issuer      string `json:"issuer"`
+	Email       string `json:"email"`
+	CreatedAt   string `json:"createdAt"`
+	LastLoginAt string `json:"lastLoginAt"`
+}
+
+// --- Two-factor types ---
+
+// TwoFactorChallenge is returned by login instead of tokens when a second
+// factor is needed. MFAToken authorizes the /login/2fa endpoints.
+type TwoFactorChallenge struct {
+	TwoFactorRequired  bool   `json:"twoFactorRequired"`
+	EnrollmentRequired bool   `json:"enrollmentRequired"`
+	MFAToken           string `json:"mfaToken"`
+}
+
+type TwoFactorCodeRequest struct {
+	MFAToken string `json:"mfaToken,omitempty"`
+	Code     string `json:"code"`
+	Password string `json:"password,omitempty"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled           bool  `json:"enabled"`
+	Required          bool  `json:"required"`
+	RecoveryCodesLeft int64 `json:"recoveryCodesLeft"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// TwoFactorLoginResult carries tokens and, right after enrolment, the new recovery codes.
+type TwoFactorLoginResult struct {
+	TokenResult
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+}
+
+// --- Auth session types ---
+
+type AuthSessionResponse struct {
+	Uuid       string `json:"uuid"`
+	UserAgent  string `json:"userAgent"`
+	IPAddress  string `json:"ipAddress"`
+	Current    bool   `json:"current"`
+	CreatedAt  string `json:"createdAt"`
+	LastUsedAt string `json:"lastUsedAt"`
+	ExpiresAt  string `json:"expiresAt"`
+}
+
+type RevokedSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+// --- Login guard types ---
+
+type LoginLockoutResponse struct {
+	Key         string `json:"key"`
+	Failures    int32  `json:"failures"`
+	LockedUntil string `json:"lockedUntil"`
+}
+
+type UnlockLoginRequest struct {
+	Email string `json:"email"`
+	IP    string `json:"ip"`
+}
+
+type UnlockLoginResponse struct {
+	Removed int64 `json:"removed"`
+}
+
+// --- Credential types ---
+
+// CredentialResponse describes a stored provider key; the secret itself is never returned.
+type CredentialResponse struct {
+	ID        int32  `json:"id"`
+	Name      string `json:"name"`
+	Hint      string `json:"hint"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+	RotatedAt string `json:"rotatedAt,omitempty"`
+}
+
+// CredentialRequest creates a credential, or updates one: an empty secret
+// keeps the current secret.
+type CredentialRequest struct {
+	Name   string `json:"name"`
+	Secret string `json:"secret"`
+}
+
+// ModelCredentialRequest attaches a credential to a chat model; null detaches it.
+type ModelCredentialRequest struct {
+	CredentialID *int32 `json:"credentialId"`
+}
+
+type RewrapCredentialsResponse struct {
+	Rewrapped int `json:"rewrapped"`
+}
+
+// WorkspaceCredentialResponse is a workspace's own key for a model.
+type WorkspaceCredentialResponse struct {
+	Model     string `json:"model"`
+	Hint      string `json:"hint"`
+	CreatedAt string `json:"createdAt"`
+	RotatedAt string `json:"rotatedAt,omitempty"`
+}
+
+type WorkspaceCredentialRequest struct {
+	Secret string `json:"secret"`
+}
+
+// --- Quota types ---
+
+// QuotaPolicyRequest creates or replaces a quota policy. Empty targets match
+// every user, workspace or model; enabled defaults to true.
+type QuotaPolicyRequest struct {
+	Name          string `json:"name"`
+	UserEmail     string `json:"userEmail"`
+	WorkspaceUuid string `json:"workspaceUuid"`
+	ChatModelName string `json:"chatModelName"`
+	// Window is minute, hour, day or month.
+	Window string `json:"window"`
+	// Metric is requests or tokens.
+	Metric  string `json:"metric"`
+	Limit   int64  `json:"limit"`
+	Enabled *bool  `json:"enabled"`
+}
+
+type QuotaPolicyResponse struct {
+	ID            int32  `json:"id"`
+	Name          string `json:"name"`
+	UserEmail     string `json:"userEmail,omitempty"`
+	WorkspaceUuid string `json:"workspaceUuid,omitempty"`
+	ChatModelName string `json:"chatModelName,omitempty"`
+	Window        string `json:"window"`
+	Metric        string `json:"metric"`
+	Limit         int64  `json:"limit"`
+	Enabled       bool   `json:"enabled"`
+	UpdatedAt     string `json:"updatedAt"`
+}
+
+// --- Audit types ---
+
+// AuditEventResponse is one entry of the audit trail. Changes maps each changed
+// field to {"before": ..., "after": ...}.
+type AuditEventResponse struct {
+	ID          int64           `json:"id"`
+	ActorUserID *int32          `json:"actorUserId,omitempty"`
+	ActorEmail  string          `json:"actorEmail"`
+	Action      string          `json:"action"`
+	TargetType  string          `json:"targetType"`
+	TargetID    string          `json:"targetId"`
+	Changes     json.RawMessage `json:"changes"`
+	IPAddress   string          `json:"ipAddress"`
+	RequestID   string          `json:"requestId"`
+	CreatedAt   string          `json:"createdAt"`
+}
+
 // --- Chat instruction response ---
 
 type ChatInstructionResponse struct {
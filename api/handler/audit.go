@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+// auditExportBatch is how many events the CSV export reads per query.
+const auditExportBatch = 500
+
+// AuditHandler lets admins search and export the audit trail.
+type AuditHandler struct {
+	service *svc.AuditService
+}
+
+func NewAuditHandler(service *svc.AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+func (h *AuditHandler) Register(router *mux.Router) {
+	router.HandleFunc("/audit_events", h.ListAuditEvents).Methods(http.MethodGet)
+	router.HandleFunc("/audit_events.csv", h.ExportAuditEvents).Methods(http.MethodGet)
+}
+
+// ListAuditEvents returns a page of audit events, newest first.
+// Query params: page, size, actor, action, target_type, target_id, since, until.
+func (h *AuditHandler) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := parseAuditFilter(query)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput(err.Error()))
+		return
+	}
+
+	page := int32(1)
+	size := int32(20)
+	if p, err := strconv.Atoi(query.Get("page")); err == nil && p > 0 {
+		page = int32(p)
+	}
+	if s, err := strconv.Atoi(query.Get("size")); err == nil && s > 0 && s <= 100 {
+		size = int32(s)
+	}
+
+	pagination := dto.Pagination{Page: page, Size: size}
+	events, total, err := h.service.List(r.Context(), filter, size, pagination.Offset())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to list audit events"))
+		return
+	}
+
+	data := make([]interface{}, len(events))
+	for i, e := range events {
+		data[i] = auditEventToResponse(e)
+	}
+	pagination.Data = data
+	pagination.Total = total
+
+	json.NewEncoder(w).Encode(pagination)
+}
+
+// ExportAuditEvents streams every audit event matching the filters of
+// ListAuditEvents as CSV.
+func (h *AuditHandler) ExportAuditEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput(err.Error()))
+		return
+	}
+	// read the first batch before writing, so a failure can still be reported as JSON
+	events, _, err := h.service.List(r.Context(), filter, auditExportBatch, 0)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to export audit events"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit_events.csv"`)
+	out := csv.NewWriter(w)
+	out.Write([]string{"id", "created_at", "actor_user_id", "actor_email", "action", "target_type", "target_id", "ip_address", "request_id", "changes"})
+	for len(events) > 0 {
+		for _, e := range events {
+			actorID := ""
+			if e.ActorUserID.Valid {
+				actorID = strconv.Itoa(int(e.ActorUserID.Int32))
+			}
+			out.Write([]string{
+				strconv.FormatInt(e.ID, 10),
+				e.CreatedAt.Format("2006-01-02T15:04:05Z"),
+				actorID,
+				csvCell(e.ActorEmail),
+				e.Action,
+				e.TargetType,
+				csvCell(e.TargetID),
+				e.IpAddress,
+				csvCell(e.RequestID),
+				string(e.Changes),
+			})
+		}
+		out.Flush()
+		if len(events) < auditExportBatch {
+			break
+		}
+		filter.BeforeID = events[len(events)-1].ID
+		// the response has started; an error can only end the file early
+		if events, _, err = h.service.List(r.Context(), filter, auditExportBatch, 0); err != nil {
+			break
+		}
+	}
+	out.Flush()
+}
+
+// parseAuditFilter reads the filters shared by the list and the export. Times
+// are RFC 3339 or dates; until is exclusive.
+func parseAuditFilter(query url.Values) (svc.AuditFilter, error) {
+	filter := svc.AuditFilter{
+		ActorEmail: strings.TrimSpace(query.Get("actor")),
+		Action:     strings.TrimSpace(query.Get("action")),
+		TargetType: strings.TrimSpace(query.Get("target_type")),
+		TargetID:   strings.TrimSpace(query.Get("target_id")),
+	}
+	var err error
+	if filter.Since, err = parseAuditTime(query.Get("since")); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = parseAuditTime(query.Get("until")); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func parseAuditTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q, use RFC 3339 or YYYY-MM-DD", v)
+	}
+	return t, nil
+}
+
+// csvCell keeps user-controlled text from being read as a formula by spreadsheets.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func auditEventToResponse(e sqlc_queries.AuditEvent) dto.AuditEventResponse {
+	resp := dto.AuditEventResponse{
+		ID: e.ID, ActorEmail: e.ActorEmail, Action: e.Action,
+		TargetType: e.TargetType, TargetID: e.TargetID, Changes: e.Changes,
+		IPAddress: e.IpAddress, RequestID: e.RequestID,
+		CreatedAt: e.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if e.ActorUserID.Valid {
+		resp.ActorUserID = &e.ActorUserID.Int32
+	}
+	return resp
+}
@@ -1,12 +1,16 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
-	"github.com/swuecho/chat_backend/auth"
 	"github.com/swuecho/chat_backend/dto"
-	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/middleware"
+	"github.com/swuecho/chat_backend/svc"
 	"log/slog"
 )
 
@@ -16,14 +20,30 @@ type ResetPasswordRequest struct {
 	Email string `json:"email"`
 }
 
-type ChangePasswordRequest struct {
-	Email       string `json:"email"`
+type ConfirmPasswordResetRequest struct {
+	Token       string `json:"token"`
 	NewPassword string `json:"new_password"`
 }
 
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+const minPasswordLength = svc.MinPasswordLength
+
+// passwordResetMailTimeout bounds sending a password reset email.
+const passwordResetMailTimeout = 30 * time.Second
+
 // --- Handlers ---
 
-// ResetPasswordHandler generates a temporary password and sends it via email.
+// ResetPasswordHandler emails a password reset link. It responds the same way
+// whether or not the address belongs to an account, and is limited per
+// address and per email.
 func (h *AuthUserHandler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
 	var req ResetPasswordRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -31,80 +51,112 @@ func (h *AuthUserHandler) ResetPasswordHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	user, err := h.service.GetUserByEmail(r.Context(), req.Email)
+	ip := middleware.ClientIP(r)
+	wait, err := h.guard.CheckPasswordReset(r.Context(), req.Email, ip)
 	if err != nil {
-		dto.RespondWithAPIError(w, dto.ErrResourceNotFound("user"))
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to check password reset requests"))
 		return
 	}
-
-	tempPassword, err := auth.GenerateRandomPassword()
-	if err != nil {
-		slog.Error("Failed to generate temporary password", "error", err)
-		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithMessage("Failed to generate temporary password").WithDebugInfo(err.Error()))
+	if wait > 0 {
+		slog.Warn("Password reset throttled", "ip", ip, "action", "password_reset_throttled")
+		respondLocked(w, wait)
 		return
 	}
 
-	hashedPassword, err := auth.GeneratePasswordHash(tempPassword)
+	user, err := h.service.GetUserByEmail(r.Context(), strings.TrimSpace(req.Email))
 	if err != nil {
-		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithMessage("Failed to hash password").WithDebugInfo(err.Error()))
+		slog.Info("Password reset requested for unknown email", "ip", ip, "action", "password_reset_unknown")
+		w.WriteHeader(http.StatusOK)
 		return
 	}
 
-	if err := h.service.UpdateUserPassword(r.Context(), sqlc_queries.UpdateUserPasswordParams{
-		Email: req.Email, Password: hashedPassword,
-	}); err != nil {
-		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithMessage("Failed to update password").WithDebugInfo(err.Error()))
+	// Sent in the background so that the response takes as long as for an
+	// unknown address.
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), passwordResetMailTimeout)
+		defer cancel()
+		if err := h.mail.SendPasswordReset(ctx, user); err != nil {
+			slog.Error("Failed to send password reset email", "user_id", user.ID, "error", err)
+		}
+	}()
+	w.WriteHeader(http.StatusOK)
+}
+
+// ConfirmPasswordResetHandler sets a new password from a reset or invite link.
+func (h *AuthUserHandler) ConfirmPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	var req ConfirmPasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Failed to decode request body").WithDebugInfo(err.Error()))
 		return
 	}
-
-	if err := sendPasswordResetEmail(user.Email, tempPassword); err != nil {
-		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithMessage("Failed to send password reset email").WithDebugInfo(err.Error()))
+	if len(req.NewPassword) < minPasswordLength {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput(fmt.Sprintf("password must be at least %d characters", minPasswordLength)))
 		return
 	}
 
+	if err := h.mail.ResetPassword(r.Context(), req.Token, req.NewPassword); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to reset password"))
+		return
+	}
 	w.WriteHeader(http.StatusOK)
 }
 
-// sendPasswordResetEmail sends a password reset email. Currently a no-op.
-func sendPasswordResetEmail(email, tempPassword string) error {
-	return nil
-}
-
-// ChangePasswordHandler updates the user's password.
-func (h *AuthUserHandler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
-	var req ChangePasswordRequest
+// VerifyEmailHandler confirms an email address from a verification link.
+func (h *AuthUserHandler) VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Failed to decode request body").WithDebugInfo(err.Error()))
 		return
 	}
 
-	// Verify the authenticated user owns the email being changed
+	if err := h.mail.VerifyEmail(r.Context(), req.Token); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to verify email"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// ResendVerificationHandler sends a new verification link to the current user.
+func (h *AuthUserHandler) ResendVerificationHandler(w http.ResponseWriter, r *http.Request) {
 	userID, err := getUserID(r.Context())
 	if err != nil {
 		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
 		return
 	}
-
 	user, err := h.service.GetAuthUserByID(r.Context(), userID)
 	if err != nil {
 		dto.RespondWithAPIError(w, dto.ErrResourceNotFound("user").WithDebugInfo(err.Error()))
 		return
 	}
-	if user.Email != req.Email {
-		dto.RespondWithAPIError(w, dto.ErrAuthAccessDenied.WithMessage("Cannot change password for another user"))
+	if user.EmailVerified {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("email address is already verified"))
 		return
 	}
 
-	hashedPassword, err := auth.GeneratePasswordHash(req.NewPassword)
+	if err := h.mail.SendVerification(r.Context(), user); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrExternalUnavailable.WithMessage("Failed to send verification email").WithDebugInfo(err.Error()))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// ChangePasswordHandler changes the current user's password and signs out
+// their other sessions.
+func (h *AuthUserHandler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Failed to decode request body").WithDebugInfo(err.Error()))
+		return
+	}
+
+	userID, err := getUserID(r.Context())
 	if err != nil {
-		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithMessage("Failed to hash password").WithDebugInfo(err.Error()))
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
 		return
 	}
 
-	if err := h.service.UpdateUserPassword(r.Context(), sqlc_queries.UpdateUserPasswordParams{
-		Email: req.Email, Password: string(hashedPassword),
-	}); err != nil {
-		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to update password"))
+	if err := h.service.ChangePassword(r.Context(), userID, req.CurrentPassword, req.NewPassword, refreshSessionID(r, h.jwtSecret)); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to change password"))
 		return
 	}
 
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/swuecho/chat_backend/auth"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/middleware"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+// AuthSessionHandler lists and revokes the devices a user is signed in on.
+type AuthSessionHandler struct {
+	sessions  *svc.AuthSessionService
+	users     *svc.AuthUserService
+	jwtSecret string
+}
+
+func NewAuthSessionHandler(sessions *svc.AuthSessionService, users *svc.AuthUserService, jwtSecret string) *AuthSessionHandler {
+	return &AuthSessionHandler{sessions: sessions, users: users, jwtSecret: jwtSecret}
+}
+
+func (h *AuthSessionHandler) Register(router *mux.Router) {
+	router.HandleFunc("/auth/sessions", h.ListSessions).Methods(http.MethodGet)
+	router.HandleFunc("/auth/sessions", h.RevokeOtherSessions).Methods(http.MethodDelete)
+	router.HandleFunc("/auth/sessions/{uuid}", h.RevokeSession).Methods(http.MethodDelete)
+}
+
+func (h *AuthSessionHandler) RegisterAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/users/{email}/logout_everywhere", h.LogoutEverywhere).Methods(http.MethodPost)
+}
+
+// ListSessions returns the caller's active sessions, marking the one making the request.
+func (h *AuthSessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+	sessions, err := h.sessions.List(r.Context(), userID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to list sessions"))
+		return
+	}
+
+	current := h.currentSessionID(r)
+	responses := make([]dto.AuthSessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		responses = append(responses, dto.AuthSessionResponse{
+			Uuid: s.Uuid, UserAgent: s.UserAgent, IPAddress: s.IpAddress, Current: s.Uuid == current,
+			CreatedAt:  s.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			LastUsedAt: s.LastUsedAt.Format("2006-01-02T15:04:05Z"),
+			ExpiresAt:  s.ExpiresAt.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *AuthSessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+	if err := h.sessions.Revoke(r.Context(), userID, mux.Vars(r)["uuid"], svc.SessionRevokedUser); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to revoke session"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// RevokeOtherSessions signs the caller out of every device except this one.
+func (h *AuthSessionHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+	n, err := h.sessions.RevokeAll(r.Context(), userID, h.currentSessionID(r), svc.SessionRevokedUser)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to revoke sessions"))
+		return
+	}
+	json.NewEncoder(w).Encode(dto.RevokedSessionsResponse{Revoked: n})
+}
+
+// LogoutEverywhere revokes all sessions of a user. Access tokens already
+// issued keep working until they expire, at most AccessTokenLifetime later.
+func (h *AuthSessionHandler) LogoutEverywhere(w http.ResponseWriter, r *http.Request) {
+	user, err := h.users.GetUserByEmail(r.Context(), mux.Vars(r)["email"])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.RespondWithAPIError(w, dto.ErrResourceNotFound("user"))
+			return
+		}
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get user"))
+		return
+	}
+	n, err := h.sessions.RevokeAll(r.Context(), user.ID, "", svc.SessionRevokedAdmin)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to revoke sessions"))
+		return
+	}
+	slog.Info("User logged out everywhere by admin", "user_id", user.ID, "sessions", n, "action", "logout_everywhere")
+	json.NewEncoder(w).Encode(dto.RevokedSessionsResponse{Revoked: n})
+}
+
+// currentSessionID returns the session of the request's refresh token cookie, if any.
+func (h *AuthSessionHandler) currentSessionID(r *http.Request) string {
+	return refreshSessionID(r, h.jwtSecret)
+}
+
+// refreshSessionID returns the session of the request's refresh cookie, or ""
+// without a valid one.
+func refreshSessionID(r *http.Request, jwtSecret string) string {
+	c, err := r.Cookie(RefreshTokenName)
+	if err != nil {
+		return ""
+	}
+	result := middleware.ParseAndValidateJWT(c.Value, auth.TokenTypeRefresh, jwtSecret)
+	if result.Error != nil {
+		return ""
+	}
+	sid, _ := result.Claims["sid"].(string)
+	return sid
+}
@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/oidc"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+// oidcStateCookie binds a pending login to the browser that started it, so a
+// callback URL forwarded to someone else cannot sign them in.
+const oidcStateCookie = "oidc_state"
+
+// SSOHandler signs users in through an OpenID Connect identity provider.
+type SSOHandler struct {
+	provider  *oidc.Provider
+	sso       *svc.SSOService
+	sessions  *svc.AuthSessionService
+	twoFactor *svc.TwoFactorService
+	jwtSecret string
+	audience  string
+}
+
+// NewSSOHandler creates a new SSOHandler. A nil provider disables single
+// sign-on. twoFactor holds sign-ins to the same second factor as Login.
+func NewSSOHandler(provider *oidc.Provider, sso *svc.SSOService, sessions *svc.AuthSessionService, twoFactor *svc.TwoFactorService, jwtSecret, audience string) *SSOHandler {
+	return &SSOHandler{provider: provider, sso: sso, sessions: sessions, twoFactor: twoFactor, jwtSecret: jwtSecret, audience: audience}
+}
+
+func (h *SSOHandler) Register(router *mux.Router) {
+	router.HandleFunc("/auth/oidc/link", h.StartLink).Methods(http.MethodPost)
+	router.HandleFunc("/auth/identities", h.ListIdentities).Methods(http.MethodGet)
+	router.HandleFunc("/auth/identities/{id}", h.UnlinkIdentity).Methods(http.MethodDelete)
+}
+
+func (h *SSOHandler) RegisterPublicRoutes(router *mux.Router) {
+	router.HandleFunc("/auth/oidc/config", h.Config).Methods(http.MethodGet)
+	router.HandleFunc("/auth/oidc/login", h.StartLogin).Methods(http.MethodGet)
+	router.HandleFunc("/auth/oidc/callback", h.Callback).Methods(http.MethodGet)
+}
+
+// Config tells the web app whether to show the single sign-on button.
+func (h *SSOHandler) Config(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(dto.SSOConfigResponse{Enabled: h.provider != nil})
+}
+
+// StartLogin redirects the browser to the identity provider.
+// Query params: redirect, a web app path to return to.
+func (h *SSOHandler) StartLogin(w http.ResponseWriter, r *http.Request) {
+	authURL, ok := h.begin(w, r, 0)
+	if !ok {
+		return
+	}
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// StartLink returns the identity provider URL that links an identity to the signed-in account.
+func (h *SSOHandler) StartLink(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+	authURL, ok := h.begin(w, r, userID)
+	if !ok {
+		return
+	}
+	json.NewEncoder(w).Encode(dto.SSOLinkResponse{URL: authURL})
+}
+
+func (h *SSOHandler) begin(w http.ResponseWriter, r *http.Request, linkUserID int32) (string, bool) {
+	if h.provider == nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthSSOFailed.WithDetail("single sign-on is not configured"))
+		return "", false
+	}
+	ctx := r.Context()
+	login, err := h.sso.StartLogin(ctx, safeRedirectPath(r.URL.Query().Get("redirect")), linkUserID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to start single sign-on"))
+		return "", false
+	}
+	authURL, err := h.provider.AuthCodeURL(ctx, login.State, login.Nonce, oidc.CodeChallenge(login.CodeVerifier))
+	if err != nil {
+		slog.Error("OIDC discovery failed", "issuer", h.provider.Issuer(), "error", err)
+		dto.RespondWithAPIError(w, dto.ErrExternalUnavailable.WithMessage("Identity provider is unavailable").WithDebugInfo(err.Error()))
+		return "", false
+	}
+
+	// Lax so the cookie comes back on the identity provider's top-level redirect.
+	http.SetCookie(w, &http.Cookie{
+		Name: oidcStateCookie, Value: login.State, Path: "/api/auth/oidc/callback",
+		MaxAge: 600, HttpOnly: true, Secure: isHTTPS(r), SameSite: http.SameSiteLaxMode,
+	})
+	return authURL, true
+}
+
+// Callback completes the login, sets the same refresh cookie as Login and
+// sends the browser back to the web app, which exchanges it for an access token.
+func (h *SSOHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	if h.provider == nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthSSOFailed.WithDetail("single sign-on is not configured"))
+		return
+	}
+	ctx := r.Context()
+	query := r.URL.Query()
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/api/auth/oidc/callback", MaxAge: -1})
+
+	if e := query.Get("error"); e != "" {
+		slog.Warn("SSO login refused by identity provider", "error", e, "description", query.Get("error_description"), "action", "sso_failed")
+		redirectSSOError(w, r, "/", "sign-in was cancelled or refused")
+		return
+	}
+
+	state := query.Get("state")
+	if c, err := r.Cookie(oidcStateCookie); err != nil || state == "" || c.Value != state {
+		slog.Warn("SSO callback without matching state cookie", "ip", r.RemoteAddr, "action", "sso_state_mismatch")
+		redirectSSOError(w, r, "/", "login request expired, please try again")
+		return
+	}
+	login, err := h.sso.ConsumeLogin(ctx, state)
+	if err != nil {
+		redirectSSOError(w, r, "/", ssoErrorMessage(err))
+		return
+	}
+
+	claims, err := h.provider.Exchange(ctx, query.Get("code"), login.CodeVerifier, login.Nonce)
+	if err != nil {
+		slog.Warn("SSO code exchange failed", "issuer", h.provider.Issuer(), "error", err, "action", "sso_failed")
+		redirectSSOError(w, r, login.RedirectTo, "could not verify your identity")
+		return
+	}
+
+	user, err := h.sso.Resolve(ctx, h.provider.Issuer(), claims, login.LinkUserID.Int32)
+	if err != nil {
+		slog.Warn("SSO login rejected", "issuer", h.provider.Issuer(), "subject", claims.Subject, "error", err, "action", "sso_failed")
+		redirectSSOError(w, r, login.RedirectTo, ssoErrorMessage(err))
+		return
+	}
+
+	h.signIn(w, r, user, login.RedirectTo)
+}
+
+// signIn finishes a verified sign-on the way Login does. Users with
+// two-factor authentication enabled or required get no session: the web app
+// receives an MFA token in mfa_token, with enrollment_required when they must
+// enrol first, and completes the login at /login/2fa.
+func (h *SSOHandler) signIn(w http.ResponseWriter, r *http.Request, user sqlc_queries.AuthUser, redirectTo string) {
+	challenge, err := twoFactorChallenge(r.Context(), h.twoFactor, user, h.jwtSecret, h.audience)
+	if err != nil {
+		slog.Error("Failed to check two-factor authentication", "user_id", user.ID, "error", err)
+		redirectSSOError(w, r, redirectTo, "failed to sign in")
+		return
+	}
+	if challenge != nil {
+		slog.Info("SSO login awaiting second factor", "user_id", user.ID, "enrollment", challenge.EnrollmentRequired, "action", "sso_2fa_challenge")
+		params := url.Values{"mfa_token": {challenge.MFAToken}}
+		if challenge.EnrollmentRequired {
+			params.Set("enrollment_required", "true")
+		}
+		redirectSSO(w, r, redirectTo, params)
+		return
+	}
+
+	if _, err := issueTokens(w, r, h.sessions, user, h.jwtSecret, h.audience); err != nil {
+		redirectSSOError(w, r, redirectTo, "failed to sign in")
+		return
+	}
+	slog.Info("SSO login successful", "user_id", user.ID, "email", user.Email, "action", "sso_login_success")
+	http.Redirect(w, r, "/#"+safeRedirectPath(redirectTo), http.StatusFound)
+}
+
+func (h *SSOHandler) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+	identities, err := h.sso.ListIdentities(r.Context(), userID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to list identities"))
+		return
+	}
+	responses := make([]dto.UserIdentityResponse, 0, len(identities))
+	for _, i := range identities {
+		responses = append(responses, dto.UserIdentityResponse{
+			ID: i.ID, Issuer: i.Issuer, Email: i.Email,
+			CreatedAt:   i.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			LastLoginAt: i.LastLoginAt.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *SSOHandler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("invalid identity id"))
+		return
+	}
+	if err := h.sso.Unlink(r.Context(), userID, int32(id)); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to unlink identity"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// --- Helpers ---
+
+// safeRedirectPath keeps redirects inside the web app.
+func safeRedirectPath(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.ContainsAny(p, "\\\r\n") {
+		return "/"
+	}
+	return p
+}
+
+func redirectSSOError(w http.ResponseWriter, r *http.Request, path, msg string) {
+	redirectSSO(w, r, path, url.Values{"sso_error": {msg}})
+}
+
+// redirectSSO sends the browser to the web app path with params added to its query.
+func redirectSSO(w http.ResponseWriter, r *http.Request, path string, params url.Values) {
+	path = safeRedirectPath(path)
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	http.Redirect(w, r, "/#"+path+sep+params.Encode(), http.StatusFound)
+}
+
+// ssoErrorMessage returns the user-facing detail of a sign-on failure.
+func ssoErrorMessage(err error) string {
+	var apiErr dto.APIError
+	if errors.As(err, &apiErr) && apiErr.Code == dto.ErrAuthSSOFailed.Code && apiErr.Detail != "" {
+		return apiErr.Detail
+	}
+	return "failed to sign in"
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+func TestSSOSignInRequiresSecondFactor(t *testing.T) {
+	ctx := context.Background()
+	q := sqlc_queries.New(testDB)
+	twoFactor := svc.NewTwoFactorService(q, "")
+	h := NewSSOHandler(nil, nil, svc.NewAuthSessionService(q), twoFactor, "secret", "audience")
+
+	if err := twoFactor.SetPolicy(ctx, svc.TwoFactorPolicy{RequireForStaff: true}); err != nil {
+		t.Fatal(err)
+	}
+	defer twoFactor.SetPolicy(ctx, svc.TwoFactorPolicy{})
+
+	staff, err := q.CreateAuthUser(ctx, sqlc_queries.CreateAuthUserParams{
+		Email: "sso-staff@example.com", Username: "sso-staff@example.com", Password: "x", IsStaff: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	member, err := q.CreateAuthUser(ctx, sqlc_queries.CreateAuthUserParams{
+		Email: "sso-member@example.com", Username: "sso-member@example.com", Password: "x",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signIn := func(user sqlc_queries.AuthUser) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.signIn(w, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback", nil), user, "/chat")
+		if w.Code != http.StatusFound {
+			t.Fatalf("expected redirect, got %d", w.Code)
+		}
+		return w
+	}
+
+	w := signIn(staff)
+	location := w.Header().Get("Location")
+	_, query, _ := strings.Cut(location, "?")
+	params, _ := url.ParseQuery(query)
+	if params.Get("mfa_token") == "" || params.Get("enrollment_required") != "true" {
+		t.Errorf("staff sign-on not challenged: %s", location)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == RefreshTokenName {
+			t.Error("refresh cookie issued before the second factor")
+		}
+	}
+
+	w = signIn(member)
+	if strings.Contains(w.Header().Get("Location"), "mfa_token") {
+		t.Errorf("member sign-on challenged: %s", w.Header().Get("Location"))
+	}
+	issued := false
+	for _, c := range w.Result().Cookies() {
+		issued = issued || c.Name == RefreshTokenName
+	}
+	if !issued {
+		t.Error("member sign-on issued no refresh cookie")
+	}
+}
@@ -0,0 +1,358 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/swuecho/chat_backend/auth"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/middleware"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+// TwoFactorHandler handles TOTP enrolment, the second login step and the admin policy.
+type TwoFactorHandler struct {
+	service   *svc.TwoFactorService
+	users     *svc.AuthUserService
+	sessions  *svc.AuthSessionService
+	guard     *svc.LoginGuardService
+	jwtSecret string
+	audience  string
+}
+
+func NewTwoFactorHandler(q *sqlc_queries.Queries, users *svc.AuthUserService, jwtSecret, audience string) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		service:   svc.NewTwoFactorService(q, ""),
+		users:     users,
+		sessions:  svc.NewAuthSessionService(q),
+		guard:     svc.NewLoginGuardService(q),
+		jwtSecret: jwtSecret,
+		audience:  audience,
+	}
+}
+
+func (h *TwoFactorHandler) Register(router *mux.Router) {
+	router.HandleFunc("/auth/2fa", h.Status).Methods(http.MethodGet)
+	router.HandleFunc("/auth/2fa/setup", h.Setup).Methods(http.MethodPost)
+	router.HandleFunc("/auth/2fa/confirm", h.Confirm).Methods(http.MethodPost)
+	router.HandleFunc("/auth/2fa/recovery_codes", h.RegenerateRecoveryCodes).Methods(http.MethodPost)
+	router.HandleFunc("/auth/2fa/disable", h.Disable).Methods(http.MethodPost)
+}
+
+// RegisterPublicRoutes registers the second login step, authorized by the MFA token from Login.
+func (h *TwoFactorHandler) RegisterPublicRoutes(router *mux.Router) {
+	router.HandleFunc("/login/2fa", h.LoginVerify).Methods(http.MethodPost)
+	router.HandleFunc("/login/2fa/setup", h.LoginSetup).Methods(http.MethodPost)
+	router.HandleFunc("/login/2fa/confirm", h.LoginConfirm).Methods(http.MethodPost)
+}
+
+func (h *TwoFactorHandler) RegisterAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/2fa_policy", h.GetPolicy).Methods(http.MethodGet)
+	router.HandleFunc("/2fa_policy", h.UpdatePolicy).Methods(http.MethodPut)
+	router.HandleFunc("/users/{email}/2fa", h.ResetUser).Methods(http.MethodDelete)
+}
+
+// --- Signed-in user ---
+
+func (h *TwoFactorHandler) Status(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	st, err := h.service.Status(r.Context(), user)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get two-factor status"))
+		return
+	}
+	json.NewEncoder(w).Encode(dto.TwoFactorStatusResponse{
+		Enabled: st.Enabled, Required: st.Required, RecoveryCodesLeft: st.RecoveryCodesLeft,
+	})
+}
+
+func (h *TwoFactorHandler) Setup(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	h.setup(w, r, user)
+}
+
+func (h *TwoFactorHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTwoFactorRequest(w, r)
+	if !ok {
+		return
+	}
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if !h.checkLoginGuard(w, r, user) {
+		return
+	}
+	codes, err := h.service.ConfirmEnrollment(r.Context(), user.ID, req.Code)
+	if err != nil {
+		h.recordCodeFailure(r, user)
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to enable two-factor authentication"))
+		return
+	}
+	h.recordCodeSuccess(r, user)
+	slog.Info("Two-factor authentication enabled", "user_id", user.ID, "action", "2fa_enabled")
+	json.NewEncoder(w).Encode(dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes; a current code is required.
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTwoFactorRequest(w, r)
+	if !ok {
+		return
+	}
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.Verify(r.Context(), user.ID, req.Code); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, ""))
+		return
+	}
+	codes, err := h.service.RegenerateRecoveryCodes(r.Context(), user.ID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to regenerate recovery codes"))
+		return
+	}
+	json.NewEncoder(w).Encode(dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable turns two-factor authentication off; it needs the password and a current code.
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTwoFactorRequest(w, r)
+	if !ok {
+		return
+	}
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	ctx := r.Context()
+	if !auth.ValidatePassword(req.Password, user.Password) {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidEmailOrPassword)
+		return
+	}
+	required, err := h.service.Required(ctx, user)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to check two-factor policy"))
+		return
+	}
+	if required {
+		dto.RespondWithAPIError(w, dto.ErrAuthAccessDenied.WithDetail("two-factor authentication is required for your account"))
+		return
+	}
+	if err := h.service.Verify(ctx, user.ID, req.Code); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, ""))
+		return
+	}
+	if err := h.service.Disable(ctx, user.ID); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to disable two-factor authentication"))
+		return
+	}
+	slog.Info("Two-factor authentication disabled", "user_id", user.ID, "action", "2fa_disabled")
+	w.WriteHeader(http.StatusOK)
+}
+
+// --- Second login step ---
+
+// LoginVerify exchanges an MFA token and a TOTP or recovery code for the tokens Login would issue.
+func (h *TwoFactorHandler) LoginVerify(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTwoFactorRequest(w, r)
+	if !ok {
+		return
+	}
+	user, ok := h.mfaUser(w, r, req.MFAToken)
+	if !ok {
+		return
+	}
+	if !h.checkLoginGuard(w, r, user) {
+		return
+	}
+	if err := h.service.Verify(r.Context(), user.ID, req.Code); err != nil {
+		h.recordCodeFailure(r, user)
+		dto.RespondWithAPIError(w, dto.WrapError(err, ""))
+		return
+	}
+	h.recordCodeSuccess(r, user)
+	h.respondWithTokens(w, r, user, nil)
+}
+
+// LoginSetup starts enrolment for a user whom the policy requires to use two-factor authentication.
+func (h *TwoFactorHandler) LoginSetup(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTwoFactorRequest(w, r)
+	if !ok {
+		return
+	}
+	user, ok := h.mfaUser(w, r, req.MFAToken)
+	if !ok {
+		return
+	}
+	h.setup(w, r, user)
+}
+
+// LoginConfirm completes enrolment started by LoginSetup and signs the user in.
+func (h *TwoFactorHandler) LoginConfirm(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTwoFactorRequest(w, r)
+	if !ok {
+		return
+	}
+	user, ok := h.mfaUser(w, r, req.MFAToken)
+	if !ok {
+		return
+	}
+	if !h.checkLoginGuard(w, r, user) {
+		return
+	}
+	codes, err := h.service.ConfirmEnrollment(r.Context(), user.ID, req.Code)
+	if err != nil {
+		h.recordCodeFailure(r, user)
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to enable two-factor authentication"))
+		return
+	}
+	h.recordCodeSuccess(r, user)
+	slog.Info("Two-factor authentication enabled", "user_id", user.ID, "action", "2fa_enabled")
+	h.respondWithTokens(w, r, user, codes)
+}
+
+// --- Admin ---
+
+func (h *TwoFactorHandler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	p, err := h.service.Policy(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get two-factor policy"))
+		return
+	}
+	json.NewEncoder(w).Encode(p)
+}
+
+func (h *TwoFactorHandler) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	var p svc.TwoFactorPolicy
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+	if err := h.service.SetPolicy(r.Context(), p); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to update two-factor policy"))
+		return
+	}
+	json.NewEncoder(w).Encode(p)
+}
+
+// ResetUser removes a user's second factor, e.g. after they lost their device.
+func (h *TwoFactorHandler) ResetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.users.GetUserByEmail(r.Context(), mux.Vars(r)["email"])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.RespondWithAPIError(w, dto.ErrResourceNotFound("user"))
+			return
+		}
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get user"))
+		return
+	}
+	if err := h.service.Reset(r.Context(), user); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to reset two-factor authentication"))
+		return
+	}
+	slog.Info("Two-factor authentication reset by admin", "user_id", user.ID, "action", "2fa_reset")
+	w.WriteHeader(http.StatusOK)
+}
+
+// --- Helpers ---
+
+// checkLoginGuard refuses a second login step while the account or the
+// client's address is locked out after failed attempts.
+func (h *TwoFactorHandler) checkLoginGuard(w http.ResponseWriter, r *http.Request, user sqlc_queries.AuthUser) bool {
+	wait, err := h.guard.CheckLogin(r.Context(), user.Email, middleware.ClientIP(r))
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to check login attempts"))
+		return false
+	}
+	if wait > 0 {
+		respondLocked(w, wait)
+		return false
+	}
+	return true
+}
+
+// recordCodeFailure counts a rejected code towards the login lockout.
+func (h *TwoFactorHandler) recordCodeFailure(r *http.Request, user sqlc_queries.AuthUser) {
+	ip := middleware.ClientIP(r)
+	slog.Warn("Second factor rejected", "user_id", user.ID, "ip", ip, "action", "login_2fa_failed")
+	if _, err := h.guard.RecordLoginFailure(r.Context(), user.Email, ip, r.UserAgent(), svc.LoginFailedTwoFactor); err != nil {
+		slog.Error("Failed to record login attempt", "error", err)
+	}
+}
+
+func (h *TwoFactorHandler) recordCodeSuccess(r *http.Request, user sqlc_queries.AuthUser) {
+	if err := h.guard.RecordLoginSuccess(r.Context(), user.Email); err != nil {
+		slog.Error("Failed to reset login failures", "user_id", user.ID, "error", err)
+	}
+}
+
+func (h *TwoFactorHandler) setup(w http.ResponseWriter, r *http.Request, user sqlc_queries.AuthUser) {
+	secret, uri, err := h.service.BeginEnrollment(r.Context(), user)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to start two-factor enrolment"))
+		return
+	}
+	json.NewEncoder(w).Encode(dto.TwoFactorSetupResponse{Secret: secret, URI: uri})
+}
+
+func (h *TwoFactorHandler) respondWithTokens(w http.ResponseWriter, r *http.Request, user sqlc_queries.AuthUser, recoveryCodes []string) {
+	tokens, err := issueTokens(w, r, h.sessions, user, h.jwtSecret, h.audience)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, ""))
+		return
+	}
+	slog.Info("User login successful", "user_id", user.ID, "email", user.Email, "action", "login_success")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.TwoFactorLoginResult{TokenResult: tokens, RecoveryCodes: recoveryCodes})
+}
+
+func (h *TwoFactorHandler) currentUser(w http.ResponseWriter, r *http.Request) (sqlc_queries.AuthUser, bool) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return sqlc_queries.AuthUser{}, false
+	}
+	user, err := h.users.GetAuthUserByID(r.Context(), userID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrResourceNotFound("user").WithDebugInfo(err.Error()))
+		return user, false
+	}
+	return user, true
+}
+
+// mfaUser loads the user an MFA token from Login was issued to.
+func (h *TwoFactorHandler) mfaUser(w http.ResponseWriter, r *http.Request, token string) (sqlc_queries.AuthUser, bool) {
+	userID, err := auth.ValidateToken(token, h.jwtSecret, auth.TokenTypeMFA)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithMessage("Login expired, please sign in again"))
+		return sqlc_queries.AuthUser{}, false
+	}
+	user, err := h.users.GetAuthUserByID(r.Context(), userID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return user, false
+	}
+	return user, true
+}
+
+func decodeTwoFactorRequest(w http.ResponseWriter, r *http.Request) (dto.TwoFactorCodeRequest, bool) {
+	var req dto.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return req, false
+	}
+	return req, true
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/swuecho/chat_backend/auth"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+func TestLoginConfirmLocksAfterFailedCodes(t *testing.T) {
+	ctx := context.Background()
+	q := sqlc_queries.New(testDB)
+	h := NewTwoFactorHandler(q, svc.NewAuthUserService(q, "secret", 100), "secret", "audience")
+
+	user, err := q.CreateAuthUser(ctx, sqlc_queries.CreateAuthUserParams{
+		Email: "2fa-confirm@example.com", Username: "2fa-confirm@example.com", Password: "x",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := h.service.BeginEnrollment(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	token, err := auth.GenerateToken(user.ID, user.Role(), "secret", "audience", MFATokenLifetime, auth.TokenTypeMFA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	confirm := func() int {
+		body := `{"mfaToken":"` + token + `","code":"000000"}`
+		r := httptest.NewRequest(http.MethodPost, "/api/login/2fa/confirm", strings.NewReader(body))
+		r.RemoteAddr = "198.51.100.7:40000"
+		w := httptest.NewRecorder()
+		h.LoginConfirm(w, r)
+		return w.Code
+	}
+	for i := 0; i < svc.AccountLoginThreshold; i++ {
+		if code := confirm(); code == http.StatusTooManyRequests {
+			t.Fatalf("locked after %d attempts", i)
+		}
+	}
+	if code := confirm(); code != http.StatusTooManyRequests {
+		t.Errorf("expected lockout after %d wrong codes, got %d", svc.AccountLoginThreshold, code)
+	}
+}
@@ -1,15 +1,17 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/swuecho/chat_backend/auth"
+	"github.com/swuecho/chat_backend/config"
 	"github.com/swuecho/chat_backend/dto"
 	"github.com/swuecho/chat_backend/middleware"
 	"github.com/swuecho/chat_backend/sqlc_queries"
@@ -25,23 +27,35 @@ const (
 	RefreshTokenName            = "refresh_token"
 	MobileClientHeader          = "X-Chat-Client"
 	MobileClientValue           = "mobile"
+	MFATokenLifetime            = 5 * time.Minute
 )
 
 // AuthUserHandler handles authentication HTTP requests.
 type AuthUserHandler struct {
-	service          *svc.AuthUserService
-	jwtSecret        string
-	audience         string
-	defaultRateLimit int32
+	service             *svc.AuthUserService
+	mail                *svc.MailService
+	twoFactor           *svc.TwoFactorService
+	sessions            *svc.AuthSessionService
+	guard               *svc.LoginGuardService
+	jwtSecret           string
+	audience            string
+	defaultRateLimit    int32
+	requireVerification bool
 }
 
-// NewAuthUserHandler creates a new AuthUserHandler.
-func NewAuthUserHandler(sqlc_q *sqlc_queries.Queries, jwtSecret, audience string, defaultRateLimit int32) *AuthUserHandler {
+// NewAuthUserHandler creates a new AuthUserHandler. When requireVerification is
+// set, new accounts cannot log in until their email address is confirmed.
+func NewAuthUserHandler(sqlc_q *sqlc_queries.Queries, mail *svc.MailService, jwtSecret, audience string, defaultRateLimit int32, requireVerification bool) *AuthUserHandler {
 	return &AuthUserHandler{
-		service:          svc.NewAuthUserService(sqlc_q, jwtSecret, defaultRateLimit),
-		jwtSecret:        jwtSecret,
-		audience:         audience,
-		defaultRateLimit: defaultRateLimit,
+		service:             svc.NewAuthUserService(sqlc_q, jwtSecret, defaultRateLimit),
+		mail:                mail,
+		twoFactor:           svc.NewTwoFactorService(sqlc_q, ""),
+		sessions:            svc.NewAuthSessionService(sqlc_q),
+		guard:               svc.NewLoginGuardService(sqlc_q),
+		jwtSecret:           jwtSecret,
+		audience:            audience,
+		defaultRateLimit:    defaultRateLimit,
+		requireVerification: requireVerification,
 	}
 }
 
@@ -51,13 +65,7 @@ func isHTTPS(r *http.Request) bool {
 	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" || r.Header.Get("X-Forwarded-Ssl") == "on" {
 		return true
 	}
-	env := os.Getenv("ENV")
-	if env == "" {
-		env = os.Getenv("ENVIRONMENT")
-	}
-	if env == "" {
-		env = os.Getenv("NODE_ENV")
-	}
+	env := config.Current().SERVER.ENVIRONMENT
 	return env == "production" || env == "prod"
 }
 
@@ -94,12 +102,46 @@ func refreshTokenLifetimeForRequest(r *http.Request) time.Duration {
 	return DefaultRefreshTokenLifetime
 }
 
+// issueTokens starts a server-side session for user, sets its refresh token
+// cookie and returns a new access token. Errors are dto.APIError values.
+func issueTokens(w http.ResponseWriter, r *http.Request, sessions *svc.AuthSessionService, user sqlc_queries.AuthUser, jwtSecret, audience string) (dto.TokenResult, error) {
+	accessToken, err := auth.GenerateToken(user.ID, user.Role(), jwtSecret, audience, AccessTokenLifetime, auth.TokenTypeAccess)
+	if err != nil {
+		return dto.TokenResult{}, dto.ErrInternalUnexpected.WithMessage("Failed to generate access token").WithDebugInfo(err.Error())
+	}
+
+	refreshLifetime := refreshTokenLifetimeForRequest(r)
+	sessionID := auth.NewUUID()
+	refreshToken, err := auth.GenerateSessionToken(user.ID, user.Role(), jwtSecret, audience, refreshLifetime, sessionID)
+	if err != nil {
+		return dto.TokenResult{}, dto.ErrInternalUnexpected.WithMessage("Failed to generate refresh token").WithDebugInfo(err.Error())
+	}
+	if err := sessions.Create(r.Context(), sessionID, user.ID, refreshToken, sessionDevice(r), time.Now().Add(refreshLifetime)); err != nil {
+		return dto.TokenResult{}, dto.WrapError(dto.MapDatabaseError(err), "Failed to create session")
+	}
+
+	http.SetCookie(w, createSecureRefreshCookie(RefreshTokenName, refreshToken, int(refreshLifetime.Seconds()), r))
+	return dto.TokenResult{AccessToken: accessToken, ExpiresIn: int(time.Now().Add(AccessTokenLifetime).Unix())}, nil
+}
+
+func sessionDevice(r *http.Request) svc.SessionDevice {
+	return svc.SessionDevice{UserAgent: r.UserAgent(), IP: middleware.ClientIP(r)}
+}
+
+// respondLocked rejects a request from a locked account or address.
+func respondLocked(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())))
+	dto.RespondWithAPIError(w, dto.ErrTooManyRequests.WithMessage("Too many failed attempts").
+		WithDetail("try again in "+wait.Round(time.Second).String()))
+}
+
 // --- Route registration ---
 
 func (h *AuthUserHandler) Register(router *mux.Router) {
 	router.HandleFunc("/users", h.GetUserByID).Methods(http.MethodGet)
 	router.HandleFunc("/users/{id}", h.UpdateSelf).Methods(http.MethodPut)
 	router.HandleFunc("/token_10years", h.ForeverToken).Methods(http.MethodGet)
+	router.HandleFunc("/auth/verify_email/resend", h.ResendVerificationHandler).Methods(http.MethodPost)
 }
 
 func (h *AuthUserHandler) RegisterPublicRoutes(router *mux.Router) {
@@ -107,6 +149,9 @@ func (h *AuthUserHandler) RegisterPublicRoutes(router *mux.Router) {
 	router.HandleFunc("/login", h.Login).Methods(http.MethodPost)
 	router.HandleFunc("/auth/refresh", h.RefreshToken).Methods(http.MethodPost)
 	router.HandleFunc("/logout", h.Logout).Methods(http.MethodPost)
+	router.HandleFunc("/auth/password/reset", h.ResetPasswordHandler).Methods(http.MethodPost)
+	router.HandleFunc("/auth/password/reset/confirm", h.ConfirmPasswordResetHandler).Methods(http.MethodPost)
+	router.HandleFunc("/auth/verify_email", h.VerifyEmailHandler).Methods(http.MethodPost)
 }
 
 // --- CRUD handlers ---
@@ -189,6 +234,19 @@ func (h *AuthUserHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the first account can always be created, so a fresh install gets an admin
+	if !config.Current().FEATURE.SIGNUP {
+		hasUsers, err := h.service.HasUsers(r.Context())
+		if err != nil {
+			dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to check users"))
+			return
+		}
+		if hasUsers {
+			dto.RespondWithAPIError(w, dto.ErrAuthAccessDenied.WithDetail("Sign up is disabled"))
+			return
+		}
+	}
+
 	hash, err := auth.GeneratePasswordHash(params.Password)
 	if err != nil {
 		slog.Error("Failed to hash password", "email", params.Email, "error", err)
@@ -205,25 +263,31 @@ func (h *AuthUserHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, err := auth.GenerateToken(user.ID, user.Role(), h.jwtSecret, h.audience, AccessTokenLifetime, auth.TokenTypeAccess)
-	if err != nil {
-		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithMessage("Failed to generate token").WithDebugInfo(err.Error()))
-		return
+	// The first user is the superuser and must be able to log in before mail is configured.
+	if !user.IsSuperuser {
+		if err := h.mail.SetEmailVerified(r.Context(), user.ID, false); err != nil {
+			slog.Error("Failed to mark email unverified", "user_id", user.ID, "error", err)
+		}
+		if err := h.mail.SendVerification(r.Context(), user); err != nil {
+			slog.Error("Failed to send verification email", "user_id", user.ID, "error", err)
+		}
+		if h.requireVerification {
+			slog.Info("User signup pending verification", "user_id", user.ID, "action", "signup_verification_required")
+			dto.RespondWithJSON(w, http.StatusCreated, dto.SignUpResult{VerificationRequired: true})
+			return
+		}
 	}
 
-	refreshLifetime := refreshTokenLifetimeForRequest(r)
-	refreshToken, err := auth.GenerateToken(user.ID, user.Role(), h.jwtSecret, h.audience, refreshLifetime, auth.TokenTypeRefresh)
+	tokens, err := issueTokens(w, r, h.sessions, user, h.jwtSecret, h.audience)
 	if err != nil {
-		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithMessage("Failed to generate refresh token").WithDebugInfo(err.Error()))
+		dto.RespondWithAPIError(w, dto.WrapError(err, ""))
 		return
 	}
 
-	http.SetCookie(w, createSecureRefreshCookie(RefreshTokenName, refreshToken, int(refreshLifetime.Seconds()), r))
-
 	slog.Info("User signup successful", "user_id", user.ID, "email", user.Email, "action", "signup_success")
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(dto.TokenResult{AccessToken: accessToken, ExpiresIn: int(time.Now().Add(AccessTokenLifetime).Unix())})
+	json.NewEncoder(w).Encode(tokens)
 }
 
 func (h *AuthUserHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -234,34 +298,93 @@ func (h *AuthUserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.service.Authenticate(r.Context(), params.Email, params.Password)
+	ctx := r.Context()
+	ip := middleware.ClientIP(r)
+	wait, err := h.guard.CheckLogin(ctx, params.Email, ip)
 	if err != nil {
-		slog.Warn("User login failed", "email", params.Email, "ip", r.RemoteAddr, "error", err, "action", "login_failed")
-		dto.RespondWithAPIError(w, dto.ErrAuthInvalidEmailOrPassword.WithDebugInfo(err.Error()))
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to check login attempts"))
+		return
+	}
+	if wait > 0 {
+		if _, err := h.guard.RecordLoginFailure(ctx, params.Email, ip, r.UserAgent(), svc.LoginFailedLocked); err != nil {
+			slog.Error("Failed to record login attempt", "error", err)
+		}
+		slog.Warn("Login attempt while locked", "email", params.Email, "ip", ip, "action", "login_locked")
+		respondLocked(w, wait)
 		return
 	}
 
-	accessToken, err := auth.GenerateToken(user.ID, user.Role(), h.jwtSecret, h.audience, AccessTokenLifetime, auth.TokenTypeAccess)
+	user, err := h.service.Authenticate(ctx, params.Email, params.Password)
 	if err != nil {
-		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithMessage("Failed to generate access token").WithDebugInfo(err.Error()))
+		slog.Warn("User login failed", "email", params.Email, "ip", ip, "error", err, "action", "login_failed")
+		if _, err := h.guard.RecordLoginFailure(ctx, params.Email, ip, r.UserAgent(), svc.LoginFailedPassword); err != nil {
+			slog.Error("Failed to record login attempt", "error", err)
+		}
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidEmailOrPassword.WithDebugInfo(err.Error()))
+		return
+	}
+	if h.requireVerification && !user.EmailVerified {
+		slog.Warn("Login with unverified email", "user_id", user.ID, "action", "login_unverified")
+		dto.RespondWithAPIError(w, dto.ErrAuthEmailNotVerified)
 		return
 	}
 
-	refreshLifetime := refreshTokenLifetimeForRequest(r)
-	refreshToken, err := auth.GenerateToken(user.ID, user.Role(), h.jwtSecret, h.audience, refreshLifetime, auth.TokenTypeRefresh)
+	challenge, err := twoFactorChallenge(ctx, h.twoFactor, user, h.jwtSecret, h.audience)
 	if err != nil {
-		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithMessage("Failed to generate refresh token").WithDebugInfo(err.Error()))
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to check two-factor authentication"))
+		return
+	}
+	if challenge != nil {
+		slog.Info("User login awaiting second factor", "user_id", user.ID, "enrollment", challenge.EnrollmentRequired, "action", "login_2fa_challenge")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(challenge)
 		return
 	}
 
-	http.SetCookie(w, createSecureRefreshCookie(RefreshTokenName, refreshToken, int(refreshLifetime.Seconds()), r))
+	// Failures are only cleared once every factor has passed, so a known
+	// password cannot be used to reset the count while guessing codes.
+	if err := h.guard.RecordLoginSuccess(ctx, params.Email); err != nil {
+		slog.Error("Failed to reset login failures", "user_id", user.ID, "error", err)
+	}
+	tokens, err := issueTokens(w, r, h.sessions, user, h.jwtSecret, h.audience)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, ""))
+		return
+	}
 
 	slog.Info("User login successful", "user_id", user.ID, "email", user.Email, "action", "login_success")
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(dto.TokenResult{AccessToken: accessToken, ExpiresIn: int(time.Now().Add(AccessTokenLifetime).Unix())})
+	json.NewEncoder(w).Encode(tokens)
 }
 
+// twoFactorChallenge returns the second login step for users with two-factor
+// authentication enabled or required by policy, or nil when the first factor
+// is enough. Password and single sign-on logins both go through it.
+func twoFactorChallenge(ctx context.Context, twoFactor *svc.TwoFactorService, user sqlc_queries.AuthUser, jwtSecret, audience string) (*dto.TwoFactorChallenge, error) {
+	enabled, err := twoFactor.Enabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	required := false
+	if !enabled {
+		if required, err = twoFactor.Required(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+	if !enabled && !required {
+		return nil, nil
+	}
+	token, err := auth.GenerateToken(user.ID, user.Role(), jwtSecret, audience, MFATokenLifetime, auth.TokenTypeMFA)
+	if err != nil {
+		return nil, dto.ErrInternalUnexpected.WithMessage("Failed to generate token").WithDebugInfo(err.Error())
+	}
+	return &dto.TwoFactorChallenge{TwoFactorRequired: enabled, EnrollmentRequired: !enabled, MFAToken: token}, nil
+}
+
+// ForeverToken returns a long-lived API token. The tokens are bound to one
+// session per user, so revoking that session, or signing out everywhere,
+// ends them.
 func (h *AuthUserHandler) ForeverToken(w http.ResponseWriter, r *http.Request) {
 	lifetime := time.Duration(10*365*24) * time.Hour
 	userId, err := getUserID(r.Context())
@@ -271,14 +394,19 @@ func (h *AuthUserHandler) ForeverToken(w http.ResponseWriter, r *http.Request) {
 	}
 	userRole, _ := r.Context().Value(middleware.RoleContextKey).(string)
 
-	token, err := auth.GenerateToken(userId, userRole, h.jwtSecret, h.audience, lifetime, auth.TokenTypeAccess)
+	session, err := h.sessions.APISession(r.Context(), userId, middleware.ClientIP(r), lifetime)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to create session"))
+		return
+	}
+	token, err := auth.GenerateAPIToken(userId, userRole, h.jwtSecret, h.audience, time.Until(session.ExpiresAt), session.Uuid)
 	if err != nil {
 		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithMessage("Failed to generate token").WithDebugInfo(err.Error()))
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(dto.TokenResult{AccessToken: token, ExpiresIn: int(time.Now().Add(lifetime).Unix())})
+	json.NewEncoder(w).Encode(dto.TokenResult{AccessToken: token, ExpiresIn: int(session.ExpiresAt.Unix())})
 }
 
 func (h *AuthUserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
@@ -303,8 +431,41 @@ func (h *AuthUserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithMessage("Invalid user ID in token"))
 		return
 	}
+	userID := int32(userIDInt)
 
-	accessToken, err := auth.GenerateToken(int32(userIDInt), result.Role, h.jwtSecret, h.audience, AccessTokenLifetime, auth.TokenTypeAccess)
+	// Rotate the refresh token. Tokens minted before sessions existed carry no
+	// session ID and cannot be revoked, so their holders must log in again.
+	ctx := r.Context()
+	sessionID, _ := result.Claims["sid"].(string)
+	if sessionID == "" {
+		slog.Warn("Refresh token without session", "user_id", userID, "ip", middleware.ClientIP(r), "action", "refresh_no_session")
+		http.SetCookie(w, createSecureRefreshCookie(RefreshTokenName, "", -1, r))
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithMessage("Session expired, please log in again"))
+		return
+	}
+	refreshLifetime := refreshTokenLifetimeForRequest(r)
+	newRefreshToken, err := auth.GenerateSessionToken(userID, result.Role, h.jwtSecret, h.audience, refreshLifetime, sessionID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithMessage("Failed to generate refresh token").WithDebugInfo(err.Error()))
+		return
+	}
+	rotated, err := h.sessions.Rotate(ctx, sessionID, refreshCookie.Value, newRefreshToken, sessionDevice(r), time.Now().Add(refreshLifetime))
+	if err != nil {
+		slog.Warn("Refresh token rejected", "user_id", userID, "ip", middleware.ClientIP(r), "error", err, "action", "refresh_rejected")
+		var apiErr dto.APIError
+		if errors.As(err, &apiErr) {
+			http.SetCookie(w, createSecureRefreshCookie(RefreshTokenName, "", -1, r))
+			dto.RespondWithAPIError(w, apiErr)
+			return
+		}
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to refresh session"))
+		return
+	}
+	if rotated {
+		http.SetCookie(w, createSecureRefreshCookie(RefreshTokenName, newRefreshToken, int(refreshLifetime.Seconds()), r))
+	}
+
+	accessToken, err := auth.GenerateToken(userID, result.Role, h.jwtSecret, h.audience, AccessTokenLifetime, auth.TokenTypeAccess)
 	if err != nil {
 		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithMessage("Failed to generate access token").WithDebugInfo(err.Error()))
 		return
@@ -316,7 +477,17 @@ func (h *AuthUserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(dto.TokenResult{AccessToken: accessToken, ExpiresIn: int(time.Now().Add(AccessTokenLifetime).Unix())})
 }
 
+// Logout ends the session of the refresh token cookie and clears it.
 func (h *AuthUserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(RefreshTokenName); err == nil {
+		result := middleware.ParseAndValidateJWT(c.Value, auth.TokenTypeRefresh, h.jwtSecret)
+		sessionID, _ := result.Claims["sid"].(string)
+		if userID, err := strconv.ParseInt(result.UserID, 10, 32); result.Error == nil && err == nil && sessionID != "" {
+			if err := h.sessions.Revoke(r.Context(), int32(userID), sessionID, svc.SessionRevokedLogout); err != nil {
+				slog.Warn("Failed to revoke session on logout", "user_id", userID, "error", err)
+			}
+		}
+	}
 	http.SetCookie(w, createSecureRefreshCookie(RefreshTokenName, "", -1, r))
 	w.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/swuecho/chat_backend/auth"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+func TestRefreshTokenRejectsTokenWithoutSession(t *testing.T) {
+	const secret = "secret"
+	h := NewAuthUserHandler(sqlc_queries.New(testDB), nil, secret, "audience", 100, false)
+
+	// minted before refresh tokens carried a session ID
+	token, err := auth.GenerateToken(1, "user", secret, "audience", time.Hour, auth.TokenTypeRefresh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/api/token/refresh", nil)
+		req.AddCookie(&http.Cookie{Name: RefreshTokenName, Value: token})
+		w := httptest.NewRecorder()
+		h.RefreshToken(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected 401, got %d: %s", i+1, w.Code, w.Body.String())
+		}
+		for _, c := range w.Result().Cookies() {
+			if c.Name == RefreshTokenName && c.MaxAge >= 0 {
+				t.Errorf("attempt %d: refresh cookie set instead of cleared", i+1)
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+// AuxiliaryModelHandler lets admins choose the models used for session titles,
+// follow-up suggestions and summaries, globally or per workspace.
+type AuxiliaryModelHandler struct {
+	service *svc.AuxiliaryModelService
+}
+
+func NewAuxiliaryModelHandler(service *svc.AuxiliaryModelService) *AuxiliaryModelHandler {
+	return &AuxiliaryModelHandler{service: service}
+}
+
+func (h *AuxiliaryModelHandler) Register(router *mux.Router) {
+	router.HandleFunc("/auxiliary_models", h.GetGlobal).Methods(http.MethodGet)
+	router.HandleFunc("/auxiliary_models", h.UpdateGlobal).Methods(http.MethodPut)
+	router.HandleFunc("/workspaces/{uuid}/auxiliary_models", h.GetWorkspace).Methods(http.MethodGet)
+	router.HandleFunc("/workspaces/{uuid}/auxiliary_models", h.UpdateWorkspace).Methods(http.MethodPut)
+}
+
+func (h *AuxiliaryModelHandler) GetGlobal(w http.ResponseWriter, r *http.Request) {
+	m, err := h.service.Global(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get auxiliary models"))
+		return
+	}
+	json.NewEncoder(w).Encode(m)
+}
+
+// UpdateGlobal sets the server-wide models. An empty name turns the task off
+// for workspaces that do not override it.
+func (h *AuxiliaryModelHandler) UpdateGlobal(w http.ResponseWriter, r *http.Request) {
+	m, ok := decodeAuxiliaryModels(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.SetGlobal(r.Context(), m); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to update auxiliary models"))
+		return
+	}
+	json.NewEncoder(w).Encode(m)
+}
+
+func (h *AuxiliaryModelHandler) GetWorkspace(w http.ResponseWriter, r *http.Request) {
+	m, err := h.service.Workspace(r.Context(), mux.Vars(r)["uuid"])
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to get workspace auxiliary models"))
+		return
+	}
+	json.NewEncoder(w).Encode(m)
+}
+
+// UpdateWorkspace sets a workspace's overrides. An empty name uses the global model.
+func (h *AuxiliaryModelHandler) UpdateWorkspace(w http.ResponseWriter, r *http.Request) {
+	m, ok := decodeAuxiliaryModels(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.SetWorkspace(r.Context(), mux.Vars(r)["uuid"], m); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to update workspace auxiliary models"))
+		return
+	}
+	json.NewEncoder(w).Encode(m)
+}
+
+func decodeAuxiliaryModels(w http.ResponseWriter, r *http.Request) (svc.AuxiliaryModels, bool) {
+	var m svc.AuxiliaryModels
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return m, false
+	}
+	m.Title = strings.TrimSpace(m.Title)
+	m.Suggestion = strings.TrimSpace(m.Suggestion)
+	m.Summary = strings.TrimSpace(m.Summary)
+	return m, true
+}
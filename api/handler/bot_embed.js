@@ -0,0 +1,103 @@
+// Chat widget for published chatbots.
+// Usage: <script src="https://host/api/public/bots/<uuid>/embed.js" data-token="optional" async></script>
+(function () {
+  var script = document.currentScript;
+  if (!script) return;
+  var match = script.src.match(/^(.*)\/api\/public\/bots\/([^/]+)\/embed\.js/);
+  if (!match) return;
+  var base = match[1] + '/api/public/bots/' + match[2];
+  var token = script.getAttribute('data-token');
+  var storageKey = 'chatbot-visitor-' + match[2];
+  var visitorId = window.localStorage.getItem(storageKey) || '';
+
+  function request(method, path, body) {
+    var headers = { 'Content-Type': 'application/json' };
+    if (token) headers.Authorization = 'Bearer ' + token;
+    return fetch(base + path, { method: method, headers: headers, body: body ? JSON.stringify(body) : undefined })
+      .then(function (res) {
+        var id = res.headers.get('X-Bot-Visitor-Id');
+        if (id) {
+          visitorId = id;
+          window.localStorage.setItem(storageKey, id);
+        }
+        return res.json().then(function (data) {
+          if (!res.ok) throw new Error(data.message || 'Request failed');
+          return data;
+        });
+      });
+  }
+
+  var button = document.createElement('button');
+  button.textContent = '💬';
+  button.style.cssText = 'position:fixed;right:20px;bottom:20px;width:52px;height:52px;border-radius:50%;border:none;' +
+    'background:#18a058;color:#fff;font-size:24px;cursor:pointer;box-shadow:0 2px 8px rgba(0,0,0,.2);z-index:2147483646';
+
+  var panel = document.createElement('div');
+  panel.style.cssText = 'position:fixed;right:20px;bottom:84px;width:340px;max-width:calc(100vw - 40px);height:460px;' +
+    'background:#fff;border-radius:8px;box-shadow:0 4px 16px rgba(0,0,0,.2);display:none;flex-direction:column;' +
+    'font:14px sans-serif;z-index:2147483647';
+
+  var header = document.createElement('div');
+  header.style.cssText = 'padding:10px 12px;border-bottom:1px solid #eee;font-weight:bold';
+  var log = document.createElement('div');
+  log.style.cssText = 'flex:1;overflow-y:auto;padding:10px 12px';
+  var form = document.createElement('form');
+  form.style.cssText = 'display:flex;border-top:1px solid #eee';
+  var input = document.createElement('input');
+  input.placeholder = 'Ask a question...';
+  input.style.cssText = 'flex:1;border:none;padding:10px 12px;outline:none';
+  form.appendChild(input);
+  panel.appendChild(header);
+  panel.appendChild(log);
+  panel.appendChild(form);
+
+  function append(role, text) {
+    var el = document.createElement('div');
+    el.textContent = text;
+    el.style.cssText = 'margin:6px 0;padding:8px 10px;border-radius:6px;white-space:pre-wrap;' +
+      (role === 'user' ? 'background:#e8f5ee;margin-left:40px' : 'background:#f4f4f4;margin-right:40px');
+    log.appendChild(el);
+    log.scrollTop = log.scrollHeight;
+    return el;
+  }
+
+  var loaded = false;
+  function load() {
+    if (loaded) return;
+    loaded = true;
+    request('GET', '').then(function (bot) { header.textContent = bot.title || 'Chat'; })
+      .catch(function (err) { header.textContent = err.message; });
+    if (visitorId) {
+      request('GET', '/history?visitorId=' + encodeURIComponent(visitorId)).then(function (items) {
+        items.forEach(function (item) {
+          append('user', item.prompt);
+          append('assistant', item.answer);
+        });
+      }).catch(function () {});
+    }
+  }
+
+  button.addEventListener('click', function () {
+    var open = panel.style.display === 'none';
+    panel.style.display = open ? 'flex' : 'none';
+    if (open) {
+      load();
+      input.focus();
+    }
+  });
+
+  form.addEventListener('submit', function (e) {
+    e.preventDefault();
+    var message = input.value.trim();
+    if (!message) return;
+    input.value = '';
+    append('user', message);
+    var pending = append('assistant', '...');
+    request('POST', '/chat', { message: message, visitorId: visitorId, stream: false })
+      .then(function (data) { pending.textContent = data.choices[0].message.content; })
+      .catch(function (err) { pending.textContent = err.message; });
+  });
+
+  document.body.appendChild(panel);
+  document.body.appendChild(button);
+})();
@@ -0,0 +1,331 @@
+package handler
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+//go:embed bot_embed.js
+var botEmbedScript []byte
+
+const botVisitorHeader = "X-Bot-Visitor-Id"
+
+var visitorIDPattern = regexp.MustCompile(`^[A-Za-z0-9_\-]{1,64}$`)
+
+// ChatBotPublicationHandler publishes chatbots to anonymous or token-holding visitors.
+type ChatBotPublicationHandler struct {
+	service *svc.ChatBotPublicationService
+	chat    *ChatHandler
+}
+
+func NewChatBotPublicationHandler(q *sqlc_queries.Queries, chat *ChatHandler) *ChatBotPublicationHandler {
+	return &ChatBotPublicationHandler{
+		service: svc.NewChatBotPublicationService(q),
+		chat:    chat,
+	}
+}
+
+// Register registers the owner's publish settings routes.
+func (h *ChatBotPublicationHandler) Register(router *mux.Router) {
+	router.HandleFunc("/chat_bot/{uuid}/publish", h.GetPublication).Methods(http.MethodGet)
+	router.HandleFunc("/chat_bot/{uuid}/publish", h.UpdatePublication).Methods(http.MethodPut)
+	router.HandleFunc("/chat_bot/{uuid}/publish/token", h.RotateToken).Methods(http.MethodPost)
+}
+
+// RegisterPublicRoutes registers the unauthenticated visitor routes.
+func (h *ChatBotPublicationHandler) RegisterPublicRoutes(router *mux.Router) {
+	router.HandleFunc("/public/bots/{uuid}", h.GetPublicBot).Methods(http.MethodGet)
+	router.HandleFunc("/public/bots/{uuid}/chat", h.PublicBotChat).Methods(http.MethodPost)
+	router.HandleFunc("/public/bots/{uuid}/history", h.PublicBotHistory).Methods(http.MethodGet)
+	router.HandleFunc("/public/bots/{uuid}/embed.js", h.EmbedScript).Methods(http.MethodGet)
+	router.HandleFunc("/public/bots/{uuid}", h.Preflight).Methods(http.MethodOptions)
+	router.HandleFunc("/public/bots/{uuid}/chat", h.Preflight).Methods(http.MethodOptions)
+	router.HandleFunc("/public/bots/{uuid}/history", h.Preflight).Methods(http.MethodOptions)
+}
+
+// --- Owner routes ---
+
+func (h *ChatBotPublicationHandler) GetPublication(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bot, ok := loadOwnChatBot(w, r, h.chat.sessionSvc)
+	if !ok {
+		return
+	}
+
+	p, err := h.service.GetPublication(ctx, bot.Uuid)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get chatbot publication"))
+		return
+	}
+	json.NewEncoder(w).Encode(publicationToResponse(p))
+}
+
+func (h *ChatBotPublicationHandler) UpdatePublication(w http.ResponseWriter, r *http.Request) {
+	var req dto.ChatBotPublicationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+	if !validatePublicationRequest(w, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	bot, ok := loadOwnChatBot(w, r, h.chat.sessionSvc)
+	if !ok {
+		return
+	}
+
+	p, err := h.service.SavePublication(ctx, sqlc_queries.UpsertChatBotPublicationParams{
+		BotUuid:        bot.Uuid,
+		UserID:         bot.UserID,
+		AccessMode:     req.AccessMode,
+		RateLimit:      req.RateLimit,
+		AllowedOrigins: req.AllowedOrigins,
+	})
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to save chatbot publication"))
+		return
+	}
+	json.NewEncoder(w).Encode(publicationToResponse(p))
+}
+
+func (h *ChatBotPublicationHandler) RotateToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bot, ok := loadOwnChatBot(w, r, h.chat.sessionSvc)
+	if !ok {
+		return
+	}
+
+	p, err := h.service.GetPublication(ctx, bot.Uuid)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get chatbot publication"))
+		return
+	}
+	if p.ID == 0 {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("save the publish settings before creating a token"))
+		return
+	}
+
+	token, err := h.service.RotateToken(ctx, bot.Uuid)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to create bot token"))
+		return
+	}
+	json.NewEncoder(w).Encode(dto.ChatBotTokenResponse{Token: token})
+}
+
+// --- Public routes ---
+
+func (h *ChatBotPublicationHandler) GetPublicBot(w http.ResponseWriter, r *http.Request) {
+	bot, _, ok := h.loadPublicBot(w, r)
+	if !ok {
+		return
+	}
+	json.NewEncoder(w).Encode(dto.PublicBotInfoResponse{Uuid: bot.Uuid, Title: bot.Title, Summary: bot.Summary})
+}
+
+func (h *ChatBotPublicationHandler) PublicBotChat(w http.ResponseWriter, r *http.Request) {
+	bot, p, ok := h.loadPublicBot(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.PublicBotChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("message is required"))
+		return
+	}
+	if req.VisitorId == "" {
+		req.VisitorId = uuid.New().String()
+	} else if !visitorIDPattern.MatchString(req.VisitorId) {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("invalid visitorId"))
+		return
+	}
+
+	ctx := r.Context()
+	if err := h.service.CheckRateLimit(ctx, p); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, ""))
+		return
+	}
+
+	history, err := h.service.VisitorHistory(ctx, bot.Uuid, req.VisitorId)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to load visitor history"))
+		return
+	}
+
+	w.Header().Set(botVisitorHeader, req.VisitorId)
+	// Visitor turns are recorded under the bot owner, distinguished by visitor id.
+	genBotAnswer(ctx, h.chat, w, bot, history, req.VisitorId, req.Message, bot.UserID, req.Stream)
+}
+
+func (h *ChatBotPublicationHandler) PublicBotHistory(w http.ResponseWriter, r *http.Request) {
+	bot, _, ok := h.loadPublicBot(w, r)
+	if !ok {
+		return
+	}
+
+	visitorID := r.URL.Query().Get("visitorId")
+	if !visitorIDPattern.MatchString(visitorID) {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("invalid visitorId"))
+		return
+	}
+
+	rows, err := h.service.ListVisitorTurns(r.Context(), bot.Uuid, visitorID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to load visitor history"))
+		return
+	}
+
+	items := make([]dto.PublicBotHistoryItem, 0, len(rows))
+	for i := len(rows) - 1; i >= 0; i-- {
+		items = append(items, dto.PublicBotHistoryItem{
+			Prompt:    rows[i].Prompt,
+			Answer:    rows[i].Answer,
+			CreatedAt: rows[i].CreatedAt.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+	json.NewEncoder(w).Encode(items)
+}
+
+// EmbedScript serves the chat widget loaded with <script src=".../embed.js">.
+func (h *ChatBotPublicationHandler) EmbedScript(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Write(botEmbedScript)
+}
+
+// Preflight answers CORS preflight requests using the bot's allowed origins.
+func (h *ChatBotPublicationHandler) Preflight(w http.ResponseWriter, r *http.Request) {
+	p, err := h.service.GetPublication(r.Context(), mux.Vars(r)["uuid"])
+	if err == nil && p.AccessMode != svc.BotAccessDisabled {
+		setBotCORSHeaders(w, r, p)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Helpers ---
+
+// loadOwnChatBot loads the chatbot named in the route if it belongs to the caller.
+func loadOwnChatBot(w http.ResponseWriter, r *http.Request, sessionSvc *svc.ChatSessionService) (sqlc_queries.ChatSnapshot, bool) {
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return sqlc_queries.ChatSnapshot{}, false
+	}
+
+	bot, err := sessionSvc.ChatSnapshotByUserIdAndUuid(ctx, sqlc_queries.ChatSnapshotByUserIdAndUuidParams{
+		UserID: userID, Uuid: mux.Vars(r)["uuid"],
+	})
+	if err != nil || bot.Typ != "chatbot" {
+		dto.RespondWithAPIError(w, dto.ErrResourceNotFound("Chatbot"))
+		return bot, false
+	}
+	return bot, true
+}
+
+// loadPublicBot resolves a published bot and checks the caller's token and origin.
+func (h *ChatBotPublicationHandler) loadPublicBot(w http.ResponseWriter, r *http.Request) (sqlc_queries.ChatSnapshot, sqlc_queries.ChatBotPublication, bool) {
+	ctx := r.Context()
+	botUUID := mux.Vars(r)["uuid"]
+
+	p, err := h.service.GetPublication(ctx, botUUID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get chatbot publication"))
+		return sqlc_queries.ChatSnapshot{}, p, false
+	}
+	if p.AccessMode != svc.BotAccessDisabled {
+		setBotCORSHeaders(w, r, p)
+	}
+	if err := h.service.Authorize(p, bearerToken(r), r.Header.Get("Origin")); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, ""))
+		return sqlc_queries.ChatSnapshot{}, p, false
+	}
+
+	bot, err := h.publicSnapshot(ctx, botUUID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrResourceNotFound("Chatbot"))
+		return bot, p, false
+	}
+	return bot, p, true
+}
+
+func (h *ChatBotPublicationHandler) publicSnapshot(ctx context.Context, botUUID string) (sqlc_queries.ChatSnapshot, error) {
+	bot, err := h.chat.sessionSvc.ChatSnapshotByUUID(ctx, botUUID)
+	if err == nil && bot.Typ != "chatbot" {
+		err = dto.ErrResourceNotFound("Chatbot")
+	}
+	return bot, err
+}
+
+func setBotCORSHeaders(w http.ResponseWriter, r *http.Request, p sqlc_queries.ChatBotPublication) {
+	origin := r.Header.Get("Origin")
+	if origin == "" || !svc.OriginAllowed(p.AllowedOrigins, origin) {
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	w.Header().Add("Vary", "Origin")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Set("Access-Control-Expose-Headers", botVisitorHeader)
+}
+
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+func validatePublicationRequest(w http.ResponseWriter, req *dto.ChatBotPublicationRequest) bool {
+	switch req.AccessMode {
+	case svc.BotAccessDisabled, svc.BotAccessAnonymous, svc.BotAccessToken:
+	default:
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("accessMode must be disabled, anonymous or token"))
+		return false
+	}
+	if req.RateLimit < 0 {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("rateLimit must not be negative; 0 uses the default"))
+		return false
+	}
+	origins := make([]string, 0, len(req.AllowedOrigins))
+	for _, o := range req.AllowedOrigins {
+		if o = strings.TrimRight(strings.TrimSpace(o), "/"); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	req.AllowedOrigins = origins
+	return true
+}
+
+func publicationToResponse(p sqlc_queries.ChatBotPublication) dto.ChatBotPublicationResponse {
+	origins := p.AllowedOrigins
+	if origins == nil {
+		origins = []string{}
+	}
+	return dto.ChatBotPublicationResponse{
+		BotUuid:        p.BotUuid,
+		AccessMode:     p.AccessMode,
+		RateLimit:      p.RateLimit,
+		AllowedOrigins: origins,
+		HasToken:       p.TokenHash != "",
+		EmbedUrl:       "/api/public/bots/" + p.BotUuid + "/embed.js",
+	}
+}
@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+const maxBotKnowledgeSize = 1 << 20 // 1MB
+
+// ChatBotVersionHandler edits chatbots through versions and manages their knowledge files.
+type ChatBotVersionHandler struct {
+	service    *svc.ChatBotVersionService
+	sessionSvc *svc.ChatSessionService
+}
+
+func NewChatBotVersionHandler(q *sqlc_queries.Queries) *ChatBotVersionHandler {
+	return &ChatBotVersionHandler{
+		service:    svc.NewChatBotVersionService(q),
+		sessionSvc: svc.NewChatSessionService(q),
+	}
+}
+
+func (h *ChatBotVersionHandler) Register(router *mux.Router) {
+	router.HandleFunc("/chat_bot/{uuid}/versions", h.ListVersions).Methods(http.MethodGet)
+	router.HandleFunc("/chat_bot/{uuid}/versions", h.CreateVersion).Methods(http.MethodPost)
+	router.HandleFunc("/chat_bot/{uuid}/versions/{version}", h.GetVersion).Methods(http.MethodGet)
+	router.HandleFunc("/chat_bot/{uuid}/versions/{version}/restore", h.RestoreVersion).Methods(http.MethodPost)
+	router.HandleFunc("/chat_bot/{uuid}/files", h.ListFiles).Methods(http.MethodGet)
+	router.HandleFunc("/chat_bot/{uuid}/files", h.UploadFile).Methods(http.MethodPost)
+}
+
+func (h *ChatBotVersionHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bot, ok := loadOwnChatBot(w, r, h.sessionSvc)
+	if !ok {
+		return
+	}
+	bot, err := h.service.EnsureBaseline(ctx, bot, bot.UserID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to create initial chatbot version"))
+		return
+	}
+
+	versions, err := h.service.ListVersions(ctx, bot.Uuid)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to list chatbot versions"))
+		return
+	}
+
+	responses := make([]dto.ChatBotVersionResponse, 0, len(versions))
+	for _, v := range versions {
+		responses = append(responses, botVersionToResponse(v, bot.BotVersion))
+	}
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *ChatBotVersionHandler) CreateVersion(w http.ResponseWriter, r *http.Request) {
+	var req dto.ChatBotVersionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+	if !validateBotVersionRequest(w, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	bot, ok := loadOwnChatBot(w, r, h.sessionSvc)
+	if !ok {
+		return
+	}
+	if req.Model == "" {
+		req.Model = bot.Model
+	}
+	if _, err := h.sessionSvc.ChatModelByName(ctx, req.Model); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrResourceNotFound("Chat model: "+req.Model).WithDebugInfo(err.Error()))
+		return
+	}
+
+	v, err := h.service.CreateVersion(ctx, bot, bot.UserID, svc.ChatBotEdit{
+		SystemPrompt: req.SystemPrompt,
+		Examples:     req.Examples,
+		Model:        req.Model,
+		FileIDs:      req.FileIds,
+		Note:         req.Note,
+	})
+	if err != nil {
+		var apiErr dto.APIError
+		if errors.As(err, &apiErr) {
+			dto.RespondWithAPIError(w, apiErr)
+			return
+		}
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to create chatbot version"))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(botVersionToResponse(v, v.Version))
+}
+
+func (h *ChatBotVersionHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	bot, ok := loadOwnChatBot(w, r, h.sessionSvc)
+	if !ok {
+		return
+	}
+	version, ok := parseBotVersion(w, r)
+	if !ok {
+		return
+	}
+
+	v, err := h.service.GetVersion(r.Context(), bot.Uuid, version)
+	if err != nil {
+		respondBotVersionError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(botVersionToResponse(v, bot.BotVersion))
+}
+
+func (h *ChatBotVersionHandler) RestoreVersion(w http.ResponseWriter, r *http.Request) {
+	bot, ok := loadOwnChatBot(w, r, h.sessionSvc)
+	if !ok {
+		return
+	}
+	version, ok := parseBotVersion(w, r)
+	if !ok {
+		return
+	}
+
+	v, err := h.service.Restore(r.Context(), bot.Uuid, version)
+	if err != nil {
+		respondBotVersionError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(botVersionToResponse(v, v.Version))
+}
+
+func (h *ChatBotVersionHandler) ListFiles(w http.ResponseWriter, r *http.Request) {
+	bot, ok := loadOwnChatBot(w, r, h.sessionSvc)
+	if !ok {
+		return
+	}
+
+	files, err := h.service.ListFiles(r.Context(), bot.Uuid)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to list chatbot files"))
+		return
+	}
+
+	responses := make([]dto.ChatBotFileResponse, 0, len(files))
+	for _, f := range files {
+		responses = append(responses, dto.ChatBotFileResponse{
+			ID: f.ID, Name: f.Name, MimeType: f.MimeType, Size: f.Size,
+			CreatedAt: f.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+	json.NewEncoder(w).Encode(responses)
+}
+
+// UploadFile stores a text file that versions can reference as knowledge.
+func (h *ChatBotVersionHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
+	bot, ok := loadOwnChatBot(w, r, h.sessionSvc)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBotKnowledgeSize+(64<<10))
+	if err := r.ParseMultipartForm(maxBotKnowledgeSize); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput(
+			fmt.Sprintf("file too large, max size is %d bytes", maxBotKnowledgeSize)))
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("failed to read uploaded file").WithDebugInfo(err.Error()))
+		return
+	}
+	defer file.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, file); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithDetail("failed to read file data").WithDebugInfo(err.Error()))
+		return
+	}
+	if buf.Len() > maxBotKnowledgeSize {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput(
+			fmt.Sprintf("file too large, max size is %d bytes", maxBotKnowledgeSize)))
+		return
+	}
+	if !utf8.Valid(buf.Bytes()) || bytes.IndexByte(buf.Bytes(), 0) >= 0 {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("knowledge files must be UTF-8 text"))
+		return
+	}
+
+	mimeType := header.Header.Get("Content-Type")
+	if mimeType == "" {
+		mimeType = "text/plain"
+	}
+
+	f, err := h.service.CreateFile(r.Context(), sqlc_queries.CreateChatBotFileParams{
+		BotUuid: bot.Uuid, UserID: bot.UserID, Name: header.Filename, MimeType: mimeType, Content: buf.String(),
+	})
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to store chatbot file"))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dto.ChatBotFileResponse{
+		ID: f.ID, Name: f.Name, MimeType: f.MimeType, Size: int32(len(f.Content)),
+		CreatedAt: f.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	})
+}
+
+// --- Helpers ---
+
+func parseBotVersion(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	version, err := strconv.ParseInt(mux.Vars(r)["version"], 10, 32)
+	if err != nil || version < 1 {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("invalid version"))
+		return 0, false
+	}
+	return int32(version), true
+}
+
+func respondBotVersionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		dto.RespondWithAPIError(w, dto.ErrResourceNotFound("chatbot version"))
+		return
+	}
+	dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get chatbot version"))
+}
+
+func validateBotVersionRequest(w http.ResponseWriter, req *dto.ChatBotVersionRequest) bool {
+	if strings.TrimSpace(req.SystemPrompt) == "" {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("systemPrompt is required"))
+		return false
+	}
+	for _, m := range req.Examples {
+		if strings.TrimSpace(m.Text) == "" {
+			dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("example messages must not be empty"))
+			return false
+		}
+	}
+	return true
+}
+
+func botVersionToResponse(v sqlc_queries.ChatBotVersion, current int32) dto.ChatBotVersionResponse {
+	resp := dto.ChatBotVersionResponse{
+		Version: v.Version, Model: v.Model, FileIds: v.FileIds, Note: v.Note,
+		Current:   v.Version == current,
+		Examples:  []dto.SimpleChatMessage{},
+		CreatedAt: v.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if resp.FileIds == nil {
+		resp.FileIds = []int32{}
+	}
+	var msgs []dto.SimpleChatMessage
+	if err := json.Unmarshal(v.Conversation, &msgs); err == nil && len(msgs) > 0 {
+		resp.SystemPrompt = msgs[0].Text
+		resp.Examples = msgs[1:]
+	}
+	return resp
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+// ChatLogHandler exposes admin browsing of chat logs.
+type ChatLogHandler struct {
+	service *svc.ChatLogService
+}
+
+func NewChatLogHandler(q *sqlc_queries.Queries) *ChatLogHandler {
+	return &ChatLogHandler{service: svc.NewChatLogService(q)}
+}
+
+func (h *ChatLogHandler) Register(router *mux.Router) {
+	router.HandleFunc("/chat_logs", h.ListChatLogs).Methods(http.MethodGet)
+	router.HandleFunc("/chat_logs/{id}", h.GetChatLog).Methods(http.MethodGet)
+	router.HandleFunc("/chat_logs/{id}", h.DeleteChatLog).Methods(http.MethodDelete)
+}
+
+// ListChatLogs returns a page of chat logs.
+// Query params: page, size, user_id, model, session_uuid, q, from, to (RFC 3339).
+func (h *ChatLogHandler) ListChatLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page := int32(1)
+	size := int32(20)
+	if p, err := strconv.Atoi(query.Get("page")); err == nil && p > 0 {
+		page = int32(p)
+	}
+	if s, err := strconv.Atoi(query.Get("size")); err == nil && s > 0 && s <= 100 {
+		size = int32(s)
+	}
+
+	filter := svc.ChatLogFilter{
+		Model:       query.Get("model"),
+		SessionUuid: query.Get("session_uuid"),
+		Keyword:     query.Get("q"),
+	}
+	if v := query.Get("user_id"); v != "" {
+		userID, err := strconv.Atoi(v)
+		if err != nil {
+			dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("invalid user_id"))
+			return
+		}
+		filter.UserID = int32(userID)
+	}
+	for name, dst := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		v := query.Get(name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("invalid "+name+" timestamp, expected RFC 3339"))
+			return
+		}
+		*dst = t
+	}
+
+	pagination := dto.Pagination{Page: page, Size: size}
+	logs, total, err := h.service.ListChatLogs(r.Context(), filter, size, pagination.Offset())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to list chat logs"))
+		return
+	}
+
+	data := make([]interface{}, len(logs))
+	for i, l := range logs {
+		data[i] = l
+	}
+	pagination.Data = data
+	pagination.Total = total
+
+	json.NewEncoder(w).Encode(pagination)
+}
+
+func (h *ChatLogHandler) GetChatLog(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("invalid chat log ID"))
+		return
+	}
+
+	chatLog, err := h.service.GetChatLogByID(r.Context(), int32(id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.RespondWithAPIError(w, dto.ErrResourceNotFound("chat log"))
+			return
+		}
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get chat log"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(chatLog)
+}
+
+func (h *ChatLogHandler) DeleteChatLog(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("invalid chat log ID"))
+		return
+	}
+
+	if err := h.service.DeleteChatLog(r.Context(), int32(id)); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to delete chat log"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
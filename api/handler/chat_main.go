@@ -20,6 +20,10 @@ type ChatHandler struct {
 	service         *svc.ChatService
 	sessionSvc      *svc.ChatSessionService
 	chatfileService *svc.ChatFileService
+	slashSvc        *svc.SlashCommandService
+	botVersionSvc   *svc.ChatBotVersionService
+	auxSvc          *svc.AuxiliaryModelService
+	quotaSvc        *svc.QuotaService
 	rateLimiter     *rate.Limiter
 	openAIKey       string
 	openAIProxy     string
@@ -28,11 +32,16 @@ type ChatHandler struct {
 const sessionTitleGenerationTimeout = 30 * time.Second
 
 // NewChatHandler creates a new ChatHandler.
-func NewChatHandler(sqlc_q *sqlc_queries.Queries, rateLimiter *rate.Limiter, openAIKey, openAIProxy string) *ChatHandler {
+func NewChatHandler(sqlc_q *sqlc_queries.Queries, rateLimiter *rate.Limiter, openAIKey, openAIProxy string, defaultRateLimit int32) *ChatHandler {
+	auxSvc := svc.NewAuxiliaryModelService(sqlc_q, provider.Config{OpenAIKey: openAIKey, OpenAIProxy: openAIProxy, RateLimiter: rateLimiter})
 	return &ChatHandler{
 		service:         svc.NewChatService(sqlc_q, openAIKey, openAIProxy),
 		sessionSvc:      svc.NewChatSessionService(sqlc_q),
 		chatfileService: svc.NewChatFileService(sqlc_q),
+		slashSvc:        svc.NewSlashCommandService(sqlc_q, auxSvc),
+		botVersionSvc:   svc.NewChatBotVersionService(sqlc_q),
+		auxSvc:          auxSvc,
+		quotaSvc:        svc.NewQuotaService(sqlc_q, defaultRateLimit),
 		rateLimiter:     rateLimiter,
 		openAIKey:       openAIKey,
 		openAIProxy:     openAIProxy,
@@ -42,6 +51,7 @@ func NewChatHandler(sqlc_q *sqlc_queries.Queries, rateLimiter *rate.Limiter, ope
 // Register registers chat routes on the given router.
 func (h *ChatHandler) Register(router *mux.Router) {
 	router.HandleFunc("/chat_stream", h.ChatCompletionHandler).Methods(http.MethodPost)
+	router.HandleFunc("/transcribe", h.TranscribeHandler).Methods(http.MethodPost)
 	router.HandleFunc("/chatbot", h.ChatBotCompletionHandler).Methods(http.MethodPost)
 	router.HandleFunc("/chat_instructions", h.GetChatInstructions).Methods(http.MethodGet)
 }
@@ -2,12 +2,10 @@ package handler
 
 import (
 	"context"
-	"database/sql"
-	"errors"
-	"fmt"
+	"net/http"
+	"strconv"
 
 	mapset "github.com/deckarep/golang-set/v2"
-	"log/slog"
 
 	"github.com/swuecho/chat_backend/dto"
 	"github.com/swuecho/chat_backend/models"
@@ -23,29 +21,16 @@ func (h *ChatHandler) chooseChatModel(ctx context.Context, session sqlc_queries.
 
 	chatModel, err := provider.GetChatModel(ctx, h.Queries(), session.Model)
 	if err != nil {
-		return provider.NewOpenAIChatModel(h) // fallback
+		return provider.Instrument("openai", provider.NewOpenAIChatModel(h)) // fallback
 	}
 
 	completionModels := mapset.NewSet[string]()
 	isCompletion := completionModels.Contains(session.Model)
 
-	switch chatModel.ApiType {
-	case "claude":
-		return provider.NewClaude3ChatModel(h)
-	case "ollama":
-		return provider.NewOllamaChatModel(h)
-	case "gemini":
-		return provider.NewGeminiChatModel(h)
-	case "custom":
-		return provider.NewCustomChatModel(h)
-	case "openai":
-		if isCompletion {
-			return provider.NewCompletionChatModel(h)
-		}
-		return provider.NewOpenAIChatModel(h)
-	default:
-		return provider.NewOpenAIChatModel(h)
+	if chatModel.ApiType == "openai" && isCompletion {
+		return provider.Instrument("openai", provider.NewCompletionChatModel(h))
 	}
+	return provider.NewChatModel(chatModel.ApiType, h)
 }
 
 // isTest returns true if any message starts with the test demo prefix.
@@ -58,43 +43,57 @@ func isTest(msgs []models.Message) bool {
 	return false
 }
 
-// CheckModelAccess verifies the user hasn't exceeded per-model rate limits.
-// Returns nil if access is allowed, or an error (dto.APIError) if denied.
+// CheckModelAccess applies the quotas scoped to the session's workspace and
+// the model; the user-wide ones were checked by RateLimitByUserID before
+// routing. Returns nil if access is allowed, or an error (dto.APIError) if
+// denied. When the request's headers were attached to ctx with
+// withRateLimitHeaders, a scoped limit tighter than the user-wide one is
+// reported in them.
 func (h *ChatHandler) CheckModelAccess(ctx context.Context, chatSessionUuid, model string, userID int32) error {
-	chatModel, err := h.sessionSvc.ChatModelByName(ctx, model)
+	limit, err := h.quotaSvc.CheckModelAccess(ctx, chatSessionUuid, model, userID)
 	if err != nil {
-		slog.Error("Chat model not found", "error", err, "model", model)
-		apiErr := dto.ErrResourceNotFound("chat model: " + model)
-		return apiErr
+		return err
 	}
-
-	if !chatModel.EnablePerModeRatelimit {
-		return nil
+	if header, ok := ctx.Value(rateLimitHeaderKey{}).(http.Header); ok {
+		setRateLimitHeaders(header, limit)
 	}
+	return nil
+}
 
-	rate, err := h.sessionSvc.RateLimitByUserAndSessionUUID(ctx, sqlc_queries.RateLimiteByUserAndSessionUUIDParams{
-		Uuid: chatSessionUuid, UserID: userID,
-	})
+// checkUserModelAccess applies all of the user's quotas to a request to the
+// session's model, for routes that RateLimitByUserID does not cover, and
+// reports the tightest limit in header.
+func (h *ChatHandler) checkUserModelAccess(ctx context.Context, header http.Header, session sqlc_queries.ChatSession, userID int32) error {
+	limit, err := h.quotaSvc.CheckUserQuota(ctx, userID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil
-		}
-		return dto.WrapError(dto.MapDatabaseError(err), "Failed to get rate limit")
+		return err
 	}
-
-	usage10Min, err := h.sessionSvc.GetChatMessagesCountByUserAndModel(ctx, sqlc_queries.GetChatMessagesCountByUserAndModelParams{
-		UserID: userID, Model: rate.ChatModelName,
-	})
+	setRateLimitHeaders(header, limit)
+	limit, err = h.quotaSvc.CheckModelAccess(ctx, session.Uuid, session.Model, userID)
 	if err != nil {
-		return dto.ErrInternalUnexpected.WithDetail("Failed to get usage data").WithDebugInfo(err.Error())
+		return err
 	}
+	setRateLimitHeaders(header, limit)
+	return nil
+}
 
-	if int32(usage10Min) > rate.RateLimit {
-		apiErr := dto.ErrTooManyRequests
-		apiErr.Message = fmt.Sprintf("Rate limit exceeded for %s", rate.ChatModelName)
-		apiErr.Detail = fmt.Sprintf("Usage: %d, Limit: %d", usage10Min, rate.RateLimit)
-		return apiErr
-	}
+type rateLimitHeaderKey struct{}
 
-	return nil
+// withRateLimitHeaders lets CheckModelAccess, which runs inside the provider,
+// report the scoped limits of a model request made with ctx in header.
+func withRateLimitHeaders(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, rateLimitHeaderKey{}, header)
+}
+
+// setRateLimitHeaders writes limit to header unless it already reports a
+// limit with fewer requests remaining, so the headers always describe the
+// quota closest to being exhausted.
+func setRateLimitHeaders(header http.Header, limit *dto.RateLimit) {
+	if limit == nil {
+		return
+	}
+	if remaining, err := strconv.ParseInt(header.Get("X-RateLimit-Remaining"), 10, 64); err == nil && remaining <= limit.Remaining {
+		return
+	}
+	limit.SetHeaders(header)
 }
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/swuecho/chat_backend/dto"
+)
+
+func TestSetRateLimitHeadersKeepsTightest(t *testing.T) {
+	header := http.Header{}
+	setRateLimitHeaders(header, &dto.RateLimit{Limit: 100, Remaining: 40})
+	setRateLimitHeaders(header, &dto.RateLimit{Limit: 10, Remaining: 60})
+	if got := header.Get("X-RateLimit-Limit"); got != "100" {
+		t.Errorf("limit = %s after a looser scoped limit, want 100", got)
+	}
+	setRateLimitHeaders(header, &dto.RateLimit{Limit: 10, Remaining: 3})
+	if got := header.Get("X-RateLimit-Remaining"); got != "3" {
+		t.Errorf("remaining = %s after a tighter scoped limit, want 3", got)
+	}
+	setRateLimitHeaders(header, nil)
+	if got := header.Get("X-RateLimit-Limit"); got != "10" {
+		t.Errorf("limit = %s after no limit, want 10", got)
+	}
+}
@@ -12,7 +12,9 @@ import (
 	openai "github.com/sashabaranov/go-openai"
 	"log/slog"
 
+	"github.com/swuecho/chat_backend/config"
 	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/metrics"
 	"github.com/swuecho/chat_backend/models"
 	"github.com/swuecho/chat_backend/provider"
 	"github.com/swuecho/chat_backend/sqlc_queries"
@@ -22,32 +24,37 @@ import (
 var titleGenSemaphore = make(chan struct{}, 5)
 
 // validateChatSession validates the session UUID and retrieves session + model info.
-func (h *ChatHandler) validateChatSession(ctx context.Context, w http.ResponseWriter, chatSessionUuid string) (*sqlc_queries.ChatSession, *sqlc_queries.ChatModel, string, bool) {
+func (h *ChatHandler) validateChatSession(ctx context.Context, w http.ResponseWriter, chatSessionUuid string) (*sqlc_queries.ChatSession, *sqlc_queries.ChatModel, bool) {
 	chatSession, err := h.sessionSvc.GetChatSessionByUUID(ctx, chatSessionUuid)
 	if err != nil {
 		slog.Info("Invalid session UUID", "uuid", chatSessionUuid, "error", err)
 		dto.RespondWithAPIError(w, dto.ErrResourceNotFound("chat session").WithMessage(chatSessionUuid))
-		return nil, nil, "", false
+		return nil, nil, false
 	}
 
 	chatModel, err := h.sessionSvc.ChatModelByName(ctx, chatSession.Model)
 	if err != nil {
 		dto.RespondWithAPIError(w, dto.ErrResourceNotFound("chat model: "+chatSession.Model))
-		return nil, nil, "", false
+		return nil, nil, false
 	}
 
-	baseURL, _ := provider.GetModelBaseURL(chatModel.Url)
-
 	if chatSession.Uuid == "" {
 		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid session UUID"))
-		return nil, nil, "", false
+		return nil, nil, false
 	}
 
-	return &chatSession, &chatModel, baseURL, true
+	return &chatSession, &chatModel, true
 }
 
 // handlePromptCreation creates or reuses the system prompt and adds the user message.
-func (h *ChatHandler) handlePromptCreation(ctx context.Context, w http.ResponseWriter, chatSession *sqlc_queries.ChatSession, chatUuid, newQuestion string, userID int32, baseURL string) bool {
+// Slash commands in newQuestion are expanded before the message is saved. The returned
+// session carries any per-command model or temperature override for this turn only.
+func (h *ChatHandler) handlePromptCreation(ctx context.Context, w http.ResponseWriter, chatSession *sqlc_queries.ChatSession, chatUuid, newQuestion string, userID int32) (*sqlc_queries.ChatSession, bool) {
+	turnSession, newQuestion, ok := h.expandSlashCommand(ctx, w, chatSession, newQuestion, userID)
+	if !ok {
+		return nil, false
+	}
+
 	existingPrompt := true
 	_, err := h.sessionSvc.GetOneChatPromptBySessionUUID(ctx, chatSession.Uuid)
 	if err != nil {
@@ -56,27 +63,27 @@ func (h *ChatHandler) handlePromptCreation(ctx context.Context, w http.ResponseW
 		} else {
 			slog.Error("error checking prompt", "session", chatSession.Uuid, "error", err)
 			dto.RespondWithAPIError(w, dto.CreateAPIError(dto.ErrInternalUnexpected, "Failed to get prompt", err.Error()))
-			return false
+			return nil, false
 		}
 	}
 
 	if existingPrompt {
 		if newQuestion != "" {
-			if _, err := h.service.CreateChatMessageSimple(ctx, chatSession.Uuid, chatUuid, "user", newQuestion, "", chatSession.Model, userID, baseURL, chatSession.SummarizeMode); err != nil {
+			if _, err := h.service.CreateChatMessageSimple(ctx, chatSession.Uuid, chatUuid, "user", newQuestion, "", turnSession.Model, userID, chatSession.SummarizeMode); err != nil {
 				dto.RespondWithAPIError(w, dto.CreateAPIError(dto.ErrInternalUnexpected, "Failed to create message", err.Error()))
-				return false
+				return nil, false
 			}
 		}
 	} else {
-		if _, err := h.service.CreateChatPromptSimple(ctx, chatSession.Uuid, dto.DefaultSystemPromptText, userID); err != nil {
+		if _, err := h.service.CreateChatPromptSimple(ctx, chatSession.Uuid, config.Current().CHAT.DEFAULT_SYSTEM_PROMPT, userID); err != nil {
 			dto.RespondWithAPIError(w, dto.CreateAPIError(dto.ErrInternalUnexpected, "Failed to create prompt", err.Error()))
-			return false
+			return nil, false
 		}
 
 		if newQuestion != "" {
-			if _, err := h.service.CreateChatMessageSimple(ctx, chatSession.Uuid, chatUuid, "user", newQuestion, "", chatSession.Model, userID, baseURL, chatSession.SummarizeMode); err != nil {
+			if _, err := h.service.CreateChatMessageSimple(ctx, chatSession.Uuid, chatUuid, "user", newQuestion, "", turnSession.Model, userID, chatSession.SummarizeMode); err != nil {
 				dto.RespondWithAPIError(w, dto.CreateAPIError(dto.ErrInternalUnexpected, "Failed to create message", err.Error()))
-				return false
+				return nil, false
 			}
 
 			if title := firstNWords(newQuestion, 10); title != "" {
@@ -89,12 +96,39 @@ func (h *ChatHandler) handlePromptCreation(ctx context.Context, w http.ResponseW
 			}
 		}
 	}
-	return true
+	return turnSession, true
+}
+
+// expandSlashCommand expands a leading slash command and applies its overrides to a copy of the session.
+func (h *ChatHandler) expandSlashCommand(ctx context.Context, w http.ResponseWriter, chatSession *sqlc_queries.ChatSession, question string, userID int32) (*sqlc_queries.ChatSession, string, bool) {
+	expansion, err := h.slashSvc.Expand(ctx, *chatSession, userID, question)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to expand slash command"))
+		return nil, "", false
+	}
+	if expansion.Command == "" {
+		return chatSession, question, true
+	}
+	slog.Info("Expanded slash command", "session", chatSession.Uuid, "command", expansion.Command)
+
+	turnSession := *chatSession
+	if expansion.Temperature.Valid {
+		turnSession.Temperature = expansion.Temperature.Float64
+	}
+	if expansion.Model != "" && expansion.Model != chatSession.Model {
+		chatModel, err := h.sessionSvc.ChatModelByName(ctx, expansion.Model)
+		if err != nil {
+			dto.RespondWithAPIError(w, dto.ErrResourceNotFound("chat model: "+expansion.Model))
+			return nil, "", false
+		}
+		turnSession.Model = chatModel.Name
+	}
+	return &turnSession, expansion.Content, true
 }
 
 // generateAndSaveAnswer calls the LLM, streams the response, and persists the answer.
-func (h *ChatHandler) generateAndSaveAnswer(ctx context.Context, w http.ResponseWriter, chatSession *sqlc_queries.ChatSession, chatUuid string, userID int32, baseURL string, streamOutput bool) bool {
-	msgs, err := h.service.GetAskMessages(*chatSession, chatUuid, false)
+func (h *ChatHandler) generateAndSaveAnswer(ctx context.Context, w http.ResponseWriter, chatSession *sqlc_queries.ChatSession, chatUuid string, userID int32, streamOutput bool) bool {
+	msgs, err := h.service.GetAskMessages(ctx, *chatSession, chatUuid, false)
 	if err != nil {
 		slog.Error("error collecting messages", "session", chatSession.Uuid, "error", err)
 		dto.RespondWithAPIError(w, dto.CreateAPIError(dto.ErrInternalUnexpected, "Failed to collect messages", err.Error()))
@@ -118,29 +152,41 @@ func (h *ChatHandler) generateAndSaveAnswer(ctx context.Context, w http.Response
 		h.service.LogChat(*chatSession, msgs, LLMAnswer.ReasoningContent+LLMAnswer.Answer)
 	}
 
-	chatMessage, err := h.service.CreateChatMessageWithSuggestedQuestions(ctx, chatSession.Uuid, LLMAnswer.AnswerId, "assistant", LLMAnswer.Answer, LLMAnswer.ReasoningContent, chatSession.Model, userID, baseURL, chatSession.SummarizeMode, chatSession.ExploreMode, msgs)
+	chatMessage, err := h.service.CreateChatMessageWithSuggestedQuestions(ctx, chatSession.Uuid, LLMAnswer.AnswerId, "assistant", LLMAnswer.Answer, LLMAnswer.ReasoningContent, chatSession.Model, userID, chatSession.SummarizeMode, chatSession.ExploreMode, msgs)
 	if err != nil {
 		dto.RespondWithAPIError(w, dto.CreateAPIError(dto.ErrInternalUnexpected, "Failed to create message", err.Error()))
 		return false
 	}
 
+	attachments := h.saveGeneratedImages(ctx, w, *chatSession, LLMAnswer, streamOutput)
+	if !streamOutput {
+		writeChatCompletion(w, LLMAnswer, attachments)
+	}
+
 	if streamOutput && chatSession.ExploreMode && chatMessage.SuggestedQuestions != nil {
 		h.sendSuggestedQuestionsStream(w, LLMAnswer.AnswerId, chatMessage.SuggestedQuestions)
 	}
 
 	// Launch title generation with bounded concurrency
 	go func() {
+		metrics.TitleGenerationWaiting.Inc()
 		titleGenSemaphore <- struct{}{}
-		defer func() { <-titleGenSemaphore }()
+		metrics.TitleGenerationWaiting.Dec()
+		metrics.TitleGenerationRunning.Inc()
+		defer func() {
+			metrics.TitleGenerationRunning.Dec()
+			<-titleGenSemaphore
+		}()
 		h.generateSessionTitle(chatSession, userID)
 	}()
 	return true
 }
 
-// streamFromModel calls model.Stream() and consumes the channel, writing SSE or JSON to w.
-// Returns the final answer or an error.
+// streamFromModel calls model.Stream() and consumes the channel, writing SSE
+// to w when streamOutput is set; otherwise the caller writes the JSON response
+// with writeChatCompletion. Returns the final answer or an error.
 func streamFromModel(model provider.ChatModel, ctx context.Context, w http.ResponseWriter, session sqlc_queries.ChatSession, msgs []models.Message, chatUuid string, regenerate bool, streamOutput bool) (*models.LLMAnswer, error) {
-	ch, err := model.Stream(ctx, session, msgs, chatUuid, regenerate, streamOutput)
+	ch, err := model.Stream(withRateLimitHeaders(ctx, w.Header()), session, msgs, chatUuid, regenerate, streamOutput)
 	if err != nil {
 		return nil, err
 	}
@@ -181,22 +227,29 @@ func streamFromModel(model provider.ChatModel, ctx context.Context, w http.Respo
 				break
 			}
 		}
-		// Write non-streaming JSON response
-		if lastAnswer != nil {
-			json.NewEncoder(w).Encode(ChatCompletionResponse{
-				ID:     lastAnswer.AnswerId,
-				Object: "chat.completion",
-				Choices: []Choice{{
-					Message: openai.ChatCompletionMessage{Content: lastAnswer.Answer},
-				}},
-			})
-		}
 	}
 
 	return lastAnswer, nil
 }
 
-// generateSessionTitle asynchronously updates the session topic using an LLM.
+// writeChatCompletion writes the non-streaming JSON response for answer,
+// along with the files saved from its generated images.
+func writeChatCompletion(w http.ResponseWriter, answer *models.LLMAnswer, attachments []sqlc_queries.Attachment) {
+	if answer == nil {
+		return
+	}
+	json.NewEncoder(w).Encode(ChatCompletionResponse{
+		ID:     answer.AnswerId,
+		Object: "chat.completion",
+		Choices: []Choice{{
+			Message:     openai.ChatCompletionMessage{Content: answer.Answer},
+			Attachments: attachments,
+		}},
+	})
+}
+
+// generateSessionTitle asynchronously updates the session topic with the
+// workspace's title model. Nothing happens when no title model is configured.
 func (h *ChatHandler) generateSessionTitle(chatSession *sqlc_queries.ChatSession, userID int32) {
 	ctx, cancel := context.WithTimeout(context.Background(), sessionTitleGenerationTimeout)
 	defer cancel()
@@ -218,13 +271,8 @@ func (h *ChatHandler) generateSessionTitle(chatSession *sqlc_queries.ChatSession
 		return
 	}
 
-	model := "gemini-2.0-flash"
-	if _, err := h.sessionSvc.ChatModelByName(ctx, model); err != nil {
-		return
-	}
-
-	genTitle, err := provider.GenerateChatTitle(ctx, model, chatText.String())
-	if err != nil || genTitle == "" {
+	genTitle := h.auxSvc.GenerateTitle(ctx, *chatSession, chatText.String())
+	if genTitle == "" {
 		return
 	}
 
@@ -267,3 +315,58 @@ func (h *ChatHandler) sendSuggestedQuestionsStream(w http.ResponseWriter, answer
 	fmt.Fprintf(w, "data: %v\n\n", string(data))
 	flusher.Flush()
 }
+
+// saveGeneratedImages stores the images of an image model's answer as files
+// of the answer message and, when streaming, sends them as an SSE event. It
+// returns the saved files for the non-streaming response. The answer is saved
+// already, so failures are only logged.
+func (h *ChatHandler) saveGeneratedImages(ctx context.Context, w http.ResponseWriter, chatSession sqlc_queries.ChatSession, answer *models.LLMAnswer, stream bool) []sqlc_queries.Attachment {
+	if len(answer.Images) == 0 {
+		return nil
+	}
+	attachments, err := h.chatfileService.SaveGeneratedImages(ctx, chatSession, answer.AnswerId, answer.Images)
+	if err != nil {
+		slog.Error("Failed to save generated images", "session", chatSession.Uuid, "message", answer.AnswerId, "error", err)
+	}
+	if !stream || len(attachments) == 0 {
+		return attachments
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return attachments
+	}
+	response := map[string]interface{}{
+		"id":     answer.AnswerId,
+		"object": "chat.completion.chunk",
+		"choices": []map[string]interface{}{{
+			"index": 0,
+			"delta": map[string]interface{}{
+				"content":     "",
+				"attachments": attachments,
+			},
+			"finish_reason": nil,
+		}},
+	}
+	data, _ := json.Marshal(response)
+	fmt.Fprintf(w, "data: %v\n\n", string(data))
+	flusher.Flush()
+	return attachments
+}
+
+// regenerateSession returns the session to regenerate the answer chatUuid
+// with. An image answer is made again by its image model, which an /image
+// command chose for that turn only.
+func (h *ChatHandler) regenerateSession(ctx context.Context, chatSession *sqlc_queries.ChatSession, chatUuid string) *sqlc_queries.ChatSession {
+	message, err := h.service.Q().GetChatMessageByUUID(ctx, chatUuid)
+	if err != nil || message.Model == "" || message.Model == chatSession.Model {
+		return chatSession
+	}
+	chatModel, err := h.sessionSvc.ChatModelByName(ctx, message.Model)
+	if err != nil || chatModel.ApiType != "image" {
+		return chatSession
+	}
+	turnSession := *chatSession
+	turnSession.Model = chatModel.Name
+	return &turnSession
+}
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/gorilla/mux"
+	"github.com/swuecho/chat_backend/provider"
 	"github.com/swuecho/chat_backend/sqlc_queries"
 	"gotest.tools/v3/assert"
 )
@@ -17,7 +18,7 @@ func TestChatSnapshot(t *testing.T) {
 	const snapshotPath = "/uuid/chat_snapshot/%s"
 
 	q := sqlc_queries.New(testDB)
-	h := NewChatSnapshotHandler(q)
+	h := NewChatSnapshotHandler(q, provider.Config{})
 
 	router := mux.NewRouter()
 	h.Register(router)
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	openai "github.com/sashabaranov/go-openai"
 	"log/slog"
@@ -12,6 +13,7 @@ import (
 	"github.com/swuecho/chat_backend/dto"
 	"github.com/swuecho/chat_backend/models"
 	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
 )
 
 // --- Request types used by chat handlers ---
@@ -45,6 +47,7 @@ type ChatCompletionResponse struct {
 
 type Choice struct {
 	Message      openai.ChatCompletionMessage `json:"message"`
+	Attachments  []sqlc_queries.Attachment    `json:"attachments,omitempty"`
 	FinishReason any                          `json:"finish_reason"`
 	Index        int                          `json:"index"`
 }
@@ -75,19 +78,7 @@ func (h *ChatHandler) ChatBotCompletionHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	var session sqlc_queries.ChatSession
-	if err := json.Unmarshal(chatSnapshot.Session, &session); err != nil {
-		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithDetail("Failed to deserialize chat session").WithDebugInfo(err.Error()))
-		return
-	}
-
-	var messages []dto.SimpleChatMessage
-	if err := json.Unmarshal(chatSnapshot.Conversation, &messages); err != nil {
-		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithDetail("Failed to deserialize conversation").WithDebugInfo(err.Error()))
-		return
-	}
-
-	genBotAnswer(ctx, h, w, session, messages, req.SnapshotUuid, req.Message, userID, req.Stream)
+	genBotAnswer(ctx, h, w, chatSnapshot, nil, "", req.Message, userID, req.Stream)
 }
 
 // ChatCompletionHandler handles regular chat completion with streaming support.
@@ -116,27 +107,59 @@ func (h *ChatHandler) ChatCompletionHandler(w http.ResponseWriter, r *http.Reque
 
 // genAnswer orchestrates the full chat completion flow.
 func genAnswer(h *ChatHandler, w http.ResponseWriter, ctx context.Context, sessionUuid, chatUuid, question string, userID int32, streamOutput bool) {
-	chatSession, _, baseURL, ok := h.validateChatSession(ctx, w, sessionUuid)
+	chatSession, _, ok := h.validateChatSession(ctx, w, sessionUuid)
 	if !ok {
 		return
 	}
 	slog.Info("Processing chat session", "sessionUUID", chatSession.Uuid, "userID", userID, "model", chatSession.Model)
 
-	if !h.handlePromptCreation(ctx, w, chatSession, chatUuid, question, userID, baseURL) {
+	turnSession, ok := h.handlePromptCreation(ctx, w, chatSession, chatUuid, question, userID)
+	if !ok {
 		return
 	}
 
-	h.generateAndSaveAnswer(ctx, w, chatSession, chatUuid, userID, baseURL, streamOutput)
+	h.generateAndSaveAnswer(ctx, w, turnSession, chatUuid, userID, streamOutput)
 }
 
-// genBotAnswer generates a bot answer from a snapshot conversation.
-func genBotAnswer(ctx context.Context, h *ChatHandler, w http.ResponseWriter, session sqlc_queries.ChatSession, messages []dto.SimpleChatMessage, snapshotUuid, question string, userID int32, streamOutput bool) {
+// genBotAnswer generates an answer from the current version of a chatbot.
+// history holds earlier turns of a public visitor and is replayed after the bot's conversation.
+func genBotAnswer(ctx context.Context, h *ChatHandler, w http.ResponseWriter, bot sqlc_queries.ChatSnapshot, history []models.Message, visitorID, question string, userID int32, streamOutput bool) {
+	var session sqlc_queries.ChatSession
+	if err := json.Unmarshal(bot.Session, &session); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithDetail("Failed to deserialize chat session").WithDebugInfo(err.Error()))
+		return
+	}
+
+	var messages []dto.SimpleChatMessage
+	if err := json.Unmarshal(bot.Conversation, &messages); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithDetail("Failed to deserialize conversation").WithDebugInfo(err.Error()))
+		return
+	}
+
 	if _, err := h.sessionSvc.ChatModelByName(ctx, session.Model); err != nil {
 		dto.RespondWithAPIError(w, dto.ErrResourceNotFound("Chat model: "+session.Model).WithDebugInfo(err.Error()))
 		return
 	}
+	// Public routes skip RateLimitByUserID, so a visitor's question is checked
+	// against all of the owner's quotas here.
+	if visitorID != "" {
+		if err := h.checkUserModelAccess(ctx, w.Header(), session, userID); err != nil {
+			dto.RespondWithAPIError(w, dto.WrapError(err, ""))
+			return
+		}
+	}
+
+	knowledge, err := h.botVersionSvc.Knowledge(ctx, bot)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to load chatbot knowledge"))
+		return
+	}
 
 	msgs := simpleChatMessagesToMessages(messages)
+	if knowledge != "" && len(msgs) > 0 {
+		msgs[0].Content = strings.TrimRight(msgs[0].Content, "\n") + "\n\n" + knowledge
+	}
+	msgs = append(msgs, history...)
 	msgs = append(msgs, models.Message{Role: "user", Content: question})
 
 	model := h.chooseChatModel(ctx, session, msgs)
@@ -145,10 +168,15 @@ func genBotAnswer(ctx context.Context, h *ChatHandler, w http.ResponseWriter, se
 		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to generate answer"))
 		return
 	}
+	if !streamOutput {
+		writeChatCompletion(w, LLMAnswer, nil)
+	}
 
 	if _, err := h.sessionSvc.CreateBotAnswerHistory(ctx, sqlc_queries.CreateBotAnswerHistoryParams{
-		BotUuid:    snapshotUuid,
+		BotUuid:    bot.Uuid,
 		UserID:     userID,
+		VisitorID:  visitorID,
+		BotVersion: bot.BotVersion,
 		Prompt:     question,
 		Answer:     LLMAnswer.Answer,
 		Model:      session.Model,
@@ -156,6 +184,11 @@ func genBotAnswer(ctx context.Context, h *ChatHandler, w http.ResponseWriter, se
 	}); err != nil {
 		slog.Info("Failed to save bot answer history", "error", err)
 	}
+	// Bot answers are not chat messages, so their usage is recorded for the quotas.
+	tokens := int32(len(question)+len(LLMAnswer.Answer)) / dto.TokenEstimateRatio
+	if err := h.quotaSvc.RecordUsage(ctx, userID, session.WorkspaceID, session.Model, svc.UsageSourceBot, tokens); err != nil {
+		slog.Warn("Failed to record bot usage", "error", err)
+	}
 
 	if !isTest(msgs) {
 		h.service.LogChat(session, msgs, LLMAnswer.Answer)
@@ -164,12 +197,13 @@ func genBotAnswer(ctx context.Context, h *ChatHandler, w http.ResponseWriter, se
 
 // regenerateAnswer regenerates the last assistant response.
 func regenerateAnswer(h *ChatHandler, w http.ResponseWriter, ctx context.Context, sessionUuid, chatUuid string, stream bool) {
-	chatSession, _, _, ok := h.validateChatSession(ctx, w, sessionUuid)
+	chatSession, _, ok := h.validateChatSession(ctx, w, sessionUuid)
 	if !ok {
 		return
 	}
+	chatSession = h.regenerateSession(ctx, chatSession, chatUuid)
 
-	msgs, err := h.service.GetAskMessages(*chatSession, chatUuid, true)
+	msgs, err := h.service.GetAskMessages(ctx, *chatSession, chatUuid, true)
 	if err != nil {
 		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithDetail("Failed to get chat messages").WithDebugInfo(err.Error()))
 		return
@@ -190,8 +224,13 @@ func regenerateAnswer(h *ChatHandler, w http.ResponseWriter, ctx context.Context
 		return
 	}
 
+	attachments := h.saveGeneratedImages(ctx, w, *chatSession, LLMAnswer, stream)
+	if !stream {
+		writeChatCompletion(w, LLMAnswer, attachments)
+	}
+
 	if chatSession.ExploreMode {
-		suggested := h.service.GenerateSuggestedQuestions(LLMAnswer.Answer, msgs)
+		suggested := h.service.GenerateSuggestedQuestions(ctx, *chatSession, LLMAnswer.Answer, msgs)
 		if len(suggested) > 0 {
 			if questionsJSON, err := json.Marshal(suggested); err == nil {
 				h.service.UpdateChatMessageSuggestions(ctx, chatUuid, questionsJSON)
@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+// CredentialHandler manages encrypted provider keys: shared keys for admins,
+// and per-workspace keys (bring your own key) for workspace members.
+type CredentialHandler struct {
+	service   *svc.CredentialService
+	wsService *svc.ChatWorkspaceService
+}
+
+func NewCredentialHandler(q *sqlc_queries.Queries) *CredentialHandler {
+	return &CredentialHandler{
+		service:   svc.NewCredentialService(q),
+		wsService: svc.NewChatWorkspaceService(q),
+	}
+}
+
+func (h *CredentialHandler) Register(router *mux.Router) {
+	router.HandleFunc("/workspaces/{uuid}/credentials", h.ListWorkspaceCredentials).Methods(http.MethodGet)
+	router.HandleFunc("/workspaces/{uuid}/credentials/{model}", h.SetWorkspaceCredential).Methods(http.MethodPut)
+	router.HandleFunc("/workspaces/{uuid}/credentials/{model}", h.DeleteWorkspaceCredential).Methods(http.MethodDelete)
+}
+
+func (h *CredentialHandler) RegisterAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/credentials", h.ListCredentials).Methods(http.MethodGet)
+	router.HandleFunc("/credentials", h.CreateCredential).Methods(http.MethodPost)
+	router.HandleFunc("/credentials/rewrap", h.RewrapCredentials).Methods(http.MethodPost)
+	router.HandleFunc("/credentials/{id}", h.UpdateCredential).Methods(http.MethodPut)
+	router.HandleFunc("/credentials/{id}", h.DeleteCredential).Methods(http.MethodDelete)
+	router.HandleFunc("/chat_models/{id}/credential", h.SetModelCredential).Methods(http.MethodPut)
+}
+
+func (h *CredentialHandler) ListCredentials(w http.ResponseWriter, r *http.Request) {
+	creds, err := h.service.ListShared(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to list credentials"))
+		return
+	}
+	responses := make([]dto.CredentialResponse, 0, len(creds))
+	for _, c := range creds {
+		responses = append(responses, credentialToResponse(c))
+	}
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *CredentialHandler) CreateCredential(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+	var req dto.CredentialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+	cred, err := h.service.Create(r.Context(), req.Name, req.Secret, sql.NullInt32{}, userID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to create credential"))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(credentialToResponse(cred))
+}
+
+// UpdateCredential renames a credential and, when a secret is given, rotates it.
+func (h *CredentialHandler) UpdateCredential(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseCredentialID(w, r)
+	if !ok {
+		return
+	}
+	var req dto.CredentialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+	cred, err := h.service.Update(r.Context(), id, req.Name, req.Secret)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to update credential"))
+		return
+	}
+	json.NewEncoder(w).Encode(credentialToResponse(cred))
+}
+
+func (h *CredentialHandler) DeleteCredential(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseCredentialID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to delete credential"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RewrapCredentials moves every credential to the primary master key, after
+// which older keys can be dropped from CREDENTIAL_MASTER_KEY.
+func (h *CredentialHandler) RewrapCredentials(w http.ResponseWriter, r *http.Request) {
+	n, err := h.service.Rewrap(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to rewrap credentials"))
+		return
+	}
+	json.NewEncoder(w).Encode(dto.RewrapCredentialsResponse{Rewrapped: n})
+}
+
+func (h *CredentialHandler) SetModelCredential(w http.ResponseWriter, r *http.Request) {
+	modelID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid chat model ID").WithDebugInfo(err.Error()))
+		return
+	}
+	var req dto.ModelCredentialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+	var credentialID sql.NullInt32
+	if req.CredentialID != nil {
+		credentialID = sql.NullInt32{Int32: *req.CredentialID, Valid: true}
+	}
+	chatModel, err := h.service.SetModelCredential(r.Context(), int32(modelID), credentialID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to set model credential"))
+		return
+	}
+	json.NewEncoder(w).Encode(chatModel)
+}
+
+func (h *CredentialHandler) ListWorkspaceCredentials(w http.ResponseWriter, r *http.Request) {
+	workspace, ok := h.workspace(w, r)
+	if !ok {
+		return
+	}
+	rows, err := h.service.ListWorkspace(r.Context(), workspace.ID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to list workspace credentials"))
+		return
+	}
+	responses := make([]dto.WorkspaceCredentialResponse, 0, len(rows))
+	for _, row := range rows {
+		resp := dto.WorkspaceCredentialResponse{
+			Model: row.ModelName, Hint: row.Hint, CreatedAt: row.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		}
+		if row.RotatedAt.Valid {
+			resp.RotatedAt = row.RotatedAt.Time.Format("2006-01-02T15:04:05Z")
+		}
+		responses = append(responses, resp)
+	}
+	json.NewEncoder(w).Encode(responses)
+}
+
+// SetWorkspaceCredential stores the workspace's own key for a model, used
+// instead of the key configured by admins.
+func (h *CredentialHandler) SetWorkspaceCredential(w http.ResponseWriter, r *http.Request) {
+	workspace, ok := h.workspace(w, r)
+	if !ok {
+		return
+	}
+	userID, _ := getUserID(r.Context())
+	var req dto.WorkspaceCredentialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+	if err := h.service.SetWorkspaceKey(r.Context(), workspace.ID, mux.Vars(r)["model"], req.Secret, userID); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to save workspace credential"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CredentialHandler) DeleteWorkspaceCredential(w http.ResponseWriter, r *http.Request) {
+	workspace, ok := h.workspace(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteWorkspaceKey(r.Context(), workspace.ID, mux.Vars(r)["model"]); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to delete workspace credential"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CredentialHandler) workspace(w http.ResponseWriter, r *http.Request) (sqlc_queries.ChatWorkspace, bool) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return sqlc_queries.ChatWorkspace{}, false
+	}
+	return resolveWorkspace(w, r.Context(), h.wsService, mux.Vars(r)["uuid"], userID)
+}
+
+func parseCredentialID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid credential ID").WithDebugInfo(err.Error()))
+		return 0, false
+	}
+	return int32(id), true
+}
+
+func credentialToResponse(c sqlc_queries.ProviderCredential) dto.CredentialResponse {
+	resp := dto.CredentialResponse{
+		ID: c.ID, Name: c.Name, Hint: c.Hint,
+		CreatedAt: c.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt: c.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if c.RotatedAt.Valid {
+		resp.RotatedAt = c.RotatedAt.Time.Format("2006-01-02T15:04:05Z")
+	}
+	return resp
+}
@@ -6,14 +6,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"log/slog"
+	"mime"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/swuecho/chat_backend/config"
 	"github.com/swuecho/chat_backend/dto"
 	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/storage"
 	"github.com/swuecho/chat_backend/svc"
 )
 
@@ -28,13 +29,14 @@ func NewChatFileHandler(sqlc_q *sqlc_queries.Queries) *ChatFileHandler {
 func (h *ChatFileHandler) Register(router *mux.Router) {
 	router.HandleFunc("/upload", h.ReceiveFile).Methods(http.MethodPost)
 	router.HandleFunc("/chat_file/{uuid}/list", h.ChatFilesBySessionUUID).Methods(http.MethodGet)
+	router.HandleFunc("/chat_file/{id}", h.UpdateFile).Methods(http.MethodPut)
 	router.HandleFunc("/download/{id}", h.DownloadFile).Methods(http.MethodGet)
+	router.HandleFunc("/download/{id}/thumbnail", h.DownloadThumbnail).Methods(http.MethodGet)
 	router.HandleFunc("/download/{id}", h.DeleteFile).Methods(http.MethodDelete)
 }
 
-const maxUploadSize = 32 << 20 // 32MB
-
 func (h *ChatFileHandler) ReceiveFile(w http.ResponseWriter, r *http.Request) {
+	maxUploadSize := config.Current().UPLOAD.MAX_FILE_SIZE
 	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
 
 	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
@@ -67,19 +69,14 @@ func (h *ChatFileHandler) ReceiveFile(w http.ResponseWriter, r *http.Request) {
 		mimeType = "application/octet-stream"
 	}
 
-	var buf bytes.Buffer
-	if _, err := io.Copy(&buf, file); err != nil {
-		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithDetail("failed to read file data").WithDebugInfo(err.Error()))
-		return
-	}
-
 	chatFile, err := h.service.CreateChatUpload(r.Context(), sqlc_queries.CreateChatFileParams{
 		ChatSessionUuid: sessionUUID,
 		UserID:          userID,
 		Name:            header.Filename,
-		Data:            buf.Bytes(),
 		MimeType:        mimeType,
-	})
+		// a detached file is only sent to the model with the next question
+		Detached: r.FormValue("detached") == "true",
+	}, file)
 	if err != nil {
 		dto.RespondWithAPIError(w, dto.WrapError(err, "failed to create chat file record"))
 		return
@@ -90,34 +87,86 @@ func (h *ChatFileHandler) ReceiveFile(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{
 		"url":  fmt.Sprintf("/download/%d", chatFile.ID),
 		"name": header.Filename,
-		"type": mimeType,
-		"size": fmt.Sprintf("%d", header.Size),
+		"type": chatFile.MimeType,
+		"size": fmt.Sprintf("%d", chatFile.Size),
 	})
 }
 
-func (h *ChatFileHandler) DownloadFile(w http.ResponseWriter, r *http.Request) {
-	fileID := mux.Vars(r)["id"]
-	fileIdInt, err := strconv.ParseInt(fileID, 10, 32)
+// ownChatFile looks up the file named in the request for the caller. Files of
+// other users are reported as not found, so their IDs reveal nothing.
+func (h *ChatFileHandler) ownChatFile(w http.ResponseWriter, r *http.Request) (sqlc_queries.ChatFile, bool) {
+	fileIdInt, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
 	if err != nil {
 		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("invalid file ID"))
-		return
+		return sqlc_queries.ChatFile{}, false
+	}
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return sqlc_queries.ChatFile{}, false
 	}
 
 	file, err := h.service.GetChatFile(r.Context(), int32(fileIdInt))
+	if err == nil && file.UserID != userID {
+		err = sql.ErrNoRows
+	}
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			dto.RespondWithAPIError(w, dto.ErrChatFileNotFound.WithMessage(fmt.Sprintf("file ID %d not found", fileIdInt)))
 		} else {
 			dto.RespondWithAPIError(w, dto.WrapError(err, "failed to get chat file"))
 		}
+		return sqlc_queries.ChatFile{}, false
+	}
+	return file, true
+}
+
+// DownloadFile streams a file of the caller, answering Range requests with
+// partial content.
+func (h *ChatFileHandler) DownloadFile(w http.ResponseWriter, r *http.Request) {
+	file, ok := h.ownChatFile(w, r)
+	if !ok {
+		return
+	}
+
+	content, err := h.service.OpenChatFile(r.Context(), file)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			dto.RespondWithAPIError(w, dto.ErrChatFileNotFound.WithMessage(fmt.Sprintf("contents of file ID %d not found", file.ID)))
+		} else {
+			dto.RespondWithAPIError(w, dto.WrapError(err, "failed to open chat file"))
+		}
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", file.MimeType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+	if file.BlobHash.Valid {
+		// contents never change under a hash, so it makes a strong validator
+		w.Header().Set("ETag", `"`+file.BlobHash.String+`"`)
+	}
+	http.ServeContent(w, r, file.Name, file.CreatedAt, content)
+}
+
+// DownloadThumbnail returns a small preview of an image file of the caller.
+func (h *ChatFileHandler) DownloadThumbnail(w http.ResponseWriter, r *http.Request) {
+	file, ok := h.ownChatFile(w, r)
+	if !ok {
 		return
 	}
 
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", file.Name))
-	w.Header().Set("Content-Length", strconv.Itoa(len(file.Data)))
-	if _, err := w.Write(file.Data); err != nil {
-		slog.Info("Failed to write file data", "error", err)
+	thumbnail, mimeType, err := h.service.Thumbnail(r.Context(), file)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "failed to make thumbnail"))
+		return
 	}
+
+	w.Header().Set("Content-Type", mimeType)
+	if file.BlobHash.Valid {
+		w.Header().Set("ETag", `"`+file.BlobHash.String+`-thumbnail"`)
+	}
+	http.ServeContent(w, r, "", file.CreatedAt, bytes.NewReader(thumbnail))
 }
 
 func (h *ChatFileHandler) DeleteFile(w http.ResponseWriter, r *http.Request) {
@@ -152,6 +201,42 @@ func (h *ChatFileHandler) DeleteFile(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// UpdateFile sets whether a file is detached from the turns after its message.
+func (h *ChatFileHandler) UpdateFile(w http.ResponseWriter, r *http.Request) {
+	fileIdInt, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("invalid file ID"))
+		return
+	}
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+	var req struct {
+		Detached bool `json:"detached"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+
+	file, err := h.service.SetDetached(r.Context(), int32(fileIdInt), userID, req.Detached)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "failed to update chat file"))
+		return
+	}
+	json.NewEncoder(w).Encode(sqlc_queries.Attachment{
+		ID:       file.ID,
+		Name:     file.Name,
+		MimeType: file.MimeType,
+		Size:     file.Size,
+		Url:      fmt.Sprintf("/download/%d", file.ID),
+		Detached: file.Detached,
+		Source:   file.Source,
+	}.WithThumbnail())
+}
+
 func (h *ChatFileHandler) ChatFilesBySessionUUID(w http.ResponseWriter, r *http.Request) {
 	sessionUUID := mux.Vars(r)["uuid"]
 	userID, err := getUserID(r.Context())
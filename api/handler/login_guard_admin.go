@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+// LoginGuardHandler lets admins review failed logins and lift lockouts.
+type LoginGuardHandler struct {
+	guard *svc.LoginGuardService
+}
+
+func NewLoginGuardHandler(guard *svc.LoginGuardService) *LoginGuardHandler {
+	return &LoginGuardHandler{guard: guard}
+}
+
+func (h *LoginGuardHandler) Register(router *mux.Router) {
+	router.HandleFunc("/login_attempts", h.ListLoginAttempts).Methods(http.MethodGet)
+	router.HandleFunc("/login_lockouts", h.ListLockouts).Methods(http.MethodGet)
+	router.HandleFunc("/login_lockouts/unlock", h.Unlock).Methods(http.MethodPost)
+}
+
+// ListLoginAttempts returns a page of failed logins.
+// Query params: page, size, email, ip.
+func (h *LoginGuardHandler) ListLoginAttempts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page := int32(1)
+	size := int32(20)
+	if p, err := strconv.Atoi(query.Get("page")); err == nil && p > 0 {
+		page = int32(p)
+	}
+	if s, err := strconv.Atoi(query.Get("size")); err == nil && s > 0 && s <= 100 {
+		size = int32(s)
+	}
+
+	pagination := dto.Pagination{Page: page, Size: size}
+	attempts, total, err := h.guard.ListAttempts(r.Context(), query.Get("email"), query.Get("ip"), size, pagination.Offset())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to list login attempts"))
+		return
+	}
+
+	data := make([]interface{}, len(attempts))
+	for i, a := range attempts {
+		data[i] = a
+	}
+	pagination.Data = data
+	pagination.Total = total
+
+	json.NewEncoder(w).Encode(pagination)
+}
+
+// ListLockouts returns the accounts and addresses currently locked.
+func (h *LoginGuardHandler) ListLockouts(w http.ResponseWriter, r *http.Request) {
+	locks, err := h.guard.ListLocked(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to list lockouts"))
+		return
+	}
+	responses := make([]dto.LoginLockoutResponse, 0, len(locks))
+	for _, l := range locks {
+		responses = append(responses, dto.LoginLockoutResponse{
+			Key: l.Key, Failures: l.Failures, LockedUntil: l.LockedUntil.Time.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+	json.NewEncoder(w).Encode(responses)
+}
+
+// Unlock clears the failed-login and password reset counters of an email, an address or both.
+func (h *LoginGuardHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	var req dto.UnlockLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+	req.IP = strings.TrimSpace(req.IP)
+	if strings.TrimSpace(req.Email) == "" && req.IP == "" {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("email or ip is required"))
+		return
+	}
+	n, err := h.guard.Unlock(r.Context(), req.Email, req.IP)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to unlock"))
+		return
+	}
+	slog.Info("Login lockout cleared by admin", "email", req.Email, "ip", req.IP, "removed", n, "action", "login_unlock")
+	json.NewEncoder(w).Encode(dto.UnlockLoginResponse{Removed: n})
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/swuecho/chat_backend/auth"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+// MailAdminHandler lets admins invite users and inspect email delivery.
+type MailAdminHandler struct {
+	mail  *svc.MailService
+	users *svc.AuthUserService
+}
+
+func NewMailAdminHandler(mail *svc.MailService, users *svc.AuthUserService) *MailAdminHandler {
+	return &MailAdminHandler{mail: mail, users: users}
+}
+
+func (h *MailAdminHandler) Register(router *mux.Router) {
+	router.HandleFunc("/users/invite", h.InviteUser).Methods(http.MethodPost)
+	router.HandleFunc("/mail_deliveries", h.ListMailDeliveries).Methods(http.MethodGet)
+}
+
+// InviteUser creates an account with an unusable random password and emails a link to set one.
+func (h *MailAdminHandler) InviteUser(w http.ResponseWriter, r *http.Request) {
+	var req dto.InviteUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	if !strings.Contains(req.Email, "@") {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("a valid email is required"))
+		return
+	}
+
+	ctx := r.Context()
+	adminID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+	admin, err := h.users.GetAuthUserByID(ctx, adminID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrResourceNotFound("user").WithDebugInfo(err.Error()))
+		return
+	}
+
+	if _, err := h.users.GetUserByEmail(ctx, req.Email); err == nil {
+		dto.RespondWithAPIError(w, dto.ErrResourceAlreadyExists("user"))
+		return
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to look up user"))
+		return
+	}
+
+	password, err := auth.GenerateRandomPassword()
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithMessage("Failed to generate password").WithDebugInfo(err.Error()))
+		return
+	}
+	hash, err := auth.GeneratePasswordHash(password)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithMessage("Failed to hash password").WithDebugInfo(err.Error()))
+		return
+	}
+
+	user, err := h.users.CreateAuthUser(ctx, sqlc_queries.CreateAuthUserParams{
+		Email: req.Email, Username: req.Email, Password: hash, FirstName: req.FirstName, LastName: req.LastName,
+	})
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to create user"))
+		return
+	}
+	if err := h.mail.SetEmailVerified(ctx, user.ID, false); err != nil {
+		slog.Error("Failed to mark email unverified", "user_id", user.ID, "error", err)
+	}
+
+	if err := h.mail.SendInvite(ctx, user, admin.Email); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrExternalUnavailable.WithMessage("User created but the invitation email failed").WithDebugInfo(err.Error()))
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": user.ID, "email": user.Email})
+}
+
+// ListMailDeliveries returns a page of sent emails.
+// Query params: page, size, status, to.
+func (h *MailAdminHandler) ListMailDeliveries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page := int32(1)
+	size := int32(20)
+	if p, err := strconv.Atoi(query.Get("page")); err == nil && p > 0 {
+		page = int32(p)
+	}
+	if s, err := strconv.Atoi(query.Get("size")); err == nil && s > 0 && s <= 100 {
+		size = int32(s)
+	}
+
+	pagination := dto.Pagination{Page: page, Size: size}
+	deliveries, total, err := h.mail.ListDeliveries(r.Context(), query.Get("status"), query.Get("to"), size, pagination.Offset())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to list mail deliveries"))
+		return
+	}
+
+	data := make([]interface{}, len(deliveries))
+	for i, d := range deliveries {
+		data[i] = d
+	}
+	pagination.Data = data
+	pagination.Total = total
+
+	json.NewEncoder(w).Encode(pagination)
+}
@@ -279,7 +279,7 @@ func (h *ChatMessageHandler) GenerateMoreSuggestions(w http.ResponseWriter, r *h
 
 	chatService := svc.NewChatService(h.service.Q(), h.openAIKey, h.openAIProxy)
 
-	newSuggestions := chatService.GenerateSuggestedQuestions(message.Content, msgs)
+	newSuggestions := chatService.GenerateSuggestedQuestions(r.Context(), session, message.Content, msgs)
 	if len(newSuggestions) == 0 {
 		dto.RespondWithAPIError(w, dto.CreateAPIError(dto.ErrInternalUnexpected, "Failed to generate suggestions", "no suggestions returned"))
 		return
@@ -10,14 +10,16 @@ import (
 	"github.com/samber/lo"
 	"github.com/swuecho/chat_backend/dto"
 	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
 )
 
 type ChatModelHandler struct {
-	db *sqlc_queries.Queries
+	db      *sqlc_queries.Queries
+	service *svc.ChatModelService
 }
 
 func NewChatModelHandler(db *sqlc_queries.Queries) *ChatModelHandler {
-	return &ChatModelHandler{db: db}
+	return &ChatModelHandler{db: db, service: svc.NewChatModelService(db)}
 }
 
 func (h *ChatModelHandler) Register(r *mux.Router) {
@@ -109,20 +111,7 @@ func (h *ChatModelHandler) CreateChatModel(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	apiType := input.ApiType
-	if apiType == "" {
-		apiType = "openai"
-	}
-
-	validApiTypes := map[string]bool{
-		"openai": true, "claude": true, "gemini": true, "ollama": true, "custom": true,
-	}
-	if !validApiTypes[apiType] {
-		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid API type. Valid types are: openai, claude, gemini, ollama, custom"))
-		return
-	}
-
-	chatModel, err := h.db.CreateChatModel(r.Context(), sqlc_queries.CreateChatModelParams{
+	chatModel, err := h.service.Create(r.Context(), sqlc_queries.CreateChatModelParams{
 		Name:                   input.Name,
 		Label:                  input.Label,
 		IsDefault:              input.IsDefault,
@@ -135,10 +124,10 @@ func (h *ChatModelHandler) CreateChatModel(w http.ResponseWriter, r *http.Reques
 		DefaultToken:           2048,
 		OrderNumber:            0,
 		HttpTimeOut:            120,
-		ApiType:                apiType,
+		ApiType:                input.ApiType,
 	})
 	if err != nil {
-		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithDetail("Failed to create chat model").WithDebugInfo(err.Error()))
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to create chat model"))
 		return
 	}
 
@@ -180,20 +169,7 @@ func (h *ChatModelHandler) UpdateChatModel(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	apiType := input.ApiType
-	if apiType == "" {
-		apiType = "openai"
-	}
-
-	validApiTypes := map[string]bool{
-		"openai": true, "claude": true, "gemini": true, "ollama": true, "custom": true,
-	}
-	if !validApiTypes[apiType] {
-		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid API type. Valid types are: openai, claude, gemini, ollama, custom"))
-		return
-	}
-
-	chatModel, err := h.db.UpdateChatModel(r.Context(), sqlc_queries.UpdateChatModelParams{
+	chatModel, err := h.service.Update(r.Context(), sqlc_queries.UpdateChatModelParams{
 		ID:                     int32(id),
 		Name:                   input.Name,
 		Label:                  input.Label,
@@ -208,10 +184,10 @@ func (h *ChatModelHandler) UpdateChatModel(w http.ResponseWriter, r *http.Reques
 		MaxToken:               input.MaxToken,
 		HttpTimeOut:            input.HttpTimeOut,
 		IsEnable:               input.IsEnable,
-		ApiType:                apiType,
+		ApiType:                input.ApiType,
 	})
 	if err != nil {
-		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithDetail("Failed to update chat model").WithDebugInfo(err.Error()))
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to update chat model"))
 		return
 	}
 
@@ -233,9 +209,7 @@ func (h *ChatModelHandler) DeleteChatModel(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := h.db.DeleteChatModel(r.Context(), sqlc_queries.DeleteChatModelParams{
-		ID: int32(id), UserID: userID,
-	}); err != nil {
+	if err := h.service.Delete(r.Context(), int32(id), userID); err != nil {
 		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithDetail("Failed to delete chat model").WithDebugInfo(err.Error()))
 		return
 	}
@@ -13,14 +13,16 @@ import (
 	"github.com/samber/lo"
 	"github.com/swuecho/chat_backend/dto"
 	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
 )
 
 type UserChatModelPrivilegeHandler struct {
-	db *sqlc_queries.Queries
+	db      *sqlc_queries.Queries
+	service *svc.UserChatModelPrivilegeService
 }
 
 func NewUserChatModelPrivilegeHandler(db *sqlc_queries.Queries) *UserChatModelPrivilegeHandler {
-	return &UserChatModelPrivilegeHandler{db: db}
+	return &UserChatModelPrivilegeHandler{db: db, service: svc.NewUserChatModelPrivilegeService(db)}
 }
 
 func (h *UserChatModelPrivilegeHandler) Register(r *mux.Router) {
@@ -103,7 +105,7 @@ func (h *UserChatModelPrivilegeHandler) CreateUserChatModelPrivilege(w http.Resp
 		return
 	}
 
-	userChatModelPrivilege, err := h.db.CreateUserChatModelPrivilege(r.Context(), sqlc_queries.CreateUserChatModelPrivilegeParams{
+	userChatModelPrivilege, err := h.service.Create(r.Context(), sqlc_queries.CreateUserChatModelPrivilegeParams{
 		UserID:      user.ID,
 		ChatModelID: chatModel.ID,
 		RateLimit:   input.RateLimit,
@@ -154,7 +156,7 @@ func (h *UserChatModelPrivilegeHandler) UpdateUserChatModelPrivilege(w http.Resp
 		return
 	}
 
-	userChatModelPrivilege, err := h.db.UpdateUserChatModelPrivilege(r.Context(), sqlc_queries.UpdateUserChatModelPrivilegeParams{
+	userChatModelPrivilege, err := h.service.Update(r.Context(), sqlc_queries.UpdateUserChatModelPrivilegeParams{
 		ID:        int32(id),
 		RateLimit: input.RateLimit,
 		UpdatedBy: userID,
@@ -185,7 +187,7 @@ func (h *UserChatModelPrivilegeHandler) DeleteUserChatModelPrivilege(w http.Resp
 		return
 	}
 
-	if err := h.db.DeleteUserChatModelPrivilege(r.Context(), int32(id)); err != nil {
+	if err := h.service.Delete(r.Context(), int32(id)); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			dto.RespondWithAPIError(w, dto.ErrResourceNotFound("chat model privilege"))
 		} else {
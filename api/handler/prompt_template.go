@@ -0,0 +1,572 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/samber/lo"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+// PromptTemplateHandler handles the reusable prompt template library.
+type PromptTemplateHandler struct {
+	service        *svc.PromptTemplateService
+	wsService      *svc.ChatWorkspaceService
+	sessionService *svc.ChatSessionService
+	activeSession  *svc.UserActiveChatSessionService
+	q              *sqlc_queries.Queries
+}
+
+func NewPromptTemplateHandler(q *sqlc_queries.Queries) *PromptTemplateHandler {
+	return &PromptTemplateHandler{
+		service:        svc.NewPromptTemplateService(q),
+		wsService:      svc.NewChatWorkspaceService(q),
+		sessionService: svc.NewChatSessionService(q),
+		activeSession:  svc.NewUserActiveChatSessionService(q),
+		q:              q,
+	}
+}
+
+func (h *PromptTemplateHandler) Register(router *mux.Router) {
+	router.HandleFunc("/prompt_templates", h.ListPromptTemplates).Methods(http.MethodGet)
+	router.HandleFunc("/prompt_templates", h.CreatePromptTemplate).Methods(http.MethodPost)
+	router.HandleFunc("/prompt_templates/{uuid}", h.GetPromptTemplate).Methods(http.MethodGet)
+	router.HandleFunc("/prompt_templates/{uuid}", h.UpdatePromptTemplate).Methods(http.MethodPut)
+	router.HandleFunc("/prompt_templates/{uuid}", h.DeletePromptTemplate).Methods(http.MethodDelete)
+	router.HandleFunc("/prompt_templates/{uuid}/versions", h.ListPromptTemplateVersions).Methods(http.MethodGet)
+	router.HandleFunc("/prompt_templates/{uuid}/versions/{version}/restore", h.RestorePromptTemplateVersion).Methods(http.MethodPost)
+	router.HandleFunc("/prompt_templates/{uuid}/shares", h.ListPromptTemplateShares).Methods(http.MethodGet)
+	router.HandleFunc("/prompt_templates/{uuid}/shares", h.SharePromptTemplate).Methods(http.MethodPost)
+	router.HandleFunc("/prompt_templates/{uuid}/shares/{userId}", h.UnsharePromptTemplate).Methods(http.MethodDelete)
+	router.HandleFunc("/prompt_templates/{uuid}/sessions", h.CreateSessionFromTemplate).Methods(http.MethodPost)
+}
+
+func (h *PromptTemplateHandler) ListPromptTemplates(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+
+	params := sqlc_queries.ListAccessiblePromptTemplatesParams{
+		UserID:  userID,
+		Tag:     r.URL.Query().Get("tag"),
+		Keyword: r.URL.Query().Get("q"),
+	}
+	if workspaceUUID := r.URL.Query().Get("workspace_uuid"); workspaceUUID != "" {
+		workspace, ok := resolveWorkspace(w, ctx, h.wsService, workspaceUUID, userID)
+		if !ok {
+			return
+		}
+		params.WorkspaceID = workspace.ID
+	}
+
+	rows, err := h.service.ListAccessibleTemplates(ctx, params)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to list prompt templates"))
+		return
+	}
+
+	responses := make([]dto.PromptTemplateResponse, 0, len(rows))
+	for _, row := range rows {
+		t := sqlc_queries.PromptTemplate{
+			ID: row.ID, Uuid: row.Uuid, UserID: row.UserID, WorkspaceID: row.WorkspaceID,
+			Title: row.Title, Description: row.Description, Tags: row.Tags, Visibility: row.Visibility,
+			CurrentVersion: row.CurrentVersion, UsageCount: row.UsageCount, LastUsedAt: row.LastUsedAt,
+			CreatedAt: row.CreatedAt, UpdatedAt: row.UpdatedAt,
+		}
+		v := sqlc_queries.PromptTemplateVersion{Version: row.CurrentVersion, Content: row.Content, Model: row.Model}
+		responses = append(responses, promptTemplateToResponse(t, v, row.WorkspaceUuid, userID))
+	}
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *PromptTemplateHandler) CreatePromptTemplate(w http.ResponseWriter, r *http.Request) {
+	var req dto.PromptTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+	if !validatePromptTemplateRequest(w, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+
+	workspaceUUID := lo.FromPtr(req.WorkspaceUuid)
+	var workspaceID sql.NullInt32
+	if workspaceUUID != "" {
+		workspace, ok := resolveWorkspace(w, ctx, h.wsService, workspaceUUID, userID)
+		if !ok {
+			return
+		}
+		workspaceID = sql.NullInt32{Int32: workspace.ID, Valid: true}
+	}
+
+	t, v, err := h.service.CreateTemplate(ctx, sqlc_queries.CreatePromptTemplateParams{
+		Uuid: uuid.New().String(), UserID: userID, WorkspaceID: workspaceID,
+		Title: req.Title, Description: req.Description, Tags: req.Tags, Visibility: req.Visibility,
+	}, req.Content, req.Model)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to create prompt template"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(promptTemplateToResponse(t, v, workspaceUUID, userID))
+}
+
+func (h *PromptTemplateHandler) GetPromptTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+
+	t, ok := h.loadTemplate(w, ctx, mux.Vars(r)["uuid"], userID, false)
+	if !ok {
+		return
+	}
+	v, err := h.service.GetVersion(ctx, t.ID, t.CurrentVersion)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get prompt template version"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(promptTemplateToResponse(t, v, h.workspaceUUID(ctx, t), userID))
+}
+
+// UpdatePromptTemplate updates metadata and, when the content or model changed, records a new version.
+func (h *PromptTemplateHandler) UpdatePromptTemplate(w http.ResponseWriter, r *http.Request) {
+	var req dto.PromptTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+	if !validatePromptTemplateRequest(w, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+
+	t, ok := h.loadTemplate(w, ctx, mux.Vars(r)["uuid"], userID, true)
+	if !ok {
+		return
+	}
+
+	workspaceID := t.WorkspaceID
+	if req.WorkspaceUuid != nil {
+		workspaceID = sql.NullInt32{}
+		if *req.WorkspaceUuid != "" {
+			workspace, ok := resolveWorkspace(w, ctx, h.wsService, *req.WorkspaceUuid, userID)
+			if !ok {
+				return
+			}
+			workspaceID = sql.NullInt32{Int32: workspace.ID, Valid: true}
+		}
+	}
+
+	t, err = h.service.UpdateTemplate(ctx, sqlc_queries.UpdatePromptTemplateParams{
+		Uuid: t.Uuid, Title: req.Title, Description: req.Description,
+		Tags: req.Tags, Visibility: req.Visibility, WorkspaceID: workspaceID,
+	})
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to update prompt template"))
+		return
+	}
+
+	v, err := h.service.GetVersion(ctx, t.ID, t.CurrentVersion)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get prompt template version"))
+		return
+	}
+	if v.Content != req.Content || v.Model != req.Model {
+		t, v, err = h.service.AddVersion(ctx, t, req.Content, req.Model, userID)
+		if err != nil {
+			dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to create prompt template version"))
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(promptTemplateToResponse(t, v, h.workspaceUUID(ctx, t), userID))
+}
+
+func (h *PromptTemplateHandler) DeletePromptTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+
+	t, ok := h.loadTemplate(w, ctx, mux.Vars(r)["uuid"], userID, true)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteTemplate(ctx, t.Uuid); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to delete prompt template"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *PromptTemplateHandler) ListPromptTemplateVersions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+
+	t, ok := h.loadTemplate(w, ctx, mux.Vars(r)["uuid"], userID, false)
+	if !ok {
+		return
+	}
+	versions, err := h.service.ListVersions(ctx, t.ID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to list prompt template versions"))
+		return
+	}
+
+	responses := make([]dto.PromptTemplateVersionResponse, 0, len(versions))
+	for _, v := range versions {
+		responses = append(responses, dto.PromptTemplateVersionResponse{
+			Version: v.Version, Content: v.Content, Model: v.Model,
+			Variables: svc.ExtractTemplateVariables(v.Content),
+			IsCurrent: v.Version == t.CurrentVersion,
+			CreatedAt: v.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+	json.NewEncoder(w).Encode(responses)
+}
+
+// RestorePromptTemplateVersion makes an earlier version current again.
+func (h *PromptTemplateHandler) RestorePromptTemplateVersion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+
+	version, err := strconv.Atoi(mux.Vars(r)["version"])
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("invalid version"))
+		return
+	}
+
+	t, ok := h.loadTemplate(w, ctx, mux.Vars(r)["uuid"], userID, true)
+	if !ok {
+		return
+	}
+	v, err := h.service.GetVersion(ctx, t.ID, int32(version))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.RespondWithAPIError(w, dto.ErrResourceNotFound("prompt template version"))
+			return
+		}
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get prompt template version"))
+		return
+	}
+	t, err = h.service.SetCurrentVersion(ctx, t.ID, v.Version)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to restore prompt template version"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(promptTemplateToResponse(t, v, h.workspaceUUID(ctx, t), userID))
+}
+
+func (h *PromptTemplateHandler) ListPromptTemplateShares(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+
+	t, ok := h.loadTemplate(w, ctx, mux.Vars(r)["uuid"], userID, true)
+	if !ok {
+		return
+	}
+	shares, err := h.service.ListShares(ctx, t.ID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to list prompt template shares"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(shares)
+}
+
+func (h *PromptTemplateHandler) SharePromptTemplate(w http.ResponseWriter, r *http.Request) {
+	var req dto.SharePromptTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+	if req.Email == "" {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("email is required"))
+		return
+	}
+
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+
+	t, ok := h.loadTemplate(w, ctx, mux.Vars(r)["uuid"], userID, true)
+	if !ok {
+		return
+	}
+	target, err := h.q.GetAuthUserByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.RespondWithAPIError(w, dto.ErrResourceNotFound("user"))
+			return
+		}
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get user by email"))
+		return
+	}
+	if err := h.service.Share(ctx, t.ID, target.ID); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to share prompt template"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *PromptTemplateHandler) UnsharePromptTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+
+	targetID, err := strconv.Atoi(mux.Vars(r)["userId"])
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("invalid user ID"))
+		return
+	}
+
+	t, ok := h.loadTemplate(w, ctx, mux.Vars(r)["uuid"], userID, true)
+	if !ok {
+		return
+	}
+	if err := h.service.Unshare(ctx, t.ID, int32(targetID)); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to unshare prompt template"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateSessionFromTemplate renders the template with the given variables and
+// starts a new session that uses the result as its system prompt.
+func (h *PromptTemplateHandler) CreateSessionFromTemplate(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateSessionFromTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+
+	t, ok := h.loadTemplate(w, ctx, mux.Vars(r)["uuid"], userID, false)
+	if !ok {
+		return
+	}
+
+	version := t.CurrentVersion
+	if req.Version > 0 {
+		version = req.Version
+	}
+	v, err := h.service.GetVersion(ctx, t.ID, version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.RespondWithAPIError(w, dto.ErrResourceNotFound("prompt template version"))
+			return
+		}
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get prompt template version"))
+		return
+	}
+
+	systemPrompt, missing := svc.RenderPromptTemplate(v.Content, req.Variables)
+	if len(missing) > 0 {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("missing template variables: "+strings.Join(missing, ", ")))
+		return
+	}
+
+	var workspace sqlc_queries.ChatWorkspace
+	if req.WorkspaceUuid != "" {
+		workspace, ok = resolveWorkspace(w, ctx, h.wsService, req.WorkspaceUuid, userID)
+		if !ok {
+			return
+		}
+	} else {
+		workspace, err = h.wsService.EnsureDefaultWorkspaceExists(ctx, userID)
+		if err != nil {
+			dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get default workspace"))
+			return
+		}
+	}
+
+	model := req.Model
+	if model == "" {
+		model = v.Model
+	}
+	if model == "" {
+		defaultModel, err := h.q.GetDefaultChatModel(ctx)
+		if err != nil {
+			dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get default chat model"))
+			return
+		}
+		model = defaultModel.Name
+	}
+	topic := req.Topic
+	if topic == "" {
+		topic = t.Title
+	}
+
+	session, err := h.wsService.CreateSessionInWorkspace(ctx, userID, workspace.ID, topic, model, systemPrompt)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to create session from template"))
+		return
+	}
+	if _, err := h.sessionService.EnsureDefaultSystemPrompt(ctx, session.Uuid, userID, systemPrompt); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to create system prompt"))
+		return
+	}
+	if _, err := h.activeSession.UpsertActiveSession(ctx, userID, &workspace.ID, session.Uuid); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to set active session"))
+		return
+	}
+	if err := h.service.RecordUsage(ctx, t.ID); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to record prompt template usage"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"uuid":            session.Uuid,
+		"topic":           session.Topic,
+		"model":           session.Model,
+		"artifactEnabled": session.ArtifactEnabled,
+		"workspaceUuid":   workspace.Uuid,
+		"templateVersion": v.Version,
+		"createdAt":       session.CreatedAt.Format("2006-01-02T15:04:05Z"),
+	})
+}
+
+// --- Helpers ---
+
+// loadTemplate fetches the template and checks that the user may read it, or own it when requireOwner is set.
+func (h *PromptTemplateHandler) loadTemplate(w http.ResponseWriter, ctx context.Context, templateUUID string, userID int32, requireOwner bool) (sqlc_queries.PromptTemplate, bool) {
+	t, err := h.service.GetTemplateByUUID(ctx, templateUUID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.RespondWithAPIError(w, dto.ErrResourceNotFound("prompt template"))
+			return t, false
+		}
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get prompt template"))
+		return t, false
+	}
+	if t.UserID == userID {
+		return t, true
+	}
+
+	allowed := false
+	if !requireOwner {
+		allowed, err = h.service.HasAccess(ctx, templateUUID, userID)
+		if err != nil {
+			dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to check prompt template access"))
+			return t, false
+		}
+	}
+	if !allowed {
+		apiErr := dto.ErrAuthAccessDenied
+		apiErr.Message = "Access denied to prompt template"
+		dto.RespondWithAPIError(w, apiErr)
+		return t, false
+	}
+	return t, true
+}
+
+// workspaceUUID returns the uuid of the template's workspace, or "" for user-scoped templates.
+func (h *PromptTemplateHandler) workspaceUUID(ctx context.Context, t sqlc_queries.PromptTemplate) string {
+	if !t.WorkspaceID.Valid {
+		return ""
+	}
+	workspace, err := h.wsService.GetWorkspaceByID(ctx, t.WorkspaceID.Int32)
+	if err != nil {
+		return ""
+	}
+	return workspace.Uuid
+}
+
+func validatePromptTemplateRequest(w http.ResponseWriter, req *dto.PromptTemplateRequest) bool {
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("title is required"))
+		return false
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("content is required"))
+		return false
+	}
+	switch req.Visibility {
+	case "":
+		req.Visibility = svc.PromptTemplatePrivate
+	case svc.PromptTemplatePrivate, svc.PromptTemplatePublic:
+	default:
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("visibility must be private or public"))
+		return false
+	}
+	if req.Tags == nil {
+		req.Tags = []string{}
+	}
+	return true
+}
+
+func promptTemplateToResponse(t sqlc_queries.PromptTemplate, v sqlc_queries.PromptTemplateVersion, workspaceUUID string, userID int32) dto.PromptTemplateResponse {
+	resp := dto.PromptTemplateResponse{
+		Uuid: t.Uuid, Title: t.Title, Description: t.Description,
+		Content: v.Content, Model: v.Model, Tags: t.Tags,
+		Variables:  svc.ExtractTemplateVariables(v.Content),
+		Visibility: t.Visibility, WorkspaceUuid: workspaceUUID,
+		IsOwner: t.UserID == userID, Version: t.CurrentVersion, UsageCount: t.UsageCount,
+		CreatedAt: t.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt: t.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if t.LastUsedAt.Valid {
+		resp.LastUsedAt = &t.LastUsedAt.Time
+	}
+	return resp
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+// QuotaHandler lets admins manage quota policies.
+type QuotaHandler struct {
+	service *svc.QuotaService
+}
+
+func NewQuotaHandler(service *svc.QuotaService) *QuotaHandler {
+	return &QuotaHandler{service: service}
+}
+
+func (h *QuotaHandler) Register(router *mux.Router) {
+	router.HandleFunc("/quota_policies", h.ListQuotaPolicies).Methods(http.MethodGet)
+	router.HandleFunc("/quota_policies", h.CreateQuotaPolicy).Methods(http.MethodPost)
+	router.HandleFunc("/quota_policies/{id}", h.UpdateQuotaPolicy).Methods(http.MethodPut)
+	router.HandleFunc("/quota_policies/{id}", h.DeleteQuotaPolicy).Methods(http.MethodDelete)
+}
+
+func (h *QuotaHandler) ListQuotaPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.service.ListPolicies(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to list quota policies"))
+		return
+	}
+	responses := make([]dto.QuotaPolicyResponse, 0, len(policies))
+	for _, p := range policies {
+		responses = append(responses, dto.QuotaPolicyResponse{
+			ID: p.ID, Name: p.Name, UserEmail: p.UserEmail, WorkspaceUuid: p.WorkspaceUuid, ChatModelName: p.ChatModelName,
+			Window: p.TimeWindow, Metric: p.Metric, Limit: p.QuotaLimit, Enabled: p.Enabled,
+			UpdatedAt: p.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+		})
+	}
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *QuotaHandler) CreateQuotaPolicy(w http.ResponseWriter, r *http.Request) {
+	in, ok := decodeQuotaPolicy(w, r)
+	if !ok {
+		return
+	}
+	if _, err := h.service.CreatePolicy(r.Context(), in); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to create quota policy"))
+		return
+	}
+	h.ListQuotaPolicies(w, r)
+}
+
+func (h *QuotaHandler) UpdateQuotaPolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid quota policy ID").WithDebugInfo(err.Error()))
+		return
+	}
+	in, ok := decodeQuotaPolicy(w, r)
+	if !ok {
+		return
+	}
+	if _, err := h.service.UpdatePolicy(r.Context(), int32(id), in); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to update quota policy"))
+		return
+	}
+	h.ListQuotaPolicies(w, r)
+}
+
+func (h *QuotaHandler) DeleteQuotaPolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid quota policy ID").WithDebugInfo(err.Error()))
+		return
+	}
+	if err := h.service.DeletePolicy(r.Context(), int32(id)); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to delete quota policy"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeQuotaPolicy(w http.ResponseWriter, r *http.Request) (svc.QuotaPolicyInput, bool) {
+	var req dto.QuotaPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return svc.QuotaPolicyInput{}, false
+	}
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	return svc.QuotaPolicyInput{
+		Name: req.Name, UserEmail: req.UserEmail, WorkspaceUUID: req.WorkspaceUuid, ChatModelName: req.ChatModelName,
+		Window: req.Window, Metric: req.Metric, Limit: req.Limit, Enabled: enabled,
+	}, true
+}
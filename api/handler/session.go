@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/gorilla/mux"
+	"github.com/swuecho/chat_backend/config"
 	"github.com/swuecho/chat_backend/dto"
 	"github.com/swuecho/chat_backend/sqlc_queries"
 	"github.com/swuecho/chat_backend/svc"
@@ -87,10 +88,11 @@ func (h *ChatSessionHandler) createChatSessionByUUID(w http.ResponseWriter, r *h
 		return
 	}
 
+	defaults := config.Current().CHAT
 	session, err := h.service.CreateOrUpdateChatSessionByUUID(ctx, sqlc_queries.CreateOrUpdateChatSessionByUUIDParams{
 		Uuid: req.Uuid, UserID: userID, Topic: req.Topic,
-		MaxLength: dto.DefaultMaxLength, Temperature: dto.DefaultTemperature,
-		Model: req.Model, MaxTokens: dto.DefaultMaxTokens, TopP: dto.DefaultTopP, N: dto.DefaultN,
+		MaxLength: int32(defaults.DEFAULT_MAX_LENGTH), Temperature: defaults.DEFAULT_TEMPERATURE,
+		Model: req.Model, MaxTokens: int32(defaults.DEFAULT_MAX_TOKENS), TopP: defaults.DEFAULT_TOP_P, N: int32(defaults.DEFAULT_N),
 		Debug: false, SummarizeMode: false, ExploreMode: false, ArtifactEnabled: false,
 		WorkspaceID: sql.NullInt32{Int32: defaultWorkspace.ID, Valid: true},
 	})
@@ -122,7 +124,7 @@ func (h *ChatSessionHandler) createOrUpdateChatSessionByUUID(w http.ResponseWrit
 		return
 	}
 	if sessionReq.MaxLength == 0 {
-		sessionReq.MaxLength = dto.DefaultMaxLength
+		sessionReq.MaxLength = int32(config.Current().CHAT.DEFAULT_MAX_LENGTH)
 	}
 
 	ctx := r.Context()
@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/provider"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+// SlashCommandHandler manages the user's slash commands.
+type SlashCommandHandler struct {
+	service   *svc.SlashCommandService
+	wsService *svc.ChatWorkspaceService
+}
+
+// NewSlashCommandHandler creates a new SlashCommandHandler. cfg is used to
+// reach the models of built-in commands.
+func NewSlashCommandHandler(q *sqlc_queries.Queries, cfg provider.Config) *SlashCommandHandler {
+	return &SlashCommandHandler{
+		service:   svc.NewSlashCommandService(q, svc.NewAuxiliaryModelService(q, cfg)),
+		wsService: svc.NewChatWorkspaceService(q),
+	}
+}
+
+func (h *SlashCommandHandler) Register(router *mux.Router) {
+	router.HandleFunc("/slash_commands", h.ListSlashCommands).Methods(http.MethodGet)
+	router.HandleFunc("/slash_commands", h.CreateSlashCommand).Methods(http.MethodPost)
+	router.HandleFunc("/slash_commands/{uuid}", h.UpdateSlashCommand).Methods(http.MethodPut)
+	router.HandleFunc("/slash_commands/{uuid}", h.DeleteSlashCommand).Methods(http.MethodDelete)
+}
+
+func (h *SlashCommandHandler) ListSlashCommands(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+
+	rows, err := h.service.ListSlashCommands(ctx, userID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to list slash commands"))
+		return
+	}
+
+	responses := make([]dto.SlashCommandResponse, 0, len(rows))
+	for _, row := range rows {
+		responses = append(responses, slashCommandToResponse(sqlc_queries.SlashCommand{
+			Uuid: row.Uuid, Name: row.Name, Description: row.Description, Template: row.Template,
+			Model: row.Model, Temperature: row.Temperature, CreatedAt: row.CreatedAt, UpdatedAt: row.UpdatedAt,
+		}, row.WorkspaceUuid))
+	}
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (h *SlashCommandHandler) CreateSlashCommand(w http.ResponseWriter, r *http.Request) {
+	var req dto.SlashCommandRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+	if !validateSlashCommandRequest(w, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+	workspaceID, ok := h.workspaceID(w, ctx, req.WorkspaceUuid, userID)
+	if !ok {
+		return
+	}
+
+	cmd, err := h.service.CreateSlashCommand(ctx, sqlc_queries.CreateSlashCommandParams{
+		Uuid: uuid.New().String(), UserID: userID, WorkspaceID: workspaceID,
+		Name: req.Name, Description: req.Description, Template: req.Template,
+		Model: req.Model, Temperature: nullFloat64(req.Temperature),
+	})
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to create slash command"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(slashCommandToResponse(cmd, req.WorkspaceUuid))
+}
+
+func (h *SlashCommandHandler) UpdateSlashCommand(w http.ResponseWriter, r *http.Request) {
+	var req dto.SlashCommandRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+	if !validateSlashCommandRequest(w, &req) {
+		return
+	}
+
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+	cmd, ok := h.loadOwnCommand(w, ctx, mux.Vars(r)["uuid"], userID)
+	if !ok {
+		return
+	}
+	workspaceID, ok := h.workspaceID(w, ctx, req.WorkspaceUuid, userID)
+	if !ok {
+		return
+	}
+
+	cmd, err = h.service.UpdateSlashCommand(ctx, sqlc_queries.UpdateSlashCommandParams{
+		Uuid: cmd.Uuid, Name: req.Name, Description: req.Description, Template: req.Template,
+		Model: req.Model, Temperature: nullFloat64(req.Temperature), WorkspaceID: workspaceID,
+	})
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to update slash command"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(slashCommandToResponse(cmd, req.WorkspaceUuid))
+}
+
+func (h *SlashCommandHandler) DeleteSlashCommand(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+	cmd, ok := h.loadOwnCommand(w, ctx, mux.Vars(r)["uuid"], userID)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSlashCommand(ctx, cmd.Uuid); err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to delete slash command"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Helpers ---
+
+func (h *SlashCommandHandler) loadOwnCommand(w http.ResponseWriter, ctx context.Context, commandUUID string, userID int32) (sqlc_queries.SlashCommand, bool) {
+	cmd, err := h.service.GetSlashCommandByUUID(ctx, commandUUID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			dto.RespondWithAPIError(w, dto.ErrResourceNotFound("slash command"))
+			return cmd, false
+		}
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get slash command"))
+		return cmd, false
+	}
+	if cmd.UserID != userID {
+		apiErr := dto.ErrAuthAccessDenied
+		apiErr.Message = "Access denied to slash command"
+		dto.RespondWithAPIError(w, apiErr)
+		return cmd, false
+	}
+	return cmd, true
+}
+
+// workspaceID resolves an optional workspace uuid to a nullable id.
+func (h *SlashCommandHandler) workspaceID(w http.ResponseWriter, ctx context.Context, workspaceUUID string, userID int32) (sql.NullInt32, bool) {
+	if workspaceUUID == "" {
+		return sql.NullInt32{}, true
+	}
+	workspace, ok := resolveWorkspace(w, ctx, h.wsService, workspaceUUID, userID)
+	if !ok {
+		return sql.NullInt32{}, false
+	}
+	return sql.NullInt32{Int32: workspace.ID, Valid: true}, true
+}
+
+func validateSlashCommandRequest(w http.ResponseWriter, req *dto.SlashCommandRequest) bool {
+	req.Name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(req.Name), "/"))
+	if !svc.ValidSlashCommandName(req.Name) {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("name must be lowercase letters, digits, '-' or '_'"))
+		return false
+	}
+	if strings.TrimSpace(req.Template) == "" {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("template is required"))
+		return false
+	}
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 2) {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("temperature must be between 0 and 2"))
+		return false
+	}
+	return true
+}
+
+func nullFloat64(v *float64) sql.NullFloat64 {
+	if v == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *v, Valid: true}
+}
+
+func slashCommandToResponse(cmd sqlc_queries.SlashCommand, workspaceUUID string) dto.SlashCommandResponse {
+	resp := dto.SlashCommandResponse{
+		Uuid: cmd.Uuid, Name: cmd.Name, Description: cmd.Description, Template: cmd.Template,
+		Model: cmd.Model, WorkspaceUuid: workspaceUUID,
+		CreatedAt: cmd.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		UpdatedAt: cmd.UpdatedAt.Format("2006-01-02T15:04:05Z"),
+	}
+	if cmd.Temperature.Valid {
+		resp.Temperature = &cmd.Temperature.Float64
+	}
+	return resp
+}
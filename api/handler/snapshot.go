@@ -7,6 +7,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/provider"
 	"github.com/swuecho/chat_backend/sqlc_queries"
 	"github.com/swuecho/chat_backend/svc"
 )
@@ -15,9 +16,11 @@ type ChatSnapshotHandler struct {
 	Service *svc.ChatSnapshotService
 }
 
-func NewChatSnapshotHandler(sqlc_q *sqlc_queries.Queries) *ChatSnapshotHandler {
+// NewChatSnapshotHandler creates a new ChatSnapshotHandler. cfg is used to
+// generate snapshot titles.
+func NewChatSnapshotHandler(sqlc_q *sqlc_queries.Queries, cfg provider.Config) *ChatSnapshotHandler {
 	return &ChatSnapshotHandler{
-		Service: svc.NewChatSnapshotService(sqlc_q),
+		Service: svc.NewChatSnapshotService(sqlc_q, cfg),
 	}
 }
 
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+
+	"github.com/swuecho/chat_backend/config"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
+)
+
+// recordingExtensions name recordings for transcription endpoints, which
+// tell formats apart by file extension.
+var recordingExtensions = map[string]string{
+	"audio/webm":  ".webm",
+	"video/webm":  ".webm",
+	"audio/ogg":   ".ogg",
+	"audio/mpeg":  ".mp3",
+	"audio/mp3":   ".mp3",
+	"audio/mp4":   ".m4a",
+	"audio/x-m4a": ".m4a",
+	"audio/wav":   ".wav",
+	"audio/wave":  ".wav",
+	"audio/x-wav": ".wav",
+	"audio/flac":  ".flac",
+}
+
+// TranscribeHandler turns a voice recording into text with the transcription
+// model of the session's workspace. The recording is kept as a voice chat
+// file attached to the next user message of the session, like an upload.
+// With send=true the text is posted as that message, under chat-uuid, and
+// the answer is returned as by /chat_stream; otherwise the text is returned
+// for the user to edit and send.
+func (h *ChatHandler) TranscribeHandler(w http.ResponseWriter, r *http.Request) {
+	maxUploadSize := config.Current().UPLOAD.MAX_FILE_SIZE
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput(
+			fmt.Sprintf("recording too large, max size is %d bytes", maxUploadSize)))
+		return
+	}
+
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+	send := r.FormValue("send") == "true"
+	chatUuid := r.FormValue("chat-uuid")
+	if send && chatUuid == "" {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("chat-uuid is required to send the transcript"))
+		return
+	}
+	chatSession, _, ok := h.validateChatSession(ctx, w, r.FormValue("session-uuid"))
+	if !ok {
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("failed to read recording").WithDebugInfo(err.Error()))
+		return
+	}
+	defer file.Close()
+	// browsers send parameters such as audio/webm;codecs=opus
+	mimeType, _, _ := mime.ParseMediaType(header.Header.Get("Content-Type"))
+	ext, ok := recordingExtensions[mimeType]
+	if !ok {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("unsupported recording type "+mimeType))
+		return
+	}
+	name := header.Filename
+	if name == "" {
+		name = "recording"
+	}
+	if filepath.Ext(name) == "" {
+		name += ext
+	}
+
+	text, err := h.auxSvc.Transcribe(ctx, *chatSession, userID, file, name, r.FormValue("language"))
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to transcribe recording"))
+		return
+	}
+	if text == "" {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("no speech recognised in the recording"))
+		return
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithMessage("Failed to read recording").WithDebugInfo(err.Error()))
+		return
+	}
+	chatFile, err := h.chatfileService.CreateChatUpload(ctx, sqlc_queries.CreateChatFileParams{
+		ChatSessionUuid: chatSession.Uuid,
+		UserID:          userID,
+		Name:            name,
+		MimeType:        mimeType,
+		Source:          svc.FileSourceVoice,
+	}, file)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to store recording"))
+		return
+	}
+
+	if send {
+		genAnswer(h, w, ctx, chatSession.Uuid, chatUuid, text, userID, r.FormValue("stream") == "true")
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"text": text,
+		"file": sqlc_queries.Attachment{
+			ID:       chatFile.ID,
+			Name:     chatFile.Name,
+			MimeType: chatFile.MimeType,
+			Size:     chatFile.Size,
+			Url:      fmt.Sprintf("/download/%d", chatFile.ID),
+			Source:   chatFile.Source,
+		},
+	})
+}
@@ -1,44 +1,148 @@
 package handler
 
 import (
-	"fmt"
+	"encoding/json"
 	"io"
+	"log/slog"
 	"net/http"
-	"os"
+	"strconv"
 
+	"github.com/gorilla/mux"
 	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/provider"
+	"github.com/swuecho/chat_backend/svc"
 )
 
-// HandleTTSRequest proxies TTS requests to the TTS backend service.
-func HandleTTSRequest(w http.ResponseWriter, r *http.Request) {
-	realHost := fmt.Sprintf("http://%s:%s/api", os.Getenv("TTS_HOST"), os.Getenv("TTS_PORT"))
-	fullURL := realHost + r.URL.String()
+// SpeechHandler reads text and chat messages aloud for signed-in users.
+type SpeechHandler struct {
+	service *svc.SpeechService
+}
+
+func NewSpeechHandler(service *svc.SpeechService) *SpeechHandler {
+	return &SpeechHandler{service: service}
+}
 
-	proxyReq, err := http.NewRequest(r.Method, fullURL, r.Body)
+func (h *SpeechHandler) Register(router *mux.Router) {
+	router.HandleFunc("/tts", h.SpeakText).Methods(http.MethodGet)
+	router.HandleFunc("/tts/settings", h.GetSettings).Methods(http.MethodGet)
+	router.HandleFunc("/tts/settings", h.UpdateSettings).Methods(http.MethodPut)
+	router.HandleFunc("/uuid/chat_messages/{uuid}/speech", h.SpeakMessage).Methods(http.MethodGet)
+}
+
+// SpeakText reads the text query parameter aloud. session-uuid selects the
+// engine of a session's workspace; voice and speed override the user's
+// settings.
+func (h *SpeechHandler) SpeakText(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
 	if err != nil {
-		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithMessage("Error creating proxy request").WithDebugInfo(err.Error()))
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
 		return
 	}
+	query := r.URL.Query()
+	speed, ok := speechSpeed(w, query.Get("speed"))
+	if !ok {
+		return
+	}
+	speech, err := h.service.SpeakText(r.Context(), userID, query.Get("session-uuid"), query.Get("text"), query.Get("voice"), speed)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to synthesize speech"))
+		return
+	}
+	streamSpeech(w, speech)
+}
 
-	for name, values := range r.Header {
-		for _, value := range values {
-			proxyReq.Header.Add(name, value)
-		}
+// SpeakMessage reads a chat message aloud, from the stored speech when the
+// message was read with the same voice before.
+func (h *SpeechHandler) SpeakMessage(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+	query := r.URL.Query()
+	speed, ok := speechSpeed(w, query.Get("speed"))
+	if !ok {
+		return
+	}
+	speech, err := h.service.SpeakMessage(r.Context(), userID, mux.Vars(r)["uuid"], query.Get("voice"), speed)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to synthesize speech"))
+		return
+	}
+	streamSpeech(w, speech)
+}
+
+func (h *SpeechHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+	settings, err := h.service.Settings(r.Context(), userID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to get speech settings"))
+		return
 	}
+	json.NewEncoder(w).Encode(settings)
+}
 
-	resp, err := http.DefaultTransport.RoundTrip(proxyReq)
+func (h *SpeechHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
 	if err != nil {
-		dto.RespondWithAPIError(w, dto.ErrInternalUnexpected.WithMessage("Error sending proxy request").WithDebugInfo(err.Error()))
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+	var settings svc.SpeechSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
 		return
 	}
-	defer resp.Body.Close()
+	settings, err = h.service.SetSettings(r.Context(), userID, settings)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(err, "Failed to update speech settings"))
+		return
+	}
+	json.NewEncoder(w).Encode(settings)
+}
 
-	for name, values := range resp.Header {
-		for _, value := range values {
-			w.Header().Add(name, value)
-		}
+// speechSpeed parses the speed query parameter; empty is 0, the user's setting.
+func speechSpeed(w http.ResponseWriter, value string) (float64, bool) {
+	if value == "" {
+		return 0, true
+	}
+	speed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("invalid speed"))
+		return 0, false
 	}
+	return speed, true
+}
 
-	w.WriteHeader(resp.StatusCode)
-	io.Copy(w, resp.Body)
+// streamSpeech writes audio as it arrives, so playback of a long answer
+// starts before it has all been synthesized.
+func streamSpeech(w http.ResponseWriter, speech provider.Speech) {
+	defer speech.Close()
+	w.Header().Set("Content-Type", speech.MimeType)
+	w.Header().Set("Cache-Control", "private, no-store")
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := speech.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			// the status is sent, so the audio just ends early
+			slog.Warn("speech stream failed", "error", err)
+			return
+		}
+	}
 }
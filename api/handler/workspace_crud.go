@@ -11,6 +11,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/swuecho/chat_backend/dto"
 	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/svc"
 )
 
 func (h *ChatWorkspaceHandler) createWorkspace(w http.ResponseWriter, r *http.Request) {
@@ -190,11 +191,32 @@ func (h *ChatWorkspaceHandler) checkPermission(w http.ResponseWriter, ctx contex
 	return true
 }
 
+// resolveWorkspace looks up a workspace by uuid after checking the user has permission on it.
+func resolveWorkspace(w http.ResponseWriter, ctx context.Context, wsService *svc.ChatWorkspaceService, workspaceUUID string, userID int32) (sqlc_queries.ChatWorkspace, bool) {
+	ok, err := wsService.HasWorkspacePermission(ctx, workspaceUUID, userID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to check workspace permission"))
+		return sqlc_queries.ChatWorkspace{}, false
+	}
+	if !ok {
+		apiErr := dto.ErrAuthAccessDenied
+		apiErr.Message = "Access denied to workspace"
+		dto.RespondWithAPIError(w, apiErr)
+		return sqlc_queries.ChatWorkspace{}, false
+	}
+	workspace, err := wsService.GetWorkspaceByUUID(ctx, workspaceUUID)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to get workspace"))
+		return workspace, false
+	}
+	return workspace, true
+}
+
 func workspaceToResponse(ws sqlc_queries.ChatWorkspace) dto.WorkspaceResponse {
 	return dto.WorkspaceResponse{
 		Uuid: ws.Uuid, Name: ws.Name, Description: ws.Description,
 		Color: ws.Color, Icon: ws.Icon,
-		IsDefault: ws.IsDefault, OrderPosition: ws.OrderPosition,
+		IsDefault: ws.IsDefault, OrderPosition: ws.OrderPosition, ChatLogEnabled: ws.ChatLogEnabled,
 		CreatedAt: ws.CreatedAt.Format("2006-01-02T15:04:05Z"),
 		UpdatedAt: ws.UpdatedAt.Format("2006-01-02T15:04:05Z"),
 	}
@@ -204,7 +226,7 @@ func workspaceRowToResponse(ws sqlc_queries.GetWorkspaceWithSessionCountRow) dto
 	return dto.WorkspaceResponse{
 		Uuid: ws.Uuid, Name: ws.Name, Description: ws.Description,
 		Color: ws.Color, Icon: ws.Icon,
-		IsDefault: ws.IsDefault, OrderPosition: ws.OrderPosition, SessionCount: ws.SessionCount,
+		IsDefault: ws.IsDefault, OrderPosition: ws.OrderPosition, SessionCount: ws.SessionCount, ChatLogEnabled: ws.ChatLogEnabled,
 		CreatedAt: ws.CreatedAt.Format("2006-01-02T15:04:05Z"),
 		UpdatedAt: ws.UpdatedAt.Format("2006-01-02T15:04:05Z"),
 	}
@@ -40,6 +40,35 @@ func (h *ChatWorkspaceHandler) updateWorkspaceOrder(w http.ResponseWriter, r *ht
 	json.NewEncoder(w).Encode(workspaceToResponse(workspace))
 }
 
+// updateWorkspaceChatLog opts the workspace in or out of chat logging.
+func (h *ChatWorkspaceHandler) updateWorkspaceChatLog(w http.ResponseWriter, r *http.Request) {
+	workspaceUUID := mux.Vars(r)["uuid"]
+
+	var req dto.UpdateWorkspaceChatLogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		dto.RespondWithAPIError(w, dto.ErrValidationInvalidInput("Invalid request format").WithDebugInfo(err.Error()))
+		return
+	}
+
+	ctx := r.Context()
+	userID, err := getUserID(ctx)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.ErrAuthInvalidCredentials.WithDebugInfo(err.Error()))
+		return
+	}
+	if !h.checkPermission(w, ctx, workspaceUUID, userID) {
+		return
+	}
+
+	workspace, err := h.wsService.UpdateWorkspaceChatLogEnabled(ctx, workspaceUUID, req.Enabled)
+	if err != nil {
+		dto.RespondWithAPIError(w, dto.WrapError(dto.MapDatabaseError(err), "Failed to update workspace chat log setting"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(workspaceToResponse(workspace))
+}
+
 func (h *ChatWorkspaceHandler) setDefaultWorkspace(w http.ResponseWriter, r *http.Request) {
 	workspaceUUID := mux.Vars(r)["uuid"]
 
@@ -0,0 +1,357 @@
+// Package imaging checks, cleans and resizes uploaded images so that models
+// receive them in a format and size they accept.
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"os/exec"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Image MIME types handled here.
+const (
+	PNG  = "image/png"
+	JPEG = "image/jpeg"
+	GIF  = "image/gif"
+	WebP = "image/webp"
+	HEIC = "image/heic"
+	HEIF = "image/heif"
+)
+
+// ErrUnsupported is returned for images that cannot be decoded, such as HEIC
+// without a convert command.
+var ErrUnsupported = eris.New("unsupported image format")
+
+// Sniff returns the MIME type of data judging by its first bytes, which is
+// enough to tell the image types apart.
+func Sniff(data []byte) string {
+	if len(data) >= 12 && string(data[4:8]) == "ftyp" {
+		switch string(data[8:12]) {
+		case "heic", "heix", "heim", "heis", "hevc", "hevx":
+			return HEIC
+		case "mif1", "msf1", "heif":
+			return HEIF
+		}
+	}
+	mime := http.DetectContentType(data)
+	if i := strings.IndexByte(mime, ';'); i >= 0 {
+		mime = mime[:i]
+	}
+	return mime
+}
+
+// IsImage reports whether mime is an image type handled here.
+func IsImage(mime string) bool {
+	switch mime {
+	case PNG, JPEG, GIF, WebP, HEIC, HEIF:
+		return true
+	}
+	return false
+}
+
+// Options configure decoding.
+type Options struct {
+	// MaxPixels rejects larger images; 0 allows any size.
+	MaxPixels int
+	// ConvertCommand converts HEIC and HEIF images, which have no Go decoder.
+	// It reads the image on standard input and writes PNG or JPEG to standard
+	// output. Empty leaves them unconverted.
+	ConvertCommand string
+}
+
+// Profile describes the images a model accepts.
+type Profile struct {
+	// Name identifies images produced for the profile, so it changes with the limits.
+	Name string
+	// MaxDimension is the longest side in pixels.
+	MaxDimension int
+	// MaxBytes is the largest encoded image.
+	MaxBytes int
+	// Formats are the accepted MIME types.
+	Formats []string
+}
+
+// Profiles of the providers, after their documented limits.
+var (
+	OpenAIProfile = Profile{Name: "openai-2048", MaxDimension: 2048, MaxBytes: 20 << 20, Formats: []string{PNG, JPEG, WebP, GIF}}
+	ClaudeProfile = Profile{Name: "claude-1568", MaxDimension: 1568, MaxBytes: 5 << 20, Formats: []string{JPEG, PNG, GIF, WebP}}
+	GeminiProfile = Profile{Name: "gemini-3072", MaxDimension: 3072, MaxBytes: 15 << 20, Formats: []string{PNG, JPEG, WebP, HEIC, HEIF}}
+	// Thumbnail is the small preview shown in the chat history.
+	Thumbnail = Profile{Name: "thumbnail-256", MaxDimension: 256, MaxBytes: 256 << 10, Formats: []string{JPEG, PNG}}
+)
+
+// ProfileFor returns the profile of a chat_model api_type. OpenAI-compatible
+// types use OpenAIProfile.
+func ProfileFor(apiType string) Profile {
+	switch apiType {
+	case "claude":
+		return ClaudeProfile
+	case "gemini":
+		return GeminiProfile
+	default:
+		return OpenAIProfile
+	}
+}
+
+func (p Profile) accepts(mime string) bool {
+	for _, f := range p.Formats {
+		if f == mime {
+			return true
+		}
+	}
+	return false
+}
+
+// Sanitize prepares an uploaded image of the sniffed type mime for storage.
+// It checks the image decodes within opts.MaxPixels and removes EXIF and
+// other metadata, applying the EXIF orientation first. HEIC and HEIF are
+// converted to JPEG or PNG when opts.ConvertCommand is set; otherwise they
+// are kept with their EXIF and XMP blanked, and rejected if that fails. It
+// returns the cleaned image and its type.
+func Sanitize(ctx context.Context, data []byte, mime string, opts Options) ([]byte, string, error) {
+	if mime == HEIC || mime == HEIF {
+		if opts.ConvertCommand == "" {
+			cleaned, err := stripHEIF(data)
+			if err != nil {
+				return nil, "", err
+			}
+			return cleaned, mime, nil
+		}
+		img, err := decode(ctx, data, mime, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		return encodeFor(img, Profile{Formats: []string{JPEG, PNG}}, 90)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", eris.Wrap(err, "not a valid image")
+	}
+	if err := checkPixels(cfg.Width, cfg.Height, opts); err != nil {
+		return nil, "", err
+	}
+
+	var cleaned []byte
+	switch mime {
+	case JPEG:
+		if jpegOrientation(data) > 1 {
+			img, err := decode(ctx, data, mime, opts)
+			if err != nil {
+				return nil, "", err
+			}
+			return encode(img, JPEG, 92)
+		}
+		cleaned, err = stripJPEG(data)
+	case PNG:
+		cleaned, err = stripPNG(data)
+	case WebP:
+		cleaned, err = stripWebP(data)
+	default:
+		// GIF carries no EXIF
+		return data, mime, nil
+	}
+	if err != nil {
+		// the image decoded, so re-encoding it drops whatever confused the parser
+		img, decodeErr := decode(ctx, data, mime, opts)
+		if decodeErr != nil {
+			return nil, "", decodeErr
+		}
+		return encodeFor(img, Profile{Formats: []string{mime, JPEG, PNG}}, 92)
+	}
+	return cleaned, mime, nil
+}
+
+// Fit returns an image that profile p accepts: converted to a supported
+// format, scaled down to p.MaxDimension and re-encoded until it is within
+// p.MaxBytes. An image that already fits is returned as it is, with changed
+// false.
+func Fit(ctx context.Context, data []byte, mime string, p Profile, opts Options) (out []byte, outMime string, changed bool, err error) {
+	if p.accepts(mime) && len(data) <= p.MaxBytes {
+		if mime == HEIC || mime == HEIF {
+			return data, mime, false, nil
+		}
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err == nil && cfg.Width <= p.MaxDimension && cfg.Height <= p.MaxDimension && jpegOrientation(data) <= 1 {
+			return data, mime, false, nil
+		}
+	}
+
+	img, err := decode(ctx, data, mime, opts)
+	if err != nil {
+		return nil, "", false, err
+	}
+	img = scale(img, p.MaxDimension)
+	quality := 85
+	for i := 0; i < 8; i++ {
+		out, outMime, err = encodeFor(img, p, quality)
+		if err != nil || len(out) <= p.MaxBytes {
+			return out, outMime, err == nil, err
+		}
+		// lower the JPEG quality a little, then the size
+		if outMime == JPEG && quality > 55 {
+			quality -= 15
+		} else {
+			b := img.Bounds()
+			img = scale(img, max(b.Dx(), b.Dy())*3/4)
+		}
+	}
+	return nil, "", false, eris.Errorf("image does not fit in %d bytes", p.MaxBytes)
+}
+
+func checkPixels(width, height int, opts Options) error {
+	if opts.MaxPixels > 0 && width*height > opts.MaxPixels {
+		return eris.Errorf("image is %dx%d, larger than %d pixels", width, height, opts.MaxPixels)
+	}
+	return nil
+}
+
+// decode decodes data of type mime, turned upright by its EXIF orientation.
+func decode(ctx context.Context, data []byte, mime string, opts Options) (image.Image, error) {
+	if mime == HEIC || mime == HEIF {
+		converted, err := convert(ctx, data, opts.ConvertCommand)
+		if err != nil {
+			return nil, err
+		}
+		data = converted
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, eris.Wrap(err, "not a valid image")
+	}
+	if err := checkPixels(cfg.Width, cfg.Height, opts); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, eris.Wrap(err, "not a valid image")
+	}
+	return orient(img, jpegOrientation(data)), nil
+}
+
+// convert runs the convert command on data.
+func convert(ctx context.Context, data []byte, command string) ([]byte, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, ErrUnsupported
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, eris.Wrapf(err, "image convert command failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// scale shrinks img so that neither side exceeds maxSide.
+func scale(img image.Image, maxSide int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if maxSide <= 0 || (w <= maxSide && h <= maxSide) {
+		return img
+	}
+	if w >= h {
+		h = max(1, h*maxSide/w)
+		w = maxSide
+	} else {
+		w = max(1, w*maxSide/h)
+		h = maxSide
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, b, xdraw.Src, nil)
+	return dst
+}
+
+// encodeFor encodes img as PNG when it has transparency and p accepts PNG,
+// and as JPEG otherwise.
+func encodeFor(img image.Image, p Profile, quality int) ([]byte, string, error) {
+	if !opaque(img) && p.accepts(PNG) {
+		return encode(img, PNG, quality)
+	}
+	return encode(img, JPEG, quality)
+}
+
+func encode(img image.Image, mime string, quality int) ([]byte, string, error) {
+	var buf bytes.Buffer
+	var err error
+	if mime == PNG {
+		err = png.Encode(&buf, img)
+	} else {
+		mime = JPEG
+		if !opaque(img) {
+			img = flatten(img)
+		}
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		return nil, "", eris.Wrap(err, "failed to encode image")
+	}
+	return buf.Bytes(), mime, nil
+}
+
+func opaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return true
+}
+
+// flatten draws img on white, since JPEG has no transparency.
+func flatten(img image.Image) image.Image {
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
+
+// orient applies an EXIF orientation (1 to 8) to img.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // flip horizontally
+				dx, dy = w-1-x, y
+			case 3: // turn 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // flip vertically
+				dx, dy = x, h-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // turn clockwise
+				dx, dy = h-1-y, x
+			case 7: // transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // turn counterclockwise
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
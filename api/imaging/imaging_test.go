@@ -0,0 +1,230 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(w, h int, alpha uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: alpha})
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withExif inserts an EXIF segment with orientation and a comment after the
+// start of image marker.
+func withExif(jpg []byte, orientation uint16) []byte {
+	tiff := []byte("II*\x00\x08\x00\x00\x00\x01\x00\x12\x01\x03\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+	binary.LittleEndian.PutUint16(tiff[18:], orientation)
+	exif := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := append([]byte{0xFF, 0xE1, 0, 0}, exif...)
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(exif)+2))
+	comment := []byte{0xFF, 0xFE, 0, 7, 'g', 'p', 's', '!', '!'}
+	out := append([]byte{0xFF, 0xD8}, app1...)
+	out = append(out, comment...)
+	return append(out, jpg[2:]...)
+}
+
+func box(typ string, contents ...[]byte) []byte {
+	out := append(make([]byte, 4), typ...)
+	for _, c := range contents {
+		out = append(out, c...)
+	}
+	binary.BigEndian.PutUint32(out, uint32(len(out)))
+	return out
+}
+
+// heicWithExif returns a HEIC file whose item 1 is EXIF data holding a
+// location and item 2 the image data.
+func heicWithExif() []byte {
+	exif := []byte("\x00\x00\x00\x06Exif\x00\x00GPS 51.5074N")
+	coded := []byte("HEVC image data")
+	ftyp := box("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+	meta := func(exifOffset uint32) []byte {
+		iinf := box("iinf", []byte{0, 0, 0, 0, 0, 2},
+			box("infe", []byte{2, 0, 0, 0, 0, 1, 0, 0}, []byte("Exif\x00")),
+			box("infe", []byte{2, 0, 0, 0, 0, 2, 0, 0}, []byte("hvc1\x00")))
+		loc := make([]byte, 8)
+		binary.BigEndian.PutUint32(loc, exifOffset)
+		binary.BigEndian.PutUint32(loc[4:], uint32(len(exif)))
+		iloc := box("iloc", []byte{0, 0, 0, 0, 0x44, 0x00, 0, 1, 0, 1, 0, 0, 0, 1}, loc)
+		return box("meta", []byte{0, 0, 0, 0}, iinf, iloc)
+	}
+	offset := len(ftyp) + len(meta(0)) + 8 + len(coded)
+	return bytes.Join([][]byte{ftyp, meta(uint32(offset)), box("mdat", coded, exif)}, nil)
+}
+
+func decodeConfig(t *testing.T, data []byte) image.Config {
+	t.Helper()
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestSniff(t *testing.T) {
+	cases := map[string][]byte{
+		PNG:  encodePNG(t, testImage(2, 2, 255)),
+		JPEG: encodeJPEG(t, testImage(2, 2, 255)),
+		HEIC: []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00"),
+		WebP: []byte("RIFF\x00\x00\x00\x00WEBPVP8 "),
+	}
+	for want, data := range cases {
+		if got := Sniff(data); got != want {
+			t.Errorf("Sniff = %q, want %q", got, want)
+		}
+	}
+	if IsImage(Sniff([]byte("<svg></svg>"))) {
+		t.Error("text must not pass as an image")
+	}
+}
+
+func TestSanitizeStripsJPEGMetadata(t *testing.T) {
+	data := withExif(encodeJPEG(t, testImage(40, 20, 255)), 1)
+	if jpegOrientation(data) != 1 {
+		t.Fatal("test image should carry an orientation")
+	}
+	out, mime, err := Sanitize(context.Background(), data, JPEG, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mime != JPEG || bytes.Contains(out, []byte("Exif")) || bytes.Contains(out, []byte("gps!!")) {
+		t.Fatalf("metadata left in %s image", mime)
+	}
+	if cfg := decodeConfig(t, out); cfg.Width != 40 || cfg.Height != 20 {
+		t.Fatalf("unexpected size %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
+func TestSanitizeAppliesOrientation(t *testing.T) {
+	data := withExif(encodeJPEG(t, testImage(40, 20, 255)), 6)
+	out, _, err := Sanitize(context.Background(), data, JPEG, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("Exif")) {
+		t.Fatal("EXIF left in image")
+	}
+	if cfg := decodeConfig(t, out); cfg.Width != 20 || cfg.Height != 40 {
+		t.Fatalf("expected the image turned upright to 20x40, got %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
+func TestSanitizeStripsPNGText(t *testing.T) {
+	data := encodePNG(t, testImage(4, 4, 255))
+	text := []byte("tEXtComment\x00secret")
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)-4))
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(text))
+	data = append(data[:33:33], append(chunk, data[33:]...)...) // after IHDR
+
+	out, _, err := Sanitize(context.Background(), data, PNG, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("secret")) {
+		t.Fatal("text chunk left in image")
+	}
+	decodeConfig(t, out)
+}
+
+func TestSanitizeBlanksHEICMetadata(t *testing.T) {
+	heic := heicWithExif()
+	out, mime, err := Sanitize(context.Background(), heic, HEIC, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mime != HEIC || len(out) != len(heic) {
+		t.Fatalf("got %s of %d bytes, want HEIC of %d", mime, len(out), len(heic))
+	}
+	if bytes.Contains(out, []byte("GPS")) {
+		t.Error("EXIF location survived")
+	}
+	if !bytes.Contains(out, []byte("HEVC image data")) {
+		t.Error("image data was blanked")
+	}
+
+	if _, _, err := Sanitize(context.Background(), heic[:len(heic)-4], HEIC, Options{}); err == nil {
+		t.Error("expected a truncated HEIC to be rejected")
+	}
+}
+
+func TestSanitizeRejects(t *testing.T) {
+	if _, _, err := Sanitize(context.Background(), []byte("\x89PNG\r\n\x1a\nnot really"), PNG, Options{}); err == nil {
+		t.Error("expected a broken image to be rejected")
+	}
+	big := encodePNG(t, testImage(100, 100, 255))
+	if _, _, err := Sanitize(context.Background(), big, PNG, Options{MaxPixels: 5000}); err == nil {
+		t.Error("expected an image over MaxPixels to be rejected")
+	}
+}
+
+func TestFit(t *testing.T) {
+	ctx := context.Background()
+	small := encodePNG(t, testImage(100, 50, 255))
+	out, mime, changed, err := Fit(ctx, small, PNG, OpenAIProfile, Options{})
+	if err != nil || changed || mime != PNG || !bytes.Equal(out, small) {
+		t.Fatalf("an image that fits should pass unchanged: %s, %v, %v", mime, changed, err)
+	}
+
+	out, mime, changed, err = Fit(ctx, small, PNG, Profile{Name: "tiny", MaxDimension: 20, MaxBytes: 1 << 20, Formats: []string{JPEG, PNG}}, Options{})
+	if err != nil || !changed || mime != JPEG {
+		t.Fatalf("expected an opaque JPEG, got %s, %v, %v", mime, changed, err)
+	}
+	if cfg := decodeConfig(t, out); cfg.Width != 20 || cfg.Height != 10 {
+		t.Fatalf("expected 20x10, got %dx%d", cfg.Width, cfg.Height)
+	}
+
+	transparent := encodePNG(t, testImage(300, 300, 100))
+	_, mime, _, err = Fit(ctx, transparent, PNG, Thumbnail, Options{})
+	if err != nil || mime != PNG {
+		t.Fatalf("transparent images should stay PNG, got %s, %v", mime, err)
+	}
+
+	if _, _, _, err := Fit(ctx, []byte("\x00\x00\x00\x18ftypheic"), HEIC, OpenAIProfile, Options{}); err == nil {
+		t.Fatal("HEIC cannot be converted without a convert command")
+	}
+}
+
+func TestOrient(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.White)
+	got := orient(img, 6)
+	if b := got.Bounds(); b.Dx() != 1 || b.Dy() != 2 {
+		t.Fatalf("expected 1x2, got %v", b)
+	}
+	// turning clockwise brings the top left pixel to the top right
+	if r, _, _, _ := got.At(0, 0).RGBA(); r != 0xffff {
+		t.Fatal("expected the white pixel on top")
+	}
+}
@@ -0,0 +1,370 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/rotisserie/eris"
+)
+
+var errMalformed = eris.New("malformed image")
+
+// jpegOrientation returns the EXIF orientation of a JPEG, or 0 when data is
+// not a JPEG or has none.
+func jpegOrientation(data []byte) int {
+	orientation := 0
+	_, _ = walkJPEG(data, func(marker byte, segment []byte, _, _ int) bool {
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			orientation = tiffOrientation(segment[6:])
+			return false
+		}
+		return true
+	})
+	return orientation
+}
+
+// tiffOrientation reads the orientation tag of the first IFD of EXIF data.
+func tiffOrientation(t []byte) int {
+	if len(t) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	off := int(order.Uint32(t[4:]))
+	if off < 8 || off+2 > len(t) {
+		return 0
+	}
+	n := int(order.Uint16(t[off:]))
+	for i := 0; i < n; i++ {
+		e := off + 2 + 12*i
+		if e+12 > len(t) {
+			return 0
+		}
+		if order.Uint16(t[e:]) == 0x0112 {
+			return int(order.Uint16(t[e+8:]))
+		}
+	}
+	return 0
+}
+
+// walkJPEG calls fn with each marker segment before the image data, and
+// the offsets of the whole segment, until fn returns false. It returns the
+// offset of the start of scan marker.
+func walkJPEG(data []byte, fn func(marker byte, segment []byte, start, end int) bool) (int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 0, errMalformed
+	}
+	i := 2
+	for {
+		for i+1 < len(data) && data[i] == 0xFF && data[i+1] == 0xFF { // fill bytes
+			i++
+		}
+		if i+4 > len(data) || data[i] != 0xFF {
+			return 0, errMalformed
+		}
+		marker := data[i+1]
+		if marker == 0xDA { // start of scan: image data follows
+			return i, nil
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 0, errMalformed
+		}
+		end := i + 2 + length
+		if !fn(marker, data[i+4:end], i, end) {
+			return i, nil
+		}
+		i = end
+	}
+}
+
+// stripJPEG removes EXIF, XMP and other application segments and comments.
+// JFIF (APP0), ICC profiles (APP2) and Adobe color information (APP14) are
+// kept because they affect how the image is displayed.
+func stripJPEG(data []byte) ([]byte, error) {
+	out := append(make([]byte, 0, len(data)), 0xFF, 0xD8)
+	scan, err := walkJPEG(data, func(marker byte, _ []byte, start, end int) bool {
+		drop := marker == 0xFE || (marker >= 0xE1 && marker <= 0xEF && marker != 0xE2 && marker != 0xEE)
+		if !drop {
+			out = append(out, data[start:end]...)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(out, data[scan:]...), nil
+}
+
+// pngMetadata are the PNG chunks holding text, EXIF and timestamps.
+var pngMetadata = map[string]bool{"tEXt": true, "zTXt": true, "iTXt": true, "eXIf": true, "tIME": true}
+
+// stripPNG removes text, EXIF and timestamp chunks.
+func stripPNG(data []byte) ([]byte, error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return nil, errMalformed
+	}
+	out := append(make([]byte, 0, len(data)), signature...)
+	for i := len(signature); i < len(data); {
+		if i+12 > len(data) {
+			return nil, errMalformed
+		}
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:]))
+		if end > len(data) || end < i {
+			return nil, errMalformed
+		}
+		if !pngMetadata[string(data[i+4:i+8])] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out, nil
+}
+
+// stripWebP removes the EXIF and XMP chunks and their VP8X flags.
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errMalformed
+	}
+	out := append(make([]byte, 0, len(data)), data[:12]...)
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, errMalformed
+		}
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + size + size%2
+		if end > len(data) || end < i {
+			return nil, errMalformed
+		}
+		switch fourCC := string(data[i : i+4]); fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[i:end]...)
+			if size > 0 {
+				out[start+8] &^= 0x08 | 0x04 // EXIF and XMP present
+			}
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
+
+// stripHEIF blanks the EXIF and XMP items of a HEIC or HEIF image. Removing
+// them would move the image data that the item locations point to, so the
+// items stay in place holding zeros.
+func stripHEIF(data []byte) ([]byte, error) {
+	top, err := heifBoxes(data, 0, len(data))
+	if err != nil {
+		return nil, err
+	}
+	meta, ok := findBox(top, "meta")
+	if !ok {
+		return nil, errMalformed
+	}
+	children, err := heifBoxes(data, meta.start+4, meta.end) // skip version and flags
+	if err != nil {
+		return nil, err
+	}
+	iinf, ok := findBox(children, "iinf")
+	if !ok {
+		return data, nil
+	}
+	metadata, err := heifMetadataItems(data, iinf)
+	if err != nil {
+		return nil, err
+	}
+	if len(metadata) == 0 {
+		return data, nil
+	}
+	iloc, ok := findBox(children, "iloc")
+	if !ok {
+		return nil, errMalformed
+	}
+	idat, hasIdat := findBox(children, "idat")
+
+	out := bytes.Clone(data)
+	r := &boxReader{data: data, p: iloc.start, end: iloc.end}
+	version := r.uint(1)
+	r.uint(3) // flags
+	sizes := r.uint(2)
+	offsetSize, lengthSize, baseSize, indexSize := int(sizes>>12), int(sizes>>8&0xF), int(sizes>>4&0xF), int(sizes&0xF)
+	if version == 0 {
+		indexSize = 0
+	}
+	idSize := 2
+	if version == 2 {
+		idSize = 4
+	}
+	for n := r.uint(idSize); n > 0 && r.err == nil; n-- {
+		id := r.uint(idSize)
+		method := uint64(0) // offsets into the file
+		if version >= 1 {
+			method = r.uint(2) & 0xF
+		}
+		r.uint(2) // data reference index
+		base := r.uint(baseSize)
+		for extents := r.uint(2); extents > 0 && r.err == nil; extents-- {
+			r.uint(indexSize)
+			offset, length := base+r.uint(offsetSize), r.uint(lengthSize)
+			if !metadata[id] || r.err != nil {
+				continue
+			}
+			start, end := 0, len(out)
+			switch {
+			case method == 1 && hasIdat: // offsets into the idat box
+				start, end = idat.start, idat.end
+			case method != 0:
+				return nil, errMalformed
+			}
+			if length == 0 || offset > uint64(end-start) || length > uint64(end-start)-offset {
+				return nil, errMalformed
+			}
+			clear(out[start+int(offset) : start+int(offset+length)])
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return out, nil
+}
+
+// heifMetadataItems returns the IDs of the EXIF and XMP items listed in iinf.
+func heifMetadataItems(data []byte, iinf heifBox) (map[uint64]bool, error) {
+	r := &boxReader{data: data, p: iinf.start, end: iinf.end}
+	countSize := 2
+	if r.uint(1) != 0 {
+		countSize = 4
+	}
+	r.uint(3) // flags
+	r.uint(countSize)
+	if r.err != nil {
+		return nil, r.err
+	}
+	entries, err := heifBoxes(data, r.p, iinf.end)
+	if err != nil {
+		return nil, err
+	}
+	items := map[uint64]bool{}
+	for _, infe := range entries {
+		r := &boxReader{data: data, p: infe.start, end: infe.end}
+		version := r.uint(1)
+		r.uint(3) // flags
+		if infe.typ != "infe" || version < 2 {
+			// earlier versions have no item type and predate HEIF
+			continue
+		}
+		idSize := 2
+		if version >= 3 {
+			idSize = 4
+		}
+		id := r.uint(idSize)
+		r.uint(2) // protection index
+		itemType := string(r.bytes(4))
+		r.cstring() // item name
+		if itemType == "Exif" || (itemType == "mime" && r.cstring() == "application/rdf+xml") {
+			items[id] = true
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+	}
+	return items, nil
+}
+
+// heifBox is an ISO base media file format box; start and end are the
+// offsets of its contents.
+type heifBox struct {
+	typ        string
+	start, end int
+}
+
+// heifBoxes returns the boxes filling data[start:end].
+func heifBoxes(data []byte, start, end int) ([]heifBox, error) {
+	var boxes []heifBox
+	for i := start; i < end; {
+		if i+8 > end {
+			return nil, errMalformed
+		}
+		size, header := uint64(binary.BigEndian.Uint32(data[i:])), 8
+		switch size {
+		case 0: // extends to the end
+			size = uint64(end - i)
+		case 1: // 64-bit size follows the type
+			if i+16 > end {
+				return nil, errMalformed
+			}
+			size, header = binary.BigEndian.Uint64(data[i+8:]), 16
+		}
+		if size < uint64(header) || size > uint64(end-i) {
+			return nil, errMalformed
+		}
+		boxes = append(boxes, heifBox{typ: string(data[i+4 : i+8]), start: i + header, end: i + int(size)})
+		i += int(size)
+	}
+	return boxes, nil
+}
+
+func findBox(boxes []heifBox, typ string) (heifBox, bool) {
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b, true
+		}
+	}
+	return heifBox{}, false
+}
+
+// boxReader reads the big-endian fields of a box, remembering the first
+// read past its end.
+type boxReader struct {
+	data   []byte
+	p, end int
+	err    error
+}
+
+func (r *boxReader) bytes(n int) []byte {
+	if r.err != nil || n > 8 || r.p+n > r.end {
+		if r.err == nil {
+			r.err = errMalformed
+		}
+		return nil
+	}
+	b := r.data[r.p : r.p+n]
+	r.p += n
+	return b
+}
+
+// uint reads an n-byte unsigned integer; n is 0, 1, 2, 3, 4 or 8.
+func (r *boxReader) uint(n int) uint64 {
+	var v uint64
+	for _, b := range r.bytes(n) {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+// cstring reads a NUL-terminated string.
+func (r *boxReader) cstring() string {
+	if r.err != nil {
+		return ""
+	}
+	n := bytes.IndexByte(r.data[r.p:r.end], 0)
+	if n < 0 {
+		r.err = errMalformed
+		return ""
+	}
+	s := string(r.data[r.p : r.p+n])
+	r.p += n + 1
+	return s
+}
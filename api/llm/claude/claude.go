@@ -42,6 +42,20 @@ func AnswerFromBlockStart(line []byte) string {
 	return response.ContentBlock.Text
 }
 
+// MessageDelta is the streamed event carrying the stop reason of a message.
+type MessageDelta struct {
+	Delta struct {
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+}
+
+// StopReasonFromMessageDelta returns the stop reason of a message_delta event.
+func StopReasonFromMessageDelta(line []byte) string {
+	var response MessageDelta
+	_ = json.Unmarshal(line, &response)
+	return response.Delta.StopReason
+}
+
 func FormatClaudePrompt(chat_compeletion_messages []models.Message) string {
 	var sb strings.Builder
 
@@ -7,13 +7,11 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
-	"os"
+	"net/url"
 	"strings"
 
 	mapset "github.com/deckarep/golang-set/v2"
-	"github.com/samber/lo"
 	models "github.com/swuecho/chat_backend/models"
-	"github.com/swuecho/chat_backend/sqlc_queries"
 )
 
 type Part interface {
@@ -177,7 +175,7 @@ func SupportedMimeTypes() mapset.Set[string] {
 	)
 }
 
-func GenGemminPayload(chat_compeletion_messages []models.Message, chatFiles []sqlc_queries.ChatFile) ([]byte, error) {
+func GenGemminPayload(chat_compeletion_messages []models.Message) ([]byte, error) {
 	payload := GeminPayload{
 		Contents: make([]GeminiMessage, len(chat_compeletion_messages)),
 	}
@@ -193,20 +191,15 @@ func GenGemminPayload(chat_compeletion_messages []models.Message, chatFiles []sq
 		} else if message.Role == "system" {
 			geminiMessage.Role = "user"
 		}
-		payload.Contents[i] = geminiMessage
-	}
-
-	if len(chatFiles) > 0 {
-		partsFromFiles := lo.Map(chatFiles, func(chatFile sqlc_queries.ChatFile, _ int) Part {
-			imageExt := SupportedMimeTypes()
-			if imageExt.Contains(chatFile.MimeType) {
-				return &PartBlob{Blob: ImageData(chatFile.MimeType, chatFile.Data)}
+		// attachments follow the text of the message they were sent with
+		for _, attachment := range message.Attachments {
+			if SupportedMimeTypes().Contains(attachment.MimeType) {
+				geminiMessage.Parts = append(geminiMessage.Parts, &PartBlob{Blob: ImageData(attachment.MimeType, attachment.Data)})
 			} else {
-				return &PartString{Text: "file: " + chatFile.Name + "\n<<<" + string(chatFile.Data) + ">>>\n"}
+				geminiMessage.Parts = append(geminiMessage.Parts, &PartString{Text: "file: " + attachment.Name + "\n<<<" + string(attachment.Data) + ">>>\n"})
 			}
-		})
-		fmt.Printf("partsFromFiles: %+v\n", partsFromFiles)
-		payload.Contents[0].Parts = append(payload.Contents[0].Parts, partsFromFiles...)
+		}
+		payload.Contents[i] = geminiMessage
 	}
 
 	payloadBytes, err := json.Marshal(payload)
@@ -285,14 +278,13 @@ func HandleRegularResponse(client http.Client, req *http.Request) (*models.LLMAn
 	}, nil
 }
 
-func BuildAPIURL(model string, stream bool) string {
-	endpoint := "generateContent"
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:%s?key=$GEMINI_API_KEY", model, endpoint)
+// BuildAPIURL returns the generateContent URL of model, authenticated with apiKey.
+func BuildAPIURL(model string, stream bool, apiKey string) string {
+	key := url.QueryEscape(apiKey)
 	if stream {
-		endpoint = "streamGenerateContent?alt=sse"
-		url = fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:%s&key=$GEMINI_API_KEY", model, endpoint)
+		return fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:streamGenerateContent?alt=sse&key=%s", model, key)
 	}
-	return os.ExpandEnv(url)
+	return fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", model, key)
 }
 
 type GoogleApiError struct {
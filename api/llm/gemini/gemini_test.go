@@ -1,15 +1,10 @@
 package gemini
 
 import (
-	"os"
 	"testing"
 )
 
 func TestBuildAPIURL(t *testing.T) {
-	// Set test environment variable
-	os.Setenv("GEMINI_API_KEY", "test-key")
-	defer os.Unsetenv("GEMINI_API_KEY")
-
 	tests := []struct {
 		name     string
 		model    string
@@ -34,7 +29,7 @@ func TestBuildAPIURL(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := BuildAPIURL(tt.model, tt.stream)
+			got := BuildAPIURL(tt.model, tt.stream, "test-key")
 			if got != tt.expected {
 				t.Errorf("buildAPIURL() = %v, want %v", got, tt.expected)
 			}
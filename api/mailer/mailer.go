@@ -0,0 +1,215 @@
+// Package mailer renders and delivers transactional email.
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rotisserie/eris"
+)
+
+// Delivery drivers.
+const (
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+	DriverLog  = "log"
+)
+
+// Message is a rendered email ready to be sent.
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Template is the name the message was rendered from, if any.
+	Template string
+}
+
+// Sender delivers messages.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+	Driver() string
+}
+
+// Config selects and configures a Sender.
+type Config struct {
+	Driver   string
+	From     string
+	SMTPHost string
+	SMTPPort int
+	SMTPUser string
+	SMTPPass string
+	FileDir  string
+}
+
+// New returns the Sender for cfg.Driver. Unknown or empty drivers log messages instead of sending them.
+func New(cfg Config) Sender {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return &SMTPSender{cfg: cfg}
+	case DriverFile:
+		dir := cfg.FileDir
+		if dir == "" {
+			dir = filepath.Join(os.TempDir(), "chat-mail")
+		}
+		return &FileSender{Dir: dir, From: cfg.From}
+	default:
+		return &LogSender{From: cfg.From}
+	}
+}
+
+// SMTPSender sends mail through an SMTP server. Port 465 uses implicit TLS,
+// other ports upgrade with STARTTLS when the server offers it.
+type SMTPSender struct {
+	cfg Config
+}
+
+func (s *SMTPSender) Driver() string { return DriverSMTP }
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	if msg.From == "" {
+		msg.From = s.cfg.From
+	}
+	port := s.cfg.SMTPPort
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(s.cfg.SMTPHost, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if s.cfg.SMTPUser != "" {
+		auth = smtp.PlainAuth("", s.cfg.SMTPUser, s.cfg.SMTPPass, s.cfg.SMTPHost)
+	}
+
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+	var conn net.Conn
+	var err error
+	if port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: s.cfg.SMTPHost})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return eris.Wrap(err, "failed to connect to smtp server")
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, s.cfg.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return eris.Wrap(err, "failed to start smtp session")
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok && port != 465 {
+		if err := c.StartTLS(&tls.Config{ServerName: s.cfg.SMTPHost}); err != nil {
+			return eris.Wrap(err, "failed to start tls")
+		}
+	}
+	if auth != nil {
+		if err := c.Auth(auth); err != nil {
+			return eris.Wrap(err, "smtp authentication failed")
+		}
+	}
+	if err := c.Mail(addressOnly(msg.From)); err != nil {
+		return eris.Wrap(err, "smtp MAIL FROM rejected")
+	}
+	if err := c.Rcpt(addressOnly(msg.To)); err != nil {
+		return eris.Wrap(err, "smtp RCPT TO rejected")
+	}
+	wc, err := c.Data()
+	if err != nil {
+		return eris.Wrap(err, "smtp DATA rejected")
+	}
+	if _, err := wc.Write(Encode(msg)); err != nil {
+		return eris.Wrap(err, "failed to write message")
+	}
+	if err := wc.Close(); err != nil {
+		return eris.Wrap(err, "smtp server rejected message")
+	}
+	return c.Quit()
+}
+
+// FileSender writes each message as an .eml file, for development and tests.
+type FileSender struct {
+	Dir  string
+	From string
+}
+
+func (s *FileSender) Driver() string { return DriverFile }
+
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	if msg.From == "" {
+		msg.From = s.From
+	}
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return eris.Wrap(err, "failed to create mail directory")
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.New().String())
+	return eris.Wrap(os.WriteFile(filepath.Join(s.Dir, name), Encode(msg), 0o644), "failed to write mail file")
+}
+
+// LogSender logs messages instead of delivering them. Only the envelope is
+// logged: bodies carry password reset and verification links.
+type LogSender struct {
+	From string
+}
+
+func (s *LogSender) Driver() string { return DriverLog }
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	slog.Info("Mail not delivered, no mail driver configured", "to", msg.To, "subject", msg.Subject, "template", msg.Template)
+	return nil
+}
+
+// Encode renders msg as a MIME message with text and optional HTML parts.
+func Encode(msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", stripNewlines(msg.From))
+	fmt.Fprintf(&b, "To: %s\r\n", stripNewlines(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", stripNewlines(msg.Subject)))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		b.WriteString(crlf(msg.Text))
+		return []byte(b.String())
+	}
+
+	boundary := "alt-" + strings.ReplaceAll(uuid.New().String(), "-", "")
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+	fmt.Fprintf(&b, "--%s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n", boundary, crlf(msg.Text))
+	fmt.Fprintf(&b, "--%s\r\nContent-Type: text/html; charset=utf-8\r\n\r\n%s\r\n", boundary, crlf(msg.HTML))
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return []byte(b.String())
+}
+
+func crlf(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\n", "\r\n")
+}
+
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+func addressOnly(addr string) string {
+	if i := strings.LastIndex(addr, "<"); i >= 0 {
+		return strings.TrimSuffix(addr[i+1:], ">")
+	}
+	return addr
+}
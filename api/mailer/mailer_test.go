@@ -0,0 +1,73 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	msg, err := Render(TemplatePasswordReset, "ann@example.com", map[string]string{
+		"Name": "ann", "Link": "https://chat.example.com/reset?token=abc&x=1", "ExpiresIn": "1 hour",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.To != "ann@example.com" || msg.Subject != "Reset your password" {
+		t.Errorf("unexpected headers: %+v", msg)
+	}
+	if !strings.HasPrefix(msg.Text, "Hello ann,") || !strings.Contains(msg.Text, "https://chat.example.com/reset?token=abc&x=1") {
+		t.Errorf("unexpected text body: %q", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, `href="https://chat.example.com/reset?token=abc&amp;x=1"`) {
+		t.Errorf("html body not escaped: %q", msg.HTML)
+	}
+}
+
+func TestEncodeStripsHeaderNewlines(t *testing.T) {
+	raw := string(Encode(Message{From: "a@example.com", To: "b@example.com\r\nBcc: c@example.com", Subject: "hi", Text: "body"}))
+	if strings.Contains(raw, "\r\nBcc:") {
+		t.Errorf("header injection not prevented:\n%s", raw)
+	}
+}
+
+func TestFileSender(t *testing.T) {
+	dir := t.TempDir()
+	s := New(Config{Driver: DriverFile, FileDir: dir, From: "noreply@example.com"})
+	if err := s.Send(context.Background(), Message{To: "b@example.com", Subject: "hi", Text: "line1\nline2"}); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(entries))
+	}
+	data, _ := os.ReadFile(dir + "/" + entries[0].Name())
+	if !strings.Contains(string(data), "From: noreply@example.com\r\n") || !strings.Contains(string(data), "line1\r\nline2") {
+		t.Errorf("unexpected message:\n%s", data)
+	}
+}
+
+func TestLogSenderOmitsBody(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	msg, err := Render(TemplatePasswordReset, "ann@example.com", map[string]string{
+		"Name": "ann", "Link": "https://chat.example.com/reset?token=secret", "ExpiresIn": "1 hour",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := New(Config{}).Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(logs.String(), "secret") {
+		t.Errorf("token logged: %s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "template="+TemplatePasswordReset) {
+		t.Errorf("template not logged: %s", logs.String())
+	}
+}
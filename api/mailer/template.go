@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/rotisserie/eris"
+)
+
+// Template names.
+const (
+	TemplatePasswordReset = "password_reset"
+	TemplateVerifyEmail   = "verify_email"
+	TemplateInvite        = "invite"
+)
+
+// Each template has a <name>.txt whose first line is the subject and an optional <name>.html body.
+//
+//go:embed templates/*
+var templateFS embed.FS
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/*.txt"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.html"))
+)
+
+// Render fills the named template with data and returns a message addressed to to.
+func Render(name, to string, data any) (Message, error) {
+	var text bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&text, name+".txt", data); err != nil {
+		return Message{}, eris.Wrapf(err, "failed to render %s", name)
+	}
+	subject, body, _ := strings.Cut(text.String(), "\n")
+	msg := Message{To: to, Subject: strings.TrimSpace(subject), Text: strings.TrimLeft(body, "\n"), Template: name}
+
+	if htmlTemplates.Lookup(name+".html") != nil {
+		var html bytes.Buffer
+		if err := htmlTemplates.ExecuteTemplate(&html, name+".html", data); err != nil {
+			return Message{}, eris.Wrapf(err, "failed to render %s html", name)
+		}
+		msg.HTML = html.String()
+	}
+	return msg, nil
+}
@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
-	_ "embed"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -19,16 +19,21 @@ import (
 	"github.com/swuecho/chat_backend/config"
 	"github.com/swuecho/chat_backend/dto"
 	"github.com/swuecho/chat_backend/handler"
+	"github.com/swuecho/chat_backend/mailer"
+	"github.com/swuecho/chat_backend/metrics"
 	"github.com/swuecho/chat_backend/middleware"
+	"github.com/swuecho/chat_backend/migrations"
+	"github.com/swuecho/chat_backend/oidc"
+	"github.com/swuecho/chat_backend/provider"
+	"github.com/swuecho/chat_backend/secrets"
 	"github.com/swuecho/chat_backend/sqlc_queries"
 	"github.com/swuecho/chat_backend/static"
+	"github.com/swuecho/chat_backend/storage"
 	"github.com/swuecho/chat_backend/svc"
+	"github.com/swuecho/chat_backend/tracing"
 	"golang.org/x/time/rate"
 )
 
-//go:embed sqlc/schema.sql
-var schemaBytes []byte
-
 // server holds all application dependencies, avoiding package-level globals.
 type server struct {
 	cfg            config.AppConfig
@@ -40,15 +45,40 @@ type server struct {
 }
 
 func main() {
-	if err := run(); err != nil {
-		slog.Error("server error", "error", err)
+	err := runCommand(os.Args[1:])
+	var usageErr usageError
+	if errors.As(err, &usageErr) {
+		fmt.Fprintln(os.Stderr, usageErr)
+		os.Exit(2)
+	}
+	if err != nil {
+		slog.Error("command failed", "error", err)
 		os.Exit(1)
 	}
 }
 
 func run() error {
 	// --- Configuration ---
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	config.Set(cfg)
+
+	// --- Tracing ---
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Endpoint: cfg.TRACING.OTLP_ENDPOINT, ServiceName: cfg.TRACING.SERVICE_NAME, SampleRatio: cfg.TRACING.SAMPLE_RATIO,
+	})
+	if err != nil {
+		return fmt.Errorf("tracing: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("failed to flush traces", "error", err)
+		}
+	}()
 
 	// --- Database ---
 	pgdb, err := openDB(cfg)
@@ -56,22 +86,43 @@ func run() error {
 		return fmt.Errorf("database: %w", err)
 	}
 	defer pgdb.Close()
+	metrics.RegisterDB(pgdb, "chat")
 
-	// Run schema migrations
-	if _, err := pgdb.Exec(string(schemaBytes)); err != nil {
-		return fmt.Errorf("schema migration: %w", err)
+	// Apply pending schema migrations
+	if !cfg.MIGRATE.SKIP_ON_START {
+		migrator, err := migrations.New(pgdb)
+		if err != nil {
+			return fmt.Errorf("schema migration: %w", err)
+		}
+		n, err := migrator.Up(context.Background())
+		if err != nil {
+			return fmt.Errorf("schema migration: %w", err)
+		}
+		slog.Info("schema migration complete", "applied", n, "version", migrator.Latest())
 	}
-	slog.Info("schema migration complete")
+
+	// Master keys of the provider credential store
+	if err := loadKeyring(cfg); err != nil {
+		return fmt.Errorf("credential master key: %w", err)
+	}
+	go reloadOnHangup(cfg)
 
 	// --- Build server ---
 	srv := &server{
 		cfg:            cfg,
 		db:             pgdb,
-		q:              sqlc_queries.New(pgdb),
+		q:              sqlc_queries.New(tracing.DB(pgdb)),
 		rateLimiter:    rate.NewLimiter(rate.Every(time.Minute/3000), 500),
 		requestTracker: middleware.NewLastRequestTracker(),
 	}
 
+	// Uploaded file contents
+	blobs, err := openStorage(cfg, srv.q)
+	if err != nil {
+		return fmt.Errorf("file storage: %w", err)
+	}
+	slog.Info("file storage ready", "backend", blobs.Backend())
+
 	// JWT secret
 	secretSvc := svc.NewJWTSecretService(srv.q)
 	jwtSecretAndAud, err := secretSvc.GetOrCreateJwtSecret(context.Background(), "chat")
@@ -80,23 +131,31 @@ func run() error {
 	}
 	srv.jwtSecret = jwtSecretAndAud
 
+	// --- Background jobs ---
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	chatLogRetention := time.Duration(cfg.CHAT_LOG.RETENTION_DAYS) * 24 * time.Hour
+	go svc.NewChatLogService(srv.q).RunRetentionPurger(bgCtx, chatLogRetention, time.Hour)
+	go svc.NewAuthSessionService(srv.q).RunPurger(bgCtx, 24*time.Hour)
+	go svc.NewLoginGuardService(srv.q).RunPurger(bgCtx, time.Hour)
+
 	// --- Router ---
 	router, rawRouter := srv.buildRouter()
 
 	// --- Fly.io idle monitor (before wrapping in CORS) ---
-	if os.Getenv("FLY_APP_NAME") != "" {
+	if cfg.FLY.APP_NAME != "" {
 		rawRouter.Use(middleware.UpdateLastRequestTime(srv.requestTracker))
 		go srv.idleMonitor()
 	}
 
 	// --- HTTP Server ---
 	httpServer := &http.Server{
-		Addr:              ":8080",
+		Addr:              cfg.SERVER.LISTEN_ADDR,
 		Handler:           router,
-		ReadTimeout:       60 * time.Second,
-		ReadHeaderTimeout: 30 * time.Second,
-		WriteTimeout:      10 * time.Minute, // Long write timeout for streaming
-		IdleTimeout:       120 * time.Second,
+		ReadTimeout:       cfg.SERVER.READ_TIMEOUT,
+		ReadHeaderTimeout: cfg.SERVER.READ_HEADER_TIMEOUT,
+		WriteTimeout:      cfg.SERVER.WRITE_TIMEOUT,
+		IdleTimeout:       cfg.SERVER.IDLE_TIMEOUT,
 	}
 
 	// --- Graceful shutdown ---
@@ -110,7 +169,7 @@ func run() error {
 		// Drain the rate limiter
 		srv.rateLimiter.SetLimit(0)
 
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.SERVER.SHUTDOWN_TIMEOUT)
 		defer cancel()
 
 		if err := httpServer.Shutdown(ctx); err != nil {
@@ -119,7 +178,7 @@ func run() error {
 		close(idleConnsClosed)
 	}()
 
-	slog.Info("server starting", "addr", ":8080")
+	slog.Info("server starting", "addr", httpServer.Addr)
 	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
 		return fmt.Errorf("listen: %w", err)
 	}
@@ -131,13 +190,10 @@ func run() error {
 
 // openDB creates a database connection from the given config.
 func openDB(cfg config.AppConfig) (*sql.DB, error) {
-	dbURL := os.Getenv("DATABASE_URL")
-	var connStr string
-	if dbURL == "" {
+	connStr := cfg.DATABASE.URL
+	if connStr == "" {
 		connStr = fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
 			cfg.PG.HOST, cfg.PG.PORT, cfg.PG.USER, cfg.PG.PASS, cfg.PG.DB)
-	} else {
-		connStr = dbURL
 	}
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
@@ -158,21 +214,26 @@ func (s *server) buildRouter() (http.Handler, *mux.Router) {
 	// --- Global middleware ---
 	router.Use(middleware.RecoveryMiddleware)
 	router.Use(middleware.RequestIDMiddleware)
+	router.Use(middleware.ClientIPMiddleware)
+	router.Use(middleware.TracingMiddleware)
+	router.Use(middleware.MetricsMiddleware)
 	router.Use(middleware.BodyLimitMiddleware)
 
-	// --- Health check (public, before auth) ---
+	// --- Health check and metrics (public, before auth) ---
 	apiRouter.HandleFunc("/health", s.healthCheck).Methods(http.MethodGet)
+	router.Handle("/metrics", metrics.Handler(s.cfg.METRICS.TOKEN)).Methods(http.MethodGet)
 
 	// --- Subrouters ---
 	adminRouter := apiRouter.PathPrefix("/admin").Subrouter()
 	userRouter := apiRouter.NewRoute().Subrouter()
 
 	// Auth middleware
-	adminRouter.Use(middleware.AdminAuthMiddleware(s.jwtSecret.Secret))
-	userRouter.Use(middleware.UserAuthMiddleware(s.jwtSecret.Secret))
+	authSessions := svc.NewAuthSessionService(s.q)
+	adminRouter.Use(middleware.AdminAuthMiddleware(s.jwtSecret.Secret, authSessions))
+	userRouter.Use(middleware.UserAuthMiddleware(s.jwtSecret.Secret, authSessions))
 
 	// Rate limiting
-	rateLimitMW := middleware.RateLimitByUserID(s.q, s.cfg.OPENAI.RATELIMIT)
+	rateLimitMW := middleware.RateLimitByUserID(svc.NewQuotaService(s.q, int32(s.cfg.OPENAI.RATELIMIT)))
 	adminRouter.Use(rateLimitMW)
 	userRouter.Use(rateLimitMW)
 
@@ -204,27 +265,92 @@ func (s *server) registerRoutes(apiRouter, adminRouter, userRouter *mux.Router)
 	rateLimit := int32(s.cfg.OPENAI.RATELIMIT)
 	openAIKey := s.cfg.OPENAI.API_KEY
 	openAIProxy := s.cfg.OPENAI.PROXY_URL
+	// providerCfg is what services calling models on behalf of users share.
+	providerCfg := provider.Config{OpenAIKey: openAIKey, OpenAIProxy: openAIProxy, RateLimiter: s.rateLimiter}
 	jwtSecret := s.jwtSecret.Secret
 	jwtAudience := s.jwtSecret.Audience
 
 	// Public
-	apiRouter.HandleFunc("/tts", handler.HandleTTSRequest)
 	apiRouter.HandleFunc("/errors", dto.ErrorCatalogHandler)
 
 	// Chat models
 	handler.NewChatModelHandler(q).Register(userRouter)
+	handler.NewAuxiliaryModelHandler(svc.NewAuxiliaryModelService(q, provider.Config{OpenAIProxy: openAIProxy})).Register(adminRouter)
+
+	// Text to speech
+	handler.NewSpeechHandler(svc.NewSpeechService(q, provider.Config{OpenAIProxy: openAIProxy})).Register(userRouter)
+
+	// Mail
+	mailCfg := s.cfg.MAIL
+	mailSvc := svc.NewMailService(q, mailer.New(mailer.Config{
+		Driver: mailCfg.DRIVER, From: mailCfg.FROM,
+		SMTPHost: mailCfg.SMTP_HOST, SMTPPort: mailCfg.SMTP_PORT, SMTPUser: mailCfg.SMTP_USER, SMTPPass: mailCfg.SMTP_PASS,
+		FileDir: mailCfg.FILE_DIR,
+	}), mailCfg.BASE_URL)
+	handler.NewMailAdminHandler(mailSvc, svc.NewAuthUserService(q, jwtSecret, rateLimit)).Register(adminRouter)
 
 	// Auth
-	authHandler := handler.NewAuthUserHandler(q, jwtSecret, jwtAudience, rateLimit)
+	authHandler := handler.NewAuthUserHandler(q, mailSvc, jwtSecret, jwtAudience, rateLimit, mailCfg.REQUIRE_VERIFICATION)
 	authHandler.Register(userRouter)
 	authHandler.RegisterPublicRoutes(apiRouter)
 
+	// Refresh token sessions
+	sessionHandler := handler.NewAuthSessionHandler(svc.NewAuthSessionService(q), svc.NewAuthUserService(q, jwtSecret, rateLimit), jwtSecret)
+	sessionHandler.Register(userRouter)
+	sessionHandler.RegisterAdminRoutes(adminRouter)
+
+	// Two-factor authentication
+	twoFactorHandler := handler.NewTwoFactorHandler(q, svc.NewAuthUserService(q, jwtSecret, rateLimit), jwtSecret, jwtAudience)
+	twoFactorHandler.Register(userRouter)
+	twoFactorHandler.RegisterPublicRoutes(apiRouter)
+	twoFactorHandler.RegisterAdminRoutes(adminRouter)
+
+	// Encrypted provider credentials
+	credentialHandler := handler.NewCredentialHandler(q)
+	credentialHandler.Register(userRouter)
+	credentialHandler.RegisterAdminRoutes(adminRouter)
+
+	// Failed login tracking and lockouts
+	handler.NewLoginGuardHandler(svc.NewLoginGuardService(q)).Register(adminRouter)
+
+	// Audit trail
+	handler.NewAuditHandler(svc.NewAuditService(q)).Register(adminRouter)
+
+	// Quota policies
+	handler.NewQuotaHandler(svc.NewQuotaService(q, rateLimit)).Register(adminRouter)
+
+	// Single sign-on
+	oidcCfg := s.cfg.OIDC
+	var oidcProvider *oidc.Provider
+	if oidcCfg.ISSUER != "" {
+		oidcProvider = oidc.NewProvider(oidc.Config{
+			Issuer: oidcCfg.ISSUER, ClientID: oidcCfg.CLIENT_ID, ClientSecret: oidcCfg.CLIENT_SECRET,
+			RedirectURL: oidcCfg.REDIRECT_URL, Scopes: strings.Fields(oidcCfg.SCOPES), GroupsClaim: oidcCfg.GROUPS_CLAIM,
+		}, nil)
+	}
+	ssoSvc := svc.NewSSOService(q, svc.NewAuthUserService(q, jwtSecret, rateLimit), svc.SSOPolicy{
+		AllowSignup:     !oidcCfg.DISABLE_SIGNUP,
+		LinkByEmail:     oidcCfg.LINK_BY_EMAIL,
+		AdminGroups:     splitList(oidcCfg.ADMIN_GROUPS),
+		StaffGroups:     splitList(oidcCfg.STAFF_GROUPS),
+		GroupWorkspaces: svc.ParseGroupWorkspaces(oidcCfg.GROUP_WORKSPACES),
+	})
+	ssoHandler := handler.NewSSOHandler(oidcProvider, ssoSvc, svc.NewAuthSessionService(q), svc.NewTwoFactorService(q, ""), jwtSecret, jwtAudience)
+	ssoHandler.Register(userRouter)
+	ssoHandler.RegisterPublicRoutes(apiRouter)
+
 	// Admin
 	handler.NewAdminHandler(svc.NewAuthUserService(q, jwtSecret, rateLimit), rateLimit).RegisterRoutes(adminRouter)
 
 	// Prompts
 	handler.NewChatPromptHandler(q).Register(userRouter)
 
+	// Prompt templates
+	handler.NewPromptTemplateHandler(q).Register(userRouter)
+
+	// Slash commands
+	handler.NewSlashCommandHandler(q, providerCfg).Register(userRouter)
+
 	// Sessions
 	handler.NewChatSessionHandler(q).Register(userRouter)
 
@@ -238,10 +364,19 @@ func (s *server) registerRoutes(apiRouter, adminRouter, userRouter *mux.Router)
 	handler.NewChatMessageHandler(q, openAIKey, openAIProxy).Register(userRouter)
 
 	// Snapshots
-	handler.NewChatSnapshotHandler(q).Register(userRouter)
+	handler.NewChatSnapshotHandler(q, providerCfg).Register(userRouter)
 
 	// Chat stream
-	handler.NewChatHandler(q, s.rateLimiter, openAIKey, openAIProxy).Register(userRouter)
+	chatHandler := handler.NewChatHandler(q, s.rateLimiter, openAIKey, openAIProxy, rateLimit)
+	chatHandler.Register(userRouter)
+
+	// Published chatbots
+	botPublicationHandler := handler.NewChatBotPublicationHandler(q, chatHandler)
+	botPublicationHandler.Register(userRouter)
+	botPublicationHandler.RegisterPublicRoutes(apiRouter)
+
+	// Chatbot versions
+	handler.NewChatBotVersionHandler(q).Register(userRouter)
 
 	// Model privileges
 	handler.NewUserChatModelPrivilegeHandler(q).Register(userRouter)
@@ -254,6 +389,9 @@ func (s *server) registerRoutes(apiRouter, adminRouter, userRouter *mux.Router)
 
 	// Bot history
 	handler.NewBotAnswerHistoryHandler(q).Register(userRouter)
+
+	// Chat logs (admin)
+	handler.NewChatLogHandler(q).Register(adminRouter)
 }
 
 // healthCheck returns server health status.
@@ -291,53 +429,121 @@ func statusToText(healthy bool) string {
 	return "degraded"
 }
 
-// corsMiddleware configures CORS for the router.
-func (s *server) corsMiddleware(router *mux.Router) http.Handler {
-	allowedOrigins := []string{"http://localhost:9002", "http://localhost:3000"}
-	restrict := false
-
-	if corsOrigins := os.Getenv("CORS_ALLOWED_ORIGINS"); corsOrigins != "" {
-		allowedOrigins = nil
-		for _, origin := range strings.Split(corsOrigins, ",") {
-			if trimmed := strings.TrimSpace(origin); trimmed != "" {
-				allowedOrigins = append(allowedOrigins, trimmed)
-			}
+// splitList splits a comma separated config value, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// loadKeyring installs the credential master keys from CREDENTIAL_MASTER_KEY
+// or CREDENTIAL_MASTER_KEY_FILE. With neither set the credential store is off.
+func loadKeyring(cfg config.AppConfig) error {
+	spec := cfg.CREDENTIAL.MASTER_KEY
+	if path := cfg.CREDENTIAL.MASTER_KEY_FILE; path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
 		}
-		restrict = true
+		spec = string(data)
 	}
+	if strings.TrimSpace(spec) == "" {
+		secrets.SetDefault(nil)
+		return nil
+	}
+	keyring, err := secrets.ParseKeyring(spec)
+	if err != nil {
+		return err
+	}
+	secrets.SetDefault(keyring)
+	slog.Info("credential store enabled", "primary_key", keyring.PrimaryID())
+	return nil
+}
 
-	return handlers.CORS(
-		handlers.AllowedOriginValidator(func(origin string) bool {
-			for _, allowed := range allowedOrigins {
-				if allowed == "*" || strings.EqualFold(origin, allowed) {
-					return true
-				}
-			}
-			if !restrict {
-				return strings.HasPrefix(origin, "http://localhost:") ||
-					strings.HasPrefix(origin, "http://127.0.0.1:") ||
-					strings.HasPrefix(origin, "https://localhost:") ||
-					strings.HasPrefix(origin, "https://127.0.0.1:")
-			}
-			return false
-		}),
+// openStorage installs the file storage backends configured by STORAGE_*.
+func openStorage(cfg config.AppConfig, q *sqlc_queries.Queries) (*storage.Blobs, error) {
+	blobs, err := storage.New(q, storage.Config{
+		Backend:  cfg.STORAGE.BACKEND,
+		LocalDir: cfg.STORAGE.LOCAL_DIR,
+		S3: storage.S3Config{
+			Endpoint:  cfg.STORAGE.S3_ENDPOINT,
+			Region:    cfg.STORAGE.S3_REGION,
+			Bucket:    cfg.STORAGE.S3_BUCKET,
+			AccessKey: cfg.STORAGE.S3_ACCESS_KEY,
+			SecretKey: cfg.STORAGE.S3_SECRET_KEY,
+			Prefix:    cfg.STORAGE.S3_PREFIX,
+			PathStyle: cfg.STORAGE.S3_PATH_STYLE,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	storage.SetDefault(blobs)
+	return blobs, nil
+}
+
+// reloadOnHangup reloads the configuration and re-reads the master keys on
+// SIGHUP. An invalid configuration or bad key file keeps the previous one.
+func reloadOnHangup(cfg config.AppConfig) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	for range sigCh {
+		if _, err := config.Reload(); err != nil {
+			slog.Error("failed to reload configuration", "error", err)
+		} else {
+			slog.Info("configuration reloaded")
+		}
+		if err := loadKeyring(cfg); err != nil {
+			slog.Error("failed to reload credential master key", "error", err)
+		}
+	}
+}
+
+// corsMiddleware configures CORS for the router.
+func (s *server) corsMiddleware(router *mux.Router) http.Handler {
+	cors := handlers.CORS(
+		handlers.AllowedOriginValidator(allowedOrigin),
 		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
 		handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "Cache-Control", "Connection", "Pragma", "Accept", "Accept-Language", "Origin", "Referer", "X-Request-Id"}),
 		handlers.AllowCredentials(),
 	)(router)
+
+	// Published chatbots are embedded on third-party sites and answer CORS
+	// with their own per-bot origin allowlist.
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/public/") {
+			router.ServeHTTP(w, r)
+			return
+		}
+		cors.ServeHTTP(w, r)
+	})
 }
 
-// idleMonitor periodically checks for inactivity and exits on Fly.io.
-func (s *server) idleMonitor() {
-	interval := os.Getenv("FLY_RESTART_INTERVAL_IF_IDLE")
-	if interval == "" {
-		interval = "30m"
+// allowedOrigin reports whether origin may call the API. Without configured
+// origins any localhost origin is allowed.
+func allowedOrigin(origin string) bool {
+	allowed := config.Current().CORS.ALLOWED_ORIGINS
+	if len(allowed) == 0 {
+		return strings.HasPrefix(origin, "http://localhost:") ||
+			strings.HasPrefix(origin, "http://127.0.0.1:") ||
+			strings.HasPrefix(origin, "https://localhost:") ||
+			strings.HasPrefix(origin, "https://127.0.0.1:")
 	}
-	duration, err := time.ParseDuration(interval)
-	if err != nil {
-		slog.Warn("invalid FLY_RESTART_INTERVAL_IF_IDLE, disabling idle monitor", "value", interval)
-		return
+	for _, a := range allowed {
+		if a = strings.TrimSpace(a); a == "*" || strings.EqualFold(origin, a) {
+			return true
+		}
 	}
+	return false
+}
+
+// idleMonitor periodically checks for inactivity and exits on Fly.io.
+func (s *server) idleMonitor() {
+	duration := s.cfg.FLY.RESTART_INTERVAL_IF_IDLE
 	for {
 		time.Sleep(1 * time.Minute)
 		if s.requestTracker.Since() > duration {
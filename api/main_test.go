@@ -3,6 +3,8 @@ package main
 import (
 	"os"
 	"testing"
+
+	"github.com/swuecho/chat_backend/config"
 )
 
 // TestMain is the test entry point for the main package.
@@ -11,3 +13,22 @@ import (
 func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
+
+func TestAllowedOrigin(t *testing.T) {
+	defer config.Set(config.Default())
+
+	config.Set(config.Default())
+	if !allowedOrigin("http://localhost:9002") || allowedOrigin("https://evil.example.com") {
+		t.Error("without configured origins only localhost should be allowed")
+	}
+
+	cfg := config.Default()
+	cfg.CORS.ALLOWED_ORIGINS = []string{" https://chat.example.com"}
+	config.Set(cfg)
+	if !allowedOrigin("https://CHAT.example.com") {
+		t.Error("configured origin should be allowed")
+	}
+	if allowedOrigin("http://localhost:9002") {
+		t.Error("localhost should not be allowed once origins are configured")
+	}
+}
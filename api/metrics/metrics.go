@@ -0,0 +1,119 @@
+// Package metrics defines the Prometheus metrics served on /metrics.
+//
+// Collectors live on a private registry so tests and tools that import the
+// server packages do not share global state with client_golang's default one.
+package metrics
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "chat"
+
+// Registry holds every collector of the server.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts requests by mux route template, method and status code.
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "http_requests_total",
+		Help: "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	// HTTPDuration observes request latency. For streamed answers this is the
+	// whole stream.
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Name: "http_request_duration_seconds",
+		Help:    "HTTP request latency by route and method.",
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"route", "method"})
+
+	// LLMTimeToFirstToken observes the wait for the first answer text.
+	LLMTimeToFirstToken = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Name: "llm_time_to_first_token_seconds",
+		Help:    "Time from sending a model request to the first answer text.",
+		Buckets: []float64{.1, .25, .5, 1, 2, 4, 8, 15, 30, 60},
+	}, []string{"provider", "model"})
+
+	// LLMGenerationDuration observes the time to a complete answer.
+	LLMGenerationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Name: "llm_generation_duration_seconds",
+		Help:    "Time from sending a model request to the complete answer.",
+		Buckets: []float64{.5, 1, 2.5, 5, 10, 20, 40, 60, 120, 300},
+	}, []string{"provider", "model"})
+
+	// LLMTokens counts prompt and completion tokens, estimated from text length.
+	LLMTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "llm_tokens_total",
+		Help: "Estimated tokens sent (prompt) and received (completion) per model.",
+	}, []string{"provider", "model", "type"})
+
+	// LLMTokensPerSecond observes completion throughput after the first token.
+	LLMTokensPerSecond = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Name: "llm_completion_tokens_per_second",
+		Help:    "Estimated completion tokens per second after the first token.",
+		Buckets: []float64{5, 10, 20, 40, 60, 80, 120, 200, 400},
+	}, []string{"provider", "model"})
+
+	// LLMErrors counts failed model requests by dto.APIError code.
+	LLMErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "llm_errors_total",
+		Help: "Failed model requests by provider, model and error code.",
+	}, []string{"provider", "model", "code"})
+
+	// LLMActiveStreams is the number of model requests in progress.
+	LLMActiveStreams = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace, Name: "llm_active_streams",
+		Help: "Model requests in progress.",
+	}, []string{"provider"})
+
+	// TitleGenerationRunning and TitleGenerationWaiting track the bounded
+	// background title generation.
+	TitleGenerationRunning = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace, Name: "title_generation_running",
+		Help: "Session title generations in progress.",
+	})
+	TitleGenerationWaiting = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace, Name: "title_generation_waiting",
+		Help: "Session title generations queued for a free slot.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests, HTTPDuration,
+		LLMTimeToFirstToken, LLMGenerationDuration, LLMTokens, LLMTokensPerSecond, LLMErrors, LLMActiveStreams,
+		TitleGenerationRunning, TitleGenerationWaiting,
+	)
+}
+
+// RegisterDB exposes the connection pool statistics of db.
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the registry in the Prometheus text format to callers
+// presenting token as a bearer token. Without a token the metrics are not
+// exposed at all.
+func Handler(token string) http.Handler {
+	if token == "" {
+		return http.NotFoundHandler()
+	}
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, []byte("Bearer "+token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
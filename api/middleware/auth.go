@@ -19,6 +19,7 @@ import (
 	jwt "github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/swuecho/chat_backend/auth"
+	"github.com/swuecho/chat_backend/config"
 	"github.com/swuecho/chat_backend/dto"
 )
 
@@ -133,6 +134,47 @@ func ParseAndValidateJWT(bearerToken string, expectedTokenType string, jwtSecret
 	return result
 }
 
+// SessionChecker reports whether a server-side session is still active.
+type SessionChecker interface {
+	SessionActive(ctx context.Context, sessionID string, userID int32) (bool, error)
+}
+
+// checkAccessSession refuses an access token whose session has ended. Tokens
+// without a session must be short-lived, see auth.MaxSessionlessTokenLifetime.
+func checkAccessSession(ctx context.Context, result *AuthTokenResult, sessions SessionChecker) *dto.APIError {
+	sid, _ := result.Claims["sid"].(string)
+	if sid == "" {
+		exp, err := result.Claims.GetExpirationTime()
+		if err != nil || exp == nil {
+			return nil
+		}
+		issued, err := result.Claims.GetNotBefore()
+		if err != nil || issued == nil || exp.Sub(issued.Time) <= auth.MaxSessionlessTokenLifetime {
+			return nil
+		}
+		apiErr := dto.ErrAuthInvalidCredentials
+		apiErr.Detail = "This token can no longer be used, please create a new one"
+		return &apiErr
+	}
+	userID, err := parseInt32(result.UserID)
+	if err != nil {
+		apiErr := dto.ErrAuthInvalidCredentials
+		apiErr.Detail = "Invalid user ID in token"
+		return &apiErr
+	}
+	active, err := sessions.SessionActive(ctx, sid, userID)
+	if err != nil {
+		apiErr := dto.ErrInternalUnexpected.WithDetail("Could not check session").WithDebugInfo(err.Error())
+		return &apiErr
+	}
+	if !active {
+		apiErr := dto.ErrAuthInvalidCredentials
+		apiErr.Detail = "Session has ended, please sign in again"
+		return &apiErr
+	}
+	return nil
+}
+
 // AuthTokenResult holds the result of JWT parsing and validation.
 type AuthTokenResult struct {
 	Token     *jwt.Token
@@ -164,7 +206,7 @@ func parseInt32(s string) (int32, error) {
 }
 
 // AdminAuthMiddleware provides authentication + admin authorization.
-func AdminAuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
+func AdminAuthMiddleware(jwtSecret string, sessions SessionChecker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			bearerToken := ExtractBearerToken(r)
@@ -174,6 +216,10 @@ func AdminAuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
 				dto.RespondWithAPIError(w, *result.Error)
 				return
 			}
+			if apiErr := checkAccessSession(r.Context(), result, sessions); apiErr != nil {
+				dto.RespondWithAPIError(w, *apiErr)
+				return
+			}
 
 			if result.Role != "admin" {
 				apiErr := dto.ErrAuthAdminRequired
@@ -188,7 +234,7 @@ func AdminAuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
 }
 
 // UserAuthMiddleware provides authentication for regular user routes.
-func UserAuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
+func UserAuthMiddleware(jwtSecret string, sessions SessionChecker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			bearerToken := ExtractBearerToken(r)
@@ -198,6 +244,10 @@ func UserAuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
 				dto.RespondWithAPIError(w, *result.Error)
 				return
 			}
+			if apiErr := checkAccessSession(r.Context(), result, sessions); apiErr != nil {
+				dto.RespondWithAPIError(w, *apiErr)
+				return
+			}
 
 			next.ServeHTTP(w, CreateUserContext(r, result.UserID, result.Role))
 		})
@@ -256,15 +306,12 @@ func GetRequestID(ctx context.Context) string {
 	return ""
 }
 
-// MaxBodySize is the default maximum request body size (1MB).
-const MaxBodySize = 1 << 20
-
-// BodyLimitMiddleware limits request body size to prevent DoS.
+// BodyLimitMiddleware limits request body size (UPLOAD_MAX_BODY_SIZE) to prevent DoS.
 // Routes with their own size limits (e.g., /api/upload) are excluded.
 func BodyLimitMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Body != nil && !isUploadRoute(r.URL.Path) {
-			r.Body = http.MaxBytesReader(w, r.Body, MaxBodySize)
+			r.Body = http.MaxBytesReader(w, r.Body, config.Current().UPLOAD.MAX_BODY_SIZE)
 		}
 		next.ServeHTTP(w, r)
 	})
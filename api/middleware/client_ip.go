@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP returns the address of the client that sent r. Forwarding headers
+// are only trusted when the direct peer is a loopback or private address, i.e.
+// a reverse proxy in front of the server; otherwise clients could spoof them.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer := net.ParseIP(host)
+	if peer == nil || !(peer.IsLoopback() || peer.IsPrivate()) {
+		return host
+	}
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		// the proxy appends the address it saw, so the last entry is the one to trust
+		parts := strings.Split(xff, ",")
+		if ip := strings.TrimSpace(parts[len(parts)-1]); net.ParseIP(ip) != nil {
+			return ip
+		}
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return host
+}
+
+type clientIPKeyType struct{}
+
+// ClientIPMiddleware stores ClientIP in the request context, so services can
+// record where a change came from without seeing the request.
+func ClientIPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPKeyType{}, ClientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetClientIP returns the address stored by ClientIPMiddleware.
+func GetClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKeyType{}).(string)
+	return ip
+}
@@ -1,9 +1,17 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/swuecho/chat_backend/auth"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/metrics"
 )
 
 func TestRecoveryMiddleware(t *testing.T) {
@@ -59,3 +67,142 @@ func TestBodyLimitMiddleware(t *testing.T) {
 		t.Errorf("expected 200, got %d", w.Code)
 	}
 }
+
+func TestClientIP(t *testing.T) {
+	cases := []struct {
+		remote, xff, want string
+	}{
+		{"203.0.113.7:4000", "198.51.100.1", "203.0.113.7"},
+		{"10.0.0.2:4000", "198.51.100.9, 198.51.100.1", "198.51.100.1"},
+		{"127.0.0.1:4000", "", "127.0.0.1"},
+		{"10.0.0.2:4000", "not-an-ip", "10.0.0.2"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tc.remote
+		if tc.xff != "" {
+			req.Header.Set("X-Forwarded-For", tc.xff)
+		}
+		if got := ClientIP(req); got != tc.want {
+			t.Errorf("ClientIP(%s, %q) = %s, want %s", tc.remote, tc.xff, got, tc.want)
+		}
+	}
+}
+
+func TestClientIPMiddleware(t *testing.T) {
+	var got string
+	handler := ClientIPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = GetClientIP(r.Context())
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "203.0.113.7:4000"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if got != "203.0.113.7" {
+		t.Errorf("GetClientIP = %q, want 203.0.113.7", got)
+	}
+}
+
+func TestMetricsMiddlewareUsesRouteTemplate(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(MetricsMiddleware)
+	router.HandleFunc("/api/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("wrapped writer should still flush")
+		}
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	before := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("/api/items/{id}", "GET", "418"))
+	for _, path := range []string{"/api/items/1", "/api/items/2"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	if got := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("/api/items/{id}", "GET", "418")) - before; got != 2 {
+		t.Errorf("requests counted = %v, want 2", got)
+	}
+}
+
+type fakeQuota struct {
+	limit *dto.RateLimit
+	err   error
+	calls int
+}
+
+func (f *fakeQuota) CheckUserQuota(ctx context.Context, userID int32) (*dto.RateLimit, error) {
+	f.calls++
+	return f.limit, f.err
+}
+
+func TestRateLimitByUserID(t *testing.T) {
+	serve := func(quota QuotaChecker, path string) *httptest.ResponseRecorder {
+		handler := RateLimitByUserID(quota)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		req := httptest.NewRequest("POST", path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), UserContextKey, "1"))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	quota := &fakeQuota{limit: &dto.RateLimit{Limit: 100, Remaining: 42, Reset: time.Minute}}
+	w := serve(quota, "/api/chat_stream")
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("X-RateLimit-Remaining"); got != "42" {
+		t.Errorf("expected X-RateLimit-Remaining 42, got %q", got)
+	}
+
+	serve(quota, "/api/chat_sessions")
+	serve(quota, "/api/transcribe")
+	serve(quota, "/api/uuid/chat_messages/abc/speech")
+	if quota.calls != 3 {
+		t.Errorf("expected quota to be checked for chat paths only, got %d calls", quota.calls)
+	}
+
+	exceeded := dto.ErrTooManyRequests
+	exceeded.RateLimit = &dto.RateLimit{Limit: 100, Remaining: 0, Reset: time.Minute}
+	w = serve(&fakeQuota{err: exceeded}, "/api/chat")
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "60" {
+		t.Errorf("expected Retry-After 60, got %q", w.Header().Get("Retry-After"))
+	}
+}
+
+type fakeSessions map[string]bool
+
+func (f fakeSessions) SessionActive(ctx context.Context, sessionID string, userID int32) (bool, error) {
+	return f[sessionID], nil
+}
+
+func TestUserAuthMiddlewareChecksTokenSession(t *testing.T) {
+	const secret = "test-secret"
+	sessions := fakeSessions{"active": true, "revoked": false}
+	serve := func(token string) int {
+		handler := UserAuthMiddleware(secret, sessions)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		req := httptest.NewRequest("GET", "/api/chat_sessions", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+	year := 365 * 24 * time.Hour
+
+	short, _ := auth.GenerateToken(1, "user", secret, "aud", time.Hour, auth.TokenTypeAccess)
+	active, _ := auth.GenerateAPIToken(1, "user", secret, "aud", year, "active")
+	revoked, _ := auth.GenerateAPIToken(1, "user", secret, "aud", year, "revoked")
+	unbound, _ := auth.GenerateToken(1, "user", secret, "aud", year, auth.TokenTypeAccess)
+	for name, tt := range map[string]struct {
+		token string
+		want  int
+	}{
+		"short-lived token":         {short, http.StatusOK},
+		"api token, active session": {active, http.StatusOK},
+		"api token, revoked":        {revoked, http.StatusUnauthorized},
+		"long-lived, no session":    {unbound, http.StatusUnauthorized},
+	} {
+		if got := serve(tt.token); got != tt.want {
+			t.Errorf("%s: expected %d, got %d", name, tt.want, got)
+		}
+	}
+}
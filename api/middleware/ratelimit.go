@@ -1,20 +1,39 @@
 package middleware
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/swuecho/chat_backend/dto"
-	"github.com/swuecho/chat_backend/sqlc_queries"
 )
 
-// RateLimitByUserID returns a middleware that limits requests per user.
-func RateLimitByUserID(q *sqlc_queries.Queries, defaultLimit int) func(http.Handler) http.Handler {
+// QuotaChecker decides whether a user may send another chat request. It
+// returns the quota closest to being used up, if any, and a dto.APIError when
+// the request must be refused.
+type QuotaChecker interface {
+	CheckUserQuota(ctx context.Context, userID int32) (*dto.RateLimit, error)
+}
+
+// quotaPaths are the requests that call a model for the user.
+var quotaPaths = []string{"/chat", "/chat_stream", "/chatbot", "/transcribe", "/tts", "/speech"}
+
+func isQuotaPath(path string) bool {
+	for _, suffix := range quotaPaths {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimitByUserID returns a middleware that applies the user's quotas to
+// chat requests and reports them in X-RateLimit-* headers.
+func RateLimitByUserID(quota QuotaChecker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			path := r.URL.Path
-			if !strings.HasSuffix(path, "/chat") && !strings.HasSuffix(path, "/chat_stream") && !strings.HasSuffix(path, "/chatbot") {
+			if !isQuotaPath(r.URL.Path) {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -29,25 +48,17 @@ func RateLimitByUserID(q *sqlc_queries.Queries, defaultLimit int) func(http.Hand
 				return
 			}
 
-			messageCount, err := q.GetChatMessagesCount(r.Context(), userIDInt)
+			limit, err := quota.CheckUserQuota(ctx, userIDInt)
 			if err != nil {
-				apiErr := dto.ErrInternalUnexpected
-				apiErr.Detail = "Could not get message count for rate limiting"
-				apiErr.DebugInfo = err.Error()
+				var apiErr dto.APIError
+				if !errors.As(err, &apiErr) {
+					apiErr = dto.ErrInternalUnexpected.WithDetail("Could not check rate limit").WithDebugInfo(err.Error())
+				}
 				dto.RespondWithAPIError(w, apiErr)
 				return
 			}
-
-			maxRate, err := q.GetRateLimit(r.Context(), userIDInt)
-			if err != nil {
-				maxRate = int32(defaultLimit)
-			}
-
-			if messageCount >= int64(maxRate) {
-				apiErr := dto.ErrTooManyRequests
-				apiErr.Detail = fmt.Sprintf("Rate limit exceeded: messageCount=%d, maxRate=%d", messageCount, maxRate)
-				dto.RespondWithAPIError(w, apiErr)
-				return
+			if limit != nil {
+				limit.SetHeaders(w.Header())
 			}
 
 			next.ServeHTTP(w, r)
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/swuecho/chat_backend/migrations"
+)
+
+const migrateUsage = `usage: app migrate <command>
+
+commands:
+  status        list migrations and whether they are applied
+  up            apply all pending migrations
+  down [n]      revert the last n applied migrations (default 1)
+  to <version>  apply or revert migrations until version is the latest applied`
+
+// runMigrate implements the migrate subcommand.
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return usageError(migrateUsage)
+	}
+
+	_, pgdb, _, err := openAdmin()
+	if err != nil {
+		return err
+	}
+	defer pgdb.Close()
+	migrator, err := migrations.New(pgdb)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.Applied {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+	case "up":
+		n, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", n)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return usageError(fmt.Sprintf("invalid number of migrations %q", args[1]))
+			}
+		}
+		n, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("reverted %d migration(s)\n", n)
+	case "to":
+		if len(args) < 2 {
+			return usageError(migrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return usageError(fmt.Sprintf("invalid version %q", args[1]))
+		}
+		n, err := migrator.To(ctx, version)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("changed %d migration(s), now at version %d\n", n, version)
+	default:
+		return usageError(fmt.Sprintf("unknown migrate command %q\n\n%s", args[0], migrateUsage))
+	}
+	return nil
+}
@@ -1,3 +1,8 @@
+-- Baseline: the schema as it was when versioned migrations replaced running
+-- sqlc/schema.sql on every start. Every statement is idempotent, so databases
+-- created by older releases are brought up to date and recorded as version 1.
+-- It has no down migration.
+
 CREATE TABLE IF NOT EXISTS jwt_secrets (
     id SERIAL PRIMARY KEY,
     name TEXT NOT NULL,
@@ -21,6 +26,7 @@ CREATE TABLE IF NOT EXISTS chat_model (
   api_auth_header TEXT DEFAULT '' NOT NULL,   
   -- env var that contains the api key
   -- for example: OPENAI_API_KEY, which means the api key is stored in an env var called OPENAI_API_KEY
+  -- only used when the model has no stored credential (credential_id)
   api_auth_key TEXT DEFAULT '' NOT NULL,
   user_id INTEGER NOT NULL default 1,
   enable_per_mode_ratelimit BOOLEAN DEFAULT false NOT NULL,
@@ -136,6 +142,9 @@ CREATE INDEX IF NOT EXISTS chat_workspace_user_id_idx ON chat_workspace (user_id
 -- add index on uuid for workspace
 CREATE INDEX IF NOT EXISTS chat_workspace_uuid_idx ON chat_workspace using hash (uuid);
 
+-- per-workspace opt-out of chat logging
+ALTER TABLE chat_workspace ADD COLUMN IF NOT EXISTS chat_log_enabled BOOLEAN DEFAULT true NOT NULL;
+
 -- Keep exactly one default workspace per user.
 -- If duplicates exist in old data, keep the newest default and unset the rest.
 WITH ranked_default_workspaces AS (
@@ -301,6 +310,9 @@ CREATE TABLE IF NOT EXISTS chat_logs (
 -- add brin index on created_at
 CREATE INDEX IF NOT EXISTS chat_logs_created_at_idx ON chat_logs using brin (created_at) ;
 
+-- support admin filtering of chat logs by user
+CREATE INDEX IF NOT EXISTS chat_logs_session_user_id_idx ON chat_logs (((session->>'userId')::INTEGER));
+
 
 -- user_id is the user who created the session
 -- uuid is the session uuid
@@ -423,3 +435,339 @@ CREATE TABLE IF NOT EXISTS chat_comment (
 -- Add indexes for faster lookups
 CREATE INDEX IF NOT EXISTS chat_comment_chat_session_uuid_idx ON chat_comment (chat_session_uuid);
 CREATE INDEX IF NOT EXISTS chat_comment_created_by_idx ON chat_comment (created_by);
+
+-- reusable prompt templates; workspace_id NULL means user-scoped
+CREATE TABLE IF NOT EXISTS prompt_template (
+    id SERIAL PRIMARY KEY,
+    uuid VARCHAR(255) UNIQUE NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES auth_user(id) ON DELETE CASCADE,
+    workspace_id INTEGER REFERENCES chat_workspace(id) ON DELETE SET NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    -- private: owner and explicit shares only, public: every user
+    visibility VARCHAR(20) NOT NULL DEFAULT 'private',
+    current_version INTEGER NOT NULL DEFAULT 1,
+    usage_count INTEGER NOT NULL DEFAULT 0,
+    last_used_at TIMESTAMP,
+    is_deleted BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP DEFAULT now() NOT NULL,
+    updated_at TIMESTAMP DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS prompt_template_user_id_idx ON prompt_template (user_id);
+CREATE INDEX IF NOT EXISTS prompt_template_workspace_id_idx ON prompt_template (workspace_id);
+CREATE INDEX IF NOT EXISTS prompt_template_tags_idx ON prompt_template USING GIN (tags);
+
+CREATE TABLE IF NOT EXISTS prompt_template_version (
+    id SERIAL PRIMARY KEY,
+    template_id INTEGER NOT NULL REFERENCES prompt_template(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    model VARCHAR(255) NOT NULL DEFAULT '',
+    created_by INTEGER NOT NULL REFERENCES auth_user(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT now() NOT NULL,
+    UNIQUE (template_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS prompt_template_share (
+    id SERIAL PRIMARY KEY,
+    template_id INTEGER NOT NULL REFERENCES prompt_template(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES auth_user(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT now() NOT NULL,
+    UNIQUE (template_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS prompt_template_share_user_id_idx ON prompt_template_share (user_id);
+
+-- user-defined slash commands; workspace_id NULL means the command applies in every workspace
+CREATE TABLE IF NOT EXISTS slash_command (
+    id SERIAL PRIMARY KEY,
+    uuid VARCHAR(255) UNIQUE NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES auth_user(id) ON DELETE CASCADE,
+    workspace_id INTEGER REFERENCES chat_workspace(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    template TEXT NOT NULL,
+    -- optional overrides applied to the single turn that uses the command
+    model VARCHAR(255) NOT NULL DEFAULT '',
+    temperature DOUBLE PRECISION,
+    created_at TIMESTAMP DEFAULT now() NOT NULL,
+    updated_at TIMESTAMP DEFAULT now() NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS slash_command_scope_name_idx ON slash_command (user_id, COALESCE(workspace_id, 0), name);
+
+-- publishing settings for chatbots (chat_snapshot rows with typ = 'chatbot')
+CREATE TABLE IF NOT EXISTS chat_bot_publication (
+    id SERIAL PRIMARY KEY,
+    bot_uuid VARCHAR(255) UNIQUE NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES auth_user(id) ON DELETE CASCADE,
+    -- disabled, anonymous or token
+    access_mode VARCHAR(20) NOT NULL DEFAULT 'disabled',
+    -- sha256 hex of the access token for token mode
+    token_hash VARCHAR(64) NOT NULL DEFAULT '',
+    -- max answers per hour across all visitors, 0 means unlimited
+    rate_limit INTEGER NOT NULL DEFAULT 60,
+    -- origins allowed to call the bot from a browser, empty means any
+    allowed_origins TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT now() NOT NULL,
+    updated_at TIMESTAMP DEFAULT now() NOT NULL
+);
+
+-- anonymous visitors of published bots; rows are owned by the bot owner
+ALTER TABLE bot_answer_history ADD COLUMN IF NOT EXISTS visitor_id VARCHAR(255) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS bot_answer_history_visitor_idx ON bot_answer_history (bot_uuid, visitor_id);
+
+-- chatbot versions; the live chat_snapshot row mirrors its current version
+CREATE TABLE IF NOT EXISTS chat_bot_version (
+    id SERIAL PRIMARY KEY,
+    bot_uuid VARCHAR(255) NOT NULL,
+    version INTEGER NOT NULL,
+    model VARCHAR(255) NOT NULL DEFAULT '',
+    -- SimpleChatMessage array; the first message is the system prompt
+    conversation JSONB DEFAULT '[]' NOT NULL,
+    -- chat_bot_file ids used as knowledge by this version
+    file_ids INTEGER[] NOT NULL DEFAULT '{}',
+    note TEXT NOT NULL DEFAULT '',
+    created_by INTEGER NOT NULL REFERENCES auth_user(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT now() NOT NULL,
+    UNIQUE (bot_uuid, version)
+);
+
+-- text knowledge files attached to chatbots; kept so older versions can be restored
+CREATE TABLE IF NOT EXISTS chat_bot_file (
+    id SERIAL PRIMARY KEY,
+    bot_uuid VARCHAR(255) NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES auth_user(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    mime_type VARCHAR(255) NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS chat_bot_file_bot_uuid_idx ON chat_bot_file (bot_uuid);
+
+ALTER TABLE chat_snapshot ADD COLUMN IF NOT EXISTS bot_version INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE bot_answer_history ADD COLUMN IF NOT EXISTS bot_version INTEGER NOT NULL DEFAULT 0;
+
+-- existing accounts are treated as verified; signups start unverified
+ALTER TABLE auth_user ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT true;
+
+-- single-use tokens sent by email for password reset, email verification and invites
+CREATE TABLE IF NOT EXISTS auth_user_token (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES auth_user(id) ON DELETE CASCADE,
+    -- password_reset, verify_email or invite
+    purpose VARCHAR(32) NOT NULL,
+    -- sha256 hex of the token sent to the user
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS auth_user_token_user_id_idx ON auth_user_token (user_id, purpose);
+
+-- every email the server tried to send
+CREATE TABLE IF NOT EXISTS mail_delivery (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES auth_user(id) ON DELETE SET NULL,
+    to_address VARCHAR(254) NOT NULL,
+    template VARCHAR(64) NOT NULL,
+    subject TEXT NOT NULL DEFAULT '',
+    driver VARCHAR(20) NOT NULL DEFAULT '',
+    -- pending, sent or failed
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT now() NOT NULL,
+    sent_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS mail_delivery_created_at_idx ON mail_delivery (created_at);
+
+-- external identities (OpenID Connect issuer + subject) linked to local accounts
+CREATE TABLE IF NOT EXISTS auth_user_identity (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES auth_user(id) ON DELETE CASCADE,
+    issuer VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(254) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT now() NOT NULL,
+    last_login_at TIMESTAMP DEFAULT now() NOT NULL,
+    UNIQUE (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS auth_user_identity_user_id_idx ON auth_user_identity (user_id);
+
+-- pending OpenID Connect logins, consumed by the callback
+CREATE TABLE IF NOT EXISTS oidc_login_state (
+    state VARCHAR(64) PRIMARY KEY,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    -- web app path to return to after login
+    redirect_to TEXT NOT NULL DEFAULT '',
+    -- set when an authenticated user links an identity to their account
+    link_user_id INTEGER REFERENCES auth_user(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT now() NOT NULL
+);
+
+-- TOTP second factor; enabled once the user confirms a first code
+CREATE TABLE IF NOT EXISTS auth_user_totp (
+    user_id INTEGER PRIMARY KEY REFERENCES auth_user(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    -- last accepted time step, so a code cannot be used twice
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT now() NOT NULL,
+    confirmed_at TIMESTAMP
+);
+
+-- single-use two-factor recovery codes, stored as sha256 hex
+CREATE TABLE IF NOT EXISTS auth_user_recovery_code (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES auth_user(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS auth_user_recovery_code_user_id_idx ON auth_user_recovery_code (user_id);
+
+-- server-wide settings changed by admins at runtime
+CREATE TABLE IF NOT EXISTS app_setting (
+    key VARCHAR(100) PRIMARY KEY,
+    value JSONB NOT NULL,
+    updated_at TIMESTAMP DEFAULT now() NOT NULL
+);
+
+-- server-side refresh token sessions, one per signed-in device
+CREATE TABLE IF NOT EXISTS auth_session (
+    id SERIAL PRIMARY KEY,
+    uuid VARCHAR(36) UNIQUE NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES auth_user(id) ON DELETE CASCADE,
+    -- sha256 hex of the refresh token currently valid for this session
+    token_hash VARCHAR(64) NOT NULL,
+    -- the token it replaced, accepted briefly so parallel refreshes are not taken for reuse
+    previous_hash VARCHAR(64) NOT NULL DEFAULT '',
+    rotated_at TIMESTAMP,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT now() NOT NULL,
+    last_used_at TIMESTAMP DEFAULT now() NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    -- logout, user, admin, reuse or password_reset
+    revoked_reason VARCHAR(20) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS auth_session_user_id_idx ON auth_session (user_id);
+
+-- failed-login counters per account or client address, shared by all server instances
+CREATE TABLE IF NOT EXISTS login_throttle (
+    -- "account:<email>", "ip:<address>", "reset:<email>" or "reset_ip:<address>"
+    key VARCHAR(300) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP DEFAULT now() NOT NULL,
+    locked_until TIMESTAMP
+);
+
+-- audit log of failed logins
+CREATE TABLE IF NOT EXISTS login_attempt (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(254) NOT NULL DEFAULT '',
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    -- bad_password, bad_2fa or locked
+    reason VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS login_attempt_created_at_idx ON login_attempt (created_at);
+CREATE INDEX IF NOT EXISTS login_attempt_email_idx ON login_attempt (email);
+
+-- per-workspace models for titles, suggestions and summaries; empty fields fall back to the global setting
+ALTER TABLE chat_workspace ADD COLUMN IF NOT EXISTS auxiliary_models JSONB DEFAULT '{}' NOT NULL;
+
+-- provider api keys, envelope-encrypted: ciphertext is sealed with a per-row data key,
+-- and wrapped_key is that data key sealed with the master key master_key_id
+CREATE TABLE IF NOT EXISTS provider_credential (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    -- set for a workspace's own key (bring your own key); NULL for keys managed by admins
+    workspace_id INTEGER REFERENCES chat_workspace(id) ON DELETE CASCADE,
+    master_key_id VARCHAR(64) NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    ciphertext BYTEA NOT NULL,
+    -- last characters of the secret, shown instead of the secret
+    hint VARCHAR(16) NOT NULL DEFAULT '',
+    created_by INTEGER NOT NULL REFERENCES auth_user(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT now() NOT NULL,
+    updated_at TIMESTAMP DEFAULT now() NOT NULL,
+    -- when the secret was last replaced
+    rotated_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS provider_credential_workspace_id_idx ON provider_credential (workspace_id);
+
+ALTER TABLE chat_model ADD COLUMN IF NOT EXISTS credential_id INTEGER REFERENCES provider_credential(id) ON DELETE SET NULL;
+
+-- a workspace's own key for a model, used instead of chat_model.credential_id
+CREATE TABLE IF NOT EXISTS workspace_model_credential (
+    workspace_id INTEGER NOT NULL REFERENCES chat_workspace(id) ON DELETE CASCADE,
+    chat_model_id INTEGER NOT NULL REFERENCES chat_model(id) ON DELETE CASCADE,
+    credential_id INTEGER NOT NULL REFERENCES provider_credential(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT now() NOT NULL,
+    PRIMARY KEY (workspace_id, chat_model_id)
+);
+
+-- append-only trail of admin and security-relevant changes; rows are never updated or deleted
+CREATE TABLE IF NOT EXISTS audit_event (
+    id BIGSERIAL PRIMARY KEY,
+    -- no foreign key: events outlive the accounts they mention
+    actor_user_id INTEGER,
+    actor_email VARCHAR(255) NOT NULL DEFAULT '',
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(64) NOT NULL DEFAULT '',
+    target_id VARCHAR(255) NOT NULL DEFAULT '',
+    -- changed fields as {"field": {"before": ..., "after": ...}}, secrets redacted
+    changes JSONB DEFAULT '{}' NOT NULL,
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_event_created_at_idx ON audit_event (created_at);
+CREATE INDEX IF NOT EXISTS audit_event_actor_user_id_idx ON audit_event (actor_user_id);
+CREATE INDEX IF NOT EXISTS audit_event_target_idx ON audit_event (target_type, target_id);
+
+CREATE OR REPLACE FUNCTION audit_event_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_event is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_event_append_only ON audit_event;
+CREATE TRIGGER audit_event_append_only BEFORE UPDATE OR DELETE ON audit_event
+    FOR EACH ROW EXECUTE FUNCTION audit_event_append_only();
+
+-- quota policies: a limit on requests or tokens per sliding window; NULL targets match everyone.
+-- Usage is counted per user, narrowed to the policy's workspace and model when set.
+CREATE TABLE IF NOT EXISTS quota_policy (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    user_id INTEGER REFERENCES auth_user(id) ON DELETE CASCADE,
+    workspace_id INTEGER REFERENCES chat_workspace(id) ON DELETE CASCADE,
+    chat_model_id INTEGER REFERENCES chat_model(id) ON DELETE CASCADE,
+    -- minute, hour, day or month (30 days)
+    time_window VARCHAR(16) NOT NULL,
+    -- requests (model answers) or tokens (sent and received)
+    metric VARCHAR(16) NOT NULL,
+    quota_limit BIGINT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP DEFAULT now() NOT NULL,
+    updated_at TIMESTAMP DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS quota_policy_user_id_idx ON quota_policy (user_id);
+CREATE INDEX IF NOT EXISTS chat_message_user_id_created_at_idx ON chat_message (user_id, created_at);
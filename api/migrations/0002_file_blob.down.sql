@@ -0,0 +1,15 @@
+-- Only blobs held by the postgres backend can be copied back inline; run
+-- `STORAGE_BACKEND=postgres app storage migrate` first to pull files back
+-- from the filesystem or S3.
+UPDATE chat_file cf
+SET data = d.data
+FROM file_blob_data d
+WHERE cf.blob_hash = d.hash;
+
+DROP INDEX chat_file_blob_hash_idx;
+ALTER TABLE chat_file ALTER COLUMN data DROP DEFAULT;
+ALTER TABLE chat_file DROP COLUMN size;
+ALTER TABLE chat_file DROP COLUMN blob_hash;
+
+DROP TABLE file_blob_data;
+DROP TABLE file_blob;
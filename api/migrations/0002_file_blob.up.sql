@@ -0,0 +1,23 @@
+-- Upload contents move out of chat_file into content-addressed blobs. A blob
+-- is stored once per SHA-256 hash in the backend named by file_blob.backend;
+-- the postgres backend keeps the bytes in file_blob_data. chat_file rows
+-- written before this migration keep their inline data (blob_hash IS NULL)
+-- until `app storage migrate` moves them.
+CREATE TABLE file_blob (
+    hash VARCHAR(64) PRIMARY KEY,
+    size BIGINT NOT NULL,
+    backend VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP DEFAULT now() NOT NULL
+);
+
+CREATE TABLE file_blob_data (
+    hash VARCHAR(64) PRIMARY KEY,
+    data BYTEA NOT NULL
+);
+
+ALTER TABLE chat_file ADD COLUMN blob_hash VARCHAR(64) REFERENCES file_blob(hash);
+ALTER TABLE chat_file ADD COLUMN size BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE chat_file ALTER COLUMN data SET DEFAULT ''::bytea;
+UPDATE chat_file SET size = octet_length(data);
+
+CREATE INDEX chat_file_blob_hash_idx ON chat_file (blob_hash);
@@ -0,0 +1,3 @@
+DROP INDEX chat_file_chat_message_uuid_idx;
+ALTER TABLE chat_file DROP COLUMN detached;
+ALTER TABLE chat_file DROP COLUMN chat_message_uuid;
@@ -0,0 +1,19 @@
+-- Files belong to the user message they were sent with. A file uploaded
+-- before its message is sent has no message yet and is attached to the next
+-- user message of its session. A detached file is only sent to the model on
+-- the turn of its own message.
+ALTER TABLE chat_file ADD COLUMN chat_message_uuid VARCHAR(255);
+ALTER TABLE chat_file ADD COLUMN detached BOOLEAN NOT NULL DEFAULT false;
+
+-- existing files go with the first user message sent after their upload
+UPDATE chat_file f
+SET chat_message_uuid = (
+    SELECT m.uuid FROM chat_message m
+    WHERE m.chat_session_uuid = f.chat_session_uuid
+      AND m.role = 'user'
+      AND m.created_at >= f.created_at
+    ORDER BY m.created_at, m.id
+    LIMIT 1
+);
+
+CREATE INDEX chat_file_chat_message_uuid_idx ON chat_file (chat_message_uuid);
@@ -0,0 +1,13 @@
+-- Images resized or converted for a model, and thumbnails, are kept as blobs
+-- of their own so a chat turn does not re-encode the original every time.
+-- A variant goes with its source blob.
+CREATE TABLE file_blob_variant (
+    source_hash VARCHAR(64) NOT NULL REFERENCES file_blob (hash) ON DELETE CASCADE,
+    profile VARCHAR(32) NOT NULL,
+    blob_hash VARCHAR(64) NOT NULL REFERENCES file_blob (hash),
+    mime_type VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT now() NOT NULL,
+    PRIMARY KEY (source_hash, profile)
+);
+
+CREATE INDEX file_blob_variant_blob_hash_idx ON file_blob_variant (blob_hash);
@@ -0,0 +1 @@
+ALTER TABLE chat_file DROP COLUMN source;
@@ -0,0 +1,3 @@
+-- source tells uploads, which are sent to the model with their message, from
+-- voice recordings, whose transcript is the message text.
+ALTER TABLE chat_file ADD COLUMN source VARCHAR(16) NOT NULL DEFAULT 'upload';
@@ -0,0 +1,2 @@
+DROP TABLE message_speech;
+DROP TABLE user_speech_setting;
@@ -0,0 +1,22 @@
+-- voice and speed each user has chosen for text to speech
+CREATE TABLE user_speech_setting (
+    user_id INTEGER PRIMARY KEY REFERENCES auth_user (id) ON DELETE CASCADE,
+    voice VARCHAR(64) NOT NULL DEFAULT '',
+    speed DOUBLE PRECISION NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP DEFAULT now() NOT NULL
+);
+
+-- Speech synthesized for a message, kept as a blob so replaying it costs
+-- nothing. voice_key names the engine, voice and speed; content_hash is the
+-- hash of the spoken text, so an edited message is spoken again.
+CREATE TABLE message_speech (
+    message_uuid VARCHAR(255) NOT NULL,
+    voice_key VARCHAR(255) NOT NULL,
+    content_hash VARCHAR(64) NOT NULL,
+    blob_hash VARCHAR(64) NOT NULL REFERENCES file_blob (hash),
+    mime_type VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT now() NOT NULL,
+    PRIMARY KEY (message_uuid, voice_key)
+);
+
+CREATE INDEX message_speech_blob_hash_idx ON message_speech (blob_hash);
@@ -0,0 +1,14 @@
+-- Model requests that leave no chat message, such as public chatbot answers,
+-- transcriptions and speech, so that quota policies count them too. source
+-- names the feature; tokens is an estimate where the provider reports none.
+CREATE TABLE model_usage (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES auth_user (id) ON DELETE CASCADE,
+    workspace_id INTEGER REFERENCES chat_workspace (id) ON DELETE SET NULL,
+    model VARCHAR(255) NOT NULL,
+    source VARCHAR(32) NOT NULL,
+    tokens INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT now() NOT NULL
+);
+
+CREATE INDEX model_usage_user_id_created_at_idx ON model_usage (user_id, created_at);
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.up.sql":   {Data: []byte("CREATE INDEX i ON t (c);")},
+		"0002_add_index.down.sql": {Data: []byte("DROP INDEX i;")},
+		"0001_baseline.up.sql":    {Data: []byte("CREATE TABLE t (c int);")},
+		"README.md":               {Data: []byte("not a migration")},
+	}
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[0].Name != "baseline" || migrations[0].Down != "" {
+		t.Errorf("unexpected first migration %+v", migrations[0])
+	}
+	if migrations[1].Version != 2 || migrations[1].Down != "DROP INDEX i;" {
+		t.Errorf("unexpected second migration %+v", migrations[1])
+	}
+}
+
+func TestLoadRejectsDownWithoutUp(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0003_orphan.down.sql": {Data: []byte("SELECT 1;")},
+	}
+	if _, err := Load(fsys); err == nil {
+		t.Error("expected an error for a migration without an up file")
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := Load(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 || migrations[0].Version != 1 {
+		t.Fatal("expected the baseline migration to be version 1")
+	}
+}
@@ -18,9 +18,18 @@ func getTokenCount(content string) (int, error) {
 }
 
 type Message struct {
-	Role       string `json:"role"`
-	Content    string `json:"content"`
-	tokenCount int32
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// Attachments are files sent with the message.
+	Attachments []Attachment `json:"-"`
+	tokenCount  int32
+}
+
+// Attachment is a file sent to the model with a message.
+type Attachment struct {
+	Name     string
+	MimeType string
+	Data     []byte
 }
 
 func (m Message) TokenCount() int32 {
@@ -44,4 +53,9 @@ type LLMAnswer struct {
 	AnswerId         string `json:"id"`
 	Answer           string `json:"answer"`
 	ReasoningContent string `json:"reason_content"`
+	// FinishReason is why the model stopped, as reported by the provider.
+	FinishReason string `json:"finish_reason,omitempty"`
+	// Images are the pictures an image model made; they are stored as
+	// files of the answer.
+	Images []Attachment `json:"-"`
 }
@@ -0,0 +1,157 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+// fakeIdP is an identity provider that issues an ID token for any code
+// whose PKCE verifier matches the challenge sent to the authorize endpoint.
+type fakeIdP struct {
+	srv       *httptest.Server
+	key       *rsa.PrivateKey
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeIdP{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Metadata{
+			Issuer:                f.srv.URL,
+			AuthorizationEndpoint: f.srv.URL + "/authorize",
+			TokenEndpoint:         f.srv.URL + "/token",
+			JWKSURI:               f.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []jwk{{
+			Kty: "RSA", Kid: "k1", Use: "sig",
+			N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if id, secret, _ := r.BasicAuth(); id != "client" || secret != "secret" {
+			http.Error(w, "bad client", http.StatusUnauthorized)
+			return
+		}
+		if r.Form.Get("code") != "good-code" || CodeChallenge(r.Form.Get("code_verifier")) != f.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		claims := jwt.MapClaims{
+			"iss": f.srv.URL, "aud": "client", "sub": "u-1", "nonce": f.nonce,
+			"exp": time.Now().Add(time.Hour).Unix(), "iat": time.Now().Unix(),
+			"email": "ann@example.com", "email_verified": true, "groups": []string{"chat-admins", "eng"},
+		}
+		for k, v := range f.claims {
+			claims[k] = v
+		}
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		tok.Header["kid"] = "k1"
+		signed, err := tok.SignedString(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "access_token": "at"})
+	})
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeIdP) provider() *Provider {
+	return NewProvider(Config{
+		Issuer: f.srv.URL, ClientID: "client", ClientSecret: "secret", RedirectURL: "https://chat.example.com/cb",
+	}, f.srv.Client())
+}
+
+func TestAuthCodeFlow(t *testing.T) {
+	f := newFakeIdP(t)
+	p := f.provider()
+	ctx := context.Background()
+
+	verifier, _ := RandomString(32)
+	f.challenge = CodeChallenge(verifier)
+	f.nonce = "n-123"
+
+	authURL, err := p.AuthCodeURL(ctx, "st", f.nonce, f.challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(authURL)
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("code_challenge") != f.challenge || q.Get("code_challenge_method") != "S256" ||
+		q.Get("state") != "st" || q.Get("scope") != "openid email profile" {
+		t.Errorf("unexpected authorize URL: %s", authURL)
+	}
+
+	claims, err := p.Exchange(ctx, "good-code", verifier, f.nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "u-1" || claims.Email != "ann@example.com" || !claims.EmailVerified {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+	if strings.Join(claims.Groups, ",") != "chat-admins,eng" {
+		t.Errorf("unexpected groups: %v", claims.Groups)
+	}
+}
+
+func TestExchangeRejects(t *testing.T) {
+	f := newFakeIdP(t)
+	ctx := context.Background()
+	verifier, _ := RandomString(32)
+	f.challenge = CodeChallenge(verifier)
+	f.nonce = "n-123"
+
+	cases := []struct {
+		name     string
+		verifier string
+		nonce    string
+		claims   jwt.MapClaims
+	}{
+		{"wrong verifier", "other", "n-123", nil},
+		{"wrong nonce", verifier, "n-456", nil},
+		{"wrong audience", verifier, "n-123", jwt.MapClaims{"aud": "someone-else"}},
+		{"wrong issuer", verifier, "n-123", jwt.MapClaims{"iss": "https://evil.example.com"}},
+		{"expired", verifier, "n-123", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f.claims = tc.claims
+			if _, err := f.provider().Exchange(ctx, "good-code", tc.verifier, tc.nonce); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// RFC 7636 appendix B
+	if got := CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"); got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("CodeChallenge = %s", got)
+	}
+}
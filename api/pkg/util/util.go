@@ -7,12 +7,12 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
 	"github.com/pkoukk/tiktoken-go"
+	"github.com/swuecho/chat_backend/config"
 	"github.com/swuecho/chat_backend/dto"
 	"github.com/swuecho/chat_backend/middleware"
 )
@@ -65,17 +65,9 @@ func SetupSSE(w http.ResponseWriter) (http.Flusher, error) {
 	return flusher, nil
 }
 
-// PerWordStreamLimit returns the per-word streaming limit from the environment.
+// PerWordStreamLimit returns the configured per-word streaming limit.
 func PerWordStreamLimit() int {
-	val := os.Getenv("PER_WORD_STREAM_LIMIT")
-	if val == "" {
-		return 200
-	}
-	n, err := strconv.Atoi(val)
-	if err != nil {
-		return 200
-	}
-	return n
+	return config.Current().CHAT.PER_WORD_STREAM_LIMIT
 }
 
 // PaginationParams extracts limit and offset from HTTP query parameters.
@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// transcriptionTimeout bounds one transcription; recordings are short but
+// the whole file is uploaded before the model starts.
+const transcriptionTimeout = 2 * time.Minute
+
+// Transcribe sends the recording audio, named filename, to the
+// OpenAI-compatible transcription endpoint (/audio/transcriptions) of
+// chatModel on behalf of userID in session and returns the text. language is
+// an optional ISO-639-1 hint.
+func Transcribe(ctx context.Context, h Handler, session sqlc_queries.ChatSession, userID int32, chatModel sqlc_queries.ChatModel, audio io.Reader, filename, language string) (string, error) {
+	h.Config().RateLimiter.Wait(ctx)
+
+	if err := h.CheckModelAccess(ctx, session.Uuid, chatModel.Name, userID); err != nil {
+		return "", err
+	}
+	token, err := APIKey(ctx, h.Queries(), session.WorkspaceID, chatModel)
+	if err != nil {
+		return "", err
+	}
+	config, err := GenOpenAIConfig(chatModel, token, h.Config())
+	if err != nil {
+		return "", dto.ErrOpenAIConfigFailed.WithMessage("Failed to generate OpenAI config").WithDebugInfo(err.Error())
+	}
+	return transcribe(ctx, config, NormalizeOpenAIModelName(chatModel, chatModel.Name), audio, filename, language)
+}
+
+func transcribe(ctx context.Context, config openai.ClientConfig, model string, audio io.Reader, filename, language string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, transcriptionTimeout)
+	defer cancel()
+
+	resp, err := openai.NewClientWithConfig(config).CreateTranscription(ctx, openai.AudioRequest{
+		Model:    model,
+		FilePath: filename,
+		Reader:   audio,
+		Language: language,
+		Format:   openai.AudioResponseFormatJSON,
+	})
+	if err != nil {
+		slog.Info("Transcription request failed", "model", model, "baseURL", config.BaseURL, "error", err)
+		return "", dto.ErrOpenAIRequestFailed.WithMessage("Failed to transcribe audio").WithDebugInfo(err.Error())
+	}
+	return strings.TrimSpace(resp.Text), nil
+}
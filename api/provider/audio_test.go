@@ -0,0 +1,51 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestTranscribe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("unexpected authorization %q", r.Header.Get("Authorization"))
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(file)
+		if header.Filename != "voice.webm" || string(data) != "audio" {
+			t.Errorf("unexpected file %s: %q", header.Filename, data)
+		}
+		if r.FormValue("model") != "whisper-1" || r.FormValue("language") != "de" {
+			t.Errorf("unexpected form %v", r.Form)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"text": " Hallo Welt \n"})
+	}))
+	defer srv.Close()
+
+	base, err := GetModelBaseURL(srv.URL + "/v1/audio/transcriptions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := openai.DefaultConfig("key")
+	config.BaseURL = base
+	text, err := transcribe(context.Background(), config, "whisper-1", strings.NewReader("audio"), "voice.webm", "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Hallo Welt" {
+		t.Errorf("unexpected transcript %q", text)
+	}
+}
@@ -0,0 +1,38 @@
+package provider
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/secrets"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// APIKey returns the key used to call chatModel from a workspace: the
+// workspace's own credential, else the model's stored credential, else the
+// env var named by api_auth_key.
+func APIKey(ctx context.Context, q *sqlc_queries.Queries, workspaceID sql.NullInt32, chatModel sqlc_queries.ChatModel) (string, error) {
+	cred, err := q.GetModelCredential(ctx, sqlc_queries.GetModelCredentialParams{
+		WorkspaceID: workspaceID, ChatModelID: chatModel.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return os.Getenv(chatModel.ApiAuthKey), nil
+	}
+	if err != nil {
+		return "", dto.ErrInternalUnexpected.WithMessage("Failed to load model credential").WithDebugInfo(err.Error())
+	}
+	keyring, err := secrets.Default()
+	if err != nil {
+		return "", dto.ErrInternalUnexpected.WithMessage("Credential store is not configured").WithDebugInfo(err.Error())
+	}
+	plaintext, err := keyring.Open(secrets.Envelope{
+		KeyID: cred.MasterKeyID, DataKey: cred.WrappedKey, Ciphertext: cred.Ciphertext,
+	})
+	if err != nil {
+		return "", dto.ErrInternalUnexpected.WithMessage("Failed to decrypt model credential").WithDebugInfo(err.Error())
+	}
+	return string(plaintext), nil
+}
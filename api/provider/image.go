@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rotisserie/eris"
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/models"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// imageTimeout bounds one image generation; large images take a while.
+const imageTimeout = 3 * time.Minute
+
+// maxImageSize caps an image downloaded from the URL an endpoint returns
+// instead of inline data.
+const maxImageSize = 32 << 20
+
+// ImageChatModel answers with images made by an OpenAI Images-compatible
+// endpoint (/images/generations). The prompt is the last user message.
+type ImageChatModel struct {
+	h Handler
+}
+
+// NewImageChatModel creates a new ImageChatModel.
+func NewImageChatModel(h Handler) *ImageChatModel {
+	return &ImageChatModel{h: h}
+}
+
+func (m *ImageChatModel) Stream(ctx context.Context, chatSession sqlc_queries.ChatSession, chatCompletionMessages []models.Message, chatUuid string, regenerate bool, streamOutput bool) (<-chan StreamChunk, error) {
+	m.h.Config().RateLimiter.Wait(ctx)
+
+	if err := m.h.CheckModelAccess(ctx, chatSession.Uuid, chatSession.Model, chatSession.UserID); err != nil {
+		return nil, err
+	}
+
+	prompt := imagePrompt(chatCompletionMessages)
+	if prompt == "" {
+		return nil, dto.ErrValidationInvalidInput("describe the image to generate")
+	}
+
+	chatModel, err := GetChatModel(ctx, m.h.Queries(), chatSession.Model)
+	if err != nil {
+		return nil, err
+	}
+	token, err := APIKey(ctx, m.h.Queries(), chatSession.WorkspaceID, *chatModel)
+	if err != nil {
+		return nil, err
+	}
+	config, err := GenOpenAIConfig(*chatModel, token, m.h.Config())
+	if err != nil {
+		return nil, dto.ErrOpenAIConfigFailed.WithMessage("Failed to generate OpenAI config").WithDebugInfo(err.Error())
+	}
+	model := NormalizeOpenAIModelName(*chatModel, chatModel.Name)
+	answerID := generateAnswerID(chatUuid, regenerate)
+
+	ch := make(chan StreamChunk, 2)
+	go func() {
+		defer close(ch)
+		answer, err := generateImages(ctx, config, model, prompt, int(max(chatSession.N, 1)))
+		if err != nil {
+			ch <- StreamChunk{Err: err}
+			return
+		}
+		answer.AnswerId = answerID
+		ch <- StreamChunk{ID: answerID, Content: answer.Answer}
+		ch <- StreamChunk{ID: answerID, Done: true, FinalAnswer: answer}
+	}()
+	return ch, nil
+}
+
+// imagePrompt returns the content of the last user message.
+func imagePrompt(messages []models.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return strings.TrimSpace(messages[i].Content)
+		}
+	}
+	return ""
+}
+
+// generateImages asks for n images of prompt. The answer text is the
+// prompt the endpoint actually used, which DALL-E 3 rewrites.
+func generateImages(ctx context.Context, config openai.ClientConfig, model, prompt string, n int) (*models.LLMAnswer, error) {
+	ctx, cancel := context.WithTimeout(ctx, imageTimeout)
+	defer cancel()
+
+	resp, err := openai.NewClientWithConfig(config).CreateImage(ctx, openai.ImageRequest{
+		Prompt:         prompt,
+		Model:          model,
+		N:              n,
+		ResponseFormat: openai.CreateImageResponseFormatB64JSON,
+	})
+	if err != nil {
+		slog.Info("Image request failed", "model", model, "baseURL", config.BaseURL, "error", err)
+		return nil, dto.ErrOpenAIRequestFailed.WithMessage("Failed to generate image").WithDebugInfo(err.Error())
+	}
+	if len(resp.Data) == 0 {
+		return nil, dto.ErrOpenAIRequestFailed.WithMessage("Failed to generate image").WithDebugInfo("no image in response")
+	}
+
+	answer := &models.LLMAnswer{Answer: prompt, FinishReason: string(openai.FinishReasonStop)}
+	var revised []string
+	for i, item := range resp.Data {
+		data, err := imageData(ctx, config, item)
+		if err != nil {
+			return nil, dto.ErrOpenAIRequestFailed.WithMessage("Failed to read generated image").WithDebugInfo(err.Error())
+		}
+		// The MIME type is sniffed when the image is stored.
+		answer.Images = append(answer.Images, models.Attachment{
+			Name: fmt.Sprintf("image-%d", i+1),
+			Data: data,
+		})
+		if item.RevisedPrompt != "" {
+			revised = append(revised, item.RevisedPrompt)
+		}
+	}
+	if len(revised) > 0 {
+		answer.Answer = strings.Join(revised, "\n\n")
+	}
+	return answer, nil
+}
+
+// imageData returns the bytes of a generated image, which endpoints that
+// ignore response_format return as a URL.
+func imageData(ctx context.Context, config openai.ClientConfig, item openai.ImageResponseDataInner) ([]byte, error) {
+	if item.B64JSON != "" {
+		return base64.StdEncoding.DecodeString(item.B64JSON)
+	}
+	if item.URL == "" {
+		return nil, eris.New("image has neither data nor URL")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, item.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := config.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, eris.Errorf("image download failed: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageSize {
+		return nil, eris.Errorf("image is larger than %d bytes", maxImageSize)
+	}
+	return data, nil
+}
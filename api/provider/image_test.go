@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/swuecho/chat_backend/models"
+)
+
+func TestGenerateImages(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/images/generations":
+			var req openai.ImageRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Error(err)
+				return
+			}
+			if req.Model != "dall-e-3" || req.Prompt != "a red fox" || req.N != 2 || req.ResponseFormat != "b64_json" {
+				t.Errorf("unexpected request %+v", req)
+			}
+			json.NewEncoder(w).Encode(map[string]any{"data": []map[string]string{
+				{"b64_json": base64.StdEncoding.EncodeToString([]byte("first")), "revised_prompt": "a red fox in snow"},
+				{"url": srv.URL + "/files/second.png"},
+			}})
+		case "/files/second.png":
+			w.Write([]byte("second"))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	base, err := GetModelBaseURL(srv.URL + "/v1/images/generations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := openai.DefaultConfig("key")
+	config.BaseURL = base
+	answer, err := generateImages(context.Background(), config, "dall-e-3", "a red fox", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer.Answer != "a red fox in snow" {
+		t.Errorf("unexpected answer %q", answer.Answer)
+	}
+	if len(answer.Images) != 2 || string(answer.Images[0].Data) != "first" || string(answer.Images[1].Data) != "second" {
+		t.Errorf("unexpected images %+v", answer.Images)
+	}
+}
+
+func TestImagePrompt(t *testing.T) {
+	messages := []models.Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "draw a cat"},
+		{Role: "assistant", Content: "a cat"},
+		{Role: "user", Content: "  now a dog \n"},
+	}
+	if got := imagePrompt(messages); got != "now a dog" {
+		t.Errorf("unexpected prompt %q", got)
+	}
+	if got := imagePrompt(messages[:1]); got != "" {
+		t.Errorf("unexpected prompt %q", got)
+	}
+}
@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/metrics"
+	"github.com/swuecho/chat_backend/models"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentedModel records latency, throughput and error metrics of the
+// ChatModel it wraps, and traces each request with GenAI attributes.
+type instrumentedModel struct {
+	ChatModel
+	provider string
+}
+
+// Instrument wraps m so its requests are reported under provider on /metrics
+// and in traces.
+func Instrument(provider string, m ChatModel) ChatModel {
+	return &instrumentedModel{ChatModel: m, provider: provider}
+}
+
+func (m *instrumentedModel) Stream(ctx context.Context, session sqlc_queries.ChatSession, messages []models.Message, chatUuid string, regenerate bool, stream bool) (<-chan StreamChunk, error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "chat "+session.Model,
+		attribute.String("gen_ai.operation.name", "chat"),
+		attribute.String("gen_ai.system", m.provider),
+		attribute.String("gen_ai.request.model", session.Model),
+		attribute.Float64("gen_ai.request.temperature", session.Temperature),
+		attribute.Float64("gen_ai.request.top_p", session.TopP),
+		attribute.Int("gen_ai.request.max_tokens", int(session.MaxTokens)),
+		attribute.Bool("gen_ai.request.stream", stream),
+		attribute.String("chat.session_uuid", session.Uuid),
+	)
+	in, err := m.ChatModel.Stream(ctx, session, messages, chatUuid, regenerate, stream)
+	if err != nil {
+		m.recordError(session.Model, err)
+		tracing.End(span, err)
+		return nil, err
+	}
+
+	active := metrics.LLMActiveStreams.WithLabelValues(m.provider)
+	active.Inc()
+	out := make(chan StreamChunk, cap(in))
+	go func() {
+		defer close(out)
+		defer active.Dec()
+		var firstToken time.Duration
+		var streamErr error
+		defer func() { tracing.End(span, streamErr) }()
+		for chunk := range in {
+			if chunk.Err != nil {
+				streamErr = chunk.Err
+				m.recordError(session.Model, chunk.Err)
+			}
+			// A non-streamed answer carries its content in the Done chunk, so
+			// the first token and the answer are checked independently.
+			if chunk.Content != "" && firstToken == 0 {
+				firstToken = time.Since(start)
+				span.AddEvent("gen_ai.first_token")
+				metrics.LLMTimeToFirstToken.WithLabelValues(m.provider, session.Model).Observe(firstToken.Seconds())
+			}
+			if chunk.Done {
+				m.recordAnswer(span, session.Model, messages, chunk.FinalAnswer, time.Since(start), firstToken)
+			}
+			// Keep draining the provider if the caller has gone away, so its
+			// goroutine is not left blocked on a send.
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (m *instrumentedModel) recordAnswer(span trace.Span, model string, messages []models.Message, answer *models.LLMAnswer, total, firstToken time.Duration) {
+	metrics.LLMGenerationDuration.WithLabelValues(m.provider, model).Observe(total.Seconds())
+	if answer == nil {
+		return
+	}
+	promptChars := 0
+	for _, msg := range messages {
+		promptChars += len(msg.Content)
+	}
+	prompt := promptChars / dto.TokenEstimateRatio
+	completion := (len(answer.Answer) + len(answer.ReasoningContent)) / dto.TokenEstimateRatio
+	metrics.LLMTokens.WithLabelValues(m.provider, model, "prompt").Add(float64(prompt))
+	metrics.LLMTokens.WithLabelValues(m.provider, model, "completion").Add(float64(completion))
+	// Non-streamed answers arrive at once; throughput only means something
+	// after the first of several chunks.
+	if generating := total - firstToken; firstToken > 0 && generating > 0 && completion > 0 {
+		metrics.LLMTokensPerSecond.WithLabelValues(m.provider, model).Observe(float64(completion) / generating.Seconds())
+	}
+
+	span.SetAttributes(
+		attribute.String("gen_ai.response.id", answer.AnswerId),
+		// Token counts are estimated from text length, like the metrics.
+		attribute.Int("gen_ai.usage.input_tokens", prompt),
+		attribute.Int("gen_ai.usage.output_tokens", completion),
+	)
+	if answer.FinishReason != "" {
+		span.SetAttributes(attribute.StringSlice("gen_ai.response.finish_reasons", []string{answer.FinishReason}))
+	}
+}
+
+func (m *instrumentedModel) recordError(model string, err error) {
+	code := "unknown"
+	var apiErr dto.APIError
+	if errors.As(err, &apiErr) {
+		code = apiErr.Code
+	} else if errors.Is(err, context.Canceled) {
+		code = "canceled"
+	}
+	metrics.LLMErrors.WithLabelValues(m.provider, model, code).Inc()
+}
@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/metrics"
+	"github.com/swuecho/chat_backend/models"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+type scriptedModel struct {
+	chunks []StreamChunk
+	err    error
+}
+
+func (m scriptedModel) Stream(ctx context.Context, session sqlc_queries.ChatSession, messages []models.Message, chatUuid string, regenerate bool, stream bool) (<-chan StreamChunk, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	ch := make(chan StreamChunk, len(m.chunks))
+	for _, c := range m.chunks {
+		ch <- c
+	}
+	close(ch)
+	return ch, nil
+}
+
+func TestInstrumentRecordsAnswer(t *testing.T) {
+	session := sqlc_queries.ChatSession{Model: "metrics-test-model"}
+	m := Instrument("test", scriptedModel{chunks: []StreamChunk{
+		{Content: "Hello "},
+		{Content: "world"},
+		{Done: true, FinalAnswer: &models.LLMAnswer{Answer: "Hello world, again"}},
+	}})
+	answer, err := Complete(context.Background(), m, session, []models.Message{{Role: "user", Content: "12345678"}})
+	if err != nil || answer != "Hello world, again" {
+		t.Fatalf("Complete = %q, %v", answer, err)
+	}
+
+	if n := testutil.CollectAndCount(metrics.LLMTimeToFirstToken); n == 0 {
+		t.Error("time to first token was not observed")
+	}
+	if got := testutil.ToFloat64(metrics.LLMTokens.WithLabelValues("test", session.Model, "prompt")); got != 2 {
+		t.Errorf("prompt tokens = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(metrics.LLMTokens.WithLabelValues("test", session.Model, "completion")); got != 4 {
+		t.Errorf("completion tokens = %v, want 4", got)
+	}
+	if got := testutil.ToFloat64(metrics.LLMActiveStreams.WithLabelValues("test")); got != 0 {
+		t.Errorf("active streams = %v after the answer", got)
+	}
+}
+
+func TestInstrumentRecordsSingleChunkAnswer(t *testing.T) {
+	session := sqlc_queries.ChatSession{Model: "metrics-test-single"}
+	m := Instrument("test", scriptedModel{chunks: []StreamChunk{
+		{Content: "Hello world", Done: true, FinalAnswer: &models.LLMAnswer{Answer: "Hello world"}},
+	}})
+	if _, err := Complete(context.Background(), m, session, []models.Message{{Role: "user", Content: "12345678"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(metrics.LLMTokens.WithLabelValues("test", session.Model, "completion")); got != 2 {
+		t.Errorf("completion tokens = %v, want 2", got)
+	}
+}
+
+func TestInstrumentRecordsErrorCode(t *testing.T) {
+	session := sqlc_queries.ChatSession{Model: "metrics-test-error"}
+	m := Instrument("test", scriptedModel{err: dto.ErrTooManyRequests})
+	if _, err := m.Stream(context.Background(), session, nil, "", false, false); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := testutil.ToFloat64(metrics.LLMErrors.WithLabelValues("test", session.Model, dto.ErrTooManyRequests.Code)); got != 1 {
+		t.Errorf("errors = %v, want 1", got)
+	}
+}
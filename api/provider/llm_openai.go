@@ -15,6 +15,7 @@ import (
 	openai "github.com/sashabaranov/go-openai"
 	models "github.com/swuecho/chat_backend/models"
 	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/tracing"
 )
 
 func SupportedMimeTypes() mapset.Set[string] {
@@ -42,43 +43,35 @@ func SupportedMimeTypes() mapset.Set[string] {
 	)
 }
 
-func messagesToOpenAIMesages(messages []models.Message, chatFiles []sqlc_queries.ChatFile) []openai.ChatCompletionMessage {
-	open_ai_msgs := lo.Map(messages, func(m models.Message, _ int) openai.ChatCompletionMessage {
-		return openai.ChatCompletionMessage{Role: m.Role, Content: m.Content}
-	})
-	if len(chatFiles) == 0 {
-		return open_ai_msgs
-	}
-	parts := lo.Map(chatFiles, func(m sqlc_queries.ChatFile, _ int) openai.ChatMessagePart {
-		if SupportedMimeTypes().Contains(m.MimeType) {
-			return openai.ChatMessagePart{
-				Type: openai.ChatMessagePartTypeImageURL,
-				ImageURL: &openai.ChatMessageImageURL{
-					URL:    byteToImageURL(m.MimeType, m.Data),
-					Detail: openai.ImageURLDetailAuto,
-				},
-			}
-		} else {
-			return openai.ChatMessagePart{
-				Type: openai.ChatMessagePartTypeText,
-				Text: "file: " + m.Name + "\n<<<" + string(m.Data) + ">>>\n",
-			}
+// messagesToOpenAIMesages converts messages, sending the attachments of a
+// message as content parts after its text.
+func messagesToOpenAIMesages(messages []models.Message) []openai.ChatCompletionMessage {
+	return lo.Map(messages, func(m models.Message, _ int) openai.ChatCompletionMessage {
+		if len(m.Attachments) == 0 {
+			return openai.ChatCompletionMessage{Role: m.Role, Content: m.Content}
+		}
+		parts := []openai.ChatMessagePart{{Type: openai.ChatMessagePartTypeText, Text: m.Content}}
+		for _, a := range m.Attachments {
+			parts = append(parts, attachmentToOpenAIPart(a))
 		}
+		return openai.ChatCompletionMessage{Role: m.Role, MultiContent: parts}
 	})
-	// first user message
-	firstUserMessage, idx, found := lo.FindIndexOf(open_ai_msgs, func(msg openai.ChatCompletionMessage) bool { return msg.Role == "user" })
+}
 
-	if found {
-		slog.Debug("firstUserMessage before attach", "msg", firstUserMessage)
-		open_ai_msgs[idx].MultiContent = append(
-			[]openai.ChatMessagePart{
-				{Type: openai.ChatMessagePartTypeText, Text: firstUserMessage.Content},
-			}, parts...)
-		open_ai_msgs[idx].Content = ""
-		slog.Debug("firstUserMessage after attach", "msg", firstUserMessage)
+func attachmentToOpenAIPart(a models.Attachment) openai.ChatMessagePart {
+	if SupportedMimeTypes().Contains(a.MimeType) {
+		return openai.ChatMessagePart{
+			Type: openai.ChatMessagePartTypeImageURL,
+			ImageURL: &openai.ChatMessageImageURL{
+				URL:    byteToImageURL(a.MimeType, a.Data),
+				Detail: openai.ImageURLDetailAuto,
+			},
+		}
+	}
+	return openai.ChatMessagePart{
+		Type: openai.ChatMessagePartTypeText,
+		Text: "file: " + a.Name + "\n<<<" + string(a.Data) + ">>>\n",
 	}
-
-	return open_ai_msgs
 }
 
 func byteToImageURL(mimeType string, data []byte) string {
@@ -99,7 +92,7 @@ func GetModelBaseURL(apiUrl string) (string, error) {
 	}
 
 	basePath := strings.TrimSuffix(parsedUrl.Path, "/")
-	for _, suffix := range []string{"/chat/completions", "/completions"} {
+	for _, suffix := range []string{"/chat/completions", "/completions", "/audio/transcriptions", "/audio/speech", "/images/generations"} {
 		if strings.HasSuffix(basePath, suffix) {
 			basePath = strings.TrimSuffix(basePath, suffix)
 			break
@@ -135,15 +128,15 @@ func configOpenAIProxy(clientCfg *openai.ClientConfig, proxyURL string) {
 			Proxy: http.ProxyURL(proxyUrl),
 		}
 		clientCfg.HTTPClient = &http.Client{
-			Transport: transport,
+			Transport: tracing.Transport(transport),
 			Timeout:   120 * time.Second,
 		}
 	}
 }
 
-// GenOpenAIConfig creates an OpenAI client configuration from a chat model.
-func GenOpenAIConfig(chatModel sqlc_queries.ChatModel, cfg Config) (openai.ClientConfig, error) {
-	token := os.Getenv(chatModel.ApiAuthKey)
+// GenOpenAIConfig creates an OpenAI client configuration from a chat model
+// and the API key resolved by APIKey.
+func GenOpenAIConfig(chatModel sqlc_queries.ChatModel, token string, cfg Config) (openai.ClientConfig, error) {
 	baseUrl, err := GetModelBaseURL(chatModel.Url)
 	if err != nil {
 		return openai.ClientConfig{}, err
@@ -159,9 +152,11 @@ func GenOpenAIConfig(chatModel sqlc_queries.ChatModel, cfg Config) (openai.Clien
 			}
 			return azureModelMapping[model]
 		}
+		config.HTTPClient = &http.Client{Transport: tracing.Transport(nil)}
 	} else {
 		config = openai.DefaultConfig(token)
 		config.BaseURL = baseUrl
+		config.HTTPClient = &http.Client{Transport: tracing.Transport(nil)}
 		// two minutes timeout
 		// config.HTTPClient.Timeout = 120 * time.Second
 		configOpenAIProxy(&config, cfg.OpenAIProxy)
@@ -10,13 +10,13 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/swuecho/chat_backend/dto"
 	claude "github.com/swuecho/chat_backend/llm/claude"
 	"github.com/swuecho/chat_backend/models"
 	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/tracing"
 )
 
 // ClaudeResponse represents the response structure from Claude API
@@ -41,12 +41,11 @@ func NewClaude3ChatModel(h Handler) *Claude3ChatModel {
 }
 
 func (m *Claude3ChatModel) Stream(ctx context.Context, chatSession sqlc_queries.ChatSession, chatCompletionMessages []models.Message, chatUuid string, regenerate bool, stream bool) (<-chan StreamChunk, error) {
-	chatModel, err := GetChatModel(ctx, m.h.Queries(), chatSession.Model)
-	if err != nil {
+	if err := m.h.CheckModelAccess(ctx, chatSession.Uuid, chatSession.Model, chatSession.UserID); err != nil {
 		return nil, err
 	}
 
-	chatFiles, err := GetChatFiles(ctx, m.h.Queries(), chatSession.Uuid)
+	chatModel, err := GetChatModel(ctx, m.h.Queries(), chatSession.Model)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +60,7 @@ func (m *Claude3ChatModel) Stream(ctx context.Context, chatSession sqlc_queries.
 		return nil, dto.ErrSystemMessageError
 	}
 
-	messages := messagesToOpenAIMesages(claudeMessages, chatFiles)
+	messages := messagesToOpenAIMesages(claudeMessages)
 
 	jsonData := map[string]any{
 		"system":      chatCompletionMessages[0].Content,
@@ -83,7 +82,10 @@ func (m *Claude3ChatModel) Stream(ctx context.Context, chatSession sqlc_queries.
 		return nil, dto.ErrClaudeRequestFailed.WithDetail("failed to create HTTP request").WithDebugInfo(err.Error())
 	}
 
-	apiKey := os.Getenv(chatModel.ApiAuthKey)
+	apiKey, err := APIKey(ctx, m.h.Queries(), chatSession.WorkspaceID, *chatModel)
+	if err != nil {
+		return nil, err
+	}
 	if apiKey == "" {
 		return nil, dto.ErrAuthInvalidCredentials.WithDetail(fmt.Sprintf("missing API key for model %s", chatSession.Model))
 	}
@@ -102,7 +104,7 @@ func (m *Claude3ChatModel) Stream(ctx context.Context, chatSession sqlc_queries.
 		go func() {
 			defer close(ch)
 			req.Header.Set("Accept", "application/json")
-			client := http.Client{Timeout: 5 * time.Minute}
+			client := http.Client{Timeout: 5 * time.Minute, Transport: tracing.Transport(nil)}
 			llmAnswer, err := doGenerateClaude3(ctx, client, req)
 			if err != nil {
 				ch <- StreamChunk{Err: err}
@@ -144,13 +146,14 @@ func doGenerateClaude3(ctx context.Context, client http.Client, req *http.Reques
 	firstMessage := message.Content[0].Text
 
 	return &models.LLMAnswer{
-		AnswerId: message.ID,
-		Answer:   firstMessage,
+		AnswerId:     message.ID,
+		Answer:       firstMessage,
+		FinishReason: message.StopReason,
 	}, nil
 }
 
 func chatStreamClaude3(ctx context.Context, ch chan<- StreamChunk, req *http.Request, chatUuid string, regenerate bool) {
-	client := &http.Client{Timeout: 5 * time.Minute}
+	client := &http.Client{Timeout: 5 * time.Minute, Transport: tracing.Transport(nil)}
 	resp, err := client.Do(req)
 	if err != nil {
 		ch <- StreamChunk{Err: dto.ErrClaudeRequestFailed.WithMessage("Failed to process Claude streaming request").WithDebugInfo(err.Error())}
@@ -160,7 +163,7 @@ func chatStreamClaude3(ctx context.Context, ch chan<- StreamChunk, req *http.Req
 	ioreader := bufio.NewReaderSize(resp.Body, 1024)
 	defer resp.Body.Close()
 
-	var answer string
+	var answer, stopReason string
 	answerID := generateAnswerID(chatUuid, regenerate)
 	var headerData = []byte("data: ")
 	count := 0
@@ -169,7 +172,7 @@ func chatStreamClaude3(ctx context.Context, ch chan<- StreamChunk, req *http.Req
 		select {
 		case <-ctx.Done():
 			slog.Info("Claude stream cancelled by client", "error", ctx.Err())
-			ch <- StreamChunk{Done: true, FinalAnswer: &models.LLMAnswer{Answer: answer, AnswerId: answerID}}
+			ch <- StreamChunk{Done: true, FinalAnswer: &models.LLMAnswer{Answer: answer, AnswerId: answerID, FinishReason: "cancelled"}}
 			return
 		default:
 		}
@@ -213,6 +216,11 @@ func chatStreamClaude3(ctx context.Context, ch chan<- StreamChunk, req *http.Req
 				ch <- StreamChunk{ID: answerID, Content: delta}
 			}
 		}
+		if bytes.HasPrefix(line, []byte("{\"type\":\"message_delta\"")) {
+			if reason := claude.StopReasonFromMessageDelta(line); reason != "" {
+				stopReason = reason
+			}
+		}
 		if bytes.HasPrefix(line, []byte("{\"type\":\"content_block_delta\"")) {
 			delta := claude.AnswerFromBlockDelta(line)
 			answer += delta
@@ -226,8 +234,9 @@ func chatStreamClaude3(ctx context.Context, ch chan<- StreamChunk, req *http.Req
 		ID:   answerID,
 		Done: true,
 		FinalAnswer: &models.LLMAnswer{
-			Answer:   answer,
-			AnswerId: answerID,
+			Answer:       answer,
+			AnswerId:     answerID,
+			FinishReason: stopReason,
 		},
 	}
 }
@@ -47,7 +47,13 @@ func (m *CompletionChatModel) completionStream(ctx context.Context, ch chan<- St
 		return
 	}
 
-	config, err := GenOpenAIConfig(*chatModel, m.h.Config())
+	token, err := APIKey(ctx, m.h.Queries(), chatSession.WorkspaceID, *chatModel)
+	if err != nil {
+		ch <- StreamChunk{Err: err}
+		return
+	}
+
+	config, err := GenOpenAIConfig(*chatModel, token, m.h.Config())
 	if err != nil {
 		ch <- StreamChunk{Err: err}
 		return
@@ -10,12 +10,12 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
-	"os"
 
 	"github.com/swuecho/chat_backend/dto"
 	claude "github.com/swuecho/chat_backend/llm/claude"
 	"github.com/swuecho/chat_backend/models"
 	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/tracing"
 )
 
 // CustomModelResponse represents the response structure for custom models
@@ -40,6 +40,10 @@ func NewCustomChatModel(h Handler) *CustomChatModel {
 }
 
 func (m *CustomChatModel) Stream(ctx context.Context, chatSession sqlc_queries.ChatSession, chatCompletionMessages []models.Message, chatUuid string, regenerate bool, stream bool) (<-chan StreamChunk, error) {
+	if err := m.h.CheckModelAccess(ctx, chatSession.Uuid, chatSession.Model, chatSession.UserID); err != nil {
+		return nil, err
+	}
+
 	ch := make(chan StreamChunk, 10)
 	go func() {
 		defer close(ch)
@@ -55,7 +59,11 @@ func (m *CustomChatModel) customChatStream(ctx context.Context, ch chan<- Stream
 		return
 	}
 
-	apiKey := os.Getenv(chatModel.ApiAuthKey)
+	apiKey, err := APIKey(ctx, m.h.Queries(), chatSession.WorkspaceID, *chatModel)
+	if err != nil {
+		ch <- StreamChunk{Err: err}
+		return
+	}
 	url := chatModel.Url
 
 	prompt := claude.FormatClaudePrompt(chatCompletionMessages)
@@ -84,7 +92,7 @@ func (m *CustomChatModel) customChatStream(ctx context.Context, ch chan<- Stream
 
 	SetStreamingHeaders(req)
 
-	client := &http.Client{}
+	client := &http.Client{Transport: tracing.Transport(nil)}
 	resp, err := client.Do(req)
 	if err != nil {
 		ch <- StreamChunk{Err: dto.ErrChatRequestFailed.WithMessage("Failed to send custom model request").WithDebugInfo(err.Error())}
@@ -4,19 +4,20 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"io"
 	"log/slog"
 	"net/http"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/swuecho/chat_backend/dto"
 	"github.com/swuecho/chat_backend/llm/gemini"
 	"github.com/swuecho/chat_backend/models"
 	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/tracing"
 )
 
 // GeminiClient handles communication with the Gemini API
@@ -27,7 +28,7 @@ type GeminiClient struct {
 // NewGeminiClient creates a new Gemini API client
 func NewGeminiClient() *GeminiClient {
 	return &GeminiClient{
-		client: &http.Client{Timeout: 5 * time.Minute},
+		client: &http.Client{Timeout: 5 * time.Minute, Transport: tracing.Transport(nil)},
 	}
 }
 
@@ -44,20 +45,44 @@ func NewGeminiChatModel(h Handler) *GeminiChatModel {
 	}
 }
 
+// apiKey resolves the model's credential, falling back to GEMINI_API_KEY for
+// models that are not configured with one.
+func (m *GeminiChatModel) apiKey(ctx context.Context, chatSession sqlc_queries.ChatSession) (string, error) {
+	chatModel, err := m.h.Queries().ChatModelByName(ctx, chatSession.Model)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return os.Getenv("GEMINI_API_KEY"), nil
+		}
+		return "", dto.ErrInternalUnexpected.WithMessage("Failed to get chat model").WithDebugInfo(err.Error())
+	}
+	key, err := APIKey(ctx, m.h.Queries(), chatSession.WorkspaceID, chatModel)
+	if err != nil {
+		return "", err
+	}
+	if key == "" {
+		key = os.Getenv("GEMINI_API_KEY")
+	}
+	return key, nil
+}
+
 func (m *GeminiChatModel) Stream(ctx context.Context, chatSession sqlc_queries.ChatSession, messages []models.Message, chatUuid string, regenerate bool, stream bool) (<-chan StreamChunk, error) {
+	if err := m.h.CheckModelAccess(ctx, chatSession.Uuid, chatSession.Model, chatSession.UserID); err != nil {
+		return nil, err
+	}
+
 	answerID := generateAnswerID(chatUuid, regenerate)
 
-	chatFiles, err := GetChatFiles(ctx, m.h.Queries(), chatSession.Uuid)
+	payloadBytes, err := gemini.GenGemminPayload(messages)
 	if err != nil {
-		return nil, err
+		return nil, dto.ErrInternalUnexpected.WithMessage("Failed to generate Gemini payload").WithDebugInfo(err.Error())
 	}
 
-	payloadBytes, err := gemini.GenGemminPayload(messages, chatFiles)
+	apiKey, err := m.apiKey(ctx, chatSession)
 	if err != nil {
-		return nil, dto.ErrInternalUnexpected.WithMessage("Failed to generate Gemini payload").WithDebugInfo(err.Error())
+		return nil, err
 	}
 
-	url := gemini.BuildAPIURL(chatSession.Model, stream)
+	url := gemini.BuildAPIURL(chatSession.Model, stream, apiKey)
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payloadBytes))
 	if err != nil {
 		return nil, dto.ErrInternalUnexpected.WithMessage("Failed to create Gemini API request").WithDebugInfo(err.Error())
@@ -97,67 +122,6 @@ func (m *GeminiChatModel) Stream(ctx context.Context, chatSession sqlc_queries.C
 	return ch, nil
 }
 
-func GenerateChatTitle(ctx context.Context, model, chatText string) (string, error) {
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return "", dto.ErrInternalUnexpected.WithMessage("GEMINI_API_KEY environment variable not set")
-	}
-
-	if strings.TrimSpace(chatText) == "" {
-		return "", dto.ErrValidationInvalidInput("chat text cannot be empty")
-	}
-
-	messages := []models.Message{
-		{
-			Role:    "user",
-			Content: `Generate a short title (3-6 words) for this conversation. Output ONLY the title text, no quotes, no markdown, no prefixes like "Title:". Example: "Python list comprehension guide"`,
-		},
-		{
-			Role:    "user",
-			Content: chatText,
-		},
-	}
-
-	payloadBytes, err := gemini.GenGemminPayload(messages, nil)
-	if err != nil {
-		return "", dto.ErrInternalUnexpected.WithMessage("Failed to generate Gemini payload").WithDebugInfo(err.Error())
-	}
-
-	url := gemini.BuildAPIURL(model, false)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(payloadBytes))
-	if err != nil {
-		return "", dto.ErrInternalUnexpected.WithMessage("Failed to create Gemini API request").WithDebugInfo(err.Error())
-	}
-	req.Header.Set("Content-Type", "application/json")
-	answer, err := gemini.HandleRegularResponse(http.Client{Timeout: 1 * time.Minute}, req)
-	if err != nil {
-		return "", dto.ErrInternalUnexpected.WithMessage("Failed to handle Gemini response").WithDebugInfo(err.Error())
-	}
-
-	if answer == nil || answer.Answer == "" {
-		return "", dto.ErrInternalUnexpected.WithMessage("Empty response from Gemini")
-	}
-
-	title := strings.TrimSpace(answer.Answer)
-	title = strings.Trim(title, `"`)
-	title = strings.Trim(title, `*`)
-	title = strings.Trim(title, `#`)
-	title = strings.TrimPrefix(title, "Title:")
-	title = strings.TrimPrefix(title, "title:")
-	title = strings.TrimPrefix(title, "Title: ")
-	title = strings.TrimPrefix(title, "title: ")
-	title = strings.TrimSpace(title)
-	for strings.HasPrefix(title, "#") || strings.HasPrefix(title, "-") || strings.HasPrefix(title, "*") {
-		title = strings.TrimLeft(title, "#-* ")
-		title = strings.TrimSpace(title)
-	}
-	if title == "" {
-		return "", dto.ErrInternalUnexpected.WithMessage("Invalid title generated")
-	}
-
-	return FirstN(title, 100), nil
-}
-
 func (m *GeminiChatModel) handleStreamResponse(ctx context.Context, ch chan<- StreamChunk, req *http.Request, answerID string) {
 	resp, err := m.client.client.Do(req)
 	if err != nil {
@@ -10,13 +10,13 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/swuecho/chat_backend/dto"
 	"github.com/swuecho/chat_backend/models"
 	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/tracing"
 )
 
 // OllamaResponse represents the response structure from Ollama API
@@ -44,6 +44,10 @@ func NewOllamaChatModel(h Handler) *OllamaChatModel {
 }
 
 func (m *OllamaChatModel) Stream(ctx context.Context, chatSession sqlc_queries.ChatSession, chatCompletionMessages []models.Message, chatUuid string, regenerate bool, stream bool) (<-chan StreamChunk, error) {
+	if err := m.h.CheckModelAccess(ctx, chatSession.Uuid, chatSession.Model, chatSession.UserID); err != nil {
+		return nil, err
+	}
+
 	ch := make(chan StreamChunk, 10)
 	go func() {
 		defer close(ch)
@@ -71,7 +75,11 @@ func chatOllamStream(ctx context.Context, ch chan<- StreamChunk, h Handler, chat
 		return
 	}
 
-	apiKey := os.Getenv(chatModel.ApiAuthKey)
+	apiKey, err := APIKey(ctx, h.Queries(), chatSession.WorkspaceID, *chatModel)
+	if err != nil {
+		ch <- StreamChunk{Err: err}
+		return
+	}
 	authHeaderName := chatModel.ApiAuthHeader
 	if authHeaderName != "" {
 		req.Header.Set(authHeaderName, apiKey)
@@ -83,7 +91,7 @@ func chatOllamStream(ctx context.Context, ch chan<- StreamChunk, h Handler, chat
 	req.Header.Set("Connection", "keep-alive")
 	req.Header.Set("Access-Control-Allow-Origin", "*")
 
-	client := &http.Client{Timeout: 5 * time.Minute}
+	client := &http.Client{Timeout: 5 * time.Minute, Transport: tracing.Transport(nil)}
 	resp, err := client.Do(req)
 	if err != nil {
 		ch <- StreamChunk{Err: dto.ErrInternalUnexpected.WithMessage("Failed to create chat completion stream").WithDebugInfo(err.Error())}
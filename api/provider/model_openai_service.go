@@ -39,17 +39,17 @@ func (m *OpenAIChatModel) Stream(ctx context.Context, chatSession sqlc_queries.C
 		return nil, err
 	}
 
-	config, err := GenOpenAIConfig(*chatModel, m.h.Config())
+	token, err := APIKey(ctx, m.h.Queries(), chatSession.WorkspaceID, *chatModel)
 	if err != nil {
-		return nil, dto.ErrOpenAIConfigFailed.WithMessage("Failed to generate OpenAI config").WithDebugInfo(err.Error())
+		return nil, err
 	}
 
-	chatFiles, err := GetChatFiles(ctx, m.h.Queries(), chatSession.Uuid)
+	config, err := GenOpenAIConfig(*chatModel, token, m.h.Config())
 	if err != nil {
-		return nil, err
+		return nil, dto.ErrOpenAIConfigFailed.WithMessage("Failed to generate OpenAI config").WithDebugInfo(err.Error())
 	}
 
-	openaiReq := NewChatCompletionRequest(chatSession, chatCompletionMessages, chatFiles, streamOutput)
+	openaiReq := NewChatCompletionRequest(chatSession, chatCompletionMessages, streamOutput)
 	openaiReq.Model = NormalizeOpenAIModelName(*chatModel, openaiReq.Model)
 	if len(openaiReq.Messages) <= 1 {
 		return nil, dto.ErrSystemMessageError
@@ -85,8 +85,9 @@ func handleRegularResponse(ctx context.Context, ch chan<- StreamChunk, client *o
 		Content: completion.Choices[0].Message.Content,
 		Done:    true,
 		FinalAnswer: &models.LLMAnswer{
-			Answer:   completion.Choices[0].Message.Content,
-			AnswerId: completion.ID,
+			Answer:       completion.Choices[0].Message.Content,
+			AnswerId:     completion.ID,
+			FinishReason: string(completion.Choices[0].FinishReason),
 		},
 	}
 }
@@ -111,6 +112,7 @@ func doChatStream(ctx context.Context, ch chan<- StreamChunk, client *openai.Cli
 	}()
 
 	var answerID string
+	var finishReason string
 	var hasReason bool
 	var reasonTagOpened bool
 	var reasonTagClosed bool
@@ -128,7 +130,7 @@ func doChatStream(ctx context.Context, ch chan<- StreamChunk, client *openai.Cli
 		select {
 		case <-ctx.Done():
 			slog.Info("Stream cancelled by client", "error", ctx.Err())
-			llmAnswer := models.LLMAnswer{Answer: TextBuffer.String("\n"), AnswerId: answerID}
+			llmAnswer := models.LLMAnswer{Answer: TextBuffer.String("\n"), AnswerId: answerID, FinishReason: "cancelled"}
 			if hasReason {
 				llmAnswer.ReasoningContent = reasonBuffer.String("\n")
 			}
@@ -147,7 +149,7 @@ func doChatStream(ctx context.Context, ch chan<- StreamChunk, client *openai.Cli
 					ch <- StreamChunk{Err: dto.ErrOpenAIStreamFailed.WithMessage("Stream closed without content").WithDebugInfo(errMsg)}
 					return
 				}
-				llmAnswer := models.LLMAnswer{Answer: TextBuffer.String("\n"), AnswerId: answerID}
+				llmAnswer := models.LLMAnswer{Answer: TextBuffer.String("\n"), AnswerId: answerID, FinishReason: finishReason}
 				if hasReason {
 					llmAnswer.ReasoningContent = reasonBuffer.String("\n")
 				}
@@ -167,6 +169,9 @@ func doChatStream(ctx context.Context, ch chan<- StreamChunk, client *openai.Cli
 
 		textIdx := response.Choices[0].Index
 		delta := response.Choices[0].Delta
+		if reason := response.Choices[0].FinishReason; reason != "" {
+			finishReason = string(reason)
+		}
 
 		TextBuffer.AppendByIndex(textIdx, delta.Content)
 		if len(delta.ReasoningContent) > 0 {
@@ -214,8 +219,8 @@ func NewUserMessage(content string) openai.ChatCompletionMessage {
 }
 
 // NewChatCompletionRequest creates an OpenAI chat completion request from session and messages
-func NewChatCompletionRequest(chatSession sqlc_queries.ChatSession, chatCompletionMessages []models.Message, chatFiles []sqlc_queries.ChatFile, streamOutput bool) openai.ChatCompletionRequest {
-	openaiMessages := messagesToOpenAIMesages(chatCompletionMessages, chatFiles)
+func NewChatCompletionRequest(chatSession sqlc_queries.ChatSession, chatCompletionMessages []models.Message, streamOutput bool) openai.ChatCompletionRequest {
+	openaiMessages := messagesToOpenAIMesages(chatCompletionMessages)
 
 	for _, m := range openaiMessages {
 		b, _ := m.MarshalJSON()
@@ -3,6 +3,7 @@ package provider
 import (
 	"testing"
 
+	"github.com/swuecho/chat_backend/models"
 	"github.com/swuecho/chat_backend/sqlc_queries"
 )
 
@@ -74,3 +75,32 @@ func Test_normalizeOpenAIModelName(t *testing.T) {
 		})
 	}
 }
+
+func TestMessagesToOpenAIMessagesAttachments(t *testing.T) {
+	msgs := messagesToOpenAIMesages([]models.Message{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "first question"},
+		{Role: "assistant", Content: "answer"},
+		{Role: "user", Content: "what is in this screenshot?", Attachments: []models.Attachment{
+			{Name: "shot.png", MimeType: "image/png", Data: []byte{1, 2, 3}},
+			{Name: "notes.txt", MimeType: "text/plain", Data: []byte("hello")},
+		}},
+	})
+
+	if msgs[1].Content != "first question" || msgs[1].MultiContent != nil {
+		t.Errorf("a message without attachments should stay plain text, got %+v", msgs[1])
+	}
+	last := msgs[3]
+	if last.Content != "" || len(last.MultiContent) != 3 {
+		t.Fatalf("expected text and two attachment parts, got %+v", last)
+	}
+	if last.MultiContent[0].Text != "what is in this screenshot?" {
+		t.Errorf("the question should come first, got %q", last.MultiContent[0].Text)
+	}
+	if img := last.MultiContent[1].ImageURL; img == nil || img.URL != "data:image/png;base64,AQID" {
+		t.Errorf("unexpected image part %+v", last.MultiContent[1])
+	}
+	if last.MultiContent[2].Text != "file: notes.txt\n<<<hello>>>\n" {
+		t.Errorf("unexpected text file part %q", last.MultiContent[2].Text)
+	}
+}
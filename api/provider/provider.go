@@ -1,7 +1,8 @@
 // Package provider defines LLM provider interfaces and shared configuration.
 //
 // Providers implement the ChatModel interface to support different LLM backends:
-// OpenAI, Claude, Gemini, Ollama, and custom API-compatible models.
+// OpenAI, Claude, Gemini, Ollama, custom API-compatible models, and
+// OpenAI Images-compatible image generation.
 //
 // The Handler interface decouples providers from the HTTP layer,
 // allowing them to be tested independently.
@@ -48,3 +49,40 @@ type Handler interface {
 	CheckModelAccess(ctx context.Context, chatSessionUuid, model string, userID int32) error
 	Config() Config
 }
+
+// NewChatModel returns the instrumented ChatModel implementation for a
+// chat_model api_type. Unknown types use the OpenAI-compatible implementation.
+func NewChatModel(apiType string, h Handler) ChatModel {
+	switch apiType {
+	case "claude":
+		return Instrument(apiType, NewClaude3ChatModel(h))
+	case "ollama":
+		return Instrument(apiType, NewOllamaChatModel(h))
+	case "gemini":
+		return Instrument(apiType, NewGeminiChatModel(h))
+	case "custom":
+		return Instrument(apiType, NewCustomChatModel(h))
+	case "image":
+		return Instrument(apiType, NewImageChatModel(h))
+	default:
+		return Instrument("openai", NewOpenAIChatModel(h))
+	}
+}
+
+// Complete runs a non-streaming request and returns the full answer.
+func Complete(ctx context.Context, m ChatModel, session sqlc_queries.ChatSession, messages []models.Message) (string, error) {
+	ch, err := m.Stream(ctx, session, messages, "", false, false)
+	if err != nil {
+		return "", err
+	}
+	var answer string
+	for chunk := range ch {
+		if chunk.Err != nil {
+			return "", chunk.Err
+		}
+		if chunk.Done && chunk.FinalAnswer != nil {
+			answer = chunk.FinalAnswer.Answer
+		}
+	}
+	return answer, nil
+}
@@ -0,0 +1,236 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/tracing"
+)
+
+// Speech is synthesized audio, read as the engine produces it.
+type Speech struct {
+	io.ReadCloser
+	MimeType string
+}
+
+// Synthesizer turns text into speech.
+type Synthesizer interface {
+	// Synthesize speaks text with voice at speed, 1 being normal. An empty
+	// voice is the engine's default.
+	Synthesize(ctx context.Context, text, voice string, speed float64) (Speech, error)
+	// MaxInput is the longest text in bytes one request takes; 0 is no limit.
+	MaxInput() int
+	// Name identifies the engine and model, so speech cached for another
+	// engine is not reused.
+	Name() string
+}
+
+// openAISpeechMaxInput is the input limit of /audio/speech.
+const openAISpeechMaxInput = 4096
+
+// defaultOpenAIVoice is used when the user has not chosen a voice.
+const defaultOpenAIVoice = "alloy"
+
+type openAISynthesizer struct {
+	client *openai.Client
+	model  string
+}
+
+// NewOpenAISynthesizer returns a Synthesizer for chatModel, served at the
+// OpenAI-compatible /audio/speech endpoint, used on behalf of userID in
+// session. Speech is MP3, so the audio of consecutive requests can be
+// concatenated.
+func NewOpenAISynthesizer(ctx context.Context, h Handler, session sqlc_queries.ChatSession, userID int32, chatModel sqlc_queries.ChatModel) (Synthesizer, error) {
+	if err := h.CheckModelAccess(ctx, session.Uuid, chatModel.Name, userID); err != nil {
+		return nil, err
+	}
+	token, err := APIKey(ctx, h.Queries(), session.WorkspaceID, chatModel)
+	if err != nil {
+		return nil, err
+	}
+	config, err := GenOpenAIConfig(chatModel, token, h.Config())
+	if err != nil {
+		return nil, dto.ErrOpenAIConfigFailed.WithMessage("Failed to generate OpenAI config").WithDebugInfo(err.Error())
+	}
+	return newOpenAISynthesizer(config, NormalizeOpenAIModelName(chatModel, chatModel.Name)), nil
+}
+
+func newOpenAISynthesizer(config openai.ClientConfig, model string) *openAISynthesizer {
+	return &openAISynthesizer{client: openai.NewClientWithConfig(config), model: model}
+}
+
+func (s *openAISynthesizer) Synthesize(ctx context.Context, text, voice string, speed float64) (Speech, error) {
+	if voice == "" {
+		voice = defaultOpenAIVoice
+	}
+	resp, err := s.client.CreateSpeech(ctx, openai.CreateSpeechRequest{
+		Model:          openai.SpeechModel(s.model),
+		Input:          text,
+		Voice:          openai.SpeechVoice(voice),
+		ResponseFormat: openai.SpeechResponseFormatMp3,
+		Speed:          speed,
+	})
+	if err != nil {
+		slog.Info("Speech request failed", "model", s.model, "error", err)
+		return Speech{}, dto.ErrOpenAIRequestFailed.WithMessage("Failed to synthesize speech").WithDebugInfo(err.Error())
+	}
+	return Speech{ReadCloser: resp.ReadCloser, MimeType: "audio/mpeg"}, nil
+}
+
+func (s *openAISynthesizer) MaxInput() int { return openAISpeechMaxInput }
+func (s *openAISynthesizer) Name() string  { return "openai:" + s.model }
+
+type localSynthesizer struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewLocalSynthesizer returns a Synthesizer for a TTS engine at baseURL
+// speaking the Coqui TTS server API: GET /api/tts?text=...&speaker_id=...
+// returning WAV. Such engines have no speed setting.
+func NewLocalSynthesizer(baseURL string) Synthesizer {
+	return &localSynthesizer{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Transport: tracing.Transport(nil)},
+	}
+}
+
+func (s *localSynthesizer) Synthesize(ctx context.Context, text, voice string, speed float64) (Speech, error) {
+	query := url.Values{"text": {text}}
+	if voice != "" {
+		query.Set("speaker_id", voice)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/api/tts?"+query.Encode(), nil)
+	if err != nil {
+		return Speech{}, dto.ErrInternalUnexpected.WithMessage("Failed to build speech request").WithDebugInfo(err.Error())
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Speech{}, dto.ErrInternalUnexpected.WithMessage("Failed to reach the TTS engine").WithDebugInfo(err.Error())
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Speech{}, dto.ErrInternalUnexpected.WithMessage("TTS engine failed: " + resp.Status).WithDebugInfo(string(msg))
+	}
+	mimeType := resp.Header.Get("Content-Type")
+	if mimeType == "" {
+		mimeType = "audio/wav"
+	}
+	return Speech{ReadCloser: resp.Body, MimeType: mimeType}, nil
+}
+
+func (s *localSynthesizer) MaxInput() int { return 0 }
+func (s *localSynthesizer) Name() string  { return "local" }
+
+// VoiceKey names the speech of synth with voice at speed, for caching.
+func VoiceKey(synth Synthesizer, voice string, speed float64) string {
+	return synth.Name() + "/" + voice + "/" + strconv.FormatFloat(speed, 'f', -1, 64)
+}
+
+// Speak synthesizes text, in parts of at most synth.MaxInput() bytes split
+// at sentence ends. The first part is requested before Speak returns, so
+// its errors are reported up front; each following part is requested once
+// the audio before it has been read, so long answers start playing early.
+func Speak(ctx context.Context, synth Synthesizer, text, voice string, speed float64) (Speech, error) {
+	parts := splitSpeechText(text, synth.MaxInput())
+	if len(parts) == 0 {
+		return Speech{}, dto.ErrValidationInvalidInput("nothing to speak")
+	}
+	first, err := synth.Synthesize(ctx, parts[0], voice, speed)
+	if err != nil {
+		return Speech{}, err
+	}
+	if len(parts) == 1 {
+		return first, nil
+	}
+	return Speech{
+		ReadCloser: &speechParts{ctx: ctx, synth: synth, voice: voice, speed: speed, current: first.ReadCloser, rest: parts[1:]},
+		MimeType:   first.MimeType,
+	}, nil
+}
+
+// speechParts reads the audio of each part in turn.
+type speechParts struct {
+	ctx     context.Context
+	synth   Synthesizer
+	voice   string
+	speed   float64
+	current io.ReadCloser
+	rest    []string
+}
+
+func (p *speechParts) Read(b []byte) (int, error) {
+	for {
+		if p.current == nil {
+			return 0, io.EOF
+		}
+		n, err := p.current.Read(b)
+		if err != io.EOF {
+			return n, err
+		}
+		p.current.Close()
+		p.current = nil
+		if len(p.rest) > 0 {
+			next, err := p.synth.Synthesize(p.ctx, p.rest[0], p.voice, p.speed)
+			if err != nil {
+				return n, err
+			}
+			p.current, p.rest = next.ReadCloser, p.rest[1:]
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (p *speechParts) Close() error {
+	if p.current != nil {
+		return p.current.Close()
+	}
+	return nil
+}
+
+// splitSpeechText splits text into parts of at most max bytes, preferring
+// to break after a paragraph, then a sentence, then a word. max 0 keeps
+// text whole.
+func splitSpeechText(text string, max int) []string {
+	text = strings.TrimSpace(text)
+	var parts []string
+	for max > 0 && len(text) > max {
+		cut := -1
+		for _, sep := range []string{"\n\n", "\n", ". ", "! ", "? ", "。", " "} {
+			if i := strings.LastIndex(text[:max], sep); i > 0 {
+				cut = i + len(sep)
+				break
+			}
+		}
+		if cut < 0 {
+			// one long word: cut at a character boundary
+			cut = max
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				_, cut = utf8.DecodeRuneInString(text)
+			}
+		}
+		if part := strings.TrimSpace(text[:cut]); part != "" {
+			parts = append(parts, part)
+		}
+		text = strings.TrimSpace(text[cut:])
+	}
+	if text != "" {
+		parts = append(parts, text)
+	}
+	return parts
+}
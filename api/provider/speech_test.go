@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// echoSynthesizer speaks text as itself.
+type echoSynthesizer struct {
+	max   int
+	calls []string
+}
+
+func (s *echoSynthesizer) Synthesize(ctx context.Context, text, voice string, speed float64) (Speech, error) {
+	s.calls = append(s.calls, text)
+	return Speech{ReadCloser: io.NopCloser(strings.NewReader("[" + text + "]")), MimeType: "text/plain"}, nil
+}
+
+func (s *echoSynthesizer) MaxInput() int { return s.max }
+func (s *echoSynthesizer) Name() string  { return "echo" }
+
+func TestSplitSpeechText(t *testing.T) {
+	cases := []struct {
+		text string
+		max  int
+		want []string
+	}{
+		{"  one part  ", 0, []string{"one part"}},
+		{"First sentence. Second sentence.", 20, []string{"First sentence.", "Second sentence."}},
+		{"Para one.\n\nPara two is longer", 25, []string{"Para one.", "Para two is longer"}},
+		{"abcdefgh", 3, []string{"abc", "def", "gh"}},
+		{"ééé", 3, []string{"é", "é", "é"}},
+		{"", 10, nil},
+	}
+	for _, c := range cases {
+		got := splitSpeechText(c.text, c.max)
+		if strings.Join(got, "|") != strings.Join(c.want, "|") {
+			t.Errorf("splitSpeechText(%q, %d) = %q, want %q", c.text, c.max, got, c.want)
+		}
+		for _, part := range got {
+			if c.max > 0 && len(part) > c.max {
+				t.Errorf("part %q is longer than %d", part, c.max)
+			}
+		}
+	}
+}
+
+func TestSpeakParts(t *testing.T) {
+	synth := &echoSynthesizer{max: 16}
+	speech, err := Speak(context.Background(), synth, "Hello there. How are you today?", "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(synth.calls) != 1 {
+		t.Fatalf("expected only the first part requested up front, got %q", synth.calls)
+	}
+	audio, err := io.ReadAll(speech)
+	if err != nil {
+		t.Fatal(err)
+	}
+	speech.Close()
+	if string(audio) != "[Hello there.][How are you][today?]" {
+		t.Errorf("unexpected audio %q", audio)
+	}
+
+	if _, err := Speak(context.Background(), synth, "   ", "", 1); err == nil {
+		t.Error("expected an error for empty text")
+	}
+}
+
+func TestOpenAISynthesizer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/speech" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var req openai.CreateSpeechRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "tts-1" || req.Voice != "alloy" || req.Speed != 1.5 || req.Input != "hi" {
+			t.Errorf("unexpected request %+v", req)
+		}
+		w.Write([]byte("mp3"))
+	}))
+	defer srv.Close()
+
+	config := openai.DefaultConfig("key")
+	config.BaseURL = srv.URL + "/v1"
+	speech, err := newOpenAISynthesizer(config, "tts-1").Synthesize(context.Background(), "hi", "", 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer speech.Close()
+	audio, _ := io.ReadAll(speech)
+	if string(audio) != "mp3" || speech.MimeType != "audio/mpeg" {
+		t.Errorf("unexpected speech %q %s", audio, speech.MimeType)
+	}
+}
+
+func TestLocalSynthesizer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tts" || r.URL.Query().Get("text") != "hi there" || r.URL.Query().Get("speaker_id") != "p225" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write([]byte("RIFF"))
+	}))
+	defer srv.Close()
+
+	speech, err := NewLocalSynthesizer(srv.URL+"/").Synthesize(context.Background(), "hi there", "p225", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer speech.Close()
+	if audio, _ := io.ReadAll(speech); string(audio) != "RIFF" || speech.MimeType != "audio/wav" {
+		t.Errorf("unexpected speech %q %s", audio, speech.MimeType)
+	}
+}
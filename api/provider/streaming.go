@@ -117,15 +117,6 @@ func GetChatModel(ctx context.Context, q *sqlc_queries.Queries, modelName string
 	return &chatModel, nil
 }
 
-// GetChatFiles retrieves chat files for a session.
-func GetChatFiles(ctx context.Context, q *sqlc_queries.Queries, sessionUUID string) ([]sqlc_queries.ChatFile, error) {
-	chatFiles, err := q.ListChatFilesWithContentBySessionUUID(ctx, sessionUUID)
-	if err != nil {
-		return nil, dto.ErrInternalUnexpected.WithMessage("Failed to get chat files").WithDebugInfo(err.Error())
-	}
-	return chatFiles, nil
-}
-
 // buildStreamResponse creates a simple streaming response struct.
 func buildStreamResponse(answerID, content string) openai.ChatCompletionStreamResponse {
 	return openai.ChatCompletionStreamResponse{
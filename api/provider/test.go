@@ -21,11 +21,6 @@ func NewTestChatModel(h Handler) *TestChatModel {
 func (m *TestChatModel) Stream(ctx context.Context, session sqlc_queries.ChatSession,
 	messages []models.Message, chatUuid string, regenerate bool, stream bool) (<-chan StreamChunk, error) {
 
-	chatFiles, err := GetChatFiles(ctx, m.h.Queries(), session.Uuid)
-	if err != nil {
-		return nil, err
-	}
-
 	answerID := generateAnswerID(chatUuid, regenerate)
 	answer := "Hi, I am a chatbot. I can help you to find the best answer for your question. Please ask me a question."
 
@@ -35,7 +30,7 @@ func (m *TestChatModel) Stream(ctx context.Context, session sqlc_queries.ChatSes
 		ch <- StreamChunk{ID: answerID, Content: answer}
 
 		if session.Debug {
-			openaiReq := NewChatCompletionRequest(session, messages, chatFiles, false)
+			openaiReq := NewChatCompletionRequest(session, messages, false)
 			reqJ, _ := json.Marshal(openaiReq)
 			ch <- StreamChunk{ID: answerID, Content: "\n" + string(reqJ)}
 		}
@@ -0,0 +1,176 @@
+// Package secrets envelope-encrypts provider credentials.
+//
+// Each secret is encrypted with its own random data key, and the data key is
+// encrypted ("wrapped") with a master key that never leaves the process
+// environment. Changing the master key only requires re-wrapping data keys.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"sync/atomic"
+
+	"github.com/rotisserie/eris"
+)
+
+// DefaultKeyID names a master key given without an id.
+const DefaultKeyID = "default"
+
+// ErrNoKeyring is returned when no master key is configured.
+var ErrNoKeyring = eris.New("credential master key is not configured")
+
+// Envelope is an encrypted secret as stored in the database.
+type Envelope struct {
+	// KeyID is the master key that wrapped DataKey.
+	KeyID      string
+	DataKey    []byte
+	Ciphertext []byte
+}
+
+// Keyring holds the master keys. The primary key wraps new data keys; the
+// others can still unwrap data keys written before a rotation.
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// ParseKeyring reads master keys from "id:base64,id:base64", primary first.
+// A single base64 key without an id is accepted as DefaultKeyID. Keys must
+// decode to 32 bytes.
+func ParseKeyring(spec string) (*Keyring, error) {
+	k := &Keyring{keys: map[string][]byte{}}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, encoded := DefaultKeyID, part
+		if i := strings.Index(part, ":"); i >= 0 {
+			id, encoded = part[:i], part[i+1:]
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, eris.Wrapf(err, "master key %q is not valid base64", id)
+		}
+		if len(key) != 32 {
+			return nil, eris.Errorf("master key %q must be 32 bytes, got %d", id, len(key))
+		}
+		if _, dup := k.keys[id]; dup {
+			return nil, eris.Errorf("master key %q is listed twice", id)
+		}
+		k.keys[id] = key
+		if k.primary == "" {
+			k.primary = id
+		}
+	}
+	if k.primary == "" {
+		return nil, ErrNoKeyring
+	}
+	return k, nil
+}
+
+// PrimaryID returns the id of the key that wraps new data keys.
+func (k *Keyring) PrimaryID() string { return k.primary }
+
+// Seal encrypts plaintext under a new data key wrapped by the primary key.
+func (k *Keyring) Seal(plaintext []byte) (Envelope, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return Envelope{}, eris.Wrap(err, "failed to generate data key")
+	}
+	ciphertext, err := seal(dataKey, plaintext)
+	if err != nil {
+		return Envelope{}, err
+	}
+	wrapped, err := seal(k.keys[k.primary], dataKey)
+	if err != nil {
+		return Envelope{}, err
+	}
+	return Envelope{KeyID: k.primary, DataKey: wrapped, Ciphertext: ciphertext}, nil
+}
+
+// Open decrypts an envelope.
+func (k *Keyring) Open(e Envelope) ([]byte, error) {
+	dataKey, err := k.unwrap(e)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(dataKey, e.Ciphertext)
+	return plaintext, eris.Wrap(err, "failed to decrypt secret")
+}
+
+// Rewrap re-encrypts the envelope's data key with the primary key. The
+// secret itself is not re-encrypted.
+func (k *Keyring) Rewrap(e Envelope) (Envelope, error) {
+	if e.KeyID == k.primary {
+		return e, nil
+	}
+	dataKey, err := k.unwrap(e)
+	if err != nil {
+		return e, err
+	}
+	wrapped, err := seal(k.keys[k.primary], dataKey)
+	if err != nil {
+		return e, err
+	}
+	return Envelope{KeyID: k.primary, DataKey: wrapped, Ciphertext: e.Ciphertext}, nil
+}
+
+func (k *Keyring) unwrap(e Envelope) ([]byte, error) {
+	master, ok := k.keys[e.KeyID]
+	if !ok {
+		return nil, eris.Errorf("master key %q is not configured", e.KeyID)
+	}
+	dataKey, err := open(master, e.DataKey)
+	return dataKey, eris.Wrap(err, "failed to unwrap data key")
+}
+
+// seal encrypts with AES-256-GCM and prepends the nonce.
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, eris.Wrap(err, "failed to generate nonce")
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, eris.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, eris.Wrap(err, "invalid key")
+	}
+	return cipher.NewGCM(block)
+}
+
+var current atomic.Pointer[Keyring]
+
+// SetDefault installs the process-wide keyring. It may be called again to
+// load new master keys without a restart; nil disables the credential store.
+func SetDefault(k *Keyring) { current.Store(k) }
+
+// Default returns the process-wide keyring, or ErrNoKeyring.
+func Default() (*Keyring, error) {
+	if k := current.Load(); k != nil {
+		return k, nil
+	}
+	return nil, ErrNoKeyring
+}
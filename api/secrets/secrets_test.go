@@ -0,0 +1,77 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func TestSealOpen(t *testing.T) {
+	k, err := ParseKeyring(testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.PrimaryID() != DefaultKeyID {
+		t.Errorf("primary = %q", k.PrimaryID())
+	}
+	e, err := k.Seal([]byte("sk-test-123"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(e.Ciphertext, []byte("sk-test")) {
+		t.Error("ciphertext contains the secret")
+	}
+	got, err := k.Open(e)
+	if err != nil || string(got) != "sk-test-123" {
+		t.Fatalf("Open = %q, %v", got, err)
+	}
+
+	e.Ciphertext[len(e.Ciphertext)-1] ^= 1
+	if _, err := k.Open(e); err == nil {
+		t.Error("tampered ciphertext should not decrypt")
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	old, _ := ParseKeyring("v1:" + testKey(1))
+	e, err := old.Seal([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := ParseKeyring("v2:" + testKey(2) + ",v1:" + testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := rotated.Open(e); err != nil || string(got) != "secret" {
+		t.Fatalf("old envelope should still open: %q, %v", got, err)
+	}
+	re, err := rotated.Rewrap(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if re.KeyID != "v2" {
+		t.Errorf("rewrapped key id = %q", re.KeyID)
+	}
+
+	onlyNew, _ := ParseKeyring("v2:" + testKey(2))
+	if got, err := onlyNew.Open(re); err != nil || string(got) != "secret" {
+		t.Fatalf("rewrapped envelope = %q, %v", got, err)
+	}
+	if _, err := onlyNew.Open(e); err == nil {
+		t.Error("envelope wrapped by a removed key should not open")
+	}
+}
+
+func TestParseKeyringErrors(t *testing.T) {
+	for _, spec := range []string{"", "not base64!", base64.StdEncoding.EncodeToString([]byte("short")), "a:" + testKey(1) + ",a:" + testKey(2)} {
+		if _, err := ParseKeyring(spec); err == nil {
+			t.Errorf("ParseKeyring(%q) should fail", strings.TrimSpace(spec))
+		}
+	}
+}
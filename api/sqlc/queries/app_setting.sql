@@ -0,0 +1,9 @@
+-- name: GetAppSetting :one
+SELECT * FROM app_setting
+WHERE key = $1;
+
+-- name: UpsertAppSetting :exec
+INSERT INTO app_setting (key, value)
+VALUES ($1, $2)
+ON CONFLICT (key) DO UPDATE
+SET value = EXCLUDED.value, updated_at = now();
@@ -0,0 +1,27 @@
+-- name: CreateAuditEvent :one
+INSERT INTO audit_event (actor_user_id, actor_email, action, target_type, target_id, changes, ip_address, request_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: ListAuditEvents :many
+-- Empty filters match everything; the time range is [since, until).
+-- before_id pages through a long listing without skipping events added meanwhile.
+SELECT * FROM audit_event
+WHERE (@before_id::BIGINT = 0 OR id < @before_id::BIGINT)
+  AND (@actor_email::TEXT = '' OR actor_email = @actor_email::TEXT)
+  AND (@action::TEXT = '' OR action = @action::TEXT)
+  AND (@target_type::TEXT = '' OR target_type = @target_type::TEXT)
+  AND (@target_id::TEXT = '' OR target_id = @target_id::TEXT)
+  AND (sqlc.narg('since')::TIMESTAMP IS NULL OR created_at >= sqlc.narg('since')::TIMESTAMP)
+  AND (sqlc.narg('until')::TIMESTAMP IS NULL OR created_at < sqlc.narg('until')::TIMESTAMP)
+ORDER BY id DESC
+LIMIT @page_limit OFFSET @page_offset;
+
+-- name: CountAuditEvents :one
+SELECT COUNT(*) FROM audit_event
+WHERE (@actor_email::TEXT = '' OR actor_email = @actor_email::TEXT)
+  AND (@action::TEXT = '' OR action = @action::TEXT)
+  AND (@target_type::TEXT = '' OR target_type = @target_type::TEXT)
+  AND (@target_id::TEXT = '' OR target_id = @target_id::TEXT)
+  AND (sqlc.narg('since')::TIMESTAMP IS NULL OR created_at >= sqlc.narg('since')::TIMESTAMP)
+  AND (sqlc.narg('until')::TIMESTAMP IS NULL OR created_at < sqlc.narg('until')::TIMESTAMP);
@@ -0,0 +1,38 @@
+-- name: CreateAuthSession :one
+INSERT INTO auth_session (uuid, user_id, token_hash, user_agent, ip_address, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetAuthSessionByUUID :one
+SELECT * FROM auth_session
+WHERE uuid = $1;
+
+-- name: RotateAuthSession :one
+-- Swaps in the new token only if the presented one is current, so a token can be rotated once.
+UPDATE auth_session
+SET token_hash = @new_hash, previous_hash = token_hash, rotated_at = now(),
+    last_used_at = now(), user_agent = @user_agent, ip_address = @ip_address, expires_at = @expires_at
+WHERE uuid = @uuid AND token_hash = @old_hash AND revoked_at IS NULL AND expires_at > now()
+RETURNING *;
+
+-- name: TouchAuthSession :exec
+UPDATE auth_session SET last_used_at = now()
+WHERE uuid = $1;
+
+-- name: ListActiveAuthSessionsByUserID :many
+SELECT * FROM auth_session
+WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+ORDER BY last_used_at DESC;
+
+-- name: RevokeAuthSession :execrows
+UPDATE auth_session SET revoked_at = now(), revoked_reason = @reason
+WHERE uuid = @uuid AND user_id = @user_id AND revoked_at IS NULL;
+
+-- name: RevokeAuthSessionsByUserID :execrows
+-- Revokes every session of a user except keep_uuid (pass '' to revoke all).
+UPDATE auth_session SET revoked_at = now(), revoked_reason = @reason
+WHERE user_id = @user_id AND uuid <> @keep_uuid AND revoked_at IS NULL;
+
+-- name: PurgeAuthSessions :execrows
+DELETE FROM auth_session
+WHERE expires_at < now() - interval '30 days' OR revoked_at < now() - interval '30 days';
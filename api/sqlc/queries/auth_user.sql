@@ -156,4 +156,20 @@ LIMIT $2 OFFSET $3;
 SELECT COUNT(DISTINCT cs.uuid) AS total_sessions
 FROM chat_session cs
 INNER JOIN auth_user au ON cs.user_id = au.id
-WHERE au.email = $1 AND cs.active = true;
\ No newline at end of file
+WHERE au.email = $1 AND cs.active = true;
+-- name: SetAuthUserEmailVerified :exec
+UPDATE auth_user SET email_verified = $2
+WHERE id = $1;
+
+-- name: UpdateUserPasswordByID :exec
+UPDATE auth_user SET password = $2
+WHERE id = $1;
+
+-- name: UpdateAuthUserRoles :one
+UPDATE auth_user SET is_superuser = $2, is_staff = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: TouchAuthUserLastLogin :exec
+UPDATE auth_user SET last_login = now()
+WHERE id = $1;
@@ -0,0 +1,34 @@
+-- name: GetAuthUserIdentity :one
+SELECT * FROM auth_user_identity
+WHERE issuer = $1 AND subject = $2;
+
+-- name: CreateAuthUserIdentity :one
+INSERT INTO auth_user_identity (user_id, issuer, subject, email)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: TouchAuthUserIdentity :exec
+UPDATE auth_user_identity SET email = $2, last_login_at = now()
+WHERE id = $1;
+
+-- name: ListAuthUserIdentitiesByUserID :many
+SELECT * FROM auth_user_identity
+WHERE user_id = $1
+ORDER BY id;
+
+-- name: DeleteAuthUserIdentity :execrows
+DELETE FROM auth_user_identity
+WHERE id = $1 AND user_id = $2;
+
+-- name: CreateOIDCLoginState :exec
+INSERT INTO oidc_login_state (state, nonce, code_verifier, redirect_to, link_user_id)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ConsumeOIDCLoginState :one
+DELETE FROM oidc_login_state
+WHERE state = $1 AND created_at > now() - interval '10 minutes'
+RETURNING *;
+
+-- name: PurgeOIDCLoginStates :exec
+DELETE FROM oidc_login_state
+WHERE created_at < now() - interval '10 minutes';
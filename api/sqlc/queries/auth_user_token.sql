@@ -0,0 +1,18 @@
+-- name: CreateAuthUserToken :one
+INSERT INTO auth_user_token (user_id, purpose, token_hash, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ConsumeAuthUserToken :one
+UPDATE auth_user_token
+SET used_at = now()
+WHERE token_hash = $1
+  AND purpose = ANY(@purposes::TEXT[])
+  AND used_at IS NULL
+  AND expires_at > now()
+RETURNING *;
+
+-- name: InvalidateAuthUserTokens :exec
+UPDATE auth_user_token
+SET used_at = now()
+WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;
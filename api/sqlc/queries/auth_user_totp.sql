@@ -0,0 +1,40 @@
+-- name: GetAuthUserTOTP :one
+SELECT * FROM auth_user_totp
+WHERE user_id = $1;
+
+-- name: UpsertPendingAuthUserTOTP :one
+-- Starts or restarts enrolment; an enabled secret is never replaced.
+INSERT INTO auth_user_totp (user_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret, last_step = 0, created_at = now(), confirmed_at = NULL
+WHERE auth_user_totp.enabled = false
+RETURNING *;
+
+-- name: EnableAuthUserTOTP :exec
+UPDATE auth_user_totp SET enabled = true, confirmed_at = now(), last_step = $2
+WHERE user_id = $1;
+
+-- name: UseAuthUserTOTPStep :execrows
+UPDATE auth_user_totp SET last_step = $2
+WHERE user_id = $1 AND last_step < $2;
+
+-- name: DeleteAuthUserTOTP :exec
+DELETE FROM auth_user_totp
+WHERE user_id = $1;
+
+-- name: CreateAuthUserRecoveryCodes :exec
+INSERT INTO auth_user_recovery_code (user_id, code_hash)
+SELECT @user_id::INTEGER, unnest(@code_hashes::TEXT[]);
+
+-- name: DeleteAuthUserRecoveryCodes :exec
+DELETE FROM auth_user_recovery_code
+WHERE user_id = $1;
+
+-- name: UseAuthUserRecoveryCode :execrows
+UPDATE auth_user_recovery_code SET used_at = now()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountAuthUserRecoveryCodes :one
+SELECT COUNT(*) FROM auth_user_recovery_code
+WHERE user_id = $1 AND used_at IS NULL;
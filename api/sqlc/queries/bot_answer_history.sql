@@ -7,9 +7,11 @@ INSERT INTO bot_answer_history (
     prompt,
     answer,
     model,
-    tokens_used
+    tokens_used,
+    visitor_id,
+    bot_version
 ) VALUES (
-    $1, $2, $3, $4, $5, $6
+    $1, $2, $3, $4, $5, $6, $7, $8
 ) RETURNING *;
 
 -- name: GetBotAnswerHistoryByID :one
@@ -21,6 +23,7 @@ SELECT
     bah.answer,
     bah.model,
     bah.tokens_used,
+    bah.bot_version,
     bah.created_at,
     bah.updated_at,
     au.username AS user_username,
@@ -38,6 +41,7 @@ SELECT
     bah.answer,
     bah.model,
     bah.tokens_used,
+    bah.bot_version,
     bah.created_at,
     bah.updated_at,
     au.username AS user_username,
@@ -57,6 +61,7 @@ SELECT
     bah.answer,
     bah.model,
     bah.tokens_used,
+    bah.bot_version,
     bah.created_at,
     bah.updated_at,
     au.username AS user_username,
@@ -94,6 +99,7 @@ SELECT
     bah.answer,
     bah.model,
     bah.tokens_used,
+    bah.bot_version,
     bah.created_at,
     bah.updated_at,
     au.username AS user_username,
@@ -103,3 +109,9 @@ JOIN auth_user au ON bah.user_id = au.id
 WHERE bah.bot_uuid = $1
 ORDER BY bah.created_at DESC
 LIMIT $2;
+
+-- name: GetBotVisitorHistory :many
+SELECT * FROM bot_answer_history
+WHERE bot_uuid = $1 AND visitor_id = $2
+ORDER BY created_at DESC
+LIMIT $3;
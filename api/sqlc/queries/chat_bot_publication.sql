@@ -0,0 +1,23 @@
+-- name: GetChatBotPublicationByBotUUID :one
+SELECT * FROM chat_bot_publication
+WHERE bot_uuid = $1;
+
+-- name: UpsertChatBotPublication :one
+INSERT INTO chat_bot_publication (bot_uuid, user_id, access_mode, rate_limit, allowed_origins)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (bot_uuid) DO UPDATE
+SET access_mode = EXCLUDED.access_mode,
+    rate_limit = EXCLUDED.rate_limit,
+    allowed_origins = EXCLUDED.allowed_origins,
+    updated_at = now()
+RETURNING *;
+
+-- name: UpdateChatBotPublicationToken :one
+UPDATE chat_bot_publication
+SET token_hash = $2, updated_at = now()
+WHERE bot_uuid = $1
+RETURNING *;
+
+-- name: CountBotAnswersSince :one
+SELECT COUNT(*) FROM bot_answer_history
+WHERE bot_uuid = $1 AND created_at >= $2;
@@ -0,0 +1,42 @@
+-- name: CreateChatBotVersion :one
+INSERT INTO chat_bot_version (bot_uuid, version, model, conversation, file_ids, note, created_by)
+SELECT @bot_uuid::TEXT, COALESCE(MAX(version), 0) + 1, @model::TEXT, @conversation::JSONB,
+       @file_ids::INTEGER[], @note::TEXT, @created_by::INTEGER
+FROM chat_bot_version
+WHERE bot_uuid = @bot_uuid::TEXT
+RETURNING *;
+
+-- name: GetChatBotVersion :one
+SELECT * FROM chat_bot_version
+WHERE bot_uuid = $1 AND version = $2;
+
+-- name: ListChatBotVersions :many
+SELECT * FROM chat_bot_version
+WHERE bot_uuid = $1
+ORDER BY version DESC;
+
+-- name: ApplyChatBotVersion :one
+UPDATE chat_snapshot
+SET conversation = @conversation::JSONB,
+    model = @model::TEXT,
+    session = jsonb_set(session, '{model}', to_jsonb(@model::TEXT)),
+    text = @text::TEXT,
+    bot_version = @bot_version::INTEGER
+WHERE uuid = @uuid::TEXT AND typ = 'chatbot'
+RETURNING *;
+
+-- name: CreateChatBotFile :one
+INSERT INTO chat_bot_file (bot_uuid, user_id, name, mime_type, content)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ListChatBotFiles :many
+SELECT id, bot_uuid, name, mime_type, octet_length(content)::INTEGER AS size, created_at
+FROM chat_bot_file
+WHERE bot_uuid = $1
+ORDER BY id;
+
+-- name: GetChatBotFilesByIDs :many
+SELECT * FROM chat_bot_file
+WHERE bot_uuid = @bot_uuid::TEXT AND id = ANY(@ids::INTEGER[])
+ORDER BY id;
@@ -1,6 +1,8 @@
 -- name: CreateChatFile :one
-INSERT INTO chat_file (name, data, user_id, chat_session_uuid, mime_type)
-VALUES ($1, $2, $3, $4, $5)
+-- chat_message_uuid is only set for files made with their message, such as
+-- generated images; uploads wait for the next message.
+INSERT INTO chat_file (name, user_id, chat_session_uuid, mime_type, blob_hash, size, detached, source, chat_message_uuid)
+VALUES ($1, $2, $3, $4, @blob_hash::varchar, $5, $6, $7, sqlc.narg(chat_message_uuid))
 RETURNING *;
 
 -- name: ListChatFilesBySessionUUID :many
@@ -9,15 +11,35 @@ FROM chat_file
 WHERE user_id = $1 and chat_session_uuid = $2
 ORDER BY created_at ;
 
--- name: ListChatFilesWithContentBySessionUUID :many
+-- name: ListChatFilesByMessageUUIDs :many
+-- data is only set on files stored inline before blob storage; the others
+-- are read by blob_hash.
 SELECT *
 FROM chat_file
-WHERE chat_session_uuid = $1
-ORDER BY created_at;
+WHERE chat_message_uuid = ANY(@message_uuids::varchar[])
+ORDER BY created_at, id;
 
+-- name: ListChatFileAttachmentsBySessionUUID :many
+SELECT id, name, mime_type, size, chat_message_uuid, detached, source
+FROM chat_file
+WHERE chat_session_uuid = $1 AND chat_message_uuid IS NOT NULL
+ORDER BY created_at, id;
+
+-- name: AttachPendingChatFiles :execrows
+UPDATE chat_file
+SET chat_message_uuid = @chat_message_uuid::varchar
+WHERE chat_session_uuid = @chat_session_uuid
+  AND user_id = @user_id
+  AND chat_message_uuid IS NULL;
+
+-- name: SetChatFileDetached :one
+UPDATE chat_file
+SET detached = @detached
+WHERE id = @id AND user_id = @user_id
+RETURNING *;
 
 -- name: GetChatFileByID :one
-SELECT id, name, data, created_at, user_id, chat_session_uuid
+SELECT *
 FROM chat_file
 WHERE id = $1;
 
@@ -25,3 +47,15 @@ WHERE id = $1;
 DELETE FROM chat_file
 WHERE id = $1
 RETURNING *;
+
+-- name: ListInlineChatFiles :many
+SELECT id, data
+FROM chat_file
+WHERE blob_hash IS NULL AND id > @after_id
+ORDER BY id
+LIMIT @limit_count;
+
+-- name: SetChatFileBlob :exec
+UPDATE chat_file
+SET blob_hash = @blob_hash::varchar, size = @size, data = ''::bytea
+WHERE id = @id;
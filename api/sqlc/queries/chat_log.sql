@@ -15,4 +15,27 @@ WHERE id = $1
 RETURNING *;
 
 -- name: DeleteChatLog :exec
-DELETE FROM chat_logs WHERE id = $1;
\ No newline at end of file
+DELETE FROM chat_logs WHERE id = $1;
+
+-- name: ListChatLogsFiltered :many
+SELECT * FROM chat_logs
+WHERE (@user_id::INTEGER = 0 OR (session->>'userId')::INTEGER = @user_id::INTEGER)
+  AND (@model::TEXT = '' OR session->>'model' = @model::TEXT)
+  AND (@session_uuid::TEXT = '' OR session->>'uuid' = @session_uuid::TEXT)
+  AND (@keyword::TEXT = '' OR question::TEXT ILIKE '%' || @keyword::TEXT || '%' OR answer::TEXT ILIKE '%' || @keyword::TEXT || '%')
+  AND created_at >= @created_from::TIMESTAMP
+  AND created_at < @created_to::TIMESTAMP
+ORDER BY id DESC
+LIMIT @page_limit OFFSET @page_offset;
+
+-- name: CountChatLogsFiltered :one
+SELECT COUNT(*) FROM chat_logs
+WHERE (@user_id::INTEGER = 0 OR (session->>'userId')::INTEGER = @user_id::INTEGER)
+  AND (@model::TEXT = '' OR session->>'model' = @model::TEXT)
+  AND (@session_uuid::TEXT = '' OR session->>'uuid' = @session_uuid::TEXT)
+  AND (@keyword::TEXT = '' OR question::TEXT ILIKE '%' || @keyword::TEXT || '%' OR answer::TEXT ILIKE '%' || @keyword::TEXT || '%')
+  AND created_at >= @created_from::TIMESTAMP
+  AND created_at < @created_to::TIMESTAMP;
+
+-- name: PurgeChatLogsBefore :execrows
+DELETE FROM chat_logs WHERE created_at < $1;
@@ -214,4 +214,9 @@ FROM (
     WHERE chat_session_uuid = $1 
         AND is_deleted = false
 ) combined_messages
-ORDER BY created_at ASC;
\ No newline at end of file
+ORDER BY created_at ASC;
+-- name: GetLastAssistantMessageBySessionUUID :one
+SELECT * FROM chat_message
+WHERE chat_session_uuid = $1 AND role = 'assistant' AND is_deleted = false
+ORDER BY id DESC
+LIMIT 1;
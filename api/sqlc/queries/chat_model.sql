@@ -35,4 +35,8 @@ DELETE FROM chat_model WHERE id = $1 and user_id = $2;
 SELECT * FROM chat_model WHERE is_default = true
 and user_id in (select id from auth_user where is_superuser = true)
 ORDER BY order_number, id
-LIMIT 1;
\ No newline at end of file
+LIMIT 1;
+-- name: SetChatModelCredential :one
+UPDATE chat_model SET credential_id = sqlc.narg('credential_id')
+WHERE id = @id
+RETURNING *;
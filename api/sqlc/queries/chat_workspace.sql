@@ -7,6 +7,10 @@ RETURNING *;
 SELECT * FROM chat_workspace 
 WHERE uuid = $1;
 
+-- name: GetWorkspaceByID :one
+SELECT * FROM chat_workspace
+WHERE id = $1;
+
 -- name: GetWorkspacesByUserID :many
 SELECT * FROM chat_workspace 
 WHERE user_id = $1
@@ -66,3 +70,22 @@ WHERE w.uuid = $1
       WHERE request_user.id = $2 AND request_user.is_superuser = true
     )
   );
+
+-- name: UpdateWorkspaceChatLogEnabled :one
+UPDATE chat_workspace
+SET chat_log_enabled = $2, updated_at = now()
+WHERE uuid = $1
+RETURNING *;
+
+-- name: IsChatLogEnabledForWorkspace :one
+SELECT COALESCE((SELECT chat_log_enabled FROM chat_workspace WHERE id = $1), true)::BOOLEAN AS enabled;
+
+-- name: UpdateWorkspaceAuxiliaryModels :one
+UPDATE chat_workspace
+SET auxiliary_models = $2, updated_at = now()
+WHERE uuid = $1
+RETURNING *;
+
+-- name: GetWorkspaceAuxiliaryModels :one
+SELECT auxiliary_models FROM chat_workspace
+WHERE id = $1;
@@ -0,0 +1,64 @@
+-- name: GetFileBlob :one
+SELECT * FROM file_blob
+WHERE hash = $1;
+
+-- name: LockFileBlob :exec
+-- Serializes storing and releasing the contents of one hash until the end of
+-- the transaction.
+SELECT pg_advisory_xact_lock(hashtextextended('file_blob:' || @hash::text, 0));
+
+-- name: CreateFileBlob :execrows
+INSERT INTO file_blob (hash, size, backend)
+VALUES ($1, $2, $3)
+ON CONFLICT (hash) DO NOTHING;
+
+-- name: SetFileBlobBackend :exec
+UPDATE file_blob SET backend = $2
+WHERE hash = $1;
+
+-- name: ListFileBlobsOutsideBackend :many
+SELECT * FROM file_blob
+WHERE backend <> @backend AND hash > @after_hash
+ORDER BY hash
+LIMIT @limit_count;
+
+-- name: DeleteUnreferencedFileBlob :one
+DELETE FROM file_blob
+WHERE hash = $1
+  AND NOT EXISTS (SELECT 1 FROM chat_file WHERE blob_hash = $1)
+  AND NOT EXISTS (SELECT 1 FROM file_blob_variant WHERE blob_hash = $1)
+  AND NOT EXISTS (SELECT 1 FROM message_speech WHERE blob_hash = $1)
+RETURNING *;
+
+-- name: ListUnreferencedFileBlobs :many
+SELECT fb.* FROM file_blob fb
+WHERE NOT EXISTS (SELECT 1 FROM chat_file cf WHERE cf.blob_hash = fb.hash)
+  AND NOT EXISTS (SELECT 1 FROM file_blob_variant v WHERE v.blob_hash = fb.hash)
+  AND NOT EXISTS (SELECT 1 FROM message_speech s WHERE s.blob_hash = fb.hash)
+ORDER BY fb.hash;
+
+-- name: PutFileBlobData :exec
+INSERT INTO file_blob_data (hash, data)
+VALUES ($1, $2)
+ON CONFLICT (hash) DO NOTHING;
+
+-- name: GetFileBlobData :one
+SELECT data FROM file_blob_data
+WHERE hash = $1;
+
+-- name: DeleteFileBlobData :exec
+DELETE FROM file_blob_data
+WHERE hash = $1;
+
+-- name: GetFileBlobVariant :one
+SELECT * FROM file_blob_variant
+WHERE source_hash = $1 AND profile = $2;
+
+-- name: CreateFileBlobVariant :exec
+INSERT INTO file_blob_variant (source_hash, profile, blob_hash, mime_type)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (source_hash, profile) DO NOTHING;
+
+-- name: ListFileBlobVariantsBySource :many
+SELECT * FROM file_blob_variant
+WHERE source_hash = $1;
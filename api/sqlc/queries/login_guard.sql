@@ -0,0 +1,55 @@
+-- name: GetLoginLockSeconds :one
+-- Seconds until the longest lock among keys ends, 0 when none is locked.
+SELECT CEIL(COALESCE(MAX(EXTRACT(EPOCH FROM locked_until - now())), 0))::INTEGER
+FROM login_throttle
+WHERE key = ANY(@keys::TEXT[]) AND locked_until > now();
+
+-- name: BumpLoginThrottle :one
+-- Counts a failure; the count restarts when the previous failure is older than reset_after_seconds.
+INSERT INTO login_throttle (key, failures, last_failure_at)
+VALUES (@key, 1, now())
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+        WHEN login_throttle.last_failure_at < now() - make_interval(secs => @reset_after_seconds::INTEGER) THEN 1
+        ELSE login_throttle.failures + 1
+    END,
+    last_failure_at = now()
+RETURNING *;
+
+-- name: LockLoginThrottle :exec
+UPDATE login_throttle SET locked_until = now() + make_interval(secs => @lock_seconds::INTEGER)
+WHERE key = @key;
+
+-- name: DeleteLoginThrottle :execrows
+DELETE FROM login_throttle
+WHERE key = $1;
+
+-- name: ListLockedLoginThrottles :many
+SELECT * FROM login_throttle
+WHERE locked_until > now()
+ORDER BY locked_until DESC;
+
+-- name: CreateLoginAttempt :exec
+INSERT INTO login_attempt (email, ip_address, user_agent, reason)
+VALUES ($1, $2, $3, $4);
+
+-- name: ListLoginAttempts :many
+SELECT * FROM login_attempt
+WHERE (@email::TEXT = '' OR email = @email::TEXT)
+  AND (@ip_address::TEXT = '' OR ip_address = @ip_address::TEXT)
+ORDER BY created_at DESC
+LIMIT @page_limit OFFSET @page_offset;
+
+-- name: CountLoginAttempts :one
+SELECT COUNT(*) FROM login_attempt
+WHERE (@email::TEXT = '' OR email = @email::TEXT)
+  AND (@ip_address::TEXT = '' OR ip_address = @ip_address::TEXT);
+
+-- name: PurgeLoginAttempts :execrows
+DELETE FROM login_attempt
+WHERE created_at < now() - interval '90 days';
+
+-- name: PurgeLoginThrottles :execrows
+DELETE FROM login_throttle
+WHERE last_failure_at < now() - interval '1 day'
+  AND (locked_until IS NULL OR locked_until < now());
@@ -0,0 +1,23 @@
+-- name: CreateMailDelivery :one
+INSERT INTO mail_delivery (user_id, to_address, template, subject, driver)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: UpdateMailDeliveryStatus :exec
+UPDATE mail_delivery
+SET status = $2,
+    error = $3,
+    sent_at = CASE WHEN $2 = 'sent' THEN now() ELSE sent_at END
+WHERE id = $1;
+
+-- name: ListMailDeliveries :many
+SELECT * FROM mail_delivery
+WHERE (@status::TEXT = '' OR status = @status::TEXT)
+  AND (@to_address::TEXT = '' OR to_address = @to_address::TEXT)
+ORDER BY created_at DESC
+LIMIT @page_limit OFFSET @page_offset;
+
+-- name: CountMailDeliveries :one
+SELECT COUNT(*) FROM mail_delivery
+WHERE (@status::TEXT = '' OR status = @status::TEXT)
+  AND (@to_address::TEXT = '' OR to_address = @to_address::TEXT);
@@ -0,0 +1,85 @@
+-- name: CreatePromptTemplate :one
+INSERT INTO prompt_template (uuid, user_id, workspace_id, title, description, tags, visibility)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetPromptTemplateByUUID :one
+SELECT * FROM prompt_template
+WHERE uuid = $1 AND is_deleted = false;
+
+-- name: ListAccessiblePromptTemplates :many
+SELECT t.*, v.content, v.model, COALESCE(w.uuid, '')::TEXT AS workspace_uuid
+FROM prompt_template t
+JOIN prompt_template_version v ON v.template_id = t.id AND v.version = t.current_version
+LEFT JOIN chat_workspace w ON w.id = t.workspace_id
+WHERE t.is_deleted = false
+  AND (t.user_id = @user_id::INTEGER
+       OR t.visibility = 'public'
+       OR EXISTS (SELECT 1 FROM prompt_template_share s WHERE s.template_id = t.id AND s.user_id = @user_id::INTEGER))
+  AND (@workspace_id::INTEGER = 0 OR t.workspace_id = @workspace_id::INTEGER)
+  AND (@tag::TEXT = '' OR @tag::TEXT = ANY(t.tags))
+  AND (@keyword::TEXT = '' OR t.title ILIKE '%' || @keyword::TEXT || '%' OR t.description ILIKE '%' || @keyword::TEXT || '%')
+ORDER BY t.usage_count DESC, t.updated_at DESC;
+
+-- name: HasPromptTemplateAccess :one
+SELECT EXISTS (
+    SELECT 1 FROM prompt_template t
+    WHERE t.uuid = @uuid AND t.is_deleted = false
+      AND (t.user_id = @user_id::INTEGER
+           OR t.visibility = 'public'
+           OR EXISTS (SELECT 1 FROM prompt_template_share s WHERE s.template_id = t.id AND s.user_id = @user_id::INTEGER))
+) AS has_access;
+
+-- name: UpdatePromptTemplate :one
+UPDATE prompt_template
+SET title = $2, description = $3, tags = $4, visibility = $5, workspace_id = $6, updated_at = now()
+WHERE uuid = $1 AND is_deleted = false
+RETURNING *;
+
+-- name: SetPromptTemplateCurrentVersion :one
+UPDATE prompt_template
+SET current_version = $2, updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeletePromptTemplate :exec
+UPDATE prompt_template
+SET is_deleted = true, updated_at = now()
+WHERE uuid = $1;
+
+-- name: IncrementPromptTemplateUsage :exec
+UPDATE prompt_template
+SET usage_count = usage_count + 1, last_used_at = now()
+WHERE id = $1;
+
+-- name: CreatePromptTemplateVersion :one
+INSERT INTO prompt_template_version (template_id, version, content, model, created_by)
+SELECT @template_id::INTEGER, COALESCE(MAX(version), 0) + 1, @content::TEXT, @model::TEXT, @created_by::INTEGER
+FROM prompt_template_version
+WHERE template_id = @template_id::INTEGER
+RETURNING *;
+
+-- name: GetPromptTemplateVersion :one
+SELECT * FROM prompt_template_version
+WHERE template_id = $1 AND version = $2;
+
+-- name: ListPromptTemplateVersions :many
+SELECT * FROM prompt_template_version
+WHERE template_id = $1
+ORDER BY version DESC;
+
+-- name: SharePromptTemplate :exec
+INSERT INTO prompt_template_share (template_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT (template_id, user_id) DO NOTHING;
+
+-- name: UnsharePromptTemplate :exec
+DELETE FROM prompt_template_share
+WHERE template_id = $1 AND user_id = $2;
+
+-- name: ListPromptTemplateShares :many
+SELECT s.user_id, u.email, s.created_at
+FROM prompt_template_share s
+JOIN auth_user u ON u.id = s.user_id
+WHERE s.template_id = $1
+ORDER BY s.created_at;
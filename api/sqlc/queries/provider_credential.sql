@@ -0,0 +1,70 @@
+-- name: CreateProviderCredential :one
+INSERT INTO provider_credential (name, workspace_id, master_key_id, wrapped_key, ciphertext, hint, created_by)
+VALUES (@name, sqlc.narg('workspace_id'), @master_key_id, @wrapped_key, @ciphertext, @hint, @created_by)
+RETURNING *;
+
+-- name: GetProviderCredential :one
+SELECT * FROM provider_credential
+WHERE id = $1;
+
+-- name: ListSharedProviderCredentials :many
+-- Credentials managed by admins, i.e. not owned by a workspace.
+SELECT * FROM provider_credential
+WHERE workspace_id IS NULL
+ORDER BY name, id;
+
+-- name: ListAllProviderCredentials :many
+SELECT * FROM provider_credential
+ORDER BY id;
+
+-- name: UpdateProviderCredentialSecret :one
+-- Replaces the secret, sealed under a new data key.
+UPDATE provider_credential
+SET master_key_id = @master_key_id, wrapped_key = @wrapped_key, ciphertext = @ciphertext, hint = @hint,
+    updated_at = now(), rotated_at = now()
+WHERE id = @id
+RETURNING *;
+
+-- name: UpdateProviderCredentialName :one
+UPDATE provider_credential SET name = @name, updated_at = now()
+WHERE id = @id
+RETURNING *;
+
+-- name: RewrapProviderCredential :exec
+-- Stores a data key wrapped by another master key; the ciphertext is unchanged.
+UPDATE provider_credential SET master_key_id = @master_key_id, wrapped_key = @wrapped_key
+WHERE id = @id AND master_key_id = @old_master_key_id;
+
+-- name: DeleteProviderCredential :execrows
+DELETE FROM provider_credential
+WHERE id = $1;
+
+-- name: GetModelCredential :one
+-- The credential used for a model: the workspace's own key if it has one, else the model's.
+SELECT pc.* FROM provider_credential pc
+WHERE pc.id = COALESCE(
+    (SELECT wmc.credential_id FROM workspace_model_credential wmc
+     WHERE wmc.workspace_id = sqlc.narg('workspace_id') AND wmc.chat_model_id = @chat_model_id),
+    (SELECT cm.credential_id FROM chat_model cm WHERE cm.id = @chat_model_id)
+);
+
+-- name: UpsertWorkspaceModelCredential :exec
+INSERT INTO workspace_model_credential (workspace_id, chat_model_id, credential_id)
+VALUES (@workspace_id, @chat_model_id, @credential_id)
+ON CONFLICT (workspace_id, chat_model_id) DO UPDATE SET credential_id = EXCLUDED.credential_id, created_at = now();
+
+-- name: GetWorkspaceModelCredential :one
+SELECT * FROM workspace_model_credential
+WHERE workspace_id = @workspace_id AND chat_model_id = @chat_model_id;
+
+-- name: DeleteWorkspaceModelCredential :execrows
+DELETE FROM workspace_model_credential
+WHERE workspace_id = @workspace_id AND chat_model_id = @chat_model_id;
+
+-- name: ListWorkspaceModelCredentials :many
+SELECT wmc.chat_model_id, cm.name AS model_name, pc.id AS credential_id, pc.hint, pc.created_at, pc.rotated_at
+FROM workspace_model_credential wmc
+JOIN chat_model cm ON cm.id = wmc.chat_model_id
+JOIN provider_credential pc ON pc.id = wmc.credential_id
+WHERE wmc.workspace_id = $1
+ORDER BY cm.name;
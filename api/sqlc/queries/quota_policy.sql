@@ -0,0 +1,69 @@
+-- name: ListQuotaPolicies :many
+SELECT qp.*,
+    COALESCE(au.email, '')::TEXT AS user_email,
+    COALESCE(cw.uuid, '')::TEXT AS workspace_uuid,
+    COALESCE(cm.name, '')::TEXT AS chat_model_name
+FROM quota_policy qp
+LEFT JOIN auth_user au ON au.id = qp.user_id
+LEFT JOIN chat_workspace cw ON cw.id = qp.workspace_id
+LEFT JOIN chat_model cm ON cm.id = qp.chat_model_id
+ORDER BY qp.id;
+
+-- name: GetQuotaPolicy :one
+SELECT * FROM quota_policy WHERE id = $1;
+
+-- name: ListMatchingQuotaPolicies :many
+-- Enabled policies applying to a user, optionally within a workspace and for a model.
+SELECT * FROM quota_policy
+WHERE enabled
+  AND (user_id IS NULL OR user_id = @user_id::INTEGER)
+  AND (workspace_id IS NULL OR workspace_id = sqlc.narg('workspace_id')::INTEGER)
+  AND (chat_model_id IS NULL OR chat_model_id = sqlc.narg('chat_model_id')::INTEGER)
+ORDER BY id;
+
+-- name: CreateQuotaPolicy :one
+INSERT INTO quota_policy (name, user_id, workspace_id, chat_model_id, time_window, metric, quota_limit, enabled)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: UpdateQuotaPolicy :one
+UPDATE quota_policy
+SET name = $2, user_id = $3, workspace_id = $4, chat_model_id = $5,
+    time_window = $6, metric = $7, quota_limit = $8, enabled = $9, updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteQuotaPolicy :execrows
+DELETE FROM quota_policy WHERE id = $1;
+
+-- name: GetQuotaUsage :one
+-- Requests are answers (assistant messages) and recorded model usage; tokens
+-- count every message and usage. The reset columns are seconds until the
+-- oldest counted entry leaves the window.
+WITH usage AS (
+    SELECT cm.role = 'assistant' AS is_request, cm.token_count AS tokens, cm.created_at,
+        cs.workspace_id, COALESCE(NULLIF(cm.model, ''), cs.model) AS model
+    FROM chat_message cm
+    JOIN chat_session cs ON cs.uuid = cm.chat_session_uuid
+    WHERE cm.user_id = @user_id::INTEGER
+      AND cm.created_at >= now() - make_interval(secs => @window_seconds::INTEGER)
+    UNION ALL
+    SELECT true, mu.tokens, mu.created_at, mu.workspace_id, mu.model
+    FROM model_usage mu
+    WHERE mu.user_id = @user_id::INTEGER
+      AND mu.created_at >= now() - make_interval(secs => @window_seconds::INTEGER)
+)
+SELECT
+    COUNT(*) FILTER (WHERE u.is_request)::BIGINT AS requests,
+    COALESCE(SUM(u.tokens), 0)::BIGINT AS tokens,
+    COALESCE(CEIL(EXTRACT(EPOCH FROM MIN(u.created_at) FILTER (WHERE u.is_request)
+        + make_interval(secs => @window_seconds::INTEGER) - now())), @window_seconds::INTEGER)::INTEGER AS requests_reset_seconds,
+    COALESCE(CEIL(EXTRACT(EPOCH FROM MIN(u.created_at)
+        + make_interval(secs => @window_seconds::INTEGER) - now())), @window_seconds::INTEGER)::INTEGER AS tokens_reset_seconds
+FROM usage u
+WHERE (sqlc.narg('workspace_id')::INTEGER IS NULL OR u.workspace_id = sqlc.narg('workspace_id')::INTEGER)
+  AND (@model::TEXT = '' OR u.model = @model::TEXT);
+
+-- name: CreateModelUsage :exec
+INSERT INTO model_usage (user_id, workspace_id, model, source, tokens)
+VALUES ($1, $2, $3, $4, $5);
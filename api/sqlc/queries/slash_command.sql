@@ -0,0 +1,33 @@
+-- name: CreateSlashCommand :one
+INSERT INTO slash_command (uuid, user_id, workspace_id, name, description, template, model, temperature)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetSlashCommandByUUID :one
+SELECT * FROM slash_command
+WHERE uuid = $1;
+
+-- name: ListSlashCommandsByUserID :many
+SELECT c.*, COALESCE(w.uuid, '')::TEXT AS workspace_uuid
+FROM slash_command c
+LEFT JOIN chat_workspace w ON w.id = c.workspace_id
+WHERE c.user_id = $1
+ORDER BY c.name, c.workspace_id NULLS FIRST;
+
+-- name: UpdateSlashCommand :one
+UPDATE slash_command
+SET name = $2, description = $3, template = $4, model = $5, temperature = $6, workspace_id = $7, updated_at = now()
+WHERE uuid = $1
+RETURNING *;
+
+-- name: DeleteSlashCommand :exec
+DELETE FROM slash_command
+WHERE uuid = $1;
+
+-- name: ResolveSlashCommand :one
+-- Workspace-scoped commands take precedence over user-wide ones with the same name.
+SELECT * FROM slash_command
+WHERE user_id = @user_id AND name = @name
+  AND (workspace_id IS NULL OR workspace_id = @workspace_id::INTEGER)
+ORDER BY workspace_id NULLS LAST
+LIMIT 1;
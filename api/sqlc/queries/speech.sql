@@ -0,0 +1,30 @@
+-- name: GetUserSpeechSetting :one
+SELECT * FROM user_speech_setting
+WHERE user_id = $1;
+
+-- name: UpsertUserSpeechSetting :one
+INSERT INTO user_speech_setting (user_id, voice, speed)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET voice = EXCLUDED.voice, speed = EXCLUDED.speed, updated_at = now()
+RETURNING *;
+
+-- name: GetMessageSpeech :one
+SELECT * FROM message_speech
+WHERE message_uuid = $1 AND voice_key = $2;
+
+-- name: UpsertMessageSpeech :exec
+INSERT INTO message_speech (message_uuid, voice_key, content_hash, blob_hash, mime_type)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (message_uuid, voice_key) DO UPDATE
+SET content_hash = EXCLUDED.content_hash, blob_hash = EXCLUDED.blob_hash,
+    mime_type = EXCLUDED.mime_type, created_at = now();
+
+-- name: DeleteOrphanedMessageSpeech :many
+-- speech of messages that were deleted
+DELETE FROM message_speech s
+WHERE NOT EXISTS (
+    SELECT 1 FROM chat_message m
+    WHERE m.uuid = s.message_uuid AND m.is_deleted = false
+)
+RETURNING blob_hash;
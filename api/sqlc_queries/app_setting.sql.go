@@ -0,0 +1,40 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: app_setting.sql
+
+package sqlc_queries
+
+import (
+	"context"
+	"encoding/json"
+)
+
+const getAppSetting = `-- name: GetAppSetting :one
+SELECT key, value, updated_at FROM app_setting
+WHERE key = $1
+`
+
+func (q *Queries) GetAppSetting(ctx context.Context, key string) (AppSetting, error) {
+	row := q.db.QueryRowContext(ctx, getAppSetting, key)
+	var i AppSetting
+	err := row.Scan(&i.Key, &i.Value, &i.UpdatedAt)
+	return i, err
+}
+
+const upsertAppSetting = `-- name: UpsertAppSetting :exec
+INSERT INTO app_setting (key, value)
+VALUES ($1, $2)
+ON CONFLICT (key) DO UPDATE
+SET value = EXCLUDED.value, updated_at = now()
+`
+
+type UpsertAppSettingParams struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (q *Queries) UpsertAppSetting(ctx context.Context, arg UpsertAppSettingParams) error {
+	_, err := q.db.ExecContext(ctx, upsertAppSetting, arg.Key, arg.Value)
+	return err
+}
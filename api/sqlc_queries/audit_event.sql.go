@@ -0,0 +1,160 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: audit_event.sql
+
+package sqlc_queries
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+const countAuditEvents = `-- name: CountAuditEvents :one
+SELECT COUNT(*) FROM audit_event
+WHERE ($1::TEXT = '' OR actor_email = $1::TEXT)
+  AND ($2::TEXT = '' OR action = $2::TEXT)
+  AND ($3::TEXT = '' OR target_type = $3::TEXT)
+  AND ($4::TEXT = '' OR target_id = $4::TEXT)
+  AND ($5::TIMESTAMP IS NULL OR created_at >= $5::TIMESTAMP)
+  AND ($6::TIMESTAMP IS NULL OR created_at < $6::TIMESTAMP)
+`
+
+type CountAuditEventsParams struct {
+	ActorEmail string       `json:"actorEmail"`
+	Action     string       `json:"action"`
+	TargetType string       `json:"targetType"`
+	TargetID   string       `json:"targetId"`
+	Since      sql.NullTime `json:"since"`
+	Until      sql.NullTime `json:"until"`
+}
+
+func (q *Queries) CountAuditEvents(ctx context.Context, arg CountAuditEventsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAuditEvents,
+		arg.ActorEmail,
+		arg.Action,
+		arg.TargetType,
+		arg.TargetID,
+		arg.Since,
+		arg.Until,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createAuditEvent = `-- name: CreateAuditEvent :one
+INSERT INTO audit_event (actor_user_id, actor_email, action, target_type, target_id, changes, ip_address, request_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, actor_user_id, actor_email, action, target_type, target_id, changes, ip_address, request_id, created_at
+`
+
+type CreateAuditEventParams struct {
+	ActorUserID sql.NullInt32   `json:"actorUserId"`
+	ActorEmail  string          `json:"actorEmail"`
+	Action      string          `json:"action"`
+	TargetType  string          `json:"targetType"`
+	TargetID    string          `json:"targetId"`
+	Changes     json.RawMessage `json:"changes"`
+	IpAddress   string          `json:"ipAddress"`
+	RequestID   string          `json:"requestId"`
+}
+
+func (q *Queries) CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error) {
+	row := q.db.QueryRowContext(ctx, createAuditEvent,
+		arg.ActorUserID,
+		arg.ActorEmail,
+		arg.Action,
+		arg.TargetType,
+		arg.TargetID,
+		arg.Changes,
+		arg.IpAddress,
+		arg.RequestID,
+	)
+	var i AuditEvent
+	err := row.Scan(
+		&i.ID,
+		&i.ActorUserID,
+		&i.ActorEmail,
+		&i.Action,
+		&i.TargetType,
+		&i.TargetID,
+		&i.Changes,
+		&i.IpAddress,
+		&i.RequestID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listAuditEvents = `-- name: ListAuditEvents :many
+SELECT id, actor_user_id, actor_email, action, target_type, target_id, changes, ip_address, request_id, created_at FROM audit_event
+WHERE ($1::BIGINT = 0 OR id < $1::BIGINT)
+  AND ($2::TEXT = '' OR actor_email = $2::TEXT)
+  AND ($3::TEXT = '' OR action = $3::TEXT)
+  AND ($4::TEXT = '' OR target_type = $4::TEXT)
+  AND ($5::TEXT = '' OR target_id = $5::TEXT)
+  AND ($6::TIMESTAMP IS NULL OR created_at >= $6::TIMESTAMP)
+  AND ($7::TIMESTAMP IS NULL OR created_at < $7::TIMESTAMP)
+ORDER BY id DESC
+LIMIT $9 OFFSET $8
+`
+
+type ListAuditEventsParams struct {
+	BeforeID   int64        `json:"beforeId"`
+	ActorEmail string       `json:"actorEmail"`
+	Action     string       `json:"action"`
+	TargetType string       `json:"targetType"`
+	TargetID   string       `json:"targetId"`
+	Since      sql.NullTime `json:"since"`
+	Until      sql.NullTime `json:"until"`
+	PageOffset int32        `json:"pageOffset"`
+	PageLimit  int32        `json:"pageLimit"`
+}
+
+// Empty filters match everything; the time range is [since, until).
+// before_id pages through a long listing without skipping events added meanwhile.
+func (q *Queries) ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvent, error) {
+	rows, err := q.db.QueryContext(ctx, listAuditEvents,
+		arg.BeforeID,
+		arg.ActorEmail,
+		arg.Action,
+		arg.TargetType,
+		arg.TargetID,
+		arg.Since,
+		arg.Until,
+		arg.PageOffset,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditEvent
+	for rows.Next() {
+		var i AuditEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.ActorUserID,
+			&i.ActorEmail,
+			&i.Action,
+			&i.TargetType,
+			&i.TargetID,
+			&i.Changes,
+			&i.IpAddress,
+			&i.RequestID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,231 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: auth_session.sql
+
+package sqlc_queries
+
+import (
+	"context"
+	"time"
+)
+
+const createAuthSession = `-- name: CreateAuthSession :one
+INSERT INTO auth_session (uuid, user_id, token_hash, user_agent, ip_address, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, uuid, user_id, token_hash, previous_hash, rotated_at, user_agent, ip_address, created_at, last_used_at, expires_at, revoked_at, revoked_reason
+`
+
+type CreateAuthSessionParams struct {
+	Uuid      string    `json:"uuid"`
+	UserID    int32     `json:"userId"`
+	TokenHash string    `json:"tokenHash"`
+	UserAgent string    `json:"userAgent"`
+	IpAddress string    `json:"ipAddress"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (q *Queries) CreateAuthSession(ctx context.Context, arg CreateAuthSessionParams) (AuthSession, error) {
+	row := q.db.QueryRowContext(ctx, createAuthSession,
+		arg.Uuid,
+		arg.UserID,
+		arg.TokenHash,
+		arg.UserAgent,
+		arg.IpAddress,
+		arg.ExpiresAt,
+	)
+	var i AuthSession
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.UserID,
+		&i.TokenHash,
+		&i.PreviousHash,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.RevokedReason,
+	)
+	return i, err
+}
+
+const getAuthSessionByUUID = `-- name: GetAuthSessionByUUID :one
+SELECT id, uuid, user_id, token_hash, previous_hash, rotated_at, user_agent, ip_address, created_at, last_used_at, expires_at, revoked_at, revoked_reason FROM auth_session
+WHERE uuid = $1
+`
+
+func (q *Queries) GetAuthSessionByUUID(ctx context.Context, uuid string) (AuthSession, error) {
+	row := q.db.QueryRowContext(ctx, getAuthSessionByUUID, uuid)
+	var i AuthSession
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.UserID,
+		&i.TokenHash,
+		&i.PreviousHash,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.RevokedReason,
+	)
+	return i, err
+}
+
+const listActiveAuthSessionsByUserID = `-- name: ListActiveAuthSessionsByUserID :many
+SELECT id, uuid, user_id, token_hash, previous_hash, rotated_at, user_agent, ip_address, created_at, last_used_at, expires_at, revoked_at, revoked_reason FROM auth_session
+WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+ORDER BY last_used_at DESC
+`
+
+func (q *Queries) ListActiveAuthSessionsByUserID(ctx context.Context, userID int32) ([]AuthSession, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveAuthSessionsByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuthSession
+	for rows.Next() {
+		var i AuthSession
+		if err := rows.Scan(
+			&i.ID,
+			&i.Uuid,
+			&i.UserID,
+			&i.TokenHash,
+			&i.PreviousHash,
+			&i.RotatedAt,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.CreatedAt,
+			&i.LastUsedAt,
+			&i.ExpiresAt,
+			&i.RevokedAt,
+			&i.RevokedReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeAuthSessions = `-- name: PurgeAuthSessions :execrows
+DELETE FROM auth_session
+WHERE expires_at < now() - interval '30 days' OR revoked_at < now() - interval '30 days'
+`
+
+func (q *Queries) PurgeAuthSessions(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeAuthSessions)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeAuthSession = `-- name: RevokeAuthSession :execrows
+UPDATE auth_session SET revoked_at = now(), revoked_reason = $1
+WHERE uuid = $2 AND user_id = $3 AND revoked_at IS NULL
+`
+
+type RevokeAuthSessionParams struct {
+	Reason string `json:"reason"`
+	Uuid   string `json:"uuid"`
+	UserID int32  `json:"userId"`
+}
+
+func (q *Queries) RevokeAuthSession(ctx context.Context, arg RevokeAuthSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeAuthSession, arg.Reason, arg.Uuid, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeAuthSessionsByUserID = `-- name: RevokeAuthSessionsByUserID :execrows
+UPDATE auth_session SET revoked_at = now(), revoked_reason = $1
+WHERE user_id = $2 AND uuid <> $3 AND revoked_at IS NULL
+`
+
+type RevokeAuthSessionsByUserIDParams struct {
+	Reason   string `json:"reason"`
+	UserID   int32  `json:"userId"`
+	KeepUuid string `json:"keepUuid"`
+}
+
+// Revokes every session of a user except keep_uuid (pass ” to revoke all).
+func (q *Queries) RevokeAuthSessionsByUserID(ctx context.Context, arg RevokeAuthSessionsByUserIDParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeAuthSessionsByUserID, arg.Reason, arg.UserID, arg.KeepUuid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const rotateAuthSession = `-- name: RotateAuthSession :one
+UPDATE auth_session
+SET token_hash = $1, previous_hash = token_hash, rotated_at = now(),
+    last_used_at = now(), user_agent = $2, ip_address = $3, expires_at = $4
+WHERE uuid = $5 AND token_hash = $6 AND revoked_at IS NULL AND expires_at > now()
+RETURNING id, uuid, user_id, token_hash, previous_hash, rotated_at, user_agent, ip_address, created_at, last_used_at, expires_at, revoked_at, revoked_reason
+`
+
+type RotateAuthSessionParams struct {
+	NewHash   string    `json:"newHash"`
+	UserAgent string    `json:"userAgent"`
+	IpAddress string    `json:"ipAddress"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Uuid      string    `json:"uuid"`
+	OldHash   string    `json:"oldHash"`
+}
+
+// Swaps in the new token only if the presented one is current, so a token can be rotated once.
+func (q *Queries) RotateAuthSession(ctx context.Context, arg RotateAuthSessionParams) (AuthSession, error) {
+	row := q.db.QueryRowContext(ctx, rotateAuthSession,
+		arg.NewHash,
+		arg.UserAgent,
+		arg.IpAddress,
+		arg.ExpiresAt,
+		arg.Uuid,
+		arg.OldHash,
+	)
+	var i AuthSession
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.UserID,
+		&i.TokenHash,
+		&i.PreviousHash,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.RevokedReason,
+	)
+	return i, err
+}
+
+const touchAuthSession = `-- name: TouchAuthSession :exec
+UPDATE auth_session SET last_used_at = now()
+WHERE uuid = $1
+`
+
+func (q *Queries) TouchAuthSession(ctx context.Context, uuid string) error {
+	_, err := q.db.ExecContext(ctx, touchAuthSession, uuid)
+	return err
+}
@@ -13,7 +13,7 @@ import (
 const createAuthUser = `-- name: CreateAuthUser :one
 INSERT INTO auth_user (email, "password", first_name, last_name, username, is_staff, is_superuser)
 VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, password, last_login, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined
+RETURNING id, password, last_login, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined, email_verified
 `
 
 type CreateAuthUserParams struct {
@@ -49,6 +49,7 @@ func (q *Queries) CreateAuthUser(ctx context.Context, arg CreateAuthUserParams)
 		&i.IsStaff,
 		&i.IsActive,
 		&i.DateJoined,
+		&i.EmailVerified,
 	)
 	return i, err
 }
@@ -63,7 +64,7 @@ func (q *Queries) DeleteAuthUser(ctx context.Context, email string) error {
 }
 
 const getAllAuthUsers = `-- name: GetAllAuthUsers :many
-SELECT id, password, last_login, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined FROM auth_user ORDER BY id
+SELECT id, password, last_login, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined, email_verified FROM auth_user ORDER BY id
 `
 
 func (q *Queries) GetAllAuthUsers(ctx context.Context) ([]AuthUser, error) {
@@ -87,6 +88,7 @@ func (q *Queries) GetAllAuthUsers(ctx context.Context) ([]AuthUser, error) {
 			&i.IsStaff,
 			&i.IsActive,
 			&i.DateJoined,
+			&i.EmailVerified,
 		); err != nil {
 			return nil, err
 		}
@@ -102,7 +104,7 @@ func (q *Queries) GetAllAuthUsers(ctx context.Context) ([]AuthUser, error) {
 }
 
 const getAuthUserByEmail = `-- name: GetAuthUserByEmail :one
-SELECT id, password, last_login, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined FROM auth_user WHERE email = $1
+SELECT id, password, last_login, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined, email_verified FROM auth_user WHERE email = $1
 `
 
 func (q *Queries) GetAuthUserByEmail(ctx context.Context, email string) (AuthUser, error) {
@@ -120,12 +122,13 @@ func (q *Queries) GetAuthUserByEmail(ctx context.Context, email string) (AuthUse
 		&i.IsStaff,
 		&i.IsActive,
 		&i.DateJoined,
+		&i.EmailVerified,
 	)
 	return i, err
 }
 
 const getAuthUserByID = `-- name: GetAuthUserByID :one
-SELECT id, password, last_login, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined FROM auth_user WHERE id = $1
+SELECT id, password, last_login, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined, email_verified FROM auth_user WHERE id = $1
 `
 
 func (q *Queries) GetAuthUserByID(ctx context.Context, id int32) (AuthUser, error) {
@@ -143,6 +146,7 @@ func (q *Queries) GetAuthUserByID(ctx context.Context, id int32) (AuthUser, erro
 		&i.IsStaff,
 		&i.IsActive,
 		&i.DateJoined,
+		&i.EmailVerified,
 	)
 	return i, err
 }
@@ -225,7 +229,7 @@ func (q *Queries) GetUserAnalysisByEmail(ctx context.Context, arg GetUserAnalysi
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, password, last_login, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined FROM auth_user WHERE email = $1
+SELECT id, password, last_login, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined, email_verified FROM auth_user WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (AuthUser, error) {
@@ -243,6 +247,7 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (AuthUser, e
 		&i.IsStaff,
 		&i.IsActive,
 		&i.DateJoined,
+		&i.EmailVerified,
 	)
 	return i, err
 }
@@ -506,7 +511,7 @@ func (q *Queries) GetUserStats(ctx context.Context, arg GetUserStatsParams) ([]G
 }
 
 const listAuthUsers = `-- name: ListAuthUsers :many
-SELECT id, password, last_login, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined FROM auth_user ORDER BY id LIMIT $1 OFFSET $2
+SELECT id, password, last_login, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined, email_verified FROM auth_user ORDER BY id LIMIT $1 OFFSET $2
 `
 
 type ListAuthUsersParams struct {
@@ -535,6 +540,7 @@ func (q *Queries) ListAuthUsers(ctx context.Context, arg ListAuthUsersParams) ([
 			&i.IsStaff,
 			&i.IsActive,
 			&i.DateJoined,
+			&i.EmailVerified,
 		); err != nil {
 			return nil, err
 		}
@@ -549,6 +555,31 @@ func (q *Queries) ListAuthUsers(ctx context.Context, arg ListAuthUsersParams) ([
 	return items, nil
 }
 
+const setAuthUserEmailVerified = `-- name: SetAuthUserEmailVerified :exec
+UPDATE auth_user SET email_verified = $2
+WHERE id = $1
+`
+
+type SetAuthUserEmailVerifiedParams struct {
+	ID            int32 `json:"id"`
+	EmailVerified bool  `json:"emailVerified"`
+}
+
+func (q *Queries) SetAuthUserEmailVerified(ctx context.Context, arg SetAuthUserEmailVerifiedParams) error {
+	_, err := q.db.ExecContext(ctx, setAuthUserEmailVerified, arg.ID, arg.EmailVerified)
+	return err
+}
+
+const touchAuthUserLastLogin = `-- name: TouchAuthUserLastLogin :exec
+UPDATE auth_user SET last_login = now()
+WHERE id = $1
+`
+
+func (q *Queries) TouchAuthUserLastLogin(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, touchAuthUserLastLogin, id)
+	return err
+}
+
 const updateAuthUser = `-- name: UpdateAuthUser :one
 UPDATE auth_user SET first_name = $2, last_name= $3, last_login = now() 
 WHERE id = $1
@@ -618,6 +649,38 @@ func (q *Queries) UpdateAuthUserRateLimitByEmail(ctx context.Context, arg Update
 	return rate_limit, err
 }
 
+const updateAuthUserRoles = `-- name: UpdateAuthUserRoles :one
+UPDATE auth_user SET is_superuser = $2, is_staff = $3
+WHERE id = $1
+RETURNING id, password, last_login, is_superuser, username, first_name, last_name, email, is_staff, is_active, date_joined, email_verified
+`
+
+type UpdateAuthUserRolesParams struct {
+	ID          int32 `json:"id"`
+	IsSuperuser bool  `json:"isSuperuser"`
+	IsStaff     bool  `json:"isStaff"`
+}
+
+func (q *Queries) UpdateAuthUserRoles(ctx context.Context, arg UpdateAuthUserRolesParams) (AuthUser, error) {
+	row := q.db.QueryRowContext(ctx, updateAuthUserRoles, arg.ID, arg.IsSuperuser, arg.IsStaff)
+	var i AuthUser
+	err := row.Scan(
+		&i.ID,
+		&i.Password,
+		&i.LastLogin,
+		&i.IsSuperuser,
+		&i.Username,
+		&i.FirstName,
+		&i.LastName,
+		&i.Email,
+		&i.IsStaff,
+		&i.IsActive,
+		&i.DateJoined,
+		&i.EmailVerified,
+	)
+	return i, err
+}
+
 const updateUserPassword = `-- name: UpdateUserPassword :exec
 UPDATE auth_user SET "password" = $2 WHERE email = $1
 `
@@ -631,3 +694,18 @@ func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPassword
 	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.Email, arg.Password)
 	return err
 }
+
+const updateUserPasswordByID = `-- name: UpdateUserPasswordByID :exec
+UPDATE auth_user SET password = $2
+WHERE id = $1
+`
+
+type UpdateUserPasswordByIDParams struct {
+	ID       int32  `json:"id"`
+	Password string `json:"password"`
+}
+
+func (q *Queries) UpdateUserPasswordByID(ctx context.Context, arg UpdateUserPasswordByIDParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPasswordByID, arg.ID, arg.Password)
+	return err
+}
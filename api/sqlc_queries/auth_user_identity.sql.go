@@ -0,0 +1,193 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: auth_user_identity.sql
+
+package sqlc_queries
+
+import (
+	"context"
+	"database/sql"
+)
+
+const consumeOIDCLoginState = `-- name: ConsumeOIDCLoginState :one
+DELETE FROM oidc_login_state
+WHERE state = $1 AND created_at > now() - interval '10 minutes'
+RETURNING state, nonce, code_verifier, redirect_to, link_user_id, created_at
+`
+
+func (q *Queries) ConsumeOIDCLoginState(ctx context.Context, state string) (OidcLoginState, error) {
+	row := q.db.QueryRowContext(ctx, consumeOIDCLoginState, state)
+	var i OidcLoginState
+	err := row.Scan(
+		&i.State,
+		&i.Nonce,
+		&i.CodeVerifier,
+		&i.RedirectTo,
+		&i.LinkUserID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createAuthUserIdentity = `-- name: CreateAuthUserIdentity :one
+INSERT INTO auth_user_identity (user_id, issuer, subject, email)
+VALUES ($1, $2, $3, $4)
+RETURNING id, user_id, issuer, subject, email, created_at, last_login_at
+`
+
+type CreateAuthUserIdentityParams struct {
+	UserID  int32  `json:"userId"`
+	Issuer  string `json:"issuer"`
+	Subject string `json:"subject"`
+	Email   string `json:"email"`
+}
+
+func (q *Queries) CreateAuthUserIdentity(ctx context.Context, arg CreateAuthUserIdentityParams) (AuthUserIdentity, error) {
+	row := q.db.QueryRowContext(ctx, createAuthUserIdentity,
+		arg.UserID,
+		arg.Issuer,
+		arg.Subject,
+		arg.Email,
+	)
+	var i AuthUserIdentity
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Issuer,
+		&i.Subject,
+		&i.Email,
+		&i.CreatedAt,
+		&i.LastLoginAt,
+	)
+	return i, err
+}
+
+const createOIDCLoginState = `-- name: CreateOIDCLoginState :exec
+INSERT INTO oidc_login_state (state, nonce, code_verifier, redirect_to, link_user_id)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type CreateOIDCLoginStateParams struct {
+	State        string        `json:"state"`
+	Nonce        string        `json:"nonce"`
+	CodeVerifier string        `json:"codeVerifier"`
+	RedirectTo   string        `json:"redirectTo"`
+	LinkUserID   sql.NullInt32 `json:"linkUserId"`
+}
+
+func (q *Queries) CreateOIDCLoginState(ctx context.Context, arg CreateOIDCLoginStateParams) error {
+	_, err := q.db.ExecContext(ctx, createOIDCLoginState,
+		arg.State,
+		arg.Nonce,
+		arg.CodeVerifier,
+		arg.RedirectTo,
+		arg.LinkUserID,
+	)
+	return err
+}
+
+const deleteAuthUserIdentity = `-- name: DeleteAuthUserIdentity :execrows
+DELETE FROM auth_user_identity
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteAuthUserIdentityParams struct {
+	ID     int32 `json:"id"`
+	UserID int32 `json:"userId"`
+}
+
+func (q *Queries) DeleteAuthUserIdentity(ctx context.Context, arg DeleteAuthUserIdentityParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAuthUserIdentity, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAuthUserIdentity = `-- name: GetAuthUserIdentity :one
+SELECT id, user_id, issuer, subject, email, created_at, last_login_at FROM auth_user_identity
+WHERE issuer = $1 AND subject = $2
+`
+
+type GetAuthUserIdentityParams struct {
+	Issuer  string `json:"issuer"`
+	Subject string `json:"subject"`
+}
+
+func (q *Queries) GetAuthUserIdentity(ctx context.Context, arg GetAuthUserIdentityParams) (AuthUserIdentity, error) {
+	row := q.db.QueryRowContext(ctx, getAuthUserIdentity, arg.Issuer, arg.Subject)
+	var i AuthUserIdentity
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Issuer,
+		&i.Subject,
+		&i.Email,
+		&i.CreatedAt,
+		&i.LastLoginAt,
+	)
+	return i, err
+}
+
+const listAuthUserIdentitiesByUserID = `-- name: ListAuthUserIdentitiesByUserID :many
+SELECT id, user_id, issuer, subject, email, created_at, last_login_at FROM auth_user_identity
+WHERE user_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListAuthUserIdentitiesByUserID(ctx context.Context, userID int32) ([]AuthUserIdentity, error) {
+	rows, err := q.db.QueryContext(ctx, listAuthUserIdentitiesByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuthUserIdentity
+	for rows.Next() {
+		var i AuthUserIdentity
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Issuer,
+			&i.Subject,
+			&i.Email,
+			&i.CreatedAt,
+			&i.LastLoginAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeOIDCLoginStates = `-- name: PurgeOIDCLoginStates :exec
+DELETE FROM oidc_login_state
+WHERE created_at < now() - interval '10 minutes'
+`
+
+func (q *Queries) PurgeOIDCLoginStates(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, purgeOIDCLoginStates)
+	return err
+}
+
+const touchAuthUserIdentity = `-- name: TouchAuthUserIdentity :exec
+UPDATE auth_user_identity SET email = $2, last_login_at = now()
+WHERE id = $1
+`
+
+type TouchAuthUserIdentityParams struct {
+	ID    int32  `json:"id"`
+	Email string `json:"email"`
+}
+
+func (q *Queries) TouchAuthUserIdentity(ctx context.Context, arg TouchAuthUserIdentityParams) error {
+	_, err := q.db.ExecContext(ctx, touchAuthUserIdentity, arg.ID, arg.Email)
+	return err
+}
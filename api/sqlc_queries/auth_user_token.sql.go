@@ -0,0 +1,92 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: auth_user_token.sql
+
+package sqlc_queries
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const consumeAuthUserToken = `-- name: ConsumeAuthUserToken :one
+UPDATE auth_user_token
+SET used_at = now()
+WHERE token_hash = $1
+  AND purpose = ANY($2::TEXT[])
+  AND used_at IS NULL
+  AND expires_at > now()
+RETURNING id, user_id, purpose, token_hash, expires_at, used_at, created_at
+`
+
+type ConsumeAuthUserTokenParams struct {
+	TokenHash string   `json:"tokenHash"`
+	Purposes  []string `json:"purposes"`
+}
+
+func (q *Queries) ConsumeAuthUserToken(ctx context.Context, arg ConsumeAuthUserTokenParams) (AuthUserToken, error) {
+	row := q.db.QueryRowContext(ctx, consumeAuthUserToken, arg.TokenHash, pq.Array(arg.Purposes))
+	var i AuthUserToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Purpose,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createAuthUserToken = `-- name: CreateAuthUserToken :one
+INSERT INTO auth_user_token (user_id, purpose, token_hash, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING id, user_id, purpose, token_hash, expires_at, used_at, created_at
+`
+
+type CreateAuthUserTokenParams struct {
+	UserID    int32     `json:"userId"`
+	Purpose   string    `json:"purpose"`
+	TokenHash string    `json:"tokenHash"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (q *Queries) CreateAuthUserToken(ctx context.Context, arg CreateAuthUserTokenParams) (AuthUserToken, error) {
+	row := q.db.QueryRowContext(ctx, createAuthUserToken,
+		arg.UserID,
+		arg.Purpose,
+		arg.TokenHash,
+		arg.ExpiresAt,
+	)
+	var i AuthUserToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Purpose,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const invalidateAuthUserTokens = `-- name: InvalidateAuthUserTokens :exec
+UPDATE auth_user_token
+SET used_at = now()
+WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+`
+
+type InvalidateAuthUserTokensParams struct {
+	UserID  int32  `json:"userId"`
+	Purpose string `json:"purpose"`
+}
+
+func (q *Queries) InvalidateAuthUserTokens(ctx context.Context, arg InvalidateAuthUserTokensParams) error {
+	_, err := q.db.ExecContext(ctx, invalidateAuthUserTokens, arg.UserID, arg.Purpose)
+	return err
+}
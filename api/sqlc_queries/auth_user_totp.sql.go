@@ -0,0 +1,158 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: auth_user_totp.sql
+
+package sqlc_queries
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+const countAuthUserRecoveryCodes = `-- name: CountAuthUserRecoveryCodes :one
+SELECT COUNT(*) FROM auth_user_recovery_code
+WHERE user_id = $1 AND used_at IS NULL
+`
+
+func (q *Queries) CountAuthUserRecoveryCodes(ctx context.Context, userID int32) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAuthUserRecoveryCodes, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createAuthUserRecoveryCodes = `-- name: CreateAuthUserRecoveryCodes :exec
+INSERT INTO auth_user_recovery_code (user_id, code_hash)
+SELECT $1::INTEGER, unnest($2::TEXT[])
+`
+
+type CreateAuthUserRecoveryCodesParams struct {
+	UserID     int32    `json:"userId"`
+	CodeHashes []string `json:"codeHashes"`
+}
+
+func (q *Queries) CreateAuthUserRecoveryCodes(ctx context.Context, arg CreateAuthUserRecoveryCodesParams) error {
+	_, err := q.db.ExecContext(ctx, createAuthUserRecoveryCodes, arg.UserID, pq.Array(arg.CodeHashes))
+	return err
+}
+
+const deleteAuthUserRecoveryCodes = `-- name: DeleteAuthUserRecoveryCodes :exec
+DELETE FROM auth_user_recovery_code
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteAuthUserRecoveryCodes(ctx context.Context, userID int32) error {
+	_, err := q.db.ExecContext(ctx, deleteAuthUserRecoveryCodes, userID)
+	return err
+}
+
+const deleteAuthUserTOTP = `-- name: DeleteAuthUserTOTP :exec
+DELETE FROM auth_user_totp
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteAuthUserTOTP(ctx context.Context, userID int32) error {
+	_, err := q.db.ExecContext(ctx, deleteAuthUserTOTP, userID)
+	return err
+}
+
+const enableAuthUserTOTP = `-- name: EnableAuthUserTOTP :exec
+UPDATE auth_user_totp SET enabled = true, confirmed_at = now(), last_step = $2
+WHERE user_id = $1
+`
+
+type EnableAuthUserTOTPParams struct {
+	UserID   int32 `json:"userId"`
+	LastStep int64 `json:"lastStep"`
+}
+
+func (q *Queries) EnableAuthUserTOTP(ctx context.Context, arg EnableAuthUserTOTPParams) error {
+	_, err := q.db.ExecContext(ctx, enableAuthUserTOTP, arg.UserID, arg.LastStep)
+	return err
+}
+
+const getAuthUserTOTP = `-- name: GetAuthUserTOTP :one
+SELECT user_id, secret, enabled, last_step, created_at, confirmed_at FROM auth_user_totp
+WHERE user_id = $1
+`
+
+func (q *Queries) GetAuthUserTOTP(ctx context.Context, userID int32) (AuthUserTotp, error) {
+	row := q.db.QueryRowContext(ctx, getAuthUserTOTP, userID)
+	var i AuthUserTotp
+	err := row.Scan(
+		&i.UserID,
+		&i.Secret,
+		&i.Enabled,
+		&i.LastStep,
+		&i.CreatedAt,
+		&i.ConfirmedAt,
+	)
+	return i, err
+}
+
+const upsertPendingAuthUserTOTP = `-- name: UpsertPendingAuthUserTOTP :one
+INSERT INTO auth_user_totp (user_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret, last_step = 0, created_at = now(), confirmed_at = NULL
+WHERE auth_user_totp.enabled = false
+RETURNING user_id, secret, enabled, last_step, created_at, confirmed_at
+`
+
+type UpsertPendingAuthUserTOTPParams struct {
+	UserID int32  `json:"userId"`
+	Secret string `json:"secret"`
+}
+
+// Starts or restarts enrolment; an enabled secret is never replaced.
+func (q *Queries) UpsertPendingAuthUserTOTP(ctx context.Context, arg UpsertPendingAuthUserTOTPParams) (AuthUserTotp, error) {
+	row := q.db.QueryRowContext(ctx, upsertPendingAuthUserTOTP, arg.UserID, arg.Secret)
+	var i AuthUserTotp
+	err := row.Scan(
+		&i.UserID,
+		&i.Secret,
+		&i.Enabled,
+		&i.LastStep,
+		&i.CreatedAt,
+		&i.ConfirmedAt,
+	)
+	return i, err
+}
+
+const useAuthUserRecoveryCode = `-- name: UseAuthUserRecoveryCode :execrows
+UPDATE auth_user_recovery_code SET used_at = now()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+`
+
+type UseAuthUserRecoveryCodeParams struct {
+	UserID   int32  `json:"userId"`
+	CodeHash string `json:"codeHash"`
+}
+
+func (q *Queries) UseAuthUserRecoveryCode(ctx context.Context, arg UseAuthUserRecoveryCodeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useAuthUserRecoveryCode, arg.UserID, arg.CodeHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const useAuthUserTOTPStep = `-- name: UseAuthUserTOTPStep :execrows
+UPDATE auth_user_totp SET last_step = $2
+WHERE user_id = $1 AND last_step < $2
+`
+
+type UseAuthUserTOTPStepParams struct {
+	UserID   int32 `json:"userId"`
+	LastStep int64 `json:"lastStep"`
+}
+
+func (q *Queries) UseAuthUserTOTPStep(ctx context.Context, arg UseAuthUserTOTPStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useAuthUserTOTPStep, arg.UserID, arg.LastStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
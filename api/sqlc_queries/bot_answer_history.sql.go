@@ -18,10 +18,12 @@ INSERT INTO bot_answer_history (
     prompt,
     answer,
     model,
-    tokens_used
+    tokens_used,
+    visitor_id,
+    bot_version
 ) VALUES (
-    $1, $2, $3, $4, $5, $6
-) RETURNING id, bot_uuid, user_id, prompt, answer, model, tokens_used, created_at, updated_at
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING id, bot_uuid, user_id, prompt, answer, model, tokens_used, created_at, updated_at, visitor_id, bot_version
 `
 
 type CreateBotAnswerHistoryParams struct {
@@ -31,6 +33,8 @@ type CreateBotAnswerHistoryParams struct {
 	Answer     string `json:"answer"`
 	Model      string `json:"model"`
 	TokensUsed int32  `json:"tokensUsed"`
+	VisitorID  string `json:"visitorId"`
+	BotVersion int32  `json:"botVersion"`
 }
 
 // Bot Answer History Queries --
@@ -42,6 +46,8 @@ func (q *Queries) CreateBotAnswerHistory(ctx context.Context, arg CreateBotAnswe
 		arg.Answer,
 		arg.Model,
 		arg.TokensUsed,
+		arg.VisitorID,
+		arg.BotVersion,
 	)
 	var i BotAnswerHistory
 	err := row.Scan(
@@ -54,6 +60,8 @@ func (q *Queries) CreateBotAnswerHistory(ctx context.Context, arg CreateBotAnswe
 		&i.TokensUsed,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.VisitorID,
+		&i.BotVersion,
 	)
 	return i, err
 }
@@ -76,6 +84,7 @@ SELECT
     bah.answer,
     bah.model,
     bah.tokens_used,
+    bah.bot_version,
     bah.created_at,
     bah.updated_at,
     au.username AS user_username,
@@ -101,6 +110,7 @@ type GetBotAnswerHistoryByBotUUIDRow struct {
 	Answer       string    `json:"answer"`
 	Model        string    `json:"model"`
 	TokensUsed   int32     `json:"tokensUsed"`
+	BotVersion   int32     `json:"botVersion"`
 	CreatedAt    time.Time `json:"createdAt"`
 	UpdatedAt    time.Time `json:"updatedAt"`
 	UserUsername string    `json:"userUsername"`
@@ -124,6 +134,7 @@ func (q *Queries) GetBotAnswerHistoryByBotUUID(ctx context.Context, arg GetBotAn
 			&i.Answer,
 			&i.Model,
 			&i.TokensUsed,
+			&i.BotVersion,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.UserUsername,
@@ -151,6 +162,7 @@ SELECT
     bah.answer,
     bah.model,
     bah.tokens_used,
+    bah.bot_version,
     bah.created_at,
     bah.updated_at,
     au.username AS user_username,
@@ -168,6 +180,7 @@ type GetBotAnswerHistoryByIDRow struct {
 	Answer       string    `json:"answer"`
 	Model        string    `json:"model"`
 	TokensUsed   int32     `json:"tokensUsed"`
+	BotVersion   int32     `json:"botVersion"`
 	CreatedAt    time.Time `json:"createdAt"`
 	UpdatedAt    time.Time `json:"updatedAt"`
 	UserUsername string    `json:"userUsername"`
@@ -185,6 +198,7 @@ func (q *Queries) GetBotAnswerHistoryByID(ctx context.Context, id int32) (GetBot
 		&i.Answer,
 		&i.Model,
 		&i.TokensUsed,
+		&i.BotVersion,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.UserUsername,
@@ -202,6 +216,7 @@ SELECT
     bah.answer,
     bah.model,
     bah.tokens_used,
+    bah.bot_version,
     bah.created_at,
     bah.updated_at,
     au.username AS user_username,
@@ -227,6 +242,7 @@ type GetBotAnswerHistoryByUserIDRow struct {
 	Answer       string    `json:"answer"`
 	Model        string    `json:"model"`
 	TokensUsed   int32     `json:"tokensUsed"`
+	BotVersion   int32     `json:"botVersion"`
 	CreatedAt    time.Time `json:"createdAt"`
 	UpdatedAt    time.Time `json:"updatedAt"`
 	UserUsername string    `json:"userUsername"`
@@ -250,6 +266,7 @@ func (q *Queries) GetBotAnswerHistoryByUserID(ctx context.Context, arg GetBotAns
 			&i.Answer,
 			&i.Model,
 			&i.TokensUsed,
+			&i.BotVersion,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.UserUsername,
@@ -290,6 +307,54 @@ func (q *Queries) GetBotAnswerHistoryCountByUserID(ctx context.Context, userID i
 	return count, err
 }
 
+const getBotVisitorHistory = `-- name: GetBotVisitorHistory :many
+SELECT id, bot_uuid, user_id, prompt, answer, model, tokens_used, created_at, updated_at, visitor_id, bot_version FROM bot_answer_history
+WHERE bot_uuid = $1 AND visitor_id = $2
+ORDER BY created_at DESC
+LIMIT $3
+`
+
+type GetBotVisitorHistoryParams struct {
+	BotUuid   string `json:"botUuid"`
+	VisitorID string `json:"visitorId"`
+	Limit     int32  `json:"limit"`
+}
+
+func (q *Queries) GetBotVisitorHistory(ctx context.Context, arg GetBotVisitorHistoryParams) ([]BotAnswerHistory, error) {
+	rows, err := q.db.QueryContext(ctx, getBotVisitorHistory, arg.BotUuid, arg.VisitorID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BotAnswerHistory
+	for rows.Next() {
+		var i BotAnswerHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.BotUuid,
+			&i.UserID,
+			&i.Prompt,
+			&i.Answer,
+			&i.Model,
+			&i.TokensUsed,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.VisitorID,
+			&i.BotVersion,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getLatestBotAnswerHistoryByBotUUID = `-- name: GetLatestBotAnswerHistoryByBotUUID :many
 SELECT 
     bah.id,
@@ -299,6 +364,7 @@ SELECT
     bah.answer,
     bah.model,
     bah.tokens_used,
+    bah.bot_version,
     bah.created_at,
     bah.updated_at,
     au.username AS user_username,
@@ -323,6 +389,7 @@ type GetLatestBotAnswerHistoryByBotUUIDRow struct {
 	Answer       string    `json:"answer"`
 	Model        string    `json:"model"`
 	TokensUsed   int32     `json:"tokensUsed"`
+	BotVersion   int32     `json:"botVersion"`
 	CreatedAt    time.Time `json:"createdAt"`
 	UpdatedAt    time.Time `json:"updatedAt"`
 	UserUsername string    `json:"userUsername"`
@@ -346,6 +413,7 @@ func (q *Queries) GetLatestBotAnswerHistoryByBotUUID(ctx context.Context, arg Ge
 			&i.Answer,
 			&i.Model,
 			&i.TokensUsed,
+			&i.BotVersion,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.UserUsername,
@@ -371,7 +439,7 @@ SET
     tokens_used = $3,
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, bot_uuid, user_id, prompt, answer, model, tokens_used, created_at, updated_at
+RETURNING id, bot_uuid, user_id, prompt, answer, model, tokens_used, created_at, updated_at, visitor_id, bot_version
 `
 
 type UpdateBotAnswerHistoryParams struct {
@@ -393,6 +461,8 @@ func (q *Queries) UpdateBotAnswerHistory(ctx context.Context, arg UpdateBotAnswe
 		&i.TokensUsed,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.VisitorID,
+		&i.BotVersion,
 	)
 	return i, err
 }
@@ -0,0 +1,123 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: chat_bot_publication.sql
+
+package sqlc_queries
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const countBotAnswersSince = `-- name: CountBotAnswersSince :one
+SELECT COUNT(*) FROM bot_answer_history
+WHERE bot_uuid = $1 AND created_at >= $2
+`
+
+type CountBotAnswersSinceParams struct {
+	BotUuid   string    `json:"botUuid"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (q *Queries) CountBotAnswersSince(ctx context.Context, arg CountBotAnswersSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countBotAnswersSince, arg.BotUuid, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getChatBotPublicationByBotUUID = `-- name: GetChatBotPublicationByBotUUID :one
+SELECT id, bot_uuid, user_id, access_mode, token_hash, rate_limit, allowed_origins, created_at, updated_at FROM chat_bot_publication
+WHERE bot_uuid = $1
+`
+
+func (q *Queries) GetChatBotPublicationByBotUUID(ctx context.Context, botUuid string) (ChatBotPublication, error) {
+	row := q.db.QueryRowContext(ctx, getChatBotPublicationByBotUUID, botUuid)
+	var i ChatBotPublication
+	err := row.Scan(
+		&i.ID,
+		&i.BotUuid,
+		&i.UserID,
+		&i.AccessMode,
+		&i.TokenHash,
+		&i.RateLimit,
+		pq.Array(&i.AllowedOrigins),
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateChatBotPublicationToken = `-- name: UpdateChatBotPublicationToken :one
+UPDATE chat_bot_publication
+SET token_hash = $2, updated_at = now()
+WHERE bot_uuid = $1
+RETURNING id, bot_uuid, user_id, access_mode, token_hash, rate_limit, allowed_origins, created_at, updated_at
+`
+
+type UpdateChatBotPublicationTokenParams struct {
+	BotUuid   string `json:"botUuid"`
+	TokenHash string `json:"tokenHash"`
+}
+
+func (q *Queries) UpdateChatBotPublicationToken(ctx context.Context, arg UpdateChatBotPublicationTokenParams) (ChatBotPublication, error) {
+	row := q.db.QueryRowContext(ctx, updateChatBotPublicationToken, arg.BotUuid, arg.TokenHash)
+	var i ChatBotPublication
+	err := row.Scan(
+		&i.ID,
+		&i.BotUuid,
+		&i.UserID,
+		&i.AccessMode,
+		&i.TokenHash,
+		&i.RateLimit,
+		pq.Array(&i.AllowedOrigins),
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertChatBotPublication = `-- name: UpsertChatBotPublication :one
+INSERT INTO chat_bot_publication (bot_uuid, user_id, access_mode, rate_limit, allowed_origins)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (bot_uuid) DO UPDATE
+SET access_mode = EXCLUDED.access_mode,
+    rate_limit = EXCLUDED.rate_limit,
+    allowed_origins = EXCLUDED.allowed_origins,
+    updated_at = now()
+RETURNING id, bot_uuid, user_id, access_mode, token_hash, rate_limit, allowed_origins, created_at, updated_at
+`
+
+type UpsertChatBotPublicationParams struct {
+	BotUuid        string   `json:"botUuid"`
+	UserID         int32    `json:"userId"`
+	AccessMode     string   `json:"accessMode"`
+	RateLimit      int32    `json:"rateLimit"`
+	AllowedOrigins []string `json:"allowedOrigins"`
+}
+
+func (q *Queries) UpsertChatBotPublication(ctx context.Context, arg UpsertChatBotPublicationParams) (ChatBotPublication, error) {
+	row := q.db.QueryRowContext(ctx, upsertChatBotPublication,
+		arg.BotUuid,
+		arg.UserID,
+		arg.AccessMode,
+		arg.RateLimit,
+		pq.Array(arg.AllowedOrigins),
+	)
+	var i ChatBotPublication
+	err := row.Scan(
+		&i.ID,
+		&i.BotUuid,
+		&i.UserID,
+		&i.AccessMode,
+		&i.TokenHash,
+		&i.RateLimit,
+		pq.Array(&i.AllowedOrigins),
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
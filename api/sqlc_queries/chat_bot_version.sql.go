@@ -0,0 +1,292 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: chat_bot_version.sql
+
+package sqlc_queries
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const applyChatBotVersion = `-- name: ApplyChatBotVersion :one
+UPDATE chat_snapshot
+SET conversation = $1::JSONB,
+    model = $2::TEXT,
+    session = jsonb_set(session, '{model}', to_jsonb($2::TEXT)),
+    text = $3::TEXT,
+    bot_version = $4::INTEGER
+WHERE uuid = $5::TEXT AND typ = 'chatbot'
+RETURNING id, typ, uuid, user_id, title, summary, model, tags, session, conversation, created_at, text, search_vector, bot_version
+`
+
+type ApplyChatBotVersionParams struct {
+	Conversation json.RawMessage `json:"conversation"`
+	Model        string          `json:"model"`
+	Text         string          `json:"text"`
+	BotVersion   int32           `json:"botVersion"`
+	Uuid         string          `json:"uuid"`
+}
+
+func (q *Queries) ApplyChatBotVersion(ctx context.Context, arg ApplyChatBotVersionParams) (ChatSnapshot, error) {
+	row := q.db.QueryRowContext(ctx, applyChatBotVersion,
+		arg.Conversation,
+		arg.Model,
+		arg.Text,
+		arg.BotVersion,
+		arg.Uuid,
+	)
+	var i ChatSnapshot
+	err := row.Scan(
+		&i.ID,
+		&i.Typ,
+		&i.Uuid,
+		&i.UserID,
+		&i.Title,
+		&i.Summary,
+		&i.Model,
+		&i.Tags,
+		&i.Session,
+		&i.Conversation,
+		&i.CreatedAt,
+		&i.Text,
+		&i.SearchVector,
+		&i.BotVersion,
+	)
+	return i, err
+}
+
+const createChatBotFile = `-- name: CreateChatBotFile :one
+INSERT INTO chat_bot_file (bot_uuid, user_id, name, mime_type, content)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, bot_uuid, user_id, name, mime_type, content, created_at
+`
+
+type CreateChatBotFileParams struct {
+	BotUuid  string `json:"botUuid"`
+	UserID   int32  `json:"userId"`
+	Name     string `json:"name"`
+	MimeType string `json:"mimeType"`
+	Content  string `json:"content"`
+}
+
+func (q *Queries) CreateChatBotFile(ctx context.Context, arg CreateChatBotFileParams) (ChatBotFile, error) {
+	row := q.db.QueryRowContext(ctx, createChatBotFile,
+		arg.BotUuid,
+		arg.UserID,
+		arg.Name,
+		arg.MimeType,
+		arg.Content,
+	)
+	var i ChatBotFile
+	err := row.Scan(
+		&i.ID,
+		&i.BotUuid,
+		&i.UserID,
+		&i.Name,
+		&i.MimeType,
+		&i.Content,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createChatBotVersion = `-- name: CreateChatBotVersion :one
+INSERT INTO chat_bot_version (bot_uuid, version, model, conversation, file_ids, note, created_by)
+SELECT $1::TEXT, COALESCE(MAX(version), 0) + 1, $2::TEXT, $3::JSONB,
+       $4::INTEGER[], $5::TEXT, $6::INTEGER
+FROM chat_bot_version
+WHERE bot_uuid = $1::TEXT
+RETURNING id, bot_uuid, version, model, conversation, file_ids, note, created_by, created_at
+`
+
+type CreateChatBotVersionParams struct {
+	BotUuid      string          `json:"botUuid"`
+	Model        string          `json:"model"`
+	Conversation json.RawMessage `json:"conversation"`
+	FileIds      []int32         `json:"fileIds"`
+	Note         string          `json:"note"`
+	CreatedBy    int32           `json:"createdBy"`
+}
+
+func (q *Queries) CreateChatBotVersion(ctx context.Context, arg CreateChatBotVersionParams) (ChatBotVersion, error) {
+	row := q.db.QueryRowContext(ctx, createChatBotVersion,
+		arg.BotUuid,
+		arg.Model,
+		arg.Conversation,
+		pq.Array(arg.FileIds),
+		arg.Note,
+		arg.CreatedBy,
+	)
+	var i ChatBotVersion
+	err := row.Scan(
+		&i.ID,
+		&i.BotUuid,
+		&i.Version,
+		&i.Model,
+		&i.Conversation,
+		pq.Array(&i.FileIds),
+		&i.Note,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getChatBotFilesByIDs = `-- name: GetChatBotFilesByIDs :many
+SELECT id, bot_uuid, user_id, name, mime_type, content, created_at FROM chat_bot_file
+WHERE bot_uuid = $1::TEXT AND id = ANY($2::INTEGER[])
+ORDER BY id
+`
+
+type GetChatBotFilesByIDsParams struct {
+	BotUuid string  `json:"botUuid"`
+	Ids     []int32 `json:"ids"`
+}
+
+func (q *Queries) GetChatBotFilesByIDs(ctx context.Context, arg GetChatBotFilesByIDsParams) ([]ChatBotFile, error) {
+	rows, err := q.db.QueryContext(ctx, getChatBotFilesByIDs, arg.BotUuid, pq.Array(arg.Ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChatBotFile
+	for rows.Next() {
+		var i ChatBotFile
+		if err := rows.Scan(
+			&i.ID,
+			&i.BotUuid,
+			&i.UserID,
+			&i.Name,
+			&i.MimeType,
+			&i.Content,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChatBotVersion = `-- name: GetChatBotVersion :one
+SELECT id, bot_uuid, version, model, conversation, file_ids, note, created_by, created_at FROM chat_bot_version
+WHERE bot_uuid = $1 AND version = $2
+`
+
+type GetChatBotVersionParams struct {
+	BotUuid string `json:"botUuid"`
+	Version int32  `json:"version"`
+}
+
+func (q *Queries) GetChatBotVersion(ctx context.Context, arg GetChatBotVersionParams) (ChatBotVersion, error) {
+	row := q.db.QueryRowContext(ctx, getChatBotVersion, arg.BotUuid, arg.Version)
+	var i ChatBotVersion
+	err := row.Scan(
+		&i.ID,
+		&i.BotUuid,
+		&i.Version,
+		&i.Model,
+		&i.Conversation,
+		pq.Array(&i.FileIds),
+		&i.Note,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listChatBotFiles = `-- name: ListChatBotFiles :many
+SELECT id, bot_uuid, name, mime_type, octet_length(content)::INTEGER AS size, created_at
+FROM chat_bot_file
+WHERE bot_uuid = $1
+ORDER BY id
+`
+
+type ListChatBotFilesRow struct {
+	ID        int32     `json:"id"`
+	BotUuid   string    `json:"botUuid"`
+	Name      string    `json:"name"`
+	MimeType  string    `json:"mimeType"`
+	Size      int32     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (q *Queries) ListChatBotFiles(ctx context.Context, botUuid string) ([]ListChatBotFilesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChatBotFiles, botUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChatBotFilesRow
+	for rows.Next() {
+		var i ListChatBotFilesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.BotUuid,
+			&i.Name,
+			&i.MimeType,
+			&i.Size,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChatBotVersions = `-- name: ListChatBotVersions :many
+SELECT id, bot_uuid, version, model, conversation, file_ids, note, created_by, created_at FROM chat_bot_version
+WHERE bot_uuid = $1
+ORDER BY version DESC
+`
+
+func (q *Queries) ListChatBotVersions(ctx context.Context, botUuid string) ([]ChatBotVersion, error) {
+	rows, err := q.db.QueryContext(ctx, listChatBotVersions, botUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChatBotVersion
+	for rows.Next() {
+		var i ChatBotVersion
+		if err := rows.Scan(
+			&i.ID,
+			&i.BotUuid,
+			&i.Version,
+			&i.Model,
+			&i.Conversation,
+			pq.Array(&i.FileIds),
+			&i.Note,
+			&i.CreatedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -7,30 +7,64 @@ package sqlc_queries
 
 import (
 	"context"
-	"time"
+	"database/sql"
+
+	"github.com/lib/pq"
 )
 
+const attachPendingChatFiles = `-- name: AttachPendingChatFiles :execrows
+UPDATE chat_file
+SET chat_message_uuid = $1::varchar
+WHERE chat_session_uuid = $2
+  AND user_id = $3
+  AND chat_message_uuid IS NULL
+`
+
+type AttachPendingChatFilesParams struct {
+	ChatMessageUuid string `json:"chatMessageUuid"`
+	ChatSessionUuid string `json:"chatSessionUuid"`
+	UserID          int32  `json:"userId"`
+}
+
+func (q *Queries) AttachPendingChatFiles(ctx context.Context, arg AttachPendingChatFilesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, attachPendingChatFiles, arg.ChatMessageUuid, arg.ChatSessionUuid, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const createChatFile = `-- name: CreateChatFile :one
-INSERT INTO chat_file (name, data, user_id, chat_session_uuid, mime_type)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, name, data, created_at, user_id, chat_session_uuid, mime_type
+INSERT INTO chat_file (name, user_id, chat_session_uuid, mime_type, blob_hash, size, detached, source, chat_message_uuid)
+VALUES ($1, $2, $3, $4, $8::varchar, $5, $6, $7, $9)
+RETURNING id, name, data, created_at, user_id, chat_session_uuid, mime_type, blob_hash, size, chat_message_uuid, detached, source
 `
 
 type CreateChatFileParams struct {
-	Name            string `json:"name"`
-	Data            []byte `json:"data"`
-	UserID          int32  `json:"userId"`
-	ChatSessionUuid string `json:"chatSessionUuid"`
-	MimeType        string `json:"mimeType"`
+	Name            string         `json:"name"`
+	UserID          int32          `json:"userId"`
+	ChatSessionUuid string         `json:"chatSessionUuid"`
+	MimeType        string         `json:"mimeType"`
+	Size            int64          `json:"size"`
+	Detached        bool           `json:"detached"`
+	Source          string         `json:"source"`
+	BlobHash        string         `json:"blobHash"`
+	ChatMessageUuid sql.NullString `json:"chatMessageUuid"`
 }
 
+// chat_message_uuid is only set for files made with their message, such as
+// generated images; uploads wait for the next message.
 func (q *Queries) CreateChatFile(ctx context.Context, arg CreateChatFileParams) (ChatFile, error) {
 	row := q.db.QueryRowContext(ctx, createChatFile,
 		arg.Name,
-		arg.Data,
 		arg.UserID,
 		arg.ChatSessionUuid,
 		arg.MimeType,
+		arg.Size,
+		arg.Detached,
+		arg.Source,
+		arg.BlobHash,
+		arg.ChatMessageUuid,
 	)
 	var i ChatFile
 	err := row.Scan(
@@ -41,6 +75,11 @@ func (q *Queries) CreateChatFile(ctx context.Context, arg CreateChatFileParams)
 		&i.UserID,
 		&i.ChatSessionUuid,
 		&i.MimeType,
+		&i.BlobHash,
+		&i.Size,
+		&i.ChatMessageUuid,
+		&i.Detached,
+		&i.Source,
 	)
 	return i, err
 }
@@ -48,7 +87,7 @@ func (q *Queries) CreateChatFile(ctx context.Context, arg CreateChatFileParams)
 const deleteChatFile = `-- name: DeleteChatFile :one
 DELETE FROM chat_file
 WHERE id = $1
-RETURNING id, name, data, created_at, user_id, chat_session_uuid, mime_type
+RETURNING id, name, data, created_at, user_id, chat_session_uuid, mime_type, blob_hash, size, chat_message_uuid, detached, source
 `
 
 func (q *Queries) DeleteChatFile(ctx context.Context, id int32) (ChatFile, error) {
@@ -62,28 +101,24 @@ func (q *Queries) DeleteChatFile(ctx context.Context, id int32) (ChatFile, error
 		&i.UserID,
 		&i.ChatSessionUuid,
 		&i.MimeType,
+		&i.BlobHash,
+		&i.Size,
+		&i.ChatMessageUuid,
+		&i.Detached,
+		&i.Source,
 	)
 	return i, err
 }
 
 const getChatFileByID = `-- name: GetChatFileByID :one
-SELECT id, name, data, created_at, user_id, chat_session_uuid
+SELECT id, name, data, created_at, user_id, chat_session_uuid, mime_type, blob_hash, size, chat_message_uuid, detached, source
 FROM chat_file
 WHERE id = $1
 `
 
-type GetChatFileByIDRow struct {
-	ID              int32     `json:"id"`
-	Name            string    `json:"name"`
-	Data            []byte    `json:"data"`
-	CreatedAt       time.Time `json:"createdAt"`
-	UserID          int32     `json:"userId"`
-	ChatSessionUuid string    `json:"chatSessionUuid"`
-}
-
-func (q *Queries) GetChatFileByID(ctx context.Context, id int32) (GetChatFileByIDRow, error) {
+func (q *Queries) GetChatFileByID(ctx context.Context, id int32) (ChatFile, error) {
 	row := q.db.QueryRowContext(ctx, getChatFileByID, id)
-	var i GetChatFileByIDRow
+	var i ChatFile
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
@@ -91,10 +126,109 @@ func (q *Queries) GetChatFileByID(ctx context.Context, id int32) (GetChatFileByI
 		&i.CreatedAt,
 		&i.UserID,
 		&i.ChatSessionUuid,
+		&i.MimeType,
+		&i.BlobHash,
+		&i.Size,
+		&i.ChatMessageUuid,
+		&i.Detached,
+		&i.Source,
 	)
 	return i, err
 }
 
+const listChatFileAttachmentsBySessionUUID = `-- name: ListChatFileAttachmentsBySessionUUID :many
+SELECT id, name, mime_type, size, chat_message_uuid, detached, source
+FROM chat_file
+WHERE chat_session_uuid = $1 AND chat_message_uuid IS NOT NULL
+ORDER BY created_at, id
+`
+
+type ListChatFileAttachmentsBySessionUUIDRow struct {
+	ID              int32          `json:"id"`
+	Name            string         `json:"name"`
+	MimeType        string         `json:"mimeType"`
+	Size            int64          `json:"size"`
+	ChatMessageUuid sql.NullString `json:"chatMessageUuid"`
+	Detached        bool           `json:"detached"`
+	Source          string         `json:"source"`
+}
+
+func (q *Queries) ListChatFileAttachmentsBySessionUUID(ctx context.Context, chatSessionUuid string) ([]ListChatFileAttachmentsBySessionUUIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChatFileAttachmentsBySessionUUID, chatSessionUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChatFileAttachmentsBySessionUUIDRow
+	for rows.Next() {
+		var i ListChatFileAttachmentsBySessionUUIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.MimeType,
+			&i.Size,
+			&i.ChatMessageUuid,
+			&i.Detached,
+			&i.Source,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChatFilesByMessageUUIDs = `-- name: ListChatFilesByMessageUUIDs :many
+SELECT id, name, data, created_at, user_id, chat_session_uuid, mime_type, blob_hash, size, chat_message_uuid, detached, source
+FROM chat_file
+WHERE chat_message_uuid = ANY($1::varchar[])
+ORDER BY created_at, id
+`
+
+// data is only set on files stored inline before blob storage; the others
+// are read by blob_hash.
+func (q *Queries) ListChatFilesByMessageUUIDs(ctx context.Context, messageUuids []string) ([]ChatFile, error) {
+	rows, err := q.db.QueryContext(ctx, listChatFilesByMessageUUIDs, pq.Array(messageUuids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChatFile
+	for rows.Next() {
+		var i ChatFile
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Data,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.ChatSessionUuid,
+			&i.MimeType,
+			&i.BlobHash,
+			&i.Size,
+			&i.ChatMessageUuid,
+			&i.Detached,
+			&i.Source,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listChatFilesBySessionUUID = `-- name: ListChatFilesBySessionUUID :many
 SELECT id, name
 FROM chat_file
@@ -135,31 +269,34 @@ func (q *Queries) ListChatFilesBySessionUUID(ctx context.Context, arg ListChatFi
 	return items, nil
 }
 
-const listChatFilesWithContentBySessionUUID = `-- name: ListChatFilesWithContentBySessionUUID :many
-SELECT id, name, data, created_at, user_id, chat_session_uuid, mime_type
+const listInlineChatFiles = `-- name: ListInlineChatFiles :many
+SELECT id, data
 FROM chat_file
-WHERE chat_session_uuid = $1
-ORDER BY created_at
+WHERE blob_hash IS NULL AND id > $1
+ORDER BY id
+LIMIT $2
 `
 
-func (q *Queries) ListChatFilesWithContentBySessionUUID(ctx context.Context, chatSessionUuid string) ([]ChatFile, error) {
-	rows, err := q.db.QueryContext(ctx, listChatFilesWithContentBySessionUUID, chatSessionUuid)
+type ListInlineChatFilesParams struct {
+	AfterID    int32 `json:"afterId"`
+	LimitCount int32 `json:"limitCount"`
+}
+
+type ListInlineChatFilesRow struct {
+	ID   int32  `json:"id"`
+	Data []byte `json:"data"`
+}
+
+func (q *Queries) ListInlineChatFiles(ctx context.Context, arg ListInlineChatFilesParams) ([]ListInlineChatFilesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listInlineChatFiles, arg.AfterID, arg.LimitCount)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []ChatFile
+	var items []ListInlineChatFilesRow
 	for rows.Next() {
-		var i ChatFile
-		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.Data,
-			&i.CreatedAt,
-			&i.UserID,
-			&i.ChatSessionUuid,
-			&i.MimeType,
-		); err != nil {
+		var i ListInlineChatFilesRow
+		if err := rows.Scan(&i.ID, &i.Data); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -172,3 +309,53 @@ func (q *Queries) ListChatFilesWithContentBySessionUUID(ctx context.Context, cha
 	}
 	return items, nil
 }
+
+const setChatFileBlob = `-- name: SetChatFileBlob :exec
+UPDATE chat_file
+SET blob_hash = $1::varchar, size = $2, data = ''::bytea
+WHERE id = $3
+`
+
+type SetChatFileBlobParams struct {
+	BlobHash string `json:"blobHash"`
+	Size     int64  `json:"size"`
+	ID       int32  `json:"id"`
+}
+
+func (q *Queries) SetChatFileBlob(ctx context.Context, arg SetChatFileBlobParams) error {
+	_, err := q.db.ExecContext(ctx, setChatFileBlob, arg.BlobHash, arg.Size, arg.ID)
+	return err
+}
+
+const setChatFileDetached = `-- name: SetChatFileDetached :one
+UPDATE chat_file
+SET detached = $1
+WHERE id = $2 AND user_id = $3
+RETURNING id, name, data, created_at, user_id, chat_session_uuid, mime_type, blob_hash, size, chat_message_uuid, detached, source
+`
+
+type SetChatFileDetachedParams struct {
+	Detached bool  `json:"detached"`
+	ID       int32 `json:"id"`
+	UserID   int32 `json:"userId"`
+}
+
+func (q *Queries) SetChatFileDetached(ctx context.Context, arg SetChatFileDetachedParams) (ChatFile, error) {
+	row := q.db.QueryRowContext(ctx, setChatFileDetached, arg.Detached, arg.ID, arg.UserID)
+	var i ChatFile
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Data,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.ChatSessionUuid,
+		&i.MimeType,
+		&i.BlobHash,
+		&i.Size,
+		&i.ChatMessageUuid,
+		&i.Detached,
+		&i.Source,
+	)
+	return i, err
+}
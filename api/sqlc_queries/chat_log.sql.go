@@ -8,6 +8,7 @@ package sqlc_queries
 import (
 	"context"
 	"encoding/json"
+	"time"
 )
 
 const chatLogByID = `-- name: ChatLogByID :one
@@ -27,6 +28,39 @@ func (q *Queries) ChatLogByID(ctx context.Context, id int32) (ChatLog, error) {
 	return i, err
 }
 
+const countChatLogsFiltered = `-- name: CountChatLogsFiltered :one
+SELECT COUNT(*) FROM chat_logs
+WHERE ($1::INTEGER = 0 OR (session->>'userId')::INTEGER = $1::INTEGER)
+  AND ($2::TEXT = '' OR session->>'model' = $2::TEXT)
+  AND ($3::TEXT = '' OR session->>'uuid' = $3::TEXT)
+  AND ($4::TEXT = '' OR question::TEXT ILIKE '%' || $4::TEXT || '%' OR answer::TEXT ILIKE '%' || $4::TEXT || '%')
+  AND created_at >= $5::TIMESTAMP
+  AND created_at < $6::TIMESTAMP
+`
+
+type CountChatLogsFilteredParams struct {
+	UserID      int32     `json:"userId"`
+	Model       string    `json:"model"`
+	SessionUuid string    `json:"sessionUuid"`
+	Keyword     string    `json:"keyword"`
+	CreatedFrom time.Time `json:"createdFrom"`
+	CreatedTo   time.Time `json:"createdTo"`
+}
+
+func (q *Queries) CountChatLogsFiltered(ctx context.Context, arg CountChatLogsFilteredParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChatLogsFiltered,
+		arg.UserID,
+		arg.Model,
+		arg.SessionUuid,
+		arg.Keyword,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChatLog = `-- name: CreateChatLog :one
 INSERT INTO chat_logs (session, question, answer)
 VALUES ($1, $2, $3)
@@ -94,6 +128,79 @@ func (q *Queries) ListChatLogs(ctx context.Context) ([]ChatLog, error) {
 	return items, nil
 }
 
+const listChatLogsFiltered = `-- name: ListChatLogsFiltered :many
+SELECT id, session, question, answer, created_at FROM chat_logs
+WHERE ($1::INTEGER = 0 OR (session->>'userId')::INTEGER = $1::INTEGER)
+  AND ($2::TEXT = '' OR session->>'model' = $2::TEXT)
+  AND ($3::TEXT = '' OR session->>'uuid' = $3::TEXT)
+  AND ($4::TEXT = '' OR question::TEXT ILIKE '%' || $4::TEXT || '%' OR answer::TEXT ILIKE '%' || $4::TEXT || '%')
+  AND created_at >= $5::TIMESTAMP
+  AND created_at < $6::TIMESTAMP
+ORDER BY id DESC
+LIMIT $8 OFFSET $7
+`
+
+type ListChatLogsFilteredParams struct {
+	UserID      int32     `json:"userId"`
+	Model       string    `json:"model"`
+	SessionUuid string    `json:"sessionUuid"`
+	Keyword     string    `json:"keyword"`
+	CreatedFrom time.Time `json:"createdFrom"`
+	CreatedTo   time.Time `json:"createdTo"`
+	PageOffset  int32     `json:"pageOffset"`
+	PageLimit   int32     `json:"pageLimit"`
+}
+
+func (q *Queries) ListChatLogsFiltered(ctx context.Context, arg ListChatLogsFilteredParams) ([]ChatLog, error) {
+	rows, err := q.db.QueryContext(ctx, listChatLogsFiltered,
+		arg.UserID,
+		arg.Model,
+		arg.SessionUuid,
+		arg.Keyword,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.PageOffset,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChatLog
+	for rows.Next() {
+		var i ChatLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.Session,
+			&i.Question,
+			&i.Answer,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeChatLogsBefore = `-- name: PurgeChatLogsBefore :execrows
+DELETE FROM chat_logs WHERE created_at < $1
+`
+
+func (q *Queries) PurgeChatLogsBefore(ctx context.Context, createdAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeChatLogsBefore, createdAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateChatLog = `-- name: UpdateChatLog :one
 UPDATE chat_logs SET session = $2, question = $3, answer = $4
 WHERE id = $1
@@ -503,6 +503,41 @@ func (q *Queries) GetFirstMessageBySessionUUID(ctx context.Context, chatSessionU
 	return i, err
 }
 
+const getLastAssistantMessageBySessionUUID = `-- name: GetLastAssistantMessageBySessionUUID :one
+SELECT id, uuid, chat_session_uuid, role, content, reasoning_content, model, llm_summary, score, user_id, created_at, updated_at, created_by, updated_by, is_deleted, is_pin, token_count, raw, artifacts, suggested_questions FROM chat_message
+WHERE chat_session_uuid = $1 AND role = 'assistant' AND is_deleted = false
+ORDER BY id DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLastAssistantMessageBySessionUUID(ctx context.Context, chatSessionUuid string) (ChatMessage, error) {
+	row := q.db.QueryRowContext(ctx, getLastAssistantMessageBySessionUUID, chatSessionUuid)
+	var i ChatMessage
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.ChatSessionUuid,
+		&i.Role,
+		&i.Content,
+		&i.ReasoningContent,
+		&i.Model,
+		&i.LlmSummary,
+		&i.Score,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CreatedBy,
+		&i.UpdatedBy,
+		&i.IsDeleted,
+		&i.IsPin,
+		&i.TokenCount,
+		&i.Raw,
+		&i.Artifacts,
+		&i.SuggestedQuestions,
+	)
+	return i, err
+}
+
 const getLastNChatMessages = `-- name: GetLastNChatMessages :many
 SELECT id, uuid, chat_session_uuid, role, content, reasoning_content, model, llm_summary, score, user_id, created_at, updated_at, created_by, updated_by, is_deleted, is_pin, token_count, raw, artifacts, suggested_questions
 FROM chat_message
@@ -7,10 +7,11 @@ package sqlc_queries
 
 import (
 	"context"
+	"database/sql"
 )
 
 const chatModelByID = `-- name: ChatModelByID :one
-SELECT id, name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, is_enable, api_type FROM chat_model WHERE id = $1
+SELECT id, name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, is_enable, api_type, credential_id FROM chat_model WHERE id = $1
 `
 
 func (q *Queries) ChatModelByID(ctx context.Context, id int32) (ChatModel, error) {
@@ -32,12 +33,13 @@ func (q *Queries) ChatModelByID(ctx context.Context, id int32) (ChatModel, error
 		&i.HttpTimeOut,
 		&i.IsEnable,
 		&i.ApiType,
+		&i.CredentialID,
 	)
 	return i, err
 }
 
 const chatModelByName = `-- name: ChatModelByName :one
-SELECT id, name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, is_enable, api_type FROM chat_model WHERE name = $1
+SELECT id, name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, is_enable, api_type, credential_id FROM chat_model WHERE name = $1
 `
 
 func (q *Queries) ChatModelByName(ctx context.Context, name string) (ChatModel, error) {
@@ -59,6 +61,7 @@ func (q *Queries) ChatModelByName(ctx context.Context, name string) (ChatModel,
 		&i.HttpTimeOut,
 		&i.IsEnable,
 		&i.ApiType,
+		&i.CredentialID,
 	)
 	return i, err
 }
@@ -66,7 +69,7 @@ func (q *Queries) ChatModelByName(ctx context.Context, name string) (ChatModel,
 const createChatModel = `-- name: CreateChatModel :one
 INSERT INTO chat_model (name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, api_type )
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
-RETURNING id, name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, is_enable, api_type
+RETURNING id, name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, is_enable, api_type, credential_id
 `
 
 type CreateChatModelParams struct {
@@ -118,6 +121,7 @@ func (q *Queries) CreateChatModel(ctx context.Context, arg CreateChatModelParams
 		&i.HttpTimeOut,
 		&i.IsEnable,
 		&i.ApiType,
+		&i.CredentialID,
 	)
 	return i, err
 }
@@ -137,7 +141,7 @@ func (q *Queries) DeleteChatModel(ctx context.Context, arg DeleteChatModelParams
 }
 
 const getDefaultChatModel = `-- name: GetDefaultChatModel :one
-SELECT id, name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, is_enable, api_type FROM chat_model WHERE is_default = true
+SELECT id, name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, is_enable, api_type, credential_id FROM chat_model WHERE is_default = true
 and user_id in (select id from auth_user where is_superuser = true)
 ORDER BY order_number, id
 LIMIT 1
@@ -162,12 +166,13 @@ func (q *Queries) GetDefaultChatModel(ctx context.Context) (ChatModel, error) {
 		&i.HttpTimeOut,
 		&i.IsEnable,
 		&i.ApiType,
+		&i.CredentialID,
 	)
 	return i, err
 }
 
 const listChatModels = `-- name: ListChatModels :many
-SELECT id, name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, is_enable, api_type FROM chat_model ORDER BY order_number
+SELECT id, name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, is_enable, api_type, credential_id FROM chat_model ORDER BY order_number
 `
 
 func (q *Queries) ListChatModels(ctx context.Context) ([]ChatModel, error) {
@@ -195,6 +200,7 @@ func (q *Queries) ListChatModels(ctx context.Context) ([]ChatModel, error) {
 			&i.HttpTimeOut,
 			&i.IsEnable,
 			&i.ApiType,
+			&i.CredentialID,
 		); err != nil {
 			return nil, err
 		}
@@ -210,7 +216,7 @@ func (q *Queries) ListChatModels(ctx context.Context) ([]ChatModel, error) {
 }
 
 const listSystemChatModels = `-- name: ListSystemChatModels :many
-SELECT id, name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, is_enable, api_type FROM chat_model
+SELECT id, name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, is_enable, api_type, credential_id FROM chat_model
 where user_id in (select id from auth_user where is_superuser = true)
 ORDER BY order_number, id desc
 `
@@ -240,6 +246,7 @@ func (q *Queries) ListSystemChatModels(ctx context.Context) ([]ChatModel, error)
 			&i.HttpTimeOut,
 			&i.IsEnable,
 			&i.ApiType,
+			&i.CredentialID,
 		); err != nil {
 			return nil, err
 		}
@@ -254,11 +261,46 @@ func (q *Queries) ListSystemChatModels(ctx context.Context) ([]ChatModel, error)
 	return items, nil
 }
 
+const setChatModelCredential = `-- name: SetChatModelCredential :one
+UPDATE chat_model SET credential_id = $1
+WHERE id = $2
+RETURNING id, name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, is_enable, api_type, credential_id
+`
+
+type SetChatModelCredentialParams struct {
+	CredentialID sql.NullInt32 `json:"credentialId"`
+	ID           int32         `json:"id"`
+}
+
+func (q *Queries) SetChatModelCredential(ctx context.Context, arg SetChatModelCredentialParams) (ChatModel, error) {
+	row := q.db.QueryRowContext(ctx, setChatModelCredential, arg.CredentialID, arg.ID)
+	var i ChatModel
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Label,
+		&i.IsDefault,
+		&i.Url,
+		&i.ApiAuthHeader,
+		&i.ApiAuthKey,
+		&i.UserID,
+		&i.EnablePerModeRatelimit,
+		&i.MaxToken,
+		&i.DefaultToken,
+		&i.OrderNumber,
+		&i.HttpTimeOut,
+		&i.IsEnable,
+		&i.ApiType,
+		&i.CredentialID,
+	)
+	return i, err
+}
+
 const updateChatModel = `-- name: UpdateChatModel :one
 UPDATE chat_model SET name = $2, label = $3, is_default = $4, url = $5, api_auth_header = $6, api_auth_key = $7, enable_per_mode_ratelimit = $9,
 max_token = $10, default_token = $11, order_number = $12, http_time_out = $13, is_enable = $14, api_type = $15
 WHERE id = $1 and user_id = $8
-RETURNING id, name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, is_enable, api_type
+RETURNING id, name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, is_enable, api_type, credential_id
 `
 
 type UpdateChatModelParams struct {
@@ -314,6 +356,7 @@ func (q *Queries) UpdateChatModel(ctx context.Context, arg UpdateChatModelParams
 		&i.HttpTimeOut,
 		&i.IsEnable,
 		&i.ApiType,
+		&i.CredentialID,
 	)
 	return i, err
 }
@@ -321,7 +364,7 @@ func (q *Queries) UpdateChatModel(ctx context.Context, arg UpdateChatModelParams
 const updateChatModelKey = `-- name: UpdateChatModelKey :one
 UPDATE chat_model SET api_auth_key = $2
 WHERE id = $1
-RETURNING id, name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, is_enable, api_type
+RETURNING id, name, label, is_default, url, api_auth_header, api_auth_key, user_id, enable_per_mode_ratelimit, max_token, default_token, order_number, http_time_out, is_enable, api_type, credential_id
 `
 
 type UpdateChatModelKeyParams struct {
@@ -348,6 +391,7 @@ func (q *Queries) UpdateChatModelKey(ctx context.Context, arg UpdateChatModelKey
 		&i.HttpTimeOut,
 		&i.IsEnable,
 		&i.ApiType,
+		&i.CredentialID,
 	)
 	return i, err
 }
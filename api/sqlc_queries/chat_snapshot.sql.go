@@ -12,7 +12,7 @@ import (
 )
 
 const chatSnapshotByID = `-- name: ChatSnapshotByID :one
-SELECT id, typ, uuid, user_id, title, summary, model, tags, session, conversation, created_at, text, search_vector FROM chat_snapshot WHERE id = $1
+SELECT id, typ, uuid, user_id, title, summary, model, tags, session, conversation, created_at, text, search_vector, bot_version FROM chat_snapshot WHERE id = $1
 `
 
 func (q *Queries) ChatSnapshotByID(ctx context.Context, id int32) (ChatSnapshot, error) {
@@ -32,12 +32,13 @@ func (q *Queries) ChatSnapshotByID(ctx context.Context, id int32) (ChatSnapshot,
 		&i.CreatedAt,
 		&i.Text,
 		&i.SearchVector,
+		&i.BotVersion,
 	)
 	return i, err
 }
 
 const chatSnapshotByUUID = `-- name: ChatSnapshotByUUID :one
-SELECT id, typ, uuid, user_id, title, summary, model, tags, session, conversation, created_at, text, search_vector FROM chat_snapshot WHERE uuid = $1
+SELECT id, typ, uuid, user_id, title, summary, model, tags, session, conversation, created_at, text, search_vector, bot_version FROM chat_snapshot WHERE uuid = $1
 `
 
 func (q *Queries) ChatSnapshotByUUID(ctx context.Context, uuid string) (ChatSnapshot, error) {
@@ -57,12 +58,13 @@ func (q *Queries) ChatSnapshotByUUID(ctx context.Context, uuid string) (ChatSnap
 		&i.CreatedAt,
 		&i.Text,
 		&i.SearchVector,
+		&i.BotVersion,
 	)
 	return i, err
 }
 
 const chatSnapshotByUserIdAndUuid = `-- name: ChatSnapshotByUserIdAndUuid :one
-SELECT id, typ, uuid, user_id, title, summary, model, tags, session, conversation, created_at, text, search_vector FROM chat_snapshot WHERE user_id = $1 AND uuid = $2
+SELECT id, typ, uuid, user_id, title, summary, model, tags, session, conversation, created_at, text, search_vector, bot_version FROM chat_snapshot WHERE user_id = $1 AND uuid = $2
 `
 
 type ChatSnapshotByUserIdAndUuidParams struct {
@@ -87,6 +89,7 @@ func (q *Queries) ChatSnapshotByUserIdAndUuid(ctx context.Context, arg ChatSnaps
 		&i.CreatedAt,
 		&i.Text,
 		&i.SearchVector,
+		&i.BotVersion,
 	)
 	return i, err
 }
@@ -212,7 +215,7 @@ func (q *Queries) ChatSnapshotSearch(ctx context.Context, arg ChatSnapshotSearch
 const createChatBot = `-- name: CreateChatBot :one
 INSERT INTO chat_snapshot (uuid, user_id, typ, title, model, summary, tags, conversation ,session, text )
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-RETURNING id, typ, uuid, user_id, title, summary, model, tags, session, conversation, created_at, text, search_vector
+RETURNING id, typ, uuid, user_id, title, summary, model, tags, session, conversation, created_at, text, search_vector, bot_version
 `
 
 type CreateChatBotParams struct {
@@ -256,6 +259,7 @@ func (q *Queries) CreateChatBot(ctx context.Context, arg CreateChatBotParams) (C
 		&i.CreatedAt,
 		&i.Text,
 		&i.SearchVector,
+		&i.BotVersion,
 	)
 	return i, err
 }
@@ -263,7 +267,7 @@ func (q *Queries) CreateChatBot(ctx context.Context, arg CreateChatBotParams) (C
 const createChatSnapshot = `-- name: CreateChatSnapshot :one
 INSERT INTO chat_snapshot (uuid, user_id, title, model, summary, tags, conversation ,session, text )
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-RETURNING id, typ, uuid, user_id, title, summary, model, tags, session, conversation, created_at, text, search_vector
+RETURNING id, typ, uuid, user_id, title, summary, model, tags, session, conversation, created_at, text, search_vector, bot_version
 `
 
 type CreateChatSnapshotParams struct {
@@ -305,6 +309,7 @@ func (q *Queries) CreateChatSnapshot(ctx context.Context, arg CreateChatSnapshot
 		&i.CreatedAt,
 		&i.Text,
 		&i.SearchVector,
+		&i.BotVersion,
 	)
 	return i, err
 }
@@ -312,7 +317,7 @@ func (q *Queries) CreateChatSnapshot(ctx context.Context, arg CreateChatSnapshot
 const deleteChatSnapshot = `-- name: DeleteChatSnapshot :one
 DELETE FROM chat_snapshot WHERE uuid = $1
 and user_id = $2
-RETURNING id, typ, uuid, user_id, title, summary, model, tags, session, conversation, created_at, text, search_vector
+RETURNING id, typ, uuid, user_id, title, summary, model, tags, session, conversation, created_at, text, search_vector, bot_version
 `
 
 type DeleteChatSnapshotParams struct {
@@ -337,12 +342,13 @@ func (q *Queries) DeleteChatSnapshot(ctx context.Context, arg DeleteChatSnapshot
 		&i.CreatedAt,
 		&i.Text,
 		&i.SearchVector,
+		&i.BotVersion,
 	)
 	return i, err
 }
 
 const listChatSnapshots = `-- name: ListChatSnapshots :many
-SELECT id, typ, uuid, user_id, title, summary, model, tags, session, conversation, created_at, text, search_vector FROM chat_snapshot ORDER BY id
+SELECT id, typ, uuid, user_id, title, summary, model, tags, session, conversation, created_at, text, search_vector, bot_version FROM chat_snapshot ORDER BY id
 `
 
 func (q *Queries) ListChatSnapshots(ctx context.Context) ([]ChatSnapshot, error) {
@@ -368,6 +374,7 @@ func (q *Queries) ListChatSnapshots(ctx context.Context) ([]ChatSnapshot, error)
 			&i.CreatedAt,
 			&i.Text,
 			&i.SearchVector,
+			&i.BotVersion,
 		); err != nil {
 			return nil, err
 		}
@@ -386,7 +393,7 @@ const updateChatSnapshot = `-- name: UpdateChatSnapshot :one
 UPDATE chat_snapshot
 SET uuid = $2, user_id = $3, title = $4, summary = $5, tags = $6, conversation = $7, created_at = $8
 WHERE id = $1
-RETURNING id, typ, uuid, user_id, title, summary, model, tags, session, conversation, created_at, text, search_vector
+RETURNING id, typ, uuid, user_id, title, summary, model, tags, session, conversation, created_at, text, search_vector, bot_version
 `
 
 type UpdateChatSnapshotParams struct {
@@ -426,6 +433,7 @@ func (q *Queries) UpdateChatSnapshot(ctx context.Context, arg UpdateChatSnapshot
 		&i.CreatedAt,
 		&i.Text,
 		&i.SearchVector,
+		&i.BotVersion,
 	)
 	return i, err
 }
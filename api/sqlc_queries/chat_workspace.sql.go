@@ -7,13 +7,14 @@ package sqlc_queries
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 )
 
 const createDefaultWorkspace = `-- name: CreateDefaultWorkspace :one
 INSERT INTO chat_workspace (uuid, user_id, name, description, color, icon, is_default, order_position)
 VALUES ($1, $2, 'General', 'Default workspace for all conversations', '#6366f1', 'folder', true, 0)
-RETURNING id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position
+RETURNING id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position, chat_log_enabled, auxiliary_models
 `
 
 type CreateDefaultWorkspaceParams struct {
@@ -36,6 +37,8 @@ func (q *Queries) CreateDefaultWorkspace(ctx context.Context, arg CreateDefaultW
 		&i.UpdatedAt,
 		&i.IsDefault,
 		&i.OrderPosition,
+		&i.ChatLogEnabled,
+		&i.AuxiliaryModels,
 	)
 	return i, err
 }
@@ -43,7 +46,7 @@ func (q *Queries) CreateDefaultWorkspace(ctx context.Context, arg CreateDefaultW
 const createWorkspace = `-- name: CreateWorkspace :one
 INSERT INTO chat_workspace (uuid, user_id, name, description, color, icon, is_default, order_position)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position
+RETURNING id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position, chat_log_enabled, auxiliary_models
 `
 
 type CreateWorkspaceParams struct {
@@ -81,6 +84,8 @@ func (q *Queries) CreateWorkspace(ctx context.Context, arg CreateWorkspaceParams
 		&i.UpdatedAt,
 		&i.IsDefault,
 		&i.OrderPosition,
+		&i.ChatLogEnabled,
+		&i.AuxiliaryModels,
 	)
 	return i, err
 }
@@ -96,7 +101,7 @@ func (q *Queries) DeleteWorkspace(ctx context.Context, uuid string) error {
 }
 
 const getDefaultWorkspaceByUserID = `-- name: GetDefaultWorkspaceByUserID :one
-SELECT id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position FROM chat_workspace 
+SELECT id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position, chat_log_enabled, auxiliary_models FROM chat_workspace 
 WHERE user_id = $1 AND is_default = true
 LIMIT 1
 `
@@ -116,12 +121,52 @@ func (q *Queries) GetDefaultWorkspaceByUserID(ctx context.Context, userID int32)
 		&i.UpdatedAt,
 		&i.IsDefault,
 		&i.OrderPosition,
+		&i.ChatLogEnabled,
+		&i.AuxiliaryModels,
+	)
+	return i, err
+}
+
+const getWorkspaceAuxiliaryModels = `-- name: GetWorkspaceAuxiliaryModels :one
+SELECT auxiliary_models FROM chat_workspace
+WHERE id = $1
+`
+
+func (q *Queries) GetWorkspaceAuxiliaryModels(ctx context.Context, id int32) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, getWorkspaceAuxiliaryModels, id)
+	var auxiliary_models json.RawMessage
+	err := row.Scan(&auxiliary_models)
+	return auxiliary_models, err
+}
+
+const getWorkspaceByID = `-- name: GetWorkspaceByID :one
+SELECT id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position, chat_log_enabled, auxiliary_models FROM chat_workspace
+WHERE id = $1
+`
+
+func (q *Queries) GetWorkspaceByID(ctx context.Context, id int32) (ChatWorkspace, error) {
+	row := q.db.QueryRowContext(ctx, getWorkspaceByID, id)
+	var i ChatWorkspace
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.UserID,
+		&i.Name,
+		&i.Description,
+		&i.Color,
+		&i.Icon,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsDefault,
+		&i.OrderPosition,
+		&i.ChatLogEnabled,
+		&i.AuxiliaryModels,
 	)
 	return i, err
 }
 
 const getWorkspaceByUUID = `-- name: GetWorkspaceByUUID :one
-SELECT id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position FROM chat_workspace 
+SELECT id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position, chat_log_enabled, auxiliary_models FROM chat_workspace 
 WHERE uuid = $1
 `
 
@@ -140,13 +185,15 @@ func (q *Queries) GetWorkspaceByUUID(ctx context.Context, uuid string) (ChatWork
 		&i.UpdatedAt,
 		&i.IsDefault,
 		&i.OrderPosition,
+		&i.ChatLogEnabled,
+		&i.AuxiliaryModels,
 	)
 	return i, err
 }
 
 const getWorkspaceWithSessionCount = `-- name: GetWorkspaceWithSessionCount :many
 SELECT 
-    w.id, w.uuid, w.user_id, w.name, w.description, w.color, w.icon, w.created_at, w.updated_at, w.is_default, w.order_position,
+    w.id, w.uuid, w.user_id, w.name, w.description, w.color, w.icon, w.created_at, w.updated_at, w.is_default, w.order_position, w.chat_log_enabled, w.auxiliary_models,
     COUNT(cs.id) as session_count
 FROM chat_workspace w
 LEFT JOIN chat_session cs ON w.id = cs.workspace_id AND cs.active = true
@@ -156,18 +203,20 @@ ORDER BY w.order_position ASC, w.created_at ASC
 `
 
 type GetWorkspaceWithSessionCountRow struct {
-	ID            int32     `json:"id"`
-	Uuid          string    `json:"uuid"`
-	UserID        int32     `json:"userId"`
-	Name          string    `json:"name"`
-	Description   string    `json:"description"`
-	Color         string    `json:"color"`
-	Icon          string    `json:"icon"`
-	CreatedAt     time.Time `json:"createdAt"`
-	UpdatedAt     time.Time `json:"updatedAt"`
-	IsDefault     bool      `json:"isDefault"`
-	OrderPosition int32     `json:"orderPosition"`
-	SessionCount  int64     `json:"sessionCount"`
+	ID              int32           `json:"id"`
+	Uuid            string          `json:"uuid"`
+	UserID          int32           `json:"userId"`
+	Name            string          `json:"name"`
+	Description     string          `json:"description"`
+	Color           string          `json:"color"`
+	Icon            string          `json:"icon"`
+	CreatedAt       time.Time       `json:"createdAt"`
+	UpdatedAt       time.Time       `json:"updatedAt"`
+	IsDefault       bool            `json:"isDefault"`
+	OrderPosition   int32           `json:"orderPosition"`
+	ChatLogEnabled  bool            `json:"chatLogEnabled"`
+	AuxiliaryModels json.RawMessage `json:"auxiliaryModels"`
+	SessionCount    int64           `json:"sessionCount"`
 }
 
 func (q *Queries) GetWorkspaceWithSessionCount(ctx context.Context, userID int32) ([]GetWorkspaceWithSessionCountRow, error) {
@@ -191,6 +240,8 @@ func (q *Queries) GetWorkspaceWithSessionCount(ctx context.Context, userID int32
 			&i.UpdatedAt,
 			&i.IsDefault,
 			&i.OrderPosition,
+			&i.ChatLogEnabled,
+			&i.AuxiliaryModels,
 			&i.SessionCount,
 		); err != nil {
 			return nil, err
@@ -207,7 +258,7 @@ func (q *Queries) GetWorkspaceWithSessionCount(ctx context.Context, userID int32
 }
 
 const getWorkspacesByUserID = `-- name: GetWorkspacesByUserID :many
-SELECT id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position FROM chat_workspace 
+SELECT id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position, chat_log_enabled, auxiliary_models FROM chat_workspace 
 WHERE user_id = $1
 ORDER BY order_position ASC, created_at ASC
 `
@@ -233,6 +284,8 @@ func (q *Queries) GetWorkspacesByUserID(ctx context.Context, userID int32) ([]Ch
 			&i.UpdatedAt,
 			&i.IsDefault,
 			&i.OrderPosition,
+			&i.ChatLogEnabled,
+			&i.AuxiliaryModels,
 		); err != nil {
 			return nil, err
 		}
@@ -273,11 +326,22 @@ func (q *Queries) HasWorkspacePermission(ctx context.Context, arg HasWorkspacePe
 	return has_permission, err
 }
 
+const isChatLogEnabledForWorkspace = `-- name: IsChatLogEnabledForWorkspace :one
+SELECT COALESCE((SELECT chat_log_enabled FROM chat_workspace WHERE id = $1), true)::BOOLEAN AS enabled
+`
+
+func (q *Queries) IsChatLogEnabledForWorkspace(ctx context.Context, id int32) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isChatLogEnabledForWorkspace, id)
+	var enabled bool
+	err := row.Scan(&enabled)
+	return enabled, err
+}
+
 const setDefaultWorkspace = `-- name: SetDefaultWorkspace :one
 UPDATE chat_workspace 
 SET is_default = $2, updated_at = now()
 WHERE uuid = $1
-RETURNING id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position
+RETURNING id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position, chat_log_enabled, auxiliary_models
 `
 
 type SetDefaultWorkspaceParams struct {
@@ -300,6 +364,8 @@ func (q *Queries) SetDefaultWorkspace(ctx context.Context, arg SetDefaultWorkspa
 		&i.UpdatedAt,
 		&i.IsDefault,
 		&i.OrderPosition,
+		&i.ChatLogEnabled,
+		&i.AuxiliaryModels,
 	)
 	return i, err
 }
@@ -308,7 +374,7 @@ const updateWorkspace = `-- name: UpdateWorkspace :one
 UPDATE chat_workspace 
 SET name = $2, description = $3, color = $4, icon = $5, updated_at = now()
 WHERE uuid = $1
-RETURNING id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position
+RETURNING id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position, chat_log_enabled, auxiliary_models
 `
 
 type UpdateWorkspaceParams struct {
@@ -340,6 +406,74 @@ func (q *Queries) UpdateWorkspace(ctx context.Context, arg UpdateWorkspaceParams
 		&i.UpdatedAt,
 		&i.IsDefault,
 		&i.OrderPosition,
+		&i.ChatLogEnabled,
+		&i.AuxiliaryModels,
+	)
+	return i, err
+}
+
+const updateWorkspaceAuxiliaryModels = `-- name: UpdateWorkspaceAuxiliaryModels :one
+UPDATE chat_workspace
+SET auxiliary_models = $2, updated_at = now()
+WHERE uuid = $1
+RETURNING id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position, chat_log_enabled, auxiliary_models
+`
+
+type UpdateWorkspaceAuxiliaryModelsParams struct {
+	Uuid            string          `json:"uuid"`
+	AuxiliaryModels json.RawMessage `json:"auxiliaryModels"`
+}
+
+func (q *Queries) UpdateWorkspaceAuxiliaryModels(ctx context.Context, arg UpdateWorkspaceAuxiliaryModelsParams) (ChatWorkspace, error) {
+	row := q.db.QueryRowContext(ctx, updateWorkspaceAuxiliaryModels, arg.Uuid, arg.AuxiliaryModels)
+	var i ChatWorkspace
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.UserID,
+		&i.Name,
+		&i.Description,
+		&i.Color,
+		&i.Icon,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsDefault,
+		&i.OrderPosition,
+		&i.ChatLogEnabled,
+		&i.AuxiliaryModels,
+	)
+	return i, err
+}
+
+const updateWorkspaceChatLogEnabled = `-- name: UpdateWorkspaceChatLogEnabled :one
+UPDATE chat_workspace
+SET chat_log_enabled = $2, updated_at = now()
+WHERE uuid = $1
+RETURNING id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position, chat_log_enabled, auxiliary_models
+`
+
+type UpdateWorkspaceChatLogEnabledParams struct {
+	Uuid           string `json:"uuid"`
+	ChatLogEnabled bool   `json:"chatLogEnabled"`
+}
+
+func (q *Queries) UpdateWorkspaceChatLogEnabled(ctx context.Context, arg UpdateWorkspaceChatLogEnabledParams) (ChatWorkspace, error) {
+	row := q.db.QueryRowContext(ctx, updateWorkspaceChatLogEnabled, arg.Uuid, arg.ChatLogEnabled)
+	var i ChatWorkspace
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.UserID,
+		&i.Name,
+		&i.Description,
+		&i.Color,
+		&i.Icon,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.IsDefault,
+		&i.OrderPosition,
+		&i.ChatLogEnabled,
+		&i.AuxiliaryModels,
 	)
 	return i, err
 }
@@ -348,7 +482,7 @@ const updateWorkspaceOrder = `-- name: UpdateWorkspaceOrder :one
 UPDATE chat_workspace 
 SET order_position = $2, updated_at = now()
 WHERE uuid = $1
-RETURNING id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position
+RETURNING id, uuid, user_id, name, description, color, icon, created_at, updated_at, is_default, order_position, chat_log_enabled, auxiliary_models
 `
 
 type UpdateWorkspaceOrderParams struct {
@@ -371,6 +505,8 @@ func (q *Queries) UpdateWorkspaceOrder(ctx context.Context, arg UpdateWorkspaceO
 		&i.UpdatedAt,
 		&i.IsDefault,
 		&i.OrderPosition,
+		&i.ChatLogEnabled,
+		&i.AuxiliaryModels,
 	)
 	return i, err
 }
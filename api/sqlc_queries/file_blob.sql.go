@@ -0,0 +1,289 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: file_blob.sql
+
+package sqlc_queries
+
+import (
+	"context"
+)
+
+const createFileBlob = `-- name: CreateFileBlob :execrows
+INSERT INTO file_blob (hash, size, backend)
+VALUES ($1, $2, $3)
+ON CONFLICT (hash) DO NOTHING
+`
+
+type CreateFileBlobParams struct {
+	Hash    string `json:"hash"`
+	Size    int64  `json:"size"`
+	Backend string `json:"backend"`
+}
+
+func (q *Queries) CreateFileBlob(ctx context.Context, arg CreateFileBlobParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createFileBlob, arg.Hash, arg.Size, arg.Backend)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createFileBlobVariant = `-- name: CreateFileBlobVariant :exec
+INSERT INTO file_blob_variant (source_hash, profile, blob_hash, mime_type)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (source_hash, profile) DO NOTHING
+`
+
+type CreateFileBlobVariantParams struct {
+	SourceHash string `json:"sourceHash"`
+	Profile    string `json:"profile"`
+	BlobHash   string `json:"blobHash"`
+	MimeType   string `json:"mimeType"`
+}
+
+func (q *Queries) CreateFileBlobVariant(ctx context.Context, arg CreateFileBlobVariantParams) error {
+	_, err := q.db.ExecContext(ctx, createFileBlobVariant,
+		arg.SourceHash,
+		arg.Profile,
+		arg.BlobHash,
+		arg.MimeType,
+	)
+	return err
+}
+
+const deleteFileBlobData = `-- name: DeleteFileBlobData :exec
+DELETE FROM file_blob_data
+WHERE hash = $1
+`
+
+func (q *Queries) DeleteFileBlobData(ctx context.Context, hash string) error {
+	_, err := q.db.ExecContext(ctx, deleteFileBlobData, hash)
+	return err
+}
+
+const deleteUnreferencedFileBlob = `-- name: DeleteUnreferencedFileBlob :one
+DELETE FROM file_blob
+WHERE hash = $1
+  AND NOT EXISTS (SELECT 1 FROM chat_file WHERE blob_hash = $1)
+  AND NOT EXISTS (SELECT 1 FROM file_blob_variant WHERE blob_hash = $1)
+  AND NOT EXISTS (SELECT 1 FROM message_speech WHERE blob_hash = $1)
+RETURNING hash, size, backend, created_at
+`
+
+func (q *Queries) DeleteUnreferencedFileBlob(ctx context.Context, hash string) (FileBlob, error) {
+	row := q.db.QueryRowContext(ctx, deleteUnreferencedFileBlob, hash)
+	var i FileBlob
+	err := row.Scan(
+		&i.Hash,
+		&i.Size,
+		&i.Backend,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getFileBlob = `-- name: GetFileBlob :one
+SELECT hash, size, backend, created_at FROM file_blob
+WHERE hash = $1
+`
+
+func (q *Queries) GetFileBlob(ctx context.Context, hash string) (FileBlob, error) {
+	row := q.db.QueryRowContext(ctx, getFileBlob, hash)
+	var i FileBlob
+	err := row.Scan(
+		&i.Hash,
+		&i.Size,
+		&i.Backend,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getFileBlobData = `-- name: GetFileBlobData :one
+SELECT data FROM file_blob_data
+WHERE hash = $1
+`
+
+func (q *Queries) GetFileBlobData(ctx context.Context, hash string) ([]byte, error) {
+	row := q.db.QueryRowContext(ctx, getFileBlobData, hash)
+	var data []byte
+	err := row.Scan(&data)
+	return data, err
+}
+
+const getFileBlobVariant = `-- name: GetFileBlobVariant :one
+SELECT source_hash, profile, blob_hash, mime_type, created_at FROM file_blob_variant
+WHERE source_hash = $1 AND profile = $2
+`
+
+type GetFileBlobVariantParams struct {
+	SourceHash string `json:"sourceHash"`
+	Profile    string `json:"profile"`
+}
+
+func (q *Queries) GetFileBlobVariant(ctx context.Context, arg GetFileBlobVariantParams) (FileBlobVariant, error) {
+	row := q.db.QueryRowContext(ctx, getFileBlobVariant, arg.SourceHash, arg.Profile)
+	var i FileBlobVariant
+	err := row.Scan(
+		&i.SourceHash,
+		&i.Profile,
+		&i.BlobHash,
+		&i.MimeType,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listFileBlobVariantsBySource = `-- name: ListFileBlobVariantsBySource :many
+SELECT source_hash, profile, blob_hash, mime_type, created_at FROM file_blob_variant
+WHERE source_hash = $1
+`
+
+func (q *Queries) ListFileBlobVariantsBySource(ctx context.Context, sourceHash string) ([]FileBlobVariant, error) {
+	rows, err := q.db.QueryContext(ctx, listFileBlobVariantsBySource, sourceHash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FileBlobVariant
+	for rows.Next() {
+		var i FileBlobVariant
+		if err := rows.Scan(
+			&i.SourceHash,
+			&i.Profile,
+			&i.BlobHash,
+			&i.MimeType,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFileBlobsOutsideBackend = `-- name: ListFileBlobsOutsideBackend :many
+SELECT hash, size, backend, created_at FROM file_blob
+WHERE backend <> $1 AND hash > $2
+ORDER BY hash
+LIMIT $3
+`
+
+type ListFileBlobsOutsideBackendParams struct {
+	Backend    string `json:"backend"`
+	AfterHash  string `json:"afterHash"`
+	LimitCount int32  `json:"limitCount"`
+}
+
+func (q *Queries) ListFileBlobsOutsideBackend(ctx context.Context, arg ListFileBlobsOutsideBackendParams) ([]FileBlob, error) {
+	rows, err := q.db.QueryContext(ctx, listFileBlobsOutsideBackend, arg.Backend, arg.AfterHash, arg.LimitCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FileBlob
+	for rows.Next() {
+		var i FileBlob
+		if err := rows.Scan(
+			&i.Hash,
+			&i.Size,
+			&i.Backend,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnreferencedFileBlobs = `-- name: ListUnreferencedFileBlobs :many
+SELECT fb.hash, fb.size, fb.backend, fb.created_at FROM file_blob fb
+WHERE NOT EXISTS (SELECT 1 FROM chat_file cf WHERE cf.blob_hash = fb.hash)
+  AND NOT EXISTS (SELECT 1 FROM file_blob_variant v WHERE v.blob_hash = fb.hash)
+  AND NOT EXISTS (SELECT 1 FROM message_speech s WHERE s.blob_hash = fb.hash)
+ORDER BY fb.hash
+`
+
+func (q *Queries) ListUnreferencedFileBlobs(ctx context.Context) ([]FileBlob, error) {
+	rows, err := q.db.QueryContext(ctx, listUnreferencedFileBlobs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FileBlob
+	for rows.Next() {
+		var i FileBlob
+		if err := rows.Scan(
+			&i.Hash,
+			&i.Size,
+			&i.Backend,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockFileBlob = `-- name: LockFileBlob :exec
+SELECT pg_advisory_xact_lock(hashtextextended('file_blob:' || $1::text, 0))
+`
+
+// Serializes storing and releasing the contents of one hash until the end of
+// the transaction.
+func (q *Queries) LockFileBlob(ctx context.Context, hash string) error {
+	_, err := q.db.ExecContext(ctx, lockFileBlob, hash)
+	return err
+}
+
+const putFileBlobData = `-- name: PutFileBlobData :exec
+INSERT INTO file_blob_data (hash, data)
+VALUES ($1, $2)
+ON CONFLICT (hash) DO NOTHING
+`
+
+type PutFileBlobDataParams struct {
+	Hash string `json:"hash"`
+	Data []byte `json:"data"`
+}
+
+func (q *Queries) PutFileBlobData(ctx context.Context, arg PutFileBlobDataParams) error {
+	_, err := q.db.ExecContext(ctx, putFileBlobData, arg.Hash, arg.Data)
+	return err
+}
+
+const setFileBlobBackend = `-- name: SetFileBlobBackend :exec
+UPDATE file_blob SET backend = $2
+WHERE hash = $1
+`
+
+type SetFileBlobBackendParams struct {
+	Hash    string `json:"hash"`
+	Backend string `json:"backend"`
+}
+
+func (q *Queries) SetFileBlobBackend(ctx context.Context, arg SetFileBlobBackendParams) error {
+	_, err := q.db.ExecContext(ctx, setFileBlobBackend, arg.Hash, arg.Backend)
+	return err
+}
@@ -0,0 +1,235 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: login_guard.sql
+
+package sqlc_queries
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+const bumpLoginThrottle = `-- name: BumpLoginThrottle :one
+INSERT INTO login_throttle (key, failures, last_failure_at)
+VALUES ($1, 1, now())
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+        WHEN login_throttle.last_failure_at < now() - make_interval(secs => $2::INTEGER) THEN 1
+        ELSE login_throttle.failures + 1
+    END,
+    last_failure_at = now()
+RETURNING key, failures, last_failure_at, locked_until
+`
+
+type BumpLoginThrottleParams struct {
+	Key               string `json:"key"`
+	ResetAfterSeconds int32  `json:"resetAfterSeconds"`
+}
+
+// Counts a failure; the count restarts when the previous failure is older than reset_after_seconds.
+func (q *Queries) BumpLoginThrottle(ctx context.Context, arg BumpLoginThrottleParams) (LoginThrottle, error) {
+	row := q.db.QueryRowContext(ctx, bumpLoginThrottle, arg.Key, arg.ResetAfterSeconds)
+	var i LoginThrottle
+	err := row.Scan(
+		&i.Key,
+		&i.Failures,
+		&i.LastFailureAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const countLoginAttempts = `-- name: CountLoginAttempts :one
+SELECT COUNT(*) FROM login_attempt
+WHERE ($1::TEXT = '' OR email = $1::TEXT)
+  AND ($2::TEXT = '' OR ip_address = $2::TEXT)
+`
+
+type CountLoginAttemptsParams struct {
+	Email     string `json:"email"`
+	IpAddress string `json:"ipAddress"`
+}
+
+func (q *Queries) CountLoginAttempts(ctx context.Context, arg CountLoginAttemptsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countLoginAttempts, arg.Email, arg.IpAddress)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createLoginAttempt = `-- name: CreateLoginAttempt :exec
+INSERT INTO login_attempt (email, ip_address, user_agent, reason)
+VALUES ($1, $2, $3, $4)
+`
+
+type CreateLoginAttemptParams struct {
+	Email     string `json:"email"`
+	IpAddress string `json:"ipAddress"`
+	UserAgent string `json:"userAgent"`
+	Reason    string `json:"reason"`
+}
+
+func (q *Queries) CreateLoginAttempt(ctx context.Context, arg CreateLoginAttemptParams) error {
+	_, err := q.db.ExecContext(ctx, createLoginAttempt,
+		arg.Email,
+		arg.IpAddress,
+		arg.UserAgent,
+		arg.Reason,
+	)
+	return err
+}
+
+const deleteLoginThrottle = `-- name: DeleteLoginThrottle :execrows
+DELETE FROM login_throttle
+WHERE key = $1
+`
+
+func (q *Queries) DeleteLoginThrottle(ctx context.Context, key string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteLoginThrottle, key)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getLoginLockSeconds = `-- name: GetLoginLockSeconds :one
+SELECT CEIL(COALESCE(MAX(EXTRACT(EPOCH FROM locked_until - now())), 0))::INTEGER
+FROM login_throttle
+WHERE key = ANY($1::TEXT[]) AND locked_until > now()
+`
+
+// Seconds until the longest lock among keys ends, 0 when none is locked.
+func (q *Queries) GetLoginLockSeconds(ctx context.Context, keys []string) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getLoginLockSeconds, pq.Array(keys))
+	var column_1 int32
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const listLockedLoginThrottles = `-- name: ListLockedLoginThrottles :many
+SELECT key, failures, last_failure_at, locked_until FROM login_throttle
+WHERE locked_until > now()
+ORDER BY locked_until DESC
+`
+
+func (q *Queries) ListLockedLoginThrottles(ctx context.Context) ([]LoginThrottle, error) {
+	rows, err := q.db.QueryContext(ctx, listLockedLoginThrottles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LoginThrottle
+	for rows.Next() {
+		var i LoginThrottle
+		if err := rows.Scan(
+			&i.Key,
+			&i.Failures,
+			&i.LastFailureAt,
+			&i.LockedUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLoginAttempts = `-- name: ListLoginAttempts :many
+SELECT id, email, ip_address, user_agent, reason, created_at FROM login_attempt
+WHERE ($1::TEXT = '' OR email = $1::TEXT)
+  AND ($2::TEXT = '' OR ip_address = $2::TEXT)
+ORDER BY created_at DESC
+LIMIT $4 OFFSET $3
+`
+
+type ListLoginAttemptsParams struct {
+	Email      string `json:"email"`
+	IpAddress  string `json:"ipAddress"`
+	PageOffset int32  `json:"pageOffset"`
+	PageLimit  int32  `json:"pageLimit"`
+}
+
+func (q *Queries) ListLoginAttempts(ctx context.Context, arg ListLoginAttemptsParams) ([]LoginAttempt, error) {
+	rows, err := q.db.QueryContext(ctx, listLoginAttempts,
+		arg.Email,
+		arg.IpAddress,
+		arg.PageOffset,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LoginAttempt
+	for rows.Next() {
+		var i LoginAttempt
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.IpAddress,
+			&i.UserAgent,
+			&i.Reason,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockLoginThrottle = `-- name: LockLoginThrottle :exec
+UPDATE login_throttle SET locked_until = now() + make_interval(secs => $1::INTEGER)
+WHERE key = $2
+`
+
+type LockLoginThrottleParams struct {
+	LockSeconds int32  `json:"lockSeconds"`
+	Key         string `json:"key"`
+}
+
+func (q *Queries) LockLoginThrottle(ctx context.Context, arg LockLoginThrottleParams) error {
+	_, err := q.db.ExecContext(ctx, lockLoginThrottle, arg.LockSeconds, arg.Key)
+	return err
+}
+
+const purgeLoginAttempts = `-- name: PurgeLoginAttempts :execrows
+DELETE FROM login_attempt
+WHERE created_at < now() - interval '90 days'
+`
+
+func (q *Queries) PurgeLoginAttempts(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeLoginAttempts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const purgeLoginThrottles = `-- name: PurgeLoginThrottles :execrows
+DELETE FROM login_throttle
+WHERE last_failure_at < now() - interval '1 day'
+  AND (locked_until IS NULL OR locked_until < now())
+`
+
+func (q *Queries) PurgeLoginThrottles(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeLoginThrottles)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
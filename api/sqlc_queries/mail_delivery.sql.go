@@ -0,0 +1,140 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: mail_delivery.sql
+
+package sqlc_queries
+
+import (
+	"context"
+	"database/sql"
+)
+
+const countMailDeliveries = `-- name: CountMailDeliveries :one
+SELECT COUNT(*) FROM mail_delivery
+WHERE ($1::TEXT = '' OR status = $1::TEXT)
+  AND ($2::TEXT = '' OR to_address = $2::TEXT)
+`
+
+type CountMailDeliveriesParams struct {
+	Status    string `json:"status"`
+	ToAddress string `json:"toAddress"`
+}
+
+func (q *Queries) CountMailDeliveries(ctx context.Context, arg CountMailDeliveriesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countMailDeliveries, arg.Status, arg.ToAddress)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createMailDelivery = `-- name: CreateMailDelivery :one
+INSERT INTO mail_delivery (user_id, to_address, template, subject, driver)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, to_address, template, subject, driver, status, error, created_at, sent_at
+`
+
+type CreateMailDeliveryParams struct {
+	UserID    sql.NullInt32 `json:"userId"`
+	ToAddress string        `json:"toAddress"`
+	Template  string        `json:"template"`
+	Subject   string        `json:"subject"`
+	Driver    string        `json:"driver"`
+}
+
+func (q *Queries) CreateMailDelivery(ctx context.Context, arg CreateMailDeliveryParams) (MailDelivery, error) {
+	row := q.db.QueryRowContext(ctx, createMailDelivery,
+		arg.UserID,
+		arg.ToAddress,
+		arg.Template,
+		arg.Subject,
+		arg.Driver,
+	)
+	var i MailDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ToAddress,
+		&i.Template,
+		&i.Subject,
+		&i.Driver,
+		&i.Status,
+		&i.Error,
+		&i.CreatedAt,
+		&i.SentAt,
+	)
+	return i, err
+}
+
+const listMailDeliveries = `-- name: ListMailDeliveries :many
+SELECT id, user_id, to_address, template, subject, driver, status, error, created_at, sent_at FROM mail_delivery
+WHERE ($1::TEXT = '' OR status = $1::TEXT)
+  AND ($2::TEXT = '' OR to_address = $2::TEXT)
+ORDER BY created_at DESC
+LIMIT $4 OFFSET $3
+`
+
+type ListMailDeliveriesParams struct {
+	Status     string `json:"status"`
+	ToAddress  string `json:"toAddress"`
+	PageOffset int32  `json:"pageOffset"`
+	PageLimit  int32  `json:"pageLimit"`
+}
+
+func (q *Queries) ListMailDeliveries(ctx context.Context, arg ListMailDeliveriesParams) ([]MailDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, listMailDeliveries,
+		arg.Status,
+		arg.ToAddress,
+		arg.PageOffset,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MailDelivery
+	for rows.Next() {
+		var i MailDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ToAddress,
+			&i.Template,
+			&i.Subject,
+			&i.Driver,
+			&i.Status,
+			&i.Error,
+			&i.CreatedAt,
+			&i.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateMailDeliveryStatus = `-- name: UpdateMailDeliveryStatus :exec
+UPDATE mail_delivery
+SET status = $2,
+    error = $3,
+    sent_at = CASE WHEN $2 = 'sent' THEN now() ELSE sent_at END
+WHERE id = $1
+`
+
+type UpdateMailDeliveryStatusParams struct {
+	ID     int32  `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+func (q *Queries) UpdateMailDeliveryStatus(ctx context.Context, arg UpdateMailDeliveryStatusParams) error {
+	_, err := q.db.ExecContext(ctx, updateMailDeliveryStatus, arg.ID, arg.Status, arg.Error)
+	return err
+}
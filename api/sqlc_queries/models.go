@@ -10,18 +10,64 @@ import (
 	"time"
 )
 
+type AppSetting struct {
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+type AuditEvent struct {
+	ID          int64           `json:"id"`
+	ActorUserID sql.NullInt32   `json:"actorUserId"`
+	ActorEmail  string          `json:"actorEmail"`
+	Action      string          `json:"action"`
+	TargetType  string          `json:"targetType"`
+	TargetID    string          `json:"targetId"`
+	Changes     json.RawMessage `json:"changes"`
+	IpAddress   string          `json:"ipAddress"`
+	RequestID   string          `json:"requestId"`
+	CreatedAt   time.Time       `json:"createdAt"`
+}
+
+type AuthSession struct {
+	ID            int32        `json:"id"`
+	Uuid          string       `json:"uuid"`
+	UserID        int32        `json:"userId"`
+	TokenHash     string       `json:"tokenHash"`
+	PreviousHash  string       `json:"previousHash"`
+	RotatedAt     sql.NullTime `json:"rotatedAt"`
+	UserAgent     string       `json:"userAgent"`
+	IpAddress     string       `json:"ipAddress"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	LastUsedAt    time.Time    `json:"lastUsedAt"`
+	ExpiresAt     time.Time    `json:"expiresAt"`
+	RevokedAt     sql.NullTime `json:"revokedAt"`
+	RevokedReason string       `json:"revokedReason"`
+}
+
 type AuthUser struct {
+	ID            int32     `json:"id"`
+	Password      string    `json:"password"`
+	LastLogin     time.Time `json:"lastLogin"`
+	IsSuperuser   bool      `json:"isSuperuser"`
+	Username      string    `json:"username"`
+	FirstName     string    `json:"firstName"`
+	LastName      string    `json:"lastName"`
+	Email         string    `json:"email"`
+	IsStaff       bool      `json:"isStaff"`
+	IsActive      bool      `json:"isActive"`
+	DateJoined    time.Time `json:"dateJoined"`
+	EmailVerified bool      `json:"emailVerified"`
+}
+
+type AuthUserIdentity struct {
 	ID          int32     `json:"id"`
-	Password    string    `json:"password"`
-	LastLogin   time.Time `json:"lastLogin"`
-	IsSuperuser bool      `json:"isSuperuser"`
-	Username    string    `json:"username"`
-	FirstName   string    `json:"firstName"`
-	LastName    string    `json:"lastName"`
+	UserID      int32     `json:"userId"`
+	Issuer      string    `json:"issuer"`
+	Subject     string    `json:"subject"`
 	Email       string    `json:"email"`
-	IsStaff     bool      `json:"isStaff"`
-	IsActive    bool      `json:"isActive"`
-	DateJoined  time.Time `json:"dateJoined"`
+	CreatedAt   time.Time `json:"createdAt"`
+	LastLoginAt time.Time `json:"lastLoginAt"`
 }
 
 type AuthUserManagement struct {
@@ -32,6 +78,33 @@ type AuthUserManagement struct {
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
+type AuthUserRecoveryCode struct {
+	ID        int32        `json:"id"`
+	UserID    int32        `json:"userId"`
+	CodeHash  string       `json:"codeHash"`
+	UsedAt    sql.NullTime `json:"usedAt"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+type AuthUserToken struct {
+	ID        int32        `json:"id"`
+	UserID    int32        `json:"userId"`
+	Purpose   string       `json:"purpose"`
+	TokenHash string       `json:"tokenHash"`
+	ExpiresAt time.Time    `json:"expiresAt"`
+	UsedAt    sql.NullTime `json:"usedAt"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+type AuthUserTotp struct {
+	UserID      int32        `json:"userId"`
+	Secret      string       `json:"secret"`
+	Enabled     bool         `json:"enabled"`
+	LastStep    int64        `json:"lastStep"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	ConfirmedAt sql.NullTime `json:"confirmedAt"`
+}
+
 type BotAnswerHistory struct {
 	ID         int32     `json:"id"`
 	BotUuid    string    `json:"botUuid"`
@@ -42,6 +115,42 @@ type BotAnswerHistory struct {
 	TokensUsed int32     `json:"tokensUsed"`
 	CreatedAt  time.Time `json:"createdAt"`
 	UpdatedAt  time.Time `json:"updatedAt"`
+	VisitorID  string    `json:"visitorId"`
+	BotVersion int32     `json:"botVersion"`
+}
+
+type ChatBotFile struct {
+	ID        int32     `json:"id"`
+	BotUuid   string    `json:"botUuid"`
+	UserID    int32     `json:"userId"`
+	Name      string    `json:"name"`
+	MimeType  string    `json:"mimeType"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ChatBotPublication struct {
+	ID             int32     `json:"id"`
+	BotUuid        string    `json:"botUuid"`
+	UserID         int32     `json:"userId"`
+	AccessMode     string    `json:"accessMode"`
+	TokenHash      string    `json:"tokenHash"`
+	RateLimit      int32     `json:"rateLimit"`
+	AllowedOrigins []string  `json:"allowedOrigins"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+type ChatBotVersion struct {
+	ID           int32           `json:"id"`
+	BotUuid      string          `json:"botUuid"`
+	Version      int32           `json:"version"`
+	Model        string          `json:"model"`
+	Conversation json.RawMessage `json:"conversation"`
+	FileIds      []int32         `json:"fileIds"`
+	Note         string          `json:"note"`
+	CreatedBy    int32           `json:"createdBy"`
+	CreatedAt    time.Time       `json:"createdAt"`
 }
 
 type ChatComment struct {
@@ -57,13 +166,18 @@ type ChatComment struct {
 }
 
 type ChatFile struct {
-	ID              int32     `json:"id"`
-	Name            string    `json:"name"`
-	Data            []byte    `json:"data"`
-	CreatedAt       time.Time `json:"createdAt"`
-	UserID          int32     `json:"userId"`
-	ChatSessionUuid string    `json:"chatSessionUuid"`
-	MimeType        string    `json:"mimeType"`
+	ID              int32          `json:"id"`
+	Name            string         `json:"name"`
+	Data            []byte         `json:"data"`
+	CreatedAt       time.Time      `json:"createdAt"`
+	UserID          int32          `json:"userId"`
+	ChatSessionUuid string         `json:"chatSessionUuid"`
+	MimeType        string         `json:"mimeType"`
+	BlobHash        sql.NullString `json:"blobHash"`
+	Size            int64          `json:"size"`
+	ChatMessageUuid sql.NullString `json:"chatMessageUuid"`
+	Detached        bool           `json:"detached"`
+	Source          string         `json:"source"`
 }
 
 type ChatLog struct {
@@ -98,21 +212,22 @@ type ChatMessage struct {
 }
 
 type ChatModel struct {
-	ID                     int32  `json:"id"`
-	Name                   string `json:"name"`
-	Label                  string `json:"label"`
-	IsDefault              bool   `json:"isDefault"`
-	Url                    string `json:"url"`
-	ApiAuthHeader          string `json:"apiAuthHeader"`
-	ApiAuthKey             string `json:"apiAuthKey"`
-	UserID                 int32  `json:"userId"`
-	EnablePerModeRatelimit bool   `json:"enablePerModeRatelimit"`
-	MaxToken               int32  `json:"maxToken"`
-	DefaultToken           int32  `json:"defaultToken"`
-	OrderNumber            int32  `json:"orderNumber"`
-	HttpTimeOut            int32  `json:"httpTimeOut"`
-	IsEnable               bool   `json:"isEnable"`
-	ApiType                string `json:"apiType"`
+	ID                     int32         `json:"id"`
+	Name                   string        `json:"name"`
+	Label                  string        `json:"label"`
+	IsDefault              bool          `json:"isDefault"`
+	Url                    string        `json:"url"`
+	ApiAuthHeader          string        `json:"apiAuthHeader"`
+	ApiAuthKey             string        `json:"apiAuthKey"`
+	UserID                 int32         `json:"userId"`
+	EnablePerModeRatelimit bool          `json:"enablePerModeRatelimit"`
+	MaxToken               int32         `json:"maxToken"`
+	DefaultToken           int32         `json:"defaultToken"`
+	OrderNumber            int32         `json:"orderNumber"`
+	HttpTimeOut            int32         `json:"httpTimeOut"`
+	IsEnable               bool          `json:"isEnable"`
+	ApiType                string        `json:"apiType"`
+	CredentialID           sql.NullInt32 `json:"credentialId"`
 }
 
 type ChatPrompt struct {
@@ -166,20 +281,43 @@ type ChatSnapshot struct {
 	CreatedAt    time.Time       `json:"createdAt"`
 	Text         string          `json:"text"`
 	SearchVector interface{}     `json:"searchVector"`
+	BotVersion   int32           `json:"botVersion"`
 }
 
 type ChatWorkspace struct {
-	ID            int32     `json:"id"`
-	Uuid          string    `json:"uuid"`
-	UserID        int32     `json:"userId"`
-	Name          string    `json:"name"`
-	Description   string    `json:"description"`
-	Color         string    `json:"color"`
-	Icon          string    `json:"icon"`
-	CreatedAt     time.Time `json:"createdAt"`
-	UpdatedAt     time.Time `json:"updatedAt"`
-	IsDefault     bool      `json:"isDefault"`
-	OrderPosition int32     `json:"orderPosition"`
+	ID              int32           `json:"id"`
+	Uuid            string          `json:"uuid"`
+	UserID          int32           `json:"userId"`
+	Name            string          `json:"name"`
+	Description     string          `json:"description"`
+	Color           string          `json:"color"`
+	Icon            string          `json:"icon"`
+	CreatedAt       time.Time       `json:"createdAt"`
+	UpdatedAt       time.Time       `json:"updatedAt"`
+	IsDefault       bool            `json:"isDefault"`
+	OrderPosition   int32           `json:"orderPosition"`
+	ChatLogEnabled  bool            `json:"chatLogEnabled"`
+	AuxiliaryModels json.RawMessage `json:"auxiliaryModels"`
+}
+
+type FileBlob struct {
+	Hash      string    `json:"hash"`
+	Size      int64     `json:"size"`
+	Backend   string    `json:"backend"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type FileBlobDatum struct {
+	Hash string `json:"hash"`
+	Data []byte `json:"data"`
+}
+
+type FileBlobVariant struct {
+	SourceHash string    `json:"sourceHash"`
+	Profile    string    `json:"profile"`
+	BlobHash   string    `json:"blobHash"`
+	MimeType   string    `json:"mimeType"`
+	CreatedAt  time.Time `json:"createdAt"`
 }
 
 type JwtSecret struct {
@@ -190,6 +328,139 @@ type JwtSecret struct {
 	Lifetime int16  `json:"lifetime"`
 }
 
+type LoginAttempt struct {
+	ID        int32     `json:"id"`
+	Email     string    `json:"email"`
+	IpAddress string    `json:"ipAddress"`
+	UserAgent string    `json:"userAgent"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type LoginThrottle struct {
+	Key           string       `json:"key"`
+	Failures      int32        `json:"failures"`
+	LastFailureAt time.Time    `json:"lastFailureAt"`
+	LockedUntil   sql.NullTime `json:"lockedUntil"`
+}
+
+type MailDelivery struct {
+	ID        int32         `json:"id"`
+	UserID    sql.NullInt32 `json:"userId"`
+	ToAddress string        `json:"toAddress"`
+	Template  string        `json:"template"`
+	Subject   string        `json:"subject"`
+	Driver    string        `json:"driver"`
+	Status    string        `json:"status"`
+	Error     string        `json:"error"`
+	CreatedAt time.Time     `json:"createdAt"`
+	SentAt    sql.NullTime  `json:"sentAt"`
+}
+
+type MessageSpeech struct {
+	MessageUuid string    `json:"messageUuid"`
+	VoiceKey    string    `json:"voiceKey"`
+	ContentHash string    `json:"contentHash"`
+	BlobHash    string    `json:"blobHash"`
+	MimeType    string    `json:"mimeType"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type ModelUsage struct {
+	ID          int64         `json:"id"`
+	UserID      int32         `json:"userId"`
+	WorkspaceID sql.NullInt32 `json:"workspaceId"`
+	Model       string        `json:"model"`
+	Source      string        `json:"source"`
+	Tokens      int32         `json:"tokens"`
+	CreatedAt   time.Time     `json:"createdAt"`
+}
+
+type OidcLoginState struct {
+	State        string        `json:"state"`
+	Nonce        string        `json:"nonce"`
+	CodeVerifier string        `json:"codeVerifier"`
+	RedirectTo   string        `json:"redirectTo"`
+	LinkUserID   sql.NullInt32 `json:"linkUserId"`
+	CreatedAt    time.Time     `json:"createdAt"`
+}
+
+type PromptTemplate struct {
+	ID             int32         `json:"id"`
+	Uuid           string        `json:"uuid"`
+	UserID         int32         `json:"userId"`
+	WorkspaceID    sql.NullInt32 `json:"workspaceId"`
+	Title          string        `json:"title"`
+	Description    string        `json:"description"`
+	Tags           []string      `json:"tags"`
+	Visibility     string        `json:"visibility"`
+	CurrentVersion int32         `json:"currentVersion"`
+	UsageCount     int32         `json:"usageCount"`
+	LastUsedAt     sql.NullTime  `json:"lastUsedAt"`
+	IsDeleted      bool          `json:"isDeleted"`
+	CreatedAt      time.Time     `json:"createdAt"`
+	UpdatedAt      time.Time     `json:"updatedAt"`
+}
+
+type PromptTemplateShare struct {
+	ID         int32     `json:"id"`
+	TemplateID int32     `json:"templateId"`
+	UserID     int32     `json:"userId"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type PromptTemplateVersion struct {
+	ID         int32     `json:"id"`
+	TemplateID int32     `json:"templateId"`
+	Version    int32     `json:"version"`
+	Content    string    `json:"content"`
+	Model      string    `json:"model"`
+	CreatedBy  int32     `json:"createdBy"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type ProviderCredential struct {
+	ID          int32         `json:"id"`
+	Name        string        `json:"name"`
+	WorkspaceID sql.NullInt32 `json:"workspaceId"`
+	MasterKeyID string        `json:"masterKeyId"`
+	WrappedKey  []byte        `json:"wrappedKey"`
+	Ciphertext  []byte        `json:"ciphertext"`
+	Hint        string        `json:"hint"`
+	CreatedBy   int32         `json:"createdBy"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	UpdatedAt   time.Time     `json:"updatedAt"`
+	RotatedAt   sql.NullTime  `json:"rotatedAt"`
+}
+
+type QuotaPolicy struct {
+	ID          int32         `json:"id"`
+	Name        string        `json:"name"`
+	UserID      sql.NullInt32 `json:"userId"`
+	WorkspaceID sql.NullInt32 `json:"workspaceId"`
+	ChatModelID sql.NullInt32 `json:"chatModelId"`
+	TimeWindow  string        `json:"timeWindow"`
+	Metric      string        `json:"metric"`
+	QuotaLimit  int64         `json:"quotaLimit"`
+	Enabled     bool          `json:"enabled"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	UpdatedAt   time.Time     `json:"updatedAt"`
+}
+
+type SlashCommand struct {
+	ID          int32           `json:"id"`
+	Uuid        string          `json:"uuid"`
+	UserID      int32           `json:"userId"`
+	WorkspaceID sql.NullInt32   `json:"workspaceId"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Template    string          `json:"template"`
+	Model       string          `json:"model"`
+	Temperature sql.NullFloat64 `json:"temperature"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
+
 type UserActiveChatSession struct {
 	ID              int32         `json:"id"`
 	UserID          int32         `json:"userId"`
@@ -209,3 +480,17 @@ type UserChatModelPrivilege struct {
 	CreatedBy   int32     `json:"createdBy"`
 	UpdatedBy   int32     `json:"updatedBy"`
 }
+
+type UserSpeechSetting struct {
+	UserID    int32     `json:"userId"`
+	Voice     string    `json:"voice"`
+	Speed     float64   `json:"speed"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type WorkspaceModelCredential struct {
+	WorkspaceID  int32     `json:"workspaceId"`
+	ChatModelID  int32     `json:"chatModelId"`
+	CredentialID int32     `json:"credentialId"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
@@ -0,0 +1,463 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: prompt_template.sql
+
+package sqlc_queries
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const createPromptTemplate = `-- name: CreatePromptTemplate :one
+INSERT INTO prompt_template (uuid, user_id, workspace_id, title, description, tags, visibility)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, uuid, user_id, workspace_id, title, description, tags, visibility, current_version, usage_count, last_used_at, is_deleted, created_at, updated_at
+`
+
+type CreatePromptTemplateParams struct {
+	Uuid        string        `json:"uuid"`
+	UserID      int32         `json:"userId"`
+	WorkspaceID sql.NullInt32 `json:"workspaceId"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	Tags        []string      `json:"tags"`
+	Visibility  string        `json:"visibility"`
+}
+
+func (q *Queries) CreatePromptTemplate(ctx context.Context, arg CreatePromptTemplateParams) (PromptTemplate, error) {
+	row := q.db.QueryRowContext(ctx, createPromptTemplate,
+		arg.Uuid,
+		arg.UserID,
+		arg.WorkspaceID,
+		arg.Title,
+		arg.Description,
+		pq.Array(arg.Tags),
+		arg.Visibility,
+	)
+	var i PromptTemplate
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.UserID,
+		&i.WorkspaceID,
+		&i.Title,
+		&i.Description,
+		pq.Array(&i.Tags),
+		&i.Visibility,
+		&i.CurrentVersion,
+		&i.UsageCount,
+		&i.LastUsedAt,
+		&i.IsDeleted,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createPromptTemplateVersion = `-- name: CreatePromptTemplateVersion :one
+INSERT INTO prompt_template_version (template_id, version, content, model, created_by)
+SELECT $1::INTEGER, COALESCE(MAX(version), 0) + 1, $2::TEXT, $3::TEXT, $4::INTEGER
+FROM prompt_template_version
+WHERE template_id = $1::INTEGER
+RETURNING id, template_id, version, content, model, created_by, created_at
+`
+
+type CreatePromptTemplateVersionParams struct {
+	TemplateID int32  `json:"templateId"`
+	Content    string `json:"content"`
+	Model      string `json:"model"`
+	CreatedBy  int32  `json:"createdBy"`
+}
+
+func (q *Queries) CreatePromptTemplateVersion(ctx context.Context, arg CreatePromptTemplateVersionParams) (PromptTemplateVersion, error) {
+	row := q.db.QueryRowContext(ctx, createPromptTemplateVersion,
+		arg.TemplateID,
+		arg.Content,
+		arg.Model,
+		arg.CreatedBy,
+	)
+	var i PromptTemplateVersion
+	err := row.Scan(
+		&i.ID,
+		&i.TemplateID,
+		&i.Version,
+		&i.Content,
+		&i.Model,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deletePromptTemplate = `-- name: DeletePromptTemplate :exec
+UPDATE prompt_template
+SET is_deleted = true, updated_at = now()
+WHERE uuid = $1
+`
+
+func (q *Queries) DeletePromptTemplate(ctx context.Context, uuid string) error {
+	_, err := q.db.ExecContext(ctx, deletePromptTemplate, uuid)
+	return err
+}
+
+const getPromptTemplateByUUID = `-- name: GetPromptTemplateByUUID :one
+SELECT id, uuid, user_id, workspace_id, title, description, tags, visibility, current_version, usage_count, last_used_at, is_deleted, created_at, updated_at FROM prompt_template
+WHERE uuid = $1 AND is_deleted = false
+`
+
+func (q *Queries) GetPromptTemplateByUUID(ctx context.Context, uuid string) (PromptTemplate, error) {
+	row := q.db.QueryRowContext(ctx, getPromptTemplateByUUID, uuid)
+	var i PromptTemplate
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.UserID,
+		&i.WorkspaceID,
+		&i.Title,
+		&i.Description,
+		pq.Array(&i.Tags),
+		&i.Visibility,
+		&i.CurrentVersion,
+		&i.UsageCount,
+		&i.LastUsedAt,
+		&i.IsDeleted,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getPromptTemplateVersion = `-- name: GetPromptTemplateVersion :one
+SELECT id, template_id, version, content, model, created_by, created_at FROM prompt_template_version
+WHERE template_id = $1 AND version = $2
+`
+
+type GetPromptTemplateVersionParams struct {
+	TemplateID int32 `json:"templateId"`
+	Version    int32 `json:"version"`
+}
+
+func (q *Queries) GetPromptTemplateVersion(ctx context.Context, arg GetPromptTemplateVersionParams) (PromptTemplateVersion, error) {
+	row := q.db.QueryRowContext(ctx, getPromptTemplateVersion, arg.TemplateID, arg.Version)
+	var i PromptTemplateVersion
+	err := row.Scan(
+		&i.ID,
+		&i.TemplateID,
+		&i.Version,
+		&i.Content,
+		&i.Model,
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const hasPromptTemplateAccess = `-- name: HasPromptTemplateAccess :one
+SELECT EXISTS (
+    SELECT 1 FROM prompt_template t
+    WHERE t.uuid = $1 AND t.is_deleted = false
+      AND (t.user_id = $2::INTEGER
+           OR t.visibility = 'public'
+           OR EXISTS (SELECT 1 FROM prompt_template_share s WHERE s.template_id = t.id AND s.user_id = $2::INTEGER))
+) AS has_access
+`
+
+type HasPromptTemplateAccessParams struct {
+	Uuid   string `json:"uuid"`
+	UserID int32  `json:"userId"`
+}
+
+func (q *Queries) HasPromptTemplateAccess(ctx context.Context, arg HasPromptTemplateAccessParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, hasPromptTemplateAccess, arg.Uuid, arg.UserID)
+	var has_access bool
+	err := row.Scan(&has_access)
+	return has_access, err
+}
+
+const incrementPromptTemplateUsage = `-- name: IncrementPromptTemplateUsage :exec
+UPDATE prompt_template
+SET usage_count = usage_count + 1, last_used_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) IncrementPromptTemplateUsage(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, incrementPromptTemplateUsage, id)
+	return err
+}
+
+const listAccessiblePromptTemplates = `-- name: ListAccessiblePromptTemplates :many
+SELECT t.id, t.uuid, t.user_id, t.workspace_id, t.title, t.description, t.tags, t.visibility, t.current_version, t.usage_count, t.last_used_at, t.is_deleted, t.created_at, t.updated_at, v.content, v.model, COALESCE(w.uuid, '')::TEXT AS workspace_uuid
+FROM prompt_template t
+JOIN prompt_template_version v ON v.template_id = t.id AND v.version = t.current_version
+LEFT JOIN chat_workspace w ON w.id = t.workspace_id
+WHERE t.is_deleted = false
+  AND (t.user_id = $1::INTEGER
+       OR t.visibility = 'public'
+       OR EXISTS (SELECT 1 FROM prompt_template_share s WHERE s.template_id = t.id AND s.user_id = $1::INTEGER))
+  AND ($2::INTEGER = 0 OR t.workspace_id = $2::INTEGER)
+  AND ($3::TEXT = '' OR $3::TEXT = ANY(t.tags))
+  AND ($4::TEXT = '' OR t.title ILIKE '%' || $4::TEXT || '%' OR t.description ILIKE '%' || $4::TEXT || '%')
+ORDER BY t.usage_count DESC, t.updated_at DESC
+`
+
+type ListAccessiblePromptTemplatesParams struct {
+	UserID      int32  `json:"userId"`
+	WorkspaceID int32  `json:"workspaceId"`
+	Tag         string `json:"tag"`
+	Keyword     string `json:"keyword"`
+}
+
+type ListAccessiblePromptTemplatesRow struct {
+	ID             int32         `json:"id"`
+	Uuid           string        `json:"uuid"`
+	UserID         int32         `json:"userId"`
+	WorkspaceID    sql.NullInt32 `json:"workspaceId"`
+	Title          string        `json:"title"`
+	Description    string        `json:"description"`
+	Tags           []string      `json:"tags"`
+	Visibility     string        `json:"visibility"`
+	CurrentVersion int32         `json:"currentVersion"`
+	UsageCount     int32         `json:"usageCount"`
+	LastUsedAt     sql.NullTime  `json:"lastUsedAt"`
+	IsDeleted      bool          `json:"isDeleted"`
+	CreatedAt      time.Time     `json:"createdAt"`
+	UpdatedAt      time.Time     `json:"updatedAt"`
+	Content        string        `json:"content"`
+	Model          string        `json:"model"`
+	WorkspaceUuid  string        `json:"workspaceUuid"`
+}
+
+func (q *Queries) ListAccessiblePromptTemplates(ctx context.Context, arg ListAccessiblePromptTemplatesParams) ([]ListAccessiblePromptTemplatesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAccessiblePromptTemplates,
+		arg.UserID,
+		arg.WorkspaceID,
+		arg.Tag,
+		arg.Keyword,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAccessiblePromptTemplatesRow
+	for rows.Next() {
+		var i ListAccessiblePromptTemplatesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Uuid,
+			&i.UserID,
+			&i.WorkspaceID,
+			&i.Title,
+			&i.Description,
+			pq.Array(&i.Tags),
+			&i.Visibility,
+			&i.CurrentVersion,
+			&i.UsageCount,
+			&i.LastUsedAt,
+			&i.IsDeleted,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Content,
+			&i.Model,
+			&i.WorkspaceUuid,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPromptTemplateShares = `-- name: ListPromptTemplateShares :many
+SELECT s.user_id, u.email, s.created_at
+FROM prompt_template_share s
+JOIN auth_user u ON u.id = s.user_id
+WHERE s.template_id = $1
+ORDER BY s.created_at
+`
+
+type ListPromptTemplateSharesRow struct {
+	UserID    int32     `json:"userId"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (q *Queries) ListPromptTemplateShares(ctx context.Context, templateID int32) ([]ListPromptTemplateSharesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPromptTemplateShares, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPromptTemplateSharesRow
+	for rows.Next() {
+		var i ListPromptTemplateSharesRow
+		if err := rows.Scan(&i.UserID, &i.Email, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPromptTemplateVersions = `-- name: ListPromptTemplateVersions :many
+SELECT id, template_id, version, content, model, created_by, created_at FROM prompt_template_version
+WHERE template_id = $1
+ORDER BY version DESC
+`
+
+func (q *Queries) ListPromptTemplateVersions(ctx context.Context, templateID int32) ([]PromptTemplateVersion, error) {
+	rows, err := q.db.QueryContext(ctx, listPromptTemplateVersions, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PromptTemplateVersion
+	for rows.Next() {
+		var i PromptTemplateVersion
+		if err := rows.Scan(
+			&i.ID,
+			&i.TemplateID,
+			&i.Version,
+			&i.Content,
+			&i.Model,
+			&i.CreatedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setPromptTemplateCurrentVersion = `-- name: SetPromptTemplateCurrentVersion :one
+UPDATE prompt_template
+SET current_version = $2, updated_at = now()
+WHERE id = $1
+RETURNING id, uuid, user_id, workspace_id, title, description, tags, visibility, current_version, usage_count, last_used_at, is_deleted, created_at, updated_at
+`
+
+type SetPromptTemplateCurrentVersionParams struct {
+	ID             int32 `json:"id"`
+	CurrentVersion int32 `json:"currentVersion"`
+}
+
+func (q *Queries) SetPromptTemplateCurrentVersion(ctx context.Context, arg SetPromptTemplateCurrentVersionParams) (PromptTemplate, error) {
+	row := q.db.QueryRowContext(ctx, setPromptTemplateCurrentVersion, arg.ID, arg.CurrentVersion)
+	var i PromptTemplate
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.UserID,
+		&i.WorkspaceID,
+		&i.Title,
+		&i.Description,
+		pq.Array(&i.Tags),
+		&i.Visibility,
+		&i.CurrentVersion,
+		&i.UsageCount,
+		&i.LastUsedAt,
+		&i.IsDeleted,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const sharePromptTemplate = `-- name: SharePromptTemplate :exec
+INSERT INTO prompt_template_share (template_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT (template_id, user_id) DO NOTHING
+`
+
+type SharePromptTemplateParams struct {
+	TemplateID int32 `json:"templateId"`
+	UserID     int32 `json:"userId"`
+}
+
+func (q *Queries) SharePromptTemplate(ctx context.Context, arg SharePromptTemplateParams) error {
+	_, err := q.db.ExecContext(ctx, sharePromptTemplate, arg.TemplateID, arg.UserID)
+	return err
+}
+
+const unsharePromptTemplate = `-- name: UnsharePromptTemplate :exec
+DELETE FROM prompt_template_share
+WHERE template_id = $1 AND user_id = $2
+`
+
+type UnsharePromptTemplateParams struct {
+	TemplateID int32 `json:"templateId"`
+	UserID     int32 `json:"userId"`
+}
+
+func (q *Queries) UnsharePromptTemplate(ctx context.Context, arg UnsharePromptTemplateParams) error {
+	_, err := q.db.ExecContext(ctx, unsharePromptTemplate, arg.TemplateID, arg.UserID)
+	return err
+}
+
+const updatePromptTemplate = `-- name: UpdatePromptTemplate :one
+UPDATE prompt_template
+SET title = $2, description = $3, tags = $4, visibility = $5, workspace_id = $6, updated_at = now()
+WHERE uuid = $1 AND is_deleted = false
+RETURNING id, uuid, user_id, workspace_id, title, description, tags, visibility, current_version, usage_count, last_used_at, is_deleted, created_at, updated_at
+`
+
+type UpdatePromptTemplateParams struct {
+	Uuid        string        `json:"uuid"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	Tags        []string      `json:"tags"`
+	Visibility  string        `json:"visibility"`
+	WorkspaceID sql.NullInt32 `json:"workspaceId"`
+}
+
+func (q *Queries) UpdatePromptTemplate(ctx context.Context, arg UpdatePromptTemplateParams) (PromptTemplate, error) {
+	row := q.db.QueryRowContext(ctx, updatePromptTemplate,
+		arg.Uuid,
+		arg.Title,
+		arg.Description,
+		pq.Array(arg.Tags),
+		arg.Visibility,
+		arg.WorkspaceID,
+	)
+	var i PromptTemplate
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.UserID,
+		&i.WorkspaceID,
+		&i.Title,
+		&i.Description,
+		pq.Array(&i.Tags),
+		&i.Visibility,
+		&i.CurrentVersion,
+		&i.UsageCount,
+		&i.LastUsedAt,
+		&i.IsDeleted,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
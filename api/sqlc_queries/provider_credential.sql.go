@@ -0,0 +1,408 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: provider_credential.sql
+
+package sqlc_queries
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createProviderCredential = `-- name: CreateProviderCredential :one
+INSERT INTO provider_credential (name, workspace_id, master_key_id, wrapped_key, ciphertext, hint, created_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, name, workspace_id, master_key_id, wrapped_key, ciphertext, hint, created_by, created_at, updated_at, rotated_at
+`
+
+type CreateProviderCredentialParams struct {
+	Name        string        `json:"name"`
+	WorkspaceID sql.NullInt32 `json:"workspaceId"`
+	MasterKeyID string        `json:"masterKeyId"`
+	WrappedKey  []byte        `json:"wrappedKey"`
+	Ciphertext  []byte        `json:"ciphertext"`
+	Hint        string        `json:"hint"`
+	CreatedBy   int32         `json:"createdBy"`
+}
+
+func (q *Queries) CreateProviderCredential(ctx context.Context, arg CreateProviderCredentialParams) (ProviderCredential, error) {
+	row := q.db.QueryRowContext(ctx, createProviderCredential,
+		arg.Name,
+		arg.WorkspaceID,
+		arg.MasterKeyID,
+		arg.WrappedKey,
+		arg.Ciphertext,
+		arg.Hint,
+		arg.CreatedBy,
+	)
+	var i ProviderCredential
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.WorkspaceID,
+		&i.MasterKeyID,
+		&i.WrappedKey,
+		&i.Ciphertext,
+		&i.Hint,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.RotatedAt,
+	)
+	return i, err
+}
+
+const deleteProviderCredential = `-- name: DeleteProviderCredential :execrows
+DELETE FROM provider_credential
+WHERE id = $1
+`
+
+func (q *Queries) DeleteProviderCredential(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteProviderCredential, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteWorkspaceModelCredential = `-- name: DeleteWorkspaceModelCredential :execrows
+DELETE FROM workspace_model_credential
+WHERE workspace_id = $1 AND chat_model_id = $2
+`
+
+type DeleteWorkspaceModelCredentialParams struct {
+	WorkspaceID int32 `json:"workspaceId"`
+	ChatModelID int32 `json:"chatModelId"`
+}
+
+func (q *Queries) DeleteWorkspaceModelCredential(ctx context.Context, arg DeleteWorkspaceModelCredentialParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteWorkspaceModelCredential, arg.WorkspaceID, arg.ChatModelID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getModelCredential = `-- name: GetModelCredential :one
+SELECT pc.id, pc.name, pc.workspace_id, pc.master_key_id, pc.wrapped_key, pc.ciphertext, pc.hint, pc.created_by, pc.created_at, pc.updated_at, pc.rotated_at FROM provider_credential pc
+WHERE pc.id = COALESCE(
+    (SELECT wmc.credential_id FROM workspace_model_credential wmc
+     WHERE wmc.workspace_id = $1 AND wmc.chat_model_id = $2),
+    (SELECT cm.credential_id FROM chat_model cm WHERE cm.id = $2)
+)
+`
+
+type GetModelCredentialParams struct {
+	WorkspaceID sql.NullInt32 `json:"workspaceId"`
+	ChatModelID int32         `json:"chatModelId"`
+}
+
+// The credential used for a model: the workspace's own key if it has one, else the model's.
+func (q *Queries) GetModelCredential(ctx context.Context, arg GetModelCredentialParams) (ProviderCredential, error) {
+	row := q.db.QueryRowContext(ctx, getModelCredential, arg.WorkspaceID, arg.ChatModelID)
+	var i ProviderCredential
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.WorkspaceID,
+		&i.MasterKeyID,
+		&i.WrappedKey,
+		&i.Ciphertext,
+		&i.Hint,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.RotatedAt,
+	)
+	return i, err
+}
+
+const getProviderCredential = `-- name: GetProviderCredential :one
+SELECT id, name, workspace_id, master_key_id, wrapped_key, ciphertext, hint, created_by, created_at, updated_at, rotated_at FROM provider_credential
+WHERE id = $1
+`
+
+func (q *Queries) GetProviderCredential(ctx context.Context, id int32) (ProviderCredential, error) {
+	row := q.db.QueryRowContext(ctx, getProviderCredential, id)
+	var i ProviderCredential
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.WorkspaceID,
+		&i.MasterKeyID,
+		&i.WrappedKey,
+		&i.Ciphertext,
+		&i.Hint,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.RotatedAt,
+	)
+	return i, err
+}
+
+const getWorkspaceModelCredential = `-- name: GetWorkspaceModelCredential :one
+SELECT workspace_id, chat_model_id, credential_id, created_at FROM workspace_model_credential
+WHERE workspace_id = $1 AND chat_model_id = $2
+`
+
+type GetWorkspaceModelCredentialParams struct {
+	WorkspaceID int32 `json:"workspaceId"`
+	ChatModelID int32 `json:"chatModelId"`
+}
+
+func (q *Queries) GetWorkspaceModelCredential(ctx context.Context, arg GetWorkspaceModelCredentialParams) (WorkspaceModelCredential, error) {
+	row := q.db.QueryRowContext(ctx, getWorkspaceModelCredential, arg.WorkspaceID, arg.ChatModelID)
+	var i WorkspaceModelCredential
+	err := row.Scan(
+		&i.WorkspaceID,
+		&i.ChatModelID,
+		&i.CredentialID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listAllProviderCredentials = `-- name: ListAllProviderCredentials :many
+SELECT id, name, workspace_id, master_key_id, wrapped_key, ciphertext, hint, created_by, created_at, updated_at, rotated_at FROM provider_credential
+ORDER BY id
+`
+
+func (q *Queries) ListAllProviderCredentials(ctx context.Context) ([]ProviderCredential, error) {
+	rows, err := q.db.QueryContext(ctx, listAllProviderCredentials)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ProviderCredential
+	for rows.Next() {
+		var i ProviderCredential
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.WorkspaceID,
+			&i.MasterKeyID,
+			&i.WrappedKey,
+			&i.Ciphertext,
+			&i.Hint,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.RotatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSharedProviderCredentials = `-- name: ListSharedProviderCredentials :many
+SELECT id, name, workspace_id, master_key_id, wrapped_key, ciphertext, hint, created_by, created_at, updated_at, rotated_at FROM provider_credential
+WHERE workspace_id IS NULL
+ORDER BY name, id
+`
+
+// Credentials managed by admins, i.e. not owned by a workspace.
+func (q *Queries) ListSharedProviderCredentials(ctx context.Context) ([]ProviderCredential, error) {
+	rows, err := q.db.QueryContext(ctx, listSharedProviderCredentials)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ProviderCredential
+	for rows.Next() {
+		var i ProviderCredential
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.WorkspaceID,
+			&i.MasterKeyID,
+			&i.WrappedKey,
+			&i.Ciphertext,
+			&i.Hint,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.RotatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWorkspaceModelCredentials = `-- name: ListWorkspaceModelCredentials :many
+SELECT wmc.chat_model_id, cm.name AS model_name, pc.id AS credential_id, pc.hint, pc.created_at, pc.rotated_at
+FROM workspace_model_credential wmc
+JOIN chat_model cm ON cm.id = wmc.chat_model_id
+JOIN provider_credential pc ON pc.id = wmc.credential_id
+WHERE wmc.workspace_id = $1
+ORDER BY cm.name
+`
+
+type ListWorkspaceModelCredentialsRow struct {
+	ChatModelID  int32        `json:"chatModelId"`
+	ModelName    string       `json:"modelName"`
+	CredentialID int32        `json:"credentialId"`
+	Hint         string       `json:"hint"`
+	CreatedAt    time.Time    `json:"createdAt"`
+	RotatedAt    sql.NullTime `json:"rotatedAt"`
+}
+
+func (q *Queries) ListWorkspaceModelCredentials(ctx context.Context, workspaceID int32) ([]ListWorkspaceModelCredentialsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listWorkspaceModelCredentials, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListWorkspaceModelCredentialsRow
+	for rows.Next() {
+		var i ListWorkspaceModelCredentialsRow
+		if err := rows.Scan(
+			&i.ChatModelID,
+			&i.ModelName,
+			&i.CredentialID,
+			&i.Hint,
+			&i.CreatedAt,
+			&i.RotatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const rewrapProviderCredential = `-- name: RewrapProviderCredential :exec
+UPDATE provider_credential SET master_key_id = $1, wrapped_key = $2
+WHERE id = $3 AND master_key_id = $4
+`
+
+type RewrapProviderCredentialParams struct {
+	MasterKeyID    string `json:"masterKeyId"`
+	WrappedKey     []byte `json:"wrappedKey"`
+	ID             int32  `json:"id"`
+	OldMasterKeyID string `json:"oldMasterKeyId"`
+}
+
+// Stores a data key wrapped by another master key; the ciphertext is unchanged.
+func (q *Queries) RewrapProviderCredential(ctx context.Context, arg RewrapProviderCredentialParams) error {
+	_, err := q.db.ExecContext(ctx, rewrapProviderCredential,
+		arg.MasterKeyID,
+		arg.WrappedKey,
+		arg.ID,
+		arg.OldMasterKeyID,
+	)
+	return err
+}
+
+const updateProviderCredentialName = `-- name: UpdateProviderCredentialName :one
+UPDATE provider_credential SET name = $1, updated_at = now()
+WHERE id = $2
+RETURNING id, name, workspace_id, master_key_id, wrapped_key, ciphertext, hint, created_by, created_at, updated_at, rotated_at
+`
+
+type UpdateProviderCredentialNameParams struct {
+	Name string `json:"name"`
+	ID   int32  `json:"id"`
+}
+
+func (q *Queries) UpdateProviderCredentialName(ctx context.Context, arg UpdateProviderCredentialNameParams) (ProviderCredential, error) {
+	row := q.db.QueryRowContext(ctx, updateProviderCredentialName, arg.Name, arg.ID)
+	var i ProviderCredential
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.WorkspaceID,
+		&i.MasterKeyID,
+		&i.WrappedKey,
+		&i.Ciphertext,
+		&i.Hint,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.RotatedAt,
+	)
+	return i, err
+}
+
+const updateProviderCredentialSecret = `-- name: UpdateProviderCredentialSecret :one
+UPDATE provider_credential
+SET master_key_id = $1, wrapped_key = $2, ciphertext = $3, hint = $4,
+    updated_at = now(), rotated_at = now()
+WHERE id = $5
+RETURNING id, name, workspace_id, master_key_id, wrapped_key, ciphertext, hint, created_by, created_at, updated_at, rotated_at
+`
+
+type UpdateProviderCredentialSecretParams struct {
+	MasterKeyID string `json:"masterKeyId"`
+	WrappedKey  []byte `json:"wrappedKey"`
+	Ciphertext  []byte `json:"ciphertext"`
+	Hint        string `json:"hint"`
+	ID          int32  `json:"id"`
+}
+
+// Replaces the secret, sealed under a new data key.
+func (q *Queries) UpdateProviderCredentialSecret(ctx context.Context, arg UpdateProviderCredentialSecretParams) (ProviderCredential, error) {
+	row := q.db.QueryRowContext(ctx, updateProviderCredentialSecret,
+		arg.MasterKeyID,
+		arg.WrappedKey,
+		arg.Ciphertext,
+		arg.Hint,
+		arg.ID,
+	)
+	var i ProviderCredential
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.WorkspaceID,
+		&i.MasterKeyID,
+		&i.WrappedKey,
+		&i.Ciphertext,
+		&i.Hint,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.RotatedAt,
+	)
+	return i, err
+}
+
+const upsertWorkspaceModelCredential = `-- name: UpsertWorkspaceModelCredential :exec
+INSERT INTO workspace_model_credential (workspace_id, chat_model_id, credential_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (workspace_id, chat_model_id) DO UPDATE SET credential_id = EXCLUDED.credential_id, created_at = now()
+`
+
+type UpsertWorkspaceModelCredentialParams struct {
+	WorkspaceID  int32 `json:"workspaceId"`
+	ChatModelID  int32 `json:"chatModelId"`
+	CredentialID int32 `json:"credentialId"`
+}
+
+func (q *Queries) UpsertWorkspaceModelCredential(ctx context.Context, arg UpsertWorkspaceModelCredentialParams) error {
+	_, err := q.db.ExecContext(ctx, upsertWorkspaceModelCredential, arg.WorkspaceID, arg.ChatModelID, arg.CredentialID)
+	return err
+}
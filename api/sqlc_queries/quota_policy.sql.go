@@ -0,0 +1,343 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: quota_policy.sql
+
+package sqlc_queries
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createModelUsage = `-- name: CreateModelUsage :exec
+INSERT INTO model_usage (user_id, workspace_id, model, source, tokens)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type CreateModelUsageParams struct {
+	UserID      int32         `json:"userId"`
+	WorkspaceID sql.NullInt32 `json:"workspaceId"`
+	Model       string        `json:"model"`
+	Source      string        `json:"source"`
+	Tokens      int32         `json:"tokens"`
+}
+
+func (q *Queries) CreateModelUsage(ctx context.Context, arg CreateModelUsageParams) error {
+	_, err := q.db.ExecContext(ctx, createModelUsage,
+		arg.UserID,
+		arg.WorkspaceID,
+		arg.Model,
+		arg.Source,
+		arg.Tokens,
+	)
+	return err
+}
+
+const createQuotaPolicy = `-- name: CreateQuotaPolicy :one
+INSERT INTO quota_policy (name, user_id, workspace_id, chat_model_id, time_window, metric, quota_limit, enabled)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, name, user_id, workspace_id, chat_model_id, time_window, metric, quota_limit, enabled, created_at, updated_at
+`
+
+type CreateQuotaPolicyParams struct {
+	Name        string        `json:"name"`
+	UserID      sql.NullInt32 `json:"userId"`
+	WorkspaceID sql.NullInt32 `json:"workspaceId"`
+	ChatModelID sql.NullInt32 `json:"chatModelId"`
+	TimeWindow  string        `json:"timeWindow"`
+	Metric      string        `json:"metric"`
+	QuotaLimit  int64         `json:"quotaLimit"`
+	Enabled     bool          `json:"enabled"`
+}
+
+func (q *Queries) CreateQuotaPolicy(ctx context.Context, arg CreateQuotaPolicyParams) (QuotaPolicy, error) {
+	row := q.db.QueryRowContext(ctx, createQuotaPolicy,
+		arg.Name,
+		arg.UserID,
+		arg.WorkspaceID,
+		arg.ChatModelID,
+		arg.TimeWindow,
+		arg.Metric,
+		arg.QuotaLimit,
+		arg.Enabled,
+	)
+	var i QuotaPolicy
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.UserID,
+		&i.WorkspaceID,
+		&i.ChatModelID,
+		&i.TimeWindow,
+		&i.Metric,
+		&i.QuotaLimit,
+		&i.Enabled,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteQuotaPolicy = `-- name: DeleteQuotaPolicy :execrows
+DELETE FROM quota_policy WHERE id = $1
+`
+
+func (q *Queries) DeleteQuotaPolicy(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteQuotaPolicy, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getQuotaPolicy = `-- name: GetQuotaPolicy :one
+SELECT id, name, user_id, workspace_id, chat_model_id, time_window, metric, quota_limit, enabled, created_at, updated_at FROM quota_policy WHERE id = $1
+`
+
+func (q *Queries) GetQuotaPolicy(ctx context.Context, id int32) (QuotaPolicy, error) {
+	row := q.db.QueryRowContext(ctx, getQuotaPolicy, id)
+	var i QuotaPolicy
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.UserID,
+		&i.WorkspaceID,
+		&i.ChatModelID,
+		&i.TimeWindow,
+		&i.Metric,
+		&i.QuotaLimit,
+		&i.Enabled,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getQuotaUsage = `-- name: GetQuotaUsage :one
+WITH usage AS (
+    SELECT cm.role = 'assistant' AS is_request, cm.token_count AS tokens, cm.created_at,
+        cs.workspace_id, COALESCE(NULLIF(cm.model, ''), cs.model) AS model
+    FROM chat_message cm
+    JOIN chat_session cs ON cs.uuid = cm.chat_session_uuid
+    WHERE cm.user_id = $4::INTEGER
+      AND cm.created_at >= now() - make_interval(secs => $1::INTEGER)
+    UNION ALL
+    SELECT true, mu.tokens, mu.created_at, mu.workspace_id, mu.model
+    FROM model_usage mu
+    WHERE mu.user_id = $4::INTEGER
+      AND mu.created_at >= now() - make_interval(secs => $1::INTEGER)
+)
+SELECT
+    COUNT(*) FILTER (WHERE u.is_request)::BIGINT AS requests,
+    COALESCE(SUM(u.tokens), 0)::BIGINT AS tokens,
+    COALESCE(CEIL(EXTRACT(EPOCH FROM MIN(u.created_at) FILTER (WHERE u.is_request)
+        + make_interval(secs => $1::INTEGER) - now())), $1::INTEGER)::INTEGER AS requests_reset_seconds,
+    COALESCE(CEIL(EXTRACT(EPOCH FROM MIN(u.created_at)
+        + make_interval(secs => $1::INTEGER) - now())), $1::INTEGER)::INTEGER AS tokens_reset_seconds
+FROM usage u
+WHERE ($2::INTEGER IS NULL OR u.workspace_id = $2::INTEGER)
+  AND ($3::TEXT = '' OR u.model = $3::TEXT)
+`
+
+type GetQuotaUsageParams struct {
+	WindowSeconds int32         `json:"windowSeconds"`
+	WorkspaceID   sql.NullInt32 `json:"workspaceId"`
+	Model         string        `json:"model"`
+	UserID        int32         `json:"userId"`
+}
+
+type GetQuotaUsageRow struct {
+	Requests             int64 `json:"requests"`
+	Tokens               int64 `json:"tokens"`
+	RequestsResetSeconds int32 `json:"requestsResetSeconds"`
+	TokensResetSeconds   int32 `json:"tokensResetSeconds"`
+}
+
+// Requests are answers (assistant messages) and recorded model usage; tokens
+// count every message and usage. The reset columns are seconds until the
+// oldest counted entry leaves the window.
+func (q *Queries) GetQuotaUsage(ctx context.Context, arg GetQuotaUsageParams) (GetQuotaUsageRow, error) {
+	row := q.db.QueryRowContext(ctx, getQuotaUsage,
+		arg.WindowSeconds,
+		arg.WorkspaceID,
+		arg.Model,
+		arg.UserID,
+	)
+	var i GetQuotaUsageRow
+	err := row.Scan(
+		&i.Requests,
+		&i.Tokens,
+		&i.RequestsResetSeconds,
+		&i.TokensResetSeconds,
+	)
+	return i, err
+}
+
+const listMatchingQuotaPolicies = `-- name: ListMatchingQuotaPolicies :many
+SELECT id, name, user_id, workspace_id, chat_model_id, time_window, metric, quota_limit, enabled, created_at, updated_at FROM quota_policy
+WHERE enabled
+  AND (user_id IS NULL OR user_id = $1::INTEGER)
+  AND (workspace_id IS NULL OR workspace_id = $2::INTEGER)
+  AND (chat_model_id IS NULL OR chat_model_id = $3::INTEGER)
+ORDER BY id
+`
+
+type ListMatchingQuotaPoliciesParams struct {
+	UserID      int32         `json:"userId"`
+	WorkspaceID sql.NullInt32 `json:"workspaceId"`
+	ChatModelID sql.NullInt32 `json:"chatModelId"`
+}
+
+// Enabled policies applying to a user, optionally within a workspace and for a model.
+func (q *Queries) ListMatchingQuotaPolicies(ctx context.Context, arg ListMatchingQuotaPoliciesParams) ([]QuotaPolicy, error) {
+	rows, err := q.db.QueryContext(ctx, listMatchingQuotaPolicies, arg.UserID, arg.WorkspaceID, arg.ChatModelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []QuotaPolicy
+	for rows.Next() {
+		var i QuotaPolicy
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.UserID,
+			&i.WorkspaceID,
+			&i.ChatModelID,
+			&i.TimeWindow,
+			&i.Metric,
+			&i.QuotaLimit,
+			&i.Enabled,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listQuotaPolicies = `-- name: ListQuotaPolicies :many
+SELECT qp.id, qp.name, qp.user_id, qp.workspace_id, qp.chat_model_id, qp.time_window, qp.metric, qp.quota_limit, qp.enabled, qp.created_at, qp.updated_at,
+    COALESCE(au.email, '')::TEXT AS user_email,
+    COALESCE(cw.uuid, '')::TEXT AS workspace_uuid,
+    COALESCE(cm.name, '')::TEXT AS chat_model_name
+FROM quota_policy qp
+LEFT JOIN auth_user au ON au.id = qp.user_id
+LEFT JOIN chat_workspace cw ON cw.id = qp.workspace_id
+LEFT JOIN chat_model cm ON cm.id = qp.chat_model_id
+ORDER BY qp.id
+`
+
+type ListQuotaPoliciesRow struct {
+	ID            int32         `json:"id"`
+	Name          string        `json:"name"`
+	UserID        sql.NullInt32 `json:"userId"`
+	WorkspaceID   sql.NullInt32 `json:"workspaceId"`
+	ChatModelID   sql.NullInt32 `json:"chatModelId"`
+	TimeWindow    string        `json:"timeWindow"`
+	Metric        string        `json:"metric"`
+	QuotaLimit    int64         `json:"quotaLimit"`
+	Enabled       bool          `json:"enabled"`
+	CreatedAt     time.Time     `json:"createdAt"`
+	UpdatedAt     time.Time     `json:"updatedAt"`
+	UserEmail     string        `json:"userEmail"`
+	WorkspaceUuid string        `json:"workspaceUuid"`
+	ChatModelName string        `json:"chatModelName"`
+}
+
+func (q *Queries) ListQuotaPolicies(ctx context.Context) ([]ListQuotaPoliciesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listQuotaPolicies)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListQuotaPoliciesRow
+	for rows.Next() {
+		var i ListQuotaPoliciesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.UserID,
+			&i.WorkspaceID,
+			&i.ChatModelID,
+			&i.TimeWindow,
+			&i.Metric,
+			&i.QuotaLimit,
+			&i.Enabled,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserEmail,
+			&i.WorkspaceUuid,
+			&i.ChatModelName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateQuotaPolicy = `-- name: UpdateQuotaPolicy :one
+UPDATE quota_policy
+SET name = $2, user_id = $3, workspace_id = $4, chat_model_id = $5,
+    time_window = $6, metric = $7, quota_limit = $8, enabled = $9, updated_at = now()
+WHERE id = $1
+RETURNING id, name, user_id, workspace_id, chat_model_id, time_window, metric, quota_limit, enabled, created_at, updated_at
+`
+
+type UpdateQuotaPolicyParams struct {
+	ID          int32         `json:"id"`
+	Name        string        `json:"name"`
+	UserID      sql.NullInt32 `json:"userId"`
+	WorkspaceID sql.NullInt32 `json:"workspaceId"`
+	ChatModelID sql.NullInt32 `json:"chatModelId"`
+	TimeWindow  string        `json:"timeWindow"`
+	Metric      string        `json:"metric"`
+	QuotaLimit  int64         `json:"quotaLimit"`
+	Enabled     bool          `json:"enabled"`
+}
+
+func (q *Queries) UpdateQuotaPolicy(ctx context.Context, arg UpdateQuotaPolicyParams) (QuotaPolicy, error) {
+	row := q.db.QueryRowContext(ctx, updateQuotaPolicy,
+		arg.ID,
+		arg.Name,
+		arg.UserID,
+		arg.WorkspaceID,
+		arg.ChatModelID,
+		arg.TimeWindow,
+		arg.Metric,
+		arg.QuotaLimit,
+		arg.Enabled,
+	)
+	var i QuotaPolicy
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.UserID,
+		&i.WorkspaceID,
+		&i.ChatModelID,
+		&i.TimeWindow,
+		&i.Metric,
+		&i.QuotaLimit,
+		&i.Enabled,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
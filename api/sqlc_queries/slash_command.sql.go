@@ -0,0 +1,228 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: slash_command.sql
+
+package sqlc_queries
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createSlashCommand = `-- name: CreateSlashCommand :one
+INSERT INTO slash_command (uuid, user_id, workspace_id, name, description, template, model, temperature)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, uuid, user_id, workspace_id, name, description, template, model, temperature, created_at, updated_at
+`
+
+type CreateSlashCommandParams struct {
+	Uuid        string          `json:"uuid"`
+	UserID      int32           `json:"userId"`
+	WorkspaceID sql.NullInt32   `json:"workspaceId"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Template    string          `json:"template"`
+	Model       string          `json:"model"`
+	Temperature sql.NullFloat64 `json:"temperature"`
+}
+
+func (q *Queries) CreateSlashCommand(ctx context.Context, arg CreateSlashCommandParams) (SlashCommand, error) {
+	row := q.db.QueryRowContext(ctx, createSlashCommand,
+		arg.Uuid,
+		arg.UserID,
+		arg.WorkspaceID,
+		arg.Name,
+		arg.Description,
+		arg.Template,
+		arg.Model,
+		arg.Temperature,
+	)
+	var i SlashCommand
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.UserID,
+		&i.WorkspaceID,
+		&i.Name,
+		&i.Description,
+		&i.Template,
+		&i.Model,
+		&i.Temperature,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteSlashCommand = `-- name: DeleteSlashCommand :exec
+DELETE FROM slash_command
+WHERE uuid = $1
+`
+
+func (q *Queries) DeleteSlashCommand(ctx context.Context, uuid string) error {
+	_, err := q.db.ExecContext(ctx, deleteSlashCommand, uuid)
+	return err
+}
+
+const getSlashCommandByUUID = `-- name: GetSlashCommandByUUID :one
+SELECT id, uuid, user_id, workspace_id, name, description, template, model, temperature, created_at, updated_at FROM slash_command
+WHERE uuid = $1
+`
+
+func (q *Queries) GetSlashCommandByUUID(ctx context.Context, uuid string) (SlashCommand, error) {
+	row := q.db.QueryRowContext(ctx, getSlashCommandByUUID, uuid)
+	var i SlashCommand
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.UserID,
+		&i.WorkspaceID,
+		&i.Name,
+		&i.Description,
+		&i.Template,
+		&i.Model,
+		&i.Temperature,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listSlashCommandsByUserID = `-- name: ListSlashCommandsByUserID :many
+SELECT c.id, c.uuid, c.user_id, c.workspace_id, c.name, c.description, c.template, c.model, c.temperature, c.created_at, c.updated_at, COALESCE(w.uuid, '')::TEXT AS workspace_uuid
+FROM slash_command c
+LEFT JOIN chat_workspace w ON w.id = c.workspace_id
+WHERE c.user_id = $1
+ORDER BY c.name, c.workspace_id NULLS FIRST
+`
+
+type ListSlashCommandsByUserIDRow struct {
+	ID            int32           `json:"id"`
+	Uuid          string          `json:"uuid"`
+	UserID        int32           `json:"userId"`
+	WorkspaceID   sql.NullInt32   `json:"workspaceId"`
+	Name          string          `json:"name"`
+	Description   string          `json:"description"`
+	Template      string          `json:"template"`
+	Model         string          `json:"model"`
+	Temperature   sql.NullFloat64 `json:"temperature"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	UpdatedAt     time.Time       `json:"updatedAt"`
+	WorkspaceUuid string          `json:"workspaceUuid"`
+}
+
+func (q *Queries) ListSlashCommandsByUserID(ctx context.Context, userID int32) ([]ListSlashCommandsByUserIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSlashCommandsByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSlashCommandsByUserIDRow
+	for rows.Next() {
+		var i ListSlashCommandsByUserIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Uuid,
+			&i.UserID,
+			&i.WorkspaceID,
+			&i.Name,
+			&i.Description,
+			&i.Template,
+			&i.Model,
+			&i.Temperature,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.WorkspaceUuid,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const resolveSlashCommand = `-- name: ResolveSlashCommand :one
+SELECT id, uuid, user_id, workspace_id, name, description, template, model, temperature, created_at, updated_at FROM slash_command
+WHERE user_id = $1 AND name = $2
+  AND (workspace_id IS NULL OR workspace_id = $3::INTEGER)
+ORDER BY workspace_id NULLS LAST
+LIMIT 1
+`
+
+type ResolveSlashCommandParams struct {
+	UserID      int32  `json:"userId"`
+	Name        string `json:"name"`
+	WorkspaceID int32  `json:"workspaceId"`
+}
+
+// Workspace-scoped commands take precedence over user-wide ones with the same name.
+func (q *Queries) ResolveSlashCommand(ctx context.Context, arg ResolveSlashCommandParams) (SlashCommand, error) {
+	row := q.db.QueryRowContext(ctx, resolveSlashCommand, arg.UserID, arg.Name, arg.WorkspaceID)
+	var i SlashCommand
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.UserID,
+		&i.WorkspaceID,
+		&i.Name,
+		&i.Description,
+		&i.Template,
+		&i.Model,
+		&i.Temperature,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateSlashCommand = `-- name: UpdateSlashCommand :one
+UPDATE slash_command
+SET name = $2, description = $3, template = $4, model = $5, temperature = $6, workspace_id = $7, updated_at = now()
+WHERE uuid = $1
+RETURNING id, uuid, user_id, workspace_id, name, description, template, model, temperature, created_at, updated_at
+`
+
+type UpdateSlashCommandParams struct {
+	Uuid        string          `json:"uuid"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Template    string          `json:"template"`
+	Model       string          `json:"model"`
+	Temperature sql.NullFloat64 `json:"temperature"`
+	WorkspaceID sql.NullInt32   `json:"workspaceId"`
+}
+
+func (q *Queries) UpdateSlashCommand(ctx context.Context, arg UpdateSlashCommandParams) (SlashCommand, error) {
+	row := q.db.QueryRowContext(ctx, updateSlashCommand,
+		arg.Uuid,
+		arg.Name,
+		arg.Description,
+		arg.Template,
+		arg.Model,
+		arg.Temperature,
+		arg.WorkspaceID,
+	)
+	var i SlashCommand
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.UserID,
+		&i.WorkspaceID,
+		&i.Name,
+		&i.Description,
+		&i.Template,
+		&i.Model,
+		&i.Temperature,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,137 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: speech.sql
+
+package sqlc_queries
+
+import (
+	"context"
+)
+
+const deleteOrphanedMessageSpeech = `-- name: DeleteOrphanedMessageSpeech :many
+DELETE FROM message_speech s
+WHERE NOT EXISTS (
+    SELECT 1 FROM chat_message m
+    WHERE m.uuid = s.message_uuid AND m.is_deleted = false
+)
+RETURNING blob_hash
+`
+
+// speech of messages that were deleted
+func (q *Queries) DeleteOrphanedMessageSpeech(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, deleteOrphanedMessageSpeech)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var blob_hash string
+		if err := rows.Scan(&blob_hash); err != nil {
+			return nil, err
+		}
+		items = append(items, blob_hash)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMessageSpeech = `-- name: GetMessageSpeech :one
+SELECT message_uuid, voice_key, content_hash, blob_hash, mime_type, created_at FROM message_speech
+WHERE message_uuid = $1 AND voice_key = $2
+`
+
+type GetMessageSpeechParams struct {
+	MessageUuid string `json:"messageUuid"`
+	VoiceKey    string `json:"voiceKey"`
+}
+
+func (q *Queries) GetMessageSpeech(ctx context.Context, arg GetMessageSpeechParams) (MessageSpeech, error) {
+	row := q.db.QueryRowContext(ctx, getMessageSpeech, arg.MessageUuid, arg.VoiceKey)
+	var i MessageSpeech
+	err := row.Scan(
+		&i.MessageUuid,
+		&i.VoiceKey,
+		&i.ContentHash,
+		&i.BlobHash,
+		&i.MimeType,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUserSpeechSetting = `-- name: GetUserSpeechSetting :one
+SELECT user_id, voice, speed, updated_at FROM user_speech_setting
+WHERE user_id = $1
+`
+
+func (q *Queries) GetUserSpeechSetting(ctx context.Context, userID int32) (UserSpeechSetting, error) {
+	row := q.db.QueryRowContext(ctx, getUserSpeechSetting, userID)
+	var i UserSpeechSetting
+	err := row.Scan(
+		&i.UserID,
+		&i.Voice,
+		&i.Speed,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertMessageSpeech = `-- name: UpsertMessageSpeech :exec
+INSERT INTO message_speech (message_uuid, voice_key, content_hash, blob_hash, mime_type)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (message_uuid, voice_key) DO UPDATE
+SET content_hash = EXCLUDED.content_hash, blob_hash = EXCLUDED.blob_hash,
+    mime_type = EXCLUDED.mime_type, created_at = now()
+`
+
+type UpsertMessageSpeechParams struct {
+	MessageUuid string `json:"messageUuid"`
+	VoiceKey    string `json:"voiceKey"`
+	ContentHash string `json:"contentHash"`
+	BlobHash    string `json:"blobHash"`
+	MimeType    string `json:"mimeType"`
+}
+
+func (q *Queries) UpsertMessageSpeech(ctx context.Context, arg UpsertMessageSpeechParams) error {
+	_, err := q.db.ExecContext(ctx, upsertMessageSpeech,
+		arg.MessageUuid,
+		arg.VoiceKey,
+		arg.ContentHash,
+		arg.BlobHash,
+		arg.MimeType,
+	)
+	return err
+}
+
+const upsertUserSpeechSetting = `-- name: UpsertUserSpeechSetting :one
+INSERT INTO user_speech_setting (user_id, voice, speed)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET voice = EXCLUDED.voice, speed = EXCLUDED.speed, updated_at = now()
+RETURNING user_id, voice, speed, updated_at
+`
+
+type UpsertUserSpeechSettingParams struct {
+	UserID int32   `json:"userId"`
+	Voice  string  `json:"voice"`
+	Speed  float64 `json:"speed"`
+}
+
+func (q *Queries) UpsertUserSpeechSetting(ctx context.Context, arg UpsertUserSpeechSettingParams) (UserSpeechSetting, error) {
+	row := q.db.QueryRowContext(ctx, upsertUserSpeechSetting, arg.UserID, arg.Voice, arg.Speed)
+	var i UserSpeechSetting
+	err := row.Scan(
+		&i.UserID,
+		&i.Voice,
+		&i.Speed,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -2,6 +2,7 @@ package sqlc_queries
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 
 	"github.com/samber/lo"
@@ -16,6 +17,25 @@ func (user *AuthUser) Role() string {
 	return role
 }
 
+// InTx runs fn with queries bound to a single transaction, which is committed
+// when fn returns nil and rolled back otherwise. When q is already bound to a
+// transaction, fn runs within it.
+func (q *Queries) InTx(ctx context.Context, fn func(*Queries) error) error {
+	db, ok := q.db.(*sql.DB)
+	if !ok {
+		return fn(q)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(q.WithTx(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
 func (m *ChatMessage) Authenticate(q Queries, userID int32) (bool, error) {
 	messageID := m.ID
 	ctx := context.Background()
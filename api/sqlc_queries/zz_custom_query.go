@@ -3,24 +3,64 @@ package sqlc_queries
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/rotisserie/eris"
 	"github.com/samber/lo"
+
+	"github.com/swuecho/chat_backend/imaging"
 )
 
 type SimpleChatMessage struct {
-	Uuid               string     `json:"uuid"`
-	DateTime           string     `json:"dateTime"`
-	Text               string     `json:"text"`
-	Model              string     `json:"model"`
-	Inversion          bool       `json:"inversion"`
-	Error              bool       `json:"error"`
-	Loading            bool       `json:"loading"`
-	IsPin              bool       `json:"isPin"`
-	IsPrompt           bool       `json:"isPrompt"`
-	Artifacts          []Artifact `json:"artifacts,omitempty"`
-	SuggestedQuestions []string   `json:"suggestedQuestions,omitempty"`
+	Uuid               string       `json:"uuid"`
+	DateTime           string       `json:"dateTime"`
+	Text               string       `json:"text"`
+	Model              string       `json:"model"`
+	Inversion          bool         `json:"inversion"`
+	Error              bool         `json:"error"`
+	Loading            bool         `json:"loading"`
+	IsPin              bool         `json:"isPin"`
+	IsPrompt           bool         `json:"isPrompt"`
+	Artifacts          []Artifact   `json:"artifacts,omitempty"`
+	SuggestedQuestions []string     `json:"suggestedQuestions,omitempty"`
+	Attachments        []Attachment `json:"attachments,omitempty"`
+}
+
+// Attachment describes a file sent with a message.
+type Attachment struct {
+	ID       int32  `json:"id"`
+	Name     string `json:"name"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	Url      string `json:"url"`
+	// Detached files are only sent to the model on the turn of their message.
+	Detached bool `json:"detached"`
+	// Source is "upload", "voice" for a recording transcribed into the message,
+	// or "generated" for an image an image model answered with.
+	Source string `json:"source"`
+	// ThumbnailUrl is set for images.
+	ThumbnailUrl string `json:"thumbnailUrl,omitempty"`
+}
+
+// WithThumbnail sets ThumbnailUrl if a is an image.
+func (a Attachment) WithThumbnail() Attachment {
+	if imaging.IsImage(a.MimeType) {
+		a.ThumbnailUrl = a.Url + "/thumbnail"
+	}
+	return a
+}
+
+func attachmentFromRow(f ListChatFileAttachmentsBySessionUUIDRow) Attachment {
+	return Attachment{
+		ID:       f.ID,
+		Name:     f.Name,
+		MimeType: f.MimeType,
+		Size:     f.Size,
+		Url:      fmt.Sprintf("/download/%d", f.ID),
+		Detached: f.Detached,
+		Source:   f.Source,
+	}.WithThumbnail()
 }
 
 type Artifact struct {
@@ -61,6 +101,15 @@ func (q *Queries) GetChatHistoryBySessionUUID(ctx context.Context, uuid string,
 		return nil, eris.Wrap(err, "fail to get message: ")
 	}
 
+	files, err := q.ListChatFileAttachmentsBySessionUUID(ctx, uuid)
+	if err != nil {
+		return nil, eris.Wrap(err, "fail to get files: ")
+	}
+	attachments := make(map[string][]Attachment)
+	for _, f := range files {
+		attachments[f.ChatMessageUuid.String] = append(attachments[f.ChatMessageUuid.String], attachmentFromRow(f))
+	}
+
 	simple_msgs := lo.Map(messages, func(message ChatMessage, _ int) SimpleChatMessage {
 		text := message.Content
 		// prepend reason content
@@ -99,6 +148,7 @@ func (q *Queries) GetChatHistoryBySessionUUID(ctx context.Context, uuid string,
 			IsPin:              message.IsPin,
 			Artifacts:          artifacts,
 			SuggestedQuestions: suggestedQuestions,
+			Attachments:        attachments[message.Uuid],
 		}
 	})
 
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rotisserie/eris"
+)
+
+// localStore keeps blobs as files under dir, spread over subdirectories
+// named by the first two characters of the key.
+type localStore struct {
+	dir string
+}
+
+func (s localStore) path(key string) (string, error) {
+	if len(key) < 3 || strings.ContainsAny(key, `/\.`) {
+		return "", eris.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, key[:2], key), nil
+}
+
+func (s localStore) Put(_ context.Context, key string, r io.Reader, size int64) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return eris.Wrap(err, "failed to create blob directory")
+	}
+	// write a temporary file and rename it so readers never see a partial blob
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return eris.Wrap(err, "failed to create blob file")
+	}
+	defer os.Remove(tmp.Name())
+	n, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return eris.Wrap(err, "failed to write blob file")
+	}
+	if n != size {
+		return eris.Errorf("blob %s is %d bytes, expected %d", key, n, size)
+	}
+	return eris.Wrap(os.Rename(tmp.Name(), path), "failed to write blob file")
+}
+
+func (s localStore) Open(_ context.Context, key string) (io.ReadSeekCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to open blob file")
+	}
+	return f, nil
+}
+
+func (s localStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return eris.Wrap(err, "failed to delete blob file")
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// migrateBatch is how many blobs or inline files are loaded at a time.
+const migrateBatch = 50
+
+// MigrateResult counts what Migrate moved.
+type MigrateResult struct {
+	// Blobs moved from another backend.
+	Blobs int
+	// Inline files whose data moved out of chat_file.
+	Inline int
+	// Failed blobs and files, left where they were and logged.
+	Failed int
+}
+
+// Migrate moves every blob held by another backend, and every file stored
+// inline in chat_file, to the current backend. Items that fail are logged
+// and skipped, so running it again retries them.
+func (b *Blobs) Migrate(ctx context.Context) (MigrateResult, error) {
+	var result MigrateResult
+	after := ""
+	for {
+		rows, err := b.q.ListFileBlobsOutsideBackend(ctx, sqlc_queries.ListFileBlobsOutsideBackendParams{
+			Backend: b.backend, AfterHash: after, LimitCount: migrateBatch,
+		})
+		if err != nil {
+			return result, eris.Wrap(err, "failed to list blobs")
+		}
+		if len(rows) == 0 {
+			break
+		}
+		for _, row := range rows {
+			if err := b.move(ctx, row); err != nil {
+				slog.Error("failed to move blob", "hash", row.Hash, "from", row.Backend, "to", b.backend, "error", err)
+				result.Failed++
+				continue
+			}
+			result.Blobs++
+		}
+		after = rows[len(rows)-1].Hash
+	}
+
+	var afterID int32
+	for {
+		files, err := b.q.ListInlineChatFiles(ctx, sqlc_queries.ListInlineChatFilesParams{AfterID: afterID, LimitCount: migrateBatch})
+		if err != nil {
+			return result, eris.Wrap(err, "failed to list inline files")
+		}
+		if len(files) == 0 {
+			break
+		}
+		for _, f := range files {
+			blob, err := b.Save(ctx, bytes.NewReader(f.Data))
+			if err == nil {
+				err = b.q.SetChatFileBlob(ctx, sqlc_queries.SetChatFileBlobParams{ID: f.ID, BlobHash: blob.Hash, Size: blob.Size})
+			}
+			if err != nil {
+				slog.Error("failed to move inline file", "id", f.ID, "error", err)
+				result.Failed++
+				continue
+			}
+			result.Inline++
+		}
+		afterID = files[len(files)-1].ID
+	}
+	return result, nil
+}
+
+// move copies a blob to the current backend, records the move and then
+// deletes the old copy.
+func (b *Blobs) move(ctx context.Context, row sqlc_queries.FileBlob) error {
+	src, err := b.store(row.Backend)
+	if err != nil {
+		return err
+	}
+	dst, err := b.store(b.backend)
+	if err != nil {
+		return err
+	}
+	rc, err := src.Open(ctx, row.Hash)
+	if err != nil {
+		return err
+	}
+	err = dst.Put(ctx, row.Hash, rc, row.Size)
+	rc.Close()
+	if err != nil {
+		return err
+	}
+	if err := b.q.SetFileBlobBackend(ctx, sqlc_queries.SetFileBlobBackendParams{Hash: row.Hash, Backend: b.backend}); err != nil {
+		return eris.Wrap(err, "failed to record blob move")
+	}
+	if err := src.Delete(ctx, row.Hash); err != nil {
+		slog.Warn("failed to delete moved blob", "hash", row.Hash, "backend", row.Backend, "error", err)
+	}
+	return nil
+}
+
+// CollectGarbage deletes blobs nothing refers to, left behind by uploads
+// interrupted between storing the contents and recording the file, and the
+// speech of deleted messages.
+func (b *Blobs) CollectGarbage(ctx context.Context) (int, error) {
+	if _, err := b.q.DeleteOrphanedMessageSpeech(ctx); err != nil {
+		return 0, eris.Wrap(err, "failed to delete speech of deleted messages")
+	}
+	rows, err := b.q.ListUnreferencedFileBlobs(ctx)
+	if err != nil {
+		return 0, eris.Wrap(err, "failed to list unreferenced blobs")
+	}
+	for _, row := range rows {
+		if err := b.Release(ctx, row.Hash); err != nil {
+			return 0, err
+		}
+	}
+	return len(rows), nil
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// postgresStore keeps blobs in the file_blob_data table.
+type postgresStore struct {
+	q *sqlc_queries.Queries
+}
+
+func (s postgresStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return eris.Wrap(err, "failed to read blob")
+	}
+	if int64(len(data)) != size {
+		return eris.Errorf("blob %s is %d bytes, expected %d", key, len(data), size)
+	}
+	return s.q.PutFileBlobData(ctx, sqlc_queries.PutFileBlobDataParams{Hash: key, Data: data})
+}
+
+func (s postgresStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	data, err := s.q.GetFileBlobData(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to read blob")
+	}
+	return nopCloser{bytes.NewReader(data)}, nil
+}
+
+func (s postgresStore) Delete(ctx context.Context, key string) error {
+	return s.q.DeleteFileBlobData(ctx, key)
+}
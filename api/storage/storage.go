@@ -0,0 +1,312 @@
+// Package storage keeps the contents of uploaded files outside chat_file, in
+// Postgres, a local directory or an S3-compatible bucket. Contents are
+// addressed by their SHA-256 hash, so a file uploaded many times is stored
+// once.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// Backend names, as stored in file_blob.backend.
+const (
+	Postgres = "postgres"
+	Local    = "local"
+	S3       = "s3"
+)
+
+var (
+	// ErrNotFound is returned for a blob missing from its backend.
+	ErrNotFound = eris.New("blob not found")
+	// ErrNotConfigured is returned by Default before SetDefault.
+	ErrNotConfigured = eris.New("file storage is not configured")
+)
+
+// Store holds blob contents by key.
+type Store interface {
+	// Put stores size bytes read from r under key, replacing any earlier contents.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Open returns the contents stored under key, or ErrNotFound.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete removes key; deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Config selects the backend of new blobs and configures the backends.
+// Postgres is always available; Local and S3 when they are configured, so
+// blobs written before a backend change stay readable.
+type Config struct {
+	Backend  string
+	LocalDir string
+	S3       S3Config
+}
+
+// Blob describes stored contents.
+type Blob struct {
+	Hash    string
+	Size    int64
+	Backend string
+}
+
+// Blobs stores file contents in the configured backend and records them in
+// file_blob.
+type Blobs struct {
+	q       *sqlc_queries.Queries
+	backend string
+	stores  map[string]Store
+}
+
+// New opens the backends named by cfg.
+func New(q *sqlc_queries.Queries, cfg Config) (*Blobs, error) {
+	b := &Blobs{q: q, backend: cfg.Backend, stores: map[string]Store{Postgres: postgresStore{q: q}}}
+	if b.backend == "" {
+		b.backend = Postgres
+	}
+	if cfg.LocalDir != "" {
+		b.stores[Local] = localStore{dir: cfg.LocalDir}
+	}
+	if cfg.S3.Bucket != "" {
+		s3, err := newS3Store(cfg.S3)
+		if err != nil {
+			return nil, err
+		}
+		b.stores[S3] = s3
+	}
+	if _, ok := b.stores[b.backend]; !ok {
+		return nil, eris.Errorf("storage backend %q is not configured", b.backend)
+	}
+	return b, nil
+}
+
+// Backend returns the backend new blobs are written to.
+func (b *Blobs) Backend() string { return b.backend }
+
+func (b *Blobs) store(backend string) (Store, error) {
+	s, ok := b.stores[backend]
+	if !ok {
+		return nil, eris.Errorf("storage backend %q is not configured", backend)
+	}
+	return s, nil
+}
+
+// Save stores the contents of r unless a blob with the same hash exists.
+// r is read twice: once to hash it and once to store it.
+func (b *Blobs) Save(ctx context.Context, r io.ReadSeeker) (Blob, error) {
+	h := sha256.New()
+	size, err := io.Copy(h, r)
+	if err != nil {
+		return Blob{}, eris.Wrap(err, "failed to hash file")
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	store, err := b.store(b.backend)
+	if err != nil {
+		return Blob{}, err
+	}
+	var blob Blob
+	// the lock keeps a Release of the same contents from deleting them
+	// between storing and recording them
+	err = b.q.InTx(ctx, func(q *sqlc_queries.Queries) error {
+		if err := q.LockFileBlob(ctx, hash); err != nil {
+			return eris.Wrap(err, "failed to lock blob")
+		}
+		existing, err := q.GetFileBlob(ctx, hash)
+		if err == nil {
+			blob = blobFromRow(existing)
+			return nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return eris.Wrap(err, "failed to look up blob")
+		}
+
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return eris.Wrap(err, "failed to rewind file")
+		}
+		if err := store.Put(ctx, hash, r, size); err != nil {
+			return eris.Wrap(err, "failed to store file")
+		}
+		if _, err := q.CreateFileBlob(ctx, sqlc_queries.CreateFileBlobParams{Hash: hash, Size: size, Backend: b.backend}); err != nil {
+			return eris.Wrap(err, "failed to record blob")
+		}
+		blob = Blob{Hash: hash, Size: size, Backend: b.backend}
+		return nil
+	})
+	if err != nil {
+		return Blob{}, err
+	}
+	return blob, nil
+}
+
+// Open returns the contents of the blob with hash.
+func (b *Blobs) Open(ctx context.Context, hash string) (io.ReadSeekCloser, Blob, error) {
+	row, err := b.q.GetFileBlob(ctx, hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, Blob{}, ErrNotFound
+		}
+		return nil, Blob{}, eris.Wrap(err, "failed to look up blob")
+	}
+	store, err := b.store(row.Backend)
+	if err != nil {
+		return nil, Blob{}, err
+	}
+	rc, err := store.Open(ctx, hash)
+	if err != nil {
+		return nil, Blob{}, err
+	}
+	return rc, blobFromRow(row), nil
+}
+
+// ReadAll returns the contents of the blob with hash.
+func (b *Blobs) ReadAll(ctx context.Context, hash string) ([]byte, error) {
+	rc, _, err := b.Open(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, eris.Wrapf(err, "failed to read blob %s", hash)
+	}
+	return data, nil
+}
+
+// Release deletes the blob with hash once no chat file refers to it,
+// together with its variants.
+func (b *Blobs) Release(ctx context.Context, hash string) error {
+	variants, err := b.q.ListFileBlobVariantsBySource(ctx, hash)
+	if err != nil {
+		return eris.Wrap(err, "failed to list blob variants")
+	}
+	released := false
+	// the lock keeps a concurrent Save of the same contents from recording
+	// them again before they are deleted
+	err = b.q.InTx(ctx, func(q *sqlc_queries.Queries) error {
+		if err := q.LockFileBlob(ctx, hash); err != nil {
+			return eris.Wrap(err, "failed to lock blob")
+		}
+		row, err := q.DeleteUnreferencedFileBlob(ctx, hash)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return eris.Wrap(err, "failed to release blob")
+		}
+		released = true
+		store, err := b.store(row.Backend)
+		if err != nil {
+			return err
+		}
+		if err := store.Delete(ctx, hash); err != nil {
+			// the record is gone, so the contents are only wasted space
+			slog.Warn("failed to delete released blob", "hash", hash, "backend", row.Backend, "error", err)
+		}
+		return nil
+	})
+	if err != nil || !released {
+		return err
+	}
+	// deleting the source removed the variant records; the variants are
+	// released once that is committed, so they are seen as unreferenced
+	for _, v := range variants {
+		if err := b.Release(ctx, v.BlobHash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Variant returns the contents and MIME type of the variant of the blob
+// sourceHash made for profile, with ok false if there is none yet.
+func (b *Blobs) Variant(ctx context.Context, sourceHash, profile string) (data []byte, mime string, ok bool, err error) {
+	row, err := b.q.GetFileBlobVariant(ctx, sqlc_queries.GetFileBlobVariantParams{SourceHash: sourceHash, Profile: profile})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", false, nil
+	}
+	if err != nil {
+		return nil, "", false, eris.Wrap(err, "failed to look up blob variant")
+	}
+	data, err = b.ReadAll(ctx, row.BlobHash)
+	if err != nil {
+		return nil, "", false, err
+	}
+	return data, row.MimeType, true, nil
+}
+
+// SaveVariant stores data as the variant of the blob sourceHash made for
+// profile.
+func (b *Blobs) SaveVariant(ctx context.Context, sourceHash, profile, mime string, data []byte) error {
+	blob, err := b.Save(ctx, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	err = b.q.CreateFileBlobVariant(ctx, sqlc_queries.CreateFileBlobVariantParams{
+		SourceHash: sourceHash,
+		Profile:    profile,
+		BlobHash:   blob.Hash,
+		MimeType:   mime,
+	})
+	if err != nil {
+		// nothing refers to the new blob yet
+		if releaseErr := b.Release(ctx, blob.Hash); releaseErr != nil {
+			slog.Warn("failed to release variant blob", "hash", blob.Hash, "error", releaseErr)
+		}
+		return eris.Wrap(err, "failed to record blob variant")
+	}
+	return nil
+}
+
+// FileContents returns the contents of f, read from its blob unless it was
+// stored inline before blob storage.
+func (b *Blobs) FileContents(ctx context.Context, f sqlc_queries.ChatFile) ([]byte, error) {
+	if !f.BlobHash.Valid {
+		return f.Data, nil
+	}
+	return b.ReadAll(ctx, f.BlobHash.String)
+}
+
+// OpenFile is FileContents as a stream.
+func (b *Blobs) OpenFile(ctx context.Context, f sqlc_queries.ChatFile) (io.ReadSeekCloser, error) {
+	if !f.BlobHash.Valid {
+		return NewInlineReader(f.Data), nil
+	}
+	rc, _, err := b.Open(ctx, f.BlobHash.String)
+	return rc, err
+}
+
+var current atomic.Pointer[Blobs]
+
+// SetDefault installs the process-wide blob store.
+func SetDefault(b *Blobs) { current.Store(b) }
+
+// Default returns the process-wide blob store, or ErrNotConfigured.
+func Default() (*Blobs, error) {
+	if b := current.Load(); b != nil {
+		return b, nil
+	}
+	return nil, ErrNotConfigured
+}
+
+// NewInlineReader returns data as a blob stream, for files stored inline.
+func NewInlineReader(data []byte) io.ReadSeekCloser {
+	return nopCloser{bytes.NewReader(data)}
+}
+
+func blobFromRow(row sqlc_queries.FileBlob) Blob {
+	return Blob{Hash: row.Hash, Size: row.Size, Backend: row.Backend}
+}
+
+type nopCloser struct{ io.ReadSeeker }
+
+func (nopCloser) Close() error { return nil }
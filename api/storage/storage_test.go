@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// checkStore stores, reads, seeks and deletes a blob in s.
+func checkStore(t *testing.T, s Store) {
+	t.Helper()
+	ctx := context.Background()
+	key := "3a7bd3e2360a3d29eea436fcfb7e44c735d117c42d1c1835420b6b9942dd4f1b"
+	content := "hello, blob storage"
+
+	if _, err := s.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before Put, got %v", err)
+	}
+	if err := s.Put(ctx, key, strings.NewReader(content), int64(len(content))); err != nil {
+		t.Fatal(err)
+	}
+
+	rc, err := s.Open(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(rc)
+	if err != nil || string(got) != content {
+		t.Fatalf("read %q, %v", got, err)
+	}
+	size, err := rc.Seek(0, io.SeekEnd)
+	if err != nil || size != int64(len(content)) {
+		t.Fatalf("seek to end: %d, %v", size, err)
+	}
+	if _, err := rc.Seek(7, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	part := make([]byte, 4)
+	if _, err := io.ReadFull(rc, part); err != nil || string(part) != "blob" {
+		t.Fatalf("read after seek %q, %v", part, err)
+	}
+	rc.Close()
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after Delete, got %v", err)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("deleting a missing blob: %v", err)
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	checkStore(t, localStore{dir: t.TempDir()})
+}
+
+func TestLocalStoreRejectsPaths(t *testing.T) {
+	s := localStore{dir: t.TempDir()}
+	for _, key := range []string{"../../etc/passwd", "ab/cd", "a"} {
+		if err := s.Put(context.Background(), key, strings.NewReader("x"), 1); err == nil {
+			t.Errorf("expected %q to be rejected", key)
+		}
+	}
+}
+
+func TestLocalStoreRejectsShortWrite(t *testing.T) {
+	s := localStore{dir: t.TempDir()}
+	key := "3a7bd3e2360a3d29eea436fcfb7e44c735d117c42d1c1835420b6b9942dd4f1b"
+	if err := s.Put(context.Background(), key, strings.NewReader("short"), 10); err == nil {
+		t.Fatal("expected a size mismatch error")
+	}
+	if _, err := s.Open(context.Background(), key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("a failed Put must not leave a blob, got %v", err)
+	}
+}
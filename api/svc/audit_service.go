@@ -0,0 +1,217 @@
+package svc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/middleware"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// Audited actions, named "<target type>.<verb>".
+const (
+	AuditUserCreate         = "user.create"
+	AuditUserUpdate         = "user.update"
+	AuditUserRateLimit      = "user.rate_limit"
+	AuditUserRoles          = "user.roles"
+	AuditUserPasswordChange = "user.password_change"
+	AuditUserPasswordReset  = "user.password_reset"
+	AuditUserTwoFactorReset = "user.2fa_reset"
+	AuditLoginUnlock        = "login.unlock"
+
+	AuditChatModelCreate     = "chat_model.create"
+	AuditChatModelUpdate     = "chat_model.update"
+	AuditChatModelDelete     = "chat_model.delete"
+	AuditChatModelCredential = "chat_model.credential"
+
+	AuditModelPrivilegeCreate = "model_privilege.create"
+	AuditModelPrivilegeUpdate = "model_privilege.update"
+	AuditModelPrivilegeDelete = "model_privilege.delete"
+
+	AuditCredentialCreate = "credential.create"
+	AuditCredentialUpdate = "credential.update"
+	AuditCredentialDelete = "credential.delete"
+	AuditCredentialRewrap = "credential.rewrap"
+
+	AuditQuotaPolicyCreate = "quota_policy.create"
+	AuditQuotaPolicyUpdate = "quota_policy.update"
+	AuditQuotaPolicyDelete = "quota_policy.delete"
+
+	// AuditSessionAdminRead is an admin reading another user's messages.
+	AuditSessionAdminRead = "session.admin_read"
+)
+
+// auditRedacted lists JSON fields whose values never reach the audit trail;
+// a change to them is recorded without the values.
+var auditRedacted = map[string]bool{
+	"password":   true,
+	"apiAuthKey": true,
+	"secret":     true,
+	"ciphertext": true,
+	"wrappedKey": true,
+}
+
+const auditRedactedValue = "[redacted]"
+
+// AuditEntry describes one audited change. Before and After are any values
+// marshalling to JSON objects (usually sqlc rows); nil stands for a record
+// that does not exist yet or any more.
+type AuditEntry struct {
+	Action     string
+	TargetType string
+	TargetID   string
+	Before     any
+	After      any
+	// ActorID is the user who made the change when no one is signed in,
+	// e.g. a password reset by email link. Zero uses the authenticated user.
+	ActorID int32
+}
+
+// AuditFilter selects audit events; zero fields match everything.
+type AuditFilter struct {
+	ActorEmail string
+	Action     string
+	TargetType string
+	TargetID   string
+	Since      time.Time
+	Until      time.Time
+	// BeforeID lists only events older than this one; it does not change the total.
+	BeforeID int64
+}
+
+// AuditService writes and reads the append-only audit trail. The actor, client
+// address and request ID are taken from the request context.
+type AuditService struct {
+	q *sqlc_queries.Queries
+}
+
+// NewAuditService creates a new AuditService.
+func NewAuditService(q *sqlc_queries.Queries) *AuditService {
+	return &AuditService{q: q}
+}
+
+// Q returns the underlying queries.
+func (s *AuditService) Q() *sqlc_queries.Queries { return s.q }
+
+// Record stores e. It is called after the change succeeded, so a failure is
+// logged rather than returned: the change cannot be undone at that point.
+func (s *AuditService) Record(ctx context.Context, e AuditEntry) {
+	changes, err := AuditChanges(e.Before, e.After)
+	if err != nil {
+		slog.Error("Failed to diff audit event", "action", e.Action, "error", err)
+		changes = json.RawMessage("{}")
+	}
+	params := sqlc_queries.CreateAuditEventParams{
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   truncateRunes(e.TargetID, 255),
+		Changes:    changes,
+		IpAddress:  truncateRunes(middleware.GetClientIP(ctx), 64),
+		RequestID:  truncateRunes(middleware.GetRequestID(ctx), 64),
+	}
+	actorID := e.ActorID
+	if actorID == 0 {
+		actorID, _ = middleware.GetUserID(ctx)
+	}
+	if actorID != 0 {
+		params.ActorUserID = sql.NullInt32{Int32: actorID, Valid: true}
+		if actor, err := s.q.GetAuthUserByID(ctx, actorID); err == nil {
+			params.ActorEmail = actor.Email
+		}
+	}
+	if _, err := s.q.CreateAuditEvent(ctx, params); err != nil {
+		slog.Error("Failed to record audit event", "action", e.Action, "target_type", e.TargetType, "target_id", e.TargetID, "error", err)
+	}
+}
+
+// List returns a page of audit events, newest first, and the total matching f.
+func (s *AuditService) List(ctx context.Context, f AuditFilter, limit, offset int32) ([]sqlc_queries.AuditEvent, int64, error) {
+	events, err := s.q.ListAuditEvents(ctx, sqlc_queries.ListAuditEventsParams{
+		ActorEmail: f.ActorEmail, Action: f.Action, TargetType: f.TargetType, TargetID: f.TargetID,
+		Since: nullTime(f.Since), Until: nullTime(f.Until), BeforeID: f.BeforeID,
+		PageLimit: limit, PageOffset: offset,
+	})
+	if err != nil {
+		return nil, 0, eris.Wrap(err, "failed to list audit events")
+	}
+	total, err := s.q.CountAuditEvents(ctx, sqlc_queries.CountAuditEventsParams{
+		ActorEmail: f.ActorEmail, Action: f.Action, TargetType: f.TargetType, TargetID: f.TargetID,
+		Since: nullTime(f.Since), Until: nullTime(f.Until),
+	})
+	if err != nil {
+		return nil, 0, eris.Wrap(err, "failed to count audit events")
+	}
+	return events, total, nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// AuditChanges returns the fields that differ between before and after as
+// {"field": {"before": ..., "after": ...}}. Values that are not JSON objects
+// are compared as a single field named "value".
+func AuditChanges(before, after any) (json.RawMessage, error) {
+	b, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+	changes := map[string]map[string]any{}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			a[key] = nil
+		}
+	}
+	for key, av := range a {
+		bv := b[key]
+		if reflect.DeepEqual(av, bv) {
+			continue
+		}
+		if auditRedacted[key] {
+			bv, av = redactAuditValue(bv), redactAuditValue(av)
+		}
+		changes[key] = map[string]any{"before": bv, "after": av}
+	}
+	out, err := json.Marshal(changes)
+	return out, eris.Wrap(err, "failed to marshal audit changes")
+}
+
+func auditFields(v any) (map[string]any, error) {
+	fields := map[string]any{}
+	if v == nil {
+		return fields, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to marshal audit value")
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(raw)), "{") {
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, eris.Wrap(err, "failed to decode audit value")
+		}
+		return fields, nil
+	}
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, eris.Wrap(err, "failed to decode audit value")
+	}
+	fields["value"] = value
+	return fields, nil
+}
+
+func redactAuditValue(v any) any {
+	if v == nil || v == "" {
+		return v
+	}
+	return auditRedactedValue
+}
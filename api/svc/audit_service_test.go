@@ -0,0 +1,55 @@
+package svc
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAuditChanges(t *testing.T) {
+	type model struct {
+		Name       string `json:"name"`
+		ApiAuthKey string `json:"apiAuthKey"`
+		MaxToken   int    `json:"maxToken"`
+	}
+	before := model{Name: "gpt", ApiAuthKey: "OPENAI_API_KEY", MaxToken: 4096}
+	after := model{Name: "gpt", ApiAuthKey: "OTHER_KEY", MaxToken: 8192}
+
+	raw, err := AuditChanges(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changes map[string]map[string]any
+	if err := json.Unmarshal(raw, &changes); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := changes["name"]; ok {
+		t.Error("unchanged fields should be left out")
+	}
+	if got := changes["maxToken"]; got["before"] != 4096.0 || got["after"] != 8192.0 {
+		t.Errorf("maxToken change = %v", got)
+	}
+	if got := changes["apiAuthKey"]; got["before"] != auditRedactedValue || got["after"] != auditRedactedValue {
+		t.Errorf("apiAuthKey should be redacted, got %v", got)
+	}
+}
+
+func TestAuditChangesCreateAndDelete(t *testing.T) {
+	created, err := AuditChanges(nil, map[string]any{"rateLimit": 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(created) != `{"rateLimit":{"after":10,"before":null}}` {
+		t.Errorf("create = %s", created)
+	}
+	deleted, err := AuditChanges(map[string]any{"rateLimit": 10}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(deleted) != `{"rateLimit":{"after":null,"before":10}}` {
+		t.Errorf("delete = %s", deleted)
+	}
+	same, _ := AuditChanges(map[string]int{"a": 1}, map[string]int{"a": 1})
+	if string(same) != `{}` {
+		t.Errorf("no change = %s", same)
+	}
+}
@@ -0,0 +1,186 @@
+package svc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/auth"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// Reasons recorded when a session is revoked.
+const (
+	SessionRevokedLogout = "logout"
+	SessionRevokedUser   = "user"
+	SessionRevokedAdmin  = "admin"
+	SessionRevokedReuse  = "reuse"
+	// SessionRevokedPasswordReset ends sessions when a password is reset by email.
+	SessionRevokedPasswordReset = "password_reset"
+	// SessionRevokedPasswordChange ends the other sessions when a user changes their password.
+	SessionRevokedPasswordChange = "password_change"
+)
+
+// RefreshReuseGrace is how long the token replaced by a rotation stays
+// acceptable, so two tabs refreshing at once do not look like token theft.
+const RefreshReuseGrace = 30 * time.Second
+
+// SessionDevice identifies the client a session belongs to.
+type SessionDevice struct {
+	UserAgent string
+	IP        string
+}
+
+// AuthSessionService tracks refresh tokens server-side, one session per signed-in device.
+// Only token hashes are stored.
+type AuthSessionService struct {
+	q *sqlc_queries.Queries
+}
+
+// NewAuthSessionService creates a new AuthSessionService.
+func NewAuthSessionService(q *sqlc_queries.Queries) *AuthSessionService {
+	return &AuthSessionService{q: q}
+}
+
+// Q returns the underlying queries.
+func (s *AuthSessionService) Q() *sqlc_queries.Queries { return s.q }
+
+// Create records a new session whose current refresh token is token.
+func (s *AuthSessionService) Create(ctx context.Context, sessionID string, userID int32, token string, device SessionDevice, expiresAt time.Time) error {
+	_, err := s.q.CreateAuthSession(ctx, sqlc_queries.CreateAuthSessionParams{
+		Uuid: sessionID, UserID: userID, TokenHash: hashToken(token),
+		UserAgent: truncateRunes(device.UserAgent, 512), IpAddress: device.IP, ExpiresAt: expiresAt,
+	})
+	return eris.Wrap(err, "failed to create session")
+}
+
+// Rotate replaces oldToken with newToken. It returns false without error when
+// oldToken was rotated moments ago by a parallel request: the caller should
+// then answer without setting a new cookie. Presenting any other stale token
+// is treated as theft and revokes the session.
+func (s *AuthSessionService) Rotate(ctx context.Context, sessionID, oldToken, newToken string, device SessionDevice, expiresAt time.Time) (bool, error) {
+	oldHash := hashToken(oldToken)
+	_, err := s.q.RotateAuthSession(ctx, sqlc_queries.RotateAuthSessionParams{
+		Uuid: sessionID, OldHash: oldHash, NewHash: hashToken(newToken),
+		UserAgent: truncateRunes(device.UserAgent, 512), IpAddress: device.IP, ExpiresAt: expiresAt,
+	})
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, eris.Wrap(err, "failed to rotate session")
+	}
+
+	sess, err := s.q.GetAuthSessionByUUID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, dto.ErrAuthInvalidCredentials.WithDetail("session not found")
+		}
+		return false, eris.Wrap(err, "failed to load session")
+	}
+	if sess.RevokedAt.Valid || !sess.ExpiresAt.After(time.Now()) {
+		return false, dto.ErrAuthInvalidCredentials.WithDetail("session has ended, please sign in again")
+	}
+	if sess.PreviousHash == oldHash && sess.RotatedAt.Valid && time.Since(sess.RotatedAt.Time) < RefreshReuseGrace {
+		if err := s.q.TouchAuthSession(ctx, sessionID); err != nil {
+			slog.Error("Failed to update session", "session", sessionID, "error", err)
+		}
+		return false, nil
+	}
+
+	if _, err := s.q.RevokeAuthSession(ctx, sqlc_queries.RevokeAuthSessionParams{
+		Uuid: sessionID, UserID: sess.UserID, Reason: SessionRevokedReuse,
+	}); err != nil {
+		return false, eris.Wrap(err, "failed to revoke session")
+	}
+	slog.Warn("Refresh token reuse detected, session revoked", "user_id", sess.UserID, "session", sessionID,
+		"ip", device.IP, "action", "refresh_reuse_detected")
+	return false, dto.ErrAuthInvalidCredentials.WithDetail("session has ended, please sign in again")
+}
+
+// APITokenUserAgent marks the session that API tokens of a user are bound to.
+const APITokenUserAgent = "API token"
+
+// APISession returns the user's active API token session, starting one that
+// lasts lifetime when there is none. Revoking it ends all of the user's API
+// tokens.
+func (s *AuthSessionService) APISession(ctx context.Context, userID int32, ip string, lifetime time.Duration) (sqlc_queries.AuthSession, error) {
+	sessions, err := s.List(ctx, userID)
+	if err != nil {
+		return sqlc_queries.AuthSession{}, err
+	}
+	for _, sess := range sessions {
+		if sess.UserAgent == APITokenUserAgent {
+			return sess, nil
+		}
+	}
+	sessionID := auth.NewUUID()
+	// API tokens are never rotated; the stored hash only has to be unique.
+	sess, err := s.q.CreateAuthSession(ctx, sqlc_queries.CreateAuthSessionParams{
+		Uuid: sessionID, UserID: userID, TokenHash: hashToken(sessionID),
+		UserAgent: APITokenUserAgent, IpAddress: ip, ExpiresAt: time.Now().Add(lifetime),
+	})
+	return sess, eris.Wrap(err, "failed to create session")
+}
+
+// SessionActive reports whether the session exists for userID and has been
+// neither revoked nor let expire.
+func (s *AuthSessionService) SessionActive(ctx context.Context, sessionID string, userID int32) (bool, error) {
+	sess, err := s.q.GetAuthSessionByUUID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, eris.Wrap(err, "failed to load session")
+	}
+	return sess.UserID == userID && !sess.RevokedAt.Valid && sess.ExpiresAt.After(time.Now()), nil
+}
+
+// List returns the user's active sessions, most recently used first.
+func (s *AuthSessionService) List(ctx context.Context, userID int32) ([]sqlc_queries.AuthSession, error) {
+	sessions, err := s.q.ListActiveAuthSessionsByUserID(ctx, userID)
+	return sessions, eris.Wrap(err, "failed to list sessions")
+}
+
+// Revoke ends one of the user's sessions.
+func (s *AuthSessionService) Revoke(ctx context.Context, userID int32, sessionID, reason string) error {
+	n, err := s.q.RevokeAuthSession(ctx, sqlc_queries.RevokeAuthSessionParams{Uuid: sessionID, UserID: userID, Reason: reason})
+	if err != nil {
+		return eris.Wrap(err, "failed to revoke session")
+	}
+	if n == 0 {
+		return dto.ErrResourceNotFound("session")
+	}
+	return nil
+}
+
+// RevokeAll ends every session of the user except keepSessionID, which may be
+// empty. Access tokens already issued stay valid until they expire.
+func (s *AuthSessionService) RevokeAll(ctx context.Context, userID int32, keepSessionID, reason string) (int64, error) {
+	n, err := s.q.RevokeAuthSessionsByUserID(ctx, sqlc_queries.RevokeAuthSessionsByUserIDParams{
+		UserID: userID, KeepUuid: keepSessionID, Reason: reason,
+	})
+	return n, eris.Wrap(err, "failed to revoke sessions")
+}
+
+// RunPurger deletes long-ended sessions every interval until ctx is done.
+func (s *AuthSessionService) RunPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.q.PurgeAuthSessions(ctx); err != nil {
+			slog.Error("session purge failed", "error", err)
+		} else if n > 0 {
+			slog.Info("purged ended sessions", "count", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
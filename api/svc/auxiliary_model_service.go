@@ -0,0 +1,348 @@
+package svc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/models"
+	"github.com/swuecho/chat_backend/provider"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/time/rate"
+)
+
+// AuxiliaryModelsKey is the app_setting key of the global auxiliary models.
+const AuxiliaryModelsKey = "auxiliary_models"
+
+// Auxiliary tasks run on behalf of a chat session.
+const (
+	AuxTaskTitle      = "title"
+	AuxTaskSuggestion = "suggestion"
+	AuxTaskSummary    = "summary"
+	// AuxTaskTranscription turns voice input into text, on a Whisper-compatible model.
+	AuxTaskTranscription = "transcription"
+	// AuxTaskSpeech reads answers aloud, on a model served at /audio/speech.
+	AuxTaskSpeech = "speech"
+	// AuxTaskImage answers the /image command, on a model of api_type image.
+	AuxTaskImage = "image"
+)
+
+// auxiliaryTimeout bounds one auxiliary request.
+const auxiliaryTimeout = 30 * time.Second
+
+const titleInstruction = `Generate a short title (3-6 words) for this conversation. Output ONLY the title text, no quotes, no markdown, no prefixes like "Title:". Example: "Python list comprehension guide"`
+
+const summaryInstruction = `Write a concise summary of the following text. Keep the key facts, names and conclusions. Output only the summary.`
+
+// AuxiliaryModels names the chat_model used for each auxiliary task.
+// An empty name leaves the task to the next level, or skips it.
+type AuxiliaryModels struct {
+	Title      string `json:"title"`
+	Suggestion string `json:"suggestion"`
+	Summary    string `json:"summary"`
+	// Transcription names a model served at /audio/transcriptions.
+	Transcription string `json:"transcription"`
+	// Speech names a model served at /audio/speech, such as tts-1.
+	Speech string `json:"speech"`
+	// Image names an image model, such as dall-e-3, for the /image command.
+	Image string `json:"image"`
+}
+
+// For returns the model configured for task.
+func (m AuxiliaryModels) For(task string) string {
+	switch task {
+	case AuxTaskTitle:
+		return m.Title
+	case AuxTaskSuggestion:
+		return m.Suggestion
+	case AuxTaskSummary:
+		return m.Summary
+	case AuxTaskTranscription:
+		return m.Transcription
+	case AuxTaskSpeech:
+		return m.Speech
+	case AuxTaskImage:
+		return m.Image
+	}
+	return ""
+}
+
+// AuxiliaryModelService runs session titles, follow-up suggestions,
+// summaries, voice transcription and speech on the models admins configure,
+// globally or per workspace.
+// It is its own provider.Handler so any provider can serve these tasks.
+type AuxiliaryModelService struct {
+	q   *sqlc_queries.Queries
+	cfg provider.Config
+	// quota checks and records the requests users make directly, such as
+	// transcription. Only the limits scoped to a workspace or model are
+	// checked here, and those have no default.
+	quota *QuotaService
+}
+
+// NewAuxiliaryModelService creates a new AuxiliaryModelService. Requests are
+// not throttled unless cfg carries a rate limiter.
+func NewAuxiliaryModelService(q *sqlc_queries.Queries, cfg provider.Config) *AuxiliaryModelService {
+	if cfg.RateLimiter == nil {
+		cfg.RateLimiter = rate.NewLimiter(rate.Inf, 0)
+	}
+	return &AuxiliaryModelService{q: q, cfg: cfg, quota: NewQuotaService(q, 0)}
+}
+
+// --- provider.Handler implementation ---
+
+func (s *AuxiliaryModelService) Queries() *sqlc_queries.Queries { return s.q }
+func (s *AuxiliaryModelService) Config() provider.Config        { return s.cfg }
+
+// CheckModelAccess only requires the model to be enabled: auxiliary requests
+// are made by the server and do not count against the user's model limits.
+func (s *AuxiliaryModelService) CheckModelAccess(ctx context.Context, chatSessionUuid, model string, userID int32) error {
+	chatModel, err := s.q.ChatModelByName(ctx, model)
+	if err != nil {
+		return dto.ErrResourceNotFound("chat model: " + model)
+	}
+	if !chatModel.IsEnable {
+		return dto.ErrValidationInvalidInput("chat model is disabled: " + model)
+	}
+	return nil
+}
+
+// userModelHandler serves the auxiliary requests a user makes directly, such
+// as transcription, which count against the user's model limits like chat.
+type userModelHandler struct{ *AuxiliaryModelService }
+
+// CheckModelAccess requires the model to be enabled and within the quotas
+// of the session's workspace and the model. The user-wide quotas were
+// checked by RateLimitByUserID before routing.
+func (h userModelHandler) CheckModelAccess(ctx context.Context, chatSessionUuid, model string, userID int32) error {
+	if err := h.AuxiliaryModelService.CheckModelAccess(ctx, chatSessionUuid, model, userID); err != nil {
+		return err
+	}
+	_, err := h.quota.CheckModelAccess(ctx, chatSessionUuid, model, userID)
+	return err
+}
+
+// --- Settings ---
+
+// Global returns the server-wide auxiliary models.
+func (s *AuxiliaryModelService) Global(ctx context.Context) (AuxiliaryModels, error) {
+	var m AuxiliaryModels
+	setting, err := s.q.GetAppSetting(ctx, AuxiliaryModelsKey)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return m, nil
+		}
+		return m, eris.Wrap(err, "failed to load auxiliary models")
+	}
+	if err := json.Unmarshal(setting.Value, &m); err != nil {
+		return m, eris.Wrap(err, "failed to decode auxiliary models")
+	}
+	return m, nil
+}
+
+// SetGlobal saves the server-wide auxiliary models.
+func (s *AuxiliaryModelService) SetGlobal(ctx context.Context, m AuxiliaryModels) error {
+	if err := s.validate(ctx, m); err != nil {
+		return err
+	}
+	value, err := json.Marshal(m)
+	if err != nil {
+		return eris.Wrap(err, "failed to encode auxiliary models")
+	}
+	return eris.Wrap(s.q.UpsertAppSetting(ctx, sqlc_queries.UpsertAppSettingParams{
+		Key: AuxiliaryModelsKey, Value: value,
+	}), "failed to save auxiliary models")
+}
+
+// Workspace returns the overrides of a workspace.
+func (s *AuxiliaryModelService) Workspace(ctx context.Context, workspaceUUID string) (AuxiliaryModels, error) {
+	var m AuxiliaryModels
+	ws, err := s.q.GetWorkspaceByUUID(ctx, workspaceUUID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return m, dto.ErrResourceNotFound("workspace")
+		}
+		return m, eris.Wrap(err, "failed to get workspace")
+	}
+	if err := json.Unmarshal(ws.AuxiliaryModels, &m); err != nil {
+		return m, eris.Wrap(err, "failed to decode auxiliary models")
+	}
+	return m, nil
+}
+
+// SetWorkspace saves the overrides of a workspace.
+func (s *AuxiliaryModelService) SetWorkspace(ctx context.Context, workspaceUUID string, m AuxiliaryModels) error {
+	if err := s.validate(ctx, m); err != nil {
+		return err
+	}
+	value, err := json.Marshal(m)
+	if err != nil {
+		return eris.Wrap(err, "failed to encode auxiliary models")
+	}
+	if _, err := s.q.UpdateWorkspaceAuxiliaryModels(ctx, sqlc_queries.UpdateWorkspaceAuxiliaryModelsParams{
+		Uuid: workspaceUUID, AuxiliaryModels: value,
+	}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return dto.ErrResourceNotFound("workspace")
+		}
+		return eris.Wrap(err, "failed to save auxiliary models")
+	}
+	return nil
+}
+
+// ModelFor returns the model for task in a session's workspace, falling back
+// to the global setting. It returns "" when the task is not configured.
+func (s *AuxiliaryModelService) ModelFor(ctx context.Context, workspaceID sql.NullInt32, task string) (string, error) {
+	if workspaceID.Valid {
+		raw, err := s.q.GetWorkspaceAuxiliaryModels(ctx, workspaceID.Int32)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return "", eris.Wrap(err, "failed to load workspace auxiliary models")
+		}
+		var m AuxiliaryModels
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &m); err != nil {
+				return "", eris.Wrap(err, "failed to decode workspace auxiliary models")
+			}
+		}
+		if name := m.For(task); name != "" {
+			return name, nil
+		}
+	}
+	global, err := s.Global(ctx)
+	if err != nil {
+		return "", err
+	}
+	return global.For(task), nil
+}
+
+// --- Tasks ---
+
+// Run sends instruction and input to the model configured for task and
+// returns its answer. It returns "" without error when no model is configured.
+func (s *AuxiliaryModelService) Run(ctx context.Context, task string, session sqlc_queries.ChatSession, instruction, input string) (string, error) {
+	ctx, span := tracing.Start(ctx, "AuxiliaryModelService.Run", attribute.String("chat.auxiliary_task", task), attribute.String("chat.session_uuid", session.Uuid))
+	defer span.End()
+	name, err := s.ModelFor(ctx, session.WorkspaceID, task)
+	if err != nil || name == "" {
+		return "", err
+	}
+	chatModel, err := s.q.ChatModelByName(ctx, name)
+	if err != nil {
+		return "", eris.Wrapf(err, "failed to get auxiliary model %s", name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, auxiliaryTimeout)
+	defer cancel()
+
+	// The session only carries what providers read; it has no uuid so the
+	// chat's files are not attached.
+	auxSession := sqlc_queries.ChatSession{
+		UserID: session.UserID, Model: chatModel.Name, WorkspaceID: session.WorkspaceID,
+		Temperature: 0.3, TopP: 1, N: 1, MaxTokens: 1024,
+	}
+	messages := []models.Message{
+		{Role: "system", Content: instruction},
+		{Role: "user", Content: input},
+	}
+	answer, err := provider.Complete(ctx, provider.NewChatModel(chatModel.ApiType, s), auxSession, messages)
+	if err != nil {
+		return "", eris.Wrapf(err, "auxiliary %s request to %s failed", task, chatModel.Name)
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// GenerateTitle returns a short title for a conversation, or "" when no
+// title model is configured or it fails.
+func (s *AuxiliaryModelService) GenerateTitle(ctx context.Context, session sqlc_queries.ChatSession, chatText string) string {
+	if strings.TrimSpace(chatText) == "" {
+		return ""
+	}
+	title, err := s.Run(ctx, AuxTaskTitle, session, titleInstruction, chatText)
+	if err != nil {
+		slog.Warn("Failed to generate title", "session", session.Uuid, "error", err)
+		return ""
+	}
+	return cleanTitle(title)
+}
+
+// cleanTitle strips the quotes, markdown and "Title:" prefixes models add despite being asked not to.
+func cleanTitle(title string) string {
+	title = strings.Trim(strings.TrimSpace(title), `"*#`)
+	for _, prefix := range []string{"Title:", "title:"} {
+		title = strings.TrimPrefix(title, prefix)
+	}
+	title = strings.TrimSpace(title)
+	for strings.HasPrefix(title, "#") || strings.HasPrefix(title, "-") || strings.HasPrefix(title, "*") {
+		title = strings.TrimSpace(strings.TrimLeft(title, "#-* "))
+	}
+	return provider.FirstN(title, 100)
+}
+
+// Summarize returns a summary of content, or "" when no summary model is
+// configured or it fails.
+func (s *AuxiliaryModelService) Summarize(ctx context.Context, session sqlc_queries.ChatSession, content string) string {
+	summary, err := s.Run(ctx, AuxTaskSummary, session, summaryInstruction, content)
+	if err != nil {
+		slog.Warn("Failed to summarize message", "session", session.Uuid, "error", err)
+		return ""
+	}
+	return summary
+}
+
+// Transcribe returns the text of the recording audio, named filename, made
+// by userID in session. language is an optional ISO-639-1 hint. The request
+// is subject to the user's model limits and counted towards their quotas.
+func (s *AuxiliaryModelService) Transcribe(ctx context.Context, session sqlc_queries.ChatSession, userID int32, audio io.Reader, filename, language string) (string, error) {
+	ctx, span := tracing.Start(ctx, "AuxiliaryModelService.Transcribe", attribute.String("chat.session_uuid", session.Uuid))
+	defer span.End()
+	name, err := s.ModelFor(ctx, session.WorkspaceID, AuxTaskTranscription)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", dto.ErrValidationInvalidInput("no transcription model is configured")
+	}
+	chatModel, err := s.q.ChatModelByName(ctx, name)
+	if err != nil {
+		return "", eris.Wrapf(err, "failed to get transcription model %s", name)
+	}
+	text, err := provider.Transcribe(ctx, userModelHandler{s}, session, userID, chatModel, audio, filename, language)
+	if err != nil {
+		return "", err
+	}
+	// the transcript is counted even when it is sent on as a chat message,
+	// as that is a second request to a model
+	tokens := int32(len(text)) / dto.TokenEstimateRatio
+	if err := s.quota.RecordUsage(ctx, userID, session.WorkspaceID, chatModel.Name, UsageSourceTranscription, tokens); err != nil {
+		slog.Warn("Failed to record transcription usage", "session", session.Uuid, "error", err)
+	}
+	return text, nil
+}
+
+func (s *AuxiliaryModelService) validate(ctx context.Context, m AuxiliaryModels) error {
+	for _, name := range []string{m.Title, m.Suggestion, m.Summary, m.Transcription, m.Speech, m.Image} {
+		if name == "" {
+			continue
+		}
+		chatModel, err := s.q.ChatModelByName(ctx, name)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return dto.ErrValidationInvalidInput("unknown chat model: " + name)
+			}
+			return eris.Wrap(err, "failed to get chat model")
+		}
+		if name == m.Image && chatModel.ApiType != "image" {
+			return dto.ErrValidationInvalidInput("not an image model: " + name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package svc
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/swuecho/chat_backend/provider"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+func TestAuxiliaryModelsFor(t *testing.T) {
+	m := AuxiliaryModels{Title: "gpt-4o-mini", Summary: "claude-haiku", Transcription: "whisper-1", Image: "dall-e-3"}
+	if m.For(AuxTaskTitle) != "gpt-4o-mini" || m.For(AuxTaskSummary) != "claude-haiku" || m.For(AuxTaskTranscription) != "whisper-1" || m.For(AuxTaskImage) != "dall-e-3" {
+		t.Errorf("unexpected models: %+v", m)
+	}
+	if m.For(AuxTaskSuggestion) != "" || m.For("other") != "" {
+		t.Error("unset tasks should have no model")
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	cases := map[string]string{
+		`"Python list comprehension guide"`: "Python list comprehension guide",
+		"Title: Go generics":                "Go generics",
+		"## **Rust lifetimes**":             "Rust lifetimes",
+		"- Docker networking":               "Docker networking",
+	}
+	for in, want := range cases {
+		if got := cleanTitle(in); got != want {
+			t.Errorf("cleanTitle(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestUserModelHandlerAppliesModelQuota(t *testing.T) {
+	q := sqlc_queries.New(testDB)
+	ctx := context.Background()
+	model, err := q.CreateChatModel(ctx, sqlc_queries.CreateChatModelParams{Name: "whisper-quota-test", Label: "Whisper", ApiType: "openai"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.CreateQuotaPolicy(ctx, sqlc_queries.CreateQuotaPolicyParams{
+		Name:        "one transcription an hour",
+		ChatModelID: sql.NullInt32{Int32: model.ID, Valid: true},
+		TimeWindow:  "hour",
+		Metric:      QuotaMetricRequests,
+		QuotaLimit:  1,
+		Enabled:     true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewAuxiliaryModelService(q, provider.Config{})
+	user := userModelHandler{s}
+	if err := user.CheckModelAccess(ctx, "", model.Name, 1); err != nil {
+		t.Fatalf("first transcription denied: %v", err)
+	}
+	if err := s.quota.RecordUsage(ctx, 1, sql.NullInt32{}, model.Name, UsageSourceTranscription, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := user.CheckModelAccess(ctx, "", model.Name, 1); err == nil {
+		t.Error("expected the model quota to deny a second transcription")
+	}
+	if err := s.CheckModelAccess(ctx, "", model.Name, 1); err != nil {
+		t.Errorf("server-made requests should not be limited: %v", err)
+	}
+}
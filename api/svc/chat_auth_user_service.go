@@ -3,6 +3,7 @@ package svc
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -14,6 +15,9 @@ import (
 	"github.com/swuecho/chat_backend/sqlc_queries"
 )
 
+// MinPasswordLength is the shortest password a user may set.
+const MinPasswordLength = 8
+
 type AuthUserService struct {
 	q            *sqlc_queries.Queries
 	jwtSecret    string
@@ -46,9 +50,21 @@ func (s *AuthUserService) CreateAuthUser(ctx context.Context, auth_user_params s
 	if err != nil {
 		return sqlc_queries.AuthUser{}, err
 	}
+	NewAuditService(s.q).Record(ctx, AuditEntry{
+		Action: AuditUserCreate, TargetType: "user", TargetID: auth_user.Email, After: auth_user,
+	})
 	return auth_user, nil
 }
 
+// HasUsers reports whether any active user exists.
+func (s *AuthUserService) HasUsers(ctx context.Context) (bool, error) {
+	n, err := s.q.GetTotalActiveUserCount(ctx)
+	if err != nil {
+		return false, eris.Wrap(err, "failed to count users")
+	}
+	return n > 0, nil
+}
+
 // GetAuthUserByID returns an authentication user record by ID.
 func (s *AuthUserService) GetAuthUserByID(ctx context.Context, id int32) (sqlc_queries.AuthUser, error) {
 	auth_user, err := s.q.GetAuthUserByID(ctx, id)
@@ -116,7 +132,7 @@ func (s *AuthUserService) UpdateRateLimit(ctx context.Context, user_email string
 		Email:     user_email,
 		RateLimit: rate_limit,
 	}
-	rate, err := s.q.UpdateAuthUserRateLimitByEmail(ctx, auth_user_params)
+	rate, err := s.UpdateAuthUserRateLimitByEmail(ctx, auth_user_params)
 	if err != nil {
 		return -1, errors.New("failed to update authentication user")
 	}
@@ -129,9 +145,22 @@ func (s *AuthUserService) UpdateAuthUser(ctx context.Context, params sqlc_querie
 	return s.q.UpdateAuthUser(ctx, params)
 }
 
-// UpdateAuthUserByEmail wraps the raw query for handler use.
+// UpdateAuthUserByEmail updates another user's name (admin only).
 func (s *AuthUserService) UpdateAuthUserByEmail(ctx context.Context, params sqlc_queries.UpdateAuthUserByEmailParams) (sqlc_queries.UpdateAuthUserByEmailRow, error) {
-	return s.q.UpdateAuthUserByEmail(ctx, params)
+	before, err := s.q.GetUserByEmail(ctx, params.Email)
+	if err != nil {
+		return sqlc_queries.UpdateAuthUserByEmailRow{}, err
+	}
+	user, err := s.q.UpdateAuthUserByEmail(ctx, params)
+	if err != nil {
+		return user, err
+	}
+	NewAuditService(s.q).Record(ctx, AuditEntry{
+		Action: AuditUserUpdate, TargetType: "user", TargetID: user.Email,
+		Before: sqlc_queries.UpdateAuthUserByEmailRow{FirstName: before.FirstName, LastName: before.LastName, Email: before.Email},
+		After:  user,
+	})
+	return user, nil
 }
 
 // GetUserByEmail wraps the raw query for handler use.
@@ -139,14 +168,100 @@ func (s *AuthUserService) GetUserByEmail(ctx context.Context, email string) (sql
 	return s.q.GetUserByEmail(ctx, email)
 }
 
-// UpdateUserPassword wraps the raw query for handler use.
-func (s *AuthUserService) UpdateUserPassword(ctx context.Context, params sqlc_queries.UpdateUserPasswordParams) error {
-	return s.q.UpdateUserPassword(ctx, params)
+// ChangePassword replaces the user's password after checking the current one
+// and ends every other session of the user, keeping keepSessionID, which may
+// be empty.
+func (s *AuthUserService) ChangePassword(ctx context.Context, userID int32, currentPassword, newPassword, keepSessionID string) error {
+	user, err := s.q.GetAuthUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !auth.ValidatePassword(currentPassword, user.Password) {
+		return dto.ErrAuthInvalidCredentials.WithMessage("current password is incorrect")
+	}
+	if len(newPassword) < MinPasswordLength {
+		return dto.ErrValidationInvalidInput(fmt.Sprintf("password must be at least %d characters", MinPasswordLength))
+	}
+	hash, err := auth.GeneratePasswordHash(newPassword)
+	if err != nil {
+		return eris.Wrap(err, "failed to hash password")
+	}
+	if err := s.q.UpdateUserPasswordByID(ctx, sqlc_queries.UpdateUserPasswordByIDParams{ID: user.ID, Password: hash}); err != nil {
+		return eris.Wrap(err, "failed to update password")
+	}
+	if _, err := s.q.RevokeAuthSessionsByUserID(ctx, sqlc_queries.RevokeAuthSessionsByUserIDParams{
+		UserID: user.ID, KeepUuid: keepSessionID, Reason: SessionRevokedPasswordChange,
+	}); err != nil {
+		return eris.Wrap(err, "failed to revoke sessions")
+	}
+	NewAuditService(s.q).Record(ctx, AuditEntry{
+		Action: AuditUserPasswordChange, TargetType: "user", TargetID: user.Email,
+		Before: map[string]string{"password": "old"}, After: map[string]string{"password": "new"},
+	})
+	return nil
+}
+
+// SetRoles grants or revokes superuser and staff rights (admin only).
+func (s *AuthUserService) SetRoles(ctx context.Context, email string, superuser, staff bool) (sqlc_queries.AuthUser, error) {
+	before, err := s.q.GetUserByEmail(ctx, email)
+	if err != nil {
+		return before, err
+	}
+	user, err := s.q.UpdateAuthUserRoles(ctx, sqlc_queries.UpdateAuthUserRolesParams{
+		ID: before.ID, IsSuperuser: superuser, IsStaff: staff,
+	})
+	if err != nil {
+		return user, eris.Wrap(err, "failed to update user roles")
+	}
+	NewAuditService(s.q).Record(ctx, AuditEntry{
+		Action: AuditUserRoles, TargetType: "user", TargetID: user.Email, Before: before, After: user,
+	})
+	return user, nil
 }
 
-// UpdateAuthUserRateLimitByEmail wraps the raw query.
+// ResetPassword sets a new password for another user (admin only) and signs
+// them out everywhere.
+func (s *AuthUserService) ResetPassword(ctx context.Context, email, password string) error {
+	user, err := s.q.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	hash, err := auth.GeneratePasswordHash(password)
+	if err != nil {
+		return eris.Wrap(err, "failed to hash password")
+	}
+	if err := s.q.UpdateUserPasswordByID(ctx, sqlc_queries.UpdateUserPasswordByIDParams{ID: user.ID, Password: hash}); err != nil {
+		return eris.Wrap(err, "failed to update password")
+	}
+	if _, err := s.q.RevokeAuthSessionsByUserID(ctx, sqlc_queries.RevokeAuthSessionsByUserIDParams{
+		UserID: user.ID, Reason: SessionRevokedAdmin,
+	}); err != nil {
+		return eris.Wrap(err, "failed to revoke sessions")
+	}
+	NewAuditService(s.q).Record(ctx, AuditEntry{
+		Action: AuditUserPasswordReset, TargetType: "user", TargetID: user.Email,
+		Before: map[string]string{"password": "old"}, After: map[string]string{"password": "new"},
+	})
+	return nil
+}
+
+// UpdateAuthUserRateLimitByEmail sets a user's rate limit (admin only).
 func (s *AuthUserService) UpdateAuthUserRateLimitByEmail(ctx context.Context, params sqlc_queries.UpdateAuthUserRateLimitByEmailParams) (int32, error) {
-	return s.q.UpdateAuthUserRateLimitByEmail(ctx, params)
+	var before any
+	if user, err := s.q.GetUserByEmail(ctx, params.Email); err == nil {
+		if rate, err := s.q.GetRateLimit(ctx, user.ID); err == nil {
+			before = map[string]int32{"rateLimit": rate}
+		}
+	}
+	rate, err := s.q.UpdateAuthUserRateLimitByEmail(ctx, params)
+	if err != nil {
+		return rate, err
+	}
+	NewAuditService(s.q).Record(ctx, AuditEntry{
+		Action: AuditUserRateLimit, TargetType: "user", TargetID: params.Email,
+		Before: before, After: map[string]int32{"rateLimit": rate},
+	})
+	return rate, nil
 }
 
 // GetRateLimit wraps the raw query for handler use.
@@ -0,0 +1,54 @@
+package svc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/swuecho/chat_backend/auth"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+func TestChangePassword(t *testing.T) {
+	q := sqlc_queries.New(testDB)
+	ctx := context.Background()
+	users := NewAuthUserService(q, "test-secret", 100)
+	sessions := NewAuthSessionService(q)
+
+	hash, err := auth.GeneratePasswordHash("old-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := q.CreateAuthUser(ctx, sqlc_queries.CreateAuthUserParams{
+		Email: "change-password@test.com", Username: "change-password@test.com", Password: hash,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sid := range []string{"change-password-current", "change-password-other"} {
+		if err := sessions.Create(ctx, sid, user.ID, sid, SessionDevice{}, time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := users.ChangePassword(ctx, user.ID, "wrong-password", "new-password", ""); err == nil {
+		t.Error("changed the password without the current one")
+	}
+	if err := users.ChangePassword(ctx, user.ID, "old-password", "short", ""); err == nil {
+		t.Error("accepted a password shorter than MinPasswordLength")
+	}
+	if err := users.ChangePassword(ctx, user.ID, "old-password", "new-password", "change-password-current"); err != nil {
+		t.Fatalf("failed to change password: %v", err)
+	}
+
+	if _, err := users.Authenticate(ctx, user.Email, "new-password"); err != nil {
+		t.Errorf("new password rejected: %v", err)
+	}
+	active, err := sessions.List(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 1 || active[0].Uuid != "change-password-current" {
+		t.Errorf("active sessions after change = %+v, want only the current one", active)
+	}
+}
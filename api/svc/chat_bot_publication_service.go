@@ -0,0 +1,166 @@
+package svc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/models"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// Chatbot access modes.
+const (
+	BotAccessDisabled  = "disabled"
+	BotAccessAnonymous = "anonymous"
+	BotAccessToken     = "token"
+)
+
+// DefaultBotRateLimit is the number of answers per hour a published chatbot
+// gives when no rate limit is set. Public bots are always limited.
+const DefaultBotRateLimit = 60
+
+// botVisitorHistoryLimit bounds how many previous turns are replayed for a visitor.
+const botVisitorHistoryLimit = 10
+
+// ChatBotPublicationService manages public access to chatbots.
+type ChatBotPublicationService struct {
+	q *sqlc_queries.Queries
+}
+
+// NewChatBotPublicationService creates a new ChatBotPublicationService.
+func NewChatBotPublicationService(q *sqlc_queries.Queries) *ChatBotPublicationService {
+	return &ChatBotPublicationService{q: q}
+}
+
+// Q returns the underlying queries.
+func (s *ChatBotPublicationService) Q() *sqlc_queries.Queries { return s.q }
+
+// GetPublication returns the publish settings of a bot, or a disabled default if none were saved.
+func (s *ChatBotPublicationService) GetPublication(ctx context.Context, botUUID string) (sqlc_queries.ChatBotPublication, error) {
+	p, err := s.q.GetChatBotPublicationByBotUUID(ctx, botUUID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sqlc_queries.ChatBotPublication{BotUuid: botUUID, AccessMode: BotAccessDisabled, AllowedOrigins: []string{}}, nil
+		}
+		return p, eris.Wrap(err, "failed to retrieve chatbot publication")
+	}
+	return p, nil
+}
+
+// SavePublication creates or updates the publish settings of a bot.
+func (s *ChatBotPublicationService) SavePublication(ctx context.Context, params sqlc_queries.UpsertChatBotPublicationParams) (sqlc_queries.ChatBotPublication, error) {
+	params.RateLimit = botRateLimit(params.RateLimit)
+	p, err := s.q.UpsertChatBotPublication(ctx, params)
+	return p, eris.Wrap(err, "failed to save chatbot publication")
+}
+
+// RotateToken generates a new access token and stores its hash. The plain token is only returned here.
+func (s *ChatBotPublicationService) RotateToken(ctx context.Context, botUUID string) (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", eris.Wrap(err, "failed to generate bot token")
+	}
+	token := "bot_" + hex.EncodeToString(buf)
+	if _, err := s.q.UpdateChatBotPublicationToken(ctx, sqlc_queries.UpdateChatBotPublicationTokenParams{
+		BotUuid: botUUID, TokenHash: hashToken(token),
+	}); err != nil {
+		return "", eris.Wrap(err, "failed to store bot token")
+	}
+	return token, nil
+}
+
+// Authorize checks the bot is published and the caller's token and origin are allowed.
+func (s *ChatBotPublicationService) Authorize(p sqlc_queries.ChatBotPublication, token, origin string) error {
+	switch p.AccessMode {
+	case BotAccessAnonymous:
+	case BotAccessToken:
+		if p.TokenHash == "" || token == "" ||
+			subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(p.TokenHash)) != 1 {
+			return dto.ErrAuthInvalidCredentials.WithMessage("invalid bot access token")
+		}
+	default:
+		return dto.ErrResourceNotFound("chatbot")
+	}
+	if origin != "" && !OriginAllowed(p.AllowedOrigins, origin) {
+		return dto.ErrAuthAccessDenied.WithMessage("origin not allowed for this chatbot")
+	}
+	return nil
+}
+
+// CheckRateLimit returns ErrTooManyRequests when the bot answered rate_limit
+// times in the last hour, or DefaultBotRateLimit times when none is set.
+func (s *ChatBotPublicationService) CheckRateLimit(ctx context.Context, p sqlc_queries.ChatBotPublication) error {
+	limit := botRateLimit(p.RateLimit)
+	count, err := s.q.CountBotAnswersSince(ctx, sqlc_queries.CountBotAnswersSinceParams{
+		BotUuid: p.BotUuid, CreatedAt: time.Now().Add(-time.Hour),
+	})
+	if err != nil {
+		return eris.Wrap(err, "failed to count bot answers")
+	}
+	if count >= int64(limit) {
+		return dto.ErrTooManyRequests.WithDetail(fmt.Sprintf("This chatbot is limited to %d answers per hour", limit))
+	}
+	return nil
+}
+
+// botRateLimit returns the hourly answer limit for a configured rate limit;
+// zero means DefaultBotRateLimit rather than no limit.
+func botRateLimit(limit int32) int32 {
+	if limit <= 0 {
+		return DefaultBotRateLimit
+	}
+	return limit
+}
+
+// ListVisitorTurns returns the visitor's most recent answers, newest first.
+func (s *ChatBotPublicationService) ListVisitorTurns(ctx context.Context, botUUID, visitorID string) ([]sqlc_queries.BotAnswerHistory, error) {
+	rows, err := s.q.GetBotVisitorHistory(ctx, sqlc_queries.GetBotVisitorHistoryParams{
+		BotUuid: botUUID, VisitorID: visitorID, Limit: botVisitorHistoryLimit,
+	})
+	return rows, eris.Wrap(err, "failed to get visitor history")
+}
+
+// VisitorHistory returns the visitor's recent turns as chronological user/assistant messages.
+func (s *ChatBotPublicationService) VisitorHistory(ctx context.Context, botUUID, visitorID string) ([]models.Message, error) {
+	rows, err := s.ListVisitorTurns(ctx, botUUID, visitorID)
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]models.Message, 0, len(rows)*2)
+	for i := len(rows) - 1; i >= 0; i-- {
+		msgs = append(msgs,
+			models.Message{Role: "user", Content: rows[i].Prompt},
+			models.Message{Role: "assistant", Content: rows[i].Answer})
+	}
+	return msgs, nil
+}
+
+// OriginAllowed reports whether origin matches one of the allowed origins.
+// An empty list allows every origin.
+func OriginAllowed(allowed []string, origin string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == "*" || strings.EqualFold(strings.TrimRight(a, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashToken returns the sha256 hex digest stored in place of a secret token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
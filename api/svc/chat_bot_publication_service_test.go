@@ -0,0 +1,60 @@
+package svc
+
+import (
+	"testing"
+
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+func TestOriginAllowed(t *testing.T) {
+	tests := []struct {
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{nil, "https://example.com", true},
+		{[]string{"https://example.com"}, "https://example.com", true},
+		{[]string{"https://Example.com/"}, "https://example.com", true},
+		{[]string{"https://example.com"}, "https://evil.com", false},
+		{[]string{"*"}, "https://evil.com", true},
+	}
+	for _, tt := range tests {
+		if got := OriginAllowed(tt.allowed, tt.origin); got != tt.want {
+			t.Errorf("OriginAllowed(%v, %q) = %v; want %v", tt.allowed, tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestAuthorizeBotAccess(t *testing.T) {
+	s := &ChatBotPublicationService{}
+	tokenBot := sqlc_queries.ChatBotPublication{AccessMode: BotAccessToken, TokenHash: hashToken("bot_secret")}
+
+	if err := s.Authorize(tokenBot, "bot_secret", ""); err != nil {
+		t.Errorf("valid token rejected: %v", err)
+	}
+	if err := s.Authorize(tokenBot, "bot_wrong", ""); err == nil {
+		t.Error("wrong token accepted")
+	}
+	if err := s.Authorize(sqlc_queries.ChatBotPublication{AccessMode: BotAccessToken}, "", ""); err == nil {
+		t.Error("token bot without token accepted")
+	}
+	if err := s.Authorize(sqlc_queries.ChatBotPublication{AccessMode: BotAccessDisabled}, "", ""); err == nil {
+		t.Error("disabled bot accepted")
+	}
+
+	anon := sqlc_queries.ChatBotPublication{AccessMode: BotAccessAnonymous, AllowedOrigins: []string{"https://example.com"}}
+	if err := s.Authorize(anon, "", "https://example.com"); err != nil {
+		t.Errorf("allowed origin rejected: %v", err)
+	}
+	if err := s.Authorize(anon, "", "https://evil.com"); err == nil {
+		t.Error("disallowed origin accepted")
+	}
+}
+
+func TestBotRateLimit(t *testing.T) {
+	for in, want := range map[int32]int32{0: DefaultBotRateLimit, -1: DefaultBotRateLimit, 5: 5} {
+		if got := botRateLimit(in); got != want {
+			t.Errorf("botRateLimit(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// ChatBotVersionService manages chatbot versions and their knowledge files.
+type ChatBotVersionService struct {
+	q *sqlc_queries.Queries
+}
+
+// NewChatBotVersionService creates a new ChatBotVersionService.
+func NewChatBotVersionService(q *sqlc_queries.Queries) *ChatBotVersionService {
+	return &ChatBotVersionService{q: q}
+}
+
+// Q returns the underlying queries.
+func (s *ChatBotVersionService) Q() *sqlc_queries.Queries { return s.q }
+
+// ChatBotEdit describes the content of a new chatbot version.
+type ChatBotEdit struct {
+	SystemPrompt string
+	Examples     []dto.SimpleChatMessage
+	Model        string
+	FileIDs      []int32
+	Note         string
+}
+
+// EnsureBaseline records the bot's current content as version 1 for bots
+// created before versioning. Plain snapshots have no versions.
+func (s *ChatBotVersionService) EnsureBaseline(ctx context.Context, bot sqlc_queries.ChatSnapshot, userID int32) (sqlc_queries.ChatSnapshot, error) {
+	if bot.BotVersion > 0 || bot.Typ != "chatbot" {
+		return bot, nil
+	}
+	v, err := s.q.CreateChatBotVersion(ctx, sqlc_queries.CreateChatBotVersionParams{
+		BotUuid: bot.Uuid, Model: bot.Model, Conversation: bot.Conversation,
+		FileIds: []int32{}, Note: "initial version", CreatedBy: userID,
+	})
+	if err != nil {
+		return bot, eris.Wrap(err, "failed to create baseline chatbot version")
+	}
+	return s.apply(ctx, bot.Uuid, v, bot.Text)
+}
+
+// CreateVersion stores a new version of the bot and makes it current.
+func (s *ChatBotVersionService) CreateVersion(ctx context.Context, bot sqlc_queries.ChatSnapshot, userID int32, edit ChatBotEdit) (sqlc_queries.ChatBotVersion, error) {
+	if _, err := s.EnsureBaseline(ctx, bot, userID); err != nil {
+		return sqlc_queries.ChatBotVersion{}, err
+	}
+	if err := s.checkFiles(ctx, bot.Uuid, edit.FileIDs); err != nil {
+		return sqlc_queries.ChatBotVersion{}, err
+	}
+
+	msgs := make([]dto.SimpleChatMessage, 0, len(edit.Examples)+1)
+	msgs = append(msgs, dto.SimpleChatMessage{Text: edit.SystemPrompt, IsPrompt: true})
+	msgs = append(msgs, edit.Examples...)
+	conversation, err := json.Marshal(msgs)
+	if err != nil {
+		return sqlc_queries.ChatBotVersion{}, eris.Wrap(err, "failed to encode conversation")
+	}
+
+	fileIDs := edit.FileIDs
+	if fileIDs == nil {
+		fileIDs = []int32{}
+	}
+	v, err := s.q.CreateChatBotVersion(ctx, sqlc_queries.CreateChatBotVersionParams{
+		BotUuid: bot.Uuid, Model: edit.Model, Conversation: conversation,
+		FileIds: fileIDs, Note: edit.Note, CreatedBy: userID,
+	})
+	if err != nil {
+		return v, eris.Wrap(err, "failed to create chatbot version")
+	}
+	if _, err := s.apply(ctx, bot.Uuid, v, conversationText(msgs)); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+// Restore makes an earlier version current again.
+func (s *ChatBotVersionService) Restore(ctx context.Context, botUUID string, version int32) (sqlc_queries.ChatBotVersion, error) {
+	v, err := s.GetVersion(ctx, botUUID, version)
+	if err != nil {
+		return v, err
+	}
+	var msgs []dto.SimpleChatMessage
+	if err := json.Unmarshal(v.Conversation, &msgs); err != nil {
+		return v, eris.Wrap(err, "failed to decode conversation")
+	}
+	_, err = s.apply(ctx, botUUID, v, conversationText(msgs))
+	return v, err
+}
+
+func (s *ChatBotVersionService) GetVersion(ctx context.Context, botUUID string, version int32) (sqlc_queries.ChatBotVersion, error) {
+	v, err := s.q.GetChatBotVersion(ctx, sqlc_queries.GetChatBotVersionParams{BotUuid: botUUID, Version: version})
+	return v, eris.Wrap(err, "failed to retrieve chatbot version")
+}
+
+func (s *ChatBotVersionService) ListVersions(ctx context.Context, botUUID string) ([]sqlc_queries.ChatBotVersion, error) {
+	vs, err := s.q.ListChatBotVersions(ctx, botUUID)
+	return vs, eris.Wrap(err, "failed to list chatbot versions")
+}
+
+func (s *ChatBotVersionService) CreateFile(ctx context.Context, params sqlc_queries.CreateChatBotFileParams) (sqlc_queries.ChatBotFile, error) {
+	f, err := s.q.CreateChatBotFile(ctx, params)
+	return f, eris.Wrap(err, "failed to create chatbot file")
+}
+
+func (s *ChatBotVersionService) ListFiles(ctx context.Context, botUUID string) ([]sqlc_queries.ListChatBotFilesRow, error) {
+	fs, err := s.q.ListChatBotFiles(ctx, botUUID)
+	return fs, eris.Wrap(err, "failed to list chatbot files")
+}
+
+// Knowledge returns the knowledge files of the bot's current version formatted for the system prompt.
+func (s *ChatBotVersionService) Knowledge(ctx context.Context, bot sqlc_queries.ChatSnapshot) (string, error) {
+	if bot.BotVersion == 0 {
+		return "", nil
+	}
+	v, err := s.GetVersion(ctx, bot.Uuid, bot.BotVersion)
+	if err != nil {
+		return "", err
+	}
+	if len(v.FileIds) == 0 {
+		return "", nil
+	}
+	files, err := s.q.GetChatBotFilesByIDs(ctx, sqlc_queries.GetChatBotFilesByIDsParams{BotUuid: bot.Uuid, Ids: v.FileIds})
+	if err != nil {
+		return "", eris.Wrap(err, "failed to load chatbot knowledge")
+	}
+	return FormatBotKnowledge(files), nil
+}
+
+func (s *ChatBotVersionService) checkFiles(ctx context.Context, botUUID string, ids []int32) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	files, err := s.q.GetChatBotFilesByIDs(ctx, sqlc_queries.GetChatBotFilesByIDsParams{BotUuid: botUUID, Ids: ids})
+	if err != nil {
+		return eris.Wrap(err, "failed to load chatbot files")
+	}
+	if len(files) != len(ids) {
+		return dto.ErrValidationInvalidInput("unknown knowledge file for this chatbot")
+	}
+	return nil
+}
+
+func (s *ChatBotVersionService) apply(ctx context.Context, botUUID string, v sqlc_queries.ChatBotVersion, text string) (sqlc_queries.ChatSnapshot, error) {
+	bot, err := s.q.ApplyChatBotVersion(ctx, sqlc_queries.ApplyChatBotVersionParams{
+		Conversation: v.Conversation, Model: v.Model, Text: text, BotVersion: v.Version, Uuid: botUUID,
+	})
+	return bot, eris.Wrap(err, "failed to apply chatbot version")
+}
+
+// FormatBotKnowledge renders knowledge files as a block appended to the bot's system prompt.
+func FormatBotKnowledge(files []sqlc_queries.ChatBotFile) string {
+	if len(files) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Use the following reference documents when answering.\n")
+	for _, f := range files {
+		fmt.Fprintf(&b, "\n<document name=%q>\n%s\n</document>\n", f.Name, strings.TrimSpace(f.Content))
+	}
+	return b.String()
+}
+
+func conversationText(msgs []dto.SimpleChatMessage) string {
+	var b strings.Builder
+	for _, m := range msgs {
+		b.WriteString(m.Text)
+	}
+	return b.String()
+}
@@ -0,0 +1,27 @@
+package svc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+func TestFormatBotKnowledge(t *testing.T) {
+	if got := FormatBotKnowledge(nil); got != "" {
+		t.Errorf("FormatBotKnowledge(nil) = %q; want empty", got)
+	}
+
+	got := FormatBotKnowledge([]sqlc_queries.ChatBotFile{
+		{Name: "faq.md", Content: "Q: hours?\nA: 9-5\n"},
+		{Name: "prices.txt", Content: "basic: $10"},
+	})
+	for _, want := range []string{
+		"<document name=\"faq.md\">\nQ: hours?\nA: 9-5\n</document>",
+		"<document name=\"prices.txt\">\nbasic: $10\n</document>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatBotKnowledge() = %q; missing %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package svc
+
+import (
+	"regexp"
+
+	"github.com/swuecho/chat_backend/models"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// RedactionRule replaces every match of Pattern with Replacement.
+// When Validate is set, a match is only replaced if Validate returns true.
+type RedactionRule struct {
+	Name        string
+	Pattern     *regexp.Regexp
+	Replacement string
+	Validate    func(match string) bool
+}
+
+// DefaultRedactionRules covers emails, common provider API keys and card numbers.
+var DefaultRedactionRules = []RedactionRule{
+	{
+		Name:        "email",
+		Pattern:     regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		Replacement: "[REDACTED_EMAIL]",
+	},
+	{
+		Name: "api_key",
+		Pattern: regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_\-]{16,}|AKIA[0-9A-Z]{16}|AIza[0-9A-Za-z_\-]{35}|` +
+			`gh[pousr]_[A-Za-z0-9]{36,}|xox[abprs]-[A-Za-z0-9\-]{10,})`),
+		Replacement: "[REDACTED_KEY]",
+	},
+	{
+		Name:        "bearer_token",
+		Pattern:     regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._\-]{20,}`),
+		Replacement: "Bearer [REDACTED_KEY]",
+	},
+	{
+		Name:        "card_number",
+		Pattern:     regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`),
+		Replacement: "[REDACTED_CARD]",
+		Validate:    luhnValid,
+	},
+}
+
+// Redactor strips PII from text before it is persisted to chat logs.
+type Redactor struct {
+	rules []RedactionRule
+}
+
+// NewRedactor creates a Redactor with the given rules.
+func NewRedactor(rules []RedactionRule) *Redactor {
+	return &Redactor{rules: rules}
+}
+
+// Redact applies all rules to text in order.
+func (r *Redactor) Redact(text string) string {
+	for _, rule := range r.rules {
+		if rule.Validate == nil {
+			text = rule.Pattern.ReplaceAllString(text, rule.Replacement)
+			continue
+		}
+		text = rule.Pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if rule.Validate(match) {
+				return rule.Replacement
+			}
+			return match
+		})
+	}
+	return text
+}
+
+// RedactSession returns a copy of session with its free-text fields redacted.
+func (r *Redactor) RedactSession(session sqlc_queries.ChatSession) sqlc_queries.ChatSession {
+	session.Topic = r.Redact(session.Topic)
+	return session
+}
+
+// RedactMessages returns a copy of msgs with each message content redacted.
+func (r *Redactor) RedactMessages(msgs []models.Message) []models.Message {
+	redacted := make([]models.Message, len(msgs))
+	for i, msg := range msgs {
+		msg.Content = r.Redact(msg.Content)
+		redacted[i] = msg
+	}
+	return redacted
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum.
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
@@ -0,0 +1,96 @@
+package svc
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// ChatLogFilter narrows the chat logs returned to admins. Zero values are ignored.
+type ChatLogFilter struct {
+	UserID      int32
+	Model       string
+	SessionUuid string
+	Keyword     string
+	From        time.Time
+	To          time.Time
+}
+
+// ChatLogService provides chat log browsing and retention.
+type ChatLogService struct {
+	q *sqlc_queries.Queries
+}
+
+// NewChatLogService creates a new ChatLogService.
+func NewChatLogService(q *sqlc_queries.Queries) *ChatLogService {
+	return &ChatLogService{q: q}
+}
+
+// Q returns the underlying queries.
+func (s *ChatLogService) Q() *sqlc_queries.Queries { return s.q }
+
+// ListChatLogs returns a page of chat logs matching the filter along with the total match count.
+func (s *ChatLogService) ListChatLogs(ctx context.Context, f ChatLogFilter, limit, offset int32) ([]sqlc_queries.ChatLog, int64, error) {
+	from, to := f.From, f.To
+	if to.IsZero() {
+		to = time.Now().Add(time.Minute)
+	}
+
+	logs, err := s.q.ListChatLogsFiltered(ctx, sqlc_queries.ListChatLogsFilteredParams{
+		UserID: f.UserID, Model: f.Model, SessionUuid: f.SessionUuid, Keyword: f.Keyword,
+		CreatedFrom: from, CreatedTo: to,
+		PageLimit: limit, PageOffset: offset,
+	})
+	if err != nil {
+		return nil, 0, eris.Wrap(err, "failed to list chat logs")
+	}
+	total, err := s.q.CountChatLogsFiltered(ctx, sqlc_queries.CountChatLogsFilteredParams{
+		UserID: f.UserID, Model: f.Model, SessionUuid: f.SessionUuid, Keyword: f.Keyword,
+		CreatedFrom: from, CreatedTo: to,
+	})
+	if err != nil {
+		return nil, 0, eris.Wrap(err, "failed to count chat logs")
+	}
+	return logs, total, nil
+}
+
+func (s *ChatLogService) GetChatLogByID(ctx context.Context, id int32) (sqlc_queries.ChatLog, error) {
+	l, err := s.q.ChatLogByID(ctx, id)
+	return l, eris.Wrap(err, "failed to retrieve chat log")
+}
+
+func (s *ChatLogService) DeleteChatLog(ctx context.Context, id int32) error {
+	return eris.Wrap(s.q.DeleteChatLog(ctx, id), "failed to delete chat log")
+}
+
+// PurgeOlderThan deletes chat logs created before now minus retention.
+func (s *ChatLogService) PurgeOlderThan(ctx context.Context, retention time.Duration) (int64, error) {
+	n, err := s.q.PurgeChatLogsBefore(ctx, time.Now().Add(-retention))
+	return n, eris.Wrap(err, "failed to purge chat logs")
+}
+
+// RunRetentionPurger purges expired chat logs every interval until ctx is done.
+// A non-positive retention disables purging.
+func (s *ChatLogService) RunRetentionPurger(ctx context.Context, retention, interval time.Duration) {
+	if retention <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := s.PurgeOlderThan(ctx, retention)
+		if err != nil {
+			slog.Error("chat log purge failed", "error", err)
+		} else if n > 0 {
+			slog.Info("purged expired chat logs", "count", n, "retention", retention)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,31 +1,32 @@
 package svc
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
-	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	_ "embed"
 	"github.com/rotisserie/eris"
 	"github.com/samber/lo"
-	openai "github.com/sashabaranov/go-openai"
+	"github.com/swuecho/chat_backend/config"
 	"github.com/swuecho/chat_backend/dto"
-	"github.com/swuecho/chat_backend/llm/gemini"
+	"github.com/swuecho/chat_backend/imaging"
 	models "github.com/swuecho/chat_backend/models"
 	"github.com/swuecho/chat_backend/provider"
 	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type ChatService struct {
 	q           *sqlc_queries.Queries
 	openAIKey   string
 	openAIProxy string
+	redactor    *Redactor
+	aux         *AuxiliaryModelService
 }
 
 //go:embed artifact_instruction.txt
@@ -33,7 +34,10 @@ var artifactInstructionText string
 
 // NewChatService creates a new ChatService with database queries and OpenAI configuration.
 func NewChatService(q *sqlc_queries.Queries, openAIKey, openAIProxy string) *ChatService {
-	return &ChatService{q: q, openAIKey: openAIKey, openAIProxy: openAIProxy}
+	return &ChatService{
+		q: q, openAIKey: openAIKey, openAIProxy: openAIProxy, redactor: NewRedactor(DefaultRedactionRules),
+		aux: NewAuxiliaryModelService(q, provider.Config{OpenAIKey: openAIKey, OpenAIProxy: openAIProxy}),
+	}
 }
 
 // Q returns the underlying queries.
@@ -77,15 +81,17 @@ func appendInstructionToSystemMessage(msgs []models.Message, instruction string)
 //   - regenerate: If true, excludes the target message from history
 //
 // Returns combined message array or error.
-func (s *ChatService) GetAskMessages(chatSession sqlc_queries.ChatSession, chatUuid string, regenerate bool) ([]models.Message, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*dto.RequestTimeoutSeconds)
+func (s *ChatService) GetAskMessages(ctx context.Context, chatSession sqlc_queries.ChatSession, chatUuid string, regenerate bool) ([]models.Message, error) {
+	ctx, span := tracing.Start(ctx, "ChatService.GetAskMessages", attribute.String("chat.session_uuid", chatSession.Uuid))
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, time.Second*dto.RequestTimeoutSeconds)
 	defer cancel()
 
 	chatSessionUuid := chatSession.Uuid
 
 	lastN := chatSession.MaxLength
 	if chatSession.MaxLength == 0 {
-		lastN = dto.DefaultMaxLength
+		lastN = int32(config.Current().CHAT.DEFAULT_MAX_LENGTH)
 	}
 
 	chat_prompts, err := s.q.GetChatPromptsBySessionUUID(ctx, chatSessionUuid)
@@ -116,15 +122,23 @@ func (s *ChatService) GetAskMessages(chatSession sqlc_queries.ChatSession, chatU
 		msg.SetTokenCount(int32(m.TokenCount))
 		return msg
 	})
+	profile := imaging.OpenAIProfile
+	if chatModel, err := s.q.ChatModelByName(ctx, chatSession.Model); err == nil {
+		profile = imaging.ProfileFor(chatModel.ApiType)
+	}
+	attachments, err := NewChatFileService(s.q).MessageAttachments(ctx, chatMessages, profile)
+	if err != nil {
+		return nil, err
+	}
 	chatMessageMsgs := lo.Map(chatMessages, func(m sqlc_queries.ChatMessage, _ int) models.Message {
-		msg := models.Message{Role: m.Role, Content: m.Content}
+		msg := models.Message{Role: m.Role, Content: m.Content, Attachments: attachments[m.Uuid]}
 		msg.SetTokenCount(int32(m.TokenCount))
 		return msg
 	})
 	msgs := append(chatPromptMsgs, chatMessageMsgs...)
 
 	// Add artifact instruction to system messages only if artifact mode is enabled
-	if chatSession.ArtifactEnabled {
+	if chatSession.ArtifactEnabled && config.Current().FEATURE.ARTIFACTS {
 		artifactInstruction, err := LoadArtifactInstruction()
 		if err != nil {
 			slog.Warn("Failed to load artifact instruction", "error", err)
@@ -168,11 +182,12 @@ func (s *ChatService) CreateChatPromptSimple(ctx context.Context, chatSessionUui
 //   - content, reasoningContent: Message content and reasoning (if any)
 //   - model: LLM model name
 //   - userId: User ID for ownership
-//   - baseURL: API base URL for summarization
-//   - is_summarize_mode: Whether to enable automatic summarization
+//   - is_summarize_mode: Whether to summarize long messages with the configured summary model
 //
 // Returns created message or error.
-func (s *ChatService) CreateChatMessageSimple(ctx context.Context, sessionUuid, uuid, role, content, reasoningContent, model string, userId int32, baseURL string, is_summarize_mode bool) (sqlc_queries.ChatMessage, error) {
+func (s *ChatService) CreateChatMessageSimple(ctx context.Context, sessionUuid, uuid, role, content, reasoningContent, model string, userId int32, is_summarize_mode bool) (sqlc_queries.ChatMessage, error) {
+	ctx, span := tracing.Start(ctx, "ChatService.CreateChatMessageSimple", attribute.String("chat.session_uuid", sessionUuid), attribute.String("chat.role", role))
+	defer span.End()
 	numTokens, err := provider.GetTokenCount(content)
 	if err != nil {
 		slog.Warn("Failed to get token count", "error", err)
@@ -182,9 +197,7 @@ func (s *ChatService) CreateChatMessageSimple(ctx context.Context, sessionUuid,
 	summary := ""
 
 	if is_summarize_mode && numTokens > dto.SummarizeThreshold {
-		slog.Info("summarizing")
-		summary = provider.SummarizeWithTimeout(s.openAIKey, baseURL, content)
-		slog.Info("summarizing: " + summary)
+		summary = s.summarize(ctx, sessionUuid, content)
 	}
 
 	// Extract artifacts from content
@@ -215,11 +228,19 @@ func (s *ChatService) CreateChatMessageSimple(ctx context.Context, sessionUuid,
 	if err != nil {
 		return sqlc_queries.ChatMessage{}, eris.Wrap(err, "failed to create message ")
 	}
+	if role == "user" {
+		// files uploaded since the last question were sent with this one
+		if err := NewChatFileService(s.q).AttachPendingFiles(ctx, sessionUuid, uuid, userId); err != nil {
+			return sqlc_queries.ChatMessage{}, err
+		}
+	}
 	return message, nil
 }
 
 // CreateChatMessageWithSuggestedQuestions creates a chat message with optional suggested questions for explore mode
-func (s *ChatService) CreateChatMessageWithSuggestedQuestions(ctx context.Context, sessionUuid, uuid, role, content, reasoningContent, model string, userId int32, baseURL string, is_summarize_mode, exploreMode bool, messages []models.Message) (sqlc_queries.ChatMessage, error) {
+func (s *ChatService) CreateChatMessageWithSuggestedQuestions(ctx context.Context, sessionUuid, uuid, role, content, reasoningContent, model string, userId int32, is_summarize_mode, exploreMode bool, messages []models.Message) (sqlc_queries.ChatMessage, error) {
+	ctx, span := tracing.Start(ctx, "ChatService.CreateChatMessageWithSuggestedQuestions", attribute.String("chat.session_uuid", sessionUuid), attribute.String("chat.role", role))
+	defer span.End()
 	numTokens, err := provider.GetTokenCount(content)
 	if err != nil {
 		slog.Warn("Failed to get token count", "error", err)
@@ -228,9 +249,7 @@ func (s *ChatService) CreateChatMessageWithSuggestedQuestions(ctx context.Contex
 
 	summary := ""
 	if is_summarize_mode && numTokens > dto.SummarizeThreshold {
-		slog.Info("summarizing")
-		summary = provider.SummarizeWithTimeout(s.openAIKey, baseURL, content)
-		slog.Info("summarizing: " + summary)
+		summary = s.summarize(ctx, sessionUuid, content)
 	}
 
 	// Extract artifacts from content
@@ -244,7 +263,7 @@ func (s *ChatService) CreateChatMessageWithSuggestedQuestions(ctx context.Contex
 	// Generate suggested questions if explore mode is enabled and role is assistant
 	suggestedQuestions := json.RawMessage([]byte("[]"))
 	if exploreMode && role == "assistant" && messages != nil {
-		questions := s.GenerateSuggestedQuestions(content, messages)
+		questions := s.suggestForSession(ctx, sessionUuid, content, messages)
 		if questionsJSON, err := json.Marshal(questions); err == nil {
 			suggestedQuestions = questionsJSON
 		} else {
@@ -275,8 +294,30 @@ func (s *ChatService) CreateChatMessageWithSuggestedQuestions(ctx context.Contex
 	return message, nil
 }
 
-// generateSuggestedQuestions generates follow-up questions based on the conversation context
-func (s *ChatService) GenerateSuggestedQuestions(content string, messages []models.Message) []string {
+// summarize summarizes a long message of the session, or returns "" when no summary model is configured.
+func (s *ChatService) summarize(ctx context.Context, sessionUuid, content string) string {
+	session, err := s.q.GetChatSessionByUUID(ctx, sessionUuid)
+	if err != nil {
+		slog.Warn("Failed to get session for summary", "session", sessionUuid, "error", err)
+		return ""
+	}
+	return s.aux.Summarize(ctx, session, content)
+}
+
+func (s *ChatService) suggestForSession(ctx context.Context, sessionUuid, content string, messages []models.Message) []string {
+	session, err := s.q.GetChatSessionByUUID(ctx, sessionUuid)
+	if err != nil {
+		slog.Warn("Failed to get session for suggestions", "session", sessionUuid, "error", err)
+		return nil
+	}
+	return s.GenerateSuggestedQuestions(ctx, session, content, messages)
+}
+
+// GenerateSuggestedQuestions generates follow-up questions with the session's
+// suggestion model. It returns nothing when no suggestion model is configured.
+func (s *ChatService) GenerateSuggestedQuestions(ctx context.Context, session sqlc_queries.ChatSession, content string, messages []models.Message) []string {
+	ctx, span := tracing.Start(ctx, "ChatService.GenerateSuggestedQuestions", attribute.String("chat.session_uuid", session.Uuid))
+	defer span.End()
 	// Create a simplified prompt to generate follow-up questions
 	prompt := `Based on the following conversation, generate 3 thoughtful follow-up questions that would help explore the topic further. Return only the questions, one per line, without numbering or bullet points.
 
@@ -295,8 +336,11 @@ Conversation context:
 
 	prompt += fmt.Sprintf("assistant: %s\n\nGenerate 3 follow-up questions:", content)
 
-	// Use the preferred models (deepseek-chat or gemini-2.0-flash) to generate suggestions
-	questions := s.callLLMForSuggestions(prompt)
+	questions, err := s.aux.Run(ctx, AuxTaskSuggestion, session, "You suggest follow-up questions.", prompt)
+	if err != nil {
+		slog.Warn("Failed to generate suggested questions", "session", session.Uuid, "error", err)
+		return nil
+	}
 
 	// Parse the response into individual questions
 	lines := strings.Split(strings.TrimSpace(questions), "\n")
@@ -317,163 +361,6 @@ Conversation context:
 	return result
 }
 
-// callLLMForSuggestions makes a simple API call to generate suggested questions
-func (s *ChatService) callLLMForSuggestions(prompt string) string {
-	ctx := context.Background()
-
-	// Get all models and find preferred models for suggestions
-	allModels, err := s.q.ListChatModels(ctx)
-	if err != nil {
-		slog.Warn("Failed to list models for suggestions", "error", err)
-		return ""
-	}
-
-	// Filter for enabled models and prioritize deepseek-chat or gemini-2.0-flash
-	var selectedModel sqlc_queries.ChatModel
-	var foundPreferred bool
-
-	// First pass: look for preferred models
-	for _, model := range allModels {
-		if !model.IsEnable {
-			continue
-		}
-		modelNameLower := strings.ToLower(model.Name)
-		if strings.Contains(modelNameLower, "deepseek-chat") || strings.Contains(modelNameLower, "gemini-2.0-flash") {
-			selectedModel = model
-			foundPreferred = true
-			break
-		}
-	}
-
-	// Second pass: fallback to any gemini or openai model if preferred not found
-	if !foundPreferred {
-		for _, model := range allModels {
-			if !model.IsEnable {
-				continue
-			}
-			apiType := strings.ToLower(model.ApiType)
-			modelName := strings.ToLower(model.Name)
-
-			// Prefer gemini models, then openai
-			if apiType == "gemini" || (apiType == "openai" && strings.Contains(modelName, "gpt")) {
-				selectedModel = model
-				break
-			}
-		}
-	}
-
-	if selectedModel.ID == 0 {
-		slog.Warn("No suitable models available for suggestions")
-		return ""
-	}
-
-	// Use different API calls based on model type
-	apiType := strings.ToLower(selectedModel.ApiType)
-	modelName := strings.ToLower(selectedModel.Name)
-
-	if apiType == "gemini" || strings.Contains(modelName, "gemini") {
-		return s.callGeminiForSuggestions(ctx, selectedModel, prompt)
-	} else if strings.Contains(modelName, "deepseek") || apiType == "openai" {
-		return s.callOpenAICompatibleForSuggestions(ctx, selectedModel, prompt)
-	}
-
-	slog.Warn("Unsupported model type for suggestions", "apiType", selectedModel.ApiType)
-	return ""
-}
-
-// callGeminiForSuggestions makes a Gemini API call for suggestions
-func (s *ChatService) callGeminiForSuggestions(ctx context.Context, model sqlc_queries.ChatModel, prompt string) string {
-	// Validate API key
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		slog.Warn("GEMINI_API_KEY environment variable not set")
-		return ""
-	}
-
-	// Create messages for Gemini
-	messages := []models.Message{
-		{
-			Role:    "user",
-			Content: prompt,
-		},
-	}
-
-	// Generate Gemini payload
-	payloadBytes, err := gemini.GenGemminPayload(messages, nil)
-	if err != nil {
-		slog.Warn("Failed to generate Gemini payload for suggestions", "error", err)
-		return ""
-	}
-
-	// Build URL
-	url := gemini.BuildAPIURL(model.Name, false)
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payloadBytes))
-	if err != nil {
-		slog.Warn("Failed to create Gemini request for suggestions", "error", err)
-		return ""
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	// Make the API call with timeout
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
-	answer, err := gemini.HandleRegularResponse(http.Client{Timeout: 30 * time.Second}, req)
-	if err != nil {
-		slog.Warn("Failed to get Gemini response for suggestions", "error", err)
-		return ""
-	}
-
-	if answer == nil || answer.Answer == "" {
-		slog.Warn("Empty response from Gemini for suggestions")
-		return ""
-	}
-
-	return answer.Answer
-}
-
-// callOpenAICompatibleForSuggestions makes an OpenAI-compatible API call for suggestions (including deepseek)
-func (s *ChatService) callOpenAICompatibleForSuggestions(ctx context.Context, model sqlc_queries.ChatModel, prompt string) string {
-	// Generate OpenAI client configuration
-	config, err := provider.GenOpenAIConfig(model, provider.Config{OpenAIKey: s.openAIKey, OpenAIProxy: s.openAIProxy})
-	if err != nil {
-		slog.Warn("Failed to generate OpenAI configuration for suggestions", "error", err)
-		return ""
-	}
-
-	client := openai.NewClientWithConfig(config)
-
-	// Create a simple chat completion request for generating suggestions
-	req := openai.ChatCompletionRequest{
-		Model:       model.Name,
-		Temperature: 0.7,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-		MaxTokens: 200, // Keep suggestions concise
-	}
-
-	// Make the API call with timeout
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
-	resp, err := client.CreateChatCompletion(ctx, req)
-	if err != nil {
-		slog.Warn("Failed to generate suggested questions", "model", model.Name, "error", err)
-		return ""
-	}
-
-	if len(resp.Choices) == 0 {
-		slog.Warn("No response choices for suggested questions", "model", model.Name)
-		return ""
-	}
-
-	return resp.Choices[0].Message.Content
-}
-
 // UpdateChatMessageContent updates the content of an existing chat message.
 // Recalculates token count for the updated content.
 func (s *ChatService) UpdateChatMessageContent(ctx context.Context, uuid, content string) error {
@@ -502,10 +389,25 @@ func (s *ChatService) UpdateChatMessageSuggestions(ctx context.Context, uuid str
 	return err
 }
 
-// logChat creates a chat log entry for analytics and debugging.
-// Logs the session, messages, and LLM response for audit purposes.
+// LogChat creates a chat log entry for analytics and debugging.
+// PII is redacted before writing, and sessions in workspaces that opted out are skipped.
 func (s *ChatService) LogChat(chatSession sqlc_queries.ChatSession, msgs []models.Message, answerText string) {
-	// log chat
+	ctx := context.Background()
+	if chatSession.WorkspaceID.Valid {
+		enabled, err := s.q.IsChatLogEnabledForWorkspace(ctx, chatSession.WorkspaceID.Int32)
+		if err != nil {
+			slog.Warn("Failed to check workspace chat log setting", "error", err)
+			return
+		}
+		if !enabled {
+			return
+		}
+	}
+
+	chatSession = s.redactor.RedactSession(chatSession)
+	msgs = s.redactor.RedactMessages(msgs)
+	answerText = s.redactor.Redact(answerText)
+
 	sessionRaw := chatSession.ToRawMessage()
 	if sessionRaw == nil {
 		slog.Info("failed to marshal chat session")
@@ -522,9 +424,11 @@ func (s *ChatService) LogChat(chatSession sqlc_queries.ChatSession, msgs []model
 		return // Skip logging if marshalling fails
 	}
 
-	s.q.CreateChatLog(context.Background(), sqlc_queries.CreateChatLogParams{
+	if _, err := s.q.CreateChatLog(ctx, sqlc_queries.CreateChatLogParams{
 		Session:  *sessionRaw,
 		Question: question,
 		Answer:   answerRaw,
-	})
+	}); err != nil {
+		slog.Warn("Failed to create chat log", "error", err)
+	}
 }
@@ -0,0 +1,108 @@
+package svc
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// ChatModelAPITypes are the provider protocols a chat model can speak.
+var ChatModelAPITypes = []string{"openai", "claude", "gemini", "ollama", "custom", "image"}
+
+// ChatModelService changes chat model configuration and records each change
+// in the audit trail.
+type ChatModelService struct {
+	q *sqlc_queries.Queries
+}
+
+// NewChatModelService creates a new ChatModelService.
+func NewChatModelService(q *sqlc_queries.Queries) *ChatModelService {
+	return &ChatModelService{q: q}
+}
+
+// Q returns the underlying queries.
+func (s *ChatModelService) Q() *sqlc_queries.Queries { return s.q }
+
+// Create adds a chat model. An empty ApiType means openai.
+func (s *ChatModelService) Create(ctx context.Context, params sqlc_queries.CreateChatModelParams) (sqlc_queries.ChatModel, error) {
+	if params.ApiType == "" {
+		params.ApiType = "openai"
+	}
+	if err := validateChatModelAPIType(params.ApiType); err != nil {
+		return sqlc_queries.ChatModel{}, err
+	}
+	m, err := s.q.CreateChatModel(ctx, params)
+	if err != nil {
+		return m, eris.Wrap(err, "failed to create chat model")
+	}
+	s.audit(ctx, AuditChatModelCreate, m.ID, nil, m)
+	return m, nil
+}
+
+// Update replaces the configuration of a chat model owned by params.UserID.
+// An empty ApiType means openai.
+func (s *ChatModelService) Update(ctx context.Context, params sqlc_queries.UpdateChatModelParams) (sqlc_queries.ChatModel, error) {
+	if params.ApiType == "" {
+		params.ApiType = "openai"
+	}
+	if err := validateChatModelAPIType(params.ApiType); err != nil {
+		return sqlc_queries.ChatModel{}, err
+	}
+	before, err := s.q.ChatModelByID(ctx, params.ID)
+	if err != nil {
+		return before, eris.Wrap(err, "failed to get chat model")
+	}
+	m, err := s.q.UpdateChatModel(ctx, params)
+	if err != nil {
+		return m, eris.Wrap(err, "failed to update chat model")
+	}
+	s.audit(ctx, AuditChatModelUpdate, m.ID, before, m)
+	return m, nil
+}
+
+// SetEnabled turns a chat model on or off, leaving the rest of its
+// configuration unchanged.
+func (s *ChatModelService) SetEnabled(ctx context.Context, id int32, enabled bool) (sqlc_queries.ChatModel, error) {
+	m, err := s.q.ChatModelByID(ctx, id)
+	if err != nil {
+		return m, eris.Wrap(err, "failed to get chat model")
+	}
+	return s.Update(ctx, sqlc_queries.UpdateChatModelParams{
+		ID: m.ID, Name: m.Name, Label: m.Label, IsDefault: m.IsDefault, Url: m.Url,
+		ApiAuthHeader: m.ApiAuthHeader, ApiAuthKey: m.ApiAuthKey, UserID: m.UserID,
+		EnablePerModeRatelimit: m.EnablePerModeRatelimit, MaxToken: m.MaxToken, DefaultToken: m.DefaultToken,
+		OrderNumber: m.OrderNumber, HttpTimeOut: m.HttpTimeOut, IsEnable: enabled, ApiType: m.ApiType,
+	})
+}
+
+// Delete removes a chat model owned by userID; other models are left alone.
+func (s *ChatModelService) Delete(ctx context.Context, id, userID int32) error {
+	before, err := s.q.ChatModelByID(ctx, id)
+	found := err == nil && before.UserID == userID
+	if err := s.q.DeleteChatModel(ctx, sqlc_queries.DeleteChatModelParams{ID: id, UserID: userID}); err != nil {
+		return eris.Wrap(err, "failed to delete chat model")
+	}
+	if found {
+		s.audit(ctx, AuditChatModelDelete, id, before, nil)
+	}
+	return nil
+}
+
+func (s *ChatModelService) audit(ctx context.Context, action string, id int32, before, after any) {
+	NewAuditService(s.q).Record(ctx, AuditEntry{
+		Action: action, TargetType: "chat_model", TargetID: strconv.Itoa(int(id)), Before: before, After: after,
+	})
+}
+
+func validateChatModelAPIType(apiType string) error {
+	for _, t := range ChatModelAPITypes {
+		if t == apiType {
+			return nil
+		}
+	}
+	return dto.ErrValidationInvalidInput("Invalid API type. Valid types are: " + strings.Join(ChatModelAPITypes, ", "))
+}
@@ -0,0 +1,25 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+func TestChatModelServiceRejectsUnknownAPIType(t *testing.T) {
+	s := &ChatModelService{}
+	ctx := context.Background()
+
+	_, createErr := s.Create(ctx, sqlc_queries.CreateChatModelParams{Name: "m", ApiType: "bogus"})
+	_, updateErr := s.Update(ctx, sqlc_queries.UpdateChatModelParams{ID: 1, Name: "m", ApiType: "bogus"})
+	for name, err := range map[string]error{"Create": createErr, "Update": updateErr} {
+		var apiErr dto.APIError
+		if !errors.As(err, &apiErr) || apiErr.HTTPCode != http.StatusBadRequest {
+			t.Errorf("%s with unknown api type: got %v, want a validation error", name, err)
+		}
+	}
+}
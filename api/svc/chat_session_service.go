@@ -8,6 +8,7 @@ import (
 
 	"github.com/rotisserie/eris"
 	"github.com/samber/lo"
+	"github.com/swuecho/chat_backend/config"
 	"github.com/swuecho/chat_backend/dto"
 	"github.com/swuecho/chat_backend/provider"
 	"github.com/swuecho/chat_backend/sqlc_queries"
@@ -189,17 +190,6 @@ func (s *ChatSessionService) GetChatMessagesBySessionUUID(ctx context.Context, p
 	return msgs, eris.Wrap(err, "failed to get chat messages")
 }
 
-// RateLimitByUserAndSessionUUID checks per-model rate limits.
-func (s *ChatSessionService) RateLimitByUserAndSessionUUID(ctx context.Context, params sqlc_queries.RateLimiteByUserAndSessionUUIDParams) (sqlc_queries.RateLimiteByUserAndSessionUUIDRow, error) {
-	r, err := s.q.RateLimiteByUserAndSessionUUID(ctx, params)
-	return r, err
-}
-
-// GetChatMessagesCountByUserAndModel returns message count for rate limiting.
-func (s *ChatSessionService) GetChatMessagesCountByUserAndModel(ctx context.Context, params sqlc_queries.GetChatMessagesCountByUserAndModelParams) (int64, error) {
-	return s.q.GetChatMessagesCountByUserAndModel(ctx, params)
-}
-
 // ChatSnapshotByUUID returns a snapshot by UUID.
 func (s *ChatSessionService) ChatSnapshotByUUID(ctx context.Context, uuid string) (sqlc_queries.ChatSnapshot, error) {
 	sn, err := s.q.ChatSnapshotByUUID(ctx, uuid)
@@ -247,9 +237,21 @@ func (s *ChatSessionService) UpsertUserActiveSession(ctx context.Context, params
 	return sess, err
 }
 
-// GetChatMessagesBySessionUUIDForAdmin returns messages for admin view.
+// GetChatMessagesBySessionUUIDForAdmin returns messages for admin view. Each
+// read is recorded in the audit trail.
 func (s *ChatSessionService) GetChatMessagesBySessionUUIDForAdmin(ctx context.Context, uuid string) ([]sqlc_queries.GetChatMessagesBySessionUUIDForAdminRow, error) {
-	return s.q.GetChatMessagesBySessionUUIDForAdmin(ctx, uuid)
+	messages, err := s.q.GetChatMessagesBySessionUUIDForAdmin(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	read := map[string]any{"messages": len(messages)}
+	if session, err := s.q.GetChatSessionByUUID(ctx, uuid); err == nil {
+		read["ownerUserId"] = session.UserID
+	}
+	NewAuditService(s.q).Record(ctx, AuditEntry{
+		Action: AuditSessionAdminRead, TargetType: "chat_session", TargetID: uuid, After: read,
+	})
+	return messages, nil
 }
 
 // GetChatHistoryBySessionUUID returns chat history as simple messages.
@@ -270,7 +272,7 @@ func (s *ChatSessionService) EnsureDefaultSystemPrompt(ctx context.Context, chat
 
 	promptText := strings.TrimSpace(systemPrompt)
 	if promptText == "" {
-		promptText = dto.DefaultSystemPromptText
+		promptText = config.Current().CHAT.DEFAULT_SYSTEM_PROMPT
 	}
 
 	tokenCount, tokenErr := provider.GetTokenCount(promptText)
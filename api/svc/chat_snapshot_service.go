@@ -13,12 +13,14 @@ import (
 
 // ChatSnapshotService provides methods for chat snapshot management.
 type ChatSnapshotService struct {
-	q *sqlc_queries.Queries
+	q   *sqlc_queries.Queries
+	aux *AuxiliaryModelService
 }
 
-// NewChatSnapshotService creates a new ChatSnapshotService.
-func NewChatSnapshotService(q *sqlc_queries.Queries) *ChatSnapshotService {
-	return &ChatSnapshotService{q: q}
+// NewChatSnapshotService creates a new ChatSnapshotService. Titles are
+// generated with cfg, which carries the OpenAI proxy and rate limiter.
+func NewChatSnapshotService(q *sqlc_queries.Queries, cfg provider.Config) *ChatSnapshotService {
+	return &ChatSnapshotService{q: q, aux: NewAuxiliaryModelService(q, cfg)}
 }
 
 // Q returns the underlying queries.
@@ -65,7 +67,7 @@ func (s *ChatSnapshotService) CreateChatSnapshot(ctx context.Context, chatSessio
 	text := lo.Reduce(simple_msgs, func(acc string, curr sqlc_queries.SimpleChatMessage, _ int) string {
 		return acc + curr.Text
 	}, "")
-	title := GenTitle(s.q, ctx, chatSession, text)
+	title := s.genTitle(ctx, chatSession, text)
 	simple_msgs_raw, err := json.Marshal(simple_msgs)
 	if err != nil {
 		return "", err
@@ -87,20 +89,12 @@ func (s *ChatSnapshotService) CreateChatSnapshot(ctx context.Context, chatSessio
 	return one.Uuid, nil
 }
 
-func GenTitle(q *sqlc_queries.Queries, ctx context.Context, chatSession sqlc_queries.ChatSession, text string) string {
-	title := provider.FirstN(chatSession.Topic, 100)
-	model := "gemini-2.0-flash"
-	_, err := q.ChatModelByName(ctx, model)
-	if err == nil {
-		genTitle, err := provider.GenerateChatTitle(ctx, model, text)
-		if err != nil {
-			slog.Info("error", "error", err)
-		}
-		if genTitle != "" {
-			title = genTitle
-		}
+// genTitle titles a snapshot with the session's title model, falling back to the session topic.
+func (s *ChatSnapshotService) genTitle(ctx context.Context, chatSession sqlc_queries.ChatSession, text string) string {
+	if genTitle := s.aux.GenerateTitle(ctx, chatSession, text); genTitle != "" {
+		return genTitle
 	}
-	return title
+	return provider.FirstN(chatSession.Topic, 100)
 }
 
 func (s *ChatSnapshotService) CreateChatBot(ctx context.Context, chatSessionUuid string, userId int32) (string, error) {
@@ -124,7 +118,7 @@ func (s *ChatSnapshotService) CreateChatBot(ctx context.Context, chatSessionUuid
 	if err != nil {
 		return "", err
 	}
-	title := GenTitle(s.q, ctx, chatSession, text)
+	title := s.genTitle(ctx, chatSession, text)
 	one, err := s.q.CreateChatBot(ctx, sqlc_queries.CreateChatBotParams{
 		Uuid: snapshot_uuid, Model: chatSession.Model, Typ: "chatbot",
 		Title: title, UserID: userId, Session: chatSessionMsg,
@@ -134,5 +128,8 @@ func (s *ChatSnapshotService) CreateChatBot(ctx context.Context, chatSessionUuid
 		slog.Info("error", "error", err)
 		return "", err
 	}
+	if _, err := NewChatBotVersionService(s.q).EnsureBaseline(ctx, one, userId); err != nil {
+		return "", err
+	}
 	return one.Uuid, nil
 }
@@ -0,0 +1,39 @@
+package svc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/swuecho/chat_backend/provider"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+func TestCreateChatSnapshotHasNoBotVersion(t *testing.T) {
+	q := sqlc_queries.New(testDB)
+	ctx := context.Background()
+	session, err := q.CreateChatSession(ctx, sqlc_queries.CreateChatSessionParams{
+		UserID: 1, Topic: "Snapshot Session", MaxLength: 100, Uuid: "snapshot-session-uuid",
+	})
+	if err != nil {
+		t.Fatalf("failed to create chat session: %v", err)
+	}
+
+	snapshotUUID, err := NewChatSnapshotService(q, provider.Config{}).CreateChatSnapshot(ctx, session.Uuid, 1)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	snapshot, err := q.ChatSnapshotByUUID(ctx, snapshotUUID)
+	if err != nil {
+		t.Fatalf("failed to get snapshot: %v", err)
+	}
+	if snapshot.Typ == "chatbot" || snapshot.BotVersion != 0 {
+		t.Errorf("plain snapshot became a bot: typ=%s version=%d", snapshot.Typ, snapshot.BotVersion)
+	}
+	versions, err := q.ListChatBotVersions(ctx, snapshotUUID)
+	if err != nil {
+		t.Fatalf("failed to list versions: %v", err)
+	}
+	if len(versions) != 0 {
+		t.Errorf("plain snapshot has %d bot versions", len(versions))
+	}
+}
@@ -36,6 +36,11 @@ func (s *ChatWorkspaceService) GetWorkspaceByUUID(ctx context.Context, uuid stri
 	return w, eris.Wrap(err, "failed to retrieve workspace")
 }
 
+func (s *ChatWorkspaceService) GetWorkspaceByID(ctx context.Context, id int32) (sqlc_queries.ChatWorkspace, error) {
+	w, err := s.q.GetWorkspaceByID(ctx, id)
+	return w, eris.Wrap(err, "failed to retrieve workspace")
+}
+
 func (s *ChatWorkspaceService) GetWorkspacesByUserID(ctx context.Context, userID int32) ([]sqlc_queries.ChatWorkspace, error) {
 	ws, err := s.q.GetWorkspacesByUserID(ctx, userID)
 	return ws, eris.Wrap(err, "failed to retrieve workspaces")
@@ -60,6 +65,14 @@ func (s *ChatWorkspaceService) DeleteWorkspace(ctx context.Context, uuid string)
 	return eris.Wrap(s.q.DeleteWorkspace(ctx, uuid), "failed to delete workspace")
 }
 
+// UpdateWorkspaceChatLogEnabled toggles chat logging for sessions in the workspace.
+func (s *ChatWorkspaceService) UpdateWorkspaceChatLogEnabled(ctx context.Context, uuid string, enabled bool) (sqlc_queries.ChatWorkspace, error) {
+	w, err := s.q.UpdateWorkspaceChatLogEnabled(ctx, sqlc_queries.UpdateWorkspaceChatLogEnabledParams{
+		Uuid: uuid, ChatLogEnabled: enabled,
+	})
+	return w, eris.Wrap(err, "failed to update workspace chat log setting")
+}
+
 // --- Default workspace ---
 
 func (s *ChatWorkspaceService) GetDefaultWorkspaceByUserID(ctx context.Context, userID int32) (sqlc_queries.ChatWorkspace, error) {
@@ -0,0 +1,290 @@
+package svc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/secrets"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// credentialHintLength is how many trailing characters of a secret are kept
+// in clear to tell keys apart.
+const credentialHintLength = 4
+
+// CredentialHint returns the visible tail of a secret, or "" when the secret
+// is too short to reveal any of it.
+func CredentialHint(secret string) string {
+	runes := []rune(secret)
+	if len(runes) < 4*credentialHintLength {
+		return ""
+	}
+	return "…" + string(runes[len(runes)-credentialHintLength:])
+}
+
+// CredentialService stores provider API keys encrypted with the process
+// keyring (see package secrets). Shared credentials are managed by admins and
+// attached to chat models; workspaces may bring their own key for a model.
+type CredentialService struct {
+	q *sqlc_queries.Queries
+}
+
+// NewCredentialService creates a new CredentialService.
+func NewCredentialService(q *sqlc_queries.Queries) *CredentialService {
+	return &CredentialService{q: q}
+}
+
+// Q returns the underlying queries.
+func (s *CredentialService) Q() *sqlc_queries.Queries { return s.q }
+
+// Create encrypts secret and stores it as a shared credential, or as a
+// workspace's own credential when workspaceID is set.
+func (s *CredentialService) Create(ctx context.Context, name, secret string, workspaceID sql.NullInt32, createdBy int32) (sqlc_queries.ProviderCredential, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return sqlc_queries.ProviderCredential{}, dto.ErrValidationInvalidInput("name is required")
+	}
+	env, err := s.seal(secret)
+	if err != nil {
+		return sqlc_queries.ProviderCredential{}, err
+	}
+	cred, err := s.q.CreateProviderCredential(ctx, sqlc_queries.CreateProviderCredentialParams{
+		Name: truncateRunes(name, 255), WorkspaceID: workspaceID, MasterKeyID: env.KeyID,
+		WrappedKey: env.DataKey, Ciphertext: env.Ciphertext, Hint: CredentialHint(secret), CreatedBy: createdBy,
+	})
+	if err != nil {
+		return cred, eris.Wrap(err, "failed to create credential")
+	}
+	s.audit(ctx, AuditCredentialCreate, cred.ID, nil, cred)
+	return cred, nil
+}
+
+// ListShared returns the credentials managed by admins.
+func (s *CredentialService) ListShared(ctx context.Context) ([]sqlc_queries.ProviderCredential, error) {
+	creds, err := s.q.ListSharedProviderCredentials(ctx)
+	return creds, eris.Wrap(err, "failed to list credentials")
+}
+
+// Update renames a shared credential and, when secret is not empty, replaces
+// its secret under a new data key. Chat models keep using the same credential id.
+func (s *CredentialService) Update(ctx context.Context, id int32, name, secret string) (sqlc_queries.ProviderCredential, error) {
+	before, err := s.shared(ctx, id)
+	if err != nil {
+		return before, err
+	}
+	cred := before
+	if name = strings.TrimSpace(name); name != "" && name != cred.Name {
+		if cred, err = s.q.UpdateProviderCredentialName(ctx, sqlc_queries.UpdateProviderCredentialNameParams{
+			ID: id, Name: truncateRunes(name, 255),
+		}); err != nil {
+			return cred, eris.Wrap(err, "failed to rename credential")
+		}
+	}
+	if secret != "" {
+		if cred, err = s.replaceSecret(ctx, id, secret); err != nil {
+			return cred, err
+		}
+		slog.Info("Provider credential rotated", "credential_id", id, "action", "credential_rotated")
+	}
+	s.audit(ctx, AuditCredentialUpdate, id, before, cred)
+	return cred, nil
+}
+
+// Delete removes a shared credential. Chat models using it fall back to their
+// api_auth_key env var.
+func (s *CredentialService) Delete(ctx context.Context, id int32) error {
+	before, err := s.shared(ctx, id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.q.DeleteProviderCredential(ctx, id); err != nil {
+		return eris.Wrap(err, "failed to delete credential")
+	}
+	s.audit(ctx, AuditCredentialDelete, id, before, nil)
+	return nil
+}
+
+// Rewrap re-encrypts the data key of every credential not yet wrapped by the
+// primary master key, so retired master keys can be removed from the keyring.
+// It returns how many credentials were rewrapped.
+func (s *CredentialService) Rewrap(ctx context.Context) (int, error) {
+	keyring, err := keyringOrError()
+	if err != nil {
+		return 0, err
+	}
+	creds, err := s.q.ListAllProviderCredentials(ctx)
+	if err != nil {
+		return 0, eris.Wrap(err, "failed to list credentials")
+	}
+	n := 0
+	for _, cred := range creds {
+		if cred.MasterKeyID == keyring.PrimaryID() {
+			continue
+		}
+		env, err := keyring.Rewrap(secrets.Envelope{KeyID: cred.MasterKeyID, DataKey: cred.WrappedKey, Ciphertext: cred.Ciphertext})
+		if err != nil {
+			return n, eris.Wrapf(err, "failed to rewrap credential %d", cred.ID)
+		}
+		if err := s.q.RewrapProviderCredential(ctx, sqlc_queries.RewrapProviderCredentialParams{
+			ID: cred.ID, MasterKeyID: env.KeyID, WrappedKey: env.DataKey, OldMasterKeyID: cred.MasterKeyID,
+		}); err != nil {
+			return n, eris.Wrapf(err, "failed to save credential %d", cred.ID)
+		}
+		n++
+	}
+	slog.Info("Provider credentials rewrapped", "count", n, "master_key_id", keyring.PrimaryID(), "action", "credentials_rewrapped")
+	NewAuditService(s.q).Record(ctx, AuditEntry{
+		Action: AuditCredentialRewrap, TargetType: "credential",
+		After: map[string]any{"rewrapped": n, "masterKeyId": keyring.PrimaryID()},
+	})
+	return n, nil
+}
+
+// SetModelCredential points a chat model at a shared credential, or back to
+// its api_auth_key env var when credentialID is not valid.
+func (s *CredentialService) SetModelCredential(ctx context.Context, chatModelID int32, credentialID sql.NullInt32) (sqlc_queries.ChatModel, error) {
+	if credentialID.Valid {
+		if _, err := s.shared(ctx, credentialID.Int32); err != nil {
+			return sqlc_queries.ChatModel{}, err
+		}
+	}
+	before, err := s.q.ChatModelByID(ctx, chatModelID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return before, dto.ErrResourceNotFound("chat model")
+	}
+	if err != nil {
+		return before, eris.Wrap(err, "failed to get chat model")
+	}
+	m, err := s.q.SetChatModelCredential(ctx, sqlc_queries.SetChatModelCredentialParams{ID: chatModelID, CredentialID: credentialID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return m, dto.ErrResourceNotFound("chat model")
+	}
+	if err != nil {
+		return m, eris.Wrap(err, "failed to set model credential")
+	}
+	NewAuditService(s.q).Record(ctx, AuditEntry{
+		Action: AuditChatModelCredential, TargetType: "chat_model", TargetID: strconv.Itoa(int(chatModelID)),
+		Before: map[string]any{"credentialId": before.CredentialID}, After: map[string]any{"credentialId": m.CredentialID},
+	})
+	return m, nil
+}
+
+// --- Bring your own key ---
+
+// ListWorkspace returns the models a workspace has its own key for.
+func (s *CredentialService) ListWorkspace(ctx context.Context, workspaceID int32) ([]sqlc_queries.ListWorkspaceModelCredentialsRow, error) {
+	rows, err := s.q.ListWorkspaceModelCredentials(ctx, workspaceID)
+	return rows, eris.Wrap(err, "failed to list workspace credentials")
+}
+
+// SetWorkspaceKey stores a workspace's own key for a model, replacing any
+// previous one.
+func (s *CredentialService) SetWorkspaceKey(ctx context.Context, workspaceID int32, modelName, secret string, userID int32) error {
+	chatModel, err := s.q.ChatModelByName(ctx, modelName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return dto.ErrResourceNotFound("chat model: " + modelName)
+		}
+		return eris.Wrap(err, "failed to get chat model")
+	}
+
+	existing, err := s.q.GetWorkspaceModelCredential(ctx, sqlc_queries.GetWorkspaceModelCredentialParams{
+		WorkspaceID: workspaceID, ChatModelID: chatModel.ID,
+	})
+	switch {
+	case err == nil:
+		_, err = s.replaceSecret(ctx, existing.CredentialID, secret)
+		return err
+	case !errors.Is(err, sql.ErrNoRows):
+		return eris.Wrap(err, "failed to get workspace credential")
+	}
+
+	cred, err := s.Create(ctx, chatModel.Name, secret, sql.NullInt32{Int32: workspaceID, Valid: true}, userID)
+	if err != nil {
+		return err
+	}
+	return eris.Wrap(s.q.UpsertWorkspaceModelCredential(ctx, sqlc_queries.UpsertWorkspaceModelCredentialParams{
+		WorkspaceID: workspaceID, ChatModelID: chatModel.ID, CredentialID: cred.ID,
+	}), "failed to save workspace credential")
+}
+
+// DeleteWorkspaceKey removes a workspace's own key for a model.
+func (s *CredentialService) DeleteWorkspaceKey(ctx context.Context, workspaceID int32, modelName string) error {
+	chatModel, err := s.q.ChatModelByName(ctx, modelName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return dto.ErrResourceNotFound("chat model: " + modelName)
+		}
+		return eris.Wrap(err, "failed to get chat model")
+	}
+	existing, err := s.q.GetWorkspaceModelCredential(ctx, sqlc_queries.GetWorkspaceModelCredentialParams{
+		WorkspaceID: workspaceID, ChatModelID: chatModel.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return dto.ErrResourceNotFound("workspace credential")
+		}
+		return eris.Wrap(err, "failed to get workspace credential")
+	}
+	// Deleting the credential cascades to the binding.
+	_, err = s.q.DeleteProviderCredential(ctx, existing.CredentialID)
+	return eris.Wrap(err, "failed to delete workspace credential")
+}
+
+func (s *CredentialService) audit(ctx context.Context, action string, id int32, before, after any) {
+	NewAuditService(s.q).Record(ctx, AuditEntry{
+		Action: action, TargetType: "credential", TargetID: strconv.Itoa(int(id)), Before: before, After: after,
+	})
+}
+
+func (s *CredentialService) shared(ctx context.Context, id int32) (sqlc_queries.ProviderCredential, error) {
+	cred, err := s.q.GetProviderCredential(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return cred, dto.ErrResourceNotFound("credential")
+		}
+		return cred, eris.Wrap(err, "failed to get credential")
+	}
+	if cred.WorkspaceID.Valid {
+		// Workspace keys are managed through their workspace only.
+		return cred, dto.ErrResourceNotFound("credential")
+	}
+	return cred, nil
+}
+
+func (s *CredentialService) replaceSecret(ctx context.Context, id int32, secret string) (sqlc_queries.ProviderCredential, error) {
+	env, err := s.seal(secret)
+	if err != nil {
+		return sqlc_queries.ProviderCredential{}, err
+	}
+	cred, err := s.q.UpdateProviderCredentialSecret(ctx, sqlc_queries.UpdateProviderCredentialSecretParams{
+		ID: id, MasterKeyID: env.KeyID, WrappedKey: env.DataKey, Ciphertext: env.Ciphertext, Hint: CredentialHint(secret),
+	})
+	return cred, eris.Wrap(err, "failed to update credential")
+}
+
+func (s *CredentialService) seal(secret string) (secrets.Envelope, error) {
+	if strings.TrimSpace(secret) == "" {
+		return secrets.Envelope{}, dto.ErrValidationInvalidInput("secret is required")
+	}
+	keyring, err := keyringOrError()
+	if err != nil {
+		return secrets.Envelope{}, err
+	}
+	env, err := keyring.Seal([]byte(secret))
+	return env, eris.Wrap(err, "failed to encrypt credential")
+}
+
+func keyringOrError() (*secrets.Keyring, error) {
+	keyring, err := secrets.Default()
+	if err != nil {
+		return nil, dto.ErrValidationInvalidInput("credential store is not configured: set CREDENTIAL_MASTER_KEY")
+	}
+	return keyring, nil
+}
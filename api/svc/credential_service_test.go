@@ -0,0 +1,16 @@
+package svc
+
+import "testing"
+
+func TestCredentialHint(t *testing.T) {
+	cases := map[string]string{
+		"":                    "",
+		"short":               "",
+		"sk-abcdefghijklmnop": "…mnop",
+	}
+	for secret, want := range cases {
+		if got := CredentialHint(secret); got != want {
+			t.Errorf("CredentialHint(%q) = %q, want %q", secret, got, want)
+		}
+	}
+}
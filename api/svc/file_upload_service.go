@@ -1,11 +1,32 @@
 package svc
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"fmt"
+	"io"
 	"log/slog"
+	"strings"
 
+	"github.com/rotisserie/eris"
+
+	"github.com/swuecho/chat_backend/config"
 	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/imaging"
+	"github.com/swuecho/chat_backend/models"
 	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/storage"
+)
+
+// Sources of chat files, as stored in chat_file.source.
+const (
+	// FileSourceUpload is a file the user attached to a message.
+	FileSourceUpload = "upload"
+	// FileSourceVoice is a recording transcribed into its message.
+	FileSourceVoice = "voice"
+	// FileSourceGenerated is an image an image model answered with.
+	FileSourceGenerated = "generated"
 )
 
 // ChatFileService handles operations related to chat file uploads
@@ -21,8 +42,11 @@ func NewChatFileService(q *sqlc_queries.Queries) *ChatFileService {
 // Q returns the underlying queries.
 func (s *ChatFileService) Q() *sqlc_queries.Queries { return s.q }
 
-// CreateChatUpload handles creating a new chat file upload
-func (s *ChatFileService) CreateChatUpload(ctx context.Context, params sqlc_queries.CreateChatFileParams) (sqlc_queries.ChatFile, error) {
+// CreateChatUpload stores content and records it as a chat file. Content
+// already stored by an earlier upload is not stored again. Images are
+// recognised by their contents rather than the MIME type in params, checked
+// and stripped of EXIF metadata before they are stored.
+func (s *ChatFileService) CreateChatUpload(ctx context.Context, params sqlc_queries.CreateChatFileParams, content io.ReadSeeker) (sqlc_queries.ChatFile, error) {
 	// Validate input
 	if params.ChatSessionUuid == "" {
 		return sqlc_queries.ChatFile{}, dto.ErrValidationInvalidInput("missing session UUID")
@@ -33,38 +57,299 @@ func (s *ChatFileService) CreateChatUpload(ctx context.Context, params sqlc_quer
 	if params.Name == "" {
 		return sqlc_queries.ChatFile{}, dto.ErrValidationInvalidInput("missing file name")
 	}
-	if len(params.Data) == 0 {
+	size, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		return sqlc_queries.ChatFile{}, eris.Wrap(err, "failed to read file data")
+	}
+	if size == 0 {
 		return sqlc_queries.ChatFile{}, dto.ErrValidationInvalidInput("empty file data")
 	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return sqlc_queries.ChatFile{}, eris.Wrap(err, "failed to read file data")
+	}
+
+	if params.Source == "" {
+		params.Source = FileSourceUpload
+	}
+	content, params.MimeType, err = sanitizeUpload(ctx, content, params.MimeType)
+	if err != nil {
+		return sqlc_queries.ChatFile{}, err
+	}
 
 	slog.Info("Creating chat file upload", "session", params.ChatSessionUuid, "userID", params.UserID)
 
+	blobs, err := storage.Default()
+	if err != nil {
+		return sqlc_queries.ChatFile{}, err
+	}
+	blob, err := blobs.Save(ctx, content)
+	if err != nil {
+		return sqlc_queries.ChatFile{}, eris.Wrap(err, "failed to store chat file")
+	}
+	params.BlobHash = blob.Hash
+	params.Size = blob.Size
+
 	upload, err := s.q.CreateChatFile(ctx, params)
 	if err != nil {
+		if releaseErr := blobs.Release(ctx, blob.Hash); releaseErr != nil {
+			slog.Warn("failed to release blob of failed upload", "hash", blob.Hash, "error", releaseErr)
+		}
 		return sqlc_queries.ChatFile{}, dto.WrapError(err, "failed to create chat file")
 	}
 
-	slog.Info("Created chat file upload", "id", upload.ID)
+	slog.Info("Created chat file upload", "id", upload.ID, "hash", blob.Hash, "backend", blob.Backend)
 	return upload, nil
 }
 
+// SaveGeneratedImages stores the images an image model made as files of
+// the assistant message messageUUID in session. Images saved for the message
+// before it was regenerated are replaced once all new ones are stored; if one
+// fails, the new ones are removed and the previous ones kept.
+func (s *ChatFileService) SaveGeneratedImages(ctx context.Context, session sqlc_queries.ChatSession, messageUUID string, images []models.Attachment) ([]sqlc_queries.Attachment, error) {
+	previous, err := s.q.ListChatFilesByMessageUUIDs(ctx, []string{messageUUID})
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to list message files")
+	}
+
+	saved := make([]sqlc_queries.Attachment, 0, len(images))
+	for _, image := range images {
+		file, err := s.saveGeneratedImage(ctx, session, messageUUID, image)
+		if err != nil {
+			for _, a := range saved {
+				if err := s.DeleteChatFile(ctx, a.ID); err != nil {
+					slog.Warn("failed to remove generated image", "id", a.ID, "error", err)
+				}
+			}
+			return nil, err
+		}
+		saved = append(saved, sqlc_queries.Attachment{
+			ID:       file.ID,
+			Name:     file.Name,
+			MimeType: file.MimeType,
+			Size:     file.Size,
+			Url:      fmt.Sprintf("/download/%d", file.ID),
+			Source:   file.Source,
+		}.WithThumbnail())
+	}
+
+	for _, f := range previous {
+		if f.Source != FileSourceGenerated {
+			continue
+		}
+		if err := s.DeleteChatFile(ctx, f.ID); err != nil {
+			slog.Warn("failed to remove replaced generated image", "id", f.ID, "error", err)
+		}
+	}
+	return saved, nil
+}
+
+func (s *ChatFileService) saveGeneratedImage(ctx context.Context, session sqlc_queries.ChatSession, messageUUID string, image models.Attachment) (sqlc_queries.ChatFile, error) {
+	mime := imaging.Sniff(image.Data)
+	if !imaging.IsImage(mime) {
+		return sqlc_queries.ChatFile{}, dto.ErrOpenAIRequestFailed.WithMessage("image model returned a " + mime + " file")
+	}
+	return s.CreateChatUpload(ctx, sqlc_queries.CreateChatFileParams{
+		ChatSessionUuid: session.Uuid,
+		UserID:          session.UserID,
+		Name:            image.Name + "." + strings.TrimPrefix(mime, "image/"),
+		MimeType:        mime,
+		Source:          FileSourceGenerated,
+		ChatMessageUuid: sql.NullString{String: messageUUID, Valid: true},
+	}, bytes.NewReader(image.Data))
+}
+
+// sanitizeUpload sniffs the type of content. An image is checked and
+// cleaned by imaging.Sanitize and returned with its real type; a file
+// claiming to be an image that is not one is rejected.
+func sanitizeUpload(ctx context.Context, content io.ReadSeeker, claimed string) (io.ReadSeeker, string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, "", eris.Wrap(err, "failed to read file data")
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return nil, "", eris.Wrap(err, "failed to read file data")
+	}
+	sniffed := imaging.Sniff(head[:n])
+	if !imaging.IsImage(sniffed) {
+		if imaging.IsImage(claimed) {
+			return nil, "", dto.ErrValidationInvalidInput("file is not a " + claimed + " image")
+		}
+		return content, claimed, nil
+	}
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, "", eris.Wrap(err, "failed to read file data")
+	}
+	cleaned, mime, err := imaging.Sanitize(ctx, data, sniffed, imageOptions())
+	if err != nil {
+		return nil, "", dto.ErrValidationInvalidInput("invalid image").WithDebugInfo(err.Error())
+	}
+	return bytes.NewReader(cleaned), mime, nil
+}
+
+func imageOptions() imaging.Options {
+	cfg := config.Current().IMAGE
+	return imaging.Options{MaxPixels: cfg.MAX_PIXELS, ConvertCommand: cfg.CONVERT_COMMAND}
+}
+
 // GetChatFile retrieves a chat file by ID
-func (s *ChatFileService) GetChatFile(ctx context.Context, id int32) (sqlc_queries.GetChatFileByIDRow, error) {
+func (s *ChatFileService) GetChatFile(ctx context.Context, id int32) (sqlc_queries.ChatFile, error) {
 	if id <= 0 {
-		return sqlc_queries.GetChatFileByIDRow{}, dto.ErrValidationInvalidInput("invalid file ID")
+		return sqlc_queries.ChatFile{}, dto.ErrValidationInvalidInput("invalid file ID")
 	}
 
 	slog.Info("Retrieving chat file", "id", id)
 
 	file, err := s.q.GetChatFileByID(ctx, id)
 	if err != nil {
-		return sqlc_queries.GetChatFileByIDRow{}, dto.WrapError(err, "failed to get chat file")
+		return sqlc_queries.ChatFile{}, dto.WrapError(err, "failed to get chat file")
 	}
 
 	return file, nil
 }
 
-// DeleteChatFile deletes a chat file by ID
+// SetDetached sets whether a file of userID is left out of the turns after
+// the one of its message.
+func (s *ChatFileService) SetDetached(ctx context.Context, id, userID int32, detached bool) (sqlc_queries.ChatFile, error) {
+	file, err := s.q.SetChatFileDetached(ctx, sqlc_queries.SetChatFileDetachedParams{ID: id, UserID: userID, Detached: detached})
+	if err != nil {
+		return sqlc_queries.ChatFile{}, dto.WrapError(dto.MapDatabaseError(err), "failed to update chat file")
+	}
+	return file, nil
+}
+
+// AttachPendingFiles attaches the files userID uploaded to a session and not
+// yet sent to the user message messageUUID.
+func (s *ChatFileService) AttachPendingFiles(ctx context.Context, sessionUUID, messageUUID string, userID int32) error {
+	n, err := s.q.AttachPendingChatFiles(ctx, sqlc_queries.AttachPendingChatFilesParams{
+		ChatMessageUuid: messageUUID,
+		ChatSessionUuid: sessionUUID,
+		UserID:          userID,
+	})
+	if err != nil {
+		return eris.Wrap(err, "failed to attach files to message")
+	}
+	if n > 0 {
+		slog.Info("Attached files to message", "message", messageUUID, "count", n)
+	}
+	return nil
+}
+
+// MessageAttachments returns the files to send to the model with messages,
+// by message UUID. Detached files are only sent with the last user message,
+// the one being answered. Images are fitted to profile. Only uploads are
+// sent; a voice recording is already the text of its message and a
+// generated image is described by its answer.
+func (s *ChatFileService) MessageAttachments(ctx context.Context, messages []sqlc_queries.ChatMessage, profile imaging.Profile) (map[string][]models.Attachment, error) {
+	if len(messages) == 0 {
+		return nil, nil
+	}
+	uuids := make([]string, 0, len(messages))
+	current := ""
+	for _, m := range messages {
+		uuids = append(uuids, m.Uuid)
+		if m.Role == "user" {
+			current = m.Uuid
+		}
+	}
+	files, err := s.q.ListChatFilesByMessageUUIDs(ctx, uuids)
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to list message files")
+	}
+
+	attachments := make(map[string][]models.Attachment)
+	for _, f := range files {
+		messageUUID := f.ChatMessageUuid.String
+		if f.Source != FileSourceUpload || (f.Detached && messageUUID != current) {
+			continue
+		}
+		data, err := s.contents(ctx, f)
+		if err != nil {
+			return nil, eris.Wrapf(err, "failed to read chat file %d", f.ID)
+		}
+		mime := f.MimeType
+		if imaging.IsImage(mime) {
+			fitted, fittedMime, err := s.fitImage(ctx, f, data, profile)
+			if err != nil {
+				// the model may still make sense of the original
+				slog.Warn("failed to fit image for model", "id", f.ID, "profile", profile.Name, "error", err)
+			} else {
+				data, mime = fitted, fittedMime
+			}
+		}
+		attachments[messageUUID] = append(attachments[messageUUID], models.Attachment{Name: f.Name, MimeType: mime, Data: data})
+	}
+	return attachments, nil
+}
+
+// fitImage returns the image data of file fitted to profile. Fitted images
+// of stored files are kept as variants of their blob, so the work is done
+// once per image and profile.
+func (s *ChatFileService) fitImage(ctx context.Context, file sqlc_queries.ChatFile, data []byte, profile imaging.Profile) ([]byte, string, error) {
+	var blobs *storage.Blobs
+	if file.BlobHash.Valid {
+		var err error
+		if blobs, err = storage.Default(); err != nil {
+			return nil, "", err
+		}
+		cached, mime, ok, err := blobs.Variant(ctx, file.BlobHash.String, profile.Name)
+		if err != nil {
+			return nil, "", err
+		}
+		if ok {
+			return cached, mime, nil
+		}
+	}
+	out, mime, changed, err := imaging.Fit(ctx, data, file.MimeType, profile, imageOptions())
+	if err != nil || !changed || blobs == nil {
+		return out, mime, err
+	}
+	if err := blobs.SaveVariant(ctx, file.BlobHash.String, profile.Name, mime, out); err != nil {
+		slog.Warn("failed to cache fitted image", "id", file.ID, "profile", profile.Name, "error", err)
+	}
+	return out, mime, nil
+}
+
+// Thumbnail returns a small preview of the image file and its MIME type.
+func (s *ChatFileService) Thumbnail(ctx context.Context, file sqlc_queries.ChatFile) ([]byte, string, error) {
+	if !imaging.IsImage(file.MimeType) {
+		return nil, "", dto.ErrValidationInvalidInput("file is not an image")
+	}
+	data, err := s.contents(ctx, file)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.fitImage(ctx, file, data, imaging.Thumbnail)
+}
+
+// contents reads file from its blob, or returns it when stored inline.
+func (s *ChatFileService) contents(ctx context.Context, file sqlc_queries.ChatFile) ([]byte, error) {
+	if !file.BlobHash.Valid {
+		return file.Data, nil
+	}
+	blobs, err := storage.Default()
+	if err != nil {
+		return nil, err
+	}
+	return blobs.FileContents(ctx, file)
+}
+
+// OpenChatFile returns the contents of file for streaming. The caller closes it.
+func (s *ChatFileService) OpenChatFile(ctx context.Context, file sqlc_queries.ChatFile) (io.ReadSeekCloser, error) {
+	if !file.BlobHash.Valid {
+		return storage.NewInlineReader(file.Data), nil
+	}
+	blobs, err := storage.Default()
+	if err != nil {
+		return nil, err
+	}
+	return blobs.OpenFile(ctx, file)
+}
+
+// DeleteChatFile deletes a chat file by ID, and its contents unless another
+// file shares them.
 func (s *ChatFileService) DeleteChatFile(ctx context.Context, id int32) error {
 	if id <= 0 {
 		return dto.ErrValidationInvalidInput("invalid file ID")
@@ -72,10 +357,20 @@ func (s *ChatFileService) DeleteChatFile(ctx context.Context, id int32) error {
 
 	slog.Info("Deleting chat file", "id", id)
 
-	_, err := s.q.DeleteChatFile(ctx, id)
+	file, err := s.q.DeleteChatFile(ctx, id)
 	if err != nil {
 		return dto.WrapError(err, "failed to delete chat file")
 	}
+	if file.BlobHash.Valid {
+		blobs, err := storage.Default()
+		if err == nil {
+			err = blobs.Release(ctx, file.BlobHash.String)
+		}
+		if err != nil {
+			// the file is gone; CollectGarbage can remove the blob later
+			slog.Warn("failed to release blob of deleted file", "id", id, "hash", file.BlobHash.String, "error", err)
+		}
+	}
 
 	return nil
 }
@@ -0,0 +1,241 @@
+package svc
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// Reasons recorded for failed logins.
+const (
+	LoginFailedPassword  = "bad_password"
+	LoginFailedTwoFactor = "bad_2fa"
+	// LoginFailedLocked is an attempt rejected without checking the password.
+	LoginFailedLocked = "locked"
+)
+
+// Login throttling limits. Counters live in Postgres so every server instance
+// sees the same failures.
+const (
+	// AccountLoginThreshold failures on one account lock it.
+	AccountLoginThreshold = 5
+	// IPLoginThreshold failures from one address lock it, across accounts.
+	IPLoginThreshold = 20
+	// LoginLockBase is the first lock; each further failure doubles it.
+	LoginLockBase = 30 * time.Second
+	// LoginLockMax caps the lock duration.
+	LoginLockMax = time.Hour
+	// LoginFailureWindow restarts counting after a quiet period.
+	LoginFailureWindow = 24 * time.Hour
+
+	// PasswordResetAccountLimit and PasswordResetIPLimit reset requests within
+	// PasswordResetWindow start a lock, whether or not the account exists.
+	PasswordResetAccountLimit = 3
+	PasswordResetIPLimit      = 10
+	PasswordResetWindow       = time.Hour
+)
+
+// AccountKey is the throttle key of a login email.
+func AccountKey(email string) string { return "account:" + normalizeLoginEmail(email) }
+
+// IPKey is the throttle key of a client address.
+func IPKey(ip string) string { return "ip:" + ip }
+
+func resetAccountKey(email string) string { return "reset:" + normalizeLoginEmail(email) }
+
+func resetIPKey(ip string) string { return "reset_ip:" + ip }
+
+func normalizeLoginEmail(email string) string {
+	return truncateRunes(strings.ToLower(strings.TrimSpace(email)), 254)
+}
+
+// LockDuration returns how long a key stays locked after its failures-th
+// failure: nothing below threshold, then LoginLockBase doubling up to LoginLockMax.
+func LockDuration(failures, threshold int32) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+	d := LoginLockBase
+	for i := threshold; i < failures; i++ {
+		d *= 2
+		if d >= LoginLockMax {
+			return LoginLockMax
+		}
+	}
+	return d
+}
+
+// LoginGuardService slows down password guessing per account and per client
+// address, and keeps an audit log of failed logins.
+type LoginGuardService struct {
+	q *sqlc_queries.Queries
+}
+
+// NewLoginGuardService creates a new LoginGuardService.
+func NewLoginGuardService(q *sqlc_queries.Queries) *LoginGuardService {
+	return &LoginGuardService{q: q}
+}
+
+// Q returns the underlying queries.
+func (s *LoginGuardService) Q() *sqlc_queries.Queries { return s.q }
+
+// CheckLogin returns how long the caller must wait before trying to log in to
+// email from ip, or zero when a login may be attempted.
+func (s *LoginGuardService) CheckLogin(ctx context.Context, email, ip string) (time.Duration, error) {
+	return s.lockWait(ctx, AccountKey(email), IPKey(ip))
+}
+
+// RecordLoginFailure logs a failed login and counts it against the account and
+// the address. It returns the lock now in force, if any.
+func (s *LoginGuardService) RecordLoginFailure(ctx context.Context, email, ip, userAgent, reason string) (time.Duration, error) {
+	if err := s.q.CreateLoginAttempt(ctx, sqlc_queries.CreateLoginAttemptParams{
+		Email: normalizeLoginEmail(email), IpAddress: ip, UserAgent: truncateRunes(userAgent, 512), Reason: reason,
+	}); err != nil {
+		return 0, eris.Wrap(err, "failed to record login attempt")
+	}
+	if reason == LoginFailedLocked {
+		// Attempts while locked are logged but do not extend the lock.
+		return 0, nil
+	}
+
+	accountLock, err := s.bump(ctx, AccountKey(email), AccountLoginThreshold, LoginFailureWindow)
+	if err != nil {
+		return 0, err
+	}
+	ipLock, err := s.bump(ctx, IPKey(ip), IPLoginThreshold, LoginFailureWindow)
+	if err != nil {
+		return 0, err
+	}
+	if accountLock > 0 {
+		slog.Warn("Account locked after failed logins", "email", email, "ip", ip, "lock", accountLock, "action", "login_locked")
+	}
+	if ipLock > 0 {
+		slog.Warn("Client address locked after failed logins", "ip", ip, "lock", ipLock, "action", "login_ip_locked")
+	}
+	return max(accountLock, ipLock), nil
+}
+
+// RecordLoginSuccess clears the account's failures. The address keeps its
+// count, so one valid account does not reset guessing against others.
+func (s *LoginGuardService) RecordLoginSuccess(ctx context.Context, email string) error {
+	_, err := s.q.DeleteLoginThrottle(ctx, AccountKey(email))
+	return eris.Wrap(err, "failed to reset login failures")
+}
+
+// CheckPasswordReset counts a password reset request and returns how long the
+// caller must wait when the account or address has asked too often.
+func (s *LoginGuardService) CheckPasswordReset(ctx context.Context, email, ip string) (time.Duration, error) {
+	wait, err := s.lockWait(ctx, resetAccountKey(email), resetIPKey(ip))
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+	if _, err := s.bump(ctx, resetAccountKey(email), PasswordResetAccountLimit, PasswordResetWindow); err != nil {
+		return 0, err
+	}
+	_, err = s.bump(ctx, resetIPKey(ip), PasswordResetIPLimit, PasswordResetWindow)
+	return 0, err
+}
+
+// Unlock clears the login and password reset counters of an email and/or an
+// address, returning how many were removed.
+func (s *LoginGuardService) Unlock(ctx context.Context, email, ip string) (int64, error) {
+	var keys []string
+	if strings.TrimSpace(email) != "" {
+		keys = append(keys, AccountKey(email), resetAccountKey(email))
+	}
+	if ip != "" {
+		keys = append(keys, IPKey(ip), resetIPKey(ip))
+	}
+	var total int64
+	for _, key := range keys {
+		n, err := s.q.DeleteLoginThrottle(ctx, key)
+		if err != nil {
+			return total, eris.Wrap(err, "failed to unlock")
+		}
+		total += n
+	}
+	target := strings.TrimSpace(email)
+	if target == "" {
+		target = ip
+	}
+	NewAuditService(s.q).Record(ctx, AuditEntry{
+		Action: AuditLoginUnlock, TargetType: "login", TargetID: target,
+		After: map[string]any{"email": email, "ip": ip, "removed": total},
+	})
+	return total, nil
+}
+
+// ListLocked returns the keys currently locked.
+func (s *LoginGuardService) ListLocked(ctx context.Context) ([]sqlc_queries.LoginThrottle, error) {
+	locks, err := s.q.ListLockedLoginThrottles(ctx)
+	return locks, eris.Wrap(err, "failed to list lockouts")
+}
+
+// ListAttempts returns a page of failed logins, optionally filtered by email or address.
+func (s *LoginGuardService) ListAttempts(ctx context.Context, email, ip string, limit, offset int32) ([]sqlc_queries.LoginAttempt, int64, error) {
+	email = normalizeLoginEmail(email)
+	attempts, err := s.q.ListLoginAttempts(ctx, sqlc_queries.ListLoginAttemptsParams{
+		Email: email, IpAddress: ip, PageLimit: limit, PageOffset: offset,
+	})
+	if err != nil {
+		return nil, 0, eris.Wrap(err, "failed to list login attempts")
+	}
+	total, err := s.q.CountLoginAttempts(ctx, sqlc_queries.CountLoginAttemptsParams{Email: email, IpAddress: ip})
+	if err != nil {
+		return nil, 0, eris.Wrap(err, "failed to count login attempts")
+	}
+	return attempts, total, nil
+}
+
+// RunPurger deletes old login attempts and idle counters every interval until ctx is done.
+func (s *LoginGuardService) RunPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.q.PurgeLoginAttempts(ctx); err != nil {
+			slog.Error("login attempt purge failed", "error", err)
+		} else if n > 0 {
+			slog.Info("purged old login attempts", "count", n)
+		}
+		if _, err := s.q.PurgeLoginThrottles(ctx); err != nil {
+			slog.Error("login throttle purge failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *LoginGuardService) lockWait(ctx context.Context, keys ...string) (time.Duration, error) {
+	secs, err := s.q.GetLoginLockSeconds(ctx, keys)
+	if err != nil {
+		return 0, eris.Wrap(err, "failed to check login lock")
+	}
+	return time.Duration(secs) * time.Second, nil
+}
+
+// bump counts a failure on key and locks it once threshold is reached.
+func (s *LoginGuardService) bump(ctx context.Context, key string, threshold int32, window time.Duration) (time.Duration, error) {
+	t, err := s.q.BumpLoginThrottle(ctx, sqlc_queries.BumpLoginThrottleParams{
+		Key: key, ResetAfterSeconds: int32(window / time.Second),
+	})
+	if err != nil {
+		return 0, eris.Wrap(err, "failed to count login failure")
+	}
+	lock := LockDuration(t.Failures, threshold)
+	if lock == 0 {
+		return 0, nil
+	}
+	if err := s.q.LockLoginThrottle(ctx, sqlc_queries.LockLoginThrottleParams{
+		LockSeconds: int32(lock / time.Second), Key: key,
+	}); err != nil {
+		return 0, eris.Wrap(err, "failed to lock login")
+	}
+	return lock, nil
+}
@@ -0,0 +1,32 @@
+package svc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockDuration(t *testing.T) {
+	cases := []struct {
+		failures int32
+		want     time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, 30 * time.Second},
+		{6, time.Minute},
+		{8, 4 * time.Minute},
+		{12, LoginLockMax},
+		{1000, LoginLockMax},
+	}
+	for _, tc := range cases {
+		if got := LockDuration(tc.failures, 5); got != tc.want {
+			t.Errorf("LockDuration(%d, 5) = %v, want %v", tc.failures, got, tc.want)
+		}
+	}
+}
+
+func TestAccountKeyNormalizesEmail(t *testing.T) {
+	if AccountKey(" Alice@Example.com ") != AccountKey("alice@example.com") {
+		t.Error("account keys should ignore case and surrounding spaces")
+	}
+}
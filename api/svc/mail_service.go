@@ -0,0 +1,233 @@
+package svc
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/auth"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/mailer"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// Purposes of tokens sent by email.
+const (
+	TokenPasswordReset = "password_reset"
+	TokenVerifyEmail   = "verify_email"
+	TokenInvite        = "invite"
+)
+
+// Lifetimes of tokens sent by email.
+const (
+	PasswordResetTokenTTL = time.Hour
+	VerifyEmailTokenTTL   = 48 * time.Hour
+	InviteTokenTTL        = 7 * 24 * time.Hour
+)
+
+// Mail delivery statuses.
+const (
+	MailStatusSent   = "sent"
+	MailStatusFailed = "failed"
+)
+
+const mailSendTimeout = 30 * time.Second
+
+// MailService sends templated account emails and tracks their delivery.
+type MailService struct {
+	q       *sqlc_queries.Queries
+	sender  mailer.Sender
+	baseURL string
+}
+
+// NewMailService creates a new MailService. baseURL is the public URL of the web app.
+func NewMailService(q *sqlc_queries.Queries, sender mailer.Sender, baseURL string) *MailService {
+	return &MailService{q: q, sender: sender, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Q returns the underlying queries.
+func (s *MailService) Q() *sqlc_queries.Queries { return s.q }
+
+// Send renders a template, delivers it and records the outcome in mail_delivery.
+func (s *MailService) Send(ctx context.Context, userID int32, to, template string, data any) error {
+	msg, err := mailer.Render(template, to, data)
+	if err != nil {
+		return err
+	}
+
+	delivery, err := s.q.CreateMailDelivery(ctx, sqlc_queries.CreateMailDeliveryParams{
+		UserID:    sql.NullInt32{Int32: userID, Valid: userID != 0},
+		ToAddress: to,
+		Template:  template,
+		Subject:   msg.Subject,
+		Driver:    s.sender.Driver(),
+	})
+	if err != nil {
+		return eris.Wrap(err, "failed to record mail delivery")
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, mailSendTimeout)
+	defer cancel()
+	sendErr := s.sender.Send(sendCtx, msg)
+
+	status, errText := MailStatusSent, ""
+	if sendErr != nil {
+		status, errText = MailStatusFailed, sendErr.Error()
+	}
+	if err := s.q.UpdateMailDeliveryStatus(ctx, sqlc_queries.UpdateMailDeliveryStatusParams{
+		ID: delivery.ID, Status: status, Error: errText,
+	}); err != nil {
+		slog.Error("Failed to update mail delivery status", "id", delivery.ID, "error", err)
+	}
+	return eris.Wrap(sendErr, "failed to send email")
+}
+
+// IssueToken creates a single-use token for userID, revoking earlier unused tokens of the same purpose.
+func (s *MailService) IssueToken(ctx context.Context, userID int32, purpose string, ttl time.Duration) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", eris.Wrap(err, "failed to generate token")
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := s.q.InvalidateAuthUserTokens(ctx, sqlc_queries.InvalidateAuthUserTokensParams{
+		UserID: userID, Purpose: purpose,
+	}); err != nil {
+		return "", eris.Wrap(err, "failed to revoke previous tokens")
+	}
+	if _, err := s.q.CreateAuthUserToken(ctx, sqlc_queries.CreateAuthUserTokenParams{
+		UserID: userID, Purpose: purpose, TokenHash: hashToken(token), ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return "", eris.Wrap(err, "failed to store token")
+	}
+	return token, nil
+}
+
+// ConsumeToken marks a valid token as used and returns it.
+// Unknown, expired or used tokens return dto.ErrAuthInvalidLinkToken.
+func (s *MailService) ConsumeToken(ctx context.Context, token string, purposes ...string) (sqlc_queries.AuthUserToken, error) {
+	t, err := s.q.ConsumeAuthUserToken(ctx, sqlc_queries.ConsumeAuthUserTokenParams{
+		TokenHash: hashToken(token), Purposes: purposes,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return t, dto.ErrAuthInvalidLinkToken
+		}
+		return t, eris.Wrap(err, "failed to consume token")
+	}
+	return t, nil
+}
+
+// SendPasswordReset emails the user a link to choose a new password.
+func (s *MailService) SendPasswordReset(ctx context.Context, user sqlc_queries.AuthUser) error {
+	token, err := s.IssueToken(ctx, user.ID, TokenPasswordReset, PasswordResetTokenTTL)
+	if err != nil {
+		return err
+	}
+	return s.Send(ctx, user.ID, user.Email, mailer.TemplatePasswordReset, map[string]string{
+		"Name": displayName(user), "Link": s.link("reset-password", token), "ExpiresIn": "1 hour",
+	})
+}
+
+// SendVerification emails the user a link to confirm their address.
+func (s *MailService) SendVerification(ctx context.Context, user sqlc_queries.AuthUser) error {
+	token, err := s.IssueToken(ctx, user.ID, TokenVerifyEmail, VerifyEmailTokenTTL)
+	if err != nil {
+		return err
+	}
+	return s.Send(ctx, user.ID, user.Email, mailer.TemplateVerifyEmail, map[string]string{
+		"Name": displayName(user), "Link": s.link("verify-email", token), "ExpiresIn": "48 hours",
+	})
+}
+
+// SendInvite emails an invited user a link to set their password.
+func (s *MailService) SendInvite(ctx context.Context, user sqlc_queries.AuthUser, invitedBy string) error {
+	token, err := s.IssueToken(ctx, user.ID, TokenInvite, InviteTokenTTL)
+	if err != nil {
+		return err
+	}
+	return s.Send(ctx, user.ID, user.Email, mailer.TemplateInvite, map[string]string{
+		"InvitedBy": invitedBy, "Link": s.link("reset-password", token), "ExpiresIn": "7 days",
+	})
+}
+
+// ResetPassword sets a new password using a reset or invite token. Following
+// the link proves ownership of the address, so it is marked verified too.
+func (s *MailService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	t, err := s.ConsumeToken(ctx, token, TokenPasswordReset, TokenInvite)
+	if err != nil {
+		return err
+	}
+	hash, err := auth.GeneratePasswordHash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.q.UpdateUserPasswordByID(ctx, sqlc_queries.UpdateUserPasswordByIDParams{ID: t.UserID, Password: hash}); err != nil {
+		return eris.Wrap(err, "failed to update password")
+	}
+	// whoever knew the old password must not stay signed in
+	if _, err := s.q.RevokeAuthSessionsByUserID(ctx, sqlc_queries.RevokeAuthSessionsByUserIDParams{
+		UserID: t.UserID, Reason: SessionRevokedPasswordReset,
+	}); err != nil {
+		return eris.Wrap(err, "failed to revoke sessions")
+	}
+	target := ""
+	if user, err := s.q.GetAuthUserByID(ctx, t.UserID); err == nil {
+		target = user.Email
+	}
+	NewAuditService(s.q).Record(ctx, AuditEntry{
+		Action: AuditUserPasswordReset, TargetType: "user", TargetID: target, ActorID: t.UserID,
+		Before: map[string]string{"password": "old"}, After: map[string]string{"password": "new"},
+	})
+	return s.SetEmailVerified(ctx, t.UserID, true)
+}
+
+// VerifyEmail marks the token owner's email address as verified.
+func (s *MailService) VerifyEmail(ctx context.Context, token string) error {
+	t, err := s.ConsumeToken(ctx, token, TokenVerifyEmail)
+	if err != nil {
+		return err
+	}
+	return s.SetEmailVerified(ctx, t.UserID, true)
+}
+
+// SetEmailVerified sets the verification flag of a user's email address.
+func (s *MailService) SetEmailVerified(ctx context.Context, userID int32, verified bool) error {
+	return eris.Wrap(s.q.SetAuthUserEmailVerified(ctx, sqlc_queries.SetAuthUserEmailVerifiedParams{
+		ID: userID, EmailVerified: verified,
+	}), "failed to update email verification")
+}
+
+// ListDeliveries returns a page of sent emails, newest first, with the total count.
+func (s *MailService) ListDeliveries(ctx context.Context, status, to string, limit, offset int32) ([]sqlc_queries.MailDelivery, int64, error) {
+	ds, err := s.q.ListMailDeliveries(ctx, sqlc_queries.ListMailDeliveriesParams{
+		Status: status, ToAddress: to, PageLimit: limit, PageOffset: offset,
+	})
+	if err != nil {
+		return nil, 0, eris.Wrap(err, "failed to list mail deliveries")
+	}
+	total, err := s.q.CountMailDeliveries(ctx, sqlc_queries.CountMailDeliveriesParams{Status: status, ToAddress: to})
+	if err != nil {
+		return nil, 0, eris.Wrap(err, "failed to count mail deliveries")
+	}
+	return ds, total, nil
+}
+
+// link builds a web app link. The app uses hash routing.
+func (s *MailService) link(page, token string) string {
+	return s.baseURL + "/#/" + page + "?token=" + url.QueryEscape(token)
+}
+
+func displayName(user sqlc_queries.AuthUser) string {
+	if name := strings.TrimSpace(user.FirstName + " " + user.LastName); name != "" {
+		return name
+	}
+	return user.Email
+}
@@ -0,0 +1,66 @@
+package svc
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// UserChatModelPrivilegeService grants users per-model rate limits and
+// records each change in the audit trail. Errors are returned unwrapped so
+// callers can tell sql.ErrNoRows apart.
+type UserChatModelPrivilegeService struct {
+	q *sqlc_queries.Queries
+}
+
+// NewUserChatModelPrivilegeService creates a new UserChatModelPrivilegeService.
+func NewUserChatModelPrivilegeService(q *sqlc_queries.Queries) *UserChatModelPrivilegeService {
+	return &UserChatModelPrivilegeService{q: q}
+}
+
+// Q returns the underlying queries.
+func (s *UserChatModelPrivilegeService) Q() *sqlc_queries.Queries { return s.q }
+
+// Create grants a user a rate limit on a model.
+func (s *UserChatModelPrivilegeService) Create(ctx context.Context, params sqlc_queries.CreateUserChatModelPrivilegeParams) (sqlc_queries.UserChatModelPrivilege, error) {
+	p, err := s.q.CreateUserChatModelPrivilege(ctx, params)
+	if err != nil {
+		return p, err
+	}
+	s.audit(ctx, AuditModelPrivilegeCreate, p.ID, nil, p)
+	return p, nil
+}
+
+// Update changes the rate limit of a privilege.
+func (s *UserChatModelPrivilegeService) Update(ctx context.Context, params sqlc_queries.UpdateUserChatModelPrivilegeParams) (sqlc_queries.UserChatModelPrivilege, error) {
+	before, err := s.q.UserChatModelPrivilegeByID(ctx, params.ID)
+	if err != nil {
+		return before, err
+	}
+	p, err := s.q.UpdateUserChatModelPrivilege(ctx, params)
+	if err != nil {
+		return p, err
+	}
+	s.audit(ctx, AuditModelPrivilegeUpdate, p.ID, before, p)
+	return p, nil
+}
+
+// Delete revokes a privilege.
+func (s *UserChatModelPrivilegeService) Delete(ctx context.Context, id int32) error {
+	before, err := s.q.UserChatModelPrivilegeByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.q.DeleteUserChatModelPrivilege(ctx, id); err != nil {
+		return err
+	}
+	s.audit(ctx, AuditModelPrivilegeDelete, id, before, nil)
+	return nil
+}
+
+func (s *UserChatModelPrivilegeService) audit(ctx context.Context, action string, id int32, before, after any) {
+	NewAuditService(s.q).Record(ctx, AuditEntry{
+		Action: action, TargetType: "model_privilege", TargetID: strconv.Itoa(int(id)), Before: before, After: after,
+	})
+}
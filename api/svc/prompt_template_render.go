@@ -0,0 +1,39 @@
+package svc
+
+import (
+	"regexp"
+)
+
+// templateVarPattern matches {{name}} placeholders, allowing surrounding spaces.
+var templateVarPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// ExtractTemplateVariables returns the distinct placeholder names in content, in order of appearance.
+func ExtractTemplateVariables(content string) []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, m := range templateVarPattern.FindAllStringSubmatch(content, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// RenderPromptTemplate substitutes vars into content.
+// Placeholders without a value are left untouched and returned in missing.
+func RenderPromptTemplate(content string, vars map[string]string) (rendered string, missing []string) {
+	seen := map[string]bool{}
+	rendered = templateVarPattern.ReplaceAllStringFunc(content, func(placeholder string) string {
+		name := templateVarPattern.FindStringSubmatch(placeholder)[1]
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		if !seen[name] {
+			seen[name] = true
+			missing = append(missing, name)
+		}
+		return placeholder
+	})
+	return rendered, missing
+}
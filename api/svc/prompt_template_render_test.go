@@ -0,0 +1,48 @@
+package svc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractTemplateVariables(t *testing.T) {
+	got := ExtractTemplateVariables("Translate {{text}} into {{ language }}. Keep {{text}} short. {{ not valid-name }}")
+	want := []string{"text", "language"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractTemplateVariables() = %v, want %v", got, want)
+	}
+
+	if got := ExtractTemplateVariables("no placeholders"); len(got) != 0 {
+		t.Errorf("expected no variables, got %v", got)
+	}
+}
+
+func TestRenderPromptTemplate(t *testing.T) {
+	rendered, missing := RenderPromptTemplate("You are a {{role}} speaking {{ lang }}.", map[string]string{
+		"role": "reviewer",
+		"lang": "French",
+	})
+	if rendered != "You are a reviewer speaking French." {
+		t.Errorf("unexpected render: %q", rendered)
+	}
+	if len(missing) != 0 {
+		t.Errorf("expected no missing variables, got %v", missing)
+	}
+}
+
+func TestRenderPromptTemplateMissing(t *testing.T) {
+	rendered, missing := RenderPromptTemplate("{{a}} and {{b}} and {{b}}", map[string]string{"a": "x"})
+	if rendered != "x and {{b}} and {{b}}" {
+		t.Errorf("unexpected render: %q", rendered)
+	}
+	if !reflect.DeepEqual(missing, []string{"b"}) {
+		t.Errorf("missing = %v, want [b]", missing)
+	}
+}
+
+func TestRenderPromptTemplateEmptyValue(t *testing.T) {
+	rendered, missing := RenderPromptTemplate("[{{x}}]", map[string]string{"x": ""})
+	if rendered != "[]" || len(missing) != 0 {
+		t.Errorf("got %q, missing %v", rendered, missing)
+	}
+}
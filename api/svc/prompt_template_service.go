@@ -0,0 +1,136 @@
+package svc
+
+import (
+	"context"
+
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// Prompt template visibility values.
+const (
+	PromptTemplatePrivate = "private"
+	PromptTemplatePublic  = "public"
+)
+
+// PromptTemplateService manages the reusable prompt template library.
+type PromptTemplateService struct {
+	q *sqlc_queries.Queries
+}
+
+// NewPromptTemplateService creates a new PromptTemplateService.
+func NewPromptTemplateService(q *sqlc_queries.Queries) *PromptTemplateService {
+	return &PromptTemplateService{q: q}
+}
+
+// Q returns the underlying queries.
+func (s *PromptTemplateService) Q() *sqlc_queries.Queries { return s.q }
+
+// CreateTemplate creates a template together with its first version.
+func (s *PromptTemplateService) CreateTemplate(ctx context.Context, params sqlc_queries.CreatePromptTemplateParams, content, model string) (sqlc_queries.PromptTemplate, sqlc_queries.PromptTemplateVersion, error) {
+	var (
+		t sqlc_queries.PromptTemplate
+		v sqlc_queries.PromptTemplateVersion
+	)
+	err := s.q.InTx(ctx, func(q *sqlc_queries.Queries) error {
+		var err error
+		t, err = q.CreatePromptTemplate(ctx, params)
+		if err != nil {
+			return eris.Wrap(err, "failed to create prompt template")
+		}
+		v, err = q.CreatePromptTemplateVersion(ctx, sqlc_queries.CreatePromptTemplateVersionParams{
+			TemplateID: t.ID, Content: content, Model: model, CreatedBy: params.UserID,
+		})
+		return eris.Wrap(err, "failed to create prompt template version")
+	})
+	if err != nil {
+		return sqlc_queries.PromptTemplate{}, sqlc_queries.PromptTemplateVersion{}, err
+	}
+	return t, v, nil
+}
+
+func (s *PromptTemplateService) GetTemplateByUUID(ctx context.Context, uuid string) (sqlc_queries.PromptTemplate, error) {
+	t, err := s.q.GetPromptTemplateByUUID(ctx, uuid)
+	return t, eris.Wrap(err, "failed to retrieve prompt template")
+}
+
+// HasAccess reports whether the user owns the template, it is public, or it was shared with them.
+func (s *PromptTemplateService) HasAccess(ctx context.Context, uuid string, userID int32) (bool, error) {
+	ok, err := s.q.HasPromptTemplateAccess(ctx, sqlc_queries.HasPromptTemplateAccessParams{Uuid: uuid, UserID: userID})
+	return ok, eris.Wrap(err, "failed to check prompt template access")
+}
+
+// ListAccessibleTemplates returns templates visible to the user, most used first.
+func (s *PromptTemplateService) ListAccessibleTemplates(ctx context.Context, params sqlc_queries.ListAccessiblePromptTemplatesParams) ([]sqlc_queries.ListAccessiblePromptTemplatesRow, error) {
+	ts, err := s.q.ListAccessiblePromptTemplates(ctx, params)
+	return ts, eris.Wrap(err, "failed to list prompt templates")
+}
+
+func (s *PromptTemplateService) UpdateTemplate(ctx context.Context, params sqlc_queries.UpdatePromptTemplateParams) (sqlc_queries.PromptTemplate, error) {
+	t, err := s.q.UpdatePromptTemplate(ctx, params)
+	return t, eris.Wrap(err, "failed to update prompt template")
+}
+
+func (s *PromptTemplateService) DeleteTemplate(ctx context.Context, uuid string) error {
+	return eris.Wrap(s.q.DeletePromptTemplate(ctx, uuid), "failed to delete prompt template")
+}
+
+// --- Versions ---
+
+// AddVersion stores a new version and makes it current.
+func (s *PromptTemplateService) AddVersion(ctx context.Context, t sqlc_queries.PromptTemplate, content, model string, userID int32) (sqlc_queries.PromptTemplate, sqlc_queries.PromptTemplateVersion, error) {
+	v, err := s.q.CreatePromptTemplateVersion(ctx, sqlc_queries.CreatePromptTemplateVersionParams{
+		TemplateID: t.ID, Content: content, Model: model, CreatedBy: userID,
+	})
+	if err != nil {
+		return t, sqlc_queries.PromptTemplateVersion{}, eris.Wrap(err, "failed to create prompt template version")
+	}
+	t, err = s.SetCurrentVersion(ctx, t.ID, v.Version)
+	return t, v, err
+}
+
+// SetCurrentVersion points the template at an existing version, e.g. to roll back.
+func (s *PromptTemplateService) SetCurrentVersion(ctx context.Context, templateID, version int32) (sqlc_queries.PromptTemplate, error) {
+	t, err := s.q.SetPromptTemplateCurrentVersion(ctx, sqlc_queries.SetPromptTemplateCurrentVersionParams{
+		ID: templateID, CurrentVersion: version,
+	})
+	return t, eris.Wrap(err, "failed to set prompt template version")
+}
+
+func (s *PromptTemplateService) GetVersion(ctx context.Context, templateID, version int32) (sqlc_queries.PromptTemplateVersion, error) {
+	v, err := s.q.GetPromptTemplateVersion(ctx, sqlc_queries.GetPromptTemplateVersionParams{
+		TemplateID: templateID, Version: version,
+	})
+	return v, eris.Wrap(err, "failed to retrieve prompt template version")
+}
+
+func (s *PromptTemplateService) ListVersions(ctx context.Context, templateID int32) ([]sqlc_queries.PromptTemplateVersion, error) {
+	vs, err := s.q.ListPromptTemplateVersions(ctx, templateID)
+	return vs, eris.Wrap(err, "failed to list prompt template versions")
+}
+
+// --- Sharing ---
+
+func (s *PromptTemplateService) Share(ctx context.Context, templateID, userID int32) error {
+	return eris.Wrap(s.q.SharePromptTemplate(ctx, sqlc_queries.SharePromptTemplateParams{
+		TemplateID: templateID, UserID: userID,
+	}), "failed to share prompt template")
+}
+
+func (s *PromptTemplateService) Unshare(ctx context.Context, templateID, userID int32) error {
+	return eris.Wrap(s.q.UnsharePromptTemplate(ctx, sqlc_queries.UnsharePromptTemplateParams{
+		TemplateID: templateID, UserID: userID,
+	}), "failed to unshare prompt template")
+}
+
+func (s *PromptTemplateService) ListShares(ctx context.Context, templateID int32) ([]sqlc_queries.ListPromptTemplateSharesRow, error) {
+	shares, err := s.q.ListPromptTemplateShares(ctx, templateID)
+	return shares, eris.Wrap(err, "failed to list prompt template shares")
+}
+
+// --- Usage ---
+
+// RecordUsage bumps the usage counter when a template is used to start a session.
+func (s *PromptTemplateService) RecordUsage(ctx context.Context, templateID int32) error {
+	return eris.Wrap(s.q.IncrementPromptTemplateUsage(ctx, templateID), "failed to record prompt template usage")
+}
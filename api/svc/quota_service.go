@@ -0,0 +1,358 @@
+package svc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// What a quota policy counts.
+const (
+	// QuotaMetricRequests counts model answers.
+	QuotaMetricRequests = "requests"
+	// QuotaMetricTokens counts the tokens of messages sent and received.
+	QuotaMetricTokens = "tokens"
+)
+
+// Sources of model usage recorded with RecordUsage, for requests that leave no
+// chat message.
+const (
+	UsageSourceBot           = "bot"
+	UsageSourceTranscription = "transcription"
+	UsageSourceSpeech        = "speech"
+)
+
+// quotaWindows are the sliding windows a policy may use.
+var quotaWindows = map[string]time.Duration{
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+	"month":  30 * 24 * time.Hour,
+}
+
+// legacyQuotaWindow is the window of the older per-user and per-model rate
+// limits (auth_user_management and user_chat_model_privilege), which still apply.
+const legacyQuotaWindow = 10 * time.Minute
+
+// quotaRule is a limit to check, from a policy or a legacy rate limit.
+type quotaRule struct {
+	name        string
+	window      time.Duration
+	windowName  string
+	metric      string
+	limit       int64
+	workspaceID sql.NullInt32
+	// model narrows counting to one chat model; empty counts all.
+	model string
+}
+
+// QuotaPolicyInput creates or updates a quota policy. Empty targets match
+// every user, workspace or model.
+type QuotaPolicyInput struct {
+	Name          string
+	UserEmail     string
+	WorkspaceUUID string
+	ChatModelName string
+	Window        string
+	Metric        string
+	Limit         int64
+	Enabled       bool
+}
+
+// QuotaService evaluates quota policies for chat requests: the user-wide
+// limits before a request is routed (RateLimitByUserID), the workspace and
+// model limits once the model is known (CheckModelAccess). Each limit is
+// checked once. Usage is counted from chat messages, and the usage recorded
+// with RecordUsage, over sliding windows.
+type QuotaService struct {
+	q            *sqlc_queries.Queries
+	defaultLimit int32
+}
+
+// NewQuotaService creates a new QuotaService. defaultLimit is the per-user
+// rate limit for users without one.
+func NewQuotaService(q *sqlc_queries.Queries, defaultLimit int32) *QuotaService {
+	return &QuotaService{q: q, defaultLimit: defaultLimit}
+}
+
+// Q returns the underlying queries.
+func (s *QuotaService) Q() *sqlc_queries.Queries { return s.q }
+
+// CheckUserQuota evaluates the policies covering all of a user's requests. It
+// returns the tightest quota, and a dto.ErrTooManyRequests once one is used up.
+func (s *QuotaService) CheckUserQuota(ctx context.Context, userID int32) (*dto.RateLimit, error) {
+	rules, err := s.rules(ctx, userID, sql.NullInt32{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.check(ctx, userID, rules)
+}
+
+// CheckModelAccess evaluates the limits of a request to model in a chat
+// session: those of the session's workspace and the model. The user-wide
+// ones are left to CheckUserQuota.
+func (s *QuotaService) CheckModelAccess(ctx context.Context, chatSessionUuid, model string, userID int32) (*dto.RateLimit, error) {
+	chatModel, err := s.q.ChatModelByName(ctx, model)
+	if err != nil {
+		return nil, dto.ErrResourceNotFound("chat model: " + model).WithDebugInfo(err.Error())
+	}
+	var workspaceID sql.NullInt32
+	session, err := s.q.GetChatSessionByUUID(ctx, chatSessionUuid)
+	if err == nil {
+		workspaceID = session.WorkspaceID
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, dto.WrapError(dto.MapDatabaseError(err), "Failed to get chat session")
+	}
+	rules, err := s.rules(ctx, userID, workspaceID, &chatModel)
+	if err != nil {
+		return nil, err
+	}
+	return s.check(ctx, userID, rules)
+}
+
+// RecordUsage counts a request to model that leaves no chat message towards
+// the user's quotas. tokens may be an estimate.
+func (s *QuotaService) RecordUsage(ctx context.Context, userID int32, workspaceID sql.NullInt32, model, source string, tokens int32) error {
+	return eris.Wrap(s.q.CreateModelUsage(ctx, sqlc_queries.CreateModelUsageParams{
+		UserID: userID, WorkspaceID: workspaceID, Model: model, Source: source, Tokens: tokens,
+	}), "failed to record model usage")
+}
+
+// rules returns the limits to check. Without chatModel these are the
+// user-wide ones: the legacy per-user rate limit and policies naming no
+// workspace or model. With it, only the limits scoped to the workspace or
+// model, as the user-wide ones were checked before routing.
+func (s *QuotaService) rules(ctx context.Context, userID int32, workspaceID sql.NullInt32, chatModel *sqlc_queries.ChatModel) ([]quotaRule, error) {
+	var rules []quotaRule
+	var chatModelID sql.NullInt32
+	if chatModel == nil {
+		rate, err := s.q.GetRateLimit(ctx, userID)
+		if err != nil {
+			rate = s.defaultLimit
+		}
+		rules = append(rules, quotaRule{
+			name: "rate limit", window: legacyQuotaWindow, windowName: "10 minutes",
+			metric: QuotaMetricRequests, limit: int64(rate),
+		})
+	} else {
+		chatModelID = sql.NullInt32{Int32: chatModel.ID, Valid: true}
+		if chatModel.EnablePerModeRatelimit {
+			privilege, err := s.q.UserChatModelPrivilegeByUserAndModelID(ctx, sqlc_queries.UserChatModelPrivilegeByUserAndModelIDParams{
+				UserID: userID, ChatModelID: chatModel.ID,
+			})
+			if err == nil {
+				rules = append(rules, quotaRule{
+					name: chatModel.Name, window: legacyQuotaWindow, windowName: "10 minutes",
+					metric: QuotaMetricRequests, limit: int64(privilege.RateLimit), model: chatModel.Name,
+				})
+			} else if !errors.Is(err, sql.ErrNoRows) {
+				return nil, dto.WrapError(dto.MapDatabaseError(err), "Failed to get rate limit")
+			}
+		}
+	}
+
+	policies, err := s.q.ListMatchingQuotaPolicies(ctx, sqlc_queries.ListMatchingQuotaPoliciesParams{
+		UserID: userID, WorkspaceID: workspaceID, ChatModelID: chatModelID,
+	})
+	if err != nil {
+		return nil, dto.WrapError(dto.MapDatabaseError(err), "Failed to get quota policies")
+	}
+	policies = effectiveQuotaPolicies(policies)
+	if chatModel != nil {
+		policies = scopedQuotaPolicies(policies)
+	}
+	for _, p := range policies {
+		rule := quotaRule{
+			name: p.Name, window: quotaWindows[p.TimeWindow], windowName: p.TimeWindow,
+			metric: p.Metric, limit: p.QuotaLimit, workspaceID: p.WorkspaceID,
+		}
+		if p.ChatModelID.Valid && chatModel != nil {
+			rule.model = chatModel.Name
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// scopedQuotaPolicies keeps the policies for a workspace or a model.
+func scopedQuotaPolicies(policies []sqlc_queries.QuotaPolicy) []sqlc_queries.QuotaPolicy {
+	var scoped []sqlc_queries.QuotaPolicy
+	for _, p := range policies {
+		if p.WorkspaceID.Valid || p.ChatModelID.Valid {
+			scoped = append(scoped, p)
+		}
+	}
+	return scoped
+}
+
+// effectiveQuotaPolicies drops policies overridden by one for the same window,
+// metric, workspace and model that names the user.
+func effectiveQuotaPolicies(policies []sqlc_queries.QuotaPolicy) []sqlc_queries.QuotaPolicy {
+	key := func(p sqlc_queries.QuotaPolicy) string {
+		return fmt.Sprintf("%s/%s/%v/%v", p.TimeWindow, p.Metric, p.WorkspaceID, p.ChatModelID)
+	}
+	personal := map[string]bool{}
+	for _, p := range policies {
+		if p.UserID.Valid {
+			personal[key(p)] = true
+		}
+	}
+	var effective []sqlc_queries.QuotaPolicy
+	for _, p := range policies {
+		if _, ok := quotaWindows[p.TimeWindow]; !ok {
+			continue
+		}
+		if !p.UserID.Valid && personal[key(p)] {
+			continue
+		}
+		effective = append(effective, p)
+	}
+	return effective
+}
+
+func (s *QuotaService) check(ctx context.Context, userID int32, rules []quotaRule) (*dto.RateLimit, error) {
+	var tightest *dto.RateLimit
+	for _, rule := range rules {
+		usage, err := s.q.GetQuotaUsage(ctx, sqlc_queries.GetQuotaUsageParams{
+			WindowSeconds: int32(rule.window.Seconds()), UserID: userID,
+			WorkspaceID: rule.workspaceID, Model: rule.model,
+		})
+		if err != nil {
+			return nil, dto.ErrInternalUnexpected.WithDetail("Could not get usage for rate limiting").WithDebugInfo(err.Error())
+		}
+		used, reset := usage.Requests, usage.RequestsResetSeconds
+		if rule.metric == QuotaMetricTokens {
+			used, reset = usage.Tokens, usage.TokensResetSeconds
+		}
+		limit := &dto.RateLimit{Limit: rule.limit, Remaining: max(rule.limit-used, 0), Reset: time.Duration(reset) * time.Second}
+		if used >= rule.limit {
+			apiErr := dto.ErrTooManyRequests
+			apiErr.Message = "Rate limit exceeded: " + rule.name
+			apiErr.Detail = fmt.Sprintf("Used %d of %d %s per %s", used, rule.limit, rule.metric, rule.windowName)
+			apiErr.RateLimit = limit
+			return limit, apiErr
+		}
+		if tightest == nil || limit.Remaining < tightest.Remaining {
+			tightest = limit
+		}
+	}
+	return tightest, nil
+}
+
+// --- Policy management ---
+
+// ListPolicies returns every quota policy with the names of its targets.
+func (s *QuotaService) ListPolicies(ctx context.Context) ([]sqlc_queries.ListQuotaPoliciesRow, error) {
+	policies, err := s.q.ListQuotaPolicies(ctx)
+	return policies, eris.Wrap(err, "failed to list quota policies")
+}
+
+// CreatePolicy adds a quota policy.
+func (s *QuotaService) CreatePolicy(ctx context.Context, in QuotaPolicyInput) (sqlc_queries.QuotaPolicy, error) {
+	params, err := s.policyParams(ctx, in)
+	if err != nil {
+		return sqlc_queries.QuotaPolicy{}, err
+	}
+	p, err := s.q.CreateQuotaPolicy(ctx, params)
+	if err != nil {
+		return p, eris.Wrap(err, "failed to create quota policy")
+	}
+	s.audit(ctx, AuditQuotaPolicyCreate, p.ID, nil, p)
+	return p, nil
+}
+
+// UpdatePolicy replaces a quota policy.
+func (s *QuotaService) UpdatePolicy(ctx context.Context, id int32, in QuotaPolicyInput) (sqlc_queries.QuotaPolicy, error) {
+	before, err := s.q.GetQuotaPolicy(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return before, dto.ErrResourceNotFound("quota policy")
+	}
+	if err != nil {
+		return before, eris.Wrap(err, "failed to get quota policy")
+	}
+	params, err := s.policyParams(ctx, in)
+	if err != nil {
+		return before, err
+	}
+	p, err := s.q.UpdateQuotaPolicy(ctx, sqlc_queries.UpdateQuotaPolicyParams{
+		ID: id, Name: params.Name, UserID: params.UserID, WorkspaceID: params.WorkspaceID, ChatModelID: params.ChatModelID,
+		TimeWindow: params.TimeWindow, Metric: params.Metric, QuotaLimit: params.QuotaLimit, Enabled: params.Enabled,
+	})
+	if err != nil {
+		return p, eris.Wrap(err, "failed to update quota policy")
+	}
+	s.audit(ctx, AuditQuotaPolicyUpdate, id, before, p)
+	return p, nil
+}
+
+// DeletePolicy removes a quota policy.
+func (s *QuotaService) DeletePolicy(ctx context.Context, id int32) error {
+	before, err := s.q.GetQuotaPolicy(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return dto.ErrResourceNotFound("quota policy")
+	}
+	if err != nil {
+		return eris.Wrap(err, "failed to get quota policy")
+	}
+	if _, err := s.q.DeleteQuotaPolicy(ctx, id); err != nil {
+		return eris.Wrap(err, "failed to delete quota policy")
+	}
+	s.audit(ctx, AuditQuotaPolicyDelete, id, before, nil)
+	return nil
+}
+
+// policyParams validates in and resolves its targets to IDs.
+func (s *QuotaService) policyParams(ctx context.Context, in QuotaPolicyInput) (sqlc_queries.CreateQuotaPolicyParams, error) {
+	params := sqlc_queries.CreateQuotaPolicyParams{
+		Name: truncateRunes(strings.TrimSpace(in.Name), 255), TimeWindow: in.Window, Metric: in.Metric,
+		QuotaLimit: in.Limit, Enabled: in.Enabled,
+	}
+	if params.Name == "" {
+		return params, dto.ErrValidationInvalidInput("name is required")
+	}
+	if _, ok := quotaWindows[in.Window]; !ok {
+		return params, dto.ErrValidationInvalidInput("window must be minute, hour, day or month")
+	}
+	if in.Metric != QuotaMetricRequests && in.Metric != QuotaMetricTokens {
+		return params, dto.ErrValidationInvalidInput("metric must be requests or tokens")
+	}
+	if in.Limit <= 0 {
+		return params, dto.ErrValidationInvalidInput("limit must be positive")
+	}
+	if email := strings.TrimSpace(in.UserEmail); email != "" {
+		user, err := s.q.GetAuthUserByEmail(ctx, email)
+		if err != nil {
+			return params, dto.ErrResourceNotFound("user").WithDebugInfo(err.Error())
+		}
+		params.UserID = sql.NullInt32{Int32: user.ID, Valid: true}
+	}
+	if in.WorkspaceUUID != "" {
+		workspace, err := s.q.GetWorkspaceByUUID(ctx, in.WorkspaceUUID)
+		if err != nil {
+			return params, dto.ErrResourceNotFound("workspace").WithDebugInfo(err.Error())
+		}
+		params.WorkspaceID = sql.NullInt32{Int32: workspace.ID, Valid: true}
+	}
+	if in.ChatModelName != "" {
+		chatModel, err := s.q.ChatModelByName(ctx, in.ChatModelName)
+		if err != nil {
+			return params, dto.ErrResourceNotFound("chat model").WithDebugInfo(err.Error())
+		}
+		params.ChatModelID = sql.NullInt32{Int32: chatModel.ID, Valid: true}
+	}
+	return params, nil
+}
+
+func (s *QuotaService) audit(ctx context.Context, action string, id int32, before, after any) {
+	NewAuditService(s.q).Record(ctx, AuditEntry{
+		Action: action, TargetType: "quota_policy", TargetID: strconv.Itoa(int(id)), Before: before, After: after,
+	})
+}
@@ -0,0 +1,69 @@
+package svc
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+func TestEffectiveQuotaPolicies(t *testing.T) {
+	user := sql.NullInt32{Int32: 7, Valid: true}
+	model := sql.NullInt32{Int32: 3, Valid: true}
+	policies := []sqlc_queries.QuotaPolicy{
+		{ID: 1, Name: "global hourly", TimeWindow: "hour", Metric: QuotaMetricRequests},
+		{ID: 2, Name: "personal hourly", TimeWindow: "hour", Metric: QuotaMetricRequests, UserID: user},
+		{ID: 3, Name: "global daily tokens", TimeWindow: "day", Metric: QuotaMetricTokens},
+		{ID: 4, Name: "model hourly", TimeWindow: "hour", Metric: QuotaMetricRequests, ChatModelID: model},
+		{ID: 5, Name: "unknown window", TimeWindow: "fortnight", Metric: QuotaMetricRequests},
+	}
+
+	var ids []int32
+	for _, p := range effectiveQuotaPolicies(policies) {
+		ids = append(ids, p.ID)
+	}
+	want := []int32{2, 3, 4}
+	if len(ids) != len(want) {
+		t.Fatalf("effective policies = %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("effective policies = %v, want %v", ids, want)
+		}
+	}
+}
+
+func TestScopedQuotaPolicies(t *testing.T) {
+	policies := []sqlc_queries.QuotaPolicy{
+		{ID: 1, Name: "user hourly", UserID: sql.NullInt32{Int32: 7, Valid: true}},
+		{ID: 2, Name: "workspace hourly", WorkspaceID: sql.NullInt32{Int32: 2, Valid: true}},
+		{ID: 3, Name: "model hourly", ChatModelID: sql.NullInt32{Int32: 3, Valid: true}},
+		{ID: 4, Name: "global hourly"},
+	}
+	scoped := scopedQuotaPolicies(policies)
+	if len(scoped) != 2 || scoped[0].ID != 2 || scoped[1].ID != 3 {
+		t.Errorf("scoped policies = %+v, want workspace and model ones", scoped)
+	}
+}
+
+func TestRecordUsageCountsTowardsQuota(t *testing.T) {
+	q := sqlc_queries.New(testDB)
+	ctx := context.Background()
+	s := NewQuotaService(q, 100)
+	for _, source := range []string{UsageSourceBot, UsageSourceSpeech} {
+		if err := s.RecordUsage(ctx, 1, sql.NullInt32{}, "usage-test-model", source, 10); err != nil {
+			t.Fatalf("failed to record usage: %v", err)
+		}
+	}
+
+	usage, err := q.GetQuotaUsage(ctx, sqlc_queries.GetQuotaUsageParams{
+		WindowSeconds: 3600, UserID: 1, Model: "usage-test-model",
+	})
+	if err != nil {
+		t.Fatalf("failed to get usage: %v", err)
+	}
+	if usage.Requests != 2 || usage.Tokens != 20 {
+		t.Errorf("usage = %d requests, %d tokens; want 2 requests, 20 tokens", usage.Requests, usage.Tokens)
+	}
+}
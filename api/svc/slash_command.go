@@ -0,0 +1,93 @@
+package svc
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ImageSlashCommand is the built-in command that generates an image.
+const ImageSlashCommand = "image"
+
+var slashCommandNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_\-]*$`)
+
+// argPlaceholderPattern matches positional argument placeholders such as {{arg1}}.
+var argPlaceholderPattern = regexp.MustCompile(`^arg[0-9]+$`)
+
+// SlashInvocation is a parsed "/name args\ninput" user message.
+// Args is the rest of the first line; Input is everything after it.
+type SlashInvocation struct {
+	Name  string
+	Args  string
+	Input string
+}
+
+// ValidSlashCommandName reports whether name can be used as a command name.
+func ValidSlashCommandName(name string) bool {
+	return len(name) <= 64 && slashCommandNamePattern.MatchString(name)
+}
+
+// ParseSlashCommand parses text that starts with "/name". It returns false when
+// text is not a slash command, e.g. a path such as "/etc/hosts".
+func ParseSlashCommand(text string) (SlashInvocation, bool) {
+	if !strings.HasPrefix(text, "/") {
+		return SlashInvocation{}, false
+	}
+	firstLine, input, _ := strings.Cut(text[1:], "\n")
+	name, args := firstLine, ""
+	if i := strings.IndexAny(firstLine, " \t"); i >= 0 {
+		name, args = firstLine[:i], firstLine[i+1:]
+	}
+	name = strings.ToLower(name)
+	if !ValidSlashCommandName(name) {
+		return SlashInvocation{}, false
+	}
+	return SlashInvocation{
+		Name:  name,
+		Args:  strings.TrimSpace(args),
+		Input: strings.TrimSpace(input),
+	}, true
+}
+
+// RenderSlashCommand fills a command template. Supported placeholders are
+// {{args}}, {{arg1}}..{{argN}}, {{input}} and {{previous_answer}}. Positional
+// arguments that were not supplied render empty. When the template does not
+// reference the arguments or input, they are appended after it.
+func RenderSlashCommand(template string, inv SlashInvocation, previousAnswer string) string {
+	vars := map[string]string{
+		"args":            inv.Args,
+		"input":           inv.Input,
+		"previous_answer": previousAnswer,
+	}
+	for i, field := range strings.Fields(inv.Args) {
+		vars[fmt.Sprintf("arg%d", i+1)] = field
+	}
+
+	usesArgs := false
+	for _, name := range ExtractTemplateVariables(template) {
+		if name == "args" || name == "input" || argPlaceholderPattern.MatchString(name) {
+			usesArgs = true
+		}
+		if _, ok := vars[name]; !ok && argPlaceholderPattern.MatchString(name) {
+			vars[name] = ""
+		}
+	}
+
+	rendered, _ := RenderPromptTemplate(template, vars)
+	if !usesArgs {
+		if extra := strings.TrimSpace(inv.Args + "\n" + inv.Input); extra != "" {
+			rendered = strings.TrimRight(rendered, "\n") + "\n\n" + extra
+		}
+	}
+	return rendered
+}
+
+// usesPreviousAnswer reports whether the template references {{previous_answer}}.
+func usesPreviousAnswer(template string) bool {
+	for _, name := range ExtractTemplateVariables(template) {
+		if name == "previous_answer" {
+			return true
+		}
+	}
+	return false
+}
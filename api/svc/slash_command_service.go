@@ -0,0 +1,116 @@
+package svc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// SlashExpansion is the outcome of expanding a user message.
+// Command is empty when the message was not a known slash command.
+type SlashExpansion struct {
+	Content     string
+	Command     string
+	Model       string
+	Temperature sql.NullFloat64
+}
+
+// SlashCommandService manages user slash commands and expands them in messages.
+type SlashCommandService struct {
+	q   *sqlc_queries.Queries
+	aux *AuxiliaryModelService
+}
+
+// NewSlashCommandService creates a new SlashCommandService. aux picks the
+// model of built-in commands such as /image.
+func NewSlashCommandService(q *sqlc_queries.Queries, aux *AuxiliaryModelService) *SlashCommandService {
+	return &SlashCommandService{q: q, aux: aux}
+}
+
+// Q returns the underlying queries.
+func (s *SlashCommandService) Q() *sqlc_queries.Queries { return s.q }
+
+func (s *SlashCommandService) CreateSlashCommand(ctx context.Context, params sqlc_queries.CreateSlashCommandParams) (sqlc_queries.SlashCommand, error) {
+	c, err := s.q.CreateSlashCommand(ctx, params)
+	return c, eris.Wrap(err, "failed to create slash command")
+}
+
+func (s *SlashCommandService) GetSlashCommandByUUID(ctx context.Context, uuid string) (sqlc_queries.SlashCommand, error) {
+	c, err := s.q.GetSlashCommandByUUID(ctx, uuid)
+	return c, eris.Wrap(err, "failed to retrieve slash command")
+}
+
+func (s *SlashCommandService) ListSlashCommands(ctx context.Context, userID int32) ([]sqlc_queries.ListSlashCommandsByUserIDRow, error) {
+	cs, err := s.q.ListSlashCommandsByUserID(ctx, userID)
+	return cs, eris.Wrap(err, "failed to list slash commands")
+}
+
+func (s *SlashCommandService) UpdateSlashCommand(ctx context.Context, params sqlc_queries.UpdateSlashCommandParams) (sqlc_queries.SlashCommand, error) {
+	c, err := s.q.UpdateSlashCommand(ctx, params)
+	return c, eris.Wrap(err, "failed to update slash command")
+}
+
+func (s *SlashCommandService) DeleteSlashCommand(ctx context.Context, uuid string) error {
+	return eris.Wrap(s.q.DeleteSlashCommand(ctx, uuid), "failed to delete slash command")
+}
+
+// Expand replaces a leading "/name ..." in text with the user's command template.
+// Built-in commands apply when the user and workspace have no command of the
+// name. Unknown commands leave the text untouched.
+func (s *SlashCommandService) Expand(ctx context.Context, session sqlc_queries.ChatSession, userID int32, text string) (SlashExpansion, error) {
+	inv, ok := ParseSlashCommand(text)
+	if !ok {
+		return SlashExpansion{Content: text}, nil
+	}
+
+	cmd, err := s.q.ResolveSlashCommand(ctx, sqlc_queries.ResolveSlashCommandParams{
+		UserID: userID, Name: inv.Name, WorkspaceID: session.WorkspaceID.Int32,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return s.expandBuiltin(ctx, session, inv, text)
+		}
+		return SlashExpansion{}, eris.Wrap(err, "failed to resolve slash command")
+	}
+
+	previousAnswer := ""
+	if usesPreviousAnswer(cmd.Template) {
+		last, err := s.q.GetLastAssistantMessageBySessionUUID(ctx, session.Uuid)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return SlashExpansion{}, eris.Wrap(err, "failed to get previous answer")
+		}
+		previousAnswer = last.Content
+	}
+
+	return SlashExpansion{
+		Content:     RenderSlashCommand(cmd.Template, inv, previousAnswer),
+		Command:     cmd.Name,
+		Model:       cmd.Model,
+		Temperature: cmd.Temperature,
+	}, nil
+}
+
+// expandBuiltin expands the built-in commands. "/image <prompt>" sends the
+// prompt to the image model configured for the session's workspace.
+func (s *SlashCommandService) expandBuiltin(ctx context.Context, session sqlc_queries.ChatSession, inv SlashInvocation, text string) (SlashExpansion, error) {
+	if inv.Name != ImageSlashCommand {
+		return SlashExpansion{Content: text}, nil
+	}
+	prompt := strings.TrimSpace(inv.Args + "\n" + inv.Input)
+	if prompt == "" {
+		return SlashExpansion{}, dto.ErrValidationInvalidInput("describe the image after /image")
+	}
+	model, err := s.aux.ModelFor(ctx, session.WorkspaceID, AuxTaskImage)
+	if err != nil {
+		return SlashExpansion{}, err
+	}
+	if model == "" {
+		return SlashExpansion{}, dto.ErrValidationInvalidInput("no image model is configured")
+	}
+	return SlashExpansion{Content: prompt, Command: ImageSlashCommand, Model: model}, nil
+}
@@ -0,0 +1,69 @@
+package svc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+func TestParseSlashCommand(t *testing.T) {
+	tests := []struct {
+		in   string
+		ok   bool
+		want SlashInvocation
+	}{
+		{"/translate zh", true, SlashInvocation{Name: "translate", Args: "zh"}},
+		{"/Review go\nfunc main() {}", true, SlashInvocation{Name: "review", Args: "go", Input: "func main() {}"}},
+		{"/tldr", true, SlashInvocation{Name: "tldr"}},
+		{"/etc/hosts is broken", false, SlashInvocation{}},
+		{"hello /translate", false, SlashInvocation{}},
+		{"/", false, SlashInvocation{}},
+	}
+	for _, tt := range tests {
+		got, ok := ParseSlashCommand(tt.in)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseSlashCommand(%q) = %+v, %v; want %+v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRenderSlashCommand(t *testing.T) {
+	inv := SlashInvocation{Name: "translate", Args: "zh formal", Input: "Good morning"}
+
+	got := RenderSlashCommand("Translate into {{arg1}} ({{arg2}}{{arg3}}):\n{{input}}", inv, "")
+	if want := "Translate into zh (formal):\nGood morning"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = RenderSlashCommand("Translate the previous answer into {{args}}:\n\n{{previous_answer}}",
+		SlashInvocation{Name: "translate", Args: "zh"}, "Hello there")
+	if want := "Translate the previous answer into zh:\n\nHello there"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRenderSlashCommandAppendsUnreferencedArgs(t *testing.T) {
+	got := RenderSlashCommand("Review this code for bugs.", SlashInvocation{Name: "review", Args: "go", Input: "x := 1"}, "")
+	if want := "Review this code for bugs.\n\ngo\nx := 1"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = RenderSlashCommand("Summarize:\n{{previous_answer}}", SlashInvocation{Name: "tldr"}, "long text")
+	if want := "Summarize:\nlong text"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestExpandBuiltin(t *testing.T) {
+	s := &SlashCommandService{}
+	session := sqlc_queries.ChatSession{}
+
+	got, err := s.expandBuiltin(context.Background(), session, SlashInvocation{Name: "tldr"}, "/tldr")
+	if err != nil || got.Command != "" || got.Content != "/tldr" {
+		t.Errorf("unknown command expanded to %+v, %v", got, err)
+	}
+	if _, err := s.expandBuiltin(context.Background(), session, SlashInvocation{Name: ImageSlashCommand}, "/image"); err == nil {
+		t.Error("expected an error for /image without a prompt")
+	}
+}
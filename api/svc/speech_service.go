@@ -0,0 +1,248 @@
+package svc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+
+	"github.com/rotisserie/eris"
+
+	"github.com/swuecho/chat_backend/config"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/provider"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+	"github.com/swuecho/chat_backend/storage"
+)
+
+// SpeechSettings are the text to speech options of a user.
+type SpeechSettings struct {
+	Voice string  `json:"voice"`
+	Speed float64 `json:"speed"`
+}
+
+// maxSpeakText is the longest text in bytes SpeakText reads aloud, a few
+// requests to a speech model.
+const maxSpeakText = 16 << 10
+
+// localSpeechModel stands for the local TTS engine in recorded usage.
+const localSpeechModel = "local-tts"
+
+// SpeechService reads text and answers aloud with the speech auxiliary
+// model, or the local TTS engine, keeping the speech of messages for replay.
+type SpeechService struct {
+	q   *sqlc_queries.Queries
+	aux *AuxiliaryModelService
+}
+
+// NewSpeechService creates a new SpeechService.
+func NewSpeechService(q *sqlc_queries.Queries, cfg provider.Config) *SpeechService {
+	return &SpeechService{q: q, aux: NewAuxiliaryModelService(q, cfg)}
+}
+
+// Settings returns the options of userID, or the configured defaults.
+func (s *SpeechService) Settings(ctx context.Context, userID int32) (SpeechSettings, error) {
+	row, err := s.q.GetUserSpeechSetting(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		tts := config.Current().TTS
+		return SpeechSettings{Voice: tts.DEFAULT_VOICE, Speed: tts.DEFAULT_SPEED}, nil
+	}
+	if err != nil {
+		return SpeechSettings{}, eris.Wrap(err, "failed to get speech settings")
+	}
+	return SpeechSettings{Voice: row.Voice, Speed: row.Speed}, nil
+}
+
+// SetSettings saves the options of userID.
+func (s *SpeechService) SetSettings(ctx context.Context, userID int32, settings SpeechSettings) (SpeechSettings, error) {
+	if err := validateSpeech(settings.Voice, settings.Speed); err != nil {
+		return SpeechSettings{}, err
+	}
+	row, err := s.q.UpsertUserSpeechSetting(ctx, sqlc_queries.UpsertUserSpeechSettingParams{
+		UserID: userID, Voice: settings.Voice, Speed: settings.Speed,
+	})
+	if err != nil {
+		return SpeechSettings{}, eris.Wrap(err, "failed to save speech settings")
+	}
+	return SpeechSettings{Voice: row.Voice, Speed: row.Speed}, nil
+}
+
+func validateSpeech(voice string, speed float64) error {
+	if len(voice) > 64 {
+		return dto.ErrValidationInvalidInput("voice name is too long")
+	}
+	if speed < 0.25 || speed > 4 {
+		return dto.ErrValidationInvalidInput("speed must be between 0.25 and 4")
+	}
+	return nil
+}
+
+// options fills in the voice and speed of a request from the user's settings.
+func (s *SpeechService) options(ctx context.Context, userID int32, voice string, speed float64) (string, float64, error) {
+	settings, err := s.Settings(ctx, userID)
+	if err != nil {
+		return "", 0, err
+	}
+	if voice == "" {
+		voice = settings.Voice
+	}
+	if speed == 0 {
+		speed = settings.Speed
+	}
+	return voice, speed, validateSpeech(voice, speed)
+}
+
+// synthesizer returns the engine for userID in session: the speech model
+// of its workspace, subject to the user's model limits, else the local
+// engine. It also returns the model name to record usage under.
+func (s *SpeechService) synthesizer(ctx context.Context, session sqlc_queries.ChatSession, userID int32) (provider.Synthesizer, string, error) {
+	name, err := s.aux.ModelFor(ctx, session.WorkspaceID, AuxTaskSpeech)
+	if err != nil {
+		return nil, "", err
+	}
+	if name != "" {
+		chatModel, err := s.q.ChatModelByName(ctx, name)
+		if err != nil {
+			return nil, "", eris.Wrapf(err, "failed to get speech model %s", name)
+		}
+		synth, err := provider.NewOpenAISynthesizer(ctx, userModelHandler{s.aux}, session, userID, chatModel)
+		return synth, chatModel.Name, err
+	}
+	if tts := config.Current().TTS; tts.HOST != "" {
+		return provider.NewLocalSynthesizer("http://" + net.JoinHostPort(tts.HOST, tts.PORT)), localSpeechModel, nil
+	}
+	return nil, "", dto.ErrValidationInvalidInput("text to speech is not configured")
+}
+
+// speak synthesizes text with synth and counts it towards the quotas of
+// userID.
+func (s *SpeechService) speak(ctx context.Context, synth provider.Synthesizer, model string, session sqlc_queries.ChatSession, userID int32, text, voice string, speed float64) (provider.Speech, error) {
+	speech, err := provider.Speak(ctx, synth, text, voice, speed)
+	if err != nil {
+		return provider.Speech{}, err
+	}
+	tokens := int32(len(text)) / dto.TokenEstimateRatio
+	if err := s.aux.quota.RecordUsage(ctx, userID, session.WorkspaceID, model, UsageSourceSpeech, tokens); err != nil {
+		slog.Warn("Failed to record speech usage", "user", userID, "error", err)
+	}
+	return speech, nil
+}
+
+// SpeakText reads text aloud for userID, with the engine of the session
+// sessionUUID if given. Empty voice and zero speed use the user's settings.
+// Text longer than maxSpeakText is rejected.
+func (s *SpeechService) SpeakText(ctx context.Context, userID int32, sessionUUID, text, voice string, speed float64) (provider.Speech, error) {
+	if len(text) > maxSpeakText {
+		return provider.Speech{}, dto.ErrValidationInvalidInput(fmt.Sprintf("text is too long to speak, max length is %d bytes", maxSpeakText))
+	}
+	session := sqlc_queries.ChatSession{UserID: userID}
+	if sessionUUID != "" {
+		var err error
+		if session, err = s.q.GetChatSessionByUUID(ctx, sessionUUID); err != nil || session.UserID != userID {
+			return provider.Speech{}, dto.ErrResourceNotFound("chat session")
+		}
+	}
+	voice, speed, err := s.options(ctx, userID, voice, speed)
+	if err != nil {
+		return provider.Speech{}, err
+	}
+	synth, model, err := s.synthesizer(ctx, session, userID)
+	if err != nil {
+		return provider.Speech{}, err
+	}
+	return s.speak(ctx, synth, model, session, userID, text, voice, speed)
+}
+
+// SpeakMessage reads the message messageUUID of userID aloud. Speech is
+// kept per message and voice and returned from storage until the message
+// is edited; new speech is stored once it has been read to the end.
+func (s *SpeechService) SpeakMessage(ctx context.Context, userID int32, messageUUID, voice string, speed float64) (provider.Speech, error) {
+	message, err := s.q.GetChatMessageByUUID(ctx, messageUUID)
+	if err != nil || message.UserID != userID {
+		return provider.Speech{}, dto.ErrResourceNotFound("chat message")
+	}
+	session, err := s.q.GetChatSessionByUUIDWithInActive(ctx, message.ChatSessionUuid)
+	if err != nil {
+		return provider.Speech{}, dto.ErrResourceNotFound("chat session")
+	}
+	voice, speed, err = s.options(ctx, userID, voice, speed)
+	if err != nil {
+		return provider.Speech{}, err
+	}
+	synth, model, err := s.synthesizer(ctx, session, userID)
+	if err != nil {
+		return provider.Speech{}, err
+	}
+	blobs, err := storage.Default()
+	if err != nil {
+		return provider.Speech{}, err
+	}
+
+	voiceKey := provider.VoiceKey(synth, voice, speed)
+	sum := sha256.Sum256([]byte(message.Content))
+	contentHash := hex.EncodeToString(sum[:])
+	cached, err := s.q.GetMessageSpeech(ctx, sqlc_queries.GetMessageSpeechParams{MessageUuid: messageUUID, VoiceKey: voiceKey})
+	switch {
+	case err == nil && cached.ContentHash == contentHash:
+		rc, _, err := blobs.Open(ctx, cached.BlobHash)
+		if err == nil {
+			return provider.Speech{ReadCloser: rc, MimeType: cached.MimeType}, nil
+		}
+		slog.Warn("failed to open cached speech", "message", messageUUID, "hash", cached.BlobHash, "error", err)
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return provider.Speech{}, eris.Wrap(err, "failed to look up cached speech")
+	}
+
+	speech, err := s.speak(ctx, synth, model, session, userID, message.Content, voice, speed)
+	if err != nil {
+		return provider.Speech{}, err
+	}
+	// speech of an earlier version of the message, replaced once the new one is saved
+	previous := cached.BlobHash
+	save := func(audio []byte) {
+		// the reader may finish as the request ends
+		ctx := context.WithoutCancel(ctx)
+		blob, err := blobs.Save(ctx, bytes.NewReader(audio))
+		if err == nil {
+			err = s.q.UpsertMessageSpeech(ctx, sqlc_queries.UpsertMessageSpeechParams{
+				MessageUuid: messageUUID, VoiceKey: voiceKey, ContentHash: contentHash,
+				BlobHash: blob.Hash, MimeType: speech.MimeType,
+			})
+		}
+		if err != nil {
+			slog.Warn("failed to cache speech", "message", messageUUID, "error", err)
+			return
+		}
+		if previous != "" && previous != blob.Hash {
+			if err := blobs.Release(ctx, previous); err != nil {
+				slog.Warn("failed to release outdated speech", "hash", previous, "error", err)
+			}
+		}
+	}
+	return provider.Speech{ReadCloser: &recordingReader{ReadCloser: speech.ReadCloser, save: save}, MimeType: speech.MimeType}, nil
+}
+
+// recordingReader keeps what is read and passes it to save at the end, so
+// speech is stored only when it was synthesized completely.
+type recordingReader struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	save func([]byte)
+	done bool
+}
+
+func (r *recordingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.buf.Write(p[:n])
+	if err == io.EOF && !r.done {
+		r.done = true
+		r.save(r.buf.Bytes())
+	}
+	return n, err
+}
@@ -0,0 +1,20 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/swuecho/chat_backend/dto"
+)
+
+func TestSpeakTextRejectsLongText(t *testing.T) {
+	s := &SpeechService{}
+	_, err := s.SpeakText(context.Background(), 1, "", strings.Repeat("a", maxSpeakText+1), "", 0)
+	var apiErr dto.APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPCode != http.StatusBadRequest {
+		t.Errorf("got %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,327 @@
+package svc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/auth"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/oidc"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// SSOPolicy controls how identities from the identity provider map to local accounts.
+type SSOPolicy struct {
+	// AllowSignup creates accounts for unknown identities on first login.
+	AllowSignup bool
+	// LinkByEmail attaches a new identity to an existing account with the same verified email.
+	LinkByEmail bool
+	// AdminGroups and StaffGroups grant is_superuser and is_staff. When either is
+	// set, the flags follow group membership on every login.
+	AdminGroups []string
+	StaffGroups []string
+	// GroupWorkspaces maps a group to a workspace created for its members.
+	GroupWorkspaces map[string]string
+}
+
+// SSOLogin holds the values of a pending login that must survive the redirect to the identity provider.
+type SSOLogin struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+}
+
+// SSOService provisions and links accounts signed in through OpenID Connect.
+type SSOService struct {
+	q          *sqlc_queries.Queries
+	users      *AuthUserService
+	workspaces *ChatWorkspaceService
+	policy     SSOPolicy
+}
+
+// NewSSOService creates a new SSOService.
+func NewSSOService(q *sqlc_queries.Queries, users *AuthUserService, policy SSOPolicy) *SSOService {
+	return &SSOService{q: q, users: users, workspaces: NewChatWorkspaceService(q), policy: policy}
+}
+
+// Q returns the underlying queries.
+func (s *SSOService) Q() *sqlc_queries.Queries { return s.q }
+
+// StartLogin records a pending login. linkUserID is non-zero when a signed-in
+// user is linking an identity to their account.
+func (s *SSOService) StartLogin(ctx context.Context, redirectTo string, linkUserID int32) (SSOLogin, error) {
+	var l SSOLogin
+	var err error
+	if l.State, err = oidc.RandomString(24); err != nil {
+		return l, err
+	}
+	if l.Nonce, err = oidc.RandomString(24); err != nil {
+		return l, err
+	}
+	if l.CodeVerifier, err = oidc.RandomString(48); err != nil {
+		return l, err
+	}
+	if err := s.q.PurgeOIDCLoginStates(ctx); err != nil {
+		slog.Error("Failed to purge expired SSO logins", "error", err)
+	}
+	err = s.q.CreateOIDCLoginState(ctx, sqlc_queries.CreateOIDCLoginStateParams{
+		State: l.State, Nonce: l.Nonce, CodeVerifier: l.CodeVerifier, RedirectTo: redirectTo,
+		LinkUserID: sql.NullInt32{Int32: linkUserID, Valid: linkUserID != 0},
+	})
+	return l, eris.Wrap(err, "failed to store SSO login state")
+}
+
+// ConsumeLogin returns and deletes a pending login. Unknown or expired states
+// return dto.ErrAuthSSOFailed.
+func (s *SSOService) ConsumeLogin(ctx context.Context, state string) (sqlc_queries.OidcLoginState, error) {
+	l, err := s.q.ConsumeOIDCLoginState(ctx, state)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return l, dto.ErrAuthSSOFailed.WithDetail("login request expired, please try again")
+		}
+		return l, eris.Wrap(err, "failed to load SSO login state")
+	}
+	return l, nil
+}
+
+// Resolve returns the local account for a verified identity, linking or
+// provisioning it as the policy allows, then applies the group mappings.
+func (s *SSOService) Resolve(ctx context.Context, issuer string, claims oidc.Claims, linkUserID int32) (sqlc_queries.AuthUser, error) {
+	if claims.Subject == "" {
+		return sqlc_queries.AuthUser{}, dto.ErrAuthSSOFailed.WithDetail("identity token has no subject")
+	}
+
+	user, err := s.findOrCreate(ctx, issuer, claims, linkUserID)
+	if err != nil {
+		return user, err
+	}
+	if !user.IsActive {
+		return user, dto.ErrAuthSSOFailed.WithDetail("account is disabled")
+	}
+
+	if user, err = s.syncRoles(ctx, user, claims.Groups); err != nil {
+		return user, err
+	}
+	if err := s.syncWorkspaces(ctx, user.ID, claims.Groups); err != nil {
+		return user, err
+	}
+	if err := s.q.TouchAuthUserLastLogin(ctx, user.ID); err != nil {
+		slog.Error("Failed to update last login", "user_id", user.ID, "error", err)
+	}
+	return user, nil
+}
+
+func (s *SSOService) findOrCreate(ctx context.Context, issuer string, claims oidc.Claims, linkUserID int32) (sqlc_queries.AuthUser, error) {
+	ident, err := s.q.GetAuthUserIdentity(ctx, sqlc_queries.GetAuthUserIdentityParams{Issuer: issuer, Subject: claims.Subject})
+	if err == nil {
+		if linkUserID != 0 && ident.UserID != linkUserID {
+			return sqlc_queries.AuthUser{}, dto.ErrAuthSSOFailed.WithDetail("this identity is linked to another account")
+		}
+		if err := s.q.TouchAuthUserIdentity(ctx, sqlc_queries.TouchAuthUserIdentityParams{ID: ident.ID, Email: claims.Email}); err != nil {
+			slog.Error("Failed to update identity", "identity_id", ident.ID, "error", err)
+		}
+		user, err := s.q.GetAuthUserByID(ctx, ident.UserID)
+		return user, eris.Wrap(err, "failed to load linked user")
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return sqlc_queries.AuthUser{}, eris.Wrap(err, "failed to look up identity")
+	}
+
+	var user sqlc_queries.AuthUser
+	switch {
+	case linkUserID != 0:
+		user, err = s.q.GetAuthUserByID(ctx, linkUserID)
+		if err != nil {
+			return user, eris.Wrap(err, "failed to load user to link")
+		}
+	case s.policy.LinkByEmail && claims.EmailVerified && claims.Email != "":
+		user, err = s.q.GetUserByEmail(ctx, claims.Email)
+		if errors.Is(err, sql.ErrNoRows) {
+			user, err = s.provision(ctx, claims)
+		} else if err != nil {
+			err = eris.Wrap(err, "failed to look up user by email")
+		}
+	default:
+		user, err = s.provision(ctx, claims)
+	}
+	if err != nil {
+		return user, err
+	}
+
+	if _, err := s.q.CreateAuthUserIdentity(ctx, sqlc_queries.CreateAuthUserIdentityParams{
+		UserID: user.ID, Issuer: issuer, Subject: claims.Subject, Email: claims.Email,
+	}); err != nil {
+		return user, eris.Wrap(err, "failed to link identity")
+	}
+	slog.Info("SSO identity linked", "user_id", user.ID, "issuer", issuer, "action", "sso_link")
+	return user, nil
+}
+
+// provision creates an account for a new identity. The password is random, so
+// the account can only sign in through SSO until the user resets it.
+func (s *SSOService) provision(ctx context.Context, claims oidc.Claims) (sqlc_queries.AuthUser, error) {
+	if !s.policy.AllowSignup {
+		return sqlc_queries.AuthUser{}, dto.ErrAuthSSOFailed.WithDetail("no account is linked to this identity")
+	}
+	if claims.Email == "" {
+		return sqlc_queries.AuthUser{}, dto.ErrAuthSSOFailed.WithDetail("identity provider did not share an email address")
+	}
+	if _, err := s.q.GetUserByEmail(ctx, claims.Email); err == nil {
+		return sqlc_queries.AuthUser{}, dto.ErrAuthSSOFailed.WithDetail(
+			"an account with this email already exists; sign in with your password and link single sign-on from your profile")
+	}
+
+	password, err := auth.GenerateRandomPassword()
+	if err != nil {
+		return sqlc_queries.AuthUser{}, eris.Wrap(err, "failed to generate password")
+	}
+	hash, err := auth.GeneratePasswordHash(password)
+	if err != nil {
+		return sqlc_queries.AuthUser{}, eris.Wrap(err, "failed to hash password")
+	}
+	first, last := splitName(claims.Name)
+	user, err := s.users.CreateAuthUser(ctx, sqlc_queries.CreateAuthUserParams{
+		Email: claims.Email, Username: claims.Email, Password: hash, FirstName: first, LastName: last,
+	})
+	if err != nil {
+		return user, eris.Wrap(err, "failed to create user")
+	}
+	slog.Info("SSO user provisioned", "user_id", user.ID, "email", user.Email, "action", "sso_signup")
+	return user, nil
+}
+
+func (s *SSOService) syncRoles(ctx context.Context, user sqlc_queries.AuthUser, groups []string) (sqlc_queries.AuthUser, error) {
+	superuser, staff, ok := MapSSORoles(s.policy, groups)
+	if !ok || (user.IsSuperuser == superuser && user.IsStaff == staff) {
+		return user, nil
+	}
+	updated, err := s.q.UpdateAuthUserRoles(ctx, sqlc_queries.UpdateAuthUserRolesParams{
+		ID: user.ID, IsSuperuser: superuser, IsStaff: staff,
+	})
+	if err != nil {
+		return user, eris.Wrap(err, "failed to update user roles")
+	}
+	slog.Info("SSO roles updated", "user_id", user.ID, "is_superuser", superuser, "is_staff", staff, "action", "sso_roles")
+	return updated, nil
+}
+
+// syncWorkspaces creates the workspaces mapped from the user's groups that they
+// do not have yet. The first one becomes the default when the user has none.
+func (s *SSOService) syncWorkspaces(ctx context.Context, userID int32, groups []string) error {
+	names := MapSSOWorkspaces(s.policy, groups)
+	if len(names) == 0 {
+		return nil
+	}
+	existing, err := s.workspaces.GetWorkspacesByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	have := make(map[string]bool, len(existing))
+	hasDefault := false
+	for _, w := range existing {
+		have[w.Name] = true
+		hasDefault = hasDefault || w.IsDefault
+	}
+	for _, name := range names {
+		if have[name] {
+			continue
+		}
+		if _, err := s.workspaces.CreateWorkspace(ctx, sqlc_queries.CreateWorkspaceParams{
+			Uuid: uuid.New().String(), UserID: userID, Name: name,
+			Description: "Created from your identity provider groups", Color: "#6366f1", Icon: "folder",
+			IsDefault: !hasDefault, OrderPosition: int32(len(existing)),
+		}); err != nil {
+			return err
+		}
+		hasDefault = true
+		existing = append(existing, sqlc_queries.ChatWorkspace{Name: name})
+	}
+	return nil
+}
+
+// ListIdentities returns the identities linked to a user.
+func (s *SSOService) ListIdentities(ctx context.Context, userID int32) ([]sqlc_queries.AuthUserIdentity, error) {
+	ids, err := s.q.ListAuthUserIdentitiesByUserID(ctx, userID)
+	return ids, eris.Wrap(err, "failed to list identities")
+}
+
+// Unlink removes one of the user's identities.
+func (s *SSOService) Unlink(ctx context.Context, userID, identityID int32) error {
+	n, err := s.q.DeleteAuthUserIdentity(ctx, sqlc_queries.DeleteAuthUserIdentityParams{ID: identityID, UserID: userID})
+	if err != nil {
+		return eris.Wrap(err, "failed to unlink identity")
+	}
+	if n == 0 {
+		return dto.ErrResourceNotFound("identity")
+	}
+	return nil
+}
+
+// MapSSORoles returns the superuser and staff flags implied by groups. ok is
+// false when the policy maps no groups to roles, leaving the flags untouched.
+func MapSSORoles(policy SSOPolicy, groups []string) (superuser, staff, ok bool) {
+	if len(policy.AdminGroups) == 0 && len(policy.StaffGroups) == 0 {
+		return false, false, false
+	}
+	superuser = intersects(groups, policy.AdminGroups)
+	staff = superuser || intersects(groups, policy.StaffGroups)
+	return superuser, staff, true
+}
+
+// MapSSOWorkspaces returns the workspace names mapped from groups, in group order, without duplicates.
+func MapSSOWorkspaces(policy SSOPolicy, groups []string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, g := range groups {
+		name, ok := policy.GroupWorkspaces[g]
+		if !ok || name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// ParseGroupWorkspaces parses "group=Workspace name,other=Other" into a map.
+func ParseGroupWorkspaces(s string) map[string]string {
+	m := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		group, name, ok := strings.Cut(pair, "=")
+		group, name = strings.TrimSpace(group), strings.TrimSpace(name)
+		if ok && group != "" && name != "" {
+			m[group] = name
+		}
+	}
+	return m
+}
+
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitName splits a display name into auth_user first and last names, which hold 30 characters each.
+func splitName(name string) (string, string) {
+	first, last, _ := strings.Cut(strings.TrimSpace(name), " ")
+	return truncateRunes(first, 30), truncateRunes(strings.TrimSpace(last), 30)
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,48 @@
+package svc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMapSSORoles(t *testing.T) {
+	policy := SSOPolicy{AdminGroups: []string{"chat-admins"}, StaffGroups: []string{"support"}}
+	cases := []struct {
+		groups           []string
+		superuser, staff bool
+	}{
+		{[]string{"eng", "chat-admins"}, true, true},
+		{[]string{"support"}, false, true},
+		{[]string{"eng"}, false, false},
+		{nil, false, false},
+	}
+	for _, tc := range cases {
+		su, staff, ok := MapSSORoles(policy, tc.groups)
+		if !ok || su != tc.superuser || staff != tc.staff {
+			t.Errorf("MapSSORoles(%v) = %v, %v, %v", tc.groups, su, staff, ok)
+		}
+	}
+	if _, _, ok := MapSSORoles(SSOPolicy{}, []string{"chat-admins"}); ok {
+		t.Error("roles should be left alone when no groups are mapped")
+	}
+}
+
+func TestMapSSOWorkspaces(t *testing.T) {
+	policy := SSOPolicy{GroupWorkspaces: ParseGroupWorkspaces(" eng = Engineering , sre=Engineering,sales=Sales, bad,=x")}
+	if len(policy.GroupWorkspaces) != 3 {
+		t.Fatalf("unexpected mapping: %v", policy.GroupWorkspaces)
+	}
+	got := MapSSOWorkspaces(policy, []string{"sales", "eng", "sre", "other"})
+	if want := []string{"Sales", "Engineering"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MapSSOWorkspaces = %v, want %v", got, want)
+	}
+}
+
+func TestSplitName(t *testing.T) {
+	if first, last := splitName("  Ada  King Lovelace "); first != "Ada" || last != "King Lovelace" {
+		t.Errorf("splitName = %q, %q", first, last)
+	}
+	if first, _ := splitName("Wolfeschlegelsteinhausenbergerdorff"); len([]rune(first)) != 30 {
+		t.Errorf("first name not truncated: %q", first)
+	}
+}
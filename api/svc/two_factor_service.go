@@ -0,0 +1,236 @@
+package svc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/swuecho/chat_backend/auth"
+	"github.com/swuecho/chat_backend/dto"
+	"github.com/swuecho/chat_backend/sqlc_queries"
+)
+
+// TwoFactorPolicyKey is the app_setting key holding the TwoFactorPolicy.
+const TwoFactorPolicyKey = "two_factor_policy"
+
+// RecoveryCodeCount is how many recovery codes a user gets at a time.
+const RecoveryCodeCount = 10
+
+// TwoFactorPolicy is the server-wide two-factor policy set by admins.
+type TwoFactorPolicy struct {
+	// RequireForStaff forces staff and superusers to enrol before they can log in.
+	RequireForStaff bool `json:"requireForStaff"`
+}
+
+// TwoFactorStatus describes a user's two-factor setup.
+type TwoFactorStatus struct {
+	Enabled           bool
+	Required          bool
+	RecoveryCodesLeft int64
+}
+
+// TwoFactorService manages TOTP enrolment, recovery codes and the two-factor policy.
+type TwoFactorService struct {
+	q      *sqlc_queries.Queries
+	issuer string
+}
+
+// NewTwoFactorService creates a new TwoFactorService. issuer is the account
+// name shown in authenticator apps.
+func NewTwoFactorService(q *sqlc_queries.Queries, issuer string) *TwoFactorService {
+	if issuer == "" {
+		issuer = "Chat"
+	}
+	return &TwoFactorService{q: q, issuer: issuer}
+}
+
+// Q returns the underlying queries.
+func (s *TwoFactorService) Q() *sqlc_queries.Queries { return s.q }
+
+// Policy returns the two-factor policy; unset means nothing is required.
+func (s *TwoFactorService) Policy(ctx context.Context) (TwoFactorPolicy, error) {
+	var p TwoFactorPolicy
+	setting, err := s.q.GetAppSetting(ctx, TwoFactorPolicyKey)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return p, nil
+		}
+		return p, eris.Wrap(err, "failed to load two-factor policy")
+	}
+	if err := json.Unmarshal(setting.Value, &p); err != nil {
+		return p, eris.Wrap(err, "failed to decode two-factor policy")
+	}
+	return p, nil
+}
+
+func (s *TwoFactorService) SetPolicy(ctx context.Context, p TwoFactorPolicy) error {
+	value, err := json.Marshal(p)
+	if err != nil {
+		return eris.Wrap(err, "failed to encode two-factor policy")
+	}
+	return eris.Wrap(s.q.UpsertAppSetting(ctx, sqlc_queries.UpsertAppSettingParams{
+		Key: TwoFactorPolicyKey, Value: value,
+	}), "failed to save two-factor policy")
+}
+
+// Required reports whether the policy forces user to use two-factor authentication.
+func (s *TwoFactorService) Required(ctx context.Context, user sqlc_queries.AuthUser) (bool, error) {
+	if !user.IsStaff && !user.IsSuperuser {
+		return false, nil
+	}
+	p, err := s.Policy(ctx)
+	return p.RequireForStaff, err
+}
+
+// Enabled reports whether the user has confirmed a TOTP secret.
+func (s *TwoFactorService) Enabled(ctx context.Context, userID int32) (bool, error) {
+	t, err := s.q.GetAuthUserTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, eris.Wrap(err, "failed to load two-factor settings")
+	}
+	return t.Enabled, nil
+}
+
+func (s *TwoFactorService) Status(ctx context.Context, user sqlc_queries.AuthUser) (TwoFactorStatus, error) {
+	var st TwoFactorStatus
+	var err error
+	if st.Enabled, err = s.Enabled(ctx, user.ID); err != nil {
+		return st, err
+	}
+	if st.Required, err = s.Required(ctx, user); err != nil {
+		return st, err
+	}
+	if st.Enabled {
+		if st.RecoveryCodesLeft, err = s.q.CountAuthUserRecoveryCodes(ctx, user.ID); err != nil {
+			return st, eris.Wrap(err, "failed to count recovery codes")
+		}
+	}
+	return st, nil
+}
+
+// BeginEnrollment stores a new unconfirmed secret and returns it with its
+// provisioning URI. Calling it again replaces a pending secret.
+func (s *TwoFactorService) BeginEnrollment(ctx context.Context, user sqlc_queries.AuthUser) (string, string, error) {
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := s.q.UpsertPendingAuthUserTOTP(ctx, sqlc_queries.UpsertPendingAuthUserTOTPParams{
+		UserID: user.ID, Secret: secret,
+	}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "", dto.ErrValidationInvalidInput("two-factor authentication is already enabled")
+		}
+		return "", "", eris.Wrap(err, "failed to store TOTP secret")
+	}
+	return secret, auth.TOTPURI(s.issuer, user.Email, secret), nil
+}
+
+// ConfirmEnrollment enables two-factor authentication once the user proves
+// their app produces valid codes, and returns fresh recovery codes.
+func (s *TwoFactorService) ConfirmEnrollment(ctx context.Context, userID int32, code string) ([]string, error) {
+	t, err := s.q.GetAuthUserTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, dto.ErrValidationInvalidInput("start two-factor enrolment first")
+		}
+		return nil, eris.Wrap(err, "failed to load two-factor settings")
+	}
+	if t.Enabled {
+		return nil, dto.ErrValidationInvalidInput("two-factor authentication is already enabled")
+	}
+	step, ok := auth.ValidateTOTP(t.Secret, code, time.Now(), t.LastStep)
+	if !ok {
+		return nil, dto.ErrAuthInvalidTwoFactorCode
+	}
+	if err := s.q.EnableAuthUserTOTP(ctx, sqlc_queries.EnableAuthUserTOTPParams{UserID: userID, LastStep: step}); err != nil {
+		return nil, eris.Wrap(err, "failed to enable two-factor authentication")
+	}
+	return s.RegenerateRecoveryCodes(ctx, userID)
+}
+
+// Verify checks a TOTP code or an unused recovery code for the second login
+// step. Each code is accepted once.
+func (s *TwoFactorService) Verify(ctx context.Context, userID int32, code string) error {
+	t, err := s.q.GetAuthUserTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return dto.ErrAuthInvalidTwoFactorCode
+		}
+		return eris.Wrap(err, "failed to load two-factor settings")
+	}
+	if !t.Enabled {
+		return dto.ErrAuthInvalidTwoFactorCode
+	}
+
+	if step, ok := auth.ValidateTOTP(t.Secret, code, time.Now(), t.LastStep); ok {
+		// the conditional update loses to a concurrent login using the same code
+		n, err := s.q.UseAuthUserTOTPStep(ctx, sqlc_queries.UseAuthUserTOTPStepParams{UserID: userID, LastStep: step})
+		if err != nil {
+			return eris.Wrap(err, "failed to record TOTP use")
+		}
+		if n == 0 {
+			return dto.ErrAuthInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	n, err := s.q.UseAuthUserRecoveryCode(ctx, sqlc_queries.UseAuthUserRecoveryCodeParams{
+		UserID: userID, CodeHash: hashToken(auth.NormalizeRecoveryCode(code)),
+	})
+	if err != nil {
+		return eris.Wrap(err, "failed to use recovery code")
+	}
+	if n == 0 {
+		return dto.ErrAuthInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes. The plain codes
+// are only available from the return value.
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID int32) ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = hashToken(auth.NormalizeRecoveryCode(c))
+	}
+	if err := s.q.DeleteAuthUserRecoveryCodes(ctx, userID); err != nil {
+		return nil, eris.Wrap(err, "failed to delete recovery codes")
+	}
+	if err := s.q.CreateAuthUserRecoveryCodes(ctx, sqlc_queries.CreateAuthUserRecoveryCodesParams{
+		UserID: userID, CodeHashes: hashes,
+	}); err != nil {
+		return nil, eris.Wrap(err, "failed to store recovery codes")
+	}
+	return codes, nil
+}
+
+// Disable removes the user's TOTP secret and recovery codes.
+func (s *TwoFactorService) Disable(ctx context.Context, userID int32) error {
+	if err := s.q.DeleteAuthUserTOTP(ctx, userID); err != nil {
+		return eris.Wrap(err, "failed to disable two-factor authentication")
+	}
+	return eris.Wrap(s.q.DeleteAuthUserRecoveryCodes(ctx, userID), "failed to delete recovery codes")
+}
+
+// Reset disables another user's second factor on behalf of an admin.
+func (s *TwoFactorService) Reset(ctx context.Context, user sqlc_queries.AuthUser) error {
+	if err := s.Disable(ctx, user.ID); err != nil {
+		return err
+	}
+	NewAuditService(s.q).Record(ctx, AuditEntry{
+		Action: AuditUserTwoFactorReset, TargetType: "user", TargetID: user.Email,
+		Before: map[string]bool{"twoFactor": true}, After: map[string]bool{"twoFactor": false},
+	})
+	return nil
+}
@@ -2,17 +2,17 @@
 package testutil
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"io"
 	"log"
-	"os"
 	"testing"
 	"time"
 
 	_ "github.com/lib/pq"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
+	"github.com/swuecho/chat_backend/migrations"
 )
 
 // NewTestDB spins up a PostgreSQL Docker container, runs schema migrations,
@@ -73,25 +73,12 @@ func NewTestDB(m *testing.M) (*sql.DB, func()) {
 	}
 
 	// Run schema migrations
-	schemaPath := "./sqlc/schema.sql"
-	// Also try parent path for sub-package tests
-	if _, err := os.Stat(schemaPath); os.IsNotExist(err) {
-		schemaPath = "../sqlc/schema.sql"
-	}
-
-	file, err := os.Open(schemaPath)
-	if err != nil {
-		log.Fatalf("Could not open schema file: %s", err)
-	}
-	defer file.Close()
-
-	schemaBytes, err := io.ReadAll(file)
+	migrator, err := migrations.New(db)
 	if err != nil {
-		log.Fatalf("Could not read schema file: %s", err)
+		log.Fatalf("Could not load migrations: %s", err)
 	}
-
-	if _, err := db.Exec(string(schemaBytes)); err != nil {
-		log.Fatalf("Could not execute SQL: %s", err)
+	if _, err := migrator.Up(context.Background()); err != nil {
+		log.Fatalf("Could not apply migrations: %s", err)
 	}
 
 	cleanup := func() {
@@ -0,0 +1,172 @@
+// Package tracing sets up OpenTelemetry tracing and exports spans over OTLP/HTTP.
+//
+// Spans are created for HTTP requests (middleware.TracingMiddleware), service
+// methods (Start), sqlc queries (DB) and outbound provider requests
+// (Transport). When no endpoint is configured the global no-op tracer is kept
+// and all of them cost next to nothing.
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rotisserie/eris"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/swuecho/chat_backend"
+
+// RequestIDKey is the baggage member carrying the request ID, so it reaches
+// every span and outbound request of a trace.
+const RequestIDKey = "request.id"
+
+// Config selects where spans are sent.
+type Config struct {
+	// Endpoint is the collector's OTLP/HTTP URL, e.g. http://localhost:4318.
+	Endpoint string
+	// ServiceName defaults to "chat".
+	ServiceName string
+	// SampleRatio is the share of new traces recorded; 0 records all.
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider and propagators. It returns a
+// function that flushes pending spans. With an empty endpoint nothing is
+// exported.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to create OTLP exporter")
+	}
+	name := cfg.ServiceName
+	if name == "" {
+		name = "chat"
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(name)))
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to build trace resource")
+	}
+	sampler := sdktrace.AlwaysSample()
+	if cfg.SampleRatio > 0 && cfg.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(cfg.SampleRatio)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Tracer returns the tracer of this application.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start begins an internal span, tagged with the request ID of ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if id := RequestID(ctx); id != "" {
+		attrs = append(attrs, attribute.String(RequestIDKey, id))
+	}
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// WithRequestID stores the request ID in the baggage of ctx.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	member, err := baggage.NewMember(RequestIDKey, id)
+	if err != nil {
+		return ctx
+	}
+	bag, err := baggage.FromContext(ctx).SetMember(member)
+	if err != nil {
+		return ctx
+	}
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+// RequestID returns the request ID stored by WithRequestID.
+func RequestID(ctx context.Context) string {
+	return baggage.FromContext(ctx).Member(RequestIDKey).Value()
+}
+
+// --- Outbound HTTP ---
+
+type transport struct {
+	base http.RoundTripper
+}
+
+// Transport wraps base (http.DefaultTransport when nil) with a client span per
+// request. Trace context, the request ID baggage member and X-Request-Id are
+// sent upstream; other baggage, which clients can set, is not.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+// upstreamBaggage returns ctx with its baggage cut down to the members that
+// may leave for third parties such as model vendors.
+func upstreamBaggage(ctx context.Context) context.Context {
+	bag := baggage.FromContext(ctx)
+	for _, m := range bag.Members() {
+		if m.Key() != RequestIDKey {
+			bag = bag.DeleteMember(m.Key())
+		}
+	}
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := Tracer().Start(req.Context(), "HTTP "+req.Method+" "+req.URL.Host,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.ServerAddress(req.URL.Hostname()),
+			// The path only: query strings may carry API keys (Gemini).
+			semconv.URLPath(req.URL.Path),
+		))
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(upstreamBaggage(ctx), propagation.HeaderCarrier(req.Header))
+	if id := RequestID(ctx); id != "" && req.Header.Get("X-Request-Id") == "" {
+		req.Header.Set("X-Request-Id", id)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		End(span, err)
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	// Streams are read long after RoundTrip returns; the span covers the
+	// wait for the response headers.
+	span.End()
+	return resp, nil
+}
@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestQueryName(t *testing.T) {
+	cases := map[string]string{
+		"-- name: GetUserByEmail :one\nSELECT 1": "GetUserByEmail",
+		"SELECT 1":                               "query",
+		"-- name: ":                              "query",
+	}
+	for query, want := range cases {
+		if got := QueryName(query); got != want {
+			t.Errorf("QueryName(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestTransportPropagates(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var got http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer upstream.Close()
+
+	// baggage extracted from a client request
+	internal, _ := baggage.NewMember("user.email", "ann@example.com")
+	bag, _ := baggage.New(internal)
+	ctx := WithRequestID(baggage.ContextWithBaggage(context.Background(), bag), "req-123")
+	ctx, span := Start(ctx, "parent")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL+"/v1/chat?key=secret", nil)
+	resp, err := (&http.Client{Transport: Transport(nil)}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	span.End()
+
+	if got.Get("X-Request-Id") != "req-123" {
+		t.Errorf("X-Request-Id = %q", got.Get("X-Request-Id"))
+	}
+	if got.Get("Traceparent") == "" {
+		t.Error("traceparent was not sent")
+	}
+	if b := got.Get("Baggage"); b != RequestIDKey+"=req-123" {
+		t.Errorf("baggage = %q, want only the request ID", b)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ended spans = %d, want 2", len(spans))
+	}
+	client := spans[0]
+	if client.Parent().SpanID() != span.SpanContext().SpanID() {
+		t.Error("client span should be a child of the caller's span")
+	}
+	for _, a := range client.Attributes() {
+		if a.Value.Emit() == "/v1/chat?key=secret" {
+			t.Error("query string must not be recorded")
+		}
+	}
+}
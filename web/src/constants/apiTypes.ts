@@ -3,7 +3,8 @@ export const API_TYPES = {
   CLAUDE: 'claude',
   GEMINI: 'gemini',
   OLLAMA: 'ollama',
-  CUSTOM: 'custom'
+  CUSTOM: 'custom',
+  IMAGE: 'image'
 } as const
 
 export type ApiType = typeof API_TYPES[keyof typeof API_TYPES]
@@ -13,7 +14,8 @@ export const API_TYPE_OPTIONS = [
   { label: 'Claude', value: API_TYPES.CLAUDE },
   { label: 'Gemini', value: API_TYPES.GEMINI },
   { label: 'Ollama', value: API_TYPES.OLLAMA },
-  { label: 'Custom', value: API_TYPES.CUSTOM }
+  { label: 'Custom', value: API_TYPES.CUSTOM },
+  { label: 'Image', value: API_TYPES.IMAGE }
 ]
 
 export const API_TYPE_DISPLAY_NAMES = {
@@ -21,5 +23,6 @@ export const API_TYPE_DISPLAY_NAMES = {
   [API_TYPES.CLAUDE]: 'Claude',
   [API_TYPES.GEMINI]: 'Gemini',
   [API_TYPES.OLLAMA]: 'Ollama',
-  [API_TYPES.CUSTOM]: 'Custom'
+  [API_TYPES.CUSTOM]: 'Custom',
+  [API_TYPES.IMAGE]: 'Image'
 } as const
\ No newline at end of file